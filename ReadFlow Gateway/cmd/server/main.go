@@ -14,6 +14,8 @@ import (
 	"github.com/readflow/gateway/internal/api"
 	"github.com/readflow/gateway/internal/config"
 	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/middleware"
+	"github.com/readflow/gateway/internal/utils"
 	"github.com/readflow/gateway/internal/worker"
 )
 
@@ -26,6 +28,15 @@ func main() {
 	cfg := config.Load()
 	log.Printf("[INFO] Configuration loaded - DB: %s, Port: %s", cfg.DBPath, cfg.ServerPort)
 
+	// 校验出站代理，配置错误时直接退出，避免绕过代理直连
+	outboundProxy, err := utils.ParseProxyURL(cfg.OutboundProxy)
+	if err != nil {
+		log.Fatalf("[ERROR] Invalid OUTBOUND_PROXY: %v", err)
+	}
+	if outboundProxy != nil {
+		log.Printf("[INFO] Outbound requests use proxy: %s", outboundProxy.Redacted())
+	}
+
 	// 初始化数据库
 	database, err := db.New(cfg.DBPath)
 	if err != nil {
@@ -34,6 +45,15 @@ func main() {
 	defer database.Close()
 	log.Println("[INFO] Database initialized successfully")
 
+	// 根据 ADMIN_USERS 提升管理员
+	if admins := cfg.GetAdminUsernames(); len(admins) > 0 {
+		if n, err := database.PromoteAdmins(admins); err != nil {
+			log.Printf("[WARN] Failed to promote admin users: %v", err)
+		} else if n > 0 {
+			log.Printf("[INFO] Promoted %d user(s) to admin from ADMIN_USERS", n)
+		}
+	}
+
 	// 初始化 Gin 路由
 	router := setupRoutes(cfg, database, nil)
 
@@ -41,9 +61,17 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// FETCH_INTERVAL 作为新订阅源的默认抓取间隔，可在管理后台修改
+	rc := config.GetRuntimeConfig()
+	rc.SetFetchInterval(cfg.FetchInterval)
+	// IMAGE_* 环境变量作为图片处理的初始配置，同样可在管理后台修改
+	rc.SetImageMaxWidth(cfg.ImageMaxWidth)
+	rc.SetImageQuality(cfg.ImageQuality)
+	rc.SetImageConcurrent(cfg.ImageConcurrent)
+
 	w := worker.New(database, cfg)
 	go w.Start(ctx)
-	log.Printf("[INFO] RSS Worker started with interval: %d seconds", cfg.FetchInterval)
+	log.Printf("[INFO] RSS Worker started, default source fetch interval: %d seconds", cfg.FetchInterval)
 
 	// 更新路由中的 Worker 引用
 	router = setupRoutes(cfg, database, w)
@@ -83,6 +111,10 @@ func main() {
 // setupRoutes 设置所有路由
 func setupRoutes(cfg *config.Config, database *db.DB, w *worker.Worker) *gin.Engine {
 	router := gin.New()
+	// gin 默认信任所有代理的 X-Forwarded-For，客户端可以伪造 IP 绕过按 IP 限流
+	if err := router.SetTrustedProxies(cfg.GetTrustedProxies()); err != nil {
+		log.Fatalf("[ERROR] Invalid TRUSTED_PROXIES: %v", err)
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
@@ -104,43 +136,90 @@ func setupRoutes(cfg *config.Config, database *db.DB, w *worker.Worker) *gin.Eng
 		c.Next()
 	})
 
+	// 响应压缩（/static 下是已压缩的图片，不再重复压缩）
+	if cfg.GzipLevel > 0 {
+		router.Use(middleware.Gzip(middleware.GzipConfig{
+			Level:         cfg.GzipLevel,
+			MinSize:       cfg.GzipMinSize,
+			ExcludedPaths: []string{"/static/"},
+		}))
+	}
+
 	// 创建服务实例
 	authService := api.NewAuthService(database, cfg)
-	syncHandler := api.NewSyncHandler(database, w)
-	subscribeHandler := api.NewSubscribeHandler(database)
+	syncHandler := api.NewSyncHandler(database, w, cfg.GetImageProxyKey())
+	subscribeHandler := api.NewSubscribeHandler(database, w, utils.NewHostPolicy(cfg.GetRSSAllowedHosts(), cfg.GetRSSDeniedHosts()))
+	previewHandler := api.NewPreviewHandler(w)
+	readerHandler := api.NewReaderHandler(w)
 	ackHandler := api.NewAckHandler(database, cfg.StaticDir)
 	vocabHandler := api.NewVocabHandler(database)
-	adminHandler := api.NewAdminHandler(database, cfg.StaticDir, w) // 注入 Worker 用于立即刷新
-	articleHandler := api.NewArticleHandler(database)
+	adminHandler := api.NewAdminHandler(database, cfg.StaticDir, cfg.GetCredentialKey(), w) // 注入 Worker 用于立即刷新
+	articleHandler := api.NewArticleHandler(database, cfg.GetImageProxyKey())
+	outboundProxy, _ := utils.ParseProxyURL(cfg.OutboundProxy) // main 中已校验
+	imageProxyHandler := api.NewImageProxyHandler(outboundProxy, cfg)
+	catalogHandler := api.NewCatalogHandler(cfg.CatalogPath)
+	groupHandler := api.NewGroupHandler(database)
+	filterHandler := api.NewFilterHandler()
+	userDataHandler := api.NewUserDataHandler(database, authService, subscribeHandler)
 
 	// 认证 API
 	authGroup := router.Group("/api/auth")
 	{
-		authGroup.POST("/login", authService.Login)
-		authGroup.POST("/register", authService.Register)
+		authGroup.POST("/login", middleware.NewLoginLimiter().Middleware(), authService.Login)
+		authGroup.POST("/register", middleware.NewRegisterLimiter().Middleware(), authService.Register)
+		authGroup.POST("/forgot", middleware.NewPasswordResetLimiter().Middleware(), authService.ForgotPassword)
+		authGroup.POST("/reset", middleware.NewPasswordResetLimiter().Middleware(), authService.ResetPassword)
 	}
 
+	// 只读 API 令牌可调用的路由通过 readOnly 注册，其余路由需要读写令牌
+	readOnly := authService.ReadOnly
+
 	// 用户 API（需要认证）
 	userGroup := router.Group("/api/user")
 	userGroup.Use(authService.AuthMiddleware())
 	{
+		readOnly(userGroup, http.MethodGet, "/profile", authService.GetProfile)
 		userGroup.POST("/profile", authService.UpdateProfile)
+		// API 令牌（供脚本使用，只能通过登录凭证管理）
+		userGroup.POST("/tokens", authService.CreateAPIToken)
+		userGroup.GET("/tokens", authService.ListAPITokens)
+		userGroup.DELETE("/tokens/:id", authService.DeleteAPIToken)
+		// 数据导出 / 导入（导出需再次确认密码，按登录接口的频率限流）
+		readOnly(userGroup, http.MethodGet, "/export", middleware.NewLoginLimiter().Middleware(), userDataHandler.Export)
+		userGroup.POST("/import", userDataHandler.Import)
 	}
 
+	// 推荐订阅源目录（无需认证，可缓存）
+	router.GET("/api/sources/catalog", catalogHandler.GetCatalog)
+
 	// 订阅 API（需要认证）
 	subscribeGroup := router.Group("/api")
 	subscribeGroup.Use(authService.AuthMiddleware())
 	{
 		subscribeGroup.POST("/subscribe", subscribeHandler.Subscribe)
 		subscribeGroup.DELETE("/subscribe/:source_id", subscribeHandler.Unsubscribe)
-		subscribeGroup.GET("/subscriptions", subscribeHandler.GetSubscriptions)
+		readOnly(subscribeGroup, http.MethodGet, "/subscriptions", subscribeHandler.GetSubscriptions)
+		subscribeGroup.POST("/subscriptions/:source_id/catch-up", subscribeHandler.CatchUp)
+		subscribeGroup.POST("/subscriptions/:source_id/pause", subscribeHandler.PauseSubscription)
+		subscribeGroup.POST("/subscriptions/:source_id/resume", subscribeHandler.ResumeSubscription)
+		subscribeGroup.POST("/subscriptions/:source_id/refresh", middleware.NewSourceRefreshLimiter().Middleware(), subscribeHandler.RefreshSource)
+		subscribeGroup.POST("/sources/preview", previewHandler.PreviewFeed)
+		// 订阅源分组
+		readOnly(subscribeGroup, http.MethodGet, "/groups", groupHandler.ListGroups)
+		subscribeGroup.POST("/groups", groupHandler.CreateGroup)
+		subscribeGroup.DELETE("/groups/:id", groupHandler.DeleteGroup)
+		subscribeGroup.PUT("/groups/:id/sources", groupHandler.SetGroupSources)
+		subscribeGroup.PUT("/subscriptions/groups", groupHandler.SetSubscriptionGroups)
 	}
 
 	// 同步 API（需要认证）
 	syncGroup := router.Group("/api")
 	syncGroup.Use(authService.AuthMiddleware())
 	{
-		syncGroup.GET("/sync", syncHandler.Sync)
+		readOnly(syncGroup, http.MethodGet, "/sync", syncHandler.Sync)
+		readOnly(syncGroup, http.MethodGet, "/sync/counts", syncHandler.Counts)
+		readOnly(syncGroup, http.MethodGet, "/unread/count", syncHandler.UnreadCount)
+		readOnly(syncGroup, http.MethodGet, "/sync/all", syncHandler.SyncAll)
 	}
 
 	// 文章 API（需要认证）
@@ -148,8 +227,12 @@ func setupRoutes(cfg *config.Config, database *db.DB, w *worker.Worker) *gin.Eng
 	articleGroup.Use(authService.AuthMiddleware())
 	{
 		// 文章查询
-		articleGroup.GET("/articles", articleHandler.ListArticles)
-		articleGroup.GET("/articles/:id", articleHandler.GetArticleDetail)
+		readOnly(articleGroup, http.MethodGet, "/articles", articleHandler.ListArticles)
+		readOnly(articleGroup, http.MethodGet, "/articles/continue", articleHandler.ListContinueReading)
+		readOnly(articleGroup, http.MethodGet, "/articles/:id", articleHandler.GetArticleDetail)
+		readOnly(articleGroup, http.MethodPost, "/articles/detail-batch", articleHandler.GetArticleDetailBatch)
+		readOnly(articleGroup, http.MethodGet, "/articles/:id/related", articleHandler.GetRelatedArticles)
+		readOnly(articleGroup, http.MethodGet, "/categories", articleHandler.ListCategories)
 		// Quest 5: 阅读状态管理
 		articleGroup.POST("/articles/:id/read", articleHandler.MarkArticleRead)
 		articleGroup.DELETE("/articles/:id/read", articleHandler.MarkArticleUnread)
@@ -158,6 +241,20 @@ func setupRoutes(cfg *config.Config, database *db.DB, w *worker.Worker) *gin.Eng
 		articleGroup.PUT("/articles/:id/progress", articleHandler.UpdateArticleProgress)
 	}
 
+	// 阅读模式 API（需要认证）
+	readerGroup := router.Group("/api")
+	readerGroup.Use(authService.AuthMiddleware())
+	{
+		readerGroup.POST("/read", readerHandler.Read)
+	}
+
+	// 过滤规则 API（需要认证）
+	filterGroup := router.Group("/api/filters")
+	filterGroup.Use(authService.AuthMiddleware())
+	{
+		readOnly(filterGroup, http.MethodPost, "/test", filterHandler.TestRule)
+	}
+
 	// 确认 API（需要认证）
 	ackGroup := router.Group("/api")
 	ackGroup.Use(authService.AuthMiddleware())
@@ -170,42 +267,73 @@ func setupRoutes(cfg *config.Config, database *db.DB, w *worker.Worker) *gin.Eng
 	vocabGroup.Use(authService.AuthMiddleware())
 	{
 		vocabGroup.POST("/push", vocabHandler.Push)
-		vocabGroup.GET("/pull", vocabHandler.Pull)
+		readOnly(vocabGroup, http.MethodGet, "/pull", vocabHandler.Pull)
+		vocabGroup.POST("/import", vocabHandler.Import)
 	}
 
-	// 管理后台 Web UI（无需认证）
-	router.GET("/admin", func(c *gin.Context) {
+	// 管理后台 Web UI（需要管理员权限，未登录时跳转到登录页）
+	router.GET("/admin/login", func(c *gin.Context) {
+		c.File("internal/api/admin_login.html")
+	})
+	router.GET("/admin", authService.AdminPageMiddleware(), func(c *gin.Context) {
 		c.File("internal/api/admin.html")
 	})
 
 	// 静态文件服务（图片缓存）
 	router.Static("/static", cfg.StaticDir)
 
-	// 管理 API - 无需认证
+	// 图片代理（image_mode=proxy 的源使用，<img> 无法携带认证头）
+	imageProxyLimiter := middleware.NewImageProxyLimiter().Middleware()
+	router.GET("/api/image", imageProxyLimiter, imageProxyHandler.HandleImage)
+	router.HEAD("/api/image", imageProxyLimiter, imageProxyHandler.HandleImage)
+
+	// 管理 API（需要管理员权限）
 	adminGroup := router.Group("/api/admin")
+	adminGroup.Use(authService.AdminMiddleware())
 	{
 		adminGroup.GET("/dashboard", adminHandler.Dashboard)
 		adminGroup.GET("/users", adminHandler.UserSubscriptions)
+		adminGroup.GET("/users/list", adminHandler.ListUsers)
 		adminGroup.GET("/sources", adminHandler.SourceDetails)
+		adminGroup.GET("/sources/items", adminHandler.SourceItems)
 		adminGroup.GET("/cache-stats", adminHandler.CacheStats)
+		adminGroup.GET("/cache/sources", adminHandler.CacheSources)
+		adminGroup.POST("/cache/clear", adminHandler.ClearSourceCache)
+		adminGroup.POST("/cache/reprocess", adminHandler.ReprocessSourceCache)
 		adminGroup.GET("/metrics", adminHandler.SystemMetrics)
 		// 配置管理接口
 		adminGroup.GET("/config", adminHandler.GetConfig)
 		adminGroup.POST("/config", adminHandler.UpdateConfig)
 		// 用户管理接口
 		adminGroup.DELETE("/users", adminHandler.DeleteUser)
+		adminGroup.POST("/users/quota", adminHandler.SetUserQuota)
 		// 源管理接口
 		adminGroup.POST("/sources/refresh", adminHandler.RefreshSource)
+		adminGroup.POST("/sources/favicon", adminHandler.RefreshSourceFavicon)
+		adminGroup.GET("/sources/failed", adminHandler.FailedSources)
+		adminGroup.GET("/sources/latency", adminHandler.SourceLatencies)
+		adminGroup.POST("/sources/retry-failed", adminHandler.RetryFailedSources)
 		adminGroup.POST("/sources/clear-items", adminHandler.ClearSourceItems)
+		adminGroup.POST("/sources/merge", adminHandler.MergeSources)
+		adminGroup.POST("/sources/credentials", adminHandler.SetSourceCredentials)
+		adminGroup.POST("/sources/image-mode", adminHandler.SetSourceImageMode)
+		adminGroup.POST("/sources/proxy", adminHandler.SetSourceProxy)
+		adminGroup.POST("/sources/retention", adminHandler.SetSourceRetention)
+		adminGroup.POST("/sources/summary-length", adminHandler.SetSourceSummaryLength)
+		adminGroup.POST("/sources/gallery", adminHandler.SetSourceGallery)
+		adminGroup.POST("/sources/full-content", adminHandler.SetSourceFullContent)
+		adminGroup.POST("/sources/min-words", adminHandler.SetSourceMinWordCount)
+		adminGroup.POST("/sources/max-items", adminHandler.SetSourceMaxItems)
+		adminGroup.POST("/sources/lenient-parse", adminHandler.SetSourceLenientParse)
+		adminGroup.POST("/sources/body-images", adminHandler.SetSourceBodyImagesOnly)
+		adminGroup.PUT("/sources/classify", adminHandler.ClassifySources)
+		adminGroup.POST("/sources/content-updates", adminHandler.SetSourceContentUpdateMode)
+		adminGroup.POST("/items/reprocess", adminHandler.ReprocessItems)
+		adminGroup.GET("/items/:id/raw", adminHandler.ItemRaw)
 	}
 
 	// 健康检查 (支持 GET 和 HEAD)
-	router.Match([]string{"GET", "HEAD"}, "/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-			"time":   time.Now(),
-		})
-	})
+	router.Match([]string{"GET", "HEAD"}, "/health", api.HealthCheck)
 
 	return router
 }
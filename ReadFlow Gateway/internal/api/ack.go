@@ -26,8 +26,13 @@ func NewAckHandler(database *db.DB, staticDir string) *AckHandler {
 // AckRequest ACK 请求
 type AckRequest struct {
 	ItemIDs []int64 `json:"item_ids" binding:"required"`
+	// Cleanup 确认后立即清理所有订阅者都已确认的文章及其图片，默认等待后台定时任务按保留时间清理
+	Cleanup bool `json:"cleanup"`
 }
 
+// maxAckCleanupItems 单次请求立即清理的文章数上限，超出部分留给后台定时任务
+const maxAckCleanupItems = 500
+
 // AckResponse ACK 响应
 type AckResponse struct {
 	Success      bool   `json:"success"`
@@ -40,19 +45,13 @@ type AckResponse struct {
 func (h *AckHandler) Acknowledge(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	var req AckRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, AckResponse{
-			Success: false,
-			Message: "无效的请求参数",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
 		return
 	}
 
@@ -67,31 +66,65 @@ func (h *AckHandler) Acknowledge(c *gin.Context) {
 
 	// 更新投递状态为已投递
 	if err := h.db.BatchUpdateDeliveryStatus(userID, req.ItemIDs, 1); err != nil {
-		c.JSON(http.StatusInternalServerError, AckResponse{
-			Success: false,
-			Message: "更新状态失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "更新状态失败")
 		return
 	}
 
-	// 不再立即删除，等待后台定時任务清理
+	// 默认不立即删除，等待后台定时任务清理
+	cleaned := 0
+	if req.Cleanup {
+		cleaned = h.cleanAcked(userID, req.ItemIDs)
+	}
+
 	c.JSON(http.StatusOK, AckResponse{
 		Success:      true,
 		Acknowledged: len(req.ItemIDs),
-		Cleaned:      0, // 不立即清理
+		Cleaned:      cleaned,
 	})
 }
 
+// cleanAcked 立即清理本次确认的文章中已投递给该用户、且所有订阅者都已确认的文章，返回清理数
+// 只处理投递给该用户的文章，避免用户通过确认他人的文章提前删除它们；清理失败只记录日志
+func (h *AckHandler) cleanAcked(userID int64, itemIDs []int64) int {
+	unique := make([]int64, 0, len(itemIDs))
+	seen := make(map[int64]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		if !seen[id] && len(unique) < maxAckCleanupItems {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	delivered, err := h.db.GetDeliveredItemIDs(userID, unique)
+	if err != nil {
+		log.Printf("Failed to check deliveries for immediate cleanup: %v", err)
+		return 0
+	}
+
+	cleaned := 0
+	for _, itemID := range unique {
+		if !delivered[itemID] || !h.shouldCleanItem(itemID) {
+			continue
+		}
+		if err := h.cleanItem(itemID); err != nil {
+			log.Printf("Failed to clean item %d: %v", itemID, err)
+			continue
+		}
+		cleaned++
+	}
+	return cleaned
+}
+
 // shouldCleanItem 判断是否应该清理文章
 func (h *AckHandler) shouldCleanItem(itemID int64) bool {
-	total, acked, err := h.db.GetDeliveryStats(itemID)
+	total, acked, kept, err := h.db.GetDeliveryStats(itemID)
 	if err != nil {
 		log.Printf("Failed to get delivery stats for item %d: %v", itemID, err)
 		return false
 	}
 
-	// 所有用户都已确认接收
-	return total > 0 && total == acked
+	// 所有用户都已确认接收，且没有用户收藏或正在阅读（与后台保留期清理的保护规则一致）
+	return total > 0 && total == acked && kept == 0
 }
 
 // cleanItem 清理文章及相关资源
@@ -116,13 +149,8 @@ func (h *AckHandler) cleanItem(itemID int64) error {
 		}
 	}
 
-	// 删除投递记录
-	if err := h.db.DeleteUserDeliveries(itemID); err != nil {
-		return err
-	}
-
-	// 删除文章
-	if err := h.db.DeleteItem(itemID); err != nil {
+	// 删除文章及投递记录；记录墓碑，文章仍在 feed 中时不会重新入库并再次投递
+	if err := h.db.DeleteItemWithTombstone(itemID); err != nil {
 		return err
 	}
 
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestAcknowledgeCleanup(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	alice, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := database.CreateUser("bob", "bob@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := database.CreateSource("https://example.com/feed", "feed", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newItem := func(guid string, userIDs ...int64) int64 {
+		t.Helper()
+		res, err := database.Exec("INSERT INTO items (source_id, guid, title, xml_content) VALUES (?, ?, ?, '')", source.ID, guid, guid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		itemID, _ := res.LastInsertId()
+		if err := database.BatchCreateUserDeliveries(itemID, userIDs); err != nil {
+			t.Fatal(err)
+		}
+		return itemID
+	}
+	onlyAlice := newItem("only-alice", alice.ID)
+	shared := newItem("shared", alice.ID, bob.ID)
+	favorite := newItem("favorite", alice.ID)
+	onlyBob := newItem("only-bob", bob.ID)
+	if _, err := database.Exec("UPDATE user_deliveries SET is_favorite = 1 WHERE item_id = ?", favorite); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.BatchUpdateDeliveryStatus(bob.ID, []int64{onlyBob}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewAckHandler(database, t.TempDir())
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/ack", func(c *gin.Context) {
+		c.Set("user_id", alice.ID)
+	}, h.Acknowledge)
+
+	ack := func(body string) AckResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/ack", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp AckResponse
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil {
+			t.Fatalf("ack %s: code=%d body=%s", body, rec.Code, rec.Body.String())
+		}
+		return resp
+	}
+	exists := func(itemID int64) bool {
+		_, err := database.GetItemByID(itemID)
+		return err == nil
+	}
+
+	// 默认只确认，不清理
+	if resp := ack(`{"item_ids":[` + itemIDList(onlyAlice) + `]}`); resp.Cleaned != 0 || !exists(onlyAlice) {
+		t.Fatalf("lazy ack cleaned=%d exists=%v", resp.Cleaned, exists(onlyAlice))
+	}
+
+	// 只清理所有订阅者都已确认、没有被收藏、且投递给当前用户的文章
+	resp := ack(`{"item_ids":[` + itemIDList(onlyAlice, shared, favorite, onlyBob) + `],"cleanup":true}`)
+	if resp.Cleaned != 1 {
+		t.Errorf("cleaned = %d, want 1", resp.Cleaned)
+	}
+	if exists(onlyAlice) {
+		t.Error("fully acked item was not cleaned")
+	}
+	// 清理时记录墓碑，文章仍在 feed 中时不会重新入库
+	if tombstoned, err := database.MatchItemTombstone(source.ID, "only-alice", ""); err != nil || !tombstoned {
+		t.Errorf("cleaned item tombstoned = %v, %v", tombstoned, err)
+	}
+	for name, itemID := range map[string]int64{"shared": shared, "favorite": favorite, "only-bob": onlyBob} {
+		if !exists(itemID) {
+			t.Errorf("%s item was cleaned", name)
+		}
+	}
+}
+
+func itemIDList(ids ...int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
@@ -1,38 +1,55 @@
 package api
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/readflow/gateway/internal/config"
 	"github.com/readflow/gateway/internal/db"
 	"github.com/readflow/gateway/internal/image"
 	"github.com/readflow/gateway/internal/metrics"
+	"github.com/readflow/gateway/internal/utils"
+	"github.com/readflow/gateway/internal/worker"
 )
 
 // AdminRefreshWorker 定义刷新源所需的 Worker 接口
 type AdminRefreshWorker interface {
 	FetchSource(source *db.Source) error
+	RefreshFavicon(source *db.Source) (string, error)
+	RetrySources(sources []*db.Source) ([]worker.SourceRetryOutcome, error)
+	ReprocessItems(sourceID, afterID int64, limit int) (*worker.ReprocessResult, error)
+	ReprocessImages(sourceID, afterID int64, limit int) (*worker.ImageReprocessResult, error)
+	TrimSource(source *db.Source) (int, error)
 }
 
 // AdminHandler 管理后台处理器
 type AdminHandler struct {
-	db        *db.DB
-	staticDir string
-	worker    AdminRefreshWorker // Worker 实例，用于立即刷新源
+	db            *db.DB
+	staticDir     string
+	credentialKey string             // 订阅源凭据加密密钥
+	worker        AdminRefreshWorker // Worker 实例，用于立即刷新源
+	sourceCache   sourceCacheStats   // 按源统计的图片缓存扫描结果
 }
 
 // NewAdminHandler 创建管理后台处理器
-func NewAdminHandler(database *db.DB, staticDir string, worker AdminRefreshWorker) *AdminHandler {
+func NewAdminHandler(database *db.DB, staticDir, credentialKey string, worker AdminRefreshWorker) *AdminHandler {
 	return &AdminHandler{
-		db:        database,
-		staticDir: staticDir,
-		worker:    worker,
+		db:            database,
+		staticDir:     staticDir,
+		credentialKey: credentialKey,
+		worker:        worker,
 	}
 }
 
@@ -41,53 +58,73 @@ func (h *AdminHandler) Dashboard(c *gin.Context) {
 	// 获取系统统计
 	systemStats := h.getSystemStats()
 
-	// 获取用户统计
-	userStats := h.getUserStats()
+	tf := newTimeFormat(c)
+
+	// 最近登录的用户（完整列表见 /api/admin/users/list）
+	recentUsers := h.getRecentUsers(tf)
 
 	// 获取源统计
-	sourceStats := h.getSourceStats()
+	sourceStats := h.getSourceStats(tf)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"system":  systemStats,
-			"users":   userStats,
-			"sources": sourceStats,
+			"system":       systemStats,
+			"recent_users": recentUsers,
+			"sources":      sourceStats,
 		},
 	})
 }
 
+// ListUsers 分页获取用户列表
+// 参数：limit（默认 50，最大 200）、cursor（兼容 offset）、q（按用户名 / 邮箱搜索）
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit := queryLimit(c, "limit", 50, 200)
+	offset := parseOffsetCursor(c)
+	keyword := strings.TrimSpace(c.Query("q"))
+
+	users, total, err := h.db.ListUsersWithStats(keyword, limit, offset)
+	if err != nil {
+		log.Printf("[ADMIN] Failed to list users: %v", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询用户失败")
+		return
+	}
+
+	hasMore, nextCursor := offsetNextCursor(offset, len(users), total)
+	items := newUserStatsJSON(newTimeFormat(c), users)
+	c.JSON(http.StatusOK, pageResponse(c, items, hasMore, nextCursor, &total, gin.H{
+		"data": gin.H{
+			"users":  items,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	}))
+}
+
 // UserSubscriptions 获取用户订阅信息
 func (h *AdminHandler) UserSubscriptions(c *gin.Context) {
 	userIDStr := c.Query("user_id")
 	if userIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "user_id 参数缺失",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "user_id 参数缺失")
 		return
 	}
 
 	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "user_id 参数无效",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "user_id 参数无效")
 		return
 	}
 
 	// 获取用户的所有订阅
 	subscriptions, err := h.db.GetSubscriptionsByUser(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "查询失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
 		return
 	}
 
 	// 获取每个源的统计信息
+	tf := newTimeFormat(c)
 	var result []gin.H
 	for _, sub := range subscriptions {
 		source, _ := h.db.GetSourceByID(sub.SourceID)
@@ -102,51 +139,40 @@ func (h *AdminHandler) UserSubscriptions(c *gin.Context) {
 				"source_id":       source.ID,
 				"title":           source.Title,
 				"url":             source.URL,
-				"fetch_count":     source.ErrorCount,    // 这里用 error_count 作为示例，实际应该统计 fetch 次数
-				"item_total":      itemCount,            // 该源的总文章数
-				"delivered":       deliveredCount,       // 该用户从该源拉取的文章数
-				"is_active":       source.IsActive,      // 是否活跃
-				"last_fetch_time": source.LastFetchTime, // 最后抓取时间
-				"error_count":     source.ErrorCount,    // 错误计数
-				"last_error":      source.LastError,     // 最后错误信息
-				"subscribed_at":   sub.SubscribedAt,     // 订阅时间
+				"fetch_count":     source.ErrorCount,              // 这里用 error_count 作为示例，实际应该统计 fetch 次数
+				"item_total":      itemCount,                      // 该源的总文章数
+				"delivered":       deliveredCount,                 // 该用户从该源拉取的文章数
+				"is_active":       source.IsActive,                // 是否活跃
+				"last_fetch_time": tf.atPtr(source.LastFetchTime), // 最后抓取时间
+				"error_count":     source.ErrorCount,              // 错误计数
+				"last_error":      source.LastError,               // 最后错误信息
+				"subscribed_at":   tf.at(sub.SubscribedAt),        // 订阅时间
 			})
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"user_id": userID,
-		"data":    result,
-	})
+	resp := completePage(c, result, len(result), gin.H{"data": result})
+	resp["user_id"] = userID
+	c.JSON(http.StatusOK, resp)
 }
 
 // SourceDetails 获取源的详细信息
 func (h *AdminHandler) SourceDetails(c *gin.Context) {
 	sourceIDStr := c.Query("source_id")
 	if sourceIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "source_id 参数缺失",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数缺失")
 		return
 	}
 
 	sourceID, err := strconv.ParseInt(sourceIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "source_id 参数无效",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数无效")
 		return
 	}
 
 	source, err := h.db.GetSourceByID(sourceID)
 	if err != nil || source == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"message": "源不存在",
-		})
+		respondError(c, http.StatusNotFound, CodeNotFound, "源不存在")
 		return
 	}
 
@@ -154,6 +180,14 @@ func (h *AdminHandler) SourceDetails(c *gin.Context) {
 	totalItems, _ := h.db.GetItemCountBySource(sourceID)
 	totalSubscribers, _ := h.db.GetSubscriberCountBySource(sourceID)
 	totalDeliveries, _ := h.db.GetDeliveryCountBySource(sourceID)
+	_, credErr := h.db.GetSourceCredential(sourceID)
+	staleness, isStale := sourceStaleness(source)
+	tf := newTimeFormat(c)
+	// 进程启动后未抓取过时为 null
+	var latency *sourceLatencyJSON
+	if l, ok := metrics.GetMetrics().GetSourceLatency(sourceID); ok {
+		latency = &sourceLatencyJSON{SourceLatency: &l, UpdatedAt: tf.at(l.UpdatedAt)}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -164,14 +198,38 @@ func (h *AdminHandler) SourceDetails(c *gin.Context) {
 			"description":     source.Description,
 			"is_active":       source.IsActive,
 			"fetch_interval":  source.FetchInterval,
-			"last_fetch_time": source.LastFetchTime,
-			"created_at":      source.CreatedAt,
+			"last_fetch_time": tf.atPtr(source.LastFetchTime),
+			"created_at":      tf.at(source.CreatedAt),
+			"has_credentials": credErr == nil,
+			"image_mode":      source.ImageMode,
+			"proxy_url":       utils.RedactProxyURL(source.ProxyURL),
+			// 0 表示使用全局 item_retention_time
+			"retention_seconds":   source.RetentionSeconds,
+			"content_update_mode": source.ContentUpdateMode,
+			"summary_length":      source.SummaryLength,
+			"gallery_enabled":     source.GalleryEnabled,
+			"full_content":        source.FullContent,
+			"min_word_count":      source.MinWordCount,
+			"max_items":           source.MaxItems,
+			"lenient_parse":       source.LenientParse,
+			"body_images_only":    source.BodyImagesOnly,
+			"category":            source.Category,
+			"language":            source.Language,
+			"favicon":             source.Favicon,
+			// 停更检测
+			"last_item_added_at": tf.atPtr(source.LastItemAddedAt),
+			"staleness_seconds":  staleness,
+			"is_stale":           isStale,
+			// 抓取耗时（最近几次的滚动统计）
+			"fetch_latency": latency,
 			// 统计数据
 			"total_items":       totalItems,
 			"total_subscribers": totalSubscribers,
 			"total_deliveries":  totalDeliveries,
 			"error_count":       source.ErrorCount,
 			"last_error":        source.LastError,
+			// 最近一次抓取的数据质量提示（部分文章没有日期、空壳文章被跳过等），没有问题时为空
+			"last_parse_note": source.LastParseNote,
 			// 计算的指标
 			"avg_items_per_fetch": float64(totalItems) / float64(source.ErrorCount+1), // 避免除以零
 			"success_rate":        fmt.Sprintf("%.2f%%", (1.0-float64(source.ErrorCount)/float64(totalItems+1))*100),
@@ -179,6 +237,133 @@ func (h *AdminHandler) SourceDetails(c *gin.Context) {
 	})
 }
 
+// SourceItems 按发布时间范围分页查询源的文章 GET /api/admin/sources/items?source_id=&from=&to=
+// 用于排查"源里没有"还是"去重时丢弃"：返回 guid、标题、入库时间和投递用户数。
+// from（含）、to（不含）支持 Unix 时间戳、RFC3339 或 YYYY-MM-DD（UTC），缺省不限制；limit 默认 50，最大 200
+func (h *AdminHandler) SourceItems(c *gin.Context) {
+	sourceID, err := strconv.ParseInt(c.Query("source_id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数无效")
+		return
+	}
+	from, err := parseAdminTime(c.Query("from"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "from 参数无效，应为 Unix 时间戳、RFC3339 或 YYYY-MM-DD")
+		return
+	}
+	to, err := parseAdminTime(c.Query("to"))
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "to 参数无效，应为 Unix 时间戳、RFC3339 或 YYYY-MM-DD")
+		return
+	}
+	limit := queryLimit(c, "limit", 50, 200)
+	offset := parseOffsetCursor(c)
+
+	source, err := h.db.GetSourceByID(sourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "源不存在")
+		return
+	}
+
+	items, total, err := h.db.ListSourceItemsByPublished(sourceID, from, to, limit, offset)
+	if err != nil {
+		log.Printf("[ADMIN] Failed to list items for source %d: %v", sourceID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询文章失败")
+		return
+	}
+
+	hasMore, nextCursor := offsetNextCursor(offset, len(items), total)
+	data := newSourceItemStatsJSON(newTimeFormat(c), items)
+	c.JSON(http.StatusOK, pageResponse(c, data, hasMore, nextCursor, &total, gin.H{
+		"data": gin.H{
+			"items":  data,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		},
+	}))
+}
+
+// ItemRaw 查看文章入库的原始数据（GET /api/admin/items/:id/raw），用于排查客户端显示异常
+// 原样返回 xml_content、feed 原文、清洗后的正文和各派生字段（JSON 数组类字段也按存储的字符串返回），
+// 可与 /items/reprocess 的结果对照输入和输出。正文中的 HTML 由 JSON 编码转义，不影响响应结构
+func (h *AdminHandler) ItemRaw(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
+		return
+	}
+
+	item, err := h.db.GetItemByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "文章不存在")
+			return
+		}
+		log.Printf("[ADMIN] Failed to get item %d: %v", id, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询文章失败")
+		return
+	}
+
+	tf := newTimeFormat(c)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"id":            item.ID,
+			"source_id":     item.SourceID,
+			"guid":          item.GUID,
+			"title":         item.Title,
+			"url":           item.URL,
+			"published_at":  tf.atPtr(item.PublishedAt),
+			"created_at":    tf.at(item.CreatedAt),
+			"xml_content":   item.XMLContent,
+			"content":       item.Content,
+			"clean_content": item.CleanContent,
+			"content_hash":  item.ContentHash,
+			"image_paths":   item.ImagePaths,
+			"sizes": gin.H{
+				"xml_content":   len(item.XMLContent),
+				"content":       len(item.Content),
+				"clean_content": len(item.CleanContent),
+			},
+			"derived": gin.H{
+				"summary":             item.Summary,
+				"difficulty":          item.Difficulty,
+				"word_count":          item.WordCount,
+				"reading_time":        item.ReadingTime,
+				"author":              item.Author,
+				"cover_image":         item.CoverImage,
+				"cover_variants":      item.CoverVariants,
+				"image_caption":       item.ImageCaption,
+				"image_credit":        item.ImageCredit,
+				"image_primary_color": item.ImagePrimaryColor,
+				"image_blurhash":      item.ImageBlurhash,
+				"tags":                item.Tags,
+				"category":            item.Category,
+				"gallery":             item.Gallery,
+				"is_truncated":        item.Truncated,
+			},
+		},
+	})
+}
+
+// parseAdminTime 解析管理接口的时间参数，空字符串返回 nil
+// 结果统一转为 UTC，与入库的发布时间（gofeed 解析为 UTC）按相同格式比较
+func parseAdminTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := parseTimestamp(value)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", value); err != nil {
+			return nil, fmt.Errorf("invalid time %q", value)
+		}
+	}
+	t = t.UTC()
+	return &t, nil
+}
+
 // CacheStats 获取图片缓存统计
 func (h *AdminHandler) CacheStats(c *gin.Context) {
 	stats := h.getImageCacheStats()
@@ -189,6 +374,145 @@ func (h *AdminHandler) CacheStats(c *gin.Context) {
 	})
 }
 
+// CacheSources 按源统计图片缓存 GET /api/admin/cache/sources
+// 遍历 staticDir/images/{source_id}，返回各源的图片数和占用空间（按大小降序）；
+// 扫描文件数有上限，结果缓存 1 分钟，refresh=true 时重新扫描
+func (h *AdminHandler) CacheSources(c *gin.Context) {
+	scan := h.sourceCache.get(c.Query("refresh") == "true", h.scanSourceCache)
+
+	sources, err := h.db.GetAllSources()
+	if err != nil {
+		log.Printf("[ADMIN] Failed to load sources for cache stats: %v", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅源失败")
+		return
+	}
+	titles := make(map[int64]string, len(sources))
+	for _, source := range sources {
+		titles[source.ID] = source.Title
+	}
+
+	entries := make([]SourceCacheEntry, 0, len(scan.entries))
+	for _, entry := range scan.entries {
+		title, ok := titles[entry.SourceID]
+		entry.Title = title
+		entry.Orphaned = !ok
+		entries = append(entries, entry)
+	}
+
+	resp := completePage(c, entries, len(entries), nil)
+	resp["truncated"] = scan.truncated
+	resp["scanned_at"] = scan.scannedAt.Unix()
+	c.JSON(http.StatusOK, resp)
+}
+
+// ClearSourceCache 删除单个源的图片缓存 POST /api/admin/cache/clear?source_id=
+// 只删除图片文件并清空文章的 image_paths 和封面缩略图，文章和投递记录保留；
+// 已入库文章中指向本地缓存的图片会失效，之后抓取或更新的文章重新下载图片
+func (h *AdminHandler) ClearSourceCache(c *gin.Context) {
+	sourceID, err := strconv.ParseInt(c.Query("source_id"), 10, 64)
+	if err != nil || sourceID <= 0 {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数无效")
+		return
+	}
+
+	source, err := h.db.GetSourceByID(sourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	// 先删文件再清空路径：中途失败时只会留下指向已删除文件的路径，清理时会被忽略
+	imageDir := image.GetImageDirPath(h.staticDir, sourceID)
+	files, bytes := countDirFiles(imageDir)
+	if err := os.RemoveAll(imageDir); err != nil {
+		log.Printf("[ADMIN] Failed to remove image cache %s: %v", imageDir, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "删除图片缓存失败")
+		return
+	}
+	items, err := h.db.ClearSourceImagePaths(sourceID)
+	if err != nil {
+		log.Printf("[ADMIN] Failed to clear image paths for source %d: %v", sourceID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "更新文章图片记录失败")
+		return
+	}
+	h.sourceCache.invalidate()
+
+	log.Printf("[ADMIN] Cleared image cache of source %d: %d files, %d bytes, %d items", sourceID, files, bytes, items)
+	c.JSON(http.StatusOK, gin.H{
+		"success":       true,
+		"message":       fmt.Sprintf("订阅源 %s 的 %d 张缓存图片已删除", source.Title, files),
+		"deleted_files": files,
+		"freed_bytes":   bytes,
+		"items":         items,
+	})
+}
+
+// ReprocessSourceCache 按当前压缩配置重新压缩单个源的缓存图片 POST /api/admin/cache/reprocess?source_id=&limit=&cursor=
+// 修改 image_quality、image_max_width 后使用：压缩参数已是当前配置的图片跳过，其余重新下载原图后压缩覆盖原文件；
+// 按文章分批处理，hasMore 为 true 时带上 nextCursor 继续调用下一批
+func (h *AdminHandler) ReprocessSourceCache(c *gin.Context) {
+	if h.worker == nil {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "Worker 不可用")
+		return
+	}
+
+	sourceID, err := strconv.ParseInt(c.Query("source_id"), 10, 64)
+	if err != nil || sourceID <= 0 {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数无效")
+		return
+	}
+	if source, err := h.db.GetSourceByID(sourceID); err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	limit := worker.DefaultImageReprocessBatch
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > worker.MaxImageReprocessBatch {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("limit 参数无效，取值范围 1-%d", worker.MaxImageReprocessBatch))
+			return
+		}
+		limit = n
+	}
+
+	var cursor int64
+	if value := c.Query("cursor"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "cursor 参数无效")
+			return
+		}
+		cursor = n
+	}
+
+	result, err := h.worker.ReprocessImages(sourceID, cursor, limit)
+	if err != nil {
+		log.Printf("[ADMIN] Reprocess image cache of source %d failed: %v", sourceID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "重新压缩缓存图片失败")
+		return
+	}
+	if result.Regenerated > 0 {
+		h.sourceCache.invalidate()
+	}
+
+	resp := gin.H{
+		"success":     true,
+		"message":     fmt.Sprintf("已处理 %d 篇文章，重新生成 %d 张图片，剩余 %d 篇", result.Processed, result.Regenerated, result.Remaining),
+		"processed":   result.Processed,
+		"regenerated": result.Regenerated,
+		"up_to_date":  result.UpToDate,
+		"unavailable": result.Unavailable,
+		"failed":      result.Failed,
+		"remaining":   result.Remaining,
+		"hasMore":     result.Remaining > 0,
+	}
+	if result.Remaining > 0 {
+		resp["nextCursor"] = strconv.FormatInt(result.NextCursor, 10)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // SystemMetrics 获取系统实时指标
 func (h *AdminHandler) SystemMetrics(c *gin.Context) {
 	stats := metrics.GetMetrics().GetStats()
@@ -208,11 +532,46 @@ func (h *AdminHandler) GetConfig(c *gin.Context) {
 	configInfo := map[string]interface{}{
 		"fetch_interval": map[string]interface{}{
 			"value":       allConfig["fetch_interval"],
-			"description": "RSS 抓取間隔（秒）",
+			"description": "新订阅源的默认抓取间隔（秒），已有源不受影响",
 			"min":         60,
 			"max":         86400,
 			"unit":        "秒",
 		},
+		"max_sources_per_round": map[string]interface{}{
+			"value":       allConfig["max_sources_per_round"],
+			"description": "定时抓取每轮（每分钟一轮）最多抓取的到期源数，0 表示不限制；超出的源按上次抓取时间顺延到下一轮",
+			"min":         0,
+			"max":         10000,
+			"unit":        "个",
+		},
+		"refresh_concurrency": map[string]interface{}{
+			"value":       allConfig["refresh_concurrency"],
+			"description": "用户手动刷新时同时抓取的源数量",
+			"min":         1,
+			"max":         10,
+			"unit":        "个",
+		},
+		"refresh_timeout": map[string]interface{}{
+			"value":       allConfig["refresh_timeout"],
+			"description": "用户手动刷新的后台整体时限（请求最多等待 20 秒），超时后不再开始新的源",
+			"min":         30,
+			"max":         1800,
+			"unit":        "秒",
+		},
+		"fetch_jitter_percent": map[string]interface{}{
+			"value":       allConfig["fetch_jitter_percent"],
+			"description": "源抓取间隔的随机偏移比例，使间隔相同的源分散到不同轮次抓取，0 表示不偏移",
+			"min":         0,
+			"max":         25,
+			"unit":        "%",
+		},
+		"feed_host_concurrency": map[string]interface{}{
+			"value":       allConfig["feed_host_concurrency"],
+			"description": "同一主机同时进行的订阅源请求数，0 表示不限制；超出的请求排队等待",
+			"min":         0,
+			"max":         50,
+			"unit":        "个",
+		},
 		"image_quality": map[string]interface{}{
 			"value":       allConfig["image_quality"],
 			"description": "图片转换品质（1-100）",
@@ -234,6 +593,37 @@ func (h *AdminHandler) GetConfig(c *gin.Context) {
 			"max":         10,
 			"unit":        "个",
 		},
+		"cover_min_width": map[string]interface{}{
+			"value":       allConfig["cover_min_width"],
+			"description": "封面图最小宽度（小于该尺寸的图片不作为封面）",
+			"min":         0,
+			"max":         2000,
+			"unit":        "px",
+		},
+		"cover_min_height": map[string]interface{}{
+			"value":       allConfig["cover_min_height"],
+			"description": "封面图最小高度（小于该尺寸的图片不作为封面）",
+			"min":         0,
+			"max":         2000,
+			"unit":        "px",
+		},
+		"cover_probe_enabled": map[string]interface{}{
+			"value":       allConfig["cover_probe_enabled"],
+			"description": "选封面时部分下载未声明尺寸的正文图片以探测真实尺寸",
+		},
+		"cover_og_image_enabled": map[string]interface{}{
+			"value":       allConfig["cover_og_image_enabled"],
+			"description": "文章没有任何图片时，抓取原文页面的 og:image 作为封面",
+		},
+		"cover_blurhash_enabled": map[string]interface{}{
+			"value":       allConfig["cover_blurhash_enabled"],
+			"description": "为封面图生成 BlurHash 占位，客户端加载图片前显示模糊预览",
+		},
+		"cover_variant_widths": map[string]interface{}{
+			"value":       allConfig["cover_variant_widths"],
+			"description": "为封面图生成的缩略图宽度（逗号分隔，如 160,640,1080），留空不生成；不小于原图宽度的尺寸跳过",
+			"unit":        "px",
+		},
 		"image_cache_expiration": map[string]interface{}{
 			"value":       allConfig["image_cache_expiration"],
 			"description": "图片缓存过期时间",
@@ -248,11 +638,57 @@ func (h *AdminHandler) GetConfig(c *gin.Context) {
 			"max":         2592000,
 			"unit":        "秒",
 		},
+		"source_stale_threshold": map[string]interface{}{
+			"value":       allConfig["source_stale_threshold"],
+			"description": "源超过该时长没有新文章时标记为停更",
+			"min":         86400,
+			"max":         7776000,
+			"unit":        "秒",
+		},
+		"max_content_bytes": map[string]interface{}{
+			"value":       allConfig["max_content_bytes"],
+			"description": "单篇文章正文入库的最大大小，超出部分截断",
+			"min":         65536,
+			"max":         10485760,
+			"unit":        "字节",
+		},
+		"db_maintenance_interval": map[string]interface{}{
+			"value":       allConfig["db_maintenance_interval"],
+			"description": "数据库维护（WAL checkpoint、按需 VACUUM）间隔",
+			"min":         3600,
+			"max":         2592000,
+			"unit":        "秒",
+		},
+		"max_subscriptions": map[string]interface{}{
+			"value":       allConfig["max_subscriptions"],
+			"description": "每个用户默认的订阅数上限，0 表示不限制",
+			"min":         0,
+			"max":         100000,
+			"unit":        "个",
+		},
+		"max_vocabulary": map[string]interface{}{
+			"value":       allConfig["max_vocabulary"],
+			"description": "每个用户默认的生词数上限（不含已删除），0 表示不限制",
+			"min":         0,
+			"max":         1000000,
+			"unit":        "个",
+		},
+		"subscribe_backfill": map[string]interface{}{
+			"value":       allConfig["subscribe_backfill"],
+			"description": "订阅其他用户已订阅的源时，补发该源最近的文章数（只含保留期内的文章），0 表示不补发",
+			"min":         0,
+			"max":         200,
+			"unit":        "篇",
+		},
 		"log_level": map[string]interface{}{
 			"value":       allConfig["log_level"],
 			"description": "日志级别（debug/info/warn/error）",
 			"options":     []string{"debug", "info", "warn", "error"},
 		},
+		"feed_timezone": map[string]interface{}{
+			"value":       allConfig["feed_timezone"],
+			"description": "解析不带时区的文章发布时间时使用的时区（IANA 名称，如 Asia/Shanghai）",
+		},
 		"max_items_per_fetch": map[string]interface{}{
 			"value":       allConfig["max_items_per_fetch"],
 			"description": "每次抓取最多保留文章数",
@@ -272,31 +708,28 @@ func (h *AdminHandler) GetConfig(c *gin.Context) {
 func (h *AdminHandler) UpdateConfig(c *gin.Context) {
 	var updates map[string]interface{}
 	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "请求体格式错误",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
 		return
 	}
 
 	rc := config.GetRuntimeConfig()
-	errors := rc.UpdateConfig(updates)
+	results := rc.UpdateConfig(updates)
 
-	if len(errors) > 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "配置更新失败",
-			"errors":  errors,
-		})
-		return
+	// 有配置项未生效时返回 400，details 中为全部配置项的结果（其余配置项已生效）
+	for _, result := range results {
+		if result.Status == config.FieldRejected {
+			respondErrorDetails(c, http.StatusBadRequest, CodeValidationFailed, "配置更新失败", results)
+			return
+		}
 	}
 
-	// 返回更新后的配置
+	// 返回更新后的配置和各配置项的结果，clamped 表示超出范围已调整
 	allConfig := rc.GetAllConfig()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "配置季更新成功",
+		"message": "配置更新成功",
 		"data":    allConfig,
+		"fields":  results,
 	})
 }
 
@@ -304,29 +737,20 @@ func (h *AdminHandler) UpdateConfig(c *gin.Context) {
 func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	userIDStr := c.Query("user_id")
 	if userIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "user_id 参数缺失",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "user_id 参数缺失")
 		return
 	}
 
 	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "user_id 参数无效",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "user_id 参数无效")
 		return
 	}
 
 	// 验证用户是否存在
 	user, err := h.db.GetUserByID(userID)
 	if err != nil || user == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"message": "用户不存在",
-		})
+		respondError(c, http.StatusNotFound, CodeNotFound, "用户不存在")
 		return
 	}
 
@@ -350,9 +774,14 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 			if _, err := h.clearSourceItemsInternal(sub.SourceID); err != nil {
 				log.Printf("[ADMIN] clearSourceItemsInternal failed for source %d: %v", sub.SourceID, err)
 			}
+			if source, err := h.db.GetSourceByID(sub.SourceID); err == nil {
+				image.DeleteFavicon(h.staticDir, source.Favicon)
+			}
 
 			if err := h.db.DeleteSource(sub.SourceID); err != nil {
 				log.Printf("[ADMIN] DeleteSource failed for source %d: %v", sub.SourceID, err)
+			} else {
+				metrics.GetMetrics().ForgetSource(sub.SourceID)
 			}
 		}
 	}
@@ -367,10 +796,7 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	// 删除用户
 	err = h.db.DeleteUser(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "删除用户失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "删除用户失败")
 		return
 	}
 
@@ -384,38 +810,26 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 func (h *AdminHandler) ClearSourceItems(c *gin.Context) {
 	sourceIDStr := c.Query("source_id")
 	if sourceIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "source_id 参数缺失",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数缺失")
 		return
 	}
 
 	sourceID, err := strconv.ParseInt(sourceIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "source_id 参数无效",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数无效")
 		return
 	}
 
 	// 验证源是否存在
 	source, err := h.db.GetSourceByID(sourceID)
 	if err != nil || source == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"message": "订阅源不存在",
-		})
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
 		return
 	}
 
 	cleared, err := h.clearSourceItemsInternal(sourceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "清空文章失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "清空文章失败")
 		return
 	}
 
@@ -426,33 +840,25 @@ func (h *AdminHandler) ClearSourceItems(c *gin.Context) {
 	})
 }
 
-// clearSourceItemsInternal 内部方法：清空指定源下的文章、投递记录和图片缓存
+// clearSourceItemsInternal 内部方法：清空指定源下的文章、投递记录、阅读状态和图片缓存
+// 数据库记录在一个事务中删除，提交后再删除图片文件
 func (h *AdminHandler) clearSourceItemsInternal(sourceID int64) (int, error) {
 	items, err := h.db.GetItemsBySource(sourceID)
 	if err != nil {
 		return 0, err
 	}
 
-	cleared := 0
+	cleared, err := h.db.PurgeSourceItems(sourceID)
+	if err != nil {
+		return 0, err
+	}
+
 	for _, item := range items {
-		// 删除图片文件
 		if item.ImagePaths != "" && item.ImagePaths != "[]" {
 			if err := image.DeleteImageFiles(h.staticDir, item.ImagePaths); err != nil {
 				log.Printf("[ADMIN] DeleteImageFiles failed for item %d: %v", item.ID, err)
 			}
 		}
-
-		// 删除投递记录
-		if err := h.db.DeleteUserDeliveries(item.ID); err != nil {
-			log.Printf("[ADMIN] DeleteUserDeliveries failed for item %d: %v", item.ID, err)
-		}
-
-		// 删除文章记录
-		if err := h.db.DeleteItem(item.ID); err != nil {
-			log.Printf("[ADMIN] DeleteItem failed for item %d: %v", item.ID, err)
-		} else {
-			cleared++
-		}
 	}
 
 	// 删除可能为空的图片目录
@@ -461,36 +867,94 @@ func (h *AdminHandler) clearSourceItemsInternal(sourceID int64) (int, error) {
 		log.Printf("[ADMIN] RemoveEmptyDir failed for %s: %v", imageDir, err)
 	}
 
-	return cleared, nil
+	return int(cleared), nil
+}
+
+// maxMergeSources 单次合并的源数上限
+const maxMergeSources = 50
+
+// MergeSourcesRequest 合并重复订阅源请求
+type MergeSourcesRequest struct {
+	KeepID   int64   `json:"keep_id" binding:"required"`
+	MergeIDs []int64 `json:"merge_ids" binding:"required"`
+}
+
+// MergeSources 把重复的订阅源合并到 keep_id POST /api/admin/sources/merge
+// 用于清理 URL 规范化之前留下的同一订阅源的多条记录：文章、订阅和投递记录移到保留源，
+// guid 重复的文章保留已有文章，随后删除被合并的源；数据库修改在一个事务中完成
+func (h *AdminHandler) MergeSources(c *gin.Context) {
+	var req MergeSourcesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	mergeIDs := make([]int64, 0, len(req.MergeIDs))
+	seen := make(map[int64]bool, len(req.MergeIDs))
+	for _, id := range req.MergeIDs {
+		if id == req.KeepID {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "merge_ids 不能包含 keep_id")
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			mergeIDs = append(mergeIDs, id)
+		}
+	}
+	if len(mergeIDs) == 0 || len(mergeIDs) > maxMergeSources {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("merge_ids 数量必须为 1-%d", maxMergeSources))
+		return
+	}
+
+	result, err := h.db.MergeSources(req.KeepID, mergeIDs)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+	if err != nil {
+		log.Printf("[ADMIN] Merge sources %v into %d failed: %v", mergeIDs, req.KeepID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "合并订阅源失败")
+		return
+	}
+
+	// 事务提交后再删除重复文章的图片文件
+	for _, paths := range result.DroppedImagePaths {
+		if err := image.DeleteImageFiles(h.staticDir, paths); err != nil {
+			log.Printf("[ADMIN] DeleteImageFiles failed after merge: %v", err)
+		}
+	}
+	for _, mergeID := range mergeIDs {
+		metrics.GetMetrics().ForgetSource(mergeID)
+	}
+	h.sourceCache.invalidate()
+
+	log.Printf("[ADMIN] Merged sources %v into %d: %d items moved, %d duplicates dropped, %d subscriptions moved",
+		mergeIDs, req.KeepID, result.MovedItems, result.DroppedItems, result.MovedSubscriptions)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已将 %d 个订阅源合并到源 %d", result.MergedSources, req.KeepID),
+		"data":    result,
+	})
 }
 
 // RefreshSource 手动刷新指定的 RSS 源
 func (h *AdminHandler) RefreshSource(c *gin.Context) {
 	sourceIDStr := c.Query("source_id")
 	if sourceIDStr == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "source_id 参数缺失",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数缺失")
 		return
 	}
 
 	sourceID, err := strconv.ParseInt(sourceIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "source_id 参数无效",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数无效")
 		return
 	}
 
 	// 验证源是否存在
 	source, err := h.db.GetSourceByID(sourceID)
 	if err != nil || source == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"message": "订阅源不存在",
-		})
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
 		return
 	}
 
@@ -500,10 +964,7 @@ func (h *AdminHandler) RefreshSource(c *gin.Context) {
 		if err := h.worker.FetchSource(source); err != nil {
 			log.Printf("[ADMIN] Failed to refresh source %s: %v", source.URL, err)
 			h.db.UpdateSourceError(source.ID, err.Error())
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": fmt.Sprintf("刷新源失败: %v", err),
-			})
+			respondError(c, http.StatusInternalServerError, CodeInternal, fmt.Sprintf("刷新源失败: %v", err))
 			return
 		}
 		// 更新最后抓取时间
@@ -513,10 +974,7 @@ func (h *AdminHandler) RefreshSource(c *gin.Context) {
 		// 没有 Worker 时降级为更新时间戳
 		log.Printf("[ADMIN] Worker not available, only updating fetch time for source: %s", source.Title)
 		if err := h.db.UpdateSourceFetchTime(sourceID); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"message": "更新抓取时间失败",
-			})
+			respondError(c, http.StatusInternalServerError, CodeInternal, "更新抓取时间失败")
 			return
 		}
 	}
@@ -527,51 +985,1034 @@ func (h *AdminHandler) RefreshSource(c *gin.Context) {
 	})
 }
 
-// 辅助方法
+// RefreshSourceFavicon 立即重新解析指定源的图标
+func (h *AdminHandler) RefreshSourceFavicon(c *gin.Context) {
+	sourceID, err := strconv.ParseInt(c.Query("source_id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数无效")
+		return
+	}
 
-// getSystemStats 获取系统统计信息
-func (h *AdminHandler) getSystemStats() gin.H {
-	totalUsers, _ := h.db.GetTotalUsers()
-	activeSources, _ := h.db.GetActiveSourcesCount()
-	totalItems, _ := h.db.GetTotalItems()
-	totalDeliveries, _ := h.db.GetTotalDeliveries()
+	source, err := h.db.GetSourceByID(sourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
 
-	return gin.H{
-		"total_users":        totalUsers,
-		"active_sources":     activeSources,
-		"total_items":        totalItems,
-		"total_deliveries":   totalDeliveries,
-		"cache_images_count": h.countCachedImages(),
-		"cache_size_mb":      h.getCacheSizeMB(),
+	if h.worker == nil {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "Worker 不可用")
+		return
 	}
-}
 
-// getUserStats 获取用户统计信息
-func (h *AdminHandler) getUserStats() []gin.H {
-	users, _ := h.db.GetAllUsers()
-	var result []gin.H
+	favicon, err := h.worker.RefreshFavicon(source)
+	if err != nil {
+		log.Printf("[ADMIN] Failed to refresh favicon for source %s: %v", source.URL, err)
+		respondError(c, http.StatusBadGateway, CodeUpstreamError, fmt.Sprintf("解析图标失败: %v", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("源 %s 的图标已更新", source.Title),
+		"data": gin.H{
+			"source_id": source.ID,
+			"favicon":   favicon,
+		},
+	})
+}
 
-	for _, user := range users {
-		subCount, _ := h.db.GetSubscriptionCountByUser(user.ID)
-		deliveryCount, _ := h.db.GetDeliveryCountByUser(user.ID)
-		vocabCount, _ := h.db.GetVocabularyCountByUser(user.ID)
+// FailedSources 列出已停用或抓取出错的订阅源 GET /api/admin/sources/failed
+func (h *AdminHandler) FailedSources(c *gin.Context) {
+	sources, err := h.db.GetFailedSources()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅源失败")
+		return
+	}
 
+	result := make([]gin.H, 0, len(sources))
+	tf := newTimeFormat(c)
+	for _, source := range sources {
 		result = append(result, gin.H{
-			"id":                 user.ID,
-			"username":           user.Username,
-			"created_at":         user.CreatedAt,
-			"last_login_at":      user.LastLoginAt,
-			"subscription_count": subCount,
-			"delivery_count":     deliveryCount,
-			"vocabulary_count":   vocabCount,
+			"id":              source.ID,
+			"title":           source.Title,
+			"url":             source.URL,
+			"is_active":       source.IsActive,
+			"error_count":     source.ErrorCount,
+			"last_error":      source.LastError,
+			"last_fetch_time": tf.atPtr(source.LastFetchTime),
 		})
 	}
 
-	return result
+	c.JSON(http.StatusOK, completePage(c, result, len(result), gin.H{"data": result}))
 }
 
-// getSourceStats 获取源统计信息
-func (h *AdminHandler) getSourceStats() []gin.H {
+// SourceLatencyEntry 源抓取耗时列表的一项
+type SourceLatencyEntry struct {
+	metrics.SourceLatency
+	Title    string `json:"title"`
+	URL      string `json:"url"`
+	IsActive bool   `json:"is_active"`
+	// 覆盖 SourceLatency.UpdatedAt，按 timeFormat 输出
+	UpdatedAt interface{} `json:"updated_at"`
+}
+
+// sourceLatencyJSON 单个源的抓取耗时，updated_at 按 timeFormat 输出
+type sourceLatencyJSON struct {
+	*metrics.SourceLatency
+	UpdatedAt interface{} `json:"updated_at"`
+}
+
+// sourceLatencySortKeys 抓取耗时列表支持的排序字段
+var sourceLatencySortKeys = map[string]func(l *metrics.SourceLatency) int64{
+	"avg":      func(l *metrics.SourceLatency) int64 { return l.AvgMs },
+	"last":     func(l *metrics.SourceLatency) int64 { return l.LastMs },
+	"max":      func(l *metrics.SourceLatency) int64 { return l.MaxMs },
+	"timeouts": func(l *metrics.SourceLatency) int64 { return int64(l.Timeouts) },
+}
+
+// SourceLatencies 按源列出最近的抓取耗时 GET /api/admin/sources/latency?sort=avg|last|max|timeouts&order=desc|asc
+// 耗时只保存在内存中，进程启动后未抓取过的源不在列表中；平均耗时持续接近单源超时时间的源 near_timeout 为 true
+func (h *AdminHandler) SourceLatencies(c *gin.Context) {
+	sortKey := c.DefaultQuery("sort", "avg")
+	value, ok := sourceLatencySortKeys[sortKey]
+	if !ok {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "sort 参数无效，可选 avg、last、max、timeouts")
+		return
+	}
+	order := c.DefaultQuery("order", "desc")
+	if order != "desc" && order != "asc" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "order 参数无效，可选 desc、asc")
+		return
+	}
+
+	sources, err := h.db.GetAllSources()
+	if err != nil {
+		log.Printf("[ADMIN] Failed to load sources for latency stats: %v", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅源失败")
+		return
+	}
+	byID := make(map[int64]*db.Source, len(sources))
+	for _, source := range sources {
+		byID[source.ID] = source
+	}
+
+	tf := newTimeFormat(c)
+	entries := make([]SourceLatencyEntry, 0, len(sources))
+	for _, latency := range metrics.GetMetrics().GetSourceLatencies() {
+		// 已删除的源跳过
+		source, ok := byID[latency.SourceID]
+		if !ok {
+			continue
+		}
+		entries = append(entries, SourceLatencyEntry{
+			SourceLatency: latency,
+			Title:         source.Title,
+			URL:           source.URL,
+			IsActive:      source.IsActive,
+			UpdatedAt:     tf.at(latency.UpdatedAt),
+		})
+	}
+	// 稳定排序：同值时保持按平均耗时降序的顺序
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := value(&entries[i].SourceLatency), value(&entries[j].SourceLatency)
+		if order == "asc" {
+			return a < b
+		}
+		return a > b
+	})
+
+	c.JSON(http.StatusOK, completePage(c, entries, len(entries), gin.H{"data": entries}))
+}
+
+// RetryFailedSources 重新启用所有失败的源并立即抓取 POST /api/admin/sources/retry-failed
+// 最多等待 20 秒后返回每个源的结果，未完成的源为 running，在后台继续抓取
+func (h *AdminHandler) RetryFailedSources(c *gin.Context) {
+	if h.worker == nil {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "Worker 不可用")
+		return
+	}
+
+	sources, err := h.db.GetFailedSources()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅源失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Retrying %d failed sources", len(sources))
+	outcomes, err := h.worker.RetrySources(sources)
+	if errors.Is(err, worker.ErrRefreshInProgress) {
+		respondError(c, http.StatusConflict, CodeConflict, "批量重试正在进行中，请稍后再试")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
+		return
+	}
+
+	counts := map[string]int{}
+	for _, o := range outcomes {
+		counts[o.Status]++
+	}
+	log.Printf("[ADMIN] Retry finished: %d succeeded, %d failed, %d running, %d skipped",
+		counts[worker.RetrySucceeded], counts[worker.RetryFailed], counts[worker.RetryRunning], counts[worker.RetrySkipped])
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已重试 %d 个源，%d 个成功，%d 个失败，%d 个仍在抓取", len(outcomes), counts[worker.RetrySucceeded], counts[worker.RetryFailed], counts[worker.RetryRunning]),
+		"data": gin.H{
+			"total":     len(outcomes),
+			"succeeded": counts[worker.RetrySucceeded],
+			"failed":    counts[worker.RetryFailed],
+			"running":   counts[worker.RetryRunning],
+			"skipped":   counts[worker.RetrySkipped],
+			"sources":   outcomes,
+		},
+	})
+}
+
+// ReprocessItems 用已保存的内容重建一批文章的派生字段 POST /api/admin/items/reprocess?source_id=&limit=&cursor=
+// 重新计算摘要、字数、阅读时间、难度、封面和标签，不重新抓取；hasMore 为 true 时带上 nextCursor 继续调用下一批
+func (h *AdminHandler) ReprocessItems(c *gin.Context) {
+	if h.worker == nil {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "Worker 不可用")
+		return
+	}
+
+	var sourceID int64
+	if value := c.Query("source_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || id <= 0 {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "source_id 参数无效")
+			return
+		}
+		if source, err := h.db.GetSourceByID(id); err != nil || source == nil {
+			respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+			return
+		}
+		sourceID = id
+	}
+
+	limit := worker.DefaultReprocessBatch
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > worker.MaxReprocessBatch {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("limit 参数无效，取值范围 1-%d", worker.MaxReprocessBatch))
+			return
+		}
+		limit = n
+	}
+
+	var cursor int64
+	if value := c.Query("cursor"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "cursor 参数无效")
+			return
+		}
+		cursor = n
+	}
+
+	result, err := h.worker.ReprocessItems(sourceID, cursor, limit)
+	if err != nil {
+		log.Printf("[ADMIN] Reprocess items failed: %v", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "重建派生字段失败")
+		return
+	}
+
+	resp := gin.H{
+		"success":   true,
+		"message":   fmt.Sprintf("已处理 %d 篇文章，%d 篇有更新，剩余 %d 篇", result.Processed, result.Updated, result.Remaining),
+		"processed": result.Processed,
+		"updated":   result.Updated,
+		"remaining": result.Remaining,
+		"hasMore":   result.Remaining > 0,
+	}
+	if result.Remaining > 0 {
+		resp["nextCursor"] = strconv.FormatInt(result.NextCursor, 10)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SourceCredentialRequest 设置订阅源凭据请求
+// auth_type 为 none 或空时删除已有凭据
+type SourceCredentialRequest struct {
+	SourceID  int64  `json:"source_id" binding:"required"`
+	AuthType  string `json:"auth_type"`  // basic | header | token | session | none
+	Username  string `json:"username"`   // basic 模式用户名
+	Secret    string `json:"secret"`     // 密码 / 请求头值 / 令牌 / 登录脚本（session 模式，JSON，见 worker.FetchScript）
+	ParamName string `json:"param_name"` // header 模式请求头名；token 模式查询参数名（源 URL 含 {token} 占位符时可省略）
+}
+
+// SetSourceCredentials 设置私有订阅源的认证凭据（secret 加密存储，不会回显）
+func (h *AdminHandler) SetSourceCredentials(c *gin.Context) {
+	var req SourceCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	authType := strings.ToLower(strings.TrimSpace(req.AuthType))
+	if authType == "" || authType == "none" {
+		if err := h.db.DeleteSourceCredential(source.ID); err != nil {
+			respondError(c, http.StatusInternalServerError, CodeInternal, "删除凭据失败")
+			return
+		}
+		log.Printf("[ADMIN] Credentials removed for source %d", source.ID)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "凭据已删除",
+		})
+		return
+	}
+
+	// 参数校验
+	var validationErr string
+	switch authType {
+	case "basic":
+		if req.Username == "" || req.Secret == "" {
+			validationErr = "basic 模式需要 username 和 secret"
+		}
+	case "header":
+		if req.ParamName == "" || req.Secret == "" {
+			validationErr = "header 模式需要 param_name 和 secret"
+		}
+	case "token":
+		if req.Secret == "" {
+			validationErr = "token 模式需要 secret"
+		}
+	case "session":
+		if _, err := worker.ParseFetchScript(req.Secret, source.URL); err != nil {
+			validationErr = "session 模式的 secret 需要是有效的登录脚本：" + err.Error()
+		}
+	default:
+		validationErr = "auth_type 仅支持 basic/header/token/session/none"
+	}
+	if validationErr != "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, validationErr)
+		return
+	}
+
+	encrypted, err := utils.EncryptString(h.credentialKey, req.Secret)
+	if err != nil {
+		log.Printf("[ADMIN] Failed to encrypt credentials for source %d", source.ID)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "凭据加密失败")
+		return
+	}
+
+	cred := &db.SourceCredential{
+		SourceID:        source.ID,
+		AuthType:        authType,
+		Username:        req.Username,
+		SecretEncrypted: encrypted,
+		ParamName:       req.ParamName,
+	}
+	if err := h.db.UpsertSourceCredential(cred); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存凭据失败")
+		return
+	}
+
+	// 凭据变更后重新激活因认证失败被停用的源
+	if !source.IsActive {
+		_ = h.db.UpdateSourceActive(source.ID, true)
+	}
+
+	log.Printf("[ADMIN] Credentials updated for source %d (type=%s)", source.ID, authType)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "凭据已保存",
+		"data": gin.H{
+			"source_id": source.ID,
+			"auth_type": authType,
+		},
+	})
+}
+
+// SourceImageModeRequest 设置订阅源图片处理模式请求
+type SourceImageModeRequest struct {
+	SourceID  int64  `json:"source_id" binding:"required"`
+	ImageMode string `json:"image_mode" binding:"required"` // process | proxy | off
+}
+
+// SetSourceImageMode 设置订阅源的图片处理模式
+// process：下载压缩后本地缓存，占用存储但离线可用；
+// proxy：经 /api/image 实时转发，不占存储但依赖源站可用性；
+// off：保留原图地址，由客户端直接加载。
+// 只影响之后抓取的文章，已入库的文章保持不变
+func (h *AdminHandler) SetSourceImageMode(c *gin.Context) {
+	var req SourceImageModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(req.ImageMode))
+	if !db.IsValidImageMode(mode) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "image_mode 仅支持 process/proxy/off")
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceImageMode(source.ID, mode); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Image mode for source %d changed: %s -> %s", source.ID, source.ImageMode, mode)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "图片模式已更新，新抓取的文章生效",
+		"data": gin.H{
+			"source_id":  source.ID,
+			"image_mode": mode,
+		},
+	})
+}
+
+// SourceContentUpdateRequest 设置订阅源内容更新模式请求
+type SourceContentUpdateRequest struct {
+	SourceID int64  `json:"source_id" binding:"required"`
+	Mode     string `json:"content_update_mode" binding:"required"` // off | update | unread
+}
+
+// SetSourceContentUpdateMode 设置订阅源的文章内容更新模式
+// 开启后，feed 以相同 GUID 发布修改后的内容时会更新已入库的文章；
+// 部分 feed 每次输出都会变动时间戳等内容，因此默认关闭，按源单独开启
+func (h *AdminHandler) SetSourceContentUpdateMode(c *gin.Context) {
+	var req SourceContentUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(req.Mode))
+	if !db.IsValidContentUpdateMode(mode) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "content_update_mode 仅支持 off/update/unread")
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceContentUpdateMode(source.ID, mode); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Content update mode for source %d changed: %s -> %s", source.ID, source.ContentUpdateMode, mode)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "内容更新模式已更新",
+		"data": gin.H{
+			"source_id":           source.ID,
+			"content_update_mode": mode,
+		},
+	})
+}
+
+// 源级文章保留时间范围（秒）
+const (
+	minSourceRetention = 3600     // 1 小时
+	maxSourceRetention = 31536000 // 365 天
+)
+
+// SourceRetentionRequest 设置源级文章保留时间请求
+type SourceRetentionRequest struct {
+	SourceID         int64 `json:"source_id" binding:"required"`
+	RetentionSeconds int   `json:"retention_seconds"` // 0 表示使用全局设置
+}
+
+// SetSourceRetention 设置订阅源的文章保留时间，覆盖全局 item_retention_time
+// 低频的归档类源可以保留更久，高频新闻源可以更早清理
+func (h *AdminHandler) SetSourceRetention(c *gin.Context) {
+	var req SourceRetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	if req.RetentionSeconds != 0 && (req.RetentionSeconds < minSourceRetention || req.RetentionSeconds > maxSourceRetention) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("retention_seconds 必须为 0 或 %d-%d", minSourceRetention, maxSourceRetention))
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceRetention(source.ID, req.RetentionSeconds); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Retention for source %d changed: %d -> %d seconds", source.ID, source.RetentionSeconds, req.RetentionSeconds)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "保留时间已更新",
+		"data": gin.H{
+			"source_id":         source.ID,
+			"retention_seconds": req.RetentionSeconds,
+		},
+	})
+}
+
+// SourceSummaryLengthRequest 设置源级摘要长度请求
+type SourceSummaryLengthRequest struct {
+	SourceID      int64 `json:"source_id" binding:"required"`
+	SummaryLength int   `json:"summary_length"` // 0 表示使用默认长度
+}
+
+// SetSourceSummaryLength 设置订阅源的文章摘要长度（字符数）
+// 只影响之后抓取或更新的文章，已入库的摘要保持不变
+func (h *AdminHandler) SetSourceSummaryLength(c *gin.Context) {
+	var req SourceSummaryLengthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	if req.SummaryLength != 0 && (req.SummaryLength < utils.MinSummaryLength || req.SummaryLength > utils.MaxSummaryLength) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("summary_length 必须为 0 或 %d-%d", utils.MinSummaryLength, utils.MaxSummaryLength))
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceSummaryLength(source.ID, req.SummaryLength); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Summary length for source %d changed: %d -> %d", source.ID, source.SummaryLength, req.SummaryLength)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "摘要长度已更新，新抓取的文章生效",
+		"data": gin.H{
+			"source_id":      source.ID,
+			"summary_length": req.SummaryLength,
+		},
+	})
+}
+
+// maxSourceMinWordCount 源级最小字数上限
+const maxSourceMinWordCount = 5000
+
+// SourceMinWordCountRequest 设置源级最小字数请求
+type SourceMinWordCountRequest struct {
+	SourceID     int64 `json:"source_id" binding:"required"`
+	MinWordCount int   `json:"min_word_count"` // 0 表示不限制
+}
+
+// SetSourceMinWordCount 设置订阅源的最小字数，字数不足的文章（标题占位、链接汇总等）只入库不投递
+// 只影响之后抓取的文章
+func (h *AdminHandler) SetSourceMinWordCount(c *gin.Context) {
+	var req SourceMinWordCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	if req.MinWordCount < 0 || req.MinWordCount > maxSourceMinWordCount {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("min_word_count 必须为 0-%d", maxSourceMinWordCount))
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceMinWordCount(source.ID, req.MinWordCount); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Min word count for source %d changed: %d -> %d", source.ID, source.MinWordCount, req.MinWordCount)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "最小字数已更新，新抓取的文章生效",
+		"data": gin.H{
+			"source_id":      source.ID,
+			"min_word_count": req.MinWordCount,
+		},
+	})
+}
+
+// maxSourceMaxItems 源级文章数上限的最大值
+const maxSourceMaxItems = 100000
+
+// SourceMaxItemsRequest 设置源最多保留的文章数请求
+type SourceMaxItemsRequest struct {
+	SourceID int64 `json:"source_id" binding:"required"`
+	MaxItems int   `json:"max_items"` // 0 表示不限制
+}
+
+// SetSourceMaxItems 设置订阅源最多保留的文章数，与保留时间同时生效（超出任一限制即删除）
+// 设置后立即删除超出的旧文章，之后每次抓取到新文章时再次检查；未确认、收藏或阅读中的文章保留
+func (h *AdminHandler) SetSourceMaxItems(c *gin.Context) {
+	var req SourceMaxItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	if req.MaxItems < 0 || req.MaxItems > maxSourceMaxItems {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("max_items 必须为 0-%d", maxSourceMaxItems))
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceMaxItems(source.ID, req.MaxItems); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+	log.Printf("[ADMIN] Max items for source %d changed: %d -> %d", source.ID, source.MaxItems, req.MaxItems)
+
+	trimmed := 0
+	if req.MaxItems > 0 {
+		source.MaxItems = req.MaxItems
+		if trimmed, err = h.worker.TrimSource(source); err != nil {
+			log.Printf("[ADMIN] Trim source %d failed: %v", source.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("文章数上限已更新，删除了 %d 篇旧文章", trimmed),
+		"data": gin.H{
+			"source_id": source.ID,
+			"max_items": req.MaxItems,
+			"trimmed":   trimmed,
+		},
+	})
+}
+
+// UserQuotaRequest 设置用户配额请求，字段为 null 时恢复使用全局配置，0 表示不限制
+type UserQuotaRequest struct {
+	UserID           int64 `json:"user_id" binding:"required"`
+	MaxSubscriptions *int  `json:"max_subscriptions"`
+	MaxVocabulary    *int  `json:"max_vocabulary"`
+}
+
+// SetUserQuota 为单个用户设置订阅数和生词数上限，覆盖全局配置
+// 下调到低于当前用量时不删除已有数据，只阻止继续新增
+func (h *AdminHandler) SetUserQuota(c *gin.Context) {
+	var req UserQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+	if (req.MaxSubscriptions != nil && *req.MaxSubscriptions < 0) ||
+		(req.MaxVocabulary != nil && *req.MaxVocabulary < 0) {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "配额不能为负数")
+		return
+	}
+
+	override := &db.UserQuotaOverride{
+		MaxSubscriptions: req.MaxSubscriptions,
+		MaxVocabulary:    req.MaxVocabulary,
+	}
+	if err := h.db.SetUserQuotaOverride(req.UserID, override); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, CodeNotFound, "用户不存在")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	quota, err := loadUserQuota(h.db, req.UserID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询配额失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Quota for user %d changed: subscriptions=%v vocabulary=%v",
+		req.UserID, formatQuotaOverride(req.MaxSubscriptions), formatQuotaOverride(req.MaxVocabulary))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "用户配额已更新",
+		"data": gin.H{
+			"user_id":  req.UserID,
+			"override": override,
+			"quota":    quota,
+		},
+	})
+}
+
+// formatQuotaOverride 日志中显示配额覆盖值
+func formatQuotaOverride(v *int) string {
+	if v == nil {
+		return "default"
+	}
+	return strconv.Itoa(*v)
+}
+
+// SourceGalleryRequest 开关源级图集提取请求
+type SourceGalleryRequest struct {
+	SourceID int64 `json:"source_id" binding:"required"`
+	Enabled  bool  `json:"enabled"`
+}
+
+// SetSourceGallery 开启或关闭订阅源的图集提取（正文前几张图片）
+// 只影响之后抓取或更新的文章
+func (h *AdminHandler) SetSourceGallery(c *gin.Context) {
+	var req SourceGalleryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceGallery(source.ID, req.Enabled); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Gallery for source %d changed: %v -> %v", source.ID, source.GalleryEnabled, req.Enabled)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "图集设置已更新，新抓取的文章生效",
+		"data": gin.H{
+			"source_id": source.ID,
+			"enabled":   req.Enabled,
+		},
+	})
+}
+
+// SourceFullContentRequest 开关源级全文提取请求
+type SourceFullContentRequest struct {
+	SourceID int64 `json:"source_id" binding:"required"`
+	Enabled  bool  `json:"enabled"`
+}
+
+// SetSourceFullContent 开启或关闭订阅源的全文提取
+// 开启后 feed 正文过短的文章会从原文链接提取全文，只影响之后抓取或更新的文章
+func (h *AdminHandler) SetSourceFullContent(c *gin.Context) {
+	var req SourceFullContentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceFullContent(source.ID, req.Enabled); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Full content for source %d changed: %v -> %v", source.ID, source.FullContent, req.Enabled)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "全文提取设置已更新，新抓取的文章生效",
+		"data": gin.H{
+			"source_id": source.ID,
+			"enabled":   req.Enabled,
+		},
+	})
+}
+
+// SourceLenientParseRequest 开关源级宽松解析请求
+type SourceLenientParseRequest struct {
+	SourceID int64 `json:"source_id" binding:"required"`
+	Enabled  bool  `json:"enabled"`
+}
+
+// SetSourceLenientParse 开启或关闭订阅源的宽松解析
+// 开启后严格解析失败时会清理 XML 中的控制字符、无效字节等再重试，用于个别格式不规范的源
+func (h *AdminHandler) SetSourceLenientParse(c *gin.Context) {
+	var req SourceLenientParseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceLenientParse(source.ID, req.Enabled); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Lenient parse for source %d changed: %v -> %v", source.ID, source.LenientParse, req.Enabled)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "宽松解析设置已更新，下次抓取生效",
+		"data": gin.H{
+			"source_id": source.ID,
+			"enabled":   req.Enabled,
+		},
+	})
+}
+
+// SourceBodyImagesRequest 开关源级"只缓存正文图片"请求
+type SourceBodyImagesRequest struct {
+	SourceID int64 `json:"source_id" binding:"required"`
+	Enabled  bool  `json:"enabled"`
+}
+
+// SetSourceBodyImagesOnly 开启或关闭订阅源的"只缓存正文图片"
+// 开启后压缩缓存模式只下载 Readability 识别出的正文主体中的图片，页眉、页脚、侧栏中的广告和挂件图片保留原始地址，
+// 封面图也只从正文主体中选取；只影响之后抓取的文章
+func (h *AdminHandler) SetSourceBodyImagesOnly(c *gin.Context) {
+	var req SourceBodyImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceBodyImagesOnly(source.ID, req.Enabled); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Body images only for source %d changed: %v -> %v", source.ID, source.BodyImagesOnly, req.Enabled)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "正文图片设置已更新，下次抓取生效",
+		"data": gin.H{
+			"source_id": source.ID,
+			"enabled":   req.Enabled,
+		},
+	})
+}
+
+// maxClassifySources 单次修正分类的源数上限
+const maxClassifySources = 100
+
+// SourceClassifyRequest 修正订阅源分类和语言请求，字段省略表示不修改，空字符串表示清除
+type SourceClassifyRequest struct {
+	SourceIDs []int64 `json:"source_ids" binding:"required"`
+	Category  *string `json:"category"`
+	Language  *string `json:"language"` // 如 en、zh-CN，保存为主语言代码
+}
+
+// ClassifySources 批量修正订阅源的分类和语言 PUT /api/admin/sources/classify
+// 修改分类时，继承自旧分类的已入库文章一并改为新分类；手动设置的语言不会被之后抓取到的 feed 语言覆盖
+func (h *AdminHandler) ClassifySources(c *gin.Context) {
+	var req SourceClassifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+	if req.Category == nil && req.Language == nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "category 和 language 至少需要提供一个")
+		return
+	}
+	if len(req.SourceIDs) == 0 || len(req.SourceIDs) > maxClassifySources {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("source_ids 数量必须为 1-%d", maxClassifySources))
+		return
+	}
+
+	var category, language *string
+	if req.Category != nil {
+		normalized := utils.NewTextProcessor().NormalizeCategory(*req.Category)
+		if normalized == "" && strings.TrimSpace(*req.Category) != "" {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "分类名称无效或过长")
+			return
+		}
+		category = &normalized
+	}
+	if req.Language != nil {
+		normalized := utils.NormalizeLanguage(*req.Language)
+		if normalized == "" && strings.TrimSpace(*req.Language) != "" {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的语言代码")
+			return
+		}
+		language = &normalized
+	}
+
+	// 先确认所有源都存在，避免只修改了一部分
+	sourceIDs := make([]int64, 0, len(req.SourceIDs))
+	seen := make(map[int64]bool, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := h.db.GetSourceByID(id); err != nil {
+			respondError(c, http.StatusNotFound, CodeNotFound, fmt.Sprintf("订阅源 %d 不存在", id))
+			return
+		}
+		sourceIDs = append(sourceIDs, id)
+	}
+
+	var itemsUpdated int64
+	for _, id := range sourceIDs {
+		updated, err := h.db.UpdateSourceClassification(id, category, language)
+		if err != nil {
+			log.Printf("[ADMIN] Classify source %d failed: %v", id, err)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+			return
+		}
+		itemsUpdated += updated
+	}
+
+	log.Printf("[ADMIN] Classified sources %v, %d items updated", sourceIDs, itemsUpdated)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分类已更新",
+		"data": gin.H{
+			"source_ids":    sourceIDs,
+			"category":      category,
+			"language":      language,
+			"items_updated": itemsUpdated,
+		},
+	})
+}
+
+// SourceProxyRequest 设置源级出站代理请求
+type SourceProxyRequest struct {
+	SourceID int64  `json:"source_id" binding:"required"`
+	ProxyURL string `json:"proxy_url"` // http/https/socks5 地址；direct 表示直连；空表示使用全局代理
+}
+
+// SetSourceProxy 设置订阅源的出站代理，覆盖全局 OUTBOUND_PROXY
+// 只作用于抓取该源的 RSS，正文和图片请求仍使用全局代理
+func (h *AdminHandler) SetSourceProxy(c *gin.Context) {
+	var req SourceProxyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	proxyURL := strings.TrimSpace(req.ProxyURL)
+	if strings.EqualFold(proxyURL, utils.ProxyDirect) {
+		proxyURL = utils.ProxyDirect
+	} else if _, err := utils.ParseProxyURL(proxyURL); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("proxy_url 无效: %v", err))
+		return
+	}
+
+	source, err := h.db.GetSourceByID(req.SourceID)
+	if err != nil || source == nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "订阅源不存在")
+		return
+	}
+
+	if err := h.db.UpdateSourceProxy(source.ID, proxyURL); err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "保存失败")
+		return
+	}
+
+	log.Printf("[ADMIN] Proxy for source %d set to %q", source.ID, utils.RedactProxyURL(proxyURL))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "代理设置已更新，下次抓取生效",
+		"data": gin.H{
+			"source_id": source.ID,
+			"proxy_url": utils.RedactProxyURL(proxyURL),
+		},
+	})
+}
+
+// 辅助方法
+
+// getSystemStats 获取系统统计信息
+func (h *AdminHandler) getSystemStats() gin.H {
+	totalUsers, _ := h.db.GetTotalUsers()
+	activeSources, _ := h.db.GetActiveSourcesCount()
+	totalItems, _ := h.db.GetTotalItems()
+	totalDeliveries, _ := h.db.GetTotalDeliveries()
+
+	return gin.H{
+		"total_users":        totalUsers,
+		"active_sources":     activeSources,
+		"total_items":        totalItems,
+		"total_deliveries":   totalDeliveries,
+		"cache_images_count": h.countCachedImages(),
+		"cache_size_mb":      h.getCacheSizeMB(),
+	}
+}
+
+// getRecentUsers 获取最近登录的用户（仪表板摘要）
+func (h *AdminHandler) getRecentUsers(tf timeFormat) []userStatsJSON {
+	users, _, err := h.db.ListUsersWithStats("", 5, 0)
+	if err != nil {
+		log.Printf("[ADMIN] Failed to load recent users: %v", err)
+	}
+	return newUserStatsJSON(tf, users)
+}
+
+// userStatsJSON 用户列表项的响应格式，时间字段按 timeFormat 输出
+type userStatsJSON struct {
+	*db.UserStats
+	CreatedAt   interface{} `json:"created_at"`
+	LastLoginAt interface{} `json:"last_login_at"`
+}
+
+// newUserStatsJSON 转换为响应格式
+func newUserStatsJSON(tf timeFormat, users []*db.UserStats) []userStatsJSON {
+	result := make([]userStatsJSON, 0, len(users))
+	for _, u := range users {
+		result = append(result, userStatsJSON{UserStats: u, CreatedAt: tf.at(u.CreatedAt), LastLoginAt: tf.atPtr(u.LastLoginAt)})
+	}
+	return result
+}
+
+// sourceItemStatsJSON 源文章查询结果的响应格式，时间字段按 timeFormat 输出
+type sourceItemStatsJSON struct {
+	*db.SourceItemStats
+	PublishedAt interface{} `json:"published_at"`
+	CreatedAt   interface{} `json:"created_at"`
+}
+
+// newSourceItemStatsJSON 转换为响应格式
+func newSourceItemStatsJSON(tf timeFormat, items []*db.SourceItemStats) []sourceItemStatsJSON {
+	result := make([]sourceItemStatsJSON, 0, len(items))
+	for _, item := range items {
+		result = append(result, sourceItemStatsJSON{SourceItemStats: item, PublishedAt: tf.atPtr(item.PublishedAt), CreatedAt: tf.at(item.CreatedAt)})
+	}
+	return result
+}
+
+// getSourceStats 获取源统计信息
+func (h *AdminHandler) getSourceStats(tf timeFormat) []gin.H {
 	sources, _ := h.db.GetAllSources()
 	var result []gin.H
 
@@ -579,24 +2020,55 @@ func (h *AdminHandler) getSourceStats() []gin.H {
 		itemCount, _ := h.db.GetItemCountBySource(source.ID)
 		subCount, _ := h.db.GetSubscriberCountBySource(source.ID)
 		deliveryCount, _ := h.db.GetDeliveryCountBySource(source.ID)
+		staleness, isStale := sourceStaleness(source)
+		latency, _ := metrics.GetMetrics().GetSourceLatency(source.ID)
 
 		result = append(result, gin.H{
-			"id":               source.ID,
-			"title":            source.Title,
-			"url":              source.URL,
-			"is_active":        source.IsActive,
-			"item_count":       itemCount,
-			"subscriber_count": subCount,
-			"delivery_count":   deliveryCount,
-			"error_count":      source.ErrorCount,
-			"last_fetch_time":  source.LastFetchTime,
-			"last_error":       source.LastError,
+			"id":                  source.ID,
+			"title":               source.Title,
+			"url":                 source.URL,
+			"is_active":           source.IsActive,
+			"item_count":          itemCount,
+			"subscriber_count":    subCount,
+			"delivery_count":      deliveryCount,
+			"error_count":         source.ErrorCount,
+			"last_fetch_time":     tf.atPtr(source.LastFetchTime),
+			"last_error":          source.LastError,
+			"last_parse_note":     source.LastParseNote,
+			"image_mode":          source.ImageMode,
+			"retention_seconds":   source.RetentionSeconds,
+			"content_update_mode": source.ContentUpdateMode,
+			"summary_length":      source.SummaryLength,
+			"gallery_enabled":     source.GalleryEnabled,
+			"full_content":        source.FullContent,
+			"min_word_count":      source.MinWordCount,
+			"max_items":           source.MaxItems,
+			"lenient_parse":       source.LenientParse,
+			"body_images_only":    source.BodyImagesOnly,
+			"category":            source.Category,
+			"language":            source.Language,
+			"favicon":             source.Favicon,
+			// 停更检测
+			"last_item_added_at": tf.atPtr(source.LastItemAddedAt),
+			"staleness_seconds":  staleness,
+			"is_stale":           isStale,
+			// 抓取耗时
+			"fetch_avg_ms":       latency.AvgMs,
+			"fetch_near_timeout": latency.NearTimeout,
 		})
 	}
 
 	return result
 }
 
+// sourceStaleness 计算源距最近一次新增文章的秒数，超过阈值时标记为停更
+// 抓取成功但长期没有新文章的源往往已失效，需要管理员排查
+func sourceStaleness(source *db.Source) (int64, bool) {
+	staleness := source.Staleness(time.Now())
+	threshold := time.Duration(config.GetRuntimeConfig().GetSourceStaleThreshold()) * time.Second
+	return int64(staleness.Seconds()), staleness > threshold
+}
+
 // getImageCacheStats 获取图片缓存统计
 func (h *AdminHandler) getImageCacheStats() gin.H {
 	imageDir := filepath.Join(h.staticDir, "images")
@@ -611,7 +2083,120 @@ func (h *AdminHandler) getImageCacheStats() gin.H {
 	}
 }
 
-// countCachedImages 计算缓存图片数量（递归统计所有源目录下的文件数）
+// 按源统计图片缓存的扫描上限和结果缓存时间
+const (
+	sourceCacheScanLimit = 200000
+	sourceCacheTTL       = time.Minute
+)
+
+// SourceCacheEntry 单个源的图片缓存统计
+type SourceCacheEntry struct {
+	SourceID   int64   `json:"source_id"`
+	Title      string  `json:"title"`
+	ImageCount int     `json:"image_count"`
+	SizeBytes  int64   `json:"size_bytes"`
+	SizeMB     float64 `json:"size_mb"`
+	Orphaned   bool    `json:"orphaned"` // 目录对应的源已不存在
+}
+
+// sourceCacheScan 一次扫描的结果
+type sourceCacheScan struct {
+	entries   []SourceCacheEntry
+	truncated bool // 文件数超过扫描上限，统计不完整
+	scannedAt time.Time
+}
+
+// sourceCacheStats 缓存按源统计的扫描结果，避免频繁遍历文件系统
+type sourceCacheStats struct {
+	mu   sync.Mutex
+	scan *sourceCacheScan
+}
+
+// get 返回未过期的扫描结果，过期、不存在或 refresh 时重新扫描（扫描期间持锁，并发请求只扫描一次）
+func (s *sourceCacheStats) get(refresh bool, scan func() *sourceCacheScan) *sourceCacheScan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if refresh || s.scan == nil || time.Since(s.scan.scannedAt) > sourceCacheTTL {
+		s.scan = scan()
+	}
+	return s.scan
+}
+
+// invalidate 丢弃缓存的扫描结果
+func (s *sourceCacheStats) invalidate() {
+	s.mu.Lock()
+	s.scan = nil
+	s.mu.Unlock()
+}
+
+// scanSourceCache 遍历 images 下以源 ID 命名的目录，统计各源的文件数和大小，按大小降序
+func (h *AdminHandler) scanSourceCache() *sourceCacheScan {
+	result := &sourceCacheScan{entries: []SourceCacheEntry{}, scannedAt: time.Now()}
+	dirs, err := os.ReadDir(filepath.Join(h.staticDir, "images"))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[ADMIN] Failed to read image cache directory: %v", err)
+		}
+		return result
+	}
+
+	scanned := 0
+	for _, dir := range dirs {
+		sourceID, err := strconv.ParseInt(dir.Name(), 10, 64)
+		if !dir.IsDir() || err != nil {
+			continue
+		}
+		entry := SourceCacheEntry{SourceID: sourceID}
+		_ = filepath.WalkDir(filepath.Join(h.staticDir, "images", dir.Name()), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if scanned >= sourceCacheScanLimit {
+				result.truncated = true
+				return filepath.SkipAll
+			}
+			scanned++
+			if info, err := d.Info(); err == nil {
+				if !image.IsImageMeta(path) {
+					entry.ImageCount++
+				}
+				entry.SizeBytes += info.Size()
+			}
+			return nil
+		})
+		entry.SizeMB = float64(entry.SizeBytes) / (1024 * 1024)
+		result.entries = append(result.entries, entry)
+		if result.truncated {
+			break
+		}
+	}
+
+	sort.Slice(result.entries, func(i, j int) bool {
+		return result.entries[i].SizeBytes > result.entries[j].SizeBytes
+	})
+	return result
+}
+
+// countDirFiles 统计目录下的图片文件数（不含附属记录）和总大小
+func countDirFiles(dir string) (int, int64) {
+	var files int
+	var size int64
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			if !image.IsImageMeta(path) {
+				files++
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size
+}
+
+// countCachedImages 计算缓存图片数量（递归统计所有源目录下的图片文件数，不含附属记录）
 func (h *AdminHandler) countCachedImages() int {
 	imageDir := filepath.Join(h.staticDir, "images")
 	count := 0
@@ -623,7 +2208,7 @@ func (h *AdminHandler) countCachedImages() int {
 		if info == nil {
 			return nil
 		}
-		if !info.IsDir() {
+		if !info.IsDir() && !image.IsImageMeta(path) {
 			count++
 		}
 		return nil
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/image"
+)
+
+func TestSourceImageCache(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	small, err := database.CreateSource("https://small.example.com/feed", "Small", "", 3600)
+	if err != nil {
+		t.Fatalf("CreateSource: %v", err)
+	}
+	large, err := database.CreateSource("https://large.example.com/feed", "Large", "", 3600)
+	if err != nil {
+		t.Fatalf("CreateSource: %v", err)
+	}
+
+	staticDir := t.TempDir()
+	writeImages := func(sourceID int64, count, size int) {
+		dir := image.GetImageDirPath(staticDir, sourceID)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < count; i++ {
+			if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(i)+".webp"), make([]byte, size), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeImages(small.ID, 1, 100)
+	writeImages(large.ID, 3, 1000)
+	writeImages(999, 1, 10) // 源已删除的残留目录
+
+	gin.SetMode(gin.TestMode)
+	h := NewAdminHandler(database, staticDir, "", nil)
+	router := gin.New()
+	router.GET("/cache/sources", h.CacheSources)
+	router.POST("/cache/clear", h.ClearSourceCache)
+
+	list := func() []SourceCacheEntry {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cache/sources", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("cache/sources = %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Items []SourceCacheEntry `json:"items"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Items
+	}
+
+	entries := list()
+	if len(entries) != 3 {
+		t.Fatalf("entries = %+v, want 3", entries)
+	}
+	if e := entries[0]; e.SourceID != large.ID || e.Title != "Large" || e.ImageCount != 3 || e.SizeBytes != 3000 {
+		t.Errorf("largest entry = %+v", e)
+	}
+	if e := entries[2]; e.SourceID != 999 || !e.Orphaned {
+		t.Errorf("orphaned entry = %+v", e)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cache/clear?source_id="+strconv.FormatInt(large.ID, 10), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cache/clear = %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := os.Stat(image.GetImageDirPath(staticDir, large.ID)); !os.IsNotExist(err) {
+		t.Errorf("image directory still exists: %v", err)
+	}
+
+	// 清除后统计立即更新
+	entries = list()
+	if len(entries) != 2 || entries[0].SourceID != small.ID {
+		t.Errorf("entries after clear = %+v", entries)
+	}
+}
+
+func TestItemRaw(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	source, err := database.CreateSource("https://example.com/feed", "Feed", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const content = `<p onclick="x">"quoted" & <b>bold</b></p>` + "\n</script>"
+	res, err := database.Exec(`INSERT INTO items (source_id, guid, title, xml_content, content, clean_content, image_paths, tags)
+		VALUES (?, 'g', 'T', '<item><description>d</description></item>', ?, '<p>clean</p>', '["/static/images/1/a.webp"]', '["go"]')`,
+		source.ID, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemID, _ := res.LastInsertId()
+
+	gin.SetMode(gin.TestMode)
+	h := NewAdminHandler(database, t.TempDir(), "", nil)
+	router := gin.New()
+	router.GET("/items/:id/raw", h.ItemRaw)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/"+strconv.FormatInt(itemID, 10)+"/raw", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("raw = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			XMLContent   string `json:"xml_content"`
+			Content      string `json:"content"`
+			CleanContent string `json:"clean_content"`
+			ImagePaths   string `json:"image_paths"`
+			Derived      struct {
+				Tags string `json:"tags"`
+			} `json:"derived"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data.Content != content || resp.Data.CleanContent != "<p>clean</p>" ||
+		resp.Data.ImagePaths != `["/static/images/1/a.webp"]` || resp.Data.Derived.Tags != `["go"]` {
+		t.Errorf("unexpected data: %+v", resp.Data)
+	}
+
+	for path, want := range map[string]int{"/items/999/raw": http.StatusNotFound, "/items/abc/raw": http.StatusBadRequest} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+)
+
+// API 令牌限制
+const (
+	apiTokenPrefix       = "rf_" // 令牌前缀，认证中间件据此区分 API 令牌和 JWT
+	apiTokenPrefixLen    = 8     // 列表中展示的令牌随机部分位数
+	maxAPITokensPerUser  = 20
+	maxAPITokenNameRunes = 64
+)
+
+// apiTokenJSON API 令牌的响应格式，时间字段按 timeFormat 输出
+type apiTokenJSON struct {
+	*db.APIToken
+	LastUsedAt interface{} `json:"last_used_at"`
+	CreatedAt  interface{} `json:"created_at"`
+}
+
+// newAPITokenJSON 转换为响应格式
+func newAPITokenJSON(tf timeFormat, token *db.APIToken) apiTokenJSON {
+	return apiTokenJSON{APIToken: token, LastUsedAt: tf.atPtr(token.LastUsedAt), CreatedAt: tf.at(token.CreatedAt)}
+}
+
+// apiTokenContextKey 通过 API 令牌认证时，上下文中保存令牌 ID 的键
+const apiTokenContextKey = "api_token_id"
+
+// CreateAPITokenRequest 创建 API 令牌请求
+type CreateAPITokenRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"` // read（只读）或 write（读写，默认）
+}
+
+// CreateAPIToken 创建 API 令牌 POST /api/user/tokens
+// 令牌只在本次响应中返回，服务端只保存哈希
+func (a *AuthService) CreateAPIToken(c *gin.Context) {
+	userID, ok := a.requireSessionAuth(c)
+	if !ok {
+		return
+	}
+
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if utf8.RuneCountInString(name) > maxAPITokenNameRunes {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "令牌名称不能超过 64 个字符")
+		return
+	}
+	scope := req.Scope
+	if scope == "" {
+		scope = db.APITokenScopeWrite
+	}
+	if scope != db.APITokenScopeRead && scope != db.APITokenScopeWrite {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的scope参数，可选值为 read、write")
+		return
+	}
+
+	count, err := a.db.CountUserAPITokens(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询令牌失败")
+		return
+	}
+	if count >= maxAPITokensPerUser {
+		respondError(c, http.StatusConflict, CodeQuotaExceeded, "API 令牌数量已达上限（20 个），请先撤销不再使用的令牌")
+		return
+	}
+
+	secret, err := generateSecretToken()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "生成令牌失败")
+		return
+	}
+	secret = apiTokenPrefix + secret
+	prefix := secret[:len(apiTokenPrefix)+apiTokenPrefixLen]
+
+	token, err := a.db.CreateAPIToken(userID, name, hashSecretToken(secret), prefix, scope)
+	if err != nil {
+		log.Printf("[AUTH] Create API token failed for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "创建令牌失败")
+		return
+	}
+
+	log.Printf("[AUTH] API token %s (%s) created for user %d", prefix, scope, userID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "令牌只显示这一次，请妥善保存",
+		"token":   secret,
+		"data":    newAPITokenJSON(newTimeFormat(c), token),
+	})
+}
+
+// ListAPITokens 列出当前用户的 API 令牌 GET /api/user/tokens
+// 只返回令牌开头几位，不返回令牌本身
+func (a *AuthService) ListAPITokens(c *gin.Context) {
+	userID, ok := a.requireSessionAuth(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := a.db.ListUserAPITokens(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询令牌失败")
+		return
+	}
+	tf := newTimeFormat(c)
+	items := make([]apiTokenJSON, 0, len(tokens))
+	for _, token := range tokens {
+		items = append(items, newAPITokenJSON(tf, token))
+	}
+	c.JSON(http.StatusOK, completePage(c, items, len(items), nil))
+}
+
+// DeleteAPIToken 撤销 API 令牌 DELETE /api/user/tokens/:id，撤销后立即失效
+func (a *AuthService) DeleteAPIToken(c *gin.Context) {
+	userID, ok := a.requireSessionAuth(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的令牌 ID")
+		return
+	}
+	if err := a.db.DeleteUserAPIToken(userID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondError(c, http.StatusNotFound, CodeNotFound, "令牌不存在")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "撤销令牌失败")
+		return
+	}
+
+	log.Printf("[AUTH] API token %d revoked by user %d", id, userID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "令牌已撤销",
+	})
+}
+
+// requireSessionAuth 返回当前用户 ID；令牌管理只接受登录凭证（JWT），API 令牌不能创建或撤销令牌
+func (a *AuthService) requireSessionAuth(c *gin.Context) (int64, bool) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return 0, false
+	}
+	if _, viaToken := c.Get(apiTokenContextKey); viaToken {
+		respondError(c, http.StatusForbidden, CodeForbidden, "API 令牌不能管理令牌，请使用登录凭证")
+		return 0, false
+	}
+	return userID, true
+}
+
+// ReadOnly 在 group 上注册只读取数据的路由，并登记为只读 API 令牌可调用
+// 未登记的路由（无论 HTTP 方法）都需要读写令牌或登录凭证；只在启动注册路由时调用
+func (a *AuthService) ReadOnly(group *gin.RouterGroup, method, relativePath string, handlers ...gin.HandlerFunc) {
+	a.readOnlyRoutes[method+" "+path.Join(group.BasePath(), relativePath)] = true
+	group.Handle(method, relativePath, handlers...)
+}
+
+// authenticateAPIToken 校验 API 令牌并把所属用户写入上下文，失败时中止请求并返回 false
+// 只读令牌只能调用通过 ReadOnly 登记的路由；API 令牌不具备管理员权限。日志中只记录令牌开头几位
+func (a *AuthService) authenticateAPIToken(c *gin.Context, secret string) bool {
+	token, err := a.db.GetAPITokenByHash(hashSecretToken(secret))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("[AUTH] Look up API token failed: %v", err)
+		}
+		AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "无效的认证信息")
+		return false
+	}
+	user, err := a.db.GetUserByID(token.UserID)
+	if err != nil {
+		AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "无效的认证信息")
+		return false
+	}
+	if token.Scope == db.APITokenScopeRead && !a.readOnlyRoutes[c.Request.Method+" "+c.FullPath()] {
+		AbortWithError(c, http.StatusForbidden, CodeForbidden, "只读 API 令牌不能修改数据")
+		return false
+	}
+
+	if err := a.db.TouchAPIToken(token.ID); err != nil {
+		log.Printf("[AUTH] Update last use of API token %s failed: %v", token.Prefix, err)
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("is_admin", false)
+	c.Set(apiTokenContextKey, token.ID)
+	return true
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAPITokens(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	auth := NewAuthService(database, &config.Config{JWTSecret: "test", BcryptCost: bcrypt.MinCost})
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	jwtToken, err := auth.GenerateToken(user.ID, user.Username, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/", auth.AuthMiddleware())
+	group.POST("/tokens", auth.CreateAPIToken)
+	group.GET("/tokens", auth.ListAPITokens)
+	group.DELETE("/tokens/:id", auth.DeleteAPIToken)
+	probe := func(c *gin.Context) {
+		userID, _ := GetCurrentUserID(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID})
+	}
+	auth.ReadOnly(group, http.MethodGet, "/probe", probe)
+	auth.ReadOnly(group, http.MethodPost, "/probe/search", probe)
+	group.GET("/probe/write", probe)
+	group.POST("/probe", probe)
+
+	do := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	create := func(body string) (string, int64) {
+		t.Helper()
+		rec := do(http.MethodPost, "/tokens", jwtToken, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("create token = %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Token string      `json:"token"`
+			Data  db.APIToken `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(resp.Token, apiTokenPrefix) || !strings.HasPrefix(resp.Token, resp.Data.Prefix) {
+			t.Fatalf("token %q does not match prefix %q", resp.Token, resp.Data.Prefix)
+		}
+		return resp.Token, resp.Data.ID
+	}
+
+	writeToken, writeID := create(`{"name":"cli"}`)
+	readToken, _ := create(`{"name":"dashboard","scope":"read"}`)
+	if rec := do(http.MethodPost, "/tokens", jwtToken, `{"scope":"admin"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid scope = %d, want 400", rec.Code)
+	}
+
+	// 列表不包含令牌本身
+	rec := do(http.MethodGet, "/tokens", jwtToken, "")
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), writeToken) || strings.Contains(rec.Body.String(), readToken) {
+		t.Fatalf("list tokens = %d: %s", rec.Code, rec.Body.String())
+	}
+	var list struct {
+		Items []db.APIToken `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Items) != 2 {
+		t.Fatalf("list = %s (%v)", rec.Body.String(), err)
+	}
+
+	// 令牌解析为所属用户；只读令牌只能调用登记为只读的路由，与 HTTP 方法无关
+	if rec := do(http.MethodGet, "/probe", readToken, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"user_id":`+strconv.FormatInt(user.ID, 10)) {
+		t.Errorf("read token GET = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodPost, "/probe/search", readToken, ""); rec.Code != http.StatusOK {
+		t.Errorf("read token read-only POST = %d, want 200", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/probe/write", readToken, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("read token unmarked GET = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/probe", readToken, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("read token POST = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/probe", writeToken, ""); rec.Code != http.StatusOK {
+		t.Errorf("write token POST = %d, want 200", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/probe", apiTokenPrefix+"unknown", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("unknown token = %d, want 401", rec.Code)
+	}
+
+	// API 令牌不能管理令牌
+	if rec := do(http.MethodPost, "/tokens", writeToken, `{}`); rec.Code != http.StatusForbidden {
+		t.Errorf("create via API token = %d, want 403", rec.Code)
+	}
+
+	// 撤销后立即失效
+	path := "/tokens/" + strconv.FormatInt(writeID, 10)
+	if rec := do(http.MethodDelete, path, jwtToken, ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete = %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, "/probe", writeToken, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token = %d, want 401", rec.Code)
+	}
+	if rec := do(http.MethodDelete, path, jwtToken, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("delete again = %d, want 404", rec.Code)
+	}
+}
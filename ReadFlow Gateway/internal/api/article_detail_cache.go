@@ -0,0 +1,101 @@
+package api
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// articleFallbackCacheSize 旧数据解析结果的缓存条数上限
+const articleFallbackCacheSize = 2000
+
+// articleFallback 缺少结构化字段的旧文章从 xml_content 解析出的回退值
+type articleFallback struct {
+	desc        string
+	contentHTML string
+	summary     string
+	imageURL    string
+	wordCount   int
+}
+
+// articleFallbackKey 缓存键：文章 ID 不复用（AUTOINCREMENT），内容更新时内容哈希随之变化
+type articleFallbackKey struct {
+	id          int64
+	contentHash string
+}
+
+// articleFallbackCache 缓存旧文章的回退解析结果，重复打开时不再解析 xml_content 和去标签统计字数
+type articleFallbackCache struct {
+	mu      sync.Mutex
+	entries map[articleFallbackKey]*articleFallback
+}
+
+var fallbackCache articleFallbackCache
+
+// needsFallback 判断文章是否缺少需要从 xml_content 回退解析的结构化字段
+func needsFallback(item *db.Item) bool {
+	return item.CleanContent == "" || item.Summary == "" || item.CoverImage == "" || item.WordCount == 0
+}
+
+// get 返回文章的回退解析结果，未缓存时解析并写入缓存；结果只取决于 xml_content
+func (c *articleFallbackCache) get(item *db.Item) *articleFallback {
+	key := articleFallbackKey{id: item.ID, contentHash: item.ContentHash}
+	c.mu.Lock()
+	fallback, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return fallback
+	}
+
+	desc, contentHTML, _ := parseXMLFields(item.XMLContent)
+	fallback = &articleFallback{
+		desc:        desc,
+		contentHTML: contentHTML,
+		summary:     utils.NewTextProcessor().SelectSummary(desc, contentHTML, utils.DefaultSummaryLength),
+		imageURL:    extractFirstImageURL(contentHTML),
+		wordCount:   countWordsFromHTML(contentHTML),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 超出容量时整体重置，旧文章通常只在一段时间内被反复打开
+	if c.entries == nil || len(c.entries) >= articleFallbackCacheSize {
+		c.entries = make(map[articleFallbackKey]*articleFallback)
+	}
+	c.entries[key] = fallback
+	return fallback
+}
+
+// articleDetailETag 文章详情的 ETag：由内容哈希和影响响应的其余字段（封面、标签、源名称、摘要长度、图片改写方式等）计算，
+// 不需要读取正文；详情不包含阅读状态，标记已读不会改变 ETag。没有内容哈希的旧数据按 xml_content 和正文计算
+func articleDetailETag(item *db.Item, source *db.Source, summaryLength int, rewriter imageRewriter) string {
+	h := sha256.New()
+	if item.ContentHash != "" {
+		h.Write([]byte(item.ContentHash))
+	} else {
+		h.Write([]byte(item.XMLContent))
+		h.Write([]byte(item.CleanContent))
+	}
+
+	var publishedAt int64
+	if item.PublishedAt != nil {
+		publishedAt = item.PublishedAt.Unix()
+	}
+	for _, field := range []string{
+		strconv.FormatInt(item.ID, 10), item.Title, item.Summary, item.CoverImage, item.ImageCaption, item.ImageCredit,
+		item.ImagePrimaryColor, item.ImageBlurhash, item.CoverVariants, item.Author, item.Tags, item.Category, item.Gallery,
+		strconv.FormatBool(item.Truncated), strconv.FormatInt(publishedAt, 10),
+		strconv.Itoa(item.WordCount), strconv.Itoa(item.ReadingTime),
+		strconv.FormatInt(source.ID, 10), source.Title, strconv.Itoa(summaryLength),
+		strconv.FormatBool(rewriter.enabled), rewriter.baseURL, rewriter.key,
+	} {
+		// 以 0 分隔各字段，避免相邻字段拼接后产生歧义
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf(`"a-%x"`, h.Sum(nil)[:12])
+}
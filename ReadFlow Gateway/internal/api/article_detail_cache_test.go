@@ -0,0 +1,61 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestArticleDetailETag(t *testing.T) {
+	item := &db.Item{ID: 1, Title: "t", ContentHash: "h1"}
+	source := &db.Source{ID: 2, Title: "s"}
+	rewriter := imageRewriter{}
+
+	etag := articleDetailETag(item, source, 0, rewriter)
+	if etag != articleDetailETag(item, source, 0, rewriter) {
+		t.Fatal("etag not stable")
+	}
+	if !etagMatches(`W/"x", `+etag, etag) {
+		t.Errorf("etagMatches(%q) = false", etag)
+	}
+
+	changed := *item
+	changed.ContentHash = "h2"
+	if articleDetailETag(&changed, source, 0, rewriter) == etag {
+		t.Errorf("content hash change kept etag")
+	}
+	if articleDetailETag(item, source, 80, rewriter) == etag {
+		t.Errorf("summary length change kept etag")
+	}
+	if articleDetailETag(item, source, 0, imageRewriter{enabled: true, key: "k"}) == etag {
+		t.Errorf("image proxy change kept etag")
+	}
+}
+
+func TestArticleFallbackCache(t *testing.T) {
+	var cache articleFallbackCache
+	item := &db.Item{
+		ID:          1,
+		ContentHash: "h1",
+		XMLContent:  `<item><link>https://example.com/a</link><content:encoded><![CDATA[<p>hello world</p><img src="https://example.com/a.jpg">]]></content:encoded></item>`,
+	}
+	if !needsFallback(item) {
+		t.Fatal("legacy item should need fallback")
+	}
+
+	first := cache.get(item)
+	if first.imageURL != "https://example.com/a.jpg" || first.wordCount == 0 {
+		t.Errorf("fallback = %+v", first)
+	}
+	if cache.get(item) != first {
+		t.Errorf("second get was not served from cache")
+	}
+
+	// 内容更新后重新解析
+	updated := *item
+	updated.ContentHash = "h2"
+	updated.XMLContent = `<item><content:encoded><![CDATA[<p>changed</p>]]></content:encoded></item>`
+	if got := cache.get(&updated); got == first || got.imageURL != "" {
+		t.Errorf("updated item served stale fallback %+v", got)
+	}
+}
@@ -1,80 +1,88 @@
 package api
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"html"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/utils"
 )
 
 // ArticleHandler 文章相关 API 处理器
 type ArticleHandler struct {
-	db *db.DB
+	db            *db.DB
+	imageProxyKey string // 代理模式改写图片地址时的签名密钥
 }
 
 // NewArticleHandler 创建文章处理器
-func NewArticleHandler(database *db.DB) *ArticleHandler {
-	return &ArticleHandler{db: database}
+func NewArticleHandler(database *db.DB, imageProxyKey string) *ArticleHandler {
+	return &ArticleHandler{db: database, imageProxyKey: imageProxyKey}
 }
 
 // ArticleListItem 列表项结构
 type ArticleListItem struct {
-	ID                int64  `json:"id"`
-	Title             string `json:"title"`
-	Summary           string `json:"summary"`
-	ImageURL          string `json:"imageUrl"`
-	ImageCaption      string `json:"imageCaption"`      // Added
-	ImageCredit       string `json:"imageCredit"`       // Added
-	ImagePrimaryColor string `json:"imagePrimaryColor"` // Added
-	Author            string `json:"author"`
-	PublishedAt       int64  `json:"publishedAt"`
-	SourceID          int64  `json:"sourceId"`
-	SourceName        string `json:"sourceName"`
-	WordCount         int    `json:"wordCount"`
-	ReadingTime       int    `json:"readingTime"`
-	IsRead            bool   `json:"isRead"`
-	IsFavorite        bool   `json:"isFavorite"`
-	ReadProgress      int    `json:"readProgress"`
-	ReadAt            *int64 `json:"readAt,omitempty"`
-	UpdatedAt         int64  `json:"updatedAt"`
-}
-
-// ArticleListResponse 列表响应
-type ArticleListResponse struct {
-	Success    bool              `json:"success"`
-	Articles   []ArticleListItem `json:"articles"`
-	HasMore    bool              `json:"hasMore"`
-	SyncTime   *int64            `json:"syncTime,omitempty"`   // 增量同步模式：服务端当前时间戳
-	NextCursor *string           `json:"nextCursor,omitempty"` // 游标分页模式：下一页游标
+	ID                int64             `json:"id"`
+	Title             string            `json:"title"`
+	Summary           string            `json:"summary"`
+	ImageURL          string            `json:"imageUrl"`
+	ImageCaption      string            `json:"imageCaption"`      // Added
+	ImageCredit       string            `json:"imageCredit"`       // Added
+	ImagePrimaryColor string            `json:"imagePrimaryColor"` // Added
+	ImageBlurhash     string            `json:"imageBlurhash"`     // 封面图 BlurHash，未生成时为空
+	ImageVariants     []db.CoverVariant `json:"imageVariants"`     // 封面缩略图（按宽度升序，可组成 srcset），未生成时为空数组
+	Author            string            `json:"author"`
+	Tags              []string          `json:"tags"`
+	Category          string            `json:"category"`
+	Truncated         bool              `json:"truncated"` // 正文入库时已截断，完整内容需打开原文链接
+	PublishedAt       int64             `json:"publishedAt"`
+	SourceID          int64             `json:"sourceId"`
+	SourceName        string            `json:"sourceName"`
+	WordCount         int               `json:"wordCount"`
+	ReadingTime       int               `json:"readingTime"`
+	IsRead            bool              `json:"isRead"`
+	IsFavorite        bool              `json:"isFavorite"`
+	ReadProgress      int               `json:"readProgress"`
+	ReadAt            *int64            `json:"readAt,omitempty"`
+	UpdatedAt         int64             `json:"updatedAt"`
 }
 
 // ArticleDetailResponse 详情响应
 type ArticleDetailResponse struct {
-	Success           bool   `json:"success"`
-	ID                int64  `json:"id"`
-	Title             string `json:"title"`
-	Content           string `json:"content"`
-	Summary           string `json:"summary"`
-	ImageURL          string `json:"imageUrl"`
-	ImageCaption      string `json:"imageCaption"`      // Added
-	ImageCredit       string `json:"imageCredit"`       // Added
-	ImagePrimaryColor string `json:"imagePrimaryColor"` // Added
-	Author            string `json:"author"`
-	PublishedAt       int64  `json:"publishedAt"`
-	URL               string `json:"url"`
-	SourceID          int64  `json:"sourceId"`
-	SourceName        string `json:"sourceName"`
-	WordCount         int    `json:"wordCount"`
-	ReadingTime       int    `json:"readingTime"`
-	IsFavorite        bool   `json:"isFavorite"`
-	ReadProgress      int    `json:"readProgress"`
-	ReadAt            *int64 `json:"readAt,omitempty"`
-	UpdatedAt         int64  `json:"updatedAt"`
+	Success           bool              `json:"success"`
+	ID                int64             `json:"id"`
+	Title             string            `json:"title"`
+	Content           string            `json:"content"`
+	Summary           string            `json:"summary"`
+	ImageURL          string            `json:"imageUrl"`
+	ImageCaption      string            `json:"imageCaption"`      // Added
+	ImageCredit       string            `json:"imageCredit"`       // Added
+	ImagePrimaryColor string            `json:"imagePrimaryColor"` // Added
+	ImageBlurhash     string            `json:"imageBlurhash"`     // 封面图 BlurHash，未生成时为空
+	ImageVariants     []db.CoverVariant `json:"imageVariants"`     // 封面缩略图（按宽度升序，可组成 srcset），未生成时为空数组
+	Author            string            `json:"author"`
+	Tags              []string          `json:"tags"`
+	Category          string            `json:"category"`
+	Truncated         bool              `json:"truncated"`
+	Gallery           []db.GalleryImage `json:"gallery"` // 图集（源开启时），按正文顺序
+	PublishedAt       int64             `json:"publishedAt"`
+	URL               string            `json:"url"`
+	SourceID          int64             `json:"sourceId"`
+	SourceName        string            `json:"sourceName"`
+	WordCount         int               `json:"wordCount"`
+	ReadingTime       int               `json:"readingTime"`
+	IsFavorite        bool              `json:"isFavorite"`
+	ReadProgress      int               `json:"readProgress"`
+	ReadAt            *int64            `json:"readAt,omitempty"`
+	UpdatedAt         int64             `json:"updatedAt"`
 }
 
 var (
@@ -85,16 +93,15 @@ var (
 
 // ListArticles 获取文章列表（按用户视角）
 // 支持三种模式：
-// 1. 增量同步：since 参数，返回该时间之后发布的文章
+// 1. 增量同步：since 参数，返回该时间之后发布的文章；sort=updated 时返回该时间之后新投递或有变化（updatedAt）的文章，可带 cursor 继续翻页
 // 2. 游标分页：cursor 参数，翻页历史文章
 // 3. 默认模式：offset 分页（兼容旧逻辑）
+// 可选 tag / category 参数按标签或分类过滤，group_id 参数只返回该分组下订阅源的文章，include_facets=true 时附带标签统计；
+// sort 参数选择排序方式：newest（默认）、oldest、unread（未读在前）、updated（最近更新在前）
 func (h *ArticleHandler) ListArticles(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
@@ -120,11 +127,40 @@ func (h *ArticleHandler) ListArticles(c *gin.Context) {
 		}
 	}
 
+	// 解析 group_id 参数（分组过滤），分组必须属于当前用户
+	var groupIDPtr *int64
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		gid, err := strconv.ParseInt(groupIDStr, 10, 64)
+		if err != nil || gid <= 0 {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的group_id参数")
+			return
+		}
+		if _, err := h.db.GetUserGroup(userID, gid); err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, CodeNotFound, "分组不存在")
+			return
+		} else if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeInternal, "查询分组失败")
+			return
+		}
+		groupIDPtr = &gid
+	}
+
+	// 解析 tag 参数（标签过滤，标签统一为小写）
+	var tagPtr *string
+	if tag := strings.ToLower(strings.TrimSpace(c.Query("tag"))); tag != "" {
+		tagPtr = &tag
+	}
+
+	// 解析 category 参数（分类过滤，按入库时的规则规范化）
+	var categoryPtr *string
+	if category := utils.NewTextProcessor().NormalizeCategory(c.Query("category")); category != "" {
+		categoryPtr = &category
+	}
+
 	// 解析 since 参数（增量同步）
 	var sinceTimePtr *time.Time
 	if sinceStr := c.Query("since"); sinceStr != "" {
-		if sinceTimestamp, err := strconv.ParseInt(sinceStr, 10, 64); err == nil && sinceTimestamp > 0 {
-			t := time.Unix(sinceTimestamp, 0)
+		if t, err := parseTimestamp(sinceStr); err == nil && t.Unix() > 0 {
 			sinceTimePtr = &t
 		}
 	}
@@ -135,173 +171,325 @@ func (h *ArticleHandler) ListArticles(c *gin.Context) {
 		cursorPtr = &cursorStr
 	}
 
+	// 解析 sort 参数（排序方式），cursor 需与生成它时的排序方式一致
+	sort, ok := db.ParseArticleSort(c.Query("sort"))
+	if !ok {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的sort参数，可选值为 newest、oldest、unread、updated")
+		return
+	}
+
+	// 解析 summary_length 参数（按请求指定摘要长度）
+	summaryLength := parseSummaryLength(c)
+
 	// 调用数据库层
-	userArticles, nextCursor, err := h.db.GetUserArticles(userID, sourceIDPtr, sinceTimePtr, cursorPtr, limit, offset)
+	userArticles, nextCursor, err := h.db.GetUserArticles(userID, sourceIDPtr, groupIDPtr, tagPtr, categoryPtr, sinceTimePtr, cursorPtr, sort, limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "查询失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
 		return
 	}
 
 	// 构建响应
 	items := make([]ArticleListItem, 0, len(userArticles))
 	for _, ua := range userArticles {
-		// 直接使用结构化字段，不需要解析 xml_content
-		summary := ua.Summary
-		imageURL := ua.CoverImage
-		wordCount := ua.WordCount
-		readingTime := ua.ReadingTime
-
-		// 如果结构化字段为空（旧数据），回退到解析 xml_content
-		if summary == "" || imageURL == "" || wordCount == 0 {
-			desc, contentHTML, _ := parseXMLFields(ua.XMLContent)
-
-			if summary == "" {
-				summary = generateSummaryFromHTML(desc, 200)
-				if summary == "" {
-					summary = generateSummaryFromHTML(contentHTML, 200)
-				}
-			}
+		items = append(items, toArticleListItem(ua, summaryLength))
+	}
 
-			if imageURL == "" {
-				imageURL = extractFirstImageURL(contentHTML)
-			}
+	// 构建响应对象，各模式统一在 hasMore 时返回 nextCursor
+	var cursor string
+	if nextCursor != nil {
+		cursor = *nextCursor
+	}
+	response := pageResponse(c, items, nextCursor != nil, cursor, nil, gin.H{"articles": items})
+	if sinceTimePtr != nil {
+		// 增量同步模式：返回 syncTime
+		response["syncTime"] = time.Now().Unix()
+	}
 
-			if wordCount == 0 {
-				wordCount = countWordsFromHTML(contentHTML)
-				if wordCount > 0 {
-					readingTime = (wordCount + 199) / 200
-				}
-			}
+	if c.Query("include_facets") == "true" {
+		facets, err := h.db.GetUserTagFacets(userID, sourceIDPtr, 20)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeInternal, "查询标签统计失败")
+			return
 		}
+		response["tagFacets"] = facets
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// 继续阅读列表参数
+const (
+	continueDefaultLimit = 20
+	continueMaxLimit     = 50
+)
+
+// ListContinueReading 获取"继续阅读"列表 GET /api/articles/continue
+// 返回读到一半（进度 1-99）的文章，最近读过的在前，进度见 readProgress
+func (h *ArticleHandler) ListContinueReading(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(continueDefaultLimit)))
+	if err != nil || limit <= 0 || limit > continueMaxLimit {
+		limit = continueDefaultLimit
+	}
+
+	userArticles, err := h.db.GetContinueReadingArticles(userID, limit)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+		return
+	}
+
+	summaryLength := parseSummaryLength(c)
+	items := make([]ArticleListItem, 0, len(userArticles))
+	for _, ua := range userArticles {
+		items = append(items, toArticleListItem(ua, summaryLength))
+	}
+
+	// 只返回最近的 limit 篇，不分页
+	c.JSON(http.StatusOK, pageResponse(c, items, false, "", nil, gin.H{"articles": items}))
+}
+
+// ListCategories 获取用户文章的分类列表及各分类文章数 GET /api/categories
+func (h *ArticleHandler) ListCategories(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	facets, err := h.db.GetUserCategoryFacets(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询分类统计失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, completePage(c, facets, len(facets), gin.H{"categories": facets}))
+}
+
+// toArticleListItem 将用户文章转换为列表项（旧数据回退到解析 xml_content）
+// summaryLength > 0 时按该长度重新生成摘要，否则使用入库时生成的摘要
+func toArticleListItem(ua *db.UserArticle, summaryLength int) ArticleListItem {
+	// 直接使用结构化字段，不需要解析 xml_content
+	summary := ua.Summary
+	if summaryLength > 0 {
+		summary = articleSummary(ua.XMLContent, ua.CleanContent, summaryLength)
+	}
+	imageURL := ua.CoverImage
+	wordCount := ua.WordCount
+	readingTime := ua.ReadingTime
+
+	// 如果结构化字段为空（旧数据），回退到解析 xml_content
+	if summary == "" || imageURL == "" || wordCount == 0 {
+		desc, contentHTML, _ := parseXMLFields(ua.XMLContent)
 
-		var publishedAt int64
-		if ua.PublishedAt != nil {
-			publishedAt = ua.PublishedAt.Unix()
+		if summary == "" {
+			summary = utils.NewTextProcessor().SelectSummary(desc, contentHTML, utils.DefaultSummaryLength)
 		}
 
-		var readAt *int64
-		if ua.ReadAt != nil {
-			t := ua.ReadAt.Unix()
-			readAt = &t
+		if imageURL == "" {
+			imageURL = extractFirstImageURL(contentHTML)
 		}
 
-		items = append(items, ArticleListItem{
-			ID:                ua.ID,
-			Title:             ua.Title,
-			Summary:           summary,
-			ImageURL:          imageURL,
-			ImageCaption:      ua.ImageCaption,
-			ImageCredit:       ua.ImageCredit,
-			ImagePrimaryColor: ua.ImagePrimaryColor,
-			Author:            ua.Author,
-			PublishedAt:       publishedAt,
-			SourceID:          ua.SourceID,
-			SourceName:        ua.SourceTitle,
-			WordCount:         wordCount,
-			ReadingTime:       readingTime,
-			IsRead:            ua.Status != 0,
-			IsFavorite:        ua.IsFavorite,
-			ReadProgress:      ua.ReadProgress,
-			ReadAt:            readAt,
-			UpdatedAt:         ua.UpdatedAt.Unix(),
-		})
-	}
-
-	// 构建响应对象
-	response := ArticleListResponse{
-		Success:  true,
-		Articles: items,
-		HasMore:  nextCursor != nil,
-	}
-
-	// 根据请求模式添加相应字段
-	if sinceTimePtr != nil {
-		// 增量同步模式：返回 syncTime
-		syncTime := time.Now().Unix()
-		response.SyncTime = &syncTime
-	} else if cursorPtr != nil || nextCursor != nil {
-		// 游标分页模式：返回 nextCursor
-		response.NextCursor = nextCursor
+		if wordCount == 0 {
+			wordCount = countWordsFromHTML(contentHTML)
+			if wordCount > 0 {
+				readingTime = (wordCount + 199) / 200
+			}
+		}
 	}
 
-	c.JSON(http.StatusOK, response)
+	var publishedAt int64
+	if ua.PublishedAt != nil {
+		publishedAt = ua.PublishedAt.Unix()
+	}
+
+	var readAt *int64
+	if ua.ReadAt != nil {
+		t := ua.ReadAt.Unix()
+		readAt = &t
+	}
+
+	return ArticleListItem{
+		ID:                ua.ID,
+		Title:             ua.Title,
+		Summary:           summary,
+		ImageURL:          imageURL,
+		ImageCaption:      ua.ImageCaption,
+		ImageCredit:       ua.ImageCredit,
+		ImagePrimaryColor: ua.ImagePrimaryColor,
+		ImageBlurhash:     ua.ImageBlurhash,
+		ImageVariants:     parseCoverVariants(ua.CoverVariants),
+		Author:            ua.Author,
+		Tags:              parseTags(ua.Tags),
+		Category:          ua.Category,
+		Truncated:         ua.Truncated,
+		PublishedAt:       publishedAt,
+		SourceID:          ua.SourceID,
+		SourceName:        ua.SourceTitle,
+		WordCount:         wordCount,
+		ReadingTime:       readingTime,
+		IsRead:            ua.Status != 0,
+		IsFavorite:        ua.IsFavorite,
+		ReadProgress:      ua.ReadProgress,
+		ReadAt:            readAt,
+		UpdatedAt:         ua.UpdatedAt.Unix(),
+	}
 }
 
 // GetArticleDetail 获取文章详情
+// 可选参数 image_proxy=true/false 控制是否将正文和封面图片改写为网关代理地址，缺省时按用户偏好 proxy_mode_enabled
+// 响应带 ETag，请求的 If-None-Match 匹配时返回 304，不再生成正文
 func (h *ArticleHandler) GetArticleDetail(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
-	_ = userID // 当前只用于鉴权，不做额外校验
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的文章 ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
 		return
 	}
 
 	item, err := h.db.GetItemByID(id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"success": false,
-			"message": "文章不存在",
-		})
+		respondError(c, http.StatusNotFound, CodeNotFound, "文章不存在")
 		return
 	}
 
 	source, err := h.db.GetSourceByID(item.SourceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "查询订阅源失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅源失败")
+		return
+	}
+
+	summaryLength := parseSummaryLength(c)
+	rewriter := newImageRewriter(c, h.db, userID, h.imageProxyKey)
+	etag := articleDetailETag(item, source, summaryLength, rewriter)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
 		return
 	}
 
-	desc, contentHTML, link := parseXMLFields(item.XMLContent)
+	detail := toArticleDetail(item, source, summaryLength)
+	detail.Content = rewriter.content(detail.Content)
+	detail.ImageURL = rewriter.cover(detail.ImageURL)
+	c.JSON(http.StatusOK, detail)
+}
+
+// 批量获取文章详情的数量上限
+const maxDetailBatch = 50
+
+// ArticleDetailBatchRequest 批量获取文章详情请求
+type ArticleDetailBatchRequest struct {
+	IDs []int64 `json:"ids" binding:"required"`
+}
+
+// GetArticleDetailBatch 批量获取文章详情 POST /api/articles/detail-batch
+// 用于离线下载正文，按请求顺序返回；未投递给当前用户或不存在的 ID 直接省略；image_proxy 参数同 GetArticleDetail
+func (h *ArticleHandler) GetArticleDetailBatch(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	var req ArticleDetailBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
+		return
+	}
+	if len(req.IDs) > maxDetailBatch {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("ids 数量不能超过 %d", maxDetailBatch))
+		return
+	}
+
+	delivered, err := h.db.GetDeliveredItemIDs(userID, req.IDs)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+		return
+	}
+
+	summaryLength := parseSummaryLength(c)
+	rewriter := newImageRewriter(c, h.db, userID, h.imageProxyKey)
+	sources := make(map[int64]*db.Source)
+	articles := make([]ArticleDetailResponse, 0, len(delivered))
+	seen := make(map[int64]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if !delivered[id] || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		item, err := h.db.GetItemByID(id)
+		if err != nil {
+			continue // 查询期间文章被清理
+		}
+		source, ok := sources[item.SourceID]
+		if !ok {
+			if source, err = h.db.GetSourceByID(item.SourceID); err != nil {
+				continue
+			}
+			sources[item.SourceID] = source
+		}
+		detail := toArticleDetail(item, source, summaryLength)
+		detail.Content = rewriter.content(detail.Content)
+		detail.ImageURL = rewriter.cover(detail.ImageURL)
+		articles = append(articles, detail)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"articles": articles,
+	})
+}
+
+// toArticleDetail 将文章转换为详情响应（旧数据回退到解析 xml_content，解析结果有缓存）
+// summaryLength > 0 时按该长度重新生成摘要，否则使用入库时生成的摘要
+func toArticleDetail(item *db.Item, source *db.Source, summaryLength int) ArticleDetailResponse {
+	link := between(item.XMLContent, "<link>", "</link>")
 
 	// 直接使用结构化字段
 	content := item.CleanContent
 	summary := item.Summary
+	if summaryLength > 0 {
+		summary = articleSummary(item.XMLContent, item.CleanContent, summaryLength)
+	}
 	imageURL := item.CoverImage
 	wordCount := item.WordCount
 	readingTime := item.ReadingTime
 
 	// 如果结构化字段为空（旧数据），回退到解析
-	if content == "" {
-		content = contentHTML
+	if needsFallback(item) {
+		fallback := fallbackCache.get(item)
 		if content == "" {
-			content = desc
+			content = fallback.contentHTML
+			if content == "" {
+				content = fallback.desc
+			}
 		}
-	}
 
-	if summary == "" {
-		summary = generateSummaryFromHTML(desc, 200)
 		if summary == "" {
-			summary = generateSummaryFromHTML(contentHTML, 200)
+			summary = fallback.summary
 		}
-	}
 
-	if imageURL == "" {
-		imageURL = extractFirstImageURL(contentHTML)
-	}
+		if imageURL == "" {
+			imageURL = fallback.imageURL
+		}
 
-	if wordCount == 0 {
-		wordCount = countWordsFromHTML(contentHTML)
-		if wordCount > 0 {
-			readingTime = (wordCount + 199) / 200
+		if wordCount == 0 {
+			wordCount = fallback.wordCount
+			if wordCount > 0 {
+				readingTime = (wordCount + 199) / 200
+			}
 		}
 	}
 
@@ -310,25 +498,188 @@ func (h *ArticleHandler) GetArticleDetail(c *gin.Context) {
 		publishedAt = item.PublishedAt.Unix()
 	}
 
-	c.JSON(http.StatusOK, ArticleDetailResponse{
-		Success:      true,
-		ID:           item.ID,
-		Title:        item.Title,
-		Content:      content,
-		Summary:      summary,
-		ImageURL:     imageURL,
-		ImageCaption: item.ImageCaption,
-		ImageCredit:  item.ImageCredit,
-		Author:       item.Author,
-		PublishedAt:  publishedAt,
-		URL:          link,
-		SourceID:     source.ID,
-		SourceName:   source.Title,
-		WordCount:    wordCount,
-		ReadingTime:  readingTime,
+	return ArticleDetailResponse{
+		Success:           true,
+		ID:                item.ID,
+		Title:             item.Title,
+		Content:           content,
+		Summary:           summary,
+		ImageURL:          imageURL,
+		ImageCaption:      item.ImageCaption,
+		ImageCredit:       item.ImageCredit,
+		ImagePrimaryColor: item.ImagePrimaryColor,
+		ImageBlurhash:     item.ImageBlurhash,
+		ImageVariants:     parseCoverVariants(item.CoverVariants),
+		Author:            item.Author,
+		Tags:              parseTags(item.Tags),
+		Category:          item.Category,
+		Truncated:         item.Truncated,
+		Gallery:           parseGallery(item.Gallery),
+		PublishedAt:       publishedAt,
+		URL:               link,
+		SourceID:          source.ID,
+		SourceName:        source.Title,
+		WordCount:         wordCount,
+		ReadingTime:       readingTime,
+	}
+}
+
+// 相关文章参数
+const (
+	relatedDefaultLimit = 5
+	relatedMaxLimit     = 20
+	relatedCandidates   = 50                  // 参与排序的候选文章上限
+	relatedWindow       = 90 * 24 * time.Hour // 只在最近 90 天的文章中查找
+	relatedTitleScan    = 500                 // 按标题相似度查找时比较的最近文章数
+	relatedTitleMatches = 20                  // 按标题相似度加入的候选文章上限
+	relatedMinTitleSim  = 0.3                 // 没有共同标签的文章，标题相似度达到该值才作为候选
+)
+
+// GetRelatedArticles 获取相关文章（"更多类似内容"）
+// 候选为有共同标签 / 标题关键词，或标题相似度较高的文章，按交集数量、标题相似度和发布时间排序
+// 优先返回未读文章，数量不足时用已读文章补齐
+func (h *ArticleHandler) GetRelatedArticles(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(relatedDefaultLimit)))
+	if err != nil || limit <= 0 || limit > relatedMaxLimit {
+		limit = relatedDefaultLimit
+	}
+	summaryLength := parseSummaryLength(c)
+
+	// 只能查询投递给自己的文章，避免借此探测其他文章的标签和标题
+	item, err := h.db.GetUserArticle(userID, id)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, CodeNotFound, "文章不存在")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+		return
+	}
+
+	// 匹配词 = 文章标签 + 标题关键词
+	textProcessor := utils.NewTextProcessor()
+	titleTerms := textProcessor.ExtractKeywords(item.Title, 5)
+	terms := textProcessor.NormalizeTags(append(parseTags(item.Tags), titleTerms...), 0)
+
+	candidates, err := h.db.GetRelatedUserArticles(userID, id, terms, time.Now().Add(-relatedWindow), relatedCandidates)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+		return
+	}
+
+	// 标题相似度作为加分项
+	scores := make(map[int64]float64, len(candidates))
+	for _, cand := range candidates {
+		similarity := termSimilarity(titleTerms, textProcessor.ExtractKeywords(cand.Article.Title, 5))
+		scores[cand.Article.ID] = float64(cand.Overlap) + 2*similarity
+	}
+
+	// 标题相似但没有共同标签的文章（如标签提取不同的源转载同一事件）也作为候选
+	titleMatches, err := h.relatedByTitle(userID, id, titleTerms, scores)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+		return
+	}
+	candidates = append(candidates, titleMatches...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].Article.ID] > scores[candidates[j].Article.ID]
+	})
+
+	// 先取未读，不足再用已读补齐
+	items := make([]ArticleListItem, 0, limit)
+	for _, unread := range []bool{true, false} {
+		for _, cand := range candidates {
+			if len(items) >= limit {
+				break
+			}
+			if (cand.Article.Status == 0) == unread {
+				items = append(items, toArticleListItem(cand.Article, summaryLength))
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"articles": items,
 	})
 }
 
+// relatedByTitle 在最近的文章中查找标题相似度不低于 relatedMinTitleSim 的文章，跳过 scores 中已有的候选
+// 返回的候选按相似度计分写入 scores（没有标签交集，Overlap 为 0）
+func (h *ArticleHandler) relatedByTitle(userID, itemID int64, titleTerms []string, scores map[int64]float64) ([]*db.RelatedArticle, error) {
+	if len(titleTerms) == 0 {
+		return nil, nil
+	}
+	titles, err := h.db.GetRecentUserItemTitles(userID, itemID, time.Now().Add(-relatedWindow), relatedTitleScan)
+	if err != nil {
+		return nil, err
+	}
+
+	textProcessor := utils.NewTextProcessor()
+	similar := make(map[int64]float64)
+	ids := make([]int64, 0)
+	for _, t := range titles {
+		if _, ok := scores[t.ID]; ok {
+			continue
+		}
+		if similarity := termSimilarity(titleTerms, textProcessor.ExtractKeywords(t.Title, 5)); similarity >= relatedMinTitleSim {
+			similar[t.ID] = similarity
+			ids = append(ids, t.ID)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return similar[ids[i]] > similar[ids[j]] })
+	if len(ids) > relatedTitleMatches {
+		ids = ids[:relatedTitleMatches]
+	}
+
+	matches := make([]*db.RelatedArticle, 0, len(ids))
+	for _, id := range ids {
+		article, err := h.db.GetUserArticle(userID, id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		scores[id] = 2 * similar[id]
+		matches = append(matches, &db.RelatedArticle{Article: article})
+	}
+	return matches, nil
+}
+
+// termSimilarity 计算两组词的 Jaccard 相似度
+func termSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	common := 0
+	union := len(set)
+	for _, t := range b {
+		if set[t] {
+			common++
+		} else {
+			union++
+		}
+	}
+	return float64(common) / float64(union)
+}
+
 // parseXMLFields 从 xml_content 中解析 description、content:encoded 和 link
 func parseXMLFields(xmlContent string) (description, contentHTML, link string) {
 	description = between(xmlContent, "<description><![CDATA[", "]]></description>")
@@ -337,6 +688,42 @@ func parseXMLFields(xmlContent string) (description, contentHTML, link string) {
 	return
 }
 
+// parseTags 解析 items.tags 中的 JSON 数组（旧数据为空时返回空数组）
+func parseTags(raw string) []string {
+	tags := []string{}
+	if raw == "" {
+		return tags
+	}
+	if err := json.Unmarshal([]byte(raw), &tags); err != nil || tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// parseCoverVariants 解析封面缩略图 JSON，无效或为空时返回空数组
+func parseCoverVariants(raw string) []db.CoverVariant {
+	variants := []db.CoverVariant{}
+	if raw == "" {
+		return variants
+	}
+	if err := json.Unmarshal([]byte(raw), &variants); err != nil || variants == nil {
+		return []db.CoverVariant{}
+	}
+	return variants
+}
+
+// parseGallery 解析图集 JSON，无效或为空时返回空数组
+func parseGallery(raw string) []db.GalleryImage {
+	gallery := []db.GalleryImage{}
+	if raw == "" {
+		return gallery
+	}
+	if err := json.Unmarshal([]byte(raw), &gallery); err != nil || gallery == nil {
+		return []db.GalleryImage{}
+	}
+	return gallery
+}
+
 // between 返回 start 和 end 中间的子串
 func between(s, start, end string) string {
 	startIdx := strings.Index(s, start)
@@ -351,19 +738,22 @@ func between(s, start, end string) string {
 	return s[startIdx : startIdx+endIdx]
 }
 
-// generateSummaryFromHTML 从 HTML 生成摘要
-func generateSummaryFromHTML(htmlText string, maxLength int) string {
-	text := stripHTML(htmlText)
-	if len(text) <= maxLength {
-		return text
+// parseSummaryLength 解析 summary_length 参数，未指定或超出范围时返回 0（使用入库时生成的摘要）
+func parseSummaryLength(c *gin.Context) int {
+	length, err := strconv.Atoi(c.Query("summary_length"))
+	if err != nil || length < utils.MinSummaryLength || length > utils.MaxSummaryLength {
+		return 0
 	}
+	return length
+}
 
-	truncated := text[:maxLength]
-	lastSpace := strings.LastIndex(truncated, " ")
-	if lastSpace > 0 {
-		return truncated[:lastSpace] + "..."
+// articleSummary 按指定长度重新生成摘要（feed 自带人工摘要时优先使用，否则从正文生成）
+func articleSummary(xmlContent, content string, maxLength int) string {
+	desc, contentHTML, _ := parseXMLFields(xmlContent)
+	if content == "" {
+		content = contentHTML
 	}
-	return truncated + "..."
+	return utils.NewTextProcessor().SelectSummary(desc, content, maxLength)
 }
 
 // stripHTML 去掉 HTML 标签并做简单清洗
@@ -426,28 +816,19 @@ func countWordsFromHTML(htmlText string) int {
 func (h *ArticleHandler) MarkArticleRead(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的文章 ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
 		return
 	}
 
 	if err := h.db.MarkArticleAsRead(userID, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "操作失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
 		return
 	}
 
@@ -458,28 +839,19 @@ func (h *ArticleHandler) MarkArticleRead(c *gin.Context) {
 func (h *ArticleHandler) MarkArticleUnread(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的文章 ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
 		return
 	}
 
 	if err := h.db.MarkArticleAsUnread(userID, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "操作失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
 		return
 	}
 
@@ -490,28 +862,19 @@ func (h *ArticleHandler) MarkArticleUnread(c *gin.Context) {
 func (h *ArticleHandler) AddFavorite(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的文章 ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
 		return
 	}
 
 	if err := h.db.SetFavorite(userID, id, true); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "操作失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
 		return
 	}
 
@@ -522,28 +885,19 @@ func (h *ArticleHandler) AddFavorite(c *gin.Context) {
 func (h *ArticleHandler) RemoveFavorite(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的文章 ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
 		return
 	}
 
 	if err := h.db.SetFavorite(userID, id, false); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "操作失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
 		return
 	}
 
@@ -554,30 +908,21 @@ func (h *ArticleHandler) RemoveFavorite(c *gin.Context) {
 func (h *ArticleHandler) ToggleFavorite(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的文章 ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
 		return
 	}
 
 	// 使用 DB 层提供的 ToggleFavorite 方法
 	newState, err := h.db.ToggleFavorite(userID, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "操作失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
 		return
 	}
 
@@ -592,20 +937,14 @@ func (h *ArticleHandler) ToggleFavorite(c *gin.Context) {
 func (h *ArticleHandler) UpdateArticleProgress(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的文章 ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的文章 ID")
 		return
 	}
 
@@ -613,18 +952,12 @@ func (h *ArticleHandler) UpdateArticleProgress(c *gin.Context) {
 		Progress int `json:"progress"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的请求参数",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
 		return
 	}
 
 	if err := h.db.UpdateReadProgress(userID, id, req.Progress); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "操作失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
 		return
 	}
 
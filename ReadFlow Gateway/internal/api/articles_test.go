@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestGetRelatedArticlesByTitle(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := database.CreateSource("https://example.com/feed", "feed", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert := func(guid, title, tags string) int64 {
+		t.Helper()
+		res, err := database.Exec("INSERT INTO items (source_id, guid, title, xml_content, tags, published_at) VALUES (?, ?, ?, '', ?, ?)",
+			source.ID, guid, title, tags, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := res.LastInsertId()
+		if err := database.CreateUserDelivery(user.ID, id); err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	article := insert("a", "Apple releases new iPhone model", `["smartphones"]`)
+	byTag := insert("b", "Quarterly market report", `["smartphones"]`)
+	byTitle := insert("c", "Apple iPhone model released", `[]`)
+	insert("d", "Gardening tips for spring", `[]`)
+
+	gin.SetMode(gin.TestMode)
+	h := &ArticleHandler{db: database}
+	router := gin.New()
+	router.GET("/articles/:id/related", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, h.GetRelatedArticles)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/articles/"+strconv.FormatInt(article, 10)+"/related", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("related = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Articles []struct {
+			ID int64 `json:"id"`
+		} `json:"articles"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[int64]bool)
+	for _, a := range resp.Articles {
+		got[a.ID] = true
+	}
+	if len(got) != 2 || !got[byTag] || !got[byTitle] {
+		t.Errorf("related = %s, want items %d (tag) and %d (title)", rec.Body.String(), byTag, byTitle)
+	}
+}
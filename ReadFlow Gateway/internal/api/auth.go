@@ -1,29 +1,50 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/readflow/gateway/internal/config"
 	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/notify"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// passwordResetTTL 密码重置令牌有效期
+const passwordResetTTL = 30 * time.Minute
+
 // AuthService 认证服务
 type AuthService struct {
-	db     *db.DB
-	config *config.Config
+	db       *db.DB
+	config   *config.Config
+	notifier notify.Notifier // 发送密码重置令牌
+
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+
+	readOnlyRoutes map[string]bool // 只读 API 令牌可调用的路由（"METHOD 路由模板"），启动时由 ReadOnly 登记
 }
 
 // NewAuthService 创建认证服务
 func NewAuthService(database *db.DB, cfg *config.Config) *AuthService {
 	return &AuthService{
-		db:     database,
-		config: cfg,
+		db:       database,
+		config:   cfg,
+		notifier: notify.New(cfg.PasswordResetWebhook),
+
+		readOnlyRoutes: make(map[string]bool),
 	}
 }
 
@@ -40,7 +61,7 @@ type RegisterRequest struct {
 	Email    string `json:"email"` // 可选
 }
 
-// LoginResponse 登录响应
+// LoginResponse 登录响应（失败时使用 ErrorResponse）
 type LoginResponse struct {
 	Success bool   `json:"success"`
 	Token   string `json:"token,omitempty"`
@@ -48,6 +69,17 @@ type LoginResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// ForgotPasswordRequest 忘记密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
 // UpdateProfileRequest 更新用户资料请求
 type UpdateProfileRequest struct {
 	ReadingSettings           *string `json:"reading_settings"`
@@ -61,24 +93,41 @@ type UpdateProfileRequest struct {
 	ProxyModeEnabled          *bool   `json:"proxy_mode_enabled"`
 	ProxyServerURL            *string `json:"proxy_server_url"`
 	ProxyToken                *string `json:"proxy_token"`
+
+	// 乐观并发控制：客户端上次看到的偏好设置 updated_at，服务端已有更新的版本时返回 409；
+	// 不传时不检查（兼容旧客户端），force 为 true 时强制覆盖
+	UpdatedAt *int64 `json:"updated_at"`
+	Force     bool   `json:"force"`
+}
+
+// ProfileResponse 用户资料响应
+type ProfileResponse struct {
+	Success     bool               `json:"success"`
+	UserID      int64              `json:"user_id"`
+	Username    string             `json:"username"`
+	Email       string             `json:"email"`
+	IsAdmin     bool               `json:"is_admin"`
+	Preferences *db.UserPreference `json:"preferences"`
+	Quota       *UserQuota         `json:"quota"` // 订阅数和生词数的当前用量与上限
 }
 
 // Claims JWT 声明
 type Claims struct {
 	UserID   int64  `json:"user_id"`
 	Username string `json:"username"`
+	IsAdmin  bool   `json:"is_admin,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// AdminTokenCookie 管理后台网页使用的认证 Cookie 名
+const AdminTokenCookie = "readflow_admin_token"
+
 // Register 用户注册
 func (a *AuthService) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("[AUTH] Invalid register request: %v", err)
-		c.JSON(http.StatusBadRequest, LoginResponse{
-			Success: false,
-			Message: "无效的请求参数",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
 		return
 	}
 
@@ -88,10 +137,7 @@ func (a *AuthService) Register(c *gin.Context) {
 	hashedPassword, err := a.HashPassword(req.Password)
 	if err != nil {
 		log.Printf("[AUTH] Password hashing failed: %v", err)
-		c.JSON(http.StatusInternalServerError, LoginResponse{
-			Success: false,
-			Message: "密码处理失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "密码处理失败")
 		return
 	}
 
@@ -99,10 +145,7 @@ func (a *AuthService) Register(c *gin.Context) {
 	user, err := a.db.CreateUser(req.Username, req.Email, hashedPassword)
 	if err != nil {
 		log.Printf("[AUTH] Create user failed: %v", err)
-		c.JSON(http.StatusBadRequest, LoginResponse{
-			Success: false,
-			Message: "用户名或邮箱已存在",
-		})
+		respondError(c, http.StatusConflict, CodeConflict, "用户名或邮箱已存在")
 		return
 	}
 
@@ -120,10 +163,7 @@ func (a *AuthService) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("[AUTH] Invalid login request: %v", err)
-		c.JSON(http.StatusBadRequest, LoginResponse{
-			Success: false,
-			Message: "无效的请求参数",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
 		return
 	}
 
@@ -137,47 +177,43 @@ func (a *AuthService) Login(c *gin.Context) {
 		user, err = a.db.GetUserByEmail(req.Username)
 		if err != nil {
 			log.Printf("[AUTH] User not found by username or email: %s", req.Username)
-			c.JSON(http.StatusUnauthorized, LoginResponse{
-				Success: false,
-				Message: "用户名或密码错误",
-			})
+			// 同样执行一次 bcrypt 比较，响应时间与用户存在时一致，避免据此探测已注册的用户名
+			a.compareDummyHash(req.Password)
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "用户名或密码错误")
 			return
 		}
 	}
 
 	// 验证密码
-	if !a.CheckPasswordHash(req.Password, user.PasswordHash) {
-		log.Printf("[AUTH] Password mismatch for user: %s", user.Username)
-		// 兼容旧的全局密码模式 (如果用户没有设置密码哈希，且输入的是全局密码)
-		if user.PasswordHash == "" && req.Password == a.config.ServerPassword {
-			log.Printf("[AUTH] Legacy password matched for user: %s", user.Username)
-		} else {
-			c.JSON(http.StatusUnauthorized, LoginResponse{
-				Success: false,
-				Message: "用户名或密码错误",
-			})
+	if user.PasswordHash == "" {
+		// 兼容旧的全局密码模式（用户没有设置密码哈希，且输入的是全局密码）
+		a.compareDummyHash(req.Password)
+		if subtle.ConstantTimeCompare([]byte(req.Password), []byte(a.config.ServerPassword)) != 1 {
+			log.Printf("[AUTH] Password mismatch for user: %s", user.Username)
+			respondError(c, http.StatusUnauthorized, CodeUnauthorized, "用户名或密码错误")
 			return
 		}
+		log.Printf("[AUTH] Legacy password matched for user: %s", user.Username)
+	} else if !a.CheckPasswordHash(req.Password, user.PasswordHash) {
+		log.Printf("[AUTH] Password mismatch for user: %s", user.Username)
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "用户名或密码错误")
+		return
+	} else {
+		a.rehashPasswordIfNeeded(user, req.Password)
 	}
 
 	// 生成 JWT Token
-	token, err := a.GenerateToken(user.ID, user.Username)
+	token, err := a.GenerateToken(user.ID, user.Username, user.IsAdmin)
 	if err != nil {
 		log.Printf("[AUTH] Token generation failed: %v", err)
-		c.JSON(http.StatusInternalServerError, LoginResponse{
-			Success: false,
-			Message: "生成 Token 失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "生成 Token 失败")
 		return
 	}
 
 	// 更新用户 Token
 	if err := a.db.UpdateUserToken(user.ID, token); err != nil {
 		log.Printf("[AUTH] Update token failed: %v", err)
-		c.JSON(http.StatusInternalServerError, LoginResponse{
-			Success: false,
-			Message: "更新 Token 失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "更新 Token 失败")
 		return
 	}
 
@@ -190,23 +226,156 @@ func (a *AuthService) Login(c *gin.Context) {
 	})
 }
 
+// ForgotPassword 申请重置密码 POST /api/auth/forgot
+// 邮箱对应的用户存在时生成一次性重置令牌并通过通知渠道发送；无论邮箱是否注册都返回相同的成功响应，避免据此探测已注册的邮箱
+func (a *AuthService) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Email) == "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
+		return
+	}
+
+	if user, err := a.db.GetUserByEmail(strings.TrimSpace(req.Email)); err == nil {
+		a.issuePasswordReset(user)
+	} else {
+		log.Printf("[AUTH] Password reset requested for unknown email")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "如果该邮箱已注册，重置说明将很快送达",
+	})
+}
+
+// issuePasswordReset 生成并保存重置令牌，在后台发送通知；失败只记录日志，不改变接口响应
+func (a *AuthService) issuePasswordReset(user *db.User) {
+	token, err := generateSecretToken()
+	if err != nil {
+		log.Printf("[AUTH] Generate password reset token failed: %v", err)
+		return
+	}
+	expiresAt := time.Now().Add(passwordResetTTL)
+	if err := a.db.CreatePasswordReset(user.ID, hashSecretToken(token), expiresAt); err != nil {
+		log.Printf("[AUTH] Save password reset token failed for user %s: %v", user.Username, err)
+		return
+	}
+
+	// 后台发送，webhook 的耗时不体现在响应时间上
+	msg := notify.PasswordReset{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := a.notifier.SendPasswordReset(ctx, msg); err != nil {
+			log.Printf("[AUTH] Send password reset notification failed for user %s: %v", msg.Username, err)
+		}
+	}()
+	log.Printf("[AUTH] Password reset token issued for user %s", user.Username)
+}
+
+// ResetPassword 使用重置令牌设置新密码 POST /api/auth/reset
+// 令牌只能使用一次，成功后该用户的全部重置令牌失效
+func (a *AuthService) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
+		return
+	}
+
+	hashedPassword, err := a.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("[AUTH] Password hashing failed: %v", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "密码处理失败")
+		return
+	}
+
+	userID, err := a.db.ResetPasswordWithToken(hashSecretToken(strings.TrimSpace(req.Token)), hashedPassword)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidPasswordReset) {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "重置令牌无效或已过期")
+			return
+		}
+		log.Printf("[AUTH] Reset password failed: %v", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "重置密码失败")
+		return
+	}
+
+	log.Printf("[AUTH] Password reset for user %d", userID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "密码已重置，请使用新密码登录",
+	})
+}
+
+// generateSecretToken 生成 32 字节随机令牌的十六进制字符串
+func generateSecretToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashSecretToken 计算令牌（密码重置令牌、API 令牌）的 SHA-256，数据库只保存该值
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetProfile 获取用户资料、偏好设置和配额用量
+func (a *AuthService) GetProfile(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	user, err := a.db.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, CodeNotFound, "用户不存在")
+		return
+	}
+
+	pref, err := a.db.GetUserPreferences(userID)
+	if err != nil {
+		// 尚未保存过偏好设置
+		pref = &db.UserPreference{UserID: userID}
+	}
+
+	quota, err := loadUserQuota(a.db, userID)
+	if err != nil {
+		log.Printf("[AUTH] Failed to load quota for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询配额失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, ProfileResponse{
+		Success:     true,
+		UserID:      user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		IsAdmin:     user.IsAdmin,
+		Preferences: pref,
+		Quota:       quota,
+	})
+}
+
 // UpdateProfile 更新用户资料
 func (a *AuthService) UpdateProfile(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	var req UpdateProfileRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的请求参数",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
 		return
 	}
 
@@ -254,24 +423,44 @@ func (a *AuthService) UpdateProfile(c *gin.Context) {
 		pref.ProxyToken = *req.ProxyToken
 	}
 
-	if err := a.db.UpsertUserPreferences(pref); err != nil {
+	expectedUpdatedAt := req.UpdatedAt
+	if req.Force {
+		expectedUpdatedAt = nil
+	}
+	if err := a.db.UpsertUserPreferences(pref, expectedUpdatedAt); err != nil {
+		if errors.Is(err, db.ErrStalePreferences) {
+			// 返回服务端当前版本，由客户端合并后带上新的 updated_at 重新提交
+			current, err := a.db.GetUserPreferences(userID)
+			if err != nil {
+				log.Printf("[AUTH] Failed to load user preferences: %v", err)
+				respondError(c, http.StatusInternalServerError, CodeInternal, "查询配置失败")
+				return
+			}
+			respondErrorDetails(c, http.StatusConflict, CodeConflict, "配置已在其他设备上更新", current)
+			return
+		}
 		log.Printf("[AUTH] Failed to update user preferences: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "更新配置失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "更新配置失败")
+		return
+	}
+
+	saved, err := a.db.GetUserPreferences(userID)
+	if err != nil {
+		log.Printf("[AUTH] Failed to load user preferences: %v", err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询配置失败")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "配置已更新",
+		"success":     true,
+		"message":     "配置已更新",
+		"preferences": saved, // updated_at 为新版本号，下次提交时带上
 	})
 }
 
 // HashPassword 生成密码哈希
 func (a *AuthService) HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), a.config.GetBcryptCost())
 	return string(bytes), err
 }
 
@@ -281,11 +470,46 @@ func (a *AuthService) CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// compareDummyHash 与固定哈希做一次 bcrypt 比较，耗时与校验真实密码相同，结果丢弃
+// 固定哈希按当前配置的成本首次使用时生成
+func (a *AuthService) compareDummyHash(password string) {
+	a.dummyHashOnce.Do(func() {
+		hash, err := bcrypt.GenerateFromPassword([]byte("readflow-dummy-password"), a.config.GetBcryptCost())
+		if err != nil {
+			log.Printf("[AUTH] Generate dummy password hash failed: %v", err)
+			return
+		}
+		a.dummyHash = hash
+	})
+	bcrypt.CompareHashAndPassword(a.dummyHash, []byte(password))
+}
+
+// rehashPasswordIfNeeded 已有哈希的成本与配置不同时，用登录时验证过的明文按当前成本重新哈希
+// 调整 BCRYPT_COST 后用户无需重置密码，下次登录即完成迁移；失败只记录日志，不影响登录
+func (a *AuthService) rehashPasswordIfNeeded(user *db.User, password string) {
+	cost, err := bcrypt.Cost([]byte(user.PasswordHash))
+	if err != nil || cost == a.config.GetBcryptCost() {
+		return
+	}
+
+	hash, err := a.HashPassword(password)
+	if err != nil {
+		log.Printf("[AUTH] Rehash password failed for user %s: %v", user.Username, err)
+		return
+	}
+	if err := a.db.UpdateUserPasswordHash(user.ID, hash); err != nil {
+		log.Printf("[AUTH] Update password hash failed for user %s: %v", user.Username, err)
+		return
+	}
+	log.Printf("[AUTH] Rehashed password for user %s (bcrypt cost %d -> %d)", user.Username, cost, a.config.GetBcryptCost())
+}
+
 // GenerateToken 生成 JWT Token
-func (a *AuthService) GenerateToken(userID int64, username string) (string, error) {
+func (a *AuthService) GenerateToken(userID int64, username string, isAdmin bool) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
+		IsAdmin:  isAdmin,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(30 * 24 * time.Hour)), // 30 天有效期
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -318,15 +542,12 @@ func (a *AuthService) ValidateToken(tokenString string) (*Claims, error) {
 }
 
 // AuthMiddleware 认证中间件
+// 接受登录返回的 JWT 和用户创建的 API 令牌
 func (a *AuthService) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "缺少认证信息",
-			})
-			c.Abort()
+			AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "缺少认证信息")
 			return
 		}
 
@@ -336,25 +557,95 @@ func (a *AuthService) AuthMiddleware() gin.HandlerFunc {
 			tokenString = authHeader[7:]
 		}
 
+		// API 令牌（rf_ 开头）按哈希查找所属用户
+		if strings.HasPrefix(tokenString, apiTokenPrefix) {
+			if a.authenticateAPIToken(c, tokenString) {
+				c.Next()
+			}
+			return
+		}
+
 		// 验证 Token
 		claims, err := a.ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"message": "无效的认证信息",
-			})
-			c.Abort()
+			AbortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "无效的认证信息")
 			return
 		}
 
 		// 将用户信息存入上下文
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("is_admin", claims.IsAdmin)
+
+		c.Next()
+	}
+}
+
+// AdminMiddleware 管理 API 认证中间件（非管理员返回 403）
+func (a *AuthService) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status, message := a.authorizeAdmin(c); status != http.StatusOK {
+			AbortWithError(c, status, codeForStatus(status), message)
+			return
+		}
+		c.Next()
+	}
+}
 
+// AdminPageMiddleware 管理后台网页认证中间件（未通过时跳转到登录页）
+func (a *AuthService) AdminPageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if status, _ := a.authorizeAdmin(c); status != http.StatusOK {
+			target := "/admin/login"
+			if status == http.StatusForbidden {
+				target += "?reason=forbidden"
+			}
+			c.Redirect(http.StatusFound, target)
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
+// authorizeAdmin 校验管理员身份，返回 HTTP 状态码和错误信息
+// 支持 Authorization 头或管理后台 Cookie；应急令牌（ADMIN_TOKEN）直接放行
+// 管理员标记以数据库为准，撤销管理员后旧 Token 立即失效
+func (a *AuthService) authorizeAdmin(c *gin.Context) (int, string) {
+	tokenString := c.GetHeader("Authorization")
+	if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+		tokenString = tokenString[7:]
+	}
+	if tokenString == "" {
+		tokenString, _ = c.Cookie(AdminTokenCookie)
+	}
+	if tokenString == "" {
+		return http.StatusUnauthorized, "缺少认证信息"
+	}
+
+	if a.config.AdminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(tokenString), []byte(a.config.AdminToken)) == 1 {
+		c.Set("is_admin", true)
+		return http.StatusOK, ""
+	}
+
+	claims, err := a.ValidateToken(tokenString)
+	if err != nil {
+		return http.StatusUnauthorized, "无效的认证信息"
+	}
+
+	user, err := a.db.GetUserByID(claims.UserID)
+	if err != nil || !user.IsAdmin {
+		log.Printf("[AUTH] Admin access denied for user id=%d", claims.UserID)
+		return http.StatusForbidden, "需要管理员权限"
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("is_admin", true)
+	return http.StatusOK, ""
+}
+
 // GetCurrentUserID 从上下文获取当前用户 ID
 func GetCurrentUserID(c *gin.Context) (int64, error) {
 	userID, exists := c.Get("user_id")
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/notify"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// login 通过 Login 接口登录，返回响应状态码
+func login(t *testing.T, auth *AuthService, username, password string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/login", auth.Login)
+
+	body := `{"username":"` + username + `","password":"` + password + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// postJSON 向指定接口发送 JSON 请求，返回响应状态码
+func postJSON(t *testing.T, handler gin.HandlerFunc, body string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", handler)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// chanNotifier 把密码重置通知转发到 channel
+type chanNotifier chan notify.PasswordReset
+
+func (n chanNotifier) SendPasswordReset(ctx context.Context, msg notify.PasswordReset) error {
+	n <- msg
+	return nil
+}
+
+func TestLoginRehashesPasswordWithConfiguredCost(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	user, err := database.CreateUser("alice", "alice@example.com", string(oldHash))
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	auth := NewAuthService(database, &config.Config{JWTSecret: "test", BcryptCost: bcrypt.MinCost + 1})
+
+	// 密码错误时不重新哈希
+	if code := login(t, auth, "alice", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("login with wrong password = %d, want 401", code)
+	}
+	stored, err := database.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if stored.PasswordHash != string(oldHash) {
+		t.Fatalf("password hash changed after failed login")
+	}
+
+	if code := login(t, auth, "alice", "secret"); code != http.StatusOK {
+		t.Fatalf("login = %d, want 200", code)
+	}
+	stored, err = database.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if cost, err := bcrypt.Cost([]byte(stored.PasswordHash)); err != nil || cost != bcrypt.MinCost+1 {
+		t.Fatalf("cost after login = %d (%v), want %d", cost, err, bcrypt.MinCost+1)
+	}
+	if !auth.CheckPasswordHash("secret", stored.PasswordHash) {
+		t.Fatalf("rehashed password does not verify")
+	}
+
+	// 成本一致时不再重新哈希
+	rehashed := stored.PasswordHash
+	if code := login(t, auth, "alice", "secret"); code != http.StatusOK {
+		t.Fatalf("second login = %d, want 200", code)
+	}
+	stored, err = database.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if stored.PasswordHash != rehashed {
+		t.Errorf("password hash changed although cost matches")
+	}
+}
+
+func TestLoginUnknownUserRunsBcrypt(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	if _, err := database.CreateUser("legacy", "legacy@example.com", ""); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	auth := NewAuthService(database, &config.Config{JWTSecret: "test", ServerPassword: "global", BcryptCost: bcrypt.MinCost + 1})
+
+	if code := login(t, auth, "nobody", "secret"); code != http.StatusUnauthorized {
+		t.Fatalf("login unknown user = %d, want 401", code)
+	}
+	// 用户不存在时也按配置的成本执行了一次 bcrypt 比较
+	if cost, err := bcrypt.Cost(auth.dummyHash); err != nil || cost != bcrypt.MinCost+1 {
+		t.Errorf("dummy hash cost = %d (%v), want %d", cost, err, bcrypt.MinCost+1)
+	}
+
+	// 旧的全局密码模式仍然可用
+	if code := login(t, auth, "legacy", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("legacy login with wrong password = %d, want 401", code)
+	}
+	if code := login(t, auth, "legacy", "global"); code != http.StatusOK {
+		t.Errorf("legacy login = %d, want 200", code)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	auth := NewAuthService(database, &config.Config{JWTSecret: "test", BcryptCost: bcrypt.MinCost})
+	notifications := make(chanNotifier, 4)
+	auth.notifier = notifications
+
+	hash, _ := auth.HashPassword("old")
+	if _, err := database.CreateUser("alice", "alice@example.com", hash); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	// 未注册的邮箱同样返回成功，但不发送通知
+	if code := postJSON(t, auth.ForgotPassword, `{"email":"nobody@example.com"}`); code != http.StatusOK {
+		t.Fatalf("forgot unknown email = %d, want 200", code)
+	}
+	if code := postJSON(t, auth.ForgotPassword, `{"email":"alice@example.com"}`); code != http.StatusOK {
+		t.Fatalf("forgot = %d, want 200", code)
+	}
+	first := <-notifications
+	if first.Username != "alice" || first.Token == "" {
+		t.Fatalf("notification = %+v, want token for alice", first)
+	}
+
+	// 再次申请后旧令牌失效
+	postJSON(t, auth.ForgotPassword, `{"email":"alice@example.com"}`)
+	second := <-notifications
+	if code := postJSON(t, auth.ResetPassword, `{"token":"`+first.Token+`","password":"new"}`); code != http.StatusBadRequest {
+		t.Errorf("reset with superseded token = %d, want 400", code)
+	}
+
+	if code := postJSON(t, auth.ResetPassword, `{"token":"`+second.Token+`","password":"new"}`); code != http.StatusOK {
+		t.Fatalf("reset = %d, want 200", code)
+	}
+	if code := login(t, auth, "alice", "new"); code != http.StatusOK {
+		t.Errorf("login with new password = %d, want 200", code)
+	}
+	if code := login(t, auth, "alice", "old"); code != http.StatusUnauthorized {
+		t.Errorf("login with old password = %d, want 401", code)
+	}
+
+	// 令牌只能使用一次
+	if code := postJSON(t, auth.ResetPassword, `{"token":"`+second.Token+`","password":"again"}`); code != http.StatusBadRequest {
+		t.Errorf("reuse token = %d, want 400", code)
+	}
+}
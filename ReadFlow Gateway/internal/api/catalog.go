@@ -0,0 +1,118 @@
+package api
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultCatalog 内置的推荐订阅源目录
+//
+//go:embed catalog.json
+var defaultCatalog []byte
+
+// CatalogSource 推荐订阅源
+// category / language 与 sources 表的同名列取值一致
+type CatalogSource struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Category    string `json:"category"`
+	Language    string `json:"language"`
+	Description string `json:"description"`
+}
+
+// CatalogHandler 订阅源目录处理器
+// 目录在启动时加载，之后只读，因此可以安全地被并发访问
+type CatalogHandler struct {
+	sources    []CatalogSource
+	categories []string
+	languages  []string
+	etag       string
+}
+
+// NewCatalogHandler 创建订阅源目录处理器
+// path 为空时使用内置目录；自定义目录加载失败时回退到内置目录
+func NewCatalogHandler(path string) *CatalogHandler {
+	data := defaultCatalog
+	if path != "" {
+		if custom, err := os.ReadFile(path); err != nil {
+			log.Printf("[Catalog] Failed to read %s, using built-in catalog: %v", path, err)
+		} else {
+			data = custom
+		}
+	}
+
+	var sources []CatalogSource
+	if err := json.Unmarshal(data, &sources); err != nil {
+		log.Printf("[Catalog] Invalid catalog JSON, using built-in catalog: %v", err)
+		data = defaultCatalog
+		sources = nil
+		_ = json.Unmarshal(data, &sources)
+	}
+
+	h := &CatalogHandler{
+		sources: sources,
+		etag:    fmt.Sprintf(`"%x"`, sha256.Sum256(data)),
+	}
+	h.categories = distinctValues(sources, func(s CatalogSource) string { return s.Category })
+	h.languages = distinctValues(sources, func(s CatalogSource) string { return s.Language })
+
+	log.Printf("[Catalog] Loaded %d catalog sources", len(sources))
+	return h
+}
+
+// GetCatalog 获取推荐订阅源目录 GET /api/sources/catalog
+// 参数：category、language（均不区分大小写）
+// 无需认证；目录只在重启时变化，响应带 ETag 供客户端和中间缓存复用
+func (h *CatalogHandler) GetCatalog(c *gin.Context) {
+	category := strings.TrimSpace(c.Query("category"))
+	language := strings.TrimSpace(c.Query("language"))
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Header("ETag", h.etag)
+	if c.GetHeader("If-None-Match") == h.etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	sources := make([]CatalogSource, 0, len(h.sources))
+	for _, s := range h.sources {
+		if category != "" && !strings.EqualFold(s.Category, category) {
+			continue
+		}
+		if language != "" && !strings.EqualFold(s.Language, language) {
+			continue
+		}
+		sources = append(sources, s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"sources":    sources,
+		"total":      len(sources),
+		"categories": h.categories,
+		"languages":  h.languages,
+	})
+}
+
+// distinctValues 提取目录中某个字段的去重取值（按字母排序）
+func distinctValues(sources []CatalogSource, field func(CatalogSource) string) []string {
+	seen := make(map[string]bool)
+	values := []string{}
+	for _, s := range sources {
+		if v := field(s); v != "" && !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
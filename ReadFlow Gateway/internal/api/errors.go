@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode 错误响应中的机器可读错误码，取值稳定，客户端可据此分支或自行本地化提示
+// 完整列表见 docs/error_codes.md
+type ErrorCode string
+
+// 错误码
+const (
+	CodeValidationFailed ErrorCode = "VALIDATION_FAILED" // 400 请求参数或请求体无效
+	CodeUnauthorized     ErrorCode = "UNAUTHORIZED"      // 401 缺少或无效的认证信息
+	CodeForbidden        ErrorCode = "FORBIDDEN"         // 403 已认证但无权限
+	CodeNotFound         ErrorCode = "NOT_FOUND"         // 404 资源不存在
+	CodeConflict         ErrorCode = "CONFLICT"          // 409 与当前状态冲突（重复注册、刷新进行中等）
+	CodeQuotaExceeded    ErrorCode = "QUOTA_EXCEEDED"    // 409 超出用户的订阅数或生词数配额
+	CodeInvalidFeed      ErrorCode = "INVALID_FEED"      // 422 地址可访问但不是有效的订阅源
+	CodeNoContent        ErrorCode = "NO_CONTENT"        // 422 页面中没有可提取的正文
+	CodeRateLimited      ErrorCode = "RATE_LIMITED"      // 429 请求频率超限
+	CodeInternal         ErrorCode = "INTERNAL_ERROR"    // 500 服务端内部错误
+	CodeUpstreamError    ErrorCode = "UPSTREAM_ERROR"    // 502 源站或图片服务器请求失败
+	CodeUnavailable      ErrorCode = "UNAVAILABLE"       // 503 依赖的服务暂不可用
+	CodeUpstreamTimeout  ErrorCode = "UPSTREAM_TIMEOUT"  // 504 源站响应超时
+)
+
+// ErrorResponse 统一错误响应
+// success / message 与成功响应保持一致，code 供客户端分支判断
+type ErrorResponse struct {
+	Success bool        `json:"success"`
+	Code    ErrorCode   `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"` // 附加信息，例如逐字段的校验错误
+}
+
+// respondError 写入统一格式的错误响应
+func respondError(c *gin.Context, status int, code ErrorCode, message string) {
+	c.JSON(status, ErrorResponse{Code: code, Message: message})
+}
+
+// respondErrorDetails 写入带附加信息的错误响应
+func respondErrorDetails(c *gin.Context, status int, code ErrorCode, message string, details interface{}) {
+	c.JSON(status, ErrorResponse{Code: code, Message: message, Details: details})
+}
+
+// AbortWithError 写入统一格式的错误响应并中止后续处理（供中间件使用）
+func AbortWithError(c *gin.Context, status int, code ErrorCode, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Code: code, Message: message})
+}
+
+// codeForStatus 状态码由下层决定时推导对应的错误码
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeValidationFailed
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeInvalidFeed
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeUpstreamError
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeUpstreamTimeout
+	default:
+		return CodeInternal
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"regexp/syntax"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// 过滤规则测试的输入限制
+const (
+	maxFilterKeywordLen = utils.MaxRegexPatternLen
+	maxFilterTextLen    = 100 << 10
+	filterMatchTimeout  = time.Second
+)
+
+// FilterHandler 过滤规则处理器
+type FilterHandler struct{}
+
+// NewFilterHandler 创建过滤规则处理器
+func NewFilterHandler() *FilterHandler {
+	return &FilterHandler{}
+}
+
+// FilterTestRequest 过滤规则测试请求
+type FilterTestRequest struct {
+	Keyword string `json:"keyword" binding:"required"`
+	IsRegex bool   `json:"is_regex"`
+	Text    string `json:"text"`
+}
+
+// FilterTestResponse 过滤规则测试响应
+// start / end 为匹配片段在 text 中的字符（Unicode 码点）位置，未匹配时省略
+type FilterTestResponse struct {
+	Success bool    `json:"success"`
+	Matched bool    `json:"matched"`
+	Match   *string `json:"match,omitempty"`
+	Start   *int    `json:"start,omitempty"`
+	End     *int    `json:"end,omitempty"`
+}
+
+// TestRule 测试过滤规则是否匹配给定文本 POST /api/filters/test
+// 关键词按不区分大小写的子串匹配；正则使用 RE2 语法（不支持回溯引用和环视），匹配时间与文本长度线性相关，
+// 过于复杂的正则（如大量计数重复）直接拒绝，另设 1 秒超时兜底。不读取数据库，可用于规则编辑器的即时校验
+func (h *FilterHandler) TestRule(c *gin.Context) {
+	var req FilterTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误，keyword 不能为空")
+		return
+	}
+	if utf8.RuneCountInString(req.Keyword) > maxFilterKeywordLen {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "keyword 不能超过 500 个字符")
+		return
+	}
+	if len(req.Text) > maxFilterTextLen {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "text 不能超过 100KB")
+		return
+	}
+
+	span, err := matchFilterRule(req.Keyword, req.IsRegex, req.Text)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		switch {
+		case errors.As(err, &syntaxErr):
+			respondErrorDetails(c, http.StatusBadRequest, CodeValidationFailed, "无效的正则表达式", gin.H{
+				"error": string(syntaxErr.Code),
+				"expr":  syntaxErr.Expr,
+			})
+		case errors.Is(err, utils.ErrRegexTooComplex):
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "正则表达式过于复杂，请减少重复次数或分支")
+		case errors.Is(err, utils.ErrRegexTimeout):
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "匹配超时，请简化正则表达式或缩短文本")
+		default:
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的正则表达式")
+		}
+		return
+	}
+
+	resp := FilterTestResponse{Success: true, Matched: span != nil}
+	if span != nil {
+		match := req.Text[span[0]:span[1]]
+		start := utf8.RuneCountInString(req.Text[:span[0]])
+		end := start + utf8.RuneCountInString(match)
+		resp.Match, resp.Start, resp.End = &match, &start, &end
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// matchFilterRule 按过滤规则匹配文本，返回首个匹配片段的字节位置，未匹配时为 nil
+// 关键词转义后按不区分大小写的正则匹配，与正则规则共用同一匹配路径；正则经 utils.CompileRegex 校验复杂度
+func matchFilterRule(keyword string, isRegex bool, text string) ([]int, error) {
+	var re *regexp.Regexp
+	var err error
+	if isRegex {
+		re, err = utils.CompileRegex(keyword)
+	} else {
+		re, err = regexp.Compile("(?i)" + regexp.QuoteMeta(keyword))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), filterMatchTimeout)
+	defer cancel()
+	return utils.FindRegexIndex(ctx, re, text)
+}
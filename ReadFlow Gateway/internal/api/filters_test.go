@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testFilterRule 调用 TestRule 接口，返回状态码和响应体
+func testFilterRule(t *testing.T, body string) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/filters/test", NewFilterHandler().TestRule)
+
+	req := httptest.NewRequest(http.MethodPost, "/filters/test", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestFilterTestRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		matched   bool
+		match     string
+		start     float64
+		end       float64
+		wantError bool
+	}{
+		{name: "keyword ignores case", body: `{"keyword":"apple","text":"New APPLE phone"}`, matched: true, match: "APPLE", start: 4, end: 9},
+		{name: "keyword is literal", body: `{"keyword":"a.c","text":"abc"}`, matched: false},
+		{name: "regex span in characters", body: `{"keyword":"广告\\d+","is_regex":true,"text":"今日广告12条"}`, matched: true, match: "广告12", start: 2, end: 6},
+		{name: "regex no match", body: `{"keyword":"^Pro$","is_regex":true,"text":"iPhone Pro"}`, matched: false},
+		{name: "invalid regex", body: `{"keyword":"(abc","is_regex":true,"text":"abc"}`, wantError: true},
+		{name: "lookahead unsupported", body: `{"keyword":"a(?=b)","is_regex":true,"text":"ab"}`, wantError: true},
+		{name: "missing keyword", body: `{"text":"abc"}`, wantError: true},
+		{name: "regex too complex", body: `{"keyword":"[a-z]{1000}[0-9]{1000}","is_regex":true,"text":"abc"}`, wantError: true},
+		{name: "nested repetition", body: `{"keyword":"(a+)+$","is_regex":true,"text":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa!"}`, matched: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := testFilterRule(t, tt.body)
+			if tt.wantError {
+				if code != http.StatusBadRequest || resp["code"] != string(CodeValidationFailed) {
+					t.Fatalf("got %d %v, want 400 %s", code, resp, CodeValidationFailed)
+				}
+				return
+			}
+			if code != http.StatusOK || resp["matched"] != tt.matched {
+				t.Fatalf("got %d %v, want matched=%v", code, resp, tt.matched)
+			}
+			if !tt.matched {
+				if _, ok := resp["match"]; ok {
+					t.Errorf("unexpected match in %v", resp)
+				}
+				return
+			}
+			if resp["match"] != tt.match || resp["start"] != tt.start || resp["end"] != tt.end {
+				t.Errorf("got match=%v [%v, %v], want %q [%v, %v]", resp["match"], resp["start"], resp["end"], tt.match, tt.start, tt.end)
+			}
+		})
+	}
+}
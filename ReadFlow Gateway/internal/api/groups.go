@@ -0,0 +1,190 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+)
+
+// maxGroupAssignments 一次调整分组的订阅数上限
+const maxGroupAssignments = 500
+
+// GroupHandler 订阅源分组处理器
+type GroupHandler struct {
+	db *db.DB
+}
+
+// NewGroupHandler 创建分组处理器
+func NewGroupHandler(database *db.DB) *GroupHandler {
+	return &GroupHandler{db: database}
+}
+
+// CreateGroupRequest 创建分组请求
+type CreateGroupRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Icon  string `json:"icon"`
+	Color string `json:"color"`
+}
+
+// GroupSourcesRequest 将多个订阅源移入同一分组的请求
+type GroupSourcesRequest struct {
+	SourceIDs []int64 `json:"source_ids" binding:"required"`
+}
+
+// SubscriptionGroupsRequest 批量调整订阅分组的请求，group_id 为 null 表示移出分组
+type SubscriptionGroupsRequest struct {
+	Assignments []db.GroupAssignment `json:"assignments" binding:"required"`
+}
+
+// ListGroups 获取用户的分组列表
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	groups, err := h.db.GetUserGroups(userID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, completePage(c, groups, len(groups), gin.H{"groups": groups}))
+}
+
+// CreateGroup 创建分组
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	var req CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
+		return
+	}
+
+	group, err := h.db.CreateGroup(userID, strings.TrimSpace(req.Name), req.Icon, req.Color)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "创建分组失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"group":   group,
+	})
+}
+
+// DeleteGroup 删除分组，组内订阅变为未分组
+func (h *GroupHandler) DeleteGroup(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的分组 ID")
+		return
+	}
+
+	if err := h.db.DeleteGroup(userID, groupID); err != nil {
+		if err == sql.ErrNoRows {
+			respondError(c, http.StatusNotFound, CodeNotFound, "分组不存在")
+			return
+		}
+		respondError(c, http.StatusInternalServerError, CodeInternal, "删除分组失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "分组已删除",
+	})
+}
+
+// SetGroupSources 将多个订阅源移入指定分组（一个事务内完成）
+// PUT /api/groups/:id/sources，不在列表中的组内订阅保持不变
+func (h *GroupHandler) SetGroupSources(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	groupID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的分组 ID")
+		return
+	}
+
+	var req GroupSourcesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
+		return
+	}
+
+	assignments := make([]db.GroupAssignment, 0, len(req.SourceIDs))
+	for _, sourceID := range req.SourceIDs {
+		assignments = append(assignments, db.GroupAssignment{SourceID: sourceID, GroupID: &groupID})
+	}
+	h.applyAssignments(c, userID, assignments)
+}
+
+// SetSubscriptionGroups 批量调整订阅的分组（一个事务内完成）
+// PUT /api/subscriptions/groups，适用于拖拽排序等一次移动多个源到不同分组的场景
+func (h *GroupHandler) SetSubscriptionGroups(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	var req SubscriptionGroupsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
+		return
+	}
+
+	h.applyAssignments(c, userID, req.Assignments)
+}
+
+// applyAssignments 校验并写入分组调整，返回调整后的归属
+func (h *GroupHandler) applyAssignments(c *gin.Context, userID int64, assignments []db.GroupAssignment) {
+	if len(assignments) == 0 {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "订阅列表不能为空")
+		return
+	}
+	if len(assignments) > maxGroupAssignments {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "一次最多调整 500 个订阅")
+		return
+	}
+
+	updated, err := h.db.SetSubscriptionGroups(userID, assignments)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrGroupNotFound):
+			respondError(c, http.StatusNotFound, CodeNotFound, "分组不存在")
+		case errors.Is(err, db.ErrNotSubscribed):
+			respondError(c, http.StatusNotFound, CodeNotFound, "存在未订阅的源")
+		default:
+			respondError(c, http.StatusInternalServerError, CodeInternal, "调整分组失败")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"assignments": updated,
+	})
+}
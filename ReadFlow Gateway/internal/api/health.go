@@ -0,0 +1,16 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthCheck 健康检查 GET/HEAD /health，time 为服务器当前时间
+func HealthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"time":   newTimeFormat(c).at(time.Now()),
+	})
+}
@@ -0,0 +1,376 @@
+package api
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/image"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// 图片代理默认限制（环境变量未设置或无效时使用）
+const (
+	defaultImageProxyTimeout  = 30 * time.Second
+	defaultImageProxyMaxBytes = 20 << 20
+)
+
+// ImageProxyHandler 图片代理处理器
+// image_mode=proxy 的源不在服务端缓存图片，正文中的图片经此接口实时转发
+type ImageProxyHandler struct {
+	client   *http.Client
+	key      string            // 地址签名密钥
+	hosts    *utils.HostPolicy // 主机允许/禁止列表（RSS_ALLOWED_HOSTS / RSS_DENIED_HOSTS），nil 表示不限制
+	timeout  time.Duration
+	maxBytes int64
+	accept   string   // 客户端未发送 Accept 时转发给源站的 Accept 头
+	formats  []string // 转码目标格式（按优先级），为空时原样转发
+}
+
+// imageProxyLimitError 触发代理限制（重定向、大小等）的错误，原因会返回给客户端
+type imageProxyLimitError struct {
+	reason string
+}
+
+func (e *imageProxyLimitError) Error() string {
+	return e.reason
+}
+
+// NewImageProxyHandler 创建图片代理处理器，proxy 为出站代理（nil 表示直连）
+// 时限、重定向次数和大小上限取自 IMAGE_PROXY_* 环境变量
+func NewImageProxyHandler(proxy *url.URL, cfg *config.Config) *ImageProxyHandler {
+	timeout := time.Duration(cfg.ImageProxyTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultImageProxyTimeout
+	}
+	maxRedirects := cfg.ImageProxyMaxRedirects
+	if maxRedirects < 0 {
+		maxRedirects = 0
+	}
+	maxBytes := int64(cfg.ImageProxyMaxBytes)
+	if maxBytes <= 0 {
+		maxBytes = defaultImageProxyMaxBytes
+	}
+
+	var formats []string
+	for _, format := range cfg.GetImageProxyTranscodeFormats() {
+		if format != image.FormatWebP && format != image.FormatAVIF {
+			log.Printf("[ImageProxy] Ignoring unsupported IMAGE_PROXY_TRANSCODE format %q", format)
+			continue
+		}
+		formats = append(formats, format)
+	}
+
+	hosts := utils.NewHostPolicy(cfg.GetRSSAllowedHosts(), cfg.GetRSSDeniedHosts())
+	return &ImageProxyHandler{
+		key:      cfg.GetImageProxyKey(),
+		hosts:    hosts,
+		timeout:  timeout,
+		maxBytes: maxBytes,
+		accept:   cfg.ImageProxyAccept,
+		formats:  formats,
+		client: utils.NewHTTPClient(utils.HTTPClientOptions{
+			Timeout:    timeout,
+			Proxy:      proxy,
+			PublicOnly: true,
+			// 每一跳都重新校验，避免被重定向到 file:、data: 等非 HTTP 地址或内网地址
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return &imageProxyLimitError{fmt.Sprintf("重定向次数超过上限 %d", maxRedirects)}
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return &imageProxyLimitError{fmt.Sprintf("不允许重定向到 %s: 地址", req.URL.Scheme)}
+				}
+				if !hosts.Allows(req.URL.Hostname()) {
+					return &imageProxyLimitError{"不允许重定向到该主机"}
+				}
+				if err := utils.CheckPublicHost(req.Context(), req.URL.Hostname()); err != nil {
+					if errors.Is(err, utils.ErrPrivateAddress) {
+						return &imageProxyLimitError{"不允许重定向到内网地址"}
+					}
+					return err
+				}
+				return nil
+			},
+		}),
+	}
+}
+
+// HandleImage 代理获取图片 GET/HEAD /api/image?url=&sig=
+// <img> 标签无法携带 Authorization 头，因此该接口不需要认证，
+// 改为只转发由服务端改写正文时签名的地址（见 image.ProxyPath），且只连接公网地址
+// 支持条件请求：源站返回 304 时原样转给客户端
+// 客户端的 Accept 头转发给源站协商格式；配置了 IMAGE_PROXY_TRANSCODE 时，源站只返回 JPEG/PNG 的图片转码为客户端支持的格式
+func (h *ImageProxyHandler) HandleImage(c *gin.Context) {
+	imageURL := strings.TrimSpace(c.Query("url"))
+	if strings.HasPrefix(imageURL, "//") {
+		imageURL = "https:" + imageURL
+	}
+	if !utils.VerifyURLSignature(h.key, imageURL, c.Query("sig")) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "图片地址签名无效")
+		return
+	}
+
+	u, err := url.Parse(imageURL)
+	if imageURL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "url 参数无效")
+		return
+	}
+	if !h.hosts.Allows(u.Hostname()) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "不允许代理该主机的图片")
+		return
+	}
+
+	// 源站没有 ETag 时使用按地址生成的 ETag，客户端带回时无需再请求源站
+	fallbackETag := urlETag(u.String())
+	target := h.transcodeTarget(c.GetHeader("Accept"))
+	for _, etag := range []string{fallbackETag, variantETag(fallbackETag, target)} {
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Header("ETag", etag)
+			c.Header("Cache-Control", "public, max-age=86400")
+			c.Header("Vary", "Accept")
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	if err := h.streamImage(c, u.String(), fallbackETag, target); err != nil {
+		log.Printf("[ImageProxy] Failed to proxy %s: %v", imageURL, err)
+		// 已开始输出图片时无法再返回错误响应
+		if !c.Writer.Written() {
+			status, code, message := h.failureReason(err)
+			respondError(c, status, code, message)
+		}
+	}
+}
+
+// streamImage 请求源站并将图片流式转发给客户端
+// HEAD 请求只返回响应头；客户端的 Accept、If-None-Match、If-Modified-Since 会转发给源站
+// target 不为空且源站返回 JPEG/PNG 时转码为该格式，ETag 带上格式后缀以区分原图
+// 可能转码时 HEAD 也向源站发 GET 并实际转码，保证响应头与 GET 一致
+func (h *ImageProxyHandler) streamImage(c *gin.Context, imageURL, fallbackETag, target string) error {
+	head := c.Request.Method == http.MethodHead
+	method := http.MethodGet
+	if head && target == "" {
+		method = http.MethodHead
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), method, imageURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		accept = h.accept
+	}
+	req.Header.Set("Accept", accept)
+	if referer := image.RefererFor(imageURL); referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	if value := c.GetHeader("If-None-Match"); value != "" {
+		// 转码结果的 ETag 去掉格式后缀后交给源站比较
+		req.Header.Set("If-None-Match", stripVariantETags(value, target))
+	}
+	if value := c.GetHeader("If-Modified-Since"); value != "" {
+		req.Header.Set("If-Modified-Since", value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		etag = fallbackETag
+	}
+
+	lastModified := resp.Header.Get("Last-Modified")
+
+	if resp.StatusCode == http.StatusNotModified {
+		// 客户端缓存的是转码结果时返回转码结果的 ETag
+		if variant := variantETag(etag, target); etagMatches(c.GetHeader("If-None-Match"), variant) {
+			etag = variant
+		}
+		writeImageCacheHeaders(c, etag, lastModified)
+		c.Status(http.StatusNotModified)
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	// 只转发图片，避免被当作通用代理
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(strings.ToLower(contentType), "image/") {
+		return fmt.Errorf("unexpected content type %q", contentType)
+	}
+	if resp.ContentLength > h.maxBytes {
+		return &imageProxyLimitError{fmt.Sprintf("图片大小超过上限 %d 字节", h.maxBytes)}
+	}
+
+	if target != "" && transcodable(contentType) {
+		return h.transcodeImage(c, resp, head, target, etag, lastModified)
+	}
+
+	c.Header("Content-Type", contentType)
+	if contentLength := resp.Header.Get("Content-Length"); contentLength != "" {
+		c.Header("Content-Length", contentLength)
+	}
+	writeImageCacheHeaders(c, etag, lastModified)
+	c.Status(http.StatusOK)
+
+	if head {
+		return nil
+	}
+	// 源站未声明长度时边转发边计数，超过上限即中断
+	n, err := io.Copy(c.Writer, io.LimitReader(resp.Body, h.maxBytes+1))
+	if err == nil && n > h.maxBytes {
+		return &imageProxyLimitError{fmt.Sprintf("图片大小超过上限 %d 字节", h.maxBytes)}
+	}
+	return err
+}
+
+// transcodeImage 读取源站图片并转码为 target 格式后返回；转码失败或结果不比原图小时返回原图
+// head 为 true 时只写响应头，与 GET 返回的格式、长度和 ETag 一致
+func (h *ImageProxyHandler) transcodeImage(c *gin.Context, resp *http.Response, head bool, target, etag, lastModified string) error {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, h.maxBytes+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > h.maxBytes {
+		return &imageProxyLimitError{fmt.Sprintf("图片大小超过上限 %d 字节", h.maxBytes)}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	converted, err := image.Transcode(data, target)
+	if err != nil {
+		log.Printf("[ImageProxy] Transcode to %s failed, serving original: %v", target, err)
+	} else if len(converted) > 0 && len(converted) < len(data) {
+		data, contentType, etag = converted, image.FormatContentType(target), variantETag(etag, target)
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.Itoa(len(data)))
+	writeImageCacheHeaders(c, etag, lastModified)
+	c.Status(http.StatusOK)
+	if head {
+		return nil
+	}
+	_, err = c.Writer.Write(data)
+	return err
+}
+
+// transcodeTarget 返回客户端 Accept 中明确支持的第一个转码目标格式，未配置转码或都不支持时返回空字符串
+func (h *ImageProxyHandler) transcodeTarget(accept string) string {
+	for _, format := range h.formats {
+		if acceptsMediaType(accept, image.FormatContentType(format)) {
+			return format
+		}
+	}
+	return ""
+}
+
+// transcodable 判断源站返回的格式是否需要转码（只转码 JPEG 和 PNG）
+func transcodable(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	return mediaType == "image/jpeg" || mediaType == "image/jpg" || mediaType == "image/png"
+}
+
+// acceptsMediaType 判断 Accept 头是否明确列出了该类型（q=0 视为不接受，通配符不算）
+func acceptsMediaType(accept, mediaType string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(params[0]), mediaType) {
+			continue
+		}
+		for _, param := range params[1:] {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil && q == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// variantETag 转码结果的 ETag：在原 ETag 的引号内追加格式后缀，format 为空时原样返回
+func variantETag(etag, format string) string {
+	if format == "" || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + format + `"`
+}
+
+// stripVariantETags 去掉 If-None-Match 中各 ETag 的格式后缀，还原为源站的 ETag
+func stripVariantETags(ifNoneMatch, format string) string {
+	if format == "" {
+		return ifNoneMatch
+	}
+	suffix := "-" + format + `"`
+	candidates := strings.Split(ifNoneMatch, ",")
+	for i, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if strings.HasSuffix(candidate, suffix) {
+			candidate = strings.TrimSuffix(candidate, suffix) + `"`
+		}
+		candidates[i] = candidate
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// failureReason 将代理失败转换为状态码、错误码和提示信息
+func (h *ImageProxyHandler) failureReason(err error) (int, ErrorCode, string) {
+	var limitErr *imageProxyLimitError
+	if errors.As(err, &limitErr) {
+		return http.StatusBadGateway, CodeUpstreamError, limitErr.reason
+	}
+	if errors.Is(err, utils.ErrPrivateAddress) {
+		return http.StatusForbidden, CodeForbidden, "不允许访问内网地址"
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Timeout() {
+		return http.StatusGatewayTimeout, CodeUpstreamTimeout, fmt.Sprintf("图片获取超时（%v）", h.timeout)
+	}
+	return http.StatusBadGateway, CodeUpstreamError, "图片获取失败"
+}
+
+// writeImageCacheHeaders 写入图片的缓存相关响应头；返回的格式随 Accept 协商，缓存需按 Accept 区分
+func writeImageCacheHeaders(c *gin.Context, etag, lastModified string) {
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Header("Vary", "Accept")
+	c.Header("ETag", etag)
+	if lastModified != "" {
+		c.Header("Last-Modified", lastModified)
+	}
+}
+
+// urlETag 按图片地址生成 ETag（同一地址的图片视为不变）
+func urlETag(imageURL string) string {
+	sum := sha256.Sum256([]byte(imageURL))
+	return fmt.Sprintf(`"u-%x"`, sum[:8])
+}
+
+// etagMatches 判断 If-None-Match 中是否包含指定 ETag（忽略弱校验前缀）
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
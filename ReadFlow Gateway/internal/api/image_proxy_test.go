@@ -0,0 +1,115 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+func TestImageProxyAcceptNegotiation(t *testing.T) {
+	var gotAccept, gotIfNoneMatch string
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAccept, gotIfNoneMatch = r.Header.Get("Accept"), r.Header.Get("If-None-Match")
+		w.Header().Set("ETag", `"abc"`)
+		if gotIfNoneMatch == `"abc"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("not really a jpeg"))
+	}))
+	defer origin.Close()
+
+	// 测试服务器在回环地址上，不使用只允许公网地址的客户端
+	h := &ImageProxyHandler{
+		client:   origin.Client(),
+		key:      "key",
+		maxBytes: 1 << 20,
+		accept:   "image/*,*/*",
+		formats:  []string{"avif", "webp"},
+	}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/image", h.HandleImage)
+	router.HEAD("/api/image", h.HandleImage)
+
+	imageURL := origin.URL + "/a.jpg"
+	path := "/api/image?url=" + url.QueryEscape(imageURL) + "&sig=" + utils.SignURL("key", imageURL)
+	doMethod := func(method string, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		for name, value := range header {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	do := func(header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		return doMethod(http.MethodGet, header)
+	}
+
+	// 未发送 Accept 时使用默认值
+	rec := do(nil)
+	if rec.Code != http.StatusOK || gotAccept != "image/*,*/*" || rec.Header().Get("Vary") != "Accept" {
+		t.Fatalf("default accept: code=%d accept=%q vary=%q", rec.Code, gotAccept, rec.Header().Get("Vary"))
+	}
+
+	// 客户端的 Accept 原样转发；无法转码时返回原图和源站 ETag
+	rec = do(map[string]string{"Accept": "image/webp,image/*"})
+	if gotAccept != "image/webp,image/*" {
+		t.Errorf("forwarded accept = %q", gotAccept)
+	}
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/jpeg" || rec.Body.String() != "not really a jpeg" || rec.Header().Get("ETag") != `"abc"` {
+		t.Errorf("fallback to original: code=%d type=%q etag=%q", rec.Code, rec.Header().Get("Content-Type"), rec.Header().Get("ETag"))
+	}
+
+	// HEAD 的响应头与 GET 一致：转码失败回退原图时不声明转码格式
+	get := rec
+	rec = doMethod(http.MethodHead, map[string]string{"Accept": "image/webp,image/*"})
+	for _, name := range []string{"Content-Type", "Content-Length", "ETag"} {
+		if rec.Header().Get(name) != get.Header().Get(name) {
+			t.Errorf("HEAD %s = %q, GET %s = %q", name, rec.Header().Get(name), name, get.Header().Get(name))
+		}
+	}
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("HEAD: code=%d body=%q", rec.Code, rec.Body.String())
+	}
+
+	// 转码结果的 ETag 去掉格式后缀后交给源站比较，304 时返回转码结果的 ETag
+	rec = do(map[string]string{"Accept": "image/webp", "If-None-Match": `"abc-webp"`})
+	if gotIfNoneMatch != `"abc"` || rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != `"abc-webp"` {
+		t.Errorf("conditional request: origin got %q, code=%d etag=%q", gotIfNoneMatch, rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestImageProxyTranscodeTarget(t *testing.T) {
+	h := &ImageProxyHandler{formats: []string{"avif", "webp"}}
+	cases := map[string]string{
+		"image/avif,image/webp,image/*": "avif",
+		"image/avif;q=0,image/webp":     "webp",
+		"image/webp;q=0.8":              "webp",
+		"image/*,*/*":                   "",
+		"":                              "",
+	}
+	for accept, want := range cases {
+		if got := h.transcodeTarget(accept); got != want {
+			t.Errorf("transcodeTarget(%q) = %q, want %q", accept, got, want)
+		}
+	}
+	if got := (&ImageProxyHandler{}).transcodeTarget("image/webp"); got != "" {
+		t.Errorf("transcoding disabled, got %q", got)
+	}
+
+	if got := variantETag(`W/"abc"`, "webp"); got != `W/"abc-webp"` {
+		t.Errorf("variantETag = %q", got)
+	}
+	if got := stripVariantETags(`"abc-webp", "def"`, "webp"); got != `"abc", "def"` {
+		t.Errorf("stripVariantETags = %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/image"
+)
+
+// imageRewriter 代理模式下把返回内容中的外部图片改写为网关图片代理地址
+// 只作用于单次响应，入库内容保持原样；未开启时所有方法原样返回
+type imageRewriter struct {
+	enabled bool
+	key     string
+	baseURL string
+}
+
+// newImageRewriter 按请求参数 image_proxy=true/false 决定是否改写，缺省时使用用户偏好中的 proxy_mode_enabled
+// 用户设置了 proxy_server_url 时代理地址以其为前缀（客户端访问网关的外部地址），否则使用网关自身的相对路径；
+// 代理地址自带签名，不需要附带 proxy_token
+func newImageRewriter(c *gin.Context, database *db.DB, userID int64, key string) imageRewriter {
+	pref, err := database.GetUserPreferences(userID)
+	if err != nil {
+		// 尚未保存过偏好设置
+		pref = &db.UserPreference{UserID: userID}
+	}
+
+	enabled := pref.ProxyModeEnabled
+	switch c.Query("image_proxy") {
+	case "true":
+		enabled = true
+	case "false":
+		enabled = false
+	}
+	return imageRewriter{
+		enabled: enabled,
+		key:     key,
+		baseURL: strings.TrimRight(strings.TrimSpace(pref.ProxyServerURL), "/"),
+	}
+}
+
+// content 改写正文中的图片地址
+func (r imageRewriter) content(htmlContent string) string {
+	if !r.enabled {
+		return htmlContent
+	}
+	return image.ProxyImageURLs(htmlContent, r.key, r.baseURL)
+}
+
+// cover 改写封面图地址
+func (r imageRewriter) cover(imageURL string) string {
+	if !r.enabled {
+		return imageURL
+	}
+	return image.ProxyImageURL(imageURL, r.key, r.baseURL)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 列表接口统一的分页响应，约定见 docs/pagination.md：
+//
+//	{"success": true, "items": [...], "hasMore": true, "nextCursor": "...", "total": 123}
+//
+// hasMore 为 true 时把 nextCursor 原样作为 cursor 参数请求下一页；total 只在能低成本统计时返回。
+// 旧客户端依赖的字段名（articles、words、has_more 等）作为别名保留，请求 legacy_fields=false 时不再输出
+
+// pageResponse 构造统一分页响应，legacy 为兼容旧客户端保留的字段
+func pageResponse(c *gin.Context, items interface{}, hasMore bool, nextCursor string, total *int64, legacy gin.H) gin.H {
+	resp := gin.H{
+		"success": true,
+		"items":   items,
+		"hasMore": hasMore,
+	}
+	if hasMore && nextCursor != "" {
+		resp["nextCursor"] = nextCursor
+	}
+	if total != nil {
+		resp["total"] = *total
+	}
+	if legacyFieldsEnabled(c) {
+		for key, value := range legacy {
+			resp[key] = value
+		}
+	}
+	return resp
+}
+
+// completePage 构造一次返回全部数据的列表响应（订阅、分组等数量很少的列表），hasMore 恒为 false
+func completePage(c *gin.Context, items interface{}, count int, legacy gin.H) gin.H {
+	total := int64(count)
+	return pageResponse(c, items, false, "", &total, legacy)
+}
+
+// parseOffsetCursor 解析按偏移量分页的列表的 cursor 参数（即上一页返回的 nextCursor），兼容旧的 offset 参数
+func parseOffsetCursor(c *gin.Context) int {
+	value := c.Query("cursor")
+	if value == "" {
+		value = c.Query("offset")
+	}
+	offset, err := strconv.Atoi(value)
+	if err != nil || offset < 0 {
+		return 0
+	}
+	return offset
+}
+
+// offsetNextCursor 按总数判断偏移量分页是否还有下一页，返回 hasMore 和下一页的 cursor
+func offsetNextCursor(offset, count int, total int64) (bool, string) {
+	next := offset + count
+	if count == 0 || int64(next) >= total {
+		return false, ""
+	}
+	return true, strconv.Itoa(next)
+}
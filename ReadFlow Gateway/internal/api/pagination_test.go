@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPageResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	items := []int{1, 2}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/list", nil)
+	total := int64(5)
+	resp := pageResponse(c, items, true, "2", &total, gin.H{"words": items, "has_more": true})
+	if resp["hasMore"] != true || resp["nextCursor"] != "2" || resp["total"] != int64(5) {
+		t.Errorf("page fields = %v", resp)
+	}
+	if _, ok := resp["words"]; !ok {
+		t.Errorf("legacy alias missing: %v", resp)
+	}
+
+	// legacy_fields=false 时只输出统一字段；没有下一页时不输出 nextCursor
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/list?legacy_fields=false", nil)
+	resp = pageResponse(c, items, false, "2", nil, gin.H{"words": items})
+	if _, ok := resp["words"]; ok {
+		t.Errorf("legacy alias present: %v", resp)
+	}
+	if _, ok := resp["nextCursor"]; ok {
+		t.Errorf("nextCursor without more pages: %v", resp)
+	}
+	if _, ok := resp["total"]; ok {
+		t.Errorf("total without count: %v", resp)
+	}
+}
+
+func TestOffsetNextCursor(t *testing.T) {
+	cases := []struct {
+		offset, count int
+		total         int64
+		hasMore       bool
+		next          string
+	}{
+		{0, 50, 120, true, "50"},
+		{100, 20, 120, false, ""},
+		{200, 0, 120, false, ""},
+	}
+	for _, tc := range cases {
+		hasMore, next := offsetNextCursor(tc.offset, tc.count, tc.total)
+		if hasMore != tc.hasMore || next != tc.next {
+			t.Errorf("offsetNextCursor(%d, %d, %d) = %v, %q", tc.offset, tc.count, tc.total, hasMore, next)
+		}
+	}
+}
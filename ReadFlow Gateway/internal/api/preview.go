@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/utils"
+	"github.com/readflow/gateway/internal/worker"
+)
+
+// PreviewHandler 订阅源预览处理器
+type PreviewHandler struct {
+	worker *worker.Worker
+}
+
+// NewPreviewHandler 创建订阅源预览处理器
+func NewPreviewHandler(w *worker.Worker) *PreviewHandler {
+	return &PreviewHandler{worker: w}
+}
+
+// PreviewRequest 订阅源预览请求
+type PreviewRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// PreviewFeed 预览订阅源 POST /api/sources/preview
+// 返回源标题、描述、图标和最近几篇文章，不创建 sources / items 记录
+func (h *PreviewHandler) PreviewFeed(c *gin.Context) {
+	var req PreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	feedURL := strings.TrimSpace(req.URL)
+	u, err := url.Parse(feedURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "url 参数无效")
+		return
+	}
+
+	preview, err := h.worker.PreviewFeed(u.String())
+	if err != nil {
+		log.Printf("[Preview] Failed to preview %s: %v", feedURL, err)
+		status, message := previewFailure(err)
+		respondError(c, status, codeForStatus(status), message)
+		return
+	}
+
+	tf := newTimeFormat(c)
+	items := make([]feedPreviewItemJSON, 0, len(preview.Items))
+	for i := range preview.Items {
+		item := &preview.Items[i]
+		items = append(items, feedPreviewItemJSON{FeedPreviewItem: item, PublishedAt: tf.atPtr(item.PublishedAt)})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    feedPreviewJSON{FeedPreview: preview, Items: items},
+	})
+}
+
+// feedPreviewJSON 预览的响应格式，文章发布时间按 timeFormat 输出
+type feedPreviewJSON struct {
+	*worker.FeedPreview
+	Items []feedPreviewItemJSON `json:"items"`
+}
+
+// feedPreviewItemJSON 预览中文章的响应格式
+type feedPreviewItemJSON struct {
+	*worker.FeedPreviewItem
+	PublishedAt interface{} `json:"published_at,omitempty"`
+}
+
+// previewFailure 将预览错误转换为状态码和提示信息
+func previewFailure(err error) (int, string) {
+	var httpErr gofeed.HTTPError
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, utils.ErrPrivateAddress):
+		return http.StatusForbidden, "不允许访问内网地址"
+	case errors.Is(err, utils.ErrHostNotAllowed):
+		return http.StatusForbidden, "该主机不在允许访问的范围内"
+	case errors.Is(err, worker.ErrNotAFeed):
+		return http.StatusUnprocessableEntity, "该地址不是有效的 RSS/Atom 订阅源"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &urlErr) && urlErr.Timeout():
+		return http.StatusGatewayTimeout, "订阅源响应超时"
+	case errors.As(err, &httpErr):
+		return http.StatusBadGateway, "订阅源返回错误状态 " + httpErr.Status
+	default:
+		return http.StatusBadGateway, "无法访问该地址"
+	}
+}
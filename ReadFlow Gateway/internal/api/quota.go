@@ -0,0 +1,74 @@
+package api
+
+import (
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/db"
+)
+
+// QuotaUsage 单项配额的当前用量和上限，Limit 为 0 表示不限制
+type QuotaUsage struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit"`
+}
+
+// exceeded 再增加 n 项后是否超出上限
+func (q QuotaUsage) exceeded(n int) bool {
+	return q.Limit > 0 && n > 0 && q.Used+n > q.Limit
+}
+
+// UserQuota 用户的订阅数和生词数配额
+type UserQuota struct {
+	Subscriptions QuotaUsage `json:"subscriptions"`
+	Vocabulary    QuotaUsage `json:"vocabulary"`
+}
+
+// quotaLimits 返回用户生效的配额上限：管理员设置的覆盖值优先，否则使用全局配置
+func quotaLimits(database *db.DB, userID int64) (maxSubscriptions, maxVocabulary int, err error) {
+	rc := config.GetRuntimeConfig()
+	maxSubscriptions, maxVocabulary = rc.GetMaxSubscriptions(), rc.GetMaxVocabulary()
+
+	override, err := database.GetUserQuotaOverride(userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if override.MaxSubscriptions != nil {
+		maxSubscriptions = *override.MaxSubscriptions
+	}
+	if override.MaxVocabulary != nil {
+		maxVocabulary = *override.MaxVocabulary
+	}
+	return maxSubscriptions, maxVocabulary, nil
+}
+
+// subscriptionQuota 获取用户的订阅配额
+func subscriptionQuota(database *db.DB, userID int64) (QuotaUsage, error) {
+	limit, _, err := quotaLimits(database, userID)
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+	used, err := database.CountUserSubscriptions(userID)
+	return QuotaUsage{Used: used, Limit: limit}, err
+}
+
+// vocabularyQuota 获取用户的生词配额（只统计未删除的词条）
+func vocabularyQuota(database *db.DB, userID int64) (QuotaUsage, error) {
+	_, limit, err := quotaLimits(database, userID)
+	if err != nil {
+		return QuotaUsage{}, err
+	}
+	used, err := database.CountUserVocabulary(userID)
+	return QuotaUsage{Used: used, Limit: limit}, err
+}
+
+// loadUserQuota 获取用户全部配额及用量
+func loadUserQuota(database *db.DB, userID int64) (*UserQuota, error) {
+	subscriptions, err := subscriptionQuota(database, userID)
+	if err != nil {
+		return nil, err
+	}
+	vocabulary, err := vocabularyQuota(database, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &UserQuota{Subscriptions: subscriptions, Vocabulary: vocabulary}, nil
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/utils"
+	"github.com/readflow/gateway/internal/worker"
+)
+
+// ReaderHandler 阅读模式处理器
+type ReaderHandler struct {
+	worker *worker.Worker
+}
+
+// NewReaderHandler 创建阅读模式处理器
+func NewReaderHandler(w *worker.Worker) *ReaderHandler {
+	return &ReaderHandler{worker: w}
+}
+
+// ReadRequest 阅读模式请求
+type ReadRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// Read 提取任意网页的正文 POST /api/read
+// 返回标题、清理后的正文和封面，不创建 sources / items 记录；同一链接的结果缓存 10 分钟
+func (h *ReaderHandler) Read(c *gin.Context) {
+	var req ReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请求体格式错误")
+		return
+	}
+
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "url 参数无效")
+		return
+	}
+	u.Fragment = ""
+
+	article, err := h.worker.ReadURL(u.String())
+	if err != nil {
+		log.Printf("[Reader] Failed to read %s: %v", u.String(), err)
+		status, code, message := readFailure(err)
+		respondError(c, status, code, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    article,
+	})
+}
+
+// readFailure 将提取错误转换为状态码、错误码和提示信息
+func readFailure(err error) (int, ErrorCode, string) {
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, utils.ErrPrivateAddress):
+		return http.StatusForbidden, CodeForbidden, "不允许访问内网地址"
+	case errors.Is(err, utils.ErrHostNotAllowed):
+		return http.StatusForbidden, CodeForbidden, "该主机不在允许访问的范围内"
+	case errors.Is(err, worker.ErrNoReadableContent):
+		return http.StatusUnprocessableEntity, CodeNoContent, "页面中没有可提取的正文"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &urlErr) && urlErr.Timeout():
+		return http.StatusGatewayTimeout, CodeUpstreamTimeout, "页面响应超时"
+	default:
+		return http.StatusBadGateway, CodeUpstreamError, "无法提取该页面的正文"
+	}
+}
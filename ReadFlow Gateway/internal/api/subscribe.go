@@ -2,27 +2,45 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/config"
 	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/utils"
 )
 
 // SubscribeHandler 订阅管理处理器
 type SubscribeHandler struct {
-	db *db.DB
+	db      *db.DB
+	fetcher SourceFetcher     // 单源刷新使用，nil 时刷新接口不可用
+	hosts   *utils.HostPolicy // 订阅源主机允许/禁止列表，nil 表示不限制
+}
+
+// SourceFetcher 定义立即抓取单个源所需的工作器接口
+type SourceFetcher interface {
+	FetchSource(source *db.Source) error
 }
 
 // NewSubscribeHandler 创建订阅处理器
-func NewSubscribeHandler(database *db.DB) *SubscribeHandler {
-	return &SubscribeHandler{db: database}
+func NewSubscribeHandler(database *db.DB, fetcher SourceFetcher, hosts *utils.HostPolicy) *SubscribeHandler {
+	return &SubscribeHandler{db: database, fetcher: fetcher, hosts: hosts}
 }
 
+// sourceRefreshWait 单源刷新最多等待的时间，需明显小于 HTTP 的 WriteTimeout（30 秒），超时后抓取在后台继续
+const sourceRefreshWait = 20 * time.Second
+
 // SubscribeRequest 订阅请求
 type SubscribeRequest struct {
-	URL   string `json:"url" binding:"required"`
-	Title string `json:"title"`
+	URL      string `json:"url" binding:"required"`
+	Title    string `json:"title"`
+	Category string `json:"category"` // 新源的分类，为空时使用用户偏好中的默认分类
+	Language string `json:"language"` // 新源的语言（如 en、zh-CN），为空时首次抓取取 feed 声明的语言
 }
 
 // SubscribeResponse 订阅响应
@@ -37,19 +55,36 @@ type SubscribeResponse struct {
 func (h *SubscribeHandler) Subscribe(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	var req SubscribeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, SubscribeResponse{
-			Success: false,
-			Message: "无效的请求参数",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
+		return
+	}
+	if err := h.hosts.Check(req.URL); err != nil {
+		respondError(c, http.StatusForbidden, CodeForbidden, "该订阅源地址不在允许订阅的范围内")
+		return
+	}
+
+	// 未订阅过该源时检查订阅数配额（在创建源之前，避免留下无人订阅的源）
+	_, err = h.db.GetUserSourceByURL(userID, req.URL)
+	newSubscription := err == sql.ErrNoRows
+	if newSubscription {
+		quota, err := subscriptionQuota(h.db, userID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅配额失败")
+			return
+		}
+		if quota.exceeded(1) {
+			respondErrorDetails(c, http.StatusConflict, CodeQuotaExceeded,
+				fmt.Sprintf("订阅数已达上限（%d 个）", quota.Limit), quota)
+			return
+		}
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅失败")
 		return
 	}
 
@@ -59,32 +94,37 @@ func (h *SubscribeHandler) Subscribe(c *gin.Context) {
 
 	if err == sql.ErrNoRows {
 		// 创建新源
-		source, err = h.db.CreateSource(req.URL, req.Title, "")
+		// 新源使用当前的默认抓取间隔，之后修改默认值不影响已有源
+		category, language := h.newSourceClassification(userID, &req)
+		source, err = h.db.CreateClassifiedSource(req.URL, req.Title, "",
+			config.GetRuntimeConfig().GetFetchInterval(), category, language)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, SubscribeResponse{
-				Success: false,
-				Message: "创建源失败",
-			})
+			respondError(c, http.StatusInternalServerError, CodeInternal, "创建源失败")
 			return
 		}
 		isNewSource = true
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, SubscribeResponse{
-			Success: false,
-			Message: "查询源失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询源失败")
 		return
 	}
 
 	// 创建订阅关系
 	if err := h.db.CreateSubscription(userID, source.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, SubscribeResponse{
-			Success: false,
-			Message: "订阅失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "订阅失败")
 		return
 	}
 
+	// 订阅已有的源时补发最近的文章，新源的文章由首次抓取投递
+	if newSubscription && !isNewSource {
+		// 其他订阅都已取消或暂停时源已停用，重新激活后才会继续抓取
+		if !source.IsActive {
+			if err := h.db.ActivateSubscribedSource(source.ID); err != nil {
+				log.Printf("[SUBSCRIBE] Activate source %d failed: %v", source.ID, err)
+			}
+		}
+		h.backfill(userID, source)
+	}
+
 	c.JSON(http.StatusOK, SubscribeResponse{
 		Success:     true,
 		SourceID:    source.ID,
@@ -93,33 +133,61 @@ func (h *SubscribeHandler) Subscribe(c *gin.Context) {
 	})
 }
 
+// newSourceClassification 新源的分类和语言：请求中指定的优先，分类其次使用用户偏好中的默认分类
+// 读取偏好失败时不设置分类，不影响订阅
+func (h *SubscribeHandler) newSourceClassification(userID int64, req *SubscribeRequest) (category, language string) {
+	textProcessor := utils.NewTextProcessor()
+	category = textProcessor.NormalizeCategory(req.Category)
+	if category == "" {
+		if pref, err := h.db.GetUserPreferences(userID); err == nil {
+			category = textProcessor.NormalizeCategory(pref.DefaultCategory)
+		}
+	}
+	return category, utils.NormalizeLanguage(req.Language)
+}
+
+// backfill 为新订阅（或恢复订阅）的用户补发源中保留期内最近的文章，返回新建的投递数；
+// 失败只记录日志，不影响订阅结果
+func (h *SubscribeHandler) backfill(userID int64, source *db.Source) int64 {
+	rc := config.GetRuntimeConfig()
+	limit := rc.GetSubscribeBackfill()
+	if limit <= 0 {
+		return 0
+	}
+	retention := rc.GetItemRetentionTime()
+	if source.RetentionSeconds > 0 {
+		retention = source.RetentionSeconds
+	}
+
+	count, err := h.db.BackfillDeliveries(userID, source.ID, limit, retention)
+	if err != nil {
+		log.Printf("[SUBSCRIBE] Backfill source %d for user %d failed: %v", source.ID, userID, err)
+		return 0
+	}
+	if count > 0 {
+		log.Printf("[SUBSCRIBE] Backfilled %d items of source %d for user %d", count, source.ID, userID)
+	}
+	return count
+}
+
 // Unsubscribe 取消订阅
 func (h *SubscribeHandler) Unsubscribe(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	sourceIDStr := c.Param("source_id")
 	sourceID, err := strconv.ParseInt(sourceIDStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的源 ID",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的源 ID")
 		return
 	}
 
 	// 删除订阅关系
 	if err := h.db.DeleteSubscription(userID, sourceID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "取消订阅失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "取消订阅失败")
 		return
 	}
 
@@ -138,57 +206,265 @@ func (h *SubscribeHandler) Unsubscribe(c *gin.Context) {
 	})
 }
 
+// PauseSubscription 暂停订阅 POST /api/subscriptions/:source_id/pause
+// 暂停期间不再向该用户投递新文章（文章照常入库供其他订阅者使用），已投递的文章不受影响
+func (h *SubscribeHandler) PauseSubscription(c *gin.Context) {
+	h.setPaused(c, true)
+}
+
+// ResumeSubscription 恢复订阅 POST /api/subscriptions/:source_id/resume
+// backfill=true 时按订阅补发设置补发保留期内最近的文章（包括暂停期间入库的文章）
+func (h *SubscribeHandler) ResumeSubscription(c *gin.Context) {
+	h.setPaused(c, false)
+}
+
+// setPaused 暂停或恢复订阅
+func (h *SubscribeHandler) setPaused(c *gin.Context, paused bool) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	sourceID, err := strconv.ParseInt(c.Param("source_id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的源 ID")
+		return
+	}
+
+	backfill := false
+	if !paused {
+		if v := c.Query("backfill"); v != "" {
+			backfill, err = strconv.ParseBool(v)
+			if err != nil {
+				respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的 backfill 参数")
+				return
+			}
+		}
+	}
+
+	err = h.db.SetSubscriptionPaused(userID, sourceID, paused)
+	if errors.Is(err, db.ErrNotSubscribed) {
+		respondError(c, http.StatusNotFound, CodeNotFound, "未订阅该源")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
+		return
+	}
+
+	response := gin.H{
+		"success":   true,
+		"source_id": sourceID,
+		"is_paused": paused,
+	}
+	if backfill {
+		var backfilled int64
+		if source, err := h.db.GetUserSourceByID(userID, sourceID); err == nil {
+			backfilled = h.backfill(userID, source)
+		}
+		response["backfilled"] = backfilled
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 // SubscriptionInfo 订阅信息
+// 时间字段为 Unix 秒；兼容期内默认保持原来的格式（见 timeFormat）
 type SubscriptionInfo struct {
-	SourceID      int64  `json:"source_id"`
-	URL           string `json:"url"`
-	Title         string `json:"title"`
-	SubscribedAt  string `json:"subscribed_at"`
-	UnreadCount   int    `json:"unread_count"`
-	LastFetchTime string `json:"last_fetch_time,omitempty"`
+	SourceID      int64       `json:"source_id"`
+	URL           string      `json:"url"`
+	Title         string      `json:"title"`
+	SubscribedAt  interface{} `json:"subscribed_at,omitempty"`
+	UnreadCount   int         `json:"unread_count"`
+	LastFetchTime interface{} `json:"last_fetch_time,omitempty"`
+	Favicon       string      `json:"favicon,omitempty"` // 本地缓存的源图标路径
+	GroupID       *int64      `json:"group_id"`          // 所属分组，未分组时为 null
+	IsPaused      bool        `json:"is_paused"`         // 已暂停，暂停期间不投递新文章
 }
 
 // GetSubscriptions 获取订阅列表
 func (h *SubscribeHandler) GetSubscriptions(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
-	sources, err := h.db.GetUserSubscriptions(userID)
+	subscriptions, err := loadSubscriptionInfos(h.db, userID, newTimeFormat(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "查询失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
 		return
 	}
 
-	// 构建响应
+	c.JSON(http.StatusOK, completePage(c, subscriptions, len(subscriptions), gin.H{"subscriptions": subscriptions}))
+}
+
+// loadSubscriptionInfos 获取用户的订阅列表，附带未读数、所属分组和暂停状态
+func loadSubscriptionInfos(database *db.DB, userID int64, tf timeFormat) ([]SubscriptionInfo, error) {
+	sources, err := database.GetUserSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 一次查询获取所有源的未读数，避免逐源查询
+	unreadCounts := make(map[int64]int, len(sources))
+	if counts, err := database.GetUserSourceCounts(userID); err == nil {
+		for _, sc := range counts {
+			unreadCounts[sc.SourceID] = sc.Unread
+		}
+	}
+
+	groups, err := database.GetSubscriptionGroups(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	paused, err := database.GetPausedSubscriptions(userID)
+	if err != nil {
+		return nil, err
+	}
+
 	subscriptions := make([]SubscriptionInfo, 0, len(sources))
 	for _, source := range sources {
-		unreadCount, _ := h.db.GetUnreadCount(userID, source.ID)
-		
 		info := SubscriptionInfo{
 			SourceID:    source.ID,
 			URL:         source.URL,
 			Title:       source.Title,
-			UnreadCount: unreadCount,
+			UnreadCount: unreadCounts[source.ID],
+			Favicon:     source.Favicon,
+			IsPaused:    paused[source.ID],
 		}
-		
-		if source.LastFetchTime != nil {
-			info.LastFetchTime = source.LastFetchTime.Format("2006-01-02T15:04:05Z")
+		if groupID, ok := groups[source.ID]; ok {
+			info.GroupID = &groupID
+		}
+		if tf.legacy {
+			// 旧格式：subscribed_at 恒为空字符串，last_fetch_time 为秒级 UTC 时间字符串
+			info.SubscribedAt = ""
+			if source.LastFetchTime != nil {
+				info.LastFetchTime = source.LastFetchTime.UTC().Format("2006-01-02T15:04:05Z")
+			}
+		} else if source.LastFetchTime != nil {
+			info.LastFetchTime = source.LastFetchTime.Unix()
 		}
-		
 		subscriptions = append(subscriptions, info)
 	}
+	return subscriptions, nil
+}
+
+// CatchUp 将订阅源中指定时间之前发布的未读文章标记为已读
+// POST /api/subscriptions/:source_id/catch-up?before=<unix 时间戳>，before 缺省为当前时间
+func (h *SubscribeHandler) CatchUp(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	sourceID, err := strconv.ParseInt(c.Param("source_id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的源 ID")
+		return
+	}
+
+	before := time.Now()
+	if beforeStr := c.Query("before"); beforeStr != "" {
+		t, err := parseTimestamp(beforeStr)
+		if err != nil || t.Unix() <= 0 {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的 before 参数")
+			return
+		}
+		before = t
+	}
+
+	marked, err := h.db.MarkSourceReadBefore(userID, sourceID, before)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "操作失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"marked":  marked,
+	})
+}
+
+// RefreshSource 立即抓取用户订阅的单个源 POST /api/subscriptions/:source_id/refresh
+// 返回本次新投递给该用户的文章数（new_items）和该源的未读数；
+// 最多等待 20 秒，未完成时返回 202 和 pending=true，抓取在后台继续，之后同步即可拿到新文章
+func (h *SubscribeHandler) RefreshSource(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	sourceID, err := strconv.ParseInt(c.Param("source_id"), 10, 64)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的源 ID")
+		return
+	}
+
+	if h.fetcher == nil {
+		respondError(c, http.StatusServiceUnavailable, CodeUnavailable, "Worker 不可用")
+		return
+	}
+
+	source, err := h.db.GetUserSourceByID(userID, sourceID)
+	if err == sql.ErrNoRows {
+		respondError(c, http.StatusNotFound, CodeNotFound, "未订阅该源")
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅源失败")
+		return
+	}
+
+	watermark, err := h.db.GetSourceDeliveryWatermark(userID, sourceID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询投递记录失败")
+		return
+	}
+
+	log.Printf("[SUBSCRIBE] User %d refreshing source %d", userID, sourceID)
+	done := make(chan error, 1)
+	go func() {
+		err := h.fetcher.FetchSource(source)
+		if err != nil {
+			h.db.UpdateSourceError(source.ID, err.Error())
+		} else {
+			h.db.UpdateSourceFetchTime(source.ID)
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Printf("[SUBSCRIBE] Refresh of source %d failed: %v", sourceID, err)
+			respondError(c, http.StatusBadGateway, CodeUpstreamError, "刷新订阅源失败")
+			return
+		}
+	case <-time.After(sourceRefreshWait):
+		c.JSON(http.StatusAccepted, gin.H{
+			"success":   true,
+			"source_id": sourceID,
+			"pending":   true,
+			"message":   "订阅源仍在抓取，稍后同步即可获取新文章",
+		})
+		return
+	}
+
+	newItems, err := h.db.CountSourceDeliveriesAfter(userID, sourceID, watermark)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询投递记录失败")
+		return
+	}
+	unread, _ := h.db.GetUnreadCount(userID, sourceID)
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":       true,
-		"subscriptions": subscriptions,
+		"success":   true,
+		"source_id": sourceID,
+		"new_items": newItems,
+		"unread":    unread,
 	})
 }
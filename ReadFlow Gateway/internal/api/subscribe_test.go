@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+)
+
+// fakeFetcher 模拟抓取：为源新增 n 篇文章并投递给订阅者
+type fakeFetcher struct {
+	database *db.DB
+	userID   int64
+	n        int
+	err      error
+	calls    int
+}
+
+func (f *fakeFetcher) FetchSource(source *db.Source) error {
+	f.calls++
+	if f.err != nil {
+		return f.err
+	}
+	for i := 0; i < f.n; i++ {
+		guid := fmt.Sprintf("%d-%d", f.calls, i)
+		res, err := f.database.Exec("INSERT INTO items (source_id, guid, title, xml_content) VALUES (?, ?, ?, '')", source.ID, guid, guid)
+		if err != nil {
+			return err
+		}
+		itemID, _ := res.LastInsertId()
+		if err := f.database.CreateUserDelivery(f.userID, itemID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRefreshSource(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscribed, err := database.CreateSource("https://example.com/feed", "feed", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := database.CreateSource("https://example.com/other", "other", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateSubscription(user.ID, subscribed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	fetcher := &fakeFetcher{database: database, userID: user.ID, n: 2}
+	h := NewSubscribeHandler(database, fetcher, nil)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/subscriptions/:source_id/refresh", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, h.RefreshSource)
+
+	refresh := func(sourceID int64) (int, map[string]interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/subscriptions/%d/refresh", sourceID), nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	code, body := refresh(subscribed.ID)
+	if code != http.StatusOK || body["new_items"] != float64(2) || body["unread"] != float64(2) {
+		t.Fatalf("first refresh = %d %v", code, body)
+	}
+	// 只统计本次新增的投递
+	fetcher.n = 1
+	if code, body = refresh(subscribed.ID); code != http.StatusOK || body["new_items"] != float64(1) || body["unread"] != float64(3) {
+		t.Errorf("second refresh = %d %v", code, body)
+	}
+
+	// 未订阅的源不能刷新
+	calls := fetcher.calls
+	if code, _ = refresh(other.ID); code != http.StatusNotFound || fetcher.calls != calls {
+		t.Errorf("unsubscribed source = %d, fetch calls %d -> %d", code, calls, fetcher.calls)
+	}
+
+	fetcher.err = errors.New("boom")
+	if code, _ = refresh(subscribed.ID); code != http.StatusBadGateway {
+		t.Errorf("failed fetch = %d, want 502", code)
+	}
+	if source, _ := database.GetSourceByID(subscribed.ID); source.ErrorCount != 1 {
+		t.Errorf("fetch error not recorded: error_count = %d", source.ErrorCount)
+	}
+}
+
+func TestSubscribeClassifiesNewSource(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpsertUserPreferences(&db.UserPreference{UserID: user.ID, DefaultCategory: "science"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewSubscribeHandler(database, nil, nil)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/subscribe", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, h.Subscribe)
+
+	subscribe := func(body string) *db.Source {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/subscribe", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var resp SubscribeResponse
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil {
+			t.Fatalf("subscribe %s: code=%d body=%s", body, rec.Code, rec.Body.String())
+		}
+		source, err := database.GetSourceByID(resp.SourceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return source
+	}
+
+	// 未指定时使用用户的默认分类，语言留空等待首次抓取
+	source := subscribe(`{"url":"https://example.com/a"}`)
+	if source.Category != "Science" || source.Language != "" {
+		t.Errorf("default: category=%q language=%q", source.Category, source.Language)
+	}
+
+	// 请求中指定的分类和语言优先
+	source = subscribe(`{"url":"https://example.com/b","category":"news","language":"zh-CN"}`)
+	if source.Category != "News" || source.Language != "zh" {
+		t.Errorf("explicit: category=%q language=%q", source.Category, source.Language)
+	}
+}
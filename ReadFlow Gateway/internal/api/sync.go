@@ -1,6 +1,8 @@
 package api
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"html"
 	"log"
@@ -11,12 +13,15 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/utils"
+	"github.com/readflow/gateway/internal/worker"
 )
 
 // SyncHandler 同步接口处理器
 type SyncHandler struct {
-	db     *db.DB
-	worker SyncWorker // 工作器接口
+	db            *db.DB
+	worker        SyncWorker // 工作器接口
+	imageProxyKey string     // 代理模式改写图片地址时的签名密钥
 }
 
 // SyncWorker 定义刷新源所需的工作器接口
@@ -26,27 +31,27 @@ type SyncWorker interface {
 }
 
 // NewSyncHandler 创建同步处理器
-func NewSyncHandler(database *db.DB, worker SyncWorker) *SyncHandler {
+func NewSyncHandler(database *db.DB, worker SyncWorker, imageProxyKey string) *SyncHandler {
 	return &SyncHandler{
-		db:     database,
-		worker: worker,
+		db:            database,
+		worker:        worker,
+		imageProxyKey: imageProxyKey,
 	}
 }
 
 // Sync 处理同步请求
 // 支持两种模式：
 // 1. mode=sync (默认): 仅同步，返回数据库中已有的文章
-// 2. mode=refresh: 刷新源并同步，先抓取最新文章再同步
+// 2. mode=refresh: 刷新源并同步，先抓取最新文章再同步（最多等待 20 秒，未完成的源在后台继续抓取）
 // 可选参数：
 // - source_url: 指定源URL，只处理该源
+// - max_content_chars: XML 格式下正文最大字符数，超出时截断并附"阅读全文"链接（默认 0 表示不截断）
+// - image_proxy: JSON 格式下将正文和封面图片改写为网关代理地址（true/false，缺省时按用户偏好 proxy_mode_enabled）
 func (h *SyncHandler) Sync(c *gin.Context) {
 	// 获取当前用户 ID
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
@@ -72,6 +77,10 @@ func (h *SyncHandler) Sync(c *gin.Context) {
 			} else {
 				// 刷新所有源
 				if err := h.worker.FetchAllSourcesForUser(userID); err != nil {
+					if errors.Is(err, worker.ErrRefreshInProgress) {
+						respondError(c, http.StatusConflict, CodeConflict, "刷新正在进行中，请稍后再试")
+						return
+					}
 					log.Printf("[SYNC] 刷新用户 %d 的源失败: %v", userID, err)
 				}
 			}
@@ -87,40 +96,43 @@ func (h *SyncHandler) Sync(c *gin.Context) {
 		limit = 50
 	}
 
-	// 查询待投递的文章
-	var items []*db.Item
-	if sourceURL != "" {
-		// 只返回指定源的文章
-		items, err = h.db.GetPendingDeliveriesBySourceURL(userID, sourceURL, limit)
-	} else {
-		// 返回所有源的文章
-		items, err = h.db.GetPendingDeliveries(userID, limit)
-	}
-
-	if err != nil {
-		log.Printf("[SYNC] 查询失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "查询失败",
-		})
-		return
-	}
-
 	// 处理图片压缩选项
-	if !imageCompression {
-		for _, item := range items {
-			// 如果用户选择不压缩，使用原始内容覆盖 CleanContent
-			// 客户端主要使用 CleanContent 进行渲染
-			if item.Content != "" {
-				item.CleanContent = item.Content
-				// 注意：这里没有重新构建 XMLContent，因为客户端主要使用 JSON 格式
-				// 如果需要支持 XML 格式的非压缩模式，需要重新构建 XMLContent
-			}
+	uncompressed := func(item *db.Item) {
+		// 如果用户选择不压缩，使用原始内容覆盖 CleanContent
+		// 客户端主要使用 CleanContent 进行渲染
+		if !imageCompression && item.Content != "" {
+			item.CleanContent = item.Content
+			// 注意：这里没有重新构建 XMLContent，因为客户端主要使用 JSON 格式
+			// 如果需要支持 XML 格式的非压缩模式，需要重新构建 XMLContent
 		}
 	}
 
 	// 如果请求 JSON 格式
 	if strings.ToLower(format) == "json" {
+		// 查询待投递的文章
+		var items []*db.Item
+		if sourceURL != "" {
+			// 只返回指定源的文章
+			items, err = h.db.GetPendingDeliveriesBySourceURL(userID, sourceURL, limit)
+		} else {
+			// 返回所有源的文章
+			items, err = h.db.GetPendingDeliveries(userID, limit)
+		}
+		if err != nil {
+			log.Printf("[SYNC] 查询失败: %v", err)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+			return
+		}
+		for _, item := range items {
+			uncompressed(item)
+		}
+
+		// 代理模式只改写本次响应，items 为本次查询结果，不会写回数据库
+		rewriter := newImageRewriter(c, h.db, userID, h.imageProxyKey)
+		for _, item := range items {
+			item.CleanContent = rewriter.content(item.CleanContent)
+			item.CoverImage = rewriter.cover(item.CoverImage)
+		}
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"items":   items, // 注意：db.Item 包含 xml_content，可能比较大
@@ -129,12 +141,86 @@ func (h *SyncHandler) Sync(c *gin.Context) {
 		return
 	}
 
-	// 默认返回 XML 格式
-	xml := h.buildRSSXML(userID, items)
+	// 正文截断长度（仅 XML 格式），客户端可通过 GET /api/articles/:id 按需获取全文
+	maxContentChars, err := strconv.Atoi(c.DefaultQuery("max_content_chars", "0"))
+	if err != nil || maxContentChars < 0 {
+		maxContentChars = 0
+	}
 
-	// 返回 XML 格式
+	// 默认返回 XML 格式：分页读取待投递的文章并逐条写入响应，内存中只保留一页文章
 	c.Header("Content-Type", "application/xml; charset=utf-8")
-	c.String(http.StatusOK, xml)
+	c.Status(http.StatusOK)
+	each := func(fn func(*db.Item) error) error {
+		return h.db.EachPendingDelivery(userID, sourceURL, limit, syncFlushEvery, func(item *db.Item) error {
+			uncompressed(item)
+			return fn(item)
+		})
+	}
+	if err := h.writeRSSXML(c.Writer, each, maxContentChars); err != nil {
+		if !c.Writer.Written() {
+			// 第一页查询失败，响应还没有发出
+			log.Printf("[SYNC] 查询失败: %v", err)
+			respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+			return
+		}
+		// 响应已开始发出，只能记录日志（通常是客户端断开）
+		log.Printf("[SYNC] 写入 XML 响应失败: %v", err)
+	}
+}
+
+// Counts 返回每个订阅源的未读数和总数（轻量接口，供侧边栏轮询）
+func (h *SyncHandler) Counts(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	counts, err := h.db.GetUserSourceCounts(userID)
+	if err != nil {
+		log.Printf("[SYNC] 查询用户 %d 的未读统计失败: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+		return
+	}
+
+	var unread, total int
+	for _, sc := range counts {
+		unread += sc.Unread
+		total += sc.Total
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"sources": counts,
+		"unread":  unread,
+		"total":   total,
+	})
+}
+
+// UnreadCount 返回用户的未读总数 GET /api/unread/count（供应用角标频繁轮询）
+// 只做一次按索引的 COUNT；ETag 由未读数生成，未读数不变时返回 304
+func (h *SyncHandler) UnreadCount(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	unread, err := h.db.GetTotalUnreadCount(userID)
+	if err != nil {
+		log.Printf("[SYNC] 查询用户 %d 的未读总数失败: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
+		return
+	}
+
+	etag := fmt.Sprintf(`"unread-%d"`, unread)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": unread})
 }
 
 // refreshSingleSource 刷新单个源
@@ -154,73 +240,130 @@ func (h *SyncHandler) refreshSingleSource(userID int64, sourceURL string) {
 	}
 }
 
-// buildRSSXML 构建 RSS XML 格式
-func (h *SyncHandler) buildRSSXML(userID int64, items []*db.Item) string {
-	var sb strings.Builder
-
-	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
-	sb.WriteString("\n")
-	sb.WriteString(`<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">`)
-	sb.WriteString("\n")
-	sb.WriteString("  <channel>\n")
-	sb.WriteString("    <title>ReadFlow Private Feed</title>\n")
-	sb.WriteString("    <description>Private RSS Gateway</description>\n")
-	sb.WriteString(fmt.Sprintf("    <lastBuildDate>%s</lastBuildDate>\n", time.Now().Format(time.RFC1123Z)))
-
-	// 缓存源信息（服务端源）
-	sourceCache := make(map[int64]*db.Source)
-
-	for _, item := range items {
-		var sourceName, sourceURL string
-		var displayLocalID int64 = item.SourceID // 默认使用服务端 source_id
-
-		if item.SourceID != 0 {
-			// 查询服务端源信息
-			src, ok := sourceCache[item.SourceID]
-			if !ok {
-				loaded, err := h.db.GetSourceByID(item.SourceID)
-				if err != nil {
-					log.Printf("[SYNC] failed to load source %d: %v", item.SourceID, err)
-				}
-				src = loaded
-				sourceCache[item.SourceID] = src
-			}
-
-			// 使用源的标题和URL
-			if src != nil {
-				sourceName = src.Title
-				sourceURL = src.URL
-				// 使用源ID作为本地ID（保持兼容性）
-				displayLocalID = src.ID
+// syncFlushEvery XML 同步每写入多少条 item 刷新一次响应
+const syncFlushEvery = 20
+
+// writeRSSXML 以 RSS XML 格式逐条写出 each 产生的 item：先写头部，再逐个 <item>，最后写尾部
+// 写入经缓冲并定期刷新到客户端，每个 item 写出后即可释放，内存占用与 item 数量无关
+// maxContentChars > 0 时截断过长的正文，并在 item 上标记 data-truncated="true"
+func (h *SyncHandler) writeRSSXML(w gin.ResponseWriter, each func(func(*db.Item) error) error, maxContentChars int) error {
+	bw := bufio.NewWriterSize(w, 32<<10)
+	textProcessor := utils.NewTextProcessor()
+
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	bw.WriteString("\n")
+	bw.WriteString(`<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">`)
+	bw.WriteString("\n")
+	bw.WriteString("  <channel>\n")
+	bw.WriteString("    <title>ReadFlow Private Feed</title>\n")
+	bw.WriteString("    <description>Private RSS Gateway</description>\n")
+	fmt.Fprintf(bw, "    <lastBuildDate>%s</lastBuildDate>\n", time.Now().Format(time.RFC1123Z))
+
+	written := 0
+	err := each(func(item *db.Item) error {
+		// 正文过长时截断
+		xmlContent := item.XMLContent
+		extraAttrs := ""
+		if maxContentChars > 0 && item.CleanContent != "" {
+			if excerpt, truncated := textProcessor.TruncateHTML(item.CleanContent, maxContentChars); truncated {
+				xmlContent = buildTruncatedXMLContent(item.XMLContent, excerpt)
+				extraAttrs += ` data-truncated="true"`
 			}
 		}
+		// 逐条写出时无法预先知道是否有播客文章，iTunes 命名空间在带播客字段的 item 上声明
+		if hasPodcastFields(item) {
+			extraAttrs += ` xmlns:itunes="` + itunesNamespace + `"`
+		}
 
-		// 输出带源信息的 item 标签
-		sb.WriteString(fmt.Sprintf(
-			"    <item data-item-id=\"%d\" data-source-id=\"%d\" data-source-name=\"%s\" data-source-url=\"%s\">\n",
+		// 输出带源信息的 item 标签（源标题和地址随文章一起查出）
+		fmt.Fprintf(bw,
+			"    <item data-item-id=\"%d\" data-source-id=\"%d\" data-source-name=\"%s\" data-source-url=\"%s\"%s>\n",
 			item.ID,
-			displayLocalID, // 使用本地源 ID（或默认服务端 ID）
-			html.EscapeString(sourceName),
-			html.EscapeString(sourceURL),
-		))
+			item.SourceID,
+			html.EscapeString(item.SourceTitle),
+			html.EscapeString(item.SourceURL),
+			extraAttrs,
+		)
 
-		sb.WriteString(fmt.Sprintf("      <title><![CDATA[%s]]></title>\n", item.Title))
-		sb.WriteString(fmt.Sprintf("      <guid>%s</guid>\n", item.GUID))
+		fmt.Fprintf(bw, "      <title><![CDATA[%s]]></title>\n", item.Title)
+		fmt.Fprintf(bw, "      <guid>%s</guid>\n", item.GUID)
 
 		if item.PublishedAt != nil {
-			sb.WriteString(fmt.Sprintf("      <pubDate>%s</pubDate>\n", item.PublishedAt.Format(time.RFC1123Z)))
+			fmt.Fprintf(bw, "      <pubDate>%s</pubDate>\n", item.PublishedAt.Format(time.RFC1123Z))
 		}
 
+		writePodcastElements(bw, item)
+
 		// 嵌入 XML 内容
-		sb.WriteString("      ")
-		sb.WriteString(item.XMLContent)
-		sb.WriteString("\n")
+		bw.WriteString("      ")
+		bw.WriteString(xmlContent)
+		bw.WriteString("\n")
+
+		bw.WriteString("    </item>\n")
 
-		sb.WriteString("    </item>\n")
+		written++
+		if written%syncFlushEvery == 0 {
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			w.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bw.WriteString("  </channel>\n")
+	bw.WriteString("</rss>")
+
+	return bw.Flush()
+}
+
+// itunesNamespace iTunes 播客命名空间
+const itunesNamespace = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// hasPodcastFields 文章是否带播客字段
+func hasPodcastFields(item *db.Item) bool {
+	return item.ITunesDuration != "" || item.ITunesImage != "" || item.ITunesEpisode != "" || item.ITunesAuthor != ""
+}
+
+// writePodcastElements 写出文章的 itunes:* 元素，没有的字段不输出
+func writePodcastElements(bw *bufio.Writer, item *db.Item) {
+	if item.ITunesDuration != "" {
+		fmt.Fprintf(bw, "      <itunes:duration>%s</itunes:duration>\n", html.EscapeString(item.ITunesDuration))
+	}
+	if item.ITunesEpisode != "" {
+		fmt.Fprintf(bw, "      <itunes:episode>%s</itunes:episode>\n", html.EscapeString(item.ITunesEpisode))
+	}
+	if item.ITunesAuthor != "" {
+		fmt.Fprintf(bw, "      <itunes:author>%s</itunes:author>\n", html.EscapeString(item.ITunesAuthor))
+	}
+	if item.ITunesImage != "" {
+		fmt.Fprintf(bw, "      <itunes:image href=\"%s\"/>\n", html.EscapeString(item.ITunesImage))
+	}
+}
+
+// buildTruncatedXMLContent 用截断后的正文重建 item 的 XML 内容
+// 保留原 description 和 link，并在正文末尾追加"阅读全文"链接
+func buildTruncatedXMLContent(xmlContent, excerpt string) string {
+	description, _, link := parseXMLFields(xmlContent)
+
+	var sb strings.Builder
+	sb.WriteString("<description><![CDATA[")
+	sb.WriteString(description)
+	sb.WriteString("]]></description>\n")
+
+	if link != "" {
+		sb.WriteString("      <link>")
+		sb.WriteString(link)
+		sb.WriteString("</link>\n")
+		excerpt += fmt.Sprintf(`<p class="readflow-read-more"><a href="%s">阅读全文</a></p>`, html.EscapeString(link))
 	}
 
-	sb.WriteString("  </channel>\n")
-	sb.WriteString("</rss>")
+	sb.WriteString("      <content:encoded><![CDATA[")
+	sb.WriteString(strings.ReplaceAll(excerpt, "]]>", "]]]]><![CDATA[>"))
+	sb.WriteString("]]></content:encoded>")
 
 	return sb.String()
 }
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+)
+
+// 合并同步各部分的默认和最大返回数量
+const (
+	syncAllArticleLimit    = 50
+	syncAllArticleMaxLimit = 200
+	syncAllVocabLimit      = 500
+	syncAllVocabMaxLimit   = 1000
+)
+
+// SyncAllArticles 合并同步中的文章部分
+type SyncAllArticles struct {
+	Items      []ArticleListItem `json:"items"`
+	HasMore    bool              `json:"hasMore"`
+	NextCursor *string           `json:"nextCursor,omitempty"` // hasMore 时继续用 GET /api/articles?sort=updated&since=&cursor= 翻页
+}
+
+// SyncAllVocabulary 合并同步中的生词部分
+type SyncAllVocabulary struct {
+	Words      []VocabWordFull `json:"words"`
+	HasMore    bool            `json:"hasMore"`
+	NextCursor string          `json:"nextCursor,omitempty"` // hasMore 时继续用 GET /api/vocab/pull?since=&cursor= 翻页
+}
+
+// SyncAllSubscriptions 合并同步中的订阅部分
+type SyncAllSubscriptions struct {
+	Items []SubscriptionInfo `json:"items"`
+}
+
+// SyncAllResponse 合并同步响应
+type SyncAllResponse struct {
+	Success       bool                 `json:"success"`
+	ServerTime    int64                `json:"serverTime"` // 客户端保存后作为下次请求的 since
+	Articles      SyncAllArticles      `json:"articles"`
+	Vocabulary    SyncAllVocabulary    `json:"vocabulary"`
+	Subscriptions SyncAllSubscriptions `json:"subscriptions"`
+}
+
+// SyncAll 一次返回文章、生词和订阅的增量 GET /api/sync/all?since=<unix 时间戳>
+// 文章为 since 之后新投递或有变化（内容更新、阅读 / 收藏状态变化）的文章，按 updatedAt 倒序（与 GET /api/articles?sort=updated&since= 相同），
+// 生词为 since 之后更新的词条（与 /api/vocab/pull 相同）；
+// 订阅列表通常很小且退订不留记录，始终返回完整列表由客户端整体替换。
+// 可选参数 article_limit、vocab_limit 控制各部分数量，某部分 hasMore 时应先通过对应接口取完再使用 serverTime
+func (h *SyncHandler) SyncAll(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	var since int64
+	if sinceStr := c.Query("since"); sinceStr != "" {
+		t, err := parseTimestamp(sinceStr)
+		if err != nil || t.Unix() < 0 {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的since参数格式，应为Unix时间戳")
+			return
+		}
+		since = t.Unix()
+	}
+	articleLimit := queryLimit(c, "article_limit", syncAllArticleLimit, syncAllArticleMaxLimit)
+	vocabLimit := queryLimit(c, "vocab_limit", syncAllVocabLimit, syncAllVocabMaxLimit)
+
+	// 先记录服务端时间再查询，查询期间发生的变化会在下次同步中返回
+	serverTime := time.Now().Unix()
+	resp := SyncAllResponse{Success: true, ServerTime: serverTime}
+
+	var sinceTime *time.Time
+	if since > 0 {
+		t := time.Unix(since, 0)
+		sinceTime = &t
+	}
+	userArticles, nextCursor, err := h.db.GetUserArticles(userID, nil, nil, nil, nil, sinceTime, nil, db.SortUpdated, articleLimit, 0)
+	if err != nil {
+		log.Printf("[SYNC] Failed to get articles for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询文章失败")
+		return
+	}
+	summaryLength := parseSummaryLength(c)
+	resp.Articles.Items = make([]ArticleListItem, 0, len(userArticles))
+	for _, ua := range userArticles {
+		resp.Articles.Items = append(resp.Articles.Items, toArticleListItem(ua, summaryLength))
+	}
+	resp.Articles.HasMore = nextCursor != nil
+	resp.Articles.NextCursor = nextCursor
+
+	vocabs, hasMore, err := h.db.GetVocabulariesSince(userID, since, 0, "", vocabLimit)
+	if err != nil {
+		log.Printf("[SYNC] Failed to get vocabularies for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询生词失败")
+		return
+	}
+	resp.Vocabulary.HasMore = hasMore
+	if hasMore {
+		last := vocabs[len(vocabs)-1]
+		resp.Vocabulary.NextCursor = encodeVocabCursor(last.UpdatedAt, last.ID)
+	}
+	resp.Vocabulary.Words = make([]VocabWordFull, 0, len(vocabs))
+	for _, vocab := range vocabs {
+		resp.Vocabulary.Words = append(resp.Vocabulary.Words, toVocabWordFull(vocab))
+	}
+
+	resp.Subscriptions.Items, err = loadSubscriptionInfos(h.db, userID, newTimeFormat(c))
+	if err != nil {
+		log.Printf("[SYNC] Failed to get subscriptions for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询订阅失败")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// queryLimit 解析数量参数，缺省、非法或超出上限时使用默认值
+func queryLimit(c *gin.Context, name string, defaultLimit, maxLimit int) int {
+	limit, err := strconv.Atoi(c.Query(name))
+	if err != nil || limit <= 0 || limit > maxLimit {
+		return defaultLimit
+	}
+	return limit
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestSyncAllIncludesChangedArticles(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := database.CreateSource("https://example.com/feed", "Feed", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateSubscription(user.ID, source.ID); err != nil {
+		t.Fatal(err)
+	}
+	published := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	deliver := func(guid string) int64 {
+		t.Helper()
+		res, err := database.Exec("INSERT INTO items (source_id, guid, title, xml_content, published_at) VALUES (?, ?, ?, '', ?)",
+			source.ID, guid, guid, published)
+		if err != nil {
+			t.Fatal(err)
+		}
+		itemID, _ := res.LastInsertId()
+		if err := database.CreateUserDelivery(user.ID, itemID); err != nil {
+			t.Fatal(err)
+		}
+		return itemID
+	}
+	unchanged := deliver("unchanged")
+	favorited := deliver("favorited")
+	// 上次同步之前的投递
+	if _, err := database.Exec("UPDATE user_deliveries SET updated_at = ?", published); err != nil {
+		t.Fatal(err)
+	}
+	since := time.Now().Add(-time.Minute)
+
+	// 上次同步之后：旧文章被收藏、投递了一篇发布时间更早的新文章
+	if err := database.SetFavorite(user.ID, favorited, true); err != nil {
+		t.Fatal(err)
+	}
+	added := deliver("added")
+
+	gin.SetMode(gin.TestMode)
+	h := NewSyncHandler(database, nil, "")
+	router := gin.New()
+	router.GET("/sync/all", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, h.SyncAll)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/sync/all?since=%d", since.Unix()), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("sync/all = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp SyncAllResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[int64]ArticleListItem)
+	for _, item := range resp.Articles.Items {
+		got[item.ID] = item
+	}
+	if len(got) != 2 {
+		t.Fatalf("articles = %+v, want the favorited and added articles", resp.Articles.Items)
+	}
+	if _, ok := got[unchanged]; ok {
+		t.Error("unchanged article returned")
+	}
+	if item, ok := got[favorited]; !ok || !item.IsFavorite || item.UpdatedAt < since.Unix() {
+		t.Errorf("favorited article = %+v, want isFavorite with updatedAt after since", item)
+	}
+	if _, ok := got[added]; !ok {
+		t.Error("newly delivered article missing")
+	}
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestWriteRSSXMLPodcast(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &SyncHandler{}
+	render := func(items []*db.Item) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		if err := h.writeRSSXML(c.Writer, eachItem(items), 0); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Body.String()
+	}
+	article := &db.Item{ID: 1, GUID: "a", Title: "Article", XMLContent: "<description><![CDATA[text]]></description>"}
+
+	// 没有播客字段时不声明命名空间
+	if out := render([]*db.Item{article}); strings.Contains(out, "itunes") {
+		t.Errorf("unexpected itunes output:\n%s", out)
+	}
+
+	episode := &db.Item{
+		ID: 2, GUID: "b", Title: "Episode 12", XMLContent: "<description><![CDATA[notes]]></description>",
+		ITunesDuration: "1:02:03", ITunesImage: "https://cdn.example.com/ep12.jpg?w=1&h=1",
+		ITunesEpisode: "12", ITunesAuthor: "Tom & Jerry",
+	}
+	out := render([]*db.Item{article, episode})
+	if !strings.Contains(out, `xmlns:itunes="`+itunesNamespace+`"`) {
+		t.Fatalf("missing itunes namespace:\n%s", out)
+	}
+
+	feed, err := gofeed.NewParser().ParseString(out)
+	if err != nil {
+		t.Fatalf("parse sync output: %v\n%s", err, out)
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("got %d items", len(feed.Items))
+	}
+	if feed.Items[0].ITunesExt != nil && feed.Items[0].ITunesExt.Duration != "" {
+		t.Errorf("article got podcast fields: %+v", feed.Items[0].ITunesExt)
+	}
+	ext := feed.Items[1].ITunesExt
+	if ext == nil || ext.Duration != "1:02:03" || ext.Image != episode.ITunesImage || ext.Episode != "12" || ext.Author != "Tom & Jerry" {
+		t.Errorf("podcast fields not round-tripped: %+v", ext)
+	}
+}
+
+// eachItem 把文章切片包装为 writeRSSXML 的逐条迭代
+func eachItem(items []*db.Item) func(func(*db.Item) error) error {
+	return func(fn func(*db.Item) error) error {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestSyncXMLStreamsPages(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := database.CreateSource("https://example.com/feed", "Feed & Co", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 超过一页（syncFlushEvery），部分文章发布时间相同，分页游标需按文章 ID 区分
+	const total = syncFlushEvery*2 + 5
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < total; i++ {
+		guid := fmt.Sprintf("g%d", i)
+		res, err := database.Exec("INSERT INTO items (source_id, guid, title, xml_content, published_at) VALUES (?, ?, ?, ?, ?)",
+			source.ID, guid, guid, "<description><![CDATA[body "+guid+"]]></description>", base.Add(time.Duration(i/3)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		itemID, _ := res.LastInsertId()
+		if err := database.CreateUserDelivery(user.ID, itemID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	h := NewSyncHandler(database, nil, "")
+	router := gin.New()
+	router.GET("/sync", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, h.Sync)
+	fetch := func(limit int) *gofeed.Feed {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/sync?limit=%d", limit), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("sync = %d: %s", rec.Code, rec.Body.String())
+		}
+		feed, err := gofeed.NewParser().ParseString(rec.Body.String())
+		if err != nil {
+			t.Fatalf("parse sync output: %v", err)
+		}
+		return feed
+	}
+
+	feed := fetch(200)
+	if len(feed.Items) != total {
+		t.Fatalf("got %d items, want %d", len(feed.Items), total)
+	}
+	seen := make(map[string]bool)
+	for i, item := range feed.Items {
+		if seen[item.GUID] {
+			t.Errorf("duplicate item %s", item.GUID)
+		}
+		seen[item.GUID] = true
+		if item.Description != "body "+item.GUID {
+			t.Errorf("item %s description = %q", item.GUID, item.Description)
+		}
+		if i > 0 && item.PublishedParsed.After(*feed.Items[i-1].PublishedParsed) {
+			t.Errorf("item %d out of order", i)
+		}
+	}
+
+	// limit 不是页大小的整数倍时只返回 limit 篇
+	if feed := fetch(syncFlushEvery + 3); len(feed.Items) != syncFlushEvery+3 {
+		t.Errorf("limited sync returned %d items", len(feed.Items))
+	}
+}
+
+func TestUnreadCount(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := database.CreateSource("https://example.com/feed", "feed", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var itemIDs []int64
+	for _, guid := range []string{"a", "b", "c"} {
+		res, err := database.Exec("INSERT INTO items (source_id, guid, title, xml_content) VALUES (?, ?, ?, '')", source.ID, guid, guid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		itemID, _ := res.LastInsertId()
+		if err := database.CreateUserDelivery(user.ID, itemID); err != nil {
+			t.Fatal(err)
+		}
+		itemIDs = append(itemIDs, itemID)
+	}
+
+	gin.SetMode(gin.TestMode)
+	h := NewSyncHandler(database, nil, "")
+	router := gin.New()
+	router.GET("/unread/count", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, h.UnreadCount)
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/unread/count", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"unread":3}` {
+		t.Fatalf("unread count = %d %s", rec.Code, rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	if rec := get(etag); rec.Code != http.StatusNotModified {
+		t.Errorf("repeat poll = %d, want 304", rec.Code)
+	}
+
+	// 标记已读后 ETag 变化
+	if err := database.BatchUpdateDeliveryStatus(user.ID, itemIDs[:1], 1); err != nil {
+		t.Fatal(err)
+	}
+	if rec := get(etag); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"unread":2}` {
+		t.Errorf("after read = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSyncCounts(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy, err := database.CreateSource("https://example.com/busy", "busy", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := database.CreateSource("https://example.com/empty", "empty", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range []*db.Source{busy, empty} {
+		if err := database.CreateSubscription(user.ID, source.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var itemIDs []int64
+	for _, guid := range []string{"a", "b", "c"} {
+		res, err := database.Exec("INSERT INTO items (source_id, guid, title, xml_content) VALUES (?, ?, ?, '')", busy.ID, guid, guid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		itemID, _ := res.LastInsertId()
+		if err := database.CreateUserDelivery(user.ID, itemID); err != nil {
+			t.Fatal(err)
+		}
+		itemIDs = append(itemIDs, itemID)
+	}
+	if err := database.BatchUpdateDeliveryStatus(user.ID, itemIDs[:1], 1); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	h := NewSyncHandler(database, nil, "")
+	router := gin.New()
+	router.GET("/sync/counts", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, h.Counts)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sync/counts", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("counts = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp struct {
+		Sources []struct {
+			SourceID int64 `json:"source_id"`
+			Unread   int   `json:"unread"`
+			Total    int   `json:"total"`
+		} `json:"sources"`
+		Unread int `json:"unread"`
+		Total  int `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Unread != 2 || resp.Total != 3 || len(resp.Sources) != 2 {
+		t.Fatalf("counts = %s", rec.Body.String())
+	}
+	for _, sc := range resp.Sources {
+		want := [2]int{2, 3}
+		if sc.SourceID == empty.ID {
+			want = [2]int{0, 0} // 没有文章的订阅源也返回
+		}
+		if [2]int{sc.Unread, sc.Total} != want {
+			t.Errorf("source %d: unread/total = %d/%d, want %d/%d", sc.SourceID, sc.Unread, sc.Total, want[0], want[1])
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON API 的时间统一为 Unix 秒（整数，未设置时为 null），约定见 docs/timestamps.md。
+// 统一之前部分接口输出 RFC3339 字符串（/health、生词同步的 server_time、订阅和管理接口的时间字段等），
+// 兼容期内这些字段默认保持原格式，请求 legacy_fields=false 时改为 Unix 秒；文章等原本就是 Unix 秒的字段不受影响。
+// RSS XML（/api/sync）的 pubDate 等按 RSS 规范保持 RFC1123Z
+
+// legacyFieldsEnabled 是否输出兼容旧客户端的字段和格式，请求 legacy_fields=false 时关闭
+func legacyFieldsEnabled(c *gin.Context) bool {
+	return c.Query("legacy_fields") != "false"
+}
+
+// timeFormat 单次响应中时间字段的输出格式
+type timeFormat struct {
+	legacy bool // 兼容期内保持字段原来的 RFC3339 格式
+}
+
+// newTimeFormat 按请求参数 legacy_fields 决定时间字段的格式
+func newTimeFormat(c *gin.Context) timeFormat {
+	return timeFormat{legacy: legacyFieldsEnabled(c)}
+}
+
+// at 输出时间字段：Unix 秒，兼容模式下为原来的 time.Time（编码为 RFC3339）
+func (f timeFormat) at(t time.Time) interface{} {
+	if f.legacy {
+		return t
+	}
+	return unixTime(t)
+}
+
+// atPtr 输出可为空的时间字段，nil 时为 null
+func (f timeFormat) atPtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return f.at(*t)
+}
+
+// unixTime 转为 Unix 秒，零值为 0
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// unixTimePtr 转为 Unix 秒，nil 时返回 nil
+func unixTimePtr(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	unix := unixTime(*t)
+	return &unix
+}
+
+// parseTimestamp 解析请求中的时间参数：Unix 秒，兼容 RFC3339（如旧版本响应中的 server_time）
+func parseTimestamp(value string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
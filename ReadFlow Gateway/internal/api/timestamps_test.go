@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	for _, value := range []string{"1714552200", "2024-05-01T08:30:00Z", "2024-05-01T16:30:00+08:00"} {
+		got, err := parseTimestamp(value)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseTimestamp(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "yesterday", "2024-05-01"} {
+		if _, err := parseTimestamp(value); err == nil {
+			t.Errorf("parseTimestamp(%q) succeeded", value)
+		}
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	created := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	token := &db.APIToken{ID: 7, Name: "cli", CreatedAt: created}
+
+	encode := func(query string) map[string]interface{} {
+		t.Helper()
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/tokens"+query, nil)
+		data, err := json.Marshal(newAPITokenJSON(newTimeFormat(c), token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		return fields
+	}
+
+	// 兼容期内默认保持 RFC3339
+	legacy := encode("")
+	if legacy["created_at"] != "2024-05-01T08:30:00Z" || legacy["last_used_at"] != nil {
+		t.Errorf("legacy format = %v", legacy)
+	}
+
+	// legacy_fields=false 时为 Unix 秒，其余字段不受影响
+	unix := encode("?legacy_fields=false")
+	if unix["created_at"] != float64(created.Unix()) || unix["last_used_at"] != nil {
+		t.Errorf("unix format = %v", unix)
+	}
+	if unix["id"] != float64(7) || unix["name"] != "cli" {
+		t.Errorf("embedded fields = %v", unix)
+	}
+}
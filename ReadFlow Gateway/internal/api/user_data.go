@@ -0,0 +1,541 @@
+package api
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// 用户数据导出格式，格式说明见 docs/user_data_export.md
+const (
+	userExportFormat  = "readflow-export"
+	userExportVersion = 1
+)
+
+// 导出 / 导入限制
+const (
+	userExportFlushEvery = 200      // 每写出多少行刷新一次响应
+	maxUserImportSize    = 50 << 20 // 导入文件大小上限
+	maxUserImportLine    = 1 << 20  // 单行大小上限
+)
+
+// 导出文件中除 db.ExportKind* 以外的记录类型
+const (
+	exportKindHeader      = "header"
+	exportKindPreferences = "preferences"
+	exportKindEnd         = "end"
+)
+
+// confirmPasswordHeader 导出时用于再次确认密码的请求头（不放在查询参数中，避免密码出现在访问日志里）
+const confirmPasswordHeader = "X-Confirm-Password"
+
+// UserDataHandler 用户数据导出 / 导入处理器
+type UserDataHandler struct {
+	db            *db.DB
+	auth          *AuthService      // 校验密码
+	subscriptions *SubscribeHandler // 导入订阅时复用主机限制和补发文章
+}
+
+// NewUserDataHandler 创建用户数据导出 / 导入处理器
+func NewUserDataHandler(database *db.DB, auth *AuthService, subscriptions *SubscribeHandler) *UserDataHandler {
+	return &UserDataHandler{db: database, auth: auth, subscriptions: subscriptions}
+}
+
+// exportLine 导出文件中的一行
+type exportLine struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// importLine 导入时解析的一行，data 按 type 再解析
+type importLine struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// exportHeader 导出文件的第一行
+type exportHeader struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	ExportedAt int64  `json:"exported_at"`
+	Username   string `json:"username"`
+}
+
+// exportEnd 导出文件的最后一行，缺少时说明导出中途中断
+type exportEnd struct {
+	Counts map[string]int `json:"counts"`
+}
+
+// UserImportResponse 导入结果
+type UserImportResponse struct {
+	Success            bool `json:"success"`
+	Complete           bool `json:"complete"`            // 文件以结束记录结尾（导出未中断）
+	Preferences        bool `json:"preferences"`         // 是否导入了偏好设置
+	Groups             int  `json:"groups"`              // 新建的分组数（同名分组复用）
+	Subscriptions      int  `json:"subscriptions"`       // 新增的订阅数
+	FilterRules        int  `json:"filter_rules"`        // 新建的过滤规则数
+	Vocabulary         int  `json:"vocabulary"`          // 写入的生词数
+	ReadState          int  `json:"read_state"`          // 写入的阅读状态数（本实例已有的不覆盖）
+	Favorites          int  `json:"favorites"`           // 标为收藏的文章数
+	FavoritesUnmatched int  `json:"favorites_unmatched"` // 文章尚未入库、暂时无法恢复的收藏数
+	Skipped            int  `json:"skipped"`             // 格式错误、校验失败或写入失败的记录数
+	QuotaReached       bool `json:"quota_reached"`       // 达到订阅数或生词数上限，部分记录未导入
+}
+
+// requirePasswordSession 返回当前用户；只接受登录凭证（JWT），并要求在请求头中再次提供密码
+func (h *UserDataHandler) requirePasswordSession(c *gin.Context) (*db.User, bool) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return nil, false
+	}
+	if _, viaToken := c.Get(apiTokenContextKey); viaToken {
+		respondError(c, http.StatusForbidden, CodeForbidden, "API 令牌不能导出数据，请使用登录凭证")
+		return nil, false
+	}
+	password := c.GetHeader(confirmPasswordHeader)
+	if password == "" {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请在 "+confirmPasswordHeader+" 请求头中提供当前密码")
+		return nil, false
+	}
+	user, err := h.db.GetUserByID(userID)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return nil, false
+	}
+	if !h.auth.CheckPasswordHash(password, user.PasswordHash) {
+		respondError(c, http.StatusForbidden, CodeForbidden, "密码错误")
+		return nil, false
+	}
+	return user, true
+}
+
+// Export 以 NDJSON 流式导出当前用户的数据 GET /api/user/export
+// 包含偏好设置、分组、订阅、过滤规则、生词本、阅读状态和收藏，不包含文章正文；
+// 需在 X-Confirm-Password 请求头中提供当前密码。各表分页读取、逐行写出，内存占用与数据量无关。
+// 响应开始后出错只能中断输出，此时文件缺少最后的 end 记录，导入时会据此提示文件不完整
+func (h *UserDataHandler) Export(c *gin.Context) {
+	user, ok := h.requirePasswordSession(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	filename := fmt.Sprintf("readflow-export-%d-%s.ndjson", user.ID, now.Format("20060102"))
+	c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	counts := make(map[string]int)
+	lines := 0
+	write := func(kind string, data interface{}) error {
+		if err := encoder.Encode(exportLine{Type: kind, Data: data}); err != nil {
+			return err
+		}
+		counts[kind]++
+		if lines++; lines%userExportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	}
+
+	err := write(exportKindHeader, exportHeader{
+		Format: userExportFormat, Version: userExportVersion, ExportedAt: now.Unix(), Username: user.Username,
+	})
+	if err == nil {
+		if pref, prefErr := h.db.GetUserPreferences(user.ID); prefErr == nil {
+			err = write(exportKindPreferences, pref)
+		} else if !errors.Is(prefErr, sql.ErrNoRows) {
+			err = prefErr
+		}
+	}
+	if err == nil {
+		err = h.db.ExportUserData(user.ID, func(kind string, record interface{}) error {
+			if vocab, ok := record.(*db.Vocabulary); ok {
+				record = toVocabWordFull(vocab)
+			}
+			return write(kind, record)
+		})
+	}
+	if err != nil {
+		log.Printf("[EXPORT] Export for user %d aborted after %d lines: %v", user.ID, lines, err)
+		return
+	}
+
+	delete(counts, exportKindHeader)
+	if err := write(exportKindEnd, exportEnd{Counts: counts}); err != nil {
+		log.Printf("[EXPORT] Export for user %d aborted at end record: %v", user.ID, err)
+		return
+	}
+	c.Writer.Flush()
+	log.Printf("[EXPORT] User %d exported %d lines", user.ID, lines)
+}
+
+// userImport 一次导入的状态
+type userImport struct {
+	h            *UserDataHandler
+	userID       int64
+	resp         UserImportResponse
+	groupIDs     map[int64]int64 // 导出文件中的分组 ID -> 本实例的分组 ID
+	groupsByName map[string]int64
+	subQuota     QuotaUsage
+	vocabQuota   QuotaUsage
+}
+
+// Import 导入 Export 生成的 NDJSON 文件 POST /api/user/import
+// 与已有数据合并：同名分组复用、已订阅的源不重复订阅、相同的过滤规则不重复创建，生词按 updated_at 合并，
+// 本实例已有的阅读状态保留；收藏只能恢复到已入库的文章。新订阅受订阅数上限和订阅源主机限制约束
+func (h *UserDataHandler) Import(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+	if _, viaToken := c.Get(apiTokenContextKey); viaToken {
+		respondError(c, http.StatusForbidden, CodeForbidden, "API 令牌不能导入数据，请使用登录凭证")
+		return
+	}
+
+	imp := &userImport{h: h, userID: userID, groupIDs: make(map[int64]int64), groupsByName: make(map[string]int64)}
+	imp.resp.Success = true
+	if err := imp.loadState(); err != nil {
+		log.Printf("[IMPORT] Load state for user %d failed: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "读取当前数据失败")
+		return
+	}
+
+	scanner := bufio.NewScanner(http.MaxBytesReader(c.Writer, c.Request.Body, maxUserImportSize))
+	scanner.Buffer(make([]byte, 64*1024), maxUserImportLine)
+	sawHeader := false
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var line importLine
+		if err := json.Unmarshal([]byte(text), &line); err != nil {
+			if !sawHeader {
+				break
+			}
+			imp.resp.Skipped++
+			continue
+		}
+
+		if !sawHeader {
+			var header exportHeader
+			if line.Type != exportKindHeader || json.Unmarshal(line.Data, &header) != nil ||
+				header.Format != userExportFormat || header.Version < 1 || header.Version > userExportVersion {
+				break
+			}
+			sawHeader = true
+			continue
+		}
+		if line.Type == exportKindEnd {
+			imp.resp.Complete = true
+			continue
+		}
+		if !imp.apply(line) {
+			imp.resp.Skipped++
+		}
+	}
+
+	if !sawHeader {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "不是有效的 ReadFlow 导出文件，或版本不受支持")
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		// 已导入的记录保留，返回已处理部分的结果
+		log.Printf("[IMPORT] Read import for user %d failed: %v", userID, err)
+		imp.resp.Complete = false
+	}
+
+	log.Printf("[IMPORT] User %d imported: %+v", userID, imp.resp)
+	c.JSON(http.StatusOK, imp.resp)
+}
+
+// loadState 读取导入前的分组和配额
+func (imp *userImport) loadState() error {
+	groups, err := imp.h.db.GetUserGroups(imp.userID)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if _, ok := imp.groupsByName[g.Name]; !ok {
+			imp.groupsByName[g.Name] = g.ID
+		}
+	}
+	if imp.subQuota, err = subscriptionQuota(imp.h.db, imp.userID); err != nil {
+		return err
+	}
+	imp.vocabQuota, err = vocabularyQuota(imp.h.db, imp.userID)
+	return err
+}
+
+// apply 导入一条记录，返回 false 表示记录无效或写入失败（计入 skipped）；未知类型跳过，兼容更新版本的导出文件
+func (imp *userImport) apply(line importLine) bool {
+	var err error
+	switch line.Type {
+	case exportKindPreferences:
+		err = imp.importPreferences(line.Data)
+	case db.ExportKindGroup:
+		err = imp.importGroup(line.Data)
+	case db.ExportKindSubscription:
+		err = imp.importSubscription(line.Data)
+	case db.ExportKindFilterRule:
+		err = imp.importFilterRule(line.Data)
+	case db.ExportKindVocabulary:
+		err = imp.importVocabulary(line.Data)
+	case db.ExportKindReadState:
+		err = imp.importReadState(line.Data)
+	case db.ExportKindFavorite:
+		err = imp.importFavorite(line.Data)
+	default:
+		return false
+	}
+	if errors.Is(err, errImportQuota) {
+		imp.resp.QuotaReached = true
+		return true
+	}
+	if err != nil {
+		log.Printf("[IMPORT] Skip %s record for user %d: %v", line.Type, imp.userID, err)
+		return false
+	}
+	return true
+}
+
+var (
+	errImportInvalid = errors.New("invalid record")
+	errImportQuota   = errors.New("quota reached")
+)
+
+func (imp *userImport) importPreferences(data json.RawMessage) error {
+	var pref db.UserPreference
+	if err := json.Unmarshal(data, &pref); err != nil {
+		return err
+	}
+	pref.UserID = imp.userID
+	if err := imp.h.db.UpsertUserPreferences(&pref, nil); err != nil {
+		return err
+	}
+	imp.resp.Preferences = true
+	return nil
+}
+
+func (imp *userImport) importGroup(data json.RawMessage) error {
+	var group db.ExportGroup
+	if err := json.Unmarshal(data, &group); err != nil {
+		return err
+	}
+	name := strings.TrimSpace(group.Name)
+	if name == "" {
+		return errImportInvalid
+	}
+	if id, ok := imp.groupsByName[name]; ok {
+		imp.groupIDs[group.ID] = id
+		return nil
+	}
+	created, err := imp.h.db.CreateGroup(imp.userID, name, group.Icon, group.Color)
+	if err != nil {
+		return err
+	}
+	imp.groupIDs[group.ID] = created.ID
+	imp.groupsByName[name] = created.ID
+	imp.resp.Groups++
+	return nil
+}
+
+func (imp *userImport) importSubscription(data json.RawMessage) error {
+	var sub db.ExportSubscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return err
+	}
+	if sub.URL == "" {
+		return errImportInvalid
+	}
+	if err := imp.h.subscriptions.hosts.Check(sub.URL); err != nil {
+		return err
+	}
+
+	source, err := imp.h.db.GetUserSourceByURL(imp.userID, sub.URL)
+	if err == sql.ErrNoRows {
+		if imp.subQuota.exceeded(1) {
+			return errImportQuota
+		}
+		if source, err = imp.subscribe(&sub); err != nil {
+			return err
+		}
+		imp.subQuota.Used++
+		imp.resp.Subscriptions++
+	} else if err != nil {
+		return err
+	}
+
+	if sub.GroupID != nil {
+		if groupID, ok := imp.groupIDs[*sub.GroupID]; ok {
+			_, err := imp.h.db.SetSubscriptionGroups(imp.userID, []db.GroupAssignment{{SourceID: source.ID, GroupID: &groupID}})
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribe 订阅导入的源：源不存在时按导出的标题、分类和语言创建，已存在时补发最近的文章
+func (imp *userImport) subscribe(sub *db.ExportSubscription) (*db.Source, error) {
+	source, err := imp.h.db.GetSourceByURL(sub.URL)
+	isNewSource := err == sql.ErrNoRows
+	if isNewSource {
+		source, err = imp.h.db.CreateClassifiedSource(sub.URL, sub.Title, "", config.GetRuntimeConfig().GetFetchInterval(),
+			utils.NewTextProcessor().NormalizeCategory(sub.Category), utils.NormalizeLanguage(sub.Language))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := imp.h.db.CreateSubscription(imp.userID, source.ID); err != nil {
+		return nil, err
+	}
+	if !isNewSource {
+		imp.h.subscriptions.backfill(imp.userID, source)
+	}
+	return source, nil
+}
+
+func (imp *userImport) importFilterRule(data json.RawMessage) error {
+	var rule db.ExportFilterRule
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return err
+	}
+	if rule.Keyword == "" || utf8.RuneCountInString(rule.Keyword) > maxFilterKeywordLen {
+		return errImportInvalid
+	}
+	if rule.IsRegex {
+		if err := utils.ValidateRegex(rule.Keyword); err != nil {
+			return err
+		}
+	}
+	if rule.Mode == "" {
+		rule.Mode = "exclude"
+	}
+	if rule.Scope == "" {
+		rule.Scope = "specific"
+	}
+
+	// 只绑定用户已订阅的源（订阅记录在过滤规则之前导入）
+	sourceIDs := make([]int64, 0, len(rule.SourceURLs))
+	for _, sourceURL := range rule.SourceURLs {
+		if source, err := imp.h.db.GetUserSourceByURL(imp.userID, sourceURL); err == nil {
+			sourceIDs = append(sourceIDs, source.ID)
+		}
+	}
+	created, err := imp.h.db.ImportFilterRule(imp.userID, &rule, sourceIDs)
+	if created {
+		imp.resp.FilterRules++
+	}
+	return err
+}
+
+func (imp *userImport) importVocabulary(data json.RawMessage) error {
+	var word VocabWordFull
+	if err := json.Unmarshal(data, &word); err != nil {
+		return err
+	}
+	if word.ID == "" || word.Word == "" || word.IsDeleted {
+		return errImportInvalid
+	}
+
+	// 词条 ID 全局唯一，属于其他用户的 ID 不能覆盖
+	existing, err := imp.h.db.GetVocabularyByID(word.ID)
+	switch {
+	case err == nil && existing.UserID != imp.userID:
+		return errImportInvalid
+	case err != nil && err != sql.ErrNoRows:
+		return err
+	}
+	isNew := err == sql.ErrNoRows || existing.IsDeleted
+	if isNew && imp.vocabQuota.exceeded(1) {
+		return errImportQuota
+	}
+
+	createdAt := word.CreatedAt
+	if createdAt == 0 {
+		createdAt = time.Now().Unix()
+	}
+	if err := imp.h.db.UpsertVocabulary(&db.Vocabulary{
+		ID:                 word.ID,
+		UserID:             imp.userID,
+		Word:               word.Word,
+		Definition:         word.Definition,
+		Translation:        word.Translation,
+		Example:            word.Example,
+		Context:            word.Context,
+		SourceArticleID:    word.SourceArticleID,
+		SourceArticleTitle: word.SourceArticleTitle,
+		ArticleID:          word.ArticleID,
+		ReviewCount:        word.ReviewCount,
+		CorrectCount:       word.CorrectCount,
+		LastReviewAt:       word.LastReviewAt,
+		NextReviewAt:       word.NextReviewAt,
+		MasteryLevel:       word.MasteryLevel,
+		Difficulty:         word.Difficulty,
+		Tags:               word.Tags,
+		Notes:              word.Notes,
+		AddedAt:            word.AddedAt,
+		CreatedAt:          createdAt,
+		UpdatedAt:          word.UpdatedAt,
+	}); err != nil {
+		return err
+	}
+	if isNew {
+		imp.vocabQuota.Used++
+	}
+	imp.resp.Vocabulary++
+	return nil
+}
+
+func (imp *userImport) importReadState(data json.RawMessage) error {
+	var state db.ExportReadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.ContentHash == "" || state.ReadProgress < 0 || state.ReadProgress > 100 {
+		return errImportInvalid
+	}
+	written, err := imp.h.db.ImportReadState(imp.userID, &state)
+	if written {
+		imp.resp.ReadState++
+	}
+	return err
+}
+
+func (imp *userImport) importFavorite(data json.RawMessage) error {
+	var favorite db.ExportFavorite
+	if err := json.Unmarshal(data, &favorite); err != nil {
+		return err
+	}
+	if favorite.ContentHash == "" {
+		return errImportInvalid
+	}
+	matched, err := imp.h.db.ImportFavorite(imp.userID, favorite.ContentHash)
+	if err != nil {
+		return err
+	}
+	if matched > 0 {
+		imp.resp.Favorites++
+	} else {
+		imp.resp.FavoritesUnmatched++
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/db"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newUserDataRouter 创建以 userID 身份访问导出 / 导入接口的路由
+func newUserDataRouter(t *testing.T, database *db.DB, userID int64) *gin.Engine {
+	t.Helper()
+	auth := NewAuthService(database, &config.Config{JWTSecret: "test", BcryptCost: bcrypt.MinCost})
+	h := NewUserDataHandler(database, auth, NewSubscribeHandler(database, nil, nil))
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", userID) })
+	router.GET("/export", h.Export)
+	router.POST("/import", h.Import)
+	return router
+}
+
+func TestUserDataExportImport(t *testing.T) {
+	src, err := db.New(filepath.Join(t.TempDir(), "src.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer src.Close()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := src.CreateUser("alice", "alice@example.com", string(hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := src.CreateSource("https://example.com/feed", "Example", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := src.CreateSubscription(alice.ID, source.ID); err != nil {
+		t.Fatal(err)
+	}
+	group, err := src.CreateGroup(alice.ID, "Tech", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.SetSubscriptionGroups(alice.ID, []db.GroupAssignment{{SourceID: source.ID, GroupID: &group.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	if err := src.UpsertVocabulary(&db.Vocabulary{ID: "w1", UserID: alice.ID, Word: "serendipity", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.UpsertUserPreferences(&db.UserPreference{UserID: alice.ID, DefaultCategory: "tech"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	rule, err := src.Exec("INSERT INTO filter_rules (user_id, keyword, is_regex, mode, scope) VALUES (?, 'sponsored', 0, 'exclude', 'specific')", alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleID, _ := rule.LastInsertId()
+	if _, err := src.Exec("INSERT INTO filter_bindings (rule_id, user_id, source_id) VALUES (?, ?, ?)", ruleID, alice.ID, source.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 阅读状态和收藏按内容哈希导出；两个实例抓取到同一篇文章时哈希相同
+	articles := []string{"hash-read", "hash-progress", "hash-favorite"}
+	newItem := func(database *db.DB, sourceID int64, hash string) int64 {
+		t.Helper()
+		res, err := database.Exec("INSERT INTO items (source_id, guid, title, xml_content, content_hash, url, published_at) VALUES (?, ?, ?, '', ?, ?, ?)",
+			sourceID, hash, "Title "+hash, hash, "https://example.com/"+hash, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+	srcItems := make(map[string]int64)
+	for _, hash := range articles {
+		srcItems[hash] = newItem(src, source.ID, hash)
+		if err := src.CreateUserDelivery(alice.ID, srcItems[hash]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.MarkArticleAsRead(alice.ID, srcItems["hash-read"]); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.UpdateReadProgress(alice.ID, srcItems["hash-progress"], 40); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.SetFavorite(alice.ID, srcItems["hash-favorite"], true); err != nil {
+		t.Fatal(err)
+	}
+
+	router := newUserDataRouter(t, src, alice.ID)
+	export := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/export", nil)
+		if password != "" {
+			req.Header.Set(confirmPasswordHeader, password)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := export(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("export without password: status %d, want 400", rec.Code)
+	}
+	if rec := export("wrong"); rec.Code != http.StatusForbidden {
+		t.Errorf("export with wrong password: status %d, want 403", rec.Code)
+	}
+	rec := export("secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/x-ndjson") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	var types []string
+	scanner := bufio.NewScanner(strings.NewReader(rec.Body.String()))
+	for scanner.Scan() {
+		var line importLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, line.Type)
+	}
+	want := []string{"header", "preferences", "group", "subscription", "filter_rule", "vocabulary", "read_state", "read_state", "favorite", "end"}
+	if strings.Join(types, ",") != strings.Join(want, ",") {
+		t.Errorf("line types = %v, want %v", types, want)
+	}
+	exported := rec.Body.String()
+
+	// 导入到另一个实例
+	dst, err := db.New(filepath.Join(t.TempDir(), "dst.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer dst.Close()
+	carol, err := dst.CreateUser("carol", "carol@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 目标实例已抓取过同一个源，订阅时补发这些文章
+	dstSource, err := dst.CreateSource(source.URL, "Example", "", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstItems := make(map[string]int64)
+	for _, hash := range articles {
+		dstItems[hash] = newItem(dst, dstSource.ID, hash)
+	}
+	importRouter := newUserDataRouter(t, dst, carol.ID)
+	importFile := func(body string) (int, UserImportResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		importRouter.ServeHTTP(rec, req)
+		var resp UserImportResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	if code, _ := importFile(`{"type":"group","data":{"id":1,"name":"x"}}`); code != http.StatusBadRequest {
+		t.Errorf("import without header: status %d, want 400", code)
+	}
+
+	code, resp := importFile(exported)
+	if code != http.StatusOK {
+		t.Fatalf("import: status %d", code)
+	}
+	if !resp.Complete || !resp.Preferences || resp.Groups != 1 || resp.Subscriptions != 1 || resp.FilterRules != 1 ||
+		resp.Vocabulary != 1 || resp.ReadState != 2 || resp.Favorites != 1 || resp.FavoritesUnmatched != 0 || resp.Skipped != 0 {
+		t.Errorf("import = %+v", resp)
+	}
+	subs, err := dst.GetUserSubscriptions(carol.ID)
+	if err != nil || len(subs) != 1 || subs[0].URL != source.URL {
+		t.Fatalf("subscriptions = %+v, %v", subs, err)
+	}
+	if pref, err := dst.GetUserPreferences(carol.ID); err != nil || pref.DefaultCategory != "tech" {
+		t.Errorf("preferences = %+v, %v", pref, err)
+	}
+
+	// 阅读状态和收藏恢复到目标实例的同内容文章上
+	for hash, check := range map[string]func(*db.UserArticle) bool{
+		"hash-read":     func(a *db.UserArticle) bool { return a.Status == 2 && a.ReadAt != nil && !a.IsFavorite },
+		"hash-progress": func(a *db.UserArticle) bool { return a.ReadProgress == 40 && a.Status == 0 },
+		"hash-favorite": func(a *db.UserArticle) bool { return a.IsFavorite && a.Status == 0 },
+	} {
+		article, err := dst.GetUserArticle(carol.ID, dstItems[hash])
+		if err != nil {
+			t.Errorf("%s: GetUserArticle: %v", hash, err)
+			continue
+		}
+		if !check(article) {
+			t.Errorf("%s: status=%d progress=%d favorite=%v read_at=%v", hash, article.Status, article.ReadProgress, article.IsFavorite, article.ReadAt)
+		}
+	}
+
+	// 过滤规则绑定到导入的订阅
+	var keyword string
+	var bound int
+	if err := dst.QueryRow(`
+		SELECT fr.keyword, (SELECT COUNT(*) FROM filter_bindings fb WHERE fb.rule_id = fr.id AND fb.source_id = ?)
+		FROM filter_rules fr WHERE fr.user_id = ?
+	`, dstSource.ID, carol.ID).Scan(&keyword, &bound); err != nil || keyword != "sponsored" || bound != 1 {
+		t.Errorf("filter rule = %q bound=%d, %v", keyword, bound, err)
+	}
+
+	// 再次导入同一文件不产生重复数据；缺少 end 行时标记为不完整
+	truncated := exported[:strings.LastIndex(strings.TrimSuffix(exported, "\n"), "\n")+1]
+	code, resp = importFile(truncated)
+	if code != http.StatusOK || resp.Complete || resp.Groups != 0 || resp.Subscriptions != 0 || resp.FilterRules != 0 || resp.ReadState != 0 {
+		t.Errorf("re-import = %d %+v", code, resp)
+	}
+}
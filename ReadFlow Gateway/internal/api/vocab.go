@@ -1,9 +1,12 @@
 package api
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -76,53 +79,49 @@ type PushRequest struct {
 
 // PushResponse Push响应
 type PushResponse struct {
-	Success    bool      `json:"success"`
-	Synced     int       `json:"synced"`
-	Conflicts  int       `json:"conflicts"`
-	ServerTime time.Time `json:"server_time"`
-}
-
-// PullResponse Pull响应
-type PullResponse struct {
-	Words      []VocabWordFull `json:"words"`
-	HasMore    bool            `json:"has_more"`
-	ServerTime time.Time       `json:"server_time"`
+	Success    bool        `json:"success"`
+	Synced     int         `json:"synced"`
+	Conflicts  int         `json:"conflicts"`
+	ServerTime interface{} `json:"server_time"` // Unix 秒，兼容期内默认为 RFC3339
 }
 
 // Push 上传生词本（客户端 -> 服务端）
+// 按 updated_at 合并：更新的覆盖、更旧的忽略，相同时内容不同也接受（见 db.UpsertVocabulary）
 func (h *VocabHandler) Push(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
 	var req PushRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "无效的请求参数",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的请求参数")
 		return
 	}
 
 	// 验证数组长度
 	if len(req.Words) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "words 数组不能为空",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "words 数组不能为空")
 		return
 	}
 
 	if len(req.Words) > 500 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"success": false,
-			"message": "words 数组长度不能超过 500",
-		})
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "words 数组长度不能超过 500")
+		return
+	}
+
+	// 检查生词数配额：只有新增（或恢复已删除）的词条计入，本次删除的词条抵扣
+	// 已超出上限（管理员下调配额）时仍允许更新和删除已有词条
+	quota, allowed, err := h.checkVocabularyQuota(userID, req.Words)
+	if err != nil {
+		log.Printf("Failed to check vocabulary quota for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询生词配额失败")
+		return
+	}
+	if !allowed {
+		respondErrorDetails(c, http.StatusConflict, CodeQuotaExceeded,
+			fmt.Sprintf("生词数超出上限（%d 个）", quota.Limit), quota)
 		return
 	}
 
@@ -184,18 +183,56 @@ func (h *VocabHandler) Push(c *gin.Context) {
 		Success:    true,
 		Synced:     synced,
 		Conflicts:  conflicts,
-		ServerTime: time.Now(),
+		ServerTime: newTimeFormat(c).at(time.Now()),
 	})
 }
 
-// Pull 下载生词本（服务端 -> 客户端）
+// checkVocabularyQuota 计算本次上传对未删除词条数的净增量，返回当前配额及是否允许上传
+func (h *VocabHandler) checkVocabularyQuota(userID int64, words []VocabWord) (QuotaUsage, bool, error) {
+	quota, err := vocabularyQuota(h.db, userID)
+	if err != nil || quota.Limit == 0 {
+		return quota, err == nil, err
+	}
+
+	ids := make([]string, 0, len(words))
+	for _, word := range words {
+		if word.Word != "" && word.ID != "" {
+			ids = append(ids, word.ID)
+		}
+	}
+	active, err := h.db.GetActiveVocabularyIDs(userID, ids)
+	if err != nil {
+		return quota, false, err
+	}
+
+	// 同一词条在一次上传中出现多次时以最后一次为准
+	final := make(map[string]bool, len(ids))
+	for _, word := range words {
+		if word.Word != "" && word.ID != "" {
+			final[word.ID] = !word.IsDeleted
+		}
+	}
+	added, removed := 0, 0
+	for id, alive := range final {
+		switch {
+		case alive && !active[id]:
+			added++
+		case !alive && active[id]:
+			removed++
+		}
+	}
+
+	after := QuotaUsage{Used: quota.Used - removed, Limit: quota.Limit}
+	return quota, !after.exceeded(added), nil
+}
+
+// Pull 下载生词本（服务端 -> 客户端）GET /api/vocab/pull?since=&limit=&cursor=
+// 按更新时间升序分页返回，hasMore 时带上 nextCursor（和相同的 since）继续拉取；
+// 取完所有页后以最后一页的 server_time 作为下次的 since
 func (h *VocabHandler) Pull(c *gin.Context) {
 	userID, err := GetCurrentUserID(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"message": "未授权",
-		})
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
 		return
 	}
 
@@ -204,7 +241,7 @@ func (h *VocabHandler) Pull(c *gin.Context) {
 	limit := 500 // 默认值
 
 	if limitStr := c.Query("limit"); limitStr != "" {
-		if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil {
+		if _, err := fmt.Sscanf(limitStr, "%d", &limit); err != nil || limit <= 0 {
 			limit = 500
 		}
 	}
@@ -214,65 +251,103 @@ func (h *VocabHandler) Pull(c *gin.Context) {
 		limit = 1000
 	}
 
-	// 解析since时间戳（客户端提供Unix时间戳）
+	// 解析since时间戳（客户端提供Unix时间戳，兼容旧版本返回的 RFC3339 server_time）
 	var sinceTimestamp int64 = 0
 	if sinceStr != "" {
-		if _, err := fmt.Sscanf(sinceStr, "%d", &sinceTimestamp); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"success": false,
-				"message": "无效的since参数格式，应为Unix时间戳",
-			})
+		t, err := parseTimestamp(sinceStr)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的since参数格式，应为Unix时间戳")
+			return
+		}
+		sinceTimestamp = t.Unix()
+	}
+
+	var afterUpdatedAt int64
+	var afterID string
+	if cursor := c.Query("cursor"); cursor != "" {
+		if afterUpdatedAt, afterID, err = decodeVocabCursor(cursor); err != nil {
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "无效的cursor参数")
 			return
 		}
 	}
 
+	// 先记录服务端时间再查询，查询期间更新的词条会在下次同步中返回
+	serverTime := time.Now()
+
 	// 查询生词
-	vocabs, err := h.db.GetVocabulariesSince(userID, sinceTimestamp)
+	vocabs, hasMore, err := h.db.GetVocabulariesSince(userID, sinceTimestamp, afterUpdatedAt, afterID, limit)
 	if err != nil {
 		log.Printf("Failed to get vocabularies for user %d: %v", userID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"message": "查询失败",
-		})
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询失败")
 		return
 	}
 
 	// 转换为响应格式
 	words := make([]VocabWordFull, 0, len(vocabs))
 	for _, vocab := range vocabs {
-		words = append(words, VocabWordFull{
-			ID:                 vocab.ID,
-			Word:               vocab.Word,
-			Definition:         vocab.Definition,
-			Translation:        vocab.Translation,
-			Example:            vocab.Example,
-			Context:            vocab.Context,
-			SourceArticleID:    vocab.SourceArticleID,
-			SourceArticleTitle: vocab.SourceArticleTitle,
-			ArticleID:          vocab.ArticleID,
-			ReviewCount:        vocab.ReviewCount,
-			CorrectCount:       vocab.CorrectCount,
-			LastReviewAt:       vocab.LastReviewAt,
-			NextReviewAt:       vocab.NextReviewAt,
-			MasteryLevel:       vocab.MasteryLevel,
-			Difficulty:         vocab.Difficulty,
-			Tags:               vocab.Tags,
-			Notes:              vocab.Notes,
-			AddedAt:            vocab.AddedAt,
-			UpdatedAt:          vocab.UpdatedAt,
-			IsDeleted:          vocab.IsDeleted,
-			CreatedAt:          vocab.CreatedAt,
-		})
-
-		// 限制返回数量
-		if len(words) >= limit {
-			break
-		}
+		words = append(words, toVocabWordFull(vocab))
 	}
 
-	c.JSON(http.StatusOK, PullResponse{
-		Words:      words,
-		HasMore:    len(vocabs) > len(words),
-		ServerTime: time.Now(),
-	})
+	var nextCursor string
+	if hasMore {
+		last := vocabs[len(vocabs)-1]
+		nextCursor = encodeVocabCursor(last.UpdatedAt, last.ID)
+	}
+	legacy := gin.H{"words": words, "has_more": hasMore}
+	if nextCursor != "" {
+		legacy["next_cursor"] = nextCursor
+	}
+	resp := pageResponse(c, words, hasMore, nextCursor, nil, legacy)
+	resp["server_time"] = newTimeFormat(c).at(serverTime)
+	c.JSON(http.StatusOK, resp)
+}
+
+// encodeVocabCursor 编码生词分页游标（"updated_at:id" -> Base64）
+// 词条 ID 由客户端生成，可能包含下划线，不能使用 utils.EncodeCursor 的 "timestamp_id" 格式
+func encodeVocabCursor(updatedAt int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(updatedAt, 10) + ":" + id))
+}
+
+// decodeVocabCursor 解码生词分页游标
+func decodeVocabCursor(cursor string) (int64, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", err
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return 0, "", fmt.Errorf("invalid vocab cursor %q", raw)
+	}
+	updatedAt, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return updatedAt, id, nil
+}
+
+// toVocabWordFull 将数据库中的生词转换为响应结构
+func toVocabWordFull(vocab *db.Vocabulary) VocabWordFull {
+	return VocabWordFull{
+		ID:                 vocab.ID,
+		Word:               vocab.Word,
+		Definition:         vocab.Definition,
+		Translation:        vocab.Translation,
+		Example:            vocab.Example,
+		Context:            vocab.Context,
+		SourceArticleID:    vocab.SourceArticleID,
+		SourceArticleTitle: vocab.SourceArticleTitle,
+		ArticleID:          vocab.ArticleID,
+		ReviewCount:        vocab.ReviewCount,
+		CorrectCount:       vocab.CorrectCount,
+		LastReviewAt:       vocab.LastReviewAt,
+		NextReviewAt:       vocab.NextReviewAt,
+		MasteryLevel:       vocab.MasteryLevel,
+		Difficulty:         vocab.Difficulty,
+		Tags:               vocab.Tags,
+		Notes:              vocab.Notes,
+		AddedAt:            vocab.AddedAt,
+		UpdatedAt:          vocab.UpdatedAt,
+		IsDeleted:          vocab.IsDeleted,
+		CreatedAt:          vocab.CreatedAt,
+	}
 }
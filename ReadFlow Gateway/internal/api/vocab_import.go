@@ -0,0 +1,301 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// 导入限制：上传文件大小、单次导入行数和单词长度
+const (
+	maxImportFileSize = 10 << 20
+	maxImportRows     = 5000
+	maxImportWordLen  = 100
+)
+
+// importColumnAliases 表头列名（小写）到字段的映射，兼容 Anki 导出的 Front/Back
+var importColumnAliases = map[string]string{
+	"word":        "word",
+	"term":        "word",
+	"front":       "word",
+	"单词":          "word",
+	"词条":          "word",
+	"definition":  "definition",
+	"meaning":     "definition",
+	"释义":          "definition",
+	"定义":          "definition",
+	"translation": "translation",
+	"back":        "translation",
+	"翻译":          "translation",
+	"译文":          "translation",
+	"example":     "example",
+	"sentence":    "example",
+	"例句":          "example",
+}
+
+// importDefaultColumns 没有表头时按位置对应的字段
+var importDefaultColumns = []string{"word", "definition", "translation", "example"}
+
+// ImportResponse 导入响应
+type ImportResponse struct {
+	Success      bool `json:"success"`
+	Imported     int  `json:"imported"`      // 新增词条数
+	Skipped      int  `json:"skipped"`       // 单词为空、过长、格式错误或写入失败的行数
+	Duplicates   int  `json:"duplicates"`    // 生词本中已有或文件内重复的单词数
+	Truncated    bool `json:"truncated"`     // 超出单次导入行数上限，后续行未处理
+	QuotaReached bool `json:"quota_reached"` // 达到生词数上限，后续行未处理
+}
+
+// Import 从 CSV 文件导入生词（multipart 字段 file）
+// 支持逗号、分号和制表符分隔（Anki 纯文本导出），自动识别表头；编码支持 UTF-8（含 BOM）、UTF-16（带 BOM）和 GBK。
+// 生词本中已有的单词（不区分大小写）不覆盖，计入 duplicates
+func (h *VocabHandler) Import(c *gin.Context) {
+	userID, err := GetCurrentUserID(c)
+	if err != nil {
+		respondError(c, http.StatusUnauthorized, CodeUnauthorized, "未授权")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "请上传不超过 10MB 的 CSV 文件（字段 file）")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "读取上传文件失败")
+		return
+	}
+	defer file.Close()
+
+	quota, err := vocabularyQuota(h.db, userID)
+	if err != nil {
+		log.Printf("Failed to check vocabulary quota for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询生词配额失败")
+		return
+	}
+	existing, err := h.db.GetUserVocabularyWords(userID)
+	if err != nil {
+		log.Printf("Failed to load vocabulary for user %d: %v", userID, err)
+		respondError(c, http.StatusInternalServerError, CodeInternal, "查询生词本失败")
+		return
+	}
+
+	reader, err := newImportCSVReader(file)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, CodeValidationFailed, "读取上传文件失败")
+		return
+	}
+
+	resp := ImportResponse{Success: true}
+	var columns map[string]int
+	rows := 0
+	now := time.Now().Unix()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				resp.Skipped++
+				continue
+			}
+			log.Printf("Failed to read vocabulary import for user %d: %v", userID, err)
+			respondError(c, http.StatusBadRequest, CodeValidationFailed, "读取上传文件失败")
+			return
+		}
+
+		if columns == nil {
+			var isHeader bool
+			columns, isHeader = detectImportColumns(record)
+			if isHeader {
+				continue
+			}
+		}
+
+		if rows >= maxImportRows {
+			resp.Truncated = true
+			break
+		}
+		rows++
+
+		word := importField(record, columns, "word")
+		key := strings.ToLower(word)
+		switch {
+		case word == "" || utf8.RuneCountInString(word) > maxImportWordLen:
+			resp.Skipped++
+			continue
+		case existing[key]:
+			resp.Duplicates++
+			continue
+		}
+		if quota.exceeded(resp.Imported + 1) {
+			resp.QuotaReached = true
+			break
+		}
+
+		vocab := &db.Vocabulary{
+			ID:          importVocabularyID(userID, key),
+			UserID:      userID,
+			Word:        word,
+			Definition:  importField(record, columns, "definition"),
+			Translation: importField(record, columns, "translation"),
+			Example:     importField(record, columns, "example"),
+			Difficulty:  "medium",
+			AddedAt:     now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		if err := h.db.UpsertVocabulary(vocab); err != nil {
+			log.Printf("Failed to import vocabulary for user %d, word %s: %v", userID, word, err)
+			resp.Skipped++
+			continue
+		}
+		existing[key] = true
+		resp.Imported++
+	}
+
+	log.Printf("User %d imported vocabulary: %d imported, %d skipped, %d duplicates",
+		userID, resp.Imported, resp.Skipped, resp.Duplicates)
+	c.JSON(http.StatusOK, resp)
+}
+
+// newImportCSVReader 按 BOM 和内容识别编码并转为 UTF-8，再按首个数据行判断分隔符
+// 只预读文件开头，整个文件按行流式解析
+func newImportCSVReader(r io.Reader) (*csv.Reader, error) {
+	raw := bufio.NewReaderSize(r, 64<<10)
+	head, err := raw.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	var decoded io.Reader = raw
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		raw.Discard(len(utf8BOM))
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		decoded = transform.NewReader(raw, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder())
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		decoded = transform.NewReader(raw, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder())
+	case !isUTF8Prefix(head):
+		// 不是合法 UTF-8 时按中文 Excel 默认的 GBK（GB18030 兼容）解码
+		decoded = transform.NewReader(raw, simplifiedchinese.GB18030.NewDecoder())
+	}
+
+	text := bufio.NewReaderSize(decoded, 64<<10)
+	head, err = text.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	reader := csv.NewReader(text)
+	reader.Comma = detectDelimiter(string(head))
+	reader.Comment = '#' // Anki 导出文件以 #separator:tab 等注释行开头
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	return reader, nil
+}
+
+// utf8BOM UTF-8 字节序标记（Excel 导出的 CSV 通常带有）
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// isUTF8Prefix 判断预读内容是否为合法 UTF-8
+// 预读可能截断在多字节字符中间，最多去掉末尾 3 个字节再判断
+func isUTF8Prefix(b []byte) bool {
+	for i := 0; i < utf8.UTFMax && i <= len(b); i++ {
+		if utf8.Valid(b[:len(b)-i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectDelimiter 判断分隔符：优先使用 Anki 的 #separator 注释，否则取首个数据行中出现最多的分隔符
+func detectDelimiter(head string) rune {
+	for _, line := range strings.Split(head, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if sep, ok := strings.CutPrefix(strings.ToLower(line), "#separator:"); ok {
+				switch sep {
+				case "tab":
+					return '\t'
+				case "semicolon":
+					return ';'
+				case "comma":
+					return ','
+				}
+			}
+			continue
+		}
+
+		delimiter, best := ',', strings.Count(line, ",")
+		for _, candidate := range []rune{'\t', ';'} {
+			if n := strings.Count(line, string(candidate)); n > best {
+				delimiter, best = candidate, n
+			}
+		}
+		return delimiter
+	}
+	return ','
+}
+
+// detectImportColumns 根据首行判断是否为表头并返回字段所在列
+// 首行能识别出单词列时视为表头，否则按 word, definition, translation, example 的顺序对应
+func detectImportColumns(record []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, cell := range record {
+		name := strings.ToLower(strings.TrimSpace(cell))
+		if field, ok := importColumnAliases[name]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["word"]; ok {
+		return columns, true
+	}
+
+	columns = make(map[string]int, len(importDefaultColumns))
+	for i, field := range importDefaultColumns {
+		columns[field] = i
+	}
+	return columns, false
+}
+
+// importField 读取字段值，列不存在时为空字符串
+func importField(record []string, columns map[string]int, field string) string {
+	i, ok := columns[field]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// importVocabularyID 由用户和单词生成稳定的词条 ID，重复导入同一单词不会产生多条记录
+func importVocabularyID(userID int64, word string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", userID, word)))
+	return "import-" + hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/db"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestDetectDelimiter(t *testing.T) {
+	for name, tc := range map[string]struct {
+		head string
+		want rune
+	}{
+		"comma":             {"word,definition\nhello,greeting\n", ','},
+		"tab":               {"hello\tgreeting\tnǐ hǎo\n", '\t'},
+		"semicolon":         {"hello;greeting;你好\n", ';'},
+		"comma in field":    {"hello;\"a, b\";你好\n", ';'},
+		"anki separator":    {"#separator:tab\n#html:false\nhello,world;x\n", '\t'},
+		"unknown separator": {"#separator:Pipe\nhello;greeting\n", ';'},
+		"comments only":     {"#deck:English\n\n", ','},
+		"empty":             {"", ','},
+	} {
+		if got := detectDelimiter(tc.head); got != tc.want {
+			t.Errorf("%s: detectDelimiter = %q, want %q", name, got, tc.want)
+		}
+	}
+}
+
+func TestDetectImportColumns(t *testing.T) {
+	for name, tc := range map[string]struct {
+		record     []string
+		wantHeader bool
+		want       map[string]int
+	}{
+		"english header": {[]string{"Word", "Meaning", "Example"}, true,
+			map[string]int{"word": 0, "definition": 1, "example": 2}},
+		"anki front back": {[]string{" front ", "back"}, true,
+			map[string]int{"word": 0, "translation": 1}},
+		"chinese header": {[]string{"例句", "单词", "翻译"}, true,
+			map[string]int{"word": 1, "translation": 2, "example": 0}},
+		"duplicate alias keeps first": {[]string{"word", "term", "definition"}, true,
+			map[string]int{"word": 0, "definition": 2}},
+		"no word column": {[]string{"definition", "translation"}, false,
+			map[string]int{"word": 0, "definition": 1, "translation": 2, "example": 3}},
+		"data row": {[]string{"hello", "greeting"}, false,
+			map[string]int{"word": 0, "definition": 1, "translation": 2, "example": 3}},
+	} {
+		columns, isHeader := detectImportColumns(tc.record)
+		if isHeader != tc.wantHeader {
+			t.Errorf("%s: isHeader = %v, want %v", name, isHeader, tc.wantHeader)
+		}
+		if len(columns) != len(tc.want) {
+			t.Errorf("%s: columns = %v, want %v", name, columns, tc.want)
+			continue
+		}
+		for field, i := range tc.want {
+			if columns[field] != i {
+				t.Errorf("%s: columns = %v, want %v", name, columns, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestNewImportCSVReaderEncodings(t *testing.T) {
+	const text = "单词,释义\n你好,hello\n"
+	encode := func(enc interface{ Bytes([]byte) ([]byte, error) }) []byte {
+		b, err := enc.Bytes([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	for name, data := range map[string][]byte{
+		"utf-8":     []byte(text),
+		"utf-8 bom": append([]byte{0xEF, 0xBB, 0xBF}, text...),
+		"utf-16le":  encode(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder()),
+		"utf-16be":  encode(unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewEncoder()),
+		"gbk":       encode(simplifiedchinese.GBK.NewEncoder()),
+		"gb18030":   encode(simplifiedchinese.GB18030.NewEncoder()),
+	} {
+		reader, err := newImportCSVReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		reader.ReuseRecord = false
+		records, err := reader.ReadAll()
+		if err != nil {
+			t.Fatalf("%s: ReadAll: %v", name, err)
+		}
+		if len(records) != 2 || records[0][0] != "单词" || records[1][0] != "你好" || records[1][1] != "hello" {
+			t.Errorf("%s: records = %q", name, records)
+		}
+	}
+}
+
+func TestVocabImport(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("db.New: %v", err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpsertVocabulary(&db.Vocabulary{ID: "v1", UserID: user.ID, Word: "Harbour", Difficulty: "medium"}); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	h := NewVocabHandler(database)
+	router := gin.New()
+	router.POST("/import", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+	}, h.Import)
+
+	upload := func(content string) ImportResponse {
+		t.Helper()
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, err := mw.CreateFormFile("file", "words.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, content)
+		mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/import", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("import = %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp ImportResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// Anki 导出：注释行、制表符分隔、无表头
+	content := strings.Join([]string{
+		"#separator:tab",
+		"#html:false",
+		"apple\t苹果\tfruit\tAn apple a day.",
+		"harbour\t港口",                           // 生词本已有（不区分大小写）
+		"Apple\t苹果",                             // 文件内重复
+		"\tno word",                             // 单词为空
+		strings.Repeat("x", maxImportWordLen+1), // 单词过长
+		"river\t河流",
+	}, "\n")
+	resp := upload(content)
+	if resp.Imported != 2 || resp.Duplicates != 2 || resp.Skipped != 2 || resp.Truncated || resp.QuotaReached {
+		t.Fatalf("first import = %+v", resp)
+	}
+
+	words, err := database.GetUserVocabularyWords(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !words["apple"] || !words["river"] || len(words) != 3 {
+		t.Errorf("vocabulary words = %v", words)
+	}
+
+	// 重新导入同一文件：全部视为重复
+	if resp := upload(content); resp.Imported != 0 || resp.Duplicates != 4 || resp.Skipped != 2 {
+		t.Errorf("repeated import = %+v", resp)
+	}
+}
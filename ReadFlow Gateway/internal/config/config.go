@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config 应用配置
@@ -13,14 +14,19 @@ type Config struct {
 	// 静态文件目录
 	StaticDir string
 
-	// RSS 抓取间隔（秒）
+	// 新订阅源的默认抓取间隔（秒），启动时写入运行时配置
 	FetchInterval int
 
 	// 图片处理配置
-	ImageMaxWidth  int
-	ImageQuality   int
+	ImageMaxWidth   int
+	ImageQuality    int
 	ImageConcurrent int
 
+	// 图片缓存的下载时限（秒）：连接到收到响应头、读取响应体时单次读取的最长等待、单篇文章全部图片的处理时限
+	ImageHeaderTimeout int
+	ImageReadTimeout   int
+	ImageArticleBudget int
+
 	// 服务器配置
 	ServerPort     string
 	ServerPassword string
@@ -28,24 +34,159 @@ type Config struct {
 	// JWT 配置
 	JWTSecret string
 
+	// 密码哈希的 bcrypt 成本（4-31），登录时已有哈希的成本不同会自动按此值重新哈希
+	BcryptCost int
+
+	// 订阅源凭据加密密钥（为空时回退到 JWTSecret）
+	CredentialKey string
+	// 图片代理地址签名密钥（为空时回退到 JWTSecret）
+	ImageProxyKey string
+
+	// 管理员配置
+	AdminUsers string // 逗号分隔的管理员用户名，启动时提升为管理员
+	AdminToken string // 应急管理令牌，为空时禁用
+
+	// 密码重置通知 webhook，为空时重置令牌只写入服务日志
+	PasswordResetWebhook string
+
+	// 出站代理（http/https/socks5），RSS、正文、图片等对外请求统一经此代理，为空时直连
+	OutboundProxy string
+
+	// 订阅源主机允许/禁止列表（逗号分隔，按域名后缀匹配），抓取订阅源和图片代理前检查；都为空时不限制
+	RSSAllowedHosts string
+	RSSDeniedHosts  string
+
+	// 推荐订阅源目录 JSON 文件，为空时使用内置目录
+	CatalogPath string
+
+	// 付费墙提示语（逗号分隔，不区分大小写），全文提取的结果较短且包含其中之一时视为付费墙，沿用 feed 内容；为空时使用内置列表
+	PaywallMarkers string
+
+	// 图片代理限制
+	ImageProxyTimeout      int // 单张图片请求的总时限（秒），包括转发图片内容
+	ImageProxyMaxRedirects int // 最多跟随的重定向次数
+	ImageProxyMaxBytes     int // 单张图片最多转发的字节数
+
+	// 图片代理格式协商：客户端未发送 Accept 时转发给源站的 Accept 头；
+	// 客户端支持、源站只提供 JPEG/PNG 时转码的目标格式（逗号分隔，按优先级，可选 webp、avif），为空时原样转发
+	ImageProxyAccept    string
+	ImageProxyTranscode string
+
+	// 响应压缩配置
+	GzipLevel   int // 压缩级别 1-9，0 表示关闭压缩
+	GzipMinSize int // 小于该字节数的响应不压缩
+
 	// 日志级别
 	LogLevel string
+
+	// 可信反向代理（逗号分隔的 IP 或 CIDR），只有来自这些地址的请求才采信 X-Forwarded-For；为空时使用连接地址
+	TrustedProxies string
 }
 
 // Load 从环境变量加载配置
 func Load() *Config {
 	return &Config{
-		DBPath:          getEnv("DB_PATH", "/app/data/readflow.db"),
-		StaticDir:       getEnv("STATIC_DIR", "/app/static"),
-		FetchInterval:   getEnvInt("FETCH_INTERVAL", 900),
-		ImageMaxWidth:   getEnvInt("IMAGE_MAX_WIDTH", 1080),
-		ImageQuality:    getEnvInt("IMAGE_QUALITY", 75),
-		ImageConcurrent: getEnvInt("IMAGE_CONCURRENT", 2),
-		ServerPort:      getEnv("SERVER_PORT", "8080"),
-		ServerPassword:  getEnv("SERVER_PASSWORD", "change_me_in_production"),
-		JWTSecret:       getEnv("JWT_SECRET", "your_jwt_secret_key_change_in_production"),
-		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		DBPath:                 getEnv("DB_PATH", "/app/data/readflow.db"),
+		StaticDir:              getEnv("STATIC_DIR", "/app/static"),
+		FetchInterval:          getEnvInt("FETCH_INTERVAL", 900),
+		ImageMaxWidth:          getEnvInt("IMAGE_MAX_WIDTH", 1080),
+		ImageQuality:           getEnvInt("IMAGE_QUALITY", 75),
+		ImageConcurrent:        getEnvInt("IMAGE_CONCURRENT", 2),
+		ImageHeaderTimeout:     getEnvInt("IMAGE_HEADER_TIMEOUT", 10),
+		ImageReadTimeout:       getEnvInt("IMAGE_READ_TIMEOUT", 10),
+		ImageArticleBudget:     getEnvInt("IMAGE_ARTICLE_BUDGET", 60),
+		ServerPort:             getEnv("SERVER_PORT", "8080"),
+		ServerPassword:         getEnv("SERVER_PASSWORD", "change_me_in_production"),
+		JWTSecret:              getEnv("JWT_SECRET", "your_jwt_secret_key_change_in_production"),
+		BcryptCost:             getEnvInt("BCRYPT_COST", 14),
+		CredentialKey:          getEnv("CREDENTIAL_KEY", ""),
+		ImageProxyKey:          getEnv("IMAGE_PROXY_KEY", ""),
+		AdminUsers:             getEnv("ADMIN_USERS", ""),
+		AdminToken:             getEnv("ADMIN_TOKEN", ""),
+		PasswordResetWebhook:   getEnv("PASSWORD_RESET_WEBHOOK", ""),
+		OutboundProxy:          getEnv("OUTBOUND_PROXY", ""),
+		RSSAllowedHosts:        getEnv("RSS_ALLOWED_HOSTS", ""),
+		RSSDeniedHosts:         getEnv("RSS_DENIED_HOSTS", ""),
+		CatalogPath:            getEnv("CATALOG_PATH", ""),
+		PaywallMarkers:         getEnv("PAYWALL_MARKERS", ""),
+		ImageProxyTimeout:      getEnvInt("IMAGE_PROXY_TIMEOUT", 30),
+		ImageProxyMaxRedirects: getEnvInt("IMAGE_PROXY_MAX_REDIRECTS", 5),
+		ImageProxyMaxBytes:     getEnvInt("IMAGE_PROXY_MAX_BYTES", 20<<20),
+		ImageProxyAccept:       getEnv("IMAGE_PROXY_ACCEPT", "image/*,*/*"),
+		ImageProxyTranscode:    getEnv("IMAGE_PROXY_TRANSCODE", ""),
+		GzipLevel:              getEnvInt("GZIP_LEVEL", 5),
+		GzipMinSize:            getEnvInt("GZIP_MIN_SIZE", 1024),
+		LogLevel:               getEnv("LOG_LEVEL", "info"),
+		TrustedProxies:         getEnv("TRUSTED_PROXIES", ""),
+	}
+}
+
+// GetCredentialKey 获取订阅源凭据加密密钥
+func (c *Config) GetCredentialKey() string {
+	if c.CredentialKey != "" {
+		return c.CredentialKey
+	}
+	return c.JWTSecret
+}
+
+// GetImageProxyKey 获取图片代理地址签名密钥
+func (c *Config) GetImageProxyKey() string {
+	if c.ImageProxyKey != "" {
+		return c.ImageProxyKey
+	}
+	return c.JWTSecret
+}
+
+// GetBcryptCost 获取 bcrypt 成本，超出 bcrypt 支持的范围时取边界值
+func (c *Config) GetBcryptCost() int {
+	if c.BcryptCost < 4 {
+		return 4
+	}
+	if c.BcryptCost > 31 {
+		return 31
+	}
+	return c.BcryptCost
+}
+
+// GetTrustedProxies 解析 TRUSTED_PROXIES 中的可信代理地址列表
+func (c *Config) GetTrustedProxies() []string {
+	return splitList(c.TrustedProxies)
+}
+
+// GetAdminUsernames 解析 ADMIN_USERS 中的管理员用户名列表
+func (c *Config) GetAdminUsernames() []string {
+	return splitList(c.AdminUsers)
+}
+
+// GetRSSAllowedHosts 解析 RSS_ALLOWED_HOSTS 中的允许主机列表
+func (c *Config) GetRSSAllowedHosts() []string {
+	return splitList(c.RSSAllowedHosts)
+}
+
+// GetRSSDeniedHosts 解析 RSS_DENIED_HOSTS 中的禁止主机列表
+func (c *Config) GetRSSDeniedHosts() []string {
+	return splitList(c.RSSDeniedHosts)
+}
+
+// GetImageProxyTranscodeFormats 解析 IMAGE_PROXY_TRANSCODE 中的转码目标格式（小写，按优先级）
+func (c *Config) GetImageProxyTranscodeFormats() []string {
+	return splitList(strings.ToLower(c.ImageProxyTranscode))
+}
+
+// GetPaywallMarkers 解析 PAYWALL_MARKERS 中的付费墙提示语（小写）
+func (c *Config) GetPaywallMarkers() []string {
+	return splitList(strings.ToLower(c.PaywallMarkers))
+}
+
+// splitList 解析逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
 
 // getEnv 获取环境变量，如果不存在则使用默认值
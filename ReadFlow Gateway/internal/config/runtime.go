@@ -1,30 +1,80 @@
 package config
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
+	_ "time/tzdata" // 镜像中可能没有系统时区数据
 )
 
 // RuntimeConfig 运行时可修改的配置
 type RuntimeConfig struct {
 	mu sync.RWMutex
 
-	// RSS 抓取间隔（秒）
+	// 新订阅源的默认抓取间隔（秒），已有源按各自的 fetch_interval 抓取
 	FetchInterval int
 
+	// 定时抓取每轮（1 分钟）最多抓取的到期源数，0 表示不限制；超出的源顺延到下一轮
+	MaxSourcesPerRound int
+
+	// 用户手动刷新（sync mode=refresh）的并发源数和整体时限（秒）
+	RefreshConcurrency int
+	RefreshTimeout     int
+
+	// 定时抓取时源抓取间隔的随机偏移比例（百分比），使间隔相同的源分散到不同轮次，0 表示不偏移
+	FetchJitterPercent int
+
+	// 同一主机同时进行的订阅源请求数，0 表示不限制；与手动刷新并发数分开，避免多个源同在一个主机（如自建 RSSHub）时被限流
+	FeedHostConcurrency int
+
 	// 图片处理配置
 	ImageMaxWidth   int
 	ImageQuality    int
 	ImageConcurrent int
 
+	// 封面图最小尺寸（像素），声明或探测到的尺寸小于该值的候选图不作为封面
+	CoverMinWidth  int
+	CoverMinHeight int
+	// 是否对未声明尺寸的正文图片做部分下载探测尺寸
+	CoverProbeEnabled bool
+	// 没有任何封面时是否抓取文章页面的 og:image
+	CoverOGImageEnabled bool
+	// 是否为封面图生成 BlurHash 占位（与主色调共用一次解码，每张封面额外消耗少量 CPU）
+	CoverBlurhashEnabled bool
+	// 为封面图生成的缩略图宽度（像素，升序），为空时不生成；不小于原图宽度的尺寸跳过
+	CoverVariantWidths []int
+
 	// 图片缓存过期时间（秒），默认 86400（1 天）
 	ImageCacheExpiration int
 
 	// 文章保留时间（秒），悾清理文章需要等待的时间，默认 86400（1 天）
 	ItemRetentionTime int
 
+	// 源超过该时长（秒）没有新文章时标记为停更，默认 7 天
+	SourceStaleThreshold int
+
+	// 单篇文章正文入库的最大字节数，超出部分截断，默认 512KB
+	MaxContentBytes int
+
+	// 数据库维护（WAL checkpoint、按需 VACUUM）间隔（秒），默认 1 天
+	DBMaintenanceInterval int
+
+	// 每个用户的订阅数和生词数上限，0 表示不限制；管理员可为单个用户单独设置
+	MaxSubscriptions int
+	MaxVocabulary    int
+
+	// 订阅已有的源时补发的最近文章数（只含保留期内的文章），0 表示不补发
+	SubscribeBackfill int
+
 	// 日志级别
 	LogLevel string
 
+	// 解析不带时区的文章发布时间时使用的时区（IANA 名称，如 Asia/Shanghai），默认 UTC
+	FeedTimezone string
+
 	// 其他运行时配置
 	MaxItemsPerFetch int // 每次抓取最多保留的文章数
 	MaxRetries       int // 最大重试次数
@@ -39,30 +89,48 @@ var once sync.Once
 func GetRuntimeConfig() *RuntimeConfig {
 	once.Do(func() {
 		runtimeConfig = &RuntimeConfig{
-			FetchInterval:        900, // 15 分钟
-			ImageMaxWidth:        1080,
-			ImageQuality:         75,
-			ImageConcurrent:      2,
-			ImageCacheExpiration: 86400, // 1 天
-			ItemRetentionTime:    86400, // 1 天
-			LogLevel:             "info",
-			MaxItemsPerFetch:     500,
-			MaxRetries:           3,
-			ReadTimeout:          30,
-			ConnectTimeout:       10,
+			FetchInterval:         900, // 15 分钟
+			MaxSourcesPerRound:    0,
+			RefreshConcurrency:    3,
+			RefreshTimeout:        300, // 5 分钟
+			FeedHostConcurrency:   2,
+			FetchJitterPercent:    5,
+			ImageMaxWidth:         1080,
+			ImageQuality:          75,
+			ImageConcurrent:       2,
+			CoverMinWidth:         300,
+			CoverMinHeight:        200,
+			CoverProbeEnabled:     true,
+			CoverOGImageEnabled:   true,
+			CoverBlurhashEnabled:  false,
+			CoverVariantWidths:    []int{160, 640, 1080},
+			ImageCacheExpiration:  86400,  // 1 天
+			ItemRetentionTime:     86400,  // 1 天
+			SourceStaleThreshold:  604800, // 7 天
+			MaxContentBytes:       524288, // 512KB
+			DBMaintenanceInterval: 86400,  // 1 天
+			MaxSubscriptions:      500,
+			MaxVocabulary:         20000,
+			SubscribeBackfill:     20,
+			LogLevel:              "info",
+			FeedTimezone:          "UTC",
+			MaxItemsPerFetch:      500,
+			MaxRetries:            3,
+			ReadTimeout:           30,
+			ConnectTimeout:        10,
 		}
 	})
 	return runtimeConfig
 }
 
-// GetFetchInterval 获取 RSS 抓取间隔
+// GetFetchInterval 获取新订阅源的默认抓取间隔
 func (rc *RuntimeConfig) GetFetchInterval() int {
 	rc.mu.RLock()
 	defer rc.mu.RUnlock()
 	return rc.FetchInterval
 }
 
-// SetFetchInterval 设置 RSS 抓取间隔
+// SetFetchInterval 设置新订阅源的默认抓取间隔
 func (rc *RuntimeConfig) SetFetchInterval(interval int) {
 	if interval < 60 {
 		interval = 60 // 最少 60 秒
@@ -75,6 +143,26 @@ func (rc *RuntimeConfig) SetFetchInterval(interval int) {
 	rc.FetchInterval = interval
 }
 
+// GetMaxSourcesPerRound 获取定时抓取每轮最多抓取的源数，0 表示不限制
+func (rc *RuntimeConfig) GetMaxSourcesPerRound() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.MaxSourcesPerRound
+}
+
+// SetMaxSourcesPerRound 设置定时抓取每轮最多抓取的源数
+func (rc *RuntimeConfig) SetMaxSourcesPerRound(count int) {
+	if count < 0 {
+		count = 0 // 不限制
+	}
+	if count > 10000 {
+		count = 10000
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.MaxSourcesPerRound = count
+}
+
 // GetImageQuality 获取图片质量
 func (rc *RuntimeConfig) GetImageQuality() int {
 	rc.mu.RLock()
@@ -115,6 +203,86 @@ func (rc *RuntimeConfig) SetImageMaxWidth(width int) {
 	rc.ImageMaxWidth = width
 }
 
+// GetRefreshConcurrency 获取用户刷新的并发源数
+func (rc *RuntimeConfig) GetRefreshConcurrency() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.RefreshConcurrency
+}
+
+// SetRefreshConcurrency 设置用户刷新的并发源数
+func (rc *RuntimeConfig) SetRefreshConcurrency(concurrent int) {
+	if concurrent < 1 {
+		concurrent = 1
+	}
+	if concurrent > 10 {
+		concurrent = 10
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.RefreshConcurrency = concurrent
+}
+
+// GetRefreshTimeout 获取用户刷新的整体时限（秒）
+func (rc *RuntimeConfig) GetRefreshTimeout() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.RefreshTimeout
+}
+
+// SetRefreshTimeout 设置用户刷新的整体时限（秒）
+func (rc *RuntimeConfig) SetRefreshTimeout(seconds int) {
+	if seconds < 30 {
+		seconds = 30 // 最少 30 秒
+	}
+	if seconds > 1800 {
+		seconds = 1800 // 最多 30 分钟
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.RefreshTimeout = seconds
+}
+
+// GetFetchJitterPercent 获取源抓取间隔的随机偏移比例（百分比），0 表示不偏移
+func (rc *RuntimeConfig) GetFetchJitterPercent() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.FetchJitterPercent
+}
+
+// SetFetchJitterPercent 设置源抓取间隔的随机偏移比例（百分比）
+func (rc *RuntimeConfig) SetFetchJitterPercent(percent int) {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 25 {
+		percent = 25
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.FetchJitterPercent = percent
+}
+
+// GetFeedHostConcurrency 获取同一主机同时进行的订阅源请求数，0 表示不限制
+func (rc *RuntimeConfig) GetFeedHostConcurrency() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.FeedHostConcurrency
+}
+
+// SetFeedHostConcurrency 设置同一主机同时进行的订阅源请求数
+func (rc *RuntimeConfig) SetFeedHostConcurrency(concurrent int) {
+	if concurrent < 0 {
+		concurrent = 0
+	}
+	if concurrent > 50 {
+		concurrent = 50
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.FeedHostConcurrency = concurrent
+}
+
 // GetImageConcurrent 获取图片并发数
 func (rc *RuntimeConfig) GetImageConcurrent() int {
 	rc.mu.RLock()
@@ -135,6 +303,152 @@ func (rc *RuntimeConfig) SetImageConcurrent(concurrent int) {
 	rc.ImageConcurrent = concurrent
 }
 
+// GetCoverMinWidth 获取封面图最小宽度
+func (rc *RuntimeConfig) GetCoverMinWidth() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.CoverMinWidth
+}
+
+// SetCoverMinWidth 设置封面图最小宽度
+func (rc *RuntimeConfig) SetCoverMinWidth(width int) {
+	if width < 0 {
+		width = 0 // 0 表示不限制
+	}
+	if width > 2000 {
+		width = 2000
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.CoverMinWidth = width
+}
+
+// GetCoverMinHeight 获取封面图最小高度
+func (rc *RuntimeConfig) GetCoverMinHeight() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.CoverMinHeight
+}
+
+// SetCoverMinHeight 设置封面图最小高度
+func (rc *RuntimeConfig) SetCoverMinHeight(height int) {
+	if height < 0 {
+		height = 0 // 0 表示不限制
+	}
+	if height > 2000 {
+		height = 2000
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.CoverMinHeight = height
+}
+
+// GetCoverProbeEnabled 获取是否探测未声明尺寸的封面候选图
+func (rc *RuntimeConfig) GetCoverProbeEnabled() bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.CoverProbeEnabled
+}
+
+// SetCoverProbeEnabled 设置是否探测未声明尺寸的封面候选图
+func (rc *RuntimeConfig) SetCoverProbeEnabled(enabled bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.CoverProbeEnabled = enabled
+}
+
+// GetCoverOGImageEnabled 获取是否回退到文章页面 og:image
+func (rc *RuntimeConfig) GetCoverOGImageEnabled() bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.CoverOGImageEnabled
+}
+
+// SetCoverOGImageEnabled 设置是否回退到文章页面 og:image
+func (rc *RuntimeConfig) SetCoverOGImageEnabled(enabled bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.CoverOGImageEnabled = enabled
+}
+
+// GetCoverBlurhashEnabled 获取是否为封面图生成 BlurHash
+func (rc *RuntimeConfig) GetCoverBlurhashEnabled() bool {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.CoverBlurhashEnabled
+}
+
+// SetCoverBlurhashEnabled 设置是否为封面图生成 BlurHash
+func (rc *RuntimeConfig) SetCoverBlurhashEnabled(enabled bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.CoverBlurhashEnabled = enabled
+}
+
+// 封面缩略图宽度的取值范围和最多个数
+const (
+	minCoverVariantWidth = 16
+	maxCoverVariantWidth = 4096
+	maxCoverVariants     = 6
+)
+
+// GetCoverVariantWidths 获取封面缩略图宽度（升序），为空时不生成缩略图
+func (rc *RuntimeConfig) GetCoverVariantWidths() []int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return append([]int(nil), rc.CoverVariantWidths...)
+}
+
+// SetCoverVariantWidths 按逗号分隔的宽度列表（如 "160,640,1080"）设置封面缩略图尺寸，空字符串表示不生成
+// 宽度去重并按升序保存；格式错误、超出范围或个数过多时返回错误
+func (rc *RuntimeConfig) SetCoverVariantWidths(value string) error {
+	widths, err := ParseCoverVariantWidths(value)
+	if err != nil {
+		return err
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.CoverVariantWidths = widths
+	return nil
+}
+
+// ParseCoverVariantWidths 解析逗号分隔的封面缩略图宽度列表，返回去重后的升序宽度
+func ParseCoverVariantWidths(value string) ([]int, error) {
+	var widths []int
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		width, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid width %q", field)
+		}
+		if width < minCoverVariantWidth || width > maxCoverVariantWidth {
+			return nil, fmt.Errorf("width %d out of range [%d, %d]", width, minCoverVariantWidth, maxCoverVariantWidth)
+		}
+		if !seen[width] {
+			seen[width] = true
+			widths = append(widths, width)
+		}
+	}
+	if len(widths) > maxCoverVariants {
+		return nil, fmt.Errorf("at most %d widths", maxCoverVariants)
+	}
+	sort.Ints(widths)
+	return widths, nil
+}
+
+// formatCoverVariantWidths 将宽度列表格式化为逗号分隔的字符串
+func formatCoverVariantWidths(widths []int) string {
+	fields := make([]string, len(widths))
+	for i, width := range widths {
+		fields[i] = strconv.Itoa(width)
+	}
+	return strings.Join(fields, ",")
+}
+
 // GetLogLevel 获取日志级别
 func (rc *RuntimeConfig) GetLogLevel() string {
 	rc.mu.RLock()
@@ -152,6 +466,36 @@ func (rc *RuntimeConfig) SetLogLevel(level string) {
 	rc.LogLevel = level
 }
 
+// GetFeedTimezone 获取解析无时区发布时间使用的时区名称
+func (rc *RuntimeConfig) GetFeedTimezone() string {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.FeedTimezone
+}
+
+// GetFeedLocation 获取解析无时区发布时间使用的时区
+func (rc *RuntimeConfig) GetFeedLocation() *time.Location {
+	loc, err := time.LoadLocation(rc.GetFeedTimezone())
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// SetFeedTimezone 设置解析无时区发布时间使用的时区，名称无效时返回错误
+func (rc *RuntimeConfig) SetFeedTimezone(name string) error {
+	if name == "" {
+		name = "UTC"
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown time zone %q", name)
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.FeedTimezone = name
+	return nil
+}
+
 // GetMaxItemsPerFetch 获取每次抓取最多保留的文章数
 func (rc *RuntimeConfig) GetMaxItemsPerFetch() int {
 	rc.mu.RLock()
@@ -212,84 +556,301 @@ func (rc *RuntimeConfig) SetImageCacheExpiration(seconds int) {
 	rc.ImageCacheExpiration = seconds
 }
 
+// GetSourceStaleThreshold 获取源停更判定阈值（秒）
+func (rc *RuntimeConfig) GetSourceStaleThreshold() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.SourceStaleThreshold
+}
+
+// SetSourceStaleThreshold 设置源停更判定阈值（秒）
+func (rc *RuntimeConfig) SetSourceStaleThreshold(seconds int) {
+	if seconds < 86400 {
+		seconds = 86400 // 最少 1 天
+	}
+	if seconds > 7776000 {
+		seconds = 7776000 // 最多 90 天
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.SourceStaleThreshold = seconds
+}
+
+// GetMaxContentBytes 获取正文入库的最大字节数
+func (rc *RuntimeConfig) GetMaxContentBytes() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.MaxContentBytes
+}
+
+// SetMaxContentBytes 设置正文入库的最大字节数
+func (rc *RuntimeConfig) SetMaxContentBytes(size int) {
+	if size < 65536 {
+		size = 65536 // 最少 64KB
+	}
+	if size > 10485760 {
+		size = 10485760 // 最多 10MB
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.MaxContentBytes = size
+}
+
+// GetDBMaintenanceInterval 获取数据库维护间隔（秒）
+func (rc *RuntimeConfig) GetDBMaintenanceInterval() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.DBMaintenanceInterval
+}
+
+// SetDBMaintenanceInterval 设置数据库维护间隔（秒）
+func (rc *RuntimeConfig) SetDBMaintenanceInterval(seconds int) {
+	if seconds < 3600 {
+		seconds = 3600 // 最少 1 小时
+	}
+	if seconds > 2592000 {
+		seconds = 2592000 // 最多 30 天
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.DBMaintenanceInterval = seconds
+}
+
+// GetMaxSubscriptions 获取每个用户的默认订阅数上限（0 表示不限制）
+func (rc *RuntimeConfig) GetMaxSubscriptions() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.MaxSubscriptions
+}
+
+// SetMaxSubscriptions 设置每个用户的默认订阅数上限
+func (rc *RuntimeConfig) SetMaxSubscriptions(count int) {
+	if count < 0 {
+		count = 0 // 不限制
+	}
+	if count > 100000 {
+		count = 100000
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.MaxSubscriptions = count
+}
+
+// GetMaxVocabulary 获取每个用户的默认生词数上限（0 表示不限制）
+func (rc *RuntimeConfig) GetMaxVocabulary() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.MaxVocabulary
+}
+
+// SetMaxVocabulary 设置每个用户的默认生词数上限
+func (rc *RuntimeConfig) SetMaxVocabulary(count int) {
+	if count < 0 {
+		count = 0 // 不限制
+	}
+	if count > 1000000 {
+		count = 1000000
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.MaxVocabulary = count
+}
+
+// GetSubscribeBackfill 获取订阅已有源时补发的文章数（0 表示不补发）
+func (rc *RuntimeConfig) GetSubscribeBackfill() int {
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.SubscribeBackfill
+}
+
+// SetSubscribeBackfill 设置订阅已有源时补发的文章数
+func (rc *RuntimeConfig) SetSubscribeBackfill(count int) {
+	if count < 0 {
+		count = 0 // 不补发
+	}
+	if count > 200 {
+		count = 200
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.SubscribeBackfill = count
+}
+
 // GetAllConfig 获取所有运行时配置
 func (rc *RuntimeConfig) GetAllConfig() map[string]interface{} {
 	rc.mu.RLock()
 	defer rc.mu.RUnlock()
 
 	return map[string]interface{}{
-		"fetch_interval":         rc.FetchInterval,
-		"image_max_width":        rc.ImageMaxWidth,
-		"image_quality":          rc.ImageQuality,
-		"image_concurrent":       rc.ImageConcurrent,
-		"image_cache_expiration": rc.ImageCacheExpiration,
-		"item_retention_time":    rc.ItemRetentionTime,
-		"log_level":              rc.LogLevel,
-		"max_items_per_fetch":    rc.MaxItemsPerFetch,
-		"max_retries":            rc.MaxRetries,
-		"read_timeout":           rc.ReadTimeout,
-		"connect_timeout":        rc.ConnectTimeout,
+		"fetch_interval":          rc.FetchInterval,
+		"max_sources_per_round":   rc.MaxSourcesPerRound,
+		"refresh_concurrency":     rc.RefreshConcurrency,
+		"refresh_timeout":         rc.RefreshTimeout,
+		"feed_host_concurrency":   rc.FeedHostConcurrency,
+		"fetch_jitter_percent":    rc.FetchJitterPercent,
+		"image_max_width":         rc.ImageMaxWidth,
+		"image_quality":           rc.ImageQuality,
+		"image_concurrent":        rc.ImageConcurrent,
+		"cover_min_width":         rc.CoverMinWidth,
+		"cover_min_height":        rc.CoverMinHeight,
+		"cover_probe_enabled":     rc.CoverProbeEnabled,
+		"cover_og_image_enabled":  rc.CoverOGImageEnabled,
+		"cover_blurhash_enabled":  rc.CoverBlurhashEnabled,
+		"cover_variant_widths":    formatCoverVariantWidths(rc.CoverVariantWidths),
+		"image_cache_expiration":  rc.ImageCacheExpiration,
+		"item_retention_time":     rc.ItemRetentionTime,
+		"source_stale_threshold":  rc.SourceStaleThreshold,
+		"max_content_bytes":       rc.MaxContentBytes,
+		"db_maintenance_interval": rc.DBMaintenanceInterval,
+		"max_subscriptions":       rc.MaxSubscriptions,
+		"max_vocabulary":          rc.MaxVocabulary,
+		"subscribe_backfill":      rc.SubscribeBackfill,
+		"log_level":               rc.LogLevel,
+		"feed_timezone":           rc.FeedTimezone,
+		"max_items_per_fetch":     rc.MaxItemsPerFetch,
+		"max_retries":             rc.MaxRetries,
+		"read_timeout":            rc.ReadTimeout,
+		"connect_timeout":         rc.ConnectTimeout,
 	}
 }
 
-// UpdateConfig 批量更新配置
-func (rc *RuntimeConfig) UpdateConfig(updates map[string]interface{}) map[string]string {
-	errors := make(map[string]string)
+// FieldStatus 单个配置项的更新结果
+type FieldStatus string
+
+// 配置项更新结果
+const (
+	FieldAccepted FieldStatus = "accepted" // 按提交的值生效
+	FieldClamped  FieldStatus = "clamped"  // 超出允许范围，已调整为 Applied 后生效
+	FieldRejected FieldStatus = "rejected" // 类型错误、取值无效或未知的配置项，未生效
+)
+
+// FieldResult 单个配置项的更新结果，Applied 为实际生效的值
+type FieldResult struct {
+	Status  FieldStatus `json:"status"`
+	Applied interface{} `json:"applied,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// rejected 构造未生效的结果
+func rejected(message string) FieldResult {
+	return FieldResult{Status: FieldRejected, Message: message}
+}
+
+// updateInt 更新整数配置，setter 会把超出范围的值调整到边界，调整后的值与提交的值不同时标记为 clamped
+func updateInt(value interface{}, set func(int), get func() int) FieldResult {
+	v, ok := value.(float64)
+	if !ok || v != float64(int(v)) {
+		return rejected("必须是整数")
+	}
+	set(int(v))
+	applied := get()
+	switch {
+	case float64(applied) == v:
+		return FieldResult{Status: FieldAccepted, Applied: applied}
+	case float64(applied) > v:
+		return FieldResult{Status: FieldClamped, Applied: applied, Message: fmt.Sprintf("低于最小值，已设为 %d", applied)}
+	default:
+		return FieldResult{Status: FieldClamped, Applied: applied, Message: fmt.Sprintf("超过最大值，已设为 %d", applied)}
+	}
+}
+
+// updateBool 更新布尔配置，兼容管理后台表单提交的 0/1
+func updateBool(value interface{}, set func(bool)) FieldResult {
+	switch v := value.(type) {
+	case bool:
+		set(v)
+		return FieldResult{Status: FieldAccepted, Applied: v}
+	case float64:
+		set(v != 0)
+		return FieldResult{Status: FieldAccepted, Applied: v != 0}
+	default:
+		return rejected("必须是布尔值")
+	}
+}
+
+// UpdateConfig 批量更新配置，返回每个配置项的结果
+// 超出范围的数值仍按原有规则调整到边界后生效，结果中标记为 clamped 并给出实际生效的值
+func (rc *RuntimeConfig) UpdateConfig(updates map[string]interface{}) map[string]FieldResult {
+	results := make(map[string]FieldResult, len(updates))
 
 	for key, value := range updates {
 		switch key {
 		case "fetch_interval":
-			if v, ok := value.(float64); ok {
-				rc.SetFetchInterval(int(v))
-			} else {
-				errors[key] = "必须是整数"
-			}
+			results[key] = updateInt(value, rc.SetFetchInterval, rc.GetFetchInterval)
+		case "max_sources_per_round":
+			results[key] = updateInt(value, rc.SetMaxSourcesPerRound, rc.GetMaxSourcesPerRound)
+		case "refresh_concurrency":
+			results[key] = updateInt(value, rc.SetRefreshConcurrency, rc.GetRefreshConcurrency)
+		case "refresh_timeout":
+			results[key] = updateInt(value, rc.SetRefreshTimeout, rc.GetRefreshTimeout)
+		case "fetch_jitter_percent":
+			results[key] = updateInt(value, rc.SetFetchJitterPercent, rc.GetFetchJitterPercent)
+		case "feed_host_concurrency":
+			results[key] = updateInt(value, rc.SetFeedHostConcurrency, rc.GetFeedHostConcurrency)
 		case "image_quality":
-			if v, ok := value.(float64); ok {
-				rc.SetImageQuality(int(v))
-			} else {
-				errors[key] = "必须是整数"
-			}
+			results[key] = updateInt(value, rc.SetImageQuality, rc.GetImageQuality)
 		case "image_max_width":
-			if v, ok := value.(float64); ok {
-				rc.SetImageMaxWidth(int(v))
-			} else {
-				errors[key] = "必须是整数"
-			}
+			results[key] = updateInt(value, rc.SetImageMaxWidth, rc.GetImageMaxWidth)
 		case "image_concurrent":
-			if v, ok := value.(float64); ok {
-				rc.SetImageConcurrent(int(v))
-			} else {
-				errors[key] = "必须是整数"
-			}
+			results[key] = updateInt(value, rc.SetImageConcurrent, rc.GetImageConcurrent)
+		case "cover_min_width":
+			results[key] = updateInt(value, rc.SetCoverMinWidth, rc.GetCoverMinWidth)
+		case "cover_min_height":
+			results[key] = updateInt(value, rc.SetCoverMinHeight, rc.GetCoverMinHeight)
+		case "cover_probe_enabled":
+			results[key] = updateBool(value, rc.SetCoverProbeEnabled)
+		case "cover_og_image_enabled":
+			results[key] = updateBool(value, rc.SetCoverOGImageEnabled)
+		case "cover_blurhash_enabled":
+			results[key] = updateBool(value, rc.SetCoverBlurhashEnabled)
 		case "item_retention_time":
-			if v, ok := value.(float64); ok {
-				rc.SetItemRetentionTime(int(v))
-			} else {
-				errors[key] = "必须是整数"
-			}
+			results[key] = updateInt(value, rc.SetItemRetentionTime, rc.GetItemRetentionTime)
+		case "source_stale_threshold":
+			results[key] = updateInt(value, rc.SetSourceStaleThreshold, rc.GetSourceStaleThreshold)
+		case "max_content_bytes":
+			results[key] = updateInt(value, rc.SetMaxContentBytes, rc.GetMaxContentBytes)
+		case "db_maintenance_interval":
+			results[key] = updateInt(value, rc.SetDBMaintenanceInterval, rc.GetDBMaintenanceInterval)
+		case "max_subscriptions":
+			results[key] = updateInt(value, rc.SetMaxSubscriptions, rc.GetMaxSubscriptions)
+		case "max_vocabulary":
+			results[key] = updateInt(value, rc.SetMaxVocabulary, rc.GetMaxVocabulary)
+		case "subscribe_backfill":
+			results[key] = updateInt(value, rc.SetSubscribeBackfill, rc.GetSubscribeBackfill)
 		case "image_cache_expiration":
-			if v, ok := value.(float64); ok {
-				rc.SetImageCacheExpiration(int(v))
-			} else {
-				errors[key] = "必须是整数"
-			}
+			results[key] = updateInt(value, rc.SetImageCacheExpiration, rc.GetImageCacheExpiration)
 		case "log_level":
-			if v, ok := value.(string); ok {
+			v, ok := value.(string)
+			if !ok {
+				results[key] = rejected("必须是字符串")
+			} else if v != "debug" && v != "info" && v != "warn" && v != "error" {
+				results[key] = rejected("必须是 debug、info、warn 或 error")
+			} else {
 				rc.SetLogLevel(v)
+				results[key] = FieldResult{Status: FieldAccepted, Applied: v}
+			}
+		case "feed_timezone":
+			if v, ok := value.(string); !ok {
+				results[key] = rejected("必须是字符串")
+			} else if err := rc.SetFeedTimezone(v); err != nil {
+				results[key] = rejected("无效的时区名称，应为 IANA 时区（如 Asia/Shanghai）")
 			} else {
-				errors[key] = "必须是字符串"
+				results[key] = FieldResult{Status: FieldAccepted, Applied: rc.GetFeedTimezone()}
 			}
-		case "max_items_per_fetch":
-			if v, ok := value.(float64); ok {
-				rc.SetMaxItemsPerFetch(int(v))
+		case "cover_variant_widths":
+			if v, ok := value.(string); !ok {
+				results[key] = rejected("必须是字符串")
+			} else if err := rc.SetCoverVariantWidths(v); err != nil {
+				results[key] = rejected(fmt.Sprintf("应为逗号分隔的宽度（%d-%d 像素，最多 %d 个），如 160,640,1080", minCoverVariantWidth, maxCoverVariantWidth, maxCoverVariants))
 			} else {
-				errors[key] = "必须是整数"
+				results[key] = FieldResult{Status: FieldAccepted, Applied: formatCoverVariantWidths(rc.GetCoverVariantWidths())}
 			}
+		case "max_items_per_fetch":
+			results[key] = updateInt(value, rc.SetMaxItemsPerFetch, rc.GetMaxItemsPerFetch)
 		default:
-			errors[key] = "未知的配置项"
+			results[key] = rejected("未知的配置项")
 		}
 	}
 
-	return errors
+	return results
 }
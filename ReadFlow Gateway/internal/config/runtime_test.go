@@ -0,0 +1,66 @@
+package config
+
+import "testing"
+
+func TestUpdateConfigReportsClamping(t *testing.T) {
+	rc := &RuntimeConfig{}
+	results := rc.UpdateConfig(map[string]interface{}{
+		"fetch_interval":        float64(10),
+		"image_quality":         float64(80),
+		"refresh_concurrency":   float64(50),
+		"max_sources_per_round": float64(-1),
+		"image_max_width":       1.5,
+		"cover_probe_enabled":   float64(1),
+		"log_level":             "verbose",
+		"unknown_key":           true,
+	})
+
+	cases := map[string]FieldResult{
+		"fetch_interval":        {Status: FieldClamped, Applied: 60, Message: "低于最小值，已设为 60"},
+		"image_quality":         {Status: FieldAccepted, Applied: 80},
+		"refresh_concurrency":   {Status: FieldClamped, Applied: 10, Message: "超过最大值，已设为 10"},
+		"max_sources_per_round": {Status: FieldClamped, Applied: 0, Message: "低于最小值，已设为 0"},
+		"image_max_width":       {Status: FieldRejected, Message: "必须是整数"},
+		"cover_probe_enabled":   {Status: FieldAccepted, Applied: true},
+		"log_level":             {Status: FieldRejected, Message: "必须是 debug、info、warn 或 error"},
+		"unknown_key":           {Status: FieldRejected, Message: "未知的配置项"},
+	}
+	for key, want := range cases {
+		if got := results[key]; got != want {
+			t.Errorf("%s = %+v, want %+v", key, got, want)
+		}
+	}
+
+	// 被调整的值按调整后的结果生效，被拒绝的值不生效
+	if got := rc.GetFetchInterval(); got != 60 {
+		t.Errorf("fetch_interval = %d, want 60", got)
+	}
+	if got := rc.GetImageMaxWidth(); got != 0 {
+		t.Errorf("image_max_width = %d, want unchanged 0", got)
+	}
+}
+
+func TestUpdateCoverVariantWidths(t *testing.T) {
+	rc := &RuntimeConfig{}
+	tests := []struct {
+		value  interface{}
+		status FieldStatus
+		want   string
+	}{
+		{value: " 1080, 160,640,160 ", status: FieldAccepted, want: "160,640,1080"},
+		{value: "", status: FieldAccepted, want: ""},
+		{value: "160,abc", status: FieldRejected, want: ""},
+		{value: "8", status: FieldRejected, want: ""},
+		{value: "100,200,300,400,500,600,700", status: FieldRejected, want: ""},
+		{value: float64(640), status: FieldRejected, want: ""},
+	}
+	for _, tt := range tests {
+		got := rc.UpdateConfig(map[string]interface{}{"cover_variant_widths": tt.value})["cover_variant_widths"]
+		if got.Status != tt.status {
+			t.Errorf("cover_variant_widths=%v: status = %s, want %s (%s)", tt.value, got.Status, tt.status, got.Message)
+		}
+		if applied := formatCoverVariantWidths(rc.GetCoverVariantWidths()); applied != tt.want {
+			t.Errorf("cover_variant_widths=%v: applied %q, want %q", tt.value, applied, tt.want)
+		}
+	}
+}
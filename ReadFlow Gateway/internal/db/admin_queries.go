@@ -3,6 +3,8 @@ package db
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 )
 
 // GetAllUsers 获取所有用户
@@ -30,13 +32,152 @@ func (db *DB) GetAllUsers() ([]*User, error) {
 	return users, rows.Err()
 }
 
+// ListUsersWithStats 分页获取用户及其统计数据（按最后登录时间倒序）
+// keyword 非空时按用户名 / 邮箱模糊搜索；统计数据按指标分组查询，避免逐用户查询
+func (db *DB) ListUsersWithStats(keyword string, limit, offset int) ([]*UserStats, int64, error) {
+	where := ""
+	args := []interface{}{}
+	if keyword != "" {
+		pattern := "%" + keyword + "%"
+		where = "WHERE username LIKE ? OR email LIKE ?"
+		args = append(args, pattern, pattern)
+	}
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM users "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`
+		SELECT id, username, COALESCE(email, ''), created_at, last_login_at, max_subscriptions, max_vocabulary
+		FROM users `+where+`
+		ORDER BY last_login_at IS NULL, last_login_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*UserStats{}
+	byID := make(map[int64]*UserStats)
+	for rows.Next() {
+		u := &UserStats{}
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt, &u.LastLoginAt, &u.MaxSubscriptions, &u.MaxVocabulary); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+		byID[u.ID] = u
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(users) == 0 {
+		return users, total, nil
+	}
+
+	// 每个指标一条分组查询，只统计当前页的用户
+	ids := make([]interface{}, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	in := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	metrics := []struct {
+		query string
+		set   func(u *UserStats, count int64)
+	}{
+		{
+			"SELECT user_id, COUNT(*) FROM subscriptions WHERE user_id IN (" + in + ") GROUP BY user_id",
+			func(u *UserStats, count int64) { u.SubscriptionCount = count },
+		},
+		{
+			"SELECT user_id, COUNT(*) FROM user_deliveries WHERE user_id IN (" + in + ") GROUP BY user_id",
+			func(u *UserStats, count int64) { u.DeliveryCount = count },
+		},
+		{
+			"SELECT user_id, COUNT(*) FROM vocabularies WHERE is_deleted = 0 AND user_id IN (" + in + ") GROUP BY user_id",
+			func(u *UserStats, count int64) { u.VocabularyCount = count },
+		},
+	}
+	for _, m := range metrics {
+		if err := db.scanUserCounts(m.query, ids, byID, m.set); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return users, total, nil
+}
+
+// ListSourceItemsByPublished 分页获取源在 [from, to) 内发布的文章及投递用户数（按发布时间倒序）
+// from、to 为 nil 时不限制对应边界；查询走 idx_items_source_published 索引
+func (db *DB) ListSourceItemsByPublished(sourceID int64, from, to *time.Time, limit, offset int) ([]*SourceItemStats, int64, error) {
+	where := "WHERE i.source_id = ?"
+	args := []interface{}{sourceID}
+	if from != nil {
+		where += " AND i.published_at >= ?"
+		args = append(args, *from)
+	}
+	if to != nil {
+		where += " AND i.published_at < ?"
+		args = append(args, *to)
+	}
+
+	var total int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM items i "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.Query(`
+		SELECT i.id, i.guid, i.title, COALESCE(i.url, ''), COALESCE(i.content_hash, ''), i.published_at, i.created_at,
+		       (SELECT COUNT(*) FROM user_deliveries ud WHERE ud.item_id = i.id)
+		FROM items i `+where+`
+		ORDER BY i.published_at DESC, i.id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	items := []*SourceItemStats{}
+	for rows.Next() {
+		item := &SourceItemStats{}
+		if err := rows.Scan(&item.ID, &item.GUID, &item.Title, &item.URL, &item.ContentHash,
+			&item.PublishedAt, &item.CreatedAt, &item.DeliveredCount); err != nil {
+			return nil, 0, err
+		}
+		items = append(items, item)
+	}
+	return items, total, rows.Err()
+}
+
+// scanUserCounts 执行 (user_id, count) 分组查询并写回对应用户
+func (db *DB) scanUserCounts(query string, args []interface{}, byID map[int64]*UserStats, set func(*UserStats, int64)) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID, count int64
+		if err := rows.Scan(&userID, &count); err != nil {
+			return err
+		}
+		if u, ok := byID[userID]; ok {
+			set(u, count)
+		}
+	}
+	return rows.Err()
+}
+
 // GetAllSources 获取所有订阅源
 func (db *DB) GetAllSources() ([]*Source, error) {
 	rows, err := db.Query(`
-		SELECT id, url, title, description, last_fetch_time, fetch_interval, 
-		       is_active, error_count, COALESCE(last_error, ''), created_at
-		FROM sources
-		ORDER BY created_at DESC
+		SELECT ` + sourceColumns + `
+		FROM sources s
+		ORDER BY s.created_at DESC
 	`)
 	if err != nil {
 		return nil, err
@@ -45,12 +186,8 @@ func (db *DB) GetAllSources() ([]*Source, error) {
 
 	var sources []*Source
 	for rows.Next() {
-		source := &Source{}
-		if err := rows.Scan(
-			&source.ID, &source.URL, &source.Title, &source.Description,
-			&source.LastFetchTime, &source.FetchInterval, &source.IsActive,
-			&source.ErrorCount, &source.LastError, &source.CreatedAt,
-		); err != nil {
+		source, err := scanSource(rows)
+		if err != nil {
 			log.Printf("Error scanning source: %v", err)
 			continue
 		}
@@ -60,6 +197,18 @@ func (db *DB) GetAllSources() ([]*Source, error) {
 	return sources, rows.Err()
 }
 
+// ClearSourceImagePaths 清空源下文章记录的本地图片路径和封面缩略图（图片缓存已被删除时调用），返回受影响的文章数
+func (db *DB) ClearSourceImagePaths(sourceID int64) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE items SET image_paths = '', cover_variants = ''
+		WHERE source_id = ? AND image_paths != '' AND image_paths != '[]'
+	`, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // GetTotalUsers 获取用户总数
 func (db *DB) GetTotalUsers() (int64, error) {
 	var count int64
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// API 令牌权限范围
+const (
+	APITokenScopeRead  = "read"  // 只读：只能调用 GET/HEAD 接口
+	APITokenScopeWrite = "write" // 读写
+)
+
+// apiTokenTouchInterval 最近使用时间的更新间隔，避免每个请求都写库
+const apiTokenTouchInterval = time.Minute
+
+// APIToken 用户 API 令牌（不含令牌本身，只保存哈希）
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // 令牌开头几位，用于识别
+	Scope      string     `json:"scope"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIToken 保存新的 API 令牌
+func (db *DB) CreateAPIToken(userID int64, name, tokenHash, prefix, scope string) (*APIToken, error) {
+	result, err := db.Exec(
+		"INSERT INTO api_tokens (user_id, name, token_hash, prefix, scope) VALUES (?, ?, ?, ?, ?)",
+		userID, name, tokenHash, prefix, scope,
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &APIToken{ID: id, UserID: userID, Name: name, Prefix: prefix, Scope: scope}
+	if err := db.QueryRow("SELECT created_at FROM api_tokens WHERE id = ?", id).Scan(&token.CreatedAt); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// CountUserAPITokens 统计用户的 API 令牌数
+func (db *DB) CountUserAPITokens(userID int64) (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM api_tokens WHERE user_id = ?", userID).Scan(&count)
+	return count, err
+}
+
+// ListUserAPITokens 列出用户的 API 令牌，最新创建的在前
+func (db *DB) ListUserAPITokens(userID int64) ([]*APIToken, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name, prefix, scope, last_used_at, created_at
+		FROM api_tokens WHERE user_id = ?
+		ORDER BY id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]*APIToken, 0)
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetAPITokenByHash 按令牌哈希查找，不存在时返回 sql.ErrNoRows
+func (db *DB) GetAPITokenByHash(tokenHash string) (*APIToken, error) {
+	row := db.QueryRow(`
+		SELECT id, user_id, name, prefix, scope, last_used_at, created_at
+		FROM api_tokens WHERE token_hash = ?
+	`, tokenHash)
+	return scanAPIToken(row)
+}
+
+// TouchAPIToken 记录令牌的最近使用时间（距上次记录不足 1 分钟时跳过）
+func (db *DB) TouchAPIToken(id int64) error {
+	now := time.Now()
+	_, err := db.Exec(
+		"UPDATE api_tokens SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		now.Unix(), id, now.Add(-apiTokenTouchInterval).Unix(),
+	)
+	return err
+}
+
+// DeleteUserAPIToken 撤销用户的 API 令牌，令牌不存在或不属于该用户时返回 sql.ErrNoRows
+func (db *DB) DeleteUserAPIToken(userID, id int64) error {
+	result, err := db.Exec("DELETE FROM api_tokens WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// scanAPIToken 扫描一行 API 令牌记录
+func scanAPIToken(row rowScanner) (*APIToken, error) {
+	token := &APIToken{}
+	var lastUsed sql.NullInt64
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &token.Prefix, &token.Scope, &lastUsed, &token.CreatedAt); err != nil {
+		return nil, err
+	}
+	if lastUsed.Valid {
+		t := time.Unix(lastUsed.Int64, 0)
+		token.LastUsedAt = &t
+	}
+	return token, nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ArticleSort 文章列表排序方式
+type ArticleSort string
+
+const (
+	SortNewest  ArticleSort = "newest"  // 发布时间倒序（默认）
+	SortOldest  ArticleSort = "oldest"  // 发布时间正序
+	SortUnread  ArticleSort = "unread"  // 未读在前，各自按发布时间倒序
+	SortUpdated ArticleSort = "updated" // 最近更新在前：文章内容更新或阅读、收藏等状态变化
+)
+
+// articleSortSpec 排序方式对应的排序列、ORDER BY 和游标条件
+// ORDER BY 与索引顺序一致（oldest 反向扫描 idx_deliveries_user_published），翻页无需临时排序
+type articleSortSpec struct {
+	keyColumn  string // 游标记录的排序列
+	orderBy    string
+	cursorCond string // 游标之后的条件，参数为游标中的排序列原始值和文章 ID
+}
+
+var articleSortSpecs = map[ArticleSort]articleSortSpec{
+	SortNewest: {
+		keyColumn:  "ud.published_at",
+		orderBy:    "ud.published_at DESC, ud.item_id DESC",
+		cursorCond: "(ud.published_at, ud.item_id) < (?, ?)",
+	},
+	SortOldest: {
+		keyColumn:  "ud.published_at",
+		orderBy:    "ud.published_at ASC, ud.item_id ASC",
+		cursorCond: "(ud.published_at, ud.item_id) > (?, ?)",
+	},
+	SortUnread: {
+		// status = 2 为已读，走 idx_deliveries_user_unread 表达式索引；游标条件见 unreadCursorCondUnread / unreadCursorCondRead
+		keyColumn: "ud.published_at",
+		orderBy:   "(ud.status = 2), ud.published_at DESC, ud.item_id DESC",
+	},
+	SortUpdated: {
+		keyColumn:  "ud.updated_at",
+		orderBy:    "ud.updated_at DESC, ud.item_id DESC",
+		cursorCond: "(ud.updated_at, ud.item_id) < (?, ?)",
+	},
+}
+
+// 未读优先排序的游标条件：游标位于未读部分时，之后是更早的未读文章和全部已读文章
+const (
+	unreadCursorCondUnread = "((ud.status = 2) = 1 OR ((ud.status = 2) = 0 AND (ud.published_at, ud.item_id) < (?, ?)))"
+	unreadCursorCondRead   = "(ud.status = 2) = 1 AND (ud.published_at, ud.item_id) < (?, ?)"
+)
+
+// ParseArticleSort 解析排序参数，空字符串为默认的 newest，未知值返回 false
+func ParseArticleSort(value string) (ArticleSort, bool) {
+	if value == "" {
+		return SortNewest, true
+	}
+	sort := ArticleSort(value)
+	_, ok := articleSortSpecs[sort]
+	return sort, ok
+}
+
+// articleCursor newest 以外排序方式的游标
+// 记录排序列在数据库中的原始文本，与索引中的值按同样规则比较，不受时间精度和时区格式影响
+type articleCursor struct {
+	Sort ArticleSort `json:"s"`
+	Read bool        `json:"r,omitempty"` // 仅 unread 排序：游标位于已读部分
+	Key  string      `json:"k"`
+	ID   int64       `json:"i"`
+}
+
+// errCursorSortMismatch 游标不是按当前排序方式生成的
+var errCursorSortMismatch = errors.New("cursor was issued for a different sort")
+
+// encode 编码为不透明的游标字符串
+func (c articleCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeArticleCursor 解码 newest 以外排序方式的游标，排序方式不一致时返回错误
+func decodeArticleCursor(value string, sort ArticleSort) (*articleCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor articleCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.Sort != sort {
+		return nil, errCursorSortMismatch
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"time"
+)
+
+// SourceCredential 相关操作
+
+// UpsertSourceCredential 设置订阅源凭据（secret 需由调用方加密）
+func (db *DB) UpsertSourceCredential(cred *SourceCredential) error {
+	now := time.Now()
+	_, err := db.Exec(`
+		INSERT INTO source_credentials (
+			source_id, auth_type, username, secret_encrypted, param_name, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(source_id) DO UPDATE SET
+			auth_type = excluded.auth_type,
+			username = excluded.username,
+			secret_encrypted = excluded.secret_encrypted,
+			param_name = excluded.param_name,
+			updated_at = excluded.updated_at
+	`, cred.SourceID, cred.AuthType, cred.Username, cred.SecretEncrypted, cred.ParamName, now, now)
+	return err
+}
+
+// GetSourceCredential 获取订阅源凭据，不存在时返回 sql.ErrNoRows
+func (db *DB) GetSourceCredential(sourceID int64) (*SourceCredential, error) {
+	cred := &SourceCredential{}
+	err := db.QueryRow(`
+		SELECT source_id, auth_type, COALESCE(username, ''), COALESCE(secret_encrypted, ''),
+		       COALESCE(param_name, ''), created_at, updated_at
+		FROM source_credentials WHERE source_id = ?
+	`, sourceID).Scan(
+		&cred.SourceID, &cred.AuthType, &cred.Username, &cred.SecretEncrypted,
+		&cred.ParamName, &cred.CreatedAt, &cred.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// DeleteSourceCredential 删除订阅源凭据
+func (db *DB) DeleteSourceCredential(sourceID int64) error {
+	_, err := db.Exec("DELETE FROM source_credentials WHERE source_id = ?", sourceID)
+	return err
+}
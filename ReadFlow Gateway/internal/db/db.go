@@ -7,6 +7,7 @@ import (
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/readflow/gateway/internal/utils"
 )
 
 // DB 数据库连接实例
@@ -63,10 +64,10 @@ func New(dbPath string) (*DB, error) {
 
 // migrate 处理简单的数据库迁移
 func (db *DB) migrate() error {
-	// 检查 sources 表是否存在 category 列
+	// 检查 sources 表是否存在 category 列（不设默认值，只有明确设置的分类才会被文章继承）
 	if !db.columnExists("sources", "category") {
 		log.Println("[Migration] Adding column 'category' to 'sources' table")
-		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN category TEXT DEFAULT 'Technology'"); err != nil {
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN category TEXT"); err != nil {
 			return err
 		}
 	}
@@ -87,6 +88,140 @@ func (db *DB) migrate() error {
 		log.Printf("[Migration] Warning: Failed to create idx_sources_language: %v", err)
 	}
 
+	// 检查 sources 表是否存在 image_mode 列
+	if !db.columnExists("sources", "image_mode") {
+		log.Println("[Migration] Adding column 'image_mode' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN image_mode TEXT DEFAULT 'process'"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 last_item_added_at 列
+	if !db.columnExists("sources", "last_item_added_at") {
+		log.Println("[Migration] Adding column 'last_item_added_at' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN last_item_added_at DATETIME"); err != nil {
+			return err
+		}
+		// 用已有文章的最新入库时间回填
+		if _, err := db.Exec("UPDATE sources SET last_item_added_at = (SELECT MAX(created_at) FROM items WHERE items.source_id = sources.id)"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 proxy_url 列
+	if !db.columnExists("sources", "proxy_url") {
+		log.Println("[Migration] Adding column 'proxy_url' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN proxy_url TEXT"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 retention_seconds 列
+	if !db.columnExists("sources", "retention_seconds") {
+		log.Println("[Migration] Adding column 'retention_seconds' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN retention_seconds INTEGER"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 content_update_mode 列
+	if !db.columnExists("sources", "content_update_mode") {
+		log.Println("[Migration] Adding column 'content_update_mode' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN content_update_mode TEXT DEFAULT 'off'"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 favicon_updated_at 列
+	if !db.columnExists("sources", "favicon_updated_at") {
+		log.Println("[Migration] Adding column 'favicon_updated_at' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN favicon_updated_at DATETIME"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 summary_length 列
+	if !db.columnExists("sources", "summary_length") {
+		log.Println("[Migration] Adding column 'summary_length' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN summary_length INTEGER"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 gallery_enabled 列
+	if !db.columnExists("sources", "gallery_enabled") {
+		log.Println("[Migration] Adding column 'gallery_enabled' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN gallery_enabled BOOLEAN DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 full_content 列
+	if !db.columnExists("sources", "full_content") {
+		log.Println("[Migration] Adding column 'full_content' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN full_content BOOLEAN DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 min_word_count 列
+	if !db.columnExists("sources", "min_word_count") {
+		log.Println("[Migration] Adding column 'min_word_count' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN min_word_count INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 lenient_parse 列
+	if !db.columnExists("sources", "lenient_parse") {
+		log.Println("[Migration] Adding column 'lenient_parse' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN lenient_parse BOOLEAN DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 body_images_only 列
+	if !db.columnExists("sources", "body_images_only") {
+		log.Println("[Migration] Adding column 'body_images_only' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN body_images_only BOOLEAN DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 max_items 列
+	if !db.columnExists("sources", "max_items") {
+		log.Println("[Migration] Adding column 'max_items' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN max_items INTEGER DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 last_parse_note 列
+	if !db.columnExists("sources", "last_parse_note") {
+		log.Println("[Migration] Adding column 'last_parse_note' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN last_parse_note TEXT"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在 feed_title 列
+	if !db.columnExists("sources", "feed_title") {
+		log.Println("[Migration] Adding column 'feed_title' to 'sources' table")
+		if _, err := db.Exec("ALTER TABLE sources ADD COLUMN feed_title TEXT"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 sources 表是否存在条件请求校验值列
+	for _, column := range []string{"http_etag", "http_last_modified"} {
+		if !db.columnExists("sources", column) {
+			log.Printf("[Migration] Adding column '%s' to 'sources' table", column)
+			if _, err := db.Exec("ALTER TABLE sources ADD COLUMN " + column + " TEXT"); err != nil {
+				return err
+			}
+		}
+	}
+
 	// 检查 items 表是否存在 category 列
 	if !db.columnExists("items", "category") {
 		log.Println("[Migration] Adding column 'category' to 'items' table")
@@ -98,6 +233,14 @@ func (db *DB) migrate() error {
 	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_items_category ON items(category)"); err != nil {
 		log.Printf("[Migration] Warning: Failed to create idx_items_category: %v", err)
 	}
+	// 旧版本的 sources.category 默认为 'Technology'：去掉默认值并一次性回填旧文章的分类
+	// 迁移后该列不再有默认值，之后启动不会重复执行
+	if db.columnDefault("sources", "category") == "'Technology'" {
+		log.Println("[Migration] Removing default 'Technology' from 'sources.category'")
+		if err := db.migrateSourceCategory(); err != nil {
+			return err
+		}
+	}
 
 	// 检查 items 表是否存在 content 列
 	if !db.columnExists("items", "content") {
@@ -130,6 +273,10 @@ func (db *DB) migrate() error {
 	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_items_url ON items(url)"); err != nil {
 		log.Printf("[Migration] Warning: Failed to create idx_items_url: %v", err)
 	}
+	// 按链接去重（GetItemByURL）按源内链接查询
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_items_source_url ON items(source_id, url)"); err != nil {
+		log.Printf("[Migration] Warning: Failed to create idx_items_source_url: %v", err)
+	}
 
 	// 检查 items 表是否存在 tags 列
 	if !db.columnExists("items", "tags") {
@@ -163,6 +310,56 @@ func (db *DB) migrate() error {
 		}
 	}
 
+	// 检查 items 表是否存在 is_truncated 列
+	if !db.columnExists("items", "is_truncated") {
+		log.Println("[Migration] Adding column 'is_truncated' to 'items' table")
+		if _, err := db.Exec("ALTER TABLE items ADD COLUMN is_truncated BOOLEAN DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 items 表是否存在 gallery 列
+	if !db.columnExists("items", "gallery") {
+		log.Println("[Migration] Adding column 'gallery' to 'items' table")
+		if _, err := db.Exec("ALTER TABLE items ADD COLUMN gallery TEXT"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 items 表是否存在 image_blurhash 列
+	if !db.columnExists("items", "image_blurhash") {
+		log.Println("[Migration] Adding column 'image_blurhash' to 'items' table")
+		if _, err := db.Exec("ALTER TABLE items ADD COLUMN image_blurhash TEXT"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 items 表是否存在 cover_variants 列
+	if !db.columnExists("items", "cover_variants") {
+		log.Println("[Migration] Adding column 'cover_variants' to 'items' table")
+		if _, err := db.Exec("ALTER TABLE items ADD COLUMN cover_variants TEXT"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 subscriptions 表是否存在 is_paused 列
+	if !db.columnExists("subscriptions", "is_paused") {
+		log.Println("[Migration] Adding column 'is_paused' to 'subscriptions' table")
+		if _, err := db.Exec("ALTER TABLE subscriptions ADD COLUMN is_paused BOOLEAN DEFAULT 0"); err != nil {
+			return err
+		}
+	}
+
+	// 检查 items 表是否存在播客（iTunes）字段
+	for _, column := range []string{"itunes_duration", "itunes_image", "itunes_episode", "itunes_author"} {
+		if !db.columnExists("items", column) {
+			log.Printf("[Migration] Adding column '%s' to 'items' table", column)
+			if _, err := db.Exec("ALTER TABLE items ADD COLUMN " + column + " TEXT"); err != nil {
+				return err
+			}
+		}
+	}
+
 	// 检查 user_deliveries 表
 	if !db.columnExists("user_deliveries", "is_read") {
 		log.Println("[Migration] Adding column 'is_read' to 'user_deliveries' table")
@@ -198,6 +395,31 @@ func (db *DB) migrate() error {
 			return err
 		}
 	}
+	if !db.columnExists("user_deliveries", "published_at") {
+		log.Println("[Migration] Adding column 'published_at' to 'user_deliveries' table")
+		if _, err := db.Exec("ALTER TABLE user_deliveries ADD COLUMN published_at DATETIME"); err != nil {
+			return err
+		}
+		// 回填已有投递的发布时间（文章入库后 published_at 不再变化）
+		if _, err := db.Exec(`
+			UPDATE user_deliveries SET published_at = (
+				SELECT published_at FROM items WHERE items.id = user_deliveries.item_id
+			) WHERE published_at IS NULL
+		`); err != nil {
+			return err
+		}
+	}
+	// 文章列表按发布时间倒序分页，索引覆盖 user_id 过滤、排序和游标条件，避免临时排序
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_deliveries_user_published ON user_deliveries(user_id, published_at DESC, item_id DESC)"); err != nil {
+		log.Printf("[Migration] Warning: Failed to create idx_deliveries_user_published: %v", err)
+	}
+	// 文章列表的未读优先、最近更新排序
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_deliveries_user_unread ON user_deliveries(user_id, (status = 2), published_at DESC, item_id DESC)"); err != nil {
+		log.Printf("[Migration] Warning: Failed to create idx_deliveries_user_unread: %v", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_deliveries_user_updated ON user_deliveries(user_id, updated_at DESC, item_id DESC)"); err != nil {
+		log.Printf("[Migration] Warning: Failed to create idx_deliveries_user_updated: %v", err)
+	}
 
 	// 检查 users 表
 	if !db.columnExists("users", "email") {
@@ -218,9 +440,119 @@ func (db *DB) migrate() error {
 		}
 	}
 
+	if !db.columnExists("users", "is_admin") {
+		log.Println("[Migration] Adding column 'is_admin' to 'users' table")
+		if _, err := db.Exec("ALTER TABLE users ADD COLUMN is_admin BOOLEAN DEFAULT 0"); err != nil {
+			return err
+		}
+		// 已有用户时，最早注册的用户成为管理员
+		if _, err := db.Exec("UPDATE users SET is_admin = 1 WHERE id = (SELECT MIN(id) FROM users)"); err != nil {
+			return err
+		}
+	}
+
+	// 用户配额覆盖值，NULL 表示沿用全局配置
+	if !db.columnExists("users", "max_subscriptions") {
+		log.Println("[Migration] Adding column 'max_subscriptions' to 'users' table")
+		if _, err := db.Exec("ALTER TABLE users ADD COLUMN max_subscriptions INTEGER"); err != nil {
+			return err
+		}
+	}
+
+	if !db.columnExists("users", "max_vocabulary") {
+		log.Println("[Migration] Adding column 'max_vocabulary' to 'users' table")
+		if _, err := db.Exec("ALTER TABLE users ADD COLUMN max_vocabulary INTEGER"); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// migrateSourceCategory 重建 sources.category 列以去掉默认值，并回填 items.category
+// 没有任何代码写入过该列，等于默认值的都视为未设置；其他值保留，并按规范化后的形式由旧文章继承
+func (db *DB) migrateSourceCategory() error {
+	explicit := map[int64]string{}
+	rows, err := db.Query("SELECT id, category FROM sources WHERE category IS NOT NULL AND category NOT IN ('', 'Technology')")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		var category string
+		if err := rows.Scan(&id, &category); err != nil {
+			rows.Close()
+			return err
+		}
+		explicit[id] = category
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// SQLite 不支持修改列默认值，删除后重新添加（被索引的列需先删除索引）
+	for _, stmt := range []string{
+		"DROP INDEX IF EXISTS idx_sources_category",
+		"ALTER TABLE sources DROP COLUMN category",
+		"ALTER TABLE sources ADD COLUMN category TEXT",
+		"CREATE INDEX IF NOT EXISTS idx_sources_category ON sources(category)",
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("rebuild sources.category: %w", err)
+		}
+	}
+
+	textProcessor := utils.NewTextProcessor()
+	for id, category := range explicit {
+		if _, err := tx.Exec("UPDATE sources SET category = ? WHERE id = ?", category, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE items SET category = ? WHERE source_id = ? AND category IS NULL",
+			textProcessor.NormalizeCategory(category), id); err != nil {
+			return err
+		}
+	}
+	// 新文章入库时总会写入分类，NULL 只出现在旧数据中
+	if _, err := tx.Exec("UPDATE items SET category = '' WHERE category IS NULL"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// columnDefault 返回列的默认值表达式（如 'Technology'），列不存在或没有默认值时返回空字符串
+func (db *DB) columnDefault(tableName, columnName string) string {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", tableName))
+	if err != nil {
+		return ""
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			dataType  string
+			notnull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &dataType, &notnull, &dfltValue, &pk); err != nil {
+			continue
+		}
+		if name == columnName {
+			return dfltValue.String
+		}
+	}
+	return ""
+}
+
 // columnExists 检查表中是否存在指定列
 func (db *DB) columnExists(tableName, columnName string) bool {
 	query := fmt.Sprintf("PRAGMA table_info(%s)", tableName)
@@ -265,6 +597,39 @@ type User struct {
 	Token        string
 	CreatedAt    time.Time
 	LastLoginAt  *time.Time
+	IsAdmin      bool
+}
+
+// UserStats 管理后台用户列表项（含聚合统计）
+type UserStats struct {
+	ID                int64      `json:"id"`
+	Username          string     `json:"username"`
+	Email             string     `json:"email"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastLoginAt       *time.Time `json:"last_login_at"`
+	SubscriptionCount int64      `json:"subscription_count"`
+	DeliveryCount     int64      `json:"delivery_count"`
+	VocabularyCount   int64      `json:"vocabulary_count"`
+	MaxSubscriptions  *int       `json:"max_subscriptions"` // 配额覆盖值，null 表示沿用全局配置
+	MaxVocabulary     *int       `json:"max_vocabulary"`
+}
+
+// SourceItemStats 管理后台按发布时间查询的源文章（用于排查漏抓 / 去重）
+type SourceItemStats struct {
+	ID             int64      `json:"id"`
+	GUID           string     `json:"guid"`
+	Title          string     `json:"title"`
+	URL            string     `json:"url"`
+	ContentHash    string     `json:"content_hash"`
+	PublishedAt    *time.Time `json:"published_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredCount int64      `json:"delivered_count"` // 投递到的用户数
+}
+
+// UserQuotaOverride 管理员为单个用户设置的配额，nil 表示沿用全局配置
+type UserQuotaOverride struct {
+	MaxSubscriptions *int `json:"max_subscriptions"`
+	MaxVocabulary    *int `json:"max_vocabulary"`
 }
 
 // UserPreference 用户偏好设置
@@ -287,16 +652,89 @@ type UserPreference struct {
 
 // Source 订阅源
 type Source struct {
-	ID            int64
-	URL           string
-	Title         string
-	Description   string
-	LastFetchTime *time.Time
-	FetchInterval int
-	IsActive      bool
-	ErrorCount    int
-	LastError     string
-	CreatedAt     time.Time
+	ID               int64
+	URL              string
+	Title            string
+	Description      string
+	LastFetchTime    *time.Time
+	FetchInterval    int
+	IsActive         bool
+	ErrorCount       int
+	LastError        string
+	CreatedAt        time.Time
+	ImageMode        string     // process | proxy | off
+	LastItemAddedAt  *time.Time // 最近一次新增文章的时间
+	ProxyURL         string     // 源级出站代理，空表示使用全局代理，direct 表示直连
+	RetentionSeconds int        // 源级文章保留时间（秒），0 表示使用全局设置
+	Category         string     // 源分类，文章没有自带分类时继承
+	// 已入库文章在 feed 中被修改时的处理方式：off | update | unread
+	ContentUpdateMode string
+	Favicon           string     // 本地缓存的图标路径（/static/favicons/...），空表示没有图标
+	FaviconUpdatedAt  *time.Time // 最近一次解析图标的时间，用于定期重新解析
+	SummaryLength     int        // 源级摘要长度（字符数），0 表示使用默认长度
+	GalleryEnabled    bool       // 是否为文章提取图集（正文前几张图片）
+	FullContent       bool       // feed 只提供摘要时是否从原文链接提取全文
+	MinWordCount      int        // 字数低于该值的文章只入库不投递，0 表示不限制
+	LenientParse      bool       // 严格解析失败时是否清理 XML 中的非法字符后重试
+	BodyImagesOnly    bool       // 只缓存正文主体中的图片，跳过页眉、页脚、侧栏等位置的广告和挂件图片
+	Language          string     // 源语言（主语言代码，如 en、zh），空表示未知，首次抓取时取 feed 声明的语言
+	MaxItems          int        // 源最多保留的文章数，超出的旧文章在抓取后删除，0 表示不限制
+	LastParseNote     string     // 最近一次抓取的数据质量提示（如部分文章没有日期），没有问题时为空
+	HTTPETag          string     // 最近一次成功抓取响应的 ETag，下次抓取时作为 If-None-Match 发送
+	HTTPLastModified  string     // 最近一次成功抓取响应的 Last-Modified，下次抓取时作为 If-Modified-Since 发送
+	FeedTitle         string     // 最近一次抓取到的 feed 标题，用于判断 feed 是否改名；空表示还没有记录
+}
+
+// Staleness 距最近一次新增文章的时长（从未产出文章时按创建时间计算）
+func (s *Source) Staleness(now time.Time) time.Duration {
+	if s.LastItemAddedAt != nil {
+		return now.Sub(*s.LastItemAddedAt)
+	}
+	return now.Sub(s.CreatedAt)
+}
+
+// 订阅源图片处理模式
+const (
+	ImageModeProcess = "process" // 下载、压缩并缓存到本地（默认）
+	ImageModeProxy   = "proxy"   // 改写为 /api/image 代理地址，不下载
+	ImageModeOff     = "off"     // 保留原始图片地址
+)
+
+// IsValidImageMode 检查图片处理模式是否合法
+func IsValidImageMode(mode string) bool {
+	switch mode {
+	case ImageModeProcess, ImageModeProxy, ImageModeOff:
+		return true
+	}
+	return false
+}
+
+// 已入库文章内容变化（同 GUID 新内容）时的处理方式
+const (
+	ContentUpdateOff    = "off"    // 忽略，保留首次入库的内容（默认）
+	ContentUpdateApply  = "update" // 更新文章内容，阅读状态不变
+	ContentUpdateUnread = "unread" // 更新文章内容，并为尚未阅读的用户重新投递
+)
+
+// IsValidContentUpdateMode 检查内容更新模式是否合法
+func IsValidContentUpdateMode(mode string) bool {
+	switch mode {
+	case ContentUpdateOff, ContentUpdateApply, ContentUpdateUnread:
+		return true
+	}
+	return false
+}
+
+// SourceCredential 订阅源凭据（私有源认证）
+// AuthType: basic（HTTP Basic）| header（自定义请求头）| token（URL 令牌）
+type SourceCredential struct {
+	SourceID        int64
+	AuthType        string
+	Username        string // basic 模式的用户名
+	SecretEncrypted string // 加密后的密码 / 请求头值 / 令牌
+	ParamName       string // header 模式为请求头名，token 模式为查询参数名
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 // Subscription 订阅关系
@@ -306,6 +744,24 @@ type Subscription struct {
 	SubscribedAt time.Time
 }
 
+// RSSGroup 用户的订阅源分组
+type RSSGroup struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"-"`
+	Name      string `json:"name"`
+	Icon      string `json:"icon"`
+	Color     string `json:"color"`
+	SortOrder int    `json:"sort_order"`
+	CreatedAt int64  `json:"created_at"` // Unix 时间戳
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// GroupAssignment 订阅所属分组，GroupID 为 nil 表示未分组
+type GroupAssignment struct {
+	SourceID int64  `json:"source_id"`
+	GroupID  *int64 `json:"group_id"`
+}
+
 // Item 文章
 type Item struct {
 	ID          int64      `json:"ID"`
@@ -318,6 +774,7 @@ type Item struct {
 	CreatedAt   time.Time  `json:"CreatedAt"`
 	// Quest 3: 新增字段
 	Summary           string `json:"Summary"`
+	Difficulty        string `json:"Difficulty"` // 难度（easy/medium/hard）
 	WordCount         int    `json:"WordCount"`
 	ReadingTime       int    `json:"ReadingTime"`
 	CoverImage        string `json:"CoverImage"`
@@ -328,10 +785,33 @@ type Item struct {
 	ImageCaption      string `json:"ImageCaption"`      // Added
 	ImageCredit       string `json:"ImageCredit"`       // Added
 	ImagePrimaryColor string `json:"ImagePrimaryColor"` // Added
+	ImageBlurhash     string `json:"ImageBlurhash"`     // 封面图 BlurHash 占位（运行时配置开启时生成）
+	CoverVariants     string `json:"CoverVariants"`     // 封面缩略图（CoverVariant JSON 数组）
+	Tags              string `json:"Tags"`              // 标签（JSON数组）
+	Category          string `json:"Category"`          // 分类（feed 首个分类，缺省继承源分类）
+	Truncated         bool   `json:"Truncated"`         // 正文超过大小上限已截断
+	URL               string `json:"URL"`               // 规范化后的文章链接（见 utils.CanonicalizeArticleURL）
+	Gallery           string `json:"Gallery"`           // 图集（GalleryImage JSON 数组）
+	ITunesDuration    string `json:"ITunesDuration"`    // 播客时长（itunes:duration 原文，如 "1:02:03" 或秒数）
+	ITunesImage       string `json:"ITunesImage"`       // 单集封面（itunes:image）
+	ITunesEpisode     string `json:"ITunesEpisode"`     // 集数（itunes:episode）
+	ITunesAuthor      string `json:"ITunesAuthor"`      // 作者（itunes:author）
 	SourceTitle       string `json:"SourceTitle"`       // Added for sync
 	SourceURL         string `json:"SourceURL"`         // Added for sync
 }
 
+// GalleryImage 图集中的一张图片，按正文中出现的顺序排列
+type GalleryImage struct {
+	URL string `json:"url"`
+	Alt string `json:"alt"`
+}
+
+// CoverVariant 封面图的一个缩略图尺寸，按宽度升序排列
+type CoverVariant struct {
+	URL   string `json:"url"`
+	Width int    `json:"width"`
+}
+
 // UserArticle 用户视角的文章（包含源信息与投递状态）
 type UserArticle struct {
 	ID          int64
@@ -357,6 +837,11 @@ type UserArticle struct {
 	ImageCaption      string // Added
 	ImageCredit       string // Added
 	ImagePrimaryColor string // Added
+	ImageBlurhash     string // 封面图 BlurHash 占位
+	CoverVariants     string // 封面缩略图（CoverVariant JSON 数组）
+	Tags              string // 标签（JSON数组）
+	Category          string // 分类
+	Truncated         bool   // 正文已截断
 	// Quest 5: 阅读状态字段
 	IsFavorite   bool
 	ReadProgress int
@@ -364,6 +849,46 @@ type UserArticle struct {
 	UpdatedAt    time.Time
 }
 
+// RelatedArticle 相关文章候选（Overlap 为共享标签数）
+type RelatedArticle struct {
+	Article *UserArticle
+	Overlap int
+}
+
+// ItemTitle 文章 ID 和标题，用于按标题相似度查找相关文章
+type ItemTitle struct {
+	ID    int64
+	Title string
+}
+
+// TagFacet 标签统计
+type TagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// CategoryFacet 分类统计
+type CategoryFacet struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+	Unread   int    `json:"unread"`
+}
+
+// SourceCount 单个订阅源的未读 / 总数统计
+type SourceCount struct {
+	SourceID int64 `json:"source_id"`
+	Unread   int   `json:"unread"`
+	Total    int   `json:"total"`
+}
+
+// ExpirableItem 可按保留策略清理的文章
+type ExpirableItem struct {
+	ItemID           int64
+	SourceID         int64
+	RetentionSeconds int       // 所属源的保留时间，0 表示使用全局设置
+	LastActivityAt   time.Time // 各投递中最近的发送或阅读时间，保留时间从此开始计算
+}
+
 // UserDelivery 用户投递状态
 type UserDelivery struct {
 	UserID      int64
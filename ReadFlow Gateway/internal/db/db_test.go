@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestDB 在临时目录中创建完整初始化（建表 + 迁移）的数据库
+func newTestDB(t testing.TB) *DB {
+	t.Helper()
+	database, err := New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// createTestUser 创建测试用户
+func createTestUser(t testing.TB, database *DB, name string) *User {
+	t.Helper()
+	user, err := database.CreateUser(name, name+"@example.com", "hash")
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+// createTestSource 创建测试订阅源
+func createTestSource(t testing.TB, database *DB, url string) *Source {
+	t.Helper()
+	source, err := database.CreateSource(url, "Test Source", "", 900)
+	if err != nil {
+		t.Fatalf("CreateSource: %v", err)
+	}
+	return source
+}
+
+// createTestItem 创建测试文章，只填写测试关心的字段
+func createTestItem(t testing.TB, database *DB, sourceID int64, guid, contentHash string, publishedAt time.Time) *Item {
+	t.Helper()
+	item, err := database.CreateItem(sourceID, guid, "Title "+guid, "<item></item>", "",
+		&publishedAt, "summary", "easy", 100, 1,
+		"", "", "<p>body</p>", "<p>body</p>", contentHash,
+		"", "", "", "", "", false, "", "", "",
+		"", "", "", "",
+		"")
+	if err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	return item
+}
+
+// countRows 统计满足条件的行数
+func countRows(t testing.TB, database *DB, table, where string, args ...interface{}) int {
+	t.Helper()
+	var n int
+	if err := database.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, where), args...).Scan(&n); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
@@ -0,0 +1,355 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// 用户数据导出 / 导入
+// 导出只包含用户自己的数据（订阅、分组、过滤规则、生词本、阅读状态、收藏），不包含各用户共享的文章正文；
+// 各表分页读取并逐条交给调用方写出，内存占用与数据量无关
+
+// 导出记录类型（导出文件每行的 type 字段）
+const (
+	ExportKindGroup        = "group"
+	ExportKindSubscription = "subscription"
+	ExportKindFilterRule   = "filter_rule"
+	ExportKindVocabulary   = "vocabulary"
+	ExportKindReadState    = "read_state"
+	ExportKindFavorite     = "favorite"
+)
+
+// ExportGroup 导出的订阅源分组，ID 只在导出文件内用于关联订阅
+type ExportGroup struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Icon  string `json:"icon,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+// ExportSubscription 导出的订阅，以源 URL 标识
+type ExportSubscription struct {
+	URL          string `json:"url"`
+	Title        string `json:"title,omitempty"`
+	Category     string `json:"category,omitempty"`
+	Language     string `json:"language,omitempty"`
+	GroupID      *int64 `json:"group_id,omitempty"` // 对应同一文件中 group 记录的 id
+	SubscribedAt int64  `json:"subscribed_at"`
+}
+
+// ExportFilterRule 导出的过滤规则，绑定的源以 URL 表示
+type ExportFilterRule struct {
+	Keyword    string   `json:"keyword"`
+	IsRegex    bool     `json:"is_regex"`
+	Mode       string   `json:"mode"`
+	Scope      string   `json:"scope"`
+	SourceURLs []string `json:"source_urls"`
+}
+
+// ExportReadState 导出的阅读状态，按文章内容哈希标识（同一文章在其他实例入库后哈希相同）
+type ExportReadState struct {
+	ContentHash  string `json:"content_hash"`
+	IsRead       bool   `json:"is_read"`
+	ReadProgress int    `json:"read_progress"`
+	ReadAt       *int64 `json:"read_at,omitempty"`
+	UpdatedAt    int64  `json:"updated_at"`
+}
+
+// ExportFavorite 导出的收藏，按内容哈希匹配，URL 和标题供用户查看
+type ExportFavorite struct {
+	ContentHash string `json:"content_hash"`
+	URL         string `json:"url,omitempty"`
+	Title       string `json:"title"`
+	SourceURL   string `json:"source_url"`
+}
+
+// exportPageSize 导出时每次查询的行数
+// 连接池只有一个连接：每页读完并释放连接后再写出，客户端下载慢时不会占住数据库
+const exportPageSize = 500
+
+// ExportUserData 按 分组、订阅、过滤规则、生词、阅读状态、收藏 的顺序读取用户数据并逐条调用 emit
+// 生词以 *Vocabulary 传给 emit，其余为对应的 Export* 结构；emit 返回错误时停止导出
+func (db *DB) ExportUserData(userID int64, emit func(kind string, record interface{}) error) error {
+	// 分组数量很少，一次读取
+	groups, err := db.GetUserGroups(userID)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := emit(ExportKindGroup, &ExportGroup{ID: g.ID, Name: g.Name, Icon: g.Icon, Color: g.Color}); err != nil {
+			return err
+		}
+	}
+
+	for _, export := range []func(int64, func(string, interface{}) error) error{
+		db.exportSubscriptions, db.exportFilterRules, db.exportVocabulary, db.exportReadState, db.exportFavorites,
+	} {
+		if err := export(userID, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportPaged 按键升序分页执行 query（参数依次为 userID、上一页最后的键、每页行数），
+// scan 返回每行的键和记录；整页读完关闭结果集后再对每条记录调用 emit
+func (db *DB) exportPaged(userID int64, after interface{}, query string, kind string,
+	scan func(*sql.Rows) (interface{}, interface{}, error), emit func(string, interface{}) error) error {
+	for {
+		rows, err := db.Query(query, userID, after, exportPageSize)
+		if err != nil {
+			return err
+		}
+		var records []interface{}
+		for rows.Next() {
+			key, record, err := scan(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			after = key
+			records = append(records, record)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if err := emit(kind, record); err != nil {
+				return err
+			}
+		}
+		if len(records) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+func (db *DB) exportSubscriptions(userID int64, emit func(string, interface{}) error) error {
+	return db.exportPaged(userID, int64(0), `
+		SELECT s.id, s.url, COALESCE(s.title, ''), COALESCE(s.category, ''), COALESCE(s.language, ''),
+		       sub.group_id, sub.subscribed_at
+		FROM subscriptions sub
+		INNER JOIN sources s ON sub.source_id = s.id
+		WHERE sub.user_id = ? AND sub.source_id > ?
+		ORDER BY sub.source_id
+		LIMIT ?
+	`, ExportKindSubscription, func(rows *sql.Rows) (interface{}, interface{}, error) {
+		s := &ExportSubscription{}
+		var sourceID int64
+		var groupID sql.NullInt64
+		var subscribedAt sql.NullTime
+		if err := rows.Scan(&sourceID, &s.URL, &s.Title, &s.Category, &s.Language, &groupID, &subscribedAt); err != nil {
+			return nil, nil, err
+		}
+		if groupID.Valid {
+			s.GroupID = &groupID.Int64
+		}
+		if subscribedAt.Valid {
+			s.SubscribedAt = subscribedAt.Time.Unix()
+		}
+		return sourceID, s, nil
+	}, emit)
+}
+
+func (db *DB) exportFilterRules(userID int64, emit func(string, interface{}) error) error {
+	return db.exportPaged(userID, int64(0), `
+		SELECT fr.id, fr.keyword, COALESCE(fr.is_regex, 0), COALESCE(fr.mode, 'exclude'), COALESCE(fr.scope, 'specific'),
+		       (SELECT json_group_array(s.url)
+		        FROM filter_bindings fb INNER JOIN sources s ON fb.source_id = s.id
+		        WHERE fb.rule_id = fr.id AND fb.user_id = fr.user_id)
+		FROM filter_rules fr
+		WHERE fr.user_id = ? AND fr.id > ?
+		ORDER BY fr.id
+		LIMIT ?
+	`, ExportKindFilterRule, func(rows *sql.Rows) (interface{}, interface{}, error) {
+		r := &ExportFilterRule{}
+		var id int64
+		var sourceURLs string
+		if err := rows.Scan(&id, &r.Keyword, &r.IsRegex, &r.Mode, &r.Scope, &sourceURLs); err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal([]byte(sourceURLs), &r.SourceURLs); err != nil {
+			return nil, nil, err
+		}
+		return id, r, nil
+	}, emit)
+}
+
+func (db *DB) exportVocabulary(userID int64, emit func(string, interface{}) error) error {
+	return db.exportPaged(userID, "", `
+		SELECT
+			id, user_id, word, COALESCE(definition, ''), COALESCE(translation, ''),
+			COALESCE(example, ''), COALESCE(context, ''),
+			COALESCE(source_article_id, ''), COALESCE(source_article_title, ''), COALESCE(article_id, 0),
+			COALESCE(review_count, 0), COALESCE(correct_count, 0),
+			COALESCE(last_review_at, 0), COALESCE(next_review_at, 0), COALESCE(mastery_level, 0),
+			COALESCE(difficulty, 'medium'), COALESCE(tags, ''), COALESCE(notes, ''),
+			COALESCE(added_at, 0), COALESCE(created_at, 0), COALESCE(updated_at, 0), is_deleted
+		FROM vocabularies
+		WHERE user_id = ? AND id > ? AND is_deleted = 0
+		ORDER BY id
+		LIMIT ?
+	`, ExportKindVocabulary, func(rows *sql.Rows) (interface{}, interface{}, error) {
+		vocab := &Vocabulary{}
+		err := rows.Scan(
+			&vocab.ID, &vocab.UserID, &vocab.Word, &vocab.Definition, &vocab.Translation,
+			&vocab.Example, &vocab.Context,
+			&vocab.SourceArticleID, &vocab.SourceArticleTitle, &vocab.ArticleID,
+			&vocab.ReviewCount, &vocab.CorrectCount,
+			&vocab.LastReviewAt, &vocab.NextReviewAt, &vocab.MasteryLevel,
+			&vocab.Difficulty, &vocab.Tags, &vocab.Notes,
+			&vocab.AddedAt, &vocab.CreatedAt, &vocab.UpdatedAt, &vocab.IsDeleted,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		return vocab.ID, vocab, nil
+	}, emit)
+}
+
+func (db *DB) exportReadState(userID int64, emit func(string, interface{}) error) error {
+	return db.exportPaged(userID, "", `
+		SELECT content_hash, COALESCE(is_read, 0), COALESCE(read_progress, 0), read_at, updated_at
+		FROM read_state
+		WHERE user_id = ? AND content_hash > ? AND (is_read = 1 OR read_progress > 0)
+		ORDER BY content_hash
+		LIMIT ?
+	`, ExportKindReadState, func(rows *sql.Rows) (interface{}, interface{}, error) {
+		rs := &ExportReadState{}
+		var readAt, updatedAt sql.NullTime
+		if err := rows.Scan(&rs.ContentHash, &rs.IsRead, &rs.ReadProgress, &readAt, &updatedAt); err != nil {
+			return nil, nil, err
+		}
+		if readAt.Valid {
+			unix := readAt.Time.Unix()
+			rs.ReadAt = &unix
+		}
+		if updatedAt.Valid {
+			rs.UpdatedAt = updatedAt.Time.Unix()
+		}
+		return rs.ContentHash, rs, nil
+	}, emit)
+}
+
+func (db *DB) exportFavorites(userID int64, emit func(string, interface{}) error) error {
+	return db.exportPaged(userID, int64(0), `
+		SELECT ud.item_id, i.content_hash, COALESCE(i.url, ''), i.title, s.url
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		INNER JOIN sources s ON i.source_id = s.id
+		WHERE ud.user_id = ? AND ud.item_id > ? AND ud.is_favorite = 1 AND COALESCE(i.content_hash, '') != ''
+		ORDER BY ud.item_id
+		LIMIT ?
+	`, ExportKindFavorite, func(rows *sql.Rows) (interface{}, interface{}, error) {
+		f := &ExportFavorite{}
+		var itemID int64
+		if err := rows.Scan(&itemID, &f.ContentHash, &f.URL, &f.Title, &f.SourceURL); err != nil {
+			return nil, nil, err
+		}
+		return itemID, f, nil
+	}, emit)
+}
+
+// ImportReadState 导入一条阅读状态：本实例已有该内容的阅读状态时保留已有的，返回是否写入
+// 写入后同步到已投递的同内容文章（只把未读改为已读、只填充没有进度的文章），之后入库的文章按 read_state 恢复
+func (db *DB) ImportReadState(userID int64, state *ExportReadState) (bool, error) {
+	now := time.Now()
+	var readAt *time.Time
+	if state.IsRead {
+		t := now
+		if state.ReadAt != nil {
+			t = time.Unix(*state.ReadAt, 0)
+		}
+		readAt = &t
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// updated_at 使用导入时间，避免按更新时间清理孤立阅读状态时，尚未重新入库的文章状态被立即清理
+	result, err := tx.Exec(`
+		INSERT INTO read_state (user_id, content_hash, is_read, read_progress, read_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, content_hash) DO NOTHING
+	`, userID, state.ContentHash, state.IsRead, state.ReadProgress, readAt, now)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE user_deliveries SET
+			status = CASE WHEN ? AND status = 0 THEN 2 ELSE status END,
+			read_at = CASE WHEN ? THEN COALESCE(read_at, ?) ELSE read_at END,
+			read_progress = CASE WHEN read_progress = 0 THEN ? ELSE read_progress END,
+			updated_at = ?
+		WHERE user_id = ? AND item_id IN (SELECT id FROM items WHERE content_hash = ?)
+	`, state.IsRead, state.IsRead, readAt, state.ReadProgress, now, userID, state.ContentHash); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ImportFavorite 把已投递给用户的同内容文章标为收藏，返回匹配的文章数（文章尚未入库时为 0）
+func (db *DB) ImportFavorite(userID int64, contentHash string) (int64, error) {
+	result, err := db.Exec(`
+		UPDATE user_deliveries SET is_favorite = 1, updated_at = ?
+		WHERE user_id = ? AND item_id IN (SELECT id FROM items WHERE content_hash = ?)
+	`, time.Now(), userID, contentHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ImportFilterRule 导入一条过滤规则并绑定到 sourceIDs（用户已订阅的源），返回是否新建
+// 用户已有相同的规则（关键词、匹配方式、模式和范围都相同）时不重复创建
+func (db *DB) ImportFilterRule(userID int64, rule *ExportFilterRule, sourceIDs []int64) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var existing int64
+	err = tx.QueryRow(`
+		SELECT id FROM filter_rules
+		WHERE user_id = ? AND keyword = ? AND COALESCE(is_regex, 0) = ? AND mode = ? AND scope = ?
+	`, userID, rule.Keyword, rule.IsRegex, rule.Mode, rule.Scope).Scan(&existing)
+	if err == nil {
+		return false, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO filter_rules (user_id, keyword, is_regex, mode, scope) VALUES (?, ?, ?, ?, ?)",
+		userID, rule.Keyword, rule.IsRegex, rule.Mode, rule.Scope,
+	)
+	if err != nil {
+		return false, err
+	}
+	ruleID, err := result.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	for _, sourceID := range sourceIDs {
+		if _, err := tx.Exec(
+			"INSERT OR IGNORE INTO filter_bindings (rule_id, user_id, source_id) VALUES (?, ?, ?)",
+			ruleID, userID, sourceID,
+		); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExportUserData(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	bob := createTestUser(t, database, "bob")
+	source := createTestSource(t, database, "https://example.com/feed")
+	for _, userID := range []int64{alice.ID, bob.ID} {
+		if err := database.CreateSubscription(userID, source.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	group, err := database.CreateGroup(alice.ID, "Tech", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.SetSubscriptionGroups(alice.ID, []GroupAssignment{{SourceID: source.ID, GroupID: &group.ID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	read := createTestItem(t, database, source.ID, "read", "hash-read", time.Now())
+	favorite := createTestItem(t, database, source.ID, "favorite", "hash-favorite", time.Now())
+	for _, item := range []*Item{read, favorite} {
+		if err := database.BatchCreateUserDeliveries(item.ID, []int64{alice.ID}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := saveReadStatus(database, alice.ID, read.ID, true, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec("UPDATE user_deliveries SET is_favorite = 1 WHERE user_id = ? AND item_id = ?", alice.ID, favorite.ID); err != nil {
+		t.Fatal(err)
+	}
+	res, err := database.Exec("INSERT INTO filter_rules (user_id, keyword) VALUES (?, 'ads')", alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ruleID, _ := res.LastInsertId()
+	if _, err := database.Exec("INSERT INTO filter_bindings (rule_id, user_id, source_id) VALUES (?, ?, ?)", ruleID, alice.ID, source.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	records := make(map[string][]interface{})
+	if err := database.ExportUserData(alice.ID, func(kind string, record interface{}) error {
+		records[kind] = append(records[kind], record)
+		return nil
+	}); err != nil {
+		t.Fatalf("ExportUserData: %v", err)
+	}
+
+	if len(records[ExportKindGroup]) != 1 || len(records[ExportKindSubscription]) != 1 {
+		t.Fatalf("groups/subscriptions = %d/%d, want 1/1", len(records[ExportKindGroup]), len(records[ExportKindSubscription]))
+	}
+	sub := records[ExportKindSubscription][0].(*ExportSubscription)
+	if sub.URL != source.URL || sub.GroupID == nil || *sub.GroupID != group.ID {
+		t.Errorf("subscription = %+v, want %s in group %d", sub, source.URL, group.ID)
+	}
+	if len(records[ExportKindFilterRule]) != 1 {
+		t.Fatalf("filter rules = %d, want 1", len(records[ExportKindFilterRule]))
+	}
+	rule := records[ExportKindFilterRule][0].(*ExportFilterRule)
+	if rule.Keyword != "ads" || rule.Mode != "exclude" || len(rule.SourceURLs) != 1 || rule.SourceURLs[0] != source.URL {
+		t.Errorf("filter rule = %+v", rule)
+	}
+	if len(records[ExportKindReadState]) != 1 || records[ExportKindReadState][0].(*ExportReadState).ContentHash != "hash-read" {
+		t.Errorf("read state = %+v, want only hash-read", records[ExportKindReadState])
+	}
+	if len(records[ExportKindFavorite]) != 1 || records[ExportKindFavorite][0].(*ExportFavorite).ContentHash != "hash-favorite" {
+		t.Errorf("favorites = %+v, want only hash-favorite", records[ExportKindFavorite])
+	}
+
+	// 其他用户的导出为空
+	bobRecords := 0
+	if err := database.ExportUserData(bob.ID, func(kind string, record interface{}) error {
+		if kind != ExportKindSubscription {
+			bobRecords++
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if bobRecords != 0 {
+		t.Errorf("bob exported %d non-subscription records, want 0", bobRecords)
+	}
+}
+
+func TestImportUserData(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "alice")
+	source := createTestSource(t, database, "https://example.com/feed")
+	if err := database.CreateSubscription(user.ID, source.ID); err != nil {
+		t.Fatal(err)
+	}
+	item := createTestItem(t, database, source.ID, "a", "hash-a", time.Now())
+	if err := database.BatchCreateUserDeliveries(item.ID, []int64{user.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 阅读状态：已投递的文章立即更新，本实例已有的状态不覆盖
+	state := &ExportReadState{ContentHash: "hash-a", IsRead: true, ReadProgress: 80}
+	if written, err := database.ImportReadState(user.ID, state); err != nil || !written {
+		t.Fatalf("ImportReadState = %v, %v, want true", written, err)
+	}
+	if n := countRows(t, database, "user_deliveries", "user_id = ? AND item_id = ? AND status = 2 AND read_progress = 80", user.ID, item.ID); n != 1 {
+		t.Errorf("delivery not marked read")
+	}
+	if written, err := database.ImportReadState(user.ID, &ExportReadState{ContentHash: "hash-a"}); err != nil || written {
+		t.Errorf("second ImportReadState = %v, %v, want false", written, err)
+	}
+	if _, err := database.ImportReadState(user.ID, &ExportReadState{ContentHash: "hash-later", IsRead: true}); err != nil {
+		t.Fatal(err)
+	}
+	later := createTestItem(t, database, source.ID, "later", "hash-later", time.Now())
+	if err := database.BatchCreateUserDeliveries(later.ID, []int64{user.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, database, "user_deliveries", "user_id = ? AND item_id = ? AND status = 2", user.ID, later.ID); n != 1 {
+		t.Errorf("later delivery did not restore imported read state")
+	}
+
+	// 收藏只能恢复到已投递的文章
+	if n, err := database.ImportFavorite(user.ID, "hash-a"); err != nil || n != 1 {
+		t.Errorf("ImportFavorite(hash-a) = %d, %v, want 1", n, err)
+	}
+	if n, err := database.ImportFavorite(user.ID, "hash-missing"); err != nil || n != 0 {
+		t.Errorf("ImportFavorite(hash-missing) = %d, %v, want 0", n, err)
+	}
+
+	// 相同的过滤规则不重复创建
+	rule := &ExportFilterRule{Keyword: "ads", Mode: "exclude", Scope: "specific"}
+	for i, want := range []bool{true, false} {
+		created, err := database.ImportFilterRule(user.ID, rule, []int64{source.ID})
+		if err != nil || created != want {
+			t.Errorf("ImportFilterRule #%d = %v, %v, want %v", i, created, err, want)
+		}
+	}
+	if n := countRows(t, database, "filter_rules", "user_id = ?", user.ID); n != 1 {
+		t.Errorf("filter rules = %d, want 1", n)
+	}
+	if n := countRows(t, database, "filter_bindings", "user_id = ? AND source_id = ?", user.ID, source.ID); n != 1 {
+		t.Errorf("filter bindings = %d, want 1", n)
+	}
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// RSSGroup 相关操作
+
+// 分组调整的校验错误
+var (
+	ErrGroupNotFound = errors.New("group not found")
+	ErrNotSubscribed = errors.New("source not subscribed")
+)
+
+// CreateGroup 创建分组，排在用户已有分组之后
+func (db *DB) CreateGroup(userID int64, name, icon, color string) (*RSSGroup, error) {
+	now := time.Now().Unix()
+	result, err := db.Exec(`
+		INSERT INTO rss_groups (user_id, name, icon, color, sort_order, created_at, updated_at)
+		VALUES (?, ?, ?, ?, (SELECT COALESCE(MAX(sort_order), -1) + 1 FROM rss_groups WHERE user_id = ?), ?, ?)
+	`, userID, name, icon, color, userID, now, now)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetUserGroup(userID, id)
+}
+
+// GetUserGroup 获取用户的分组，分组不存在或不属于该用户时返回 sql.ErrNoRows
+func (db *DB) GetUserGroup(userID, groupID int64) (*RSSGroup, error) {
+	g := &RSSGroup{}
+	err := db.QueryRow(`
+		SELECT id, user_id, name, COALESCE(icon, ''), COALESCE(color, ''), sort_order, created_at, updated_at
+		FROM rss_groups WHERE id = ? AND user_id = ?
+	`, groupID, userID).Scan(&g.ID, &g.UserID, &g.Name, &g.Icon, &g.Color, &g.SortOrder, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// GetUserGroups 获取用户的全部分组（按 sort_order 排序）
+func (db *DB) GetUserGroups(userID int64) ([]*RSSGroup, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name, COALESCE(icon, ''), COALESCE(color, ''), sort_order, created_at, updated_at
+		FROM rss_groups WHERE user_id = ?
+		ORDER BY sort_order, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []*RSSGroup{}
+	for rows.Next() {
+		g := &RSSGroup{}
+		if err := rows.Scan(&g.ID, &g.UserID, &g.Name, &g.Icon, &g.Color, &g.SortOrder, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// DeleteGroup 删除分组，组内订阅变为未分组；分组不存在时返回 sql.ErrNoRows
+func (db *DB) DeleteGroup(userID, groupID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("DELETE FROM rss_groups WHERE id = ? AND user_id = ?", groupID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := tx.Exec("UPDATE subscriptions SET group_id = NULL WHERE user_id = ? AND group_id = ?", userID, groupID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetSubscriptionGroups 获取用户各订阅所属的分组（只包含已分组的订阅）
+func (db *DB) GetSubscriptionGroups(userID int64) (map[int64]int64, error) {
+	rows, err := db.Query("SELECT source_id, group_id FROM subscriptions WHERE user_id = ? AND group_id IS NOT NULL", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[int64]int64)
+	for rows.Next() {
+		var sourceID, groupID int64
+		if err := rows.Scan(&sourceID, &groupID); err != nil {
+			return nil, err
+		}
+		groups[sourceID] = groupID
+	}
+	return groups, rows.Err()
+}
+
+// SetSubscriptionGroups 在一个事务中调整多个订阅的分组
+// 任一源未被该用户订阅（ErrNotSubscribed）或分组不属于该用户（ErrGroupNotFound）时全部不生效；
+// 同一源出现多次时以最后一次为准。返回调整后的分组归属（按 source_id 排序）
+func (db *DB) SetSubscriptionGroups(userID int64, assignments []GroupAssignment) ([]GroupAssignment, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	checkedGroups := make(map[int64]bool)
+	for _, a := range assignments {
+		if a.GroupID != nil && !checkedGroups[*a.GroupID] {
+			var exists int
+			err := tx.QueryRow("SELECT 1 FROM rss_groups WHERE id = ? AND user_id = ?", *a.GroupID, userID).Scan(&exists)
+			if err == sql.ErrNoRows {
+				return nil, ErrGroupNotFound
+			}
+			if err != nil {
+				return nil, err
+			}
+			checkedGroups[*a.GroupID] = true
+		}
+
+		result, err := tx.Exec("UPDATE subscriptions SET group_id = ? WHERE user_id = ? AND source_id = ?", a.GroupID, userID, a.SourceID)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return nil, ErrNotSubscribed
+		}
+	}
+
+	// 读回调整后的归属
+	ids := make([]interface{}, 0, len(assignments)+1)
+	ids = append(ids, userID)
+	seen := make(map[int64]bool)
+	for _, a := range assignments {
+		if !seen[a.SourceID] {
+			seen[a.SourceID] = true
+			ids = append(ids, a.SourceID)
+		}
+	}
+	updated := []GroupAssignment{}
+	if len(ids) > 1 {
+		rows, err := tx.Query(
+			"SELECT source_id, group_id FROM subscriptions WHERE user_id = ? AND source_id IN ("+
+				strings.TrimSuffix(strings.Repeat("?,", len(ids)-1), ",")+") ORDER BY source_id",
+			ids...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var a GroupAssignment
+			if err := rows.Scan(&a.SourceID, &a.GroupID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			updated = append(updated, a)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, tx.Commit()
+}
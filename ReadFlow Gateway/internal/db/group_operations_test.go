@@ -0,0 +1,81 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetSubscriptionGroups(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	bob := createTestUser(t, database, "bob")
+	s1 := createTestSource(t, database, "https://example.com/1.xml")
+	s2 := createTestSource(t, database, "https://example.com/2.xml")
+	s3 := createTestSource(t, database, "https://example.com/3.xml")
+	for _, s := range []*Source{s1, s2} {
+		if err := database.CreateSubscription(alice.ID, s.ID); err != nil {
+			t.Fatalf("CreateSubscription: %v", err)
+		}
+	}
+
+	tech, err := database.CreateGroup(alice.ID, "Tech", "", "")
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	news, err := database.CreateGroup(alice.ID, "News", "", "")
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+	if news.SortOrder != tech.SortOrder+1 {
+		t.Errorf("new group sort_order = %d, want %d", news.SortOrder, tech.SortOrder+1)
+	}
+	bobGroup, err := database.CreateGroup(bob.ID, "Bob", "", "")
+	if err != nil {
+		t.Fatalf("CreateGroup: %v", err)
+	}
+
+	updated, err := database.SetSubscriptionGroups(alice.ID, []GroupAssignment{
+		{SourceID: s1.ID, GroupID: &tech.ID},
+		{SourceID: s2.ID, GroupID: &news.ID},
+	})
+	if err != nil {
+		t.Fatalf("SetSubscriptionGroups: %v", err)
+	}
+	if len(updated) != 2 || *updated[0].GroupID != tech.ID || *updated[1].GroupID != news.ID {
+		t.Fatalf("updated = %+v", updated)
+	}
+
+	// 校验失败时整批回滚
+	cases := []struct {
+		name        string
+		assignments []GroupAssignment
+		want        error
+	}{
+		{"other user's group", []GroupAssignment{{SourceID: s1.ID}, {SourceID: s2.ID, GroupID: &bobGroup.ID}}, ErrGroupNotFound},
+		{"unsubscribed source", []GroupAssignment{{SourceID: s1.ID}, {SourceID: s3.ID, GroupID: &tech.ID}}, ErrNotSubscribed},
+	}
+	for _, tc := range cases {
+		if _, err := database.SetSubscriptionGroups(alice.ID, tc.assignments); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+	groups, err := database.GetSubscriptionGroups(alice.ID)
+	if err != nil {
+		t.Fatalf("GetSubscriptionGroups: %v", err)
+	}
+	if groups[s1.ID] != tech.ID || groups[s2.ID] != news.ID {
+		t.Errorf("assignments changed after failed update: %v", groups)
+	}
+
+	// 删除分组后组内订阅变为未分组
+	if err := database.DeleteGroup(alice.ID, tech.ID); err != nil {
+		t.Fatalf("DeleteGroup: %v", err)
+	}
+	groups, err = database.GetSubscriptionGroups(alice.ID)
+	if err != nil {
+		t.Fatalf("GetSubscriptionGroups: %v", err)
+	}
+	if _, ok := groups[s1.ID]; ok || groups[s2.ID] != news.ID {
+		t.Errorf("after DeleteGroup assignments = %v", groups)
+	}
+}
@@ -3,6 +3,8 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/readflow/gateway/internal/utils"
@@ -15,20 +17,27 @@ func (db *DB) CreateItem(
 	sourceID int64,
 	guid, title, xmlContent, imagePaths string,
 	publishedAt *time.Time,
-	summary string,
+	summary, difficulty string,
 	wordCount, readingTime int,
 	coverImage, author, cleanContent, content, contentHash string,
 	imageCaption, imageCredit, imagePrimaryColor string,
+	tags, category string,
+	truncated bool,
+	gallery, url, imageBlurhash string,
+	itunesDuration, itunesImage, itunesEpisode, itunesAuthor string,
+	coverVariants string,
 ) (*Item, error) {
 	result, err := db.Exec(`
 		INSERT INTO items (
 			source_id, guid, title, xml_content, image_paths, published_at,
-			summary, word_count, reading_time, cover_image, author, clean_content, content, content_hash,
-			image_caption, image_credit, image_primary_color
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			summary, difficulty, word_count, reading_time, cover_image, author, clean_content, content, content_hash,
+			image_caption, image_credit, image_primary_color, tags, category, is_truncated, gallery, url,
+			image_blurhash, itunes_duration, itunes_image, itunes_episode, itunes_author, cover_variants
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, sourceID, guid, title, xmlContent, imagePaths, publishedAt,
-		summary, wordCount, readingTime, coverImage, author, cleanContent, content, contentHash,
-		imageCaption, imageCredit, imagePrimaryColor)
+		summary, difficulty, wordCount, readingTime, coverImage, author, cleanContent, content, contentHash,
+		imageCaption, imageCredit, imagePrimaryColor, tags, category, truncated, gallery, url,
+		imageBlurhash, itunesDuration, itunesImage, itunesEpisode, itunesAuthor, coverVariants)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create item: %w", err)
@@ -51,7 +60,10 @@ func (db *DB) GetItemByID(id int64) (*Item, error) {
 		       COALESCE(summary, ''), COALESCE(word_count, 0), COALESCE(reading_time, 0),
 		       COALESCE(cover_image, ''), COALESCE(author, ''),
 		       COALESCE(clean_content, ''), COALESCE(content, ''), COALESCE(content_hash, ''),
-		       COALESCE(image_caption, ''), COALESCE(image_credit, ''), COALESCE(image_primary_color, '')
+		       COALESCE(image_caption, ''), COALESCE(image_credit, ''), COALESCE(image_primary_color, ''),
+		       COALESCE(tags, ''), COALESCE(category, ''), COALESCE(is_truncated, 0),
+		       COALESCE(gallery, ''), COALESCE(url, ''), COALESCE(image_blurhash, ''),
+		       COALESCE(difficulty, ''), COALESCE(cover_variants, '')
 		FROM items WHERE id = ?
 	`, id).Scan(
 		&item.ID, &item.SourceID, &item.GUID, &item.Title,
@@ -59,6 +71,8 @@ func (db *DB) GetItemByID(id int64) (*Item, error) {
 		&item.Summary, &item.WordCount, &item.ReadingTime,
 		&item.CoverImage, &item.Author, &item.CleanContent, &item.Content, &item.ContentHash,
 		&item.ImageCaption, &item.ImageCredit, &item.ImagePrimaryColor,
+		&item.Tags, &item.Category, &item.Truncated, &item.Gallery, &item.URL, &item.ImageBlurhash,
+		&item.Difficulty, &item.CoverVariants,
 	)
 
 	if err != nil {
@@ -76,7 +90,9 @@ func (db *DB) GetItemByGUID(sourceID int64, guid string) (*Item, error) {
 		       COALESCE(summary, ''), COALESCE(word_count, 0), COALESCE(reading_time, 0),
 		       COALESCE(cover_image, ''), COALESCE(author, ''),
 		       COALESCE(clean_content, ''), COALESCE(content, ''), COALESCE(content_hash, ''),
-		       COALESCE(image_caption, ''), COALESCE(image_credit, ''), COALESCE(image_primary_color, '')
+		       COALESCE(image_caption, ''), COALESCE(image_credit, ''), COALESCE(image_primary_color, ''),
+		       COALESCE(tags, ''), COALESCE(category, ''), COALESCE(is_truncated, 0),
+		       COALESCE(gallery, ''), COALESCE(url, '')
 		FROM items WHERE source_id = ? AND guid = ?
 	`, sourceID, guid).Scan(
 		&item.ID, &item.SourceID, &item.GUID, &item.Title,
@@ -84,6 +100,7 @@ func (db *DB) GetItemByGUID(sourceID int64, guid string) (*Item, error) {
 		&item.Summary, &item.WordCount, &item.ReadingTime,
 		&item.CoverImage, &item.Author, &item.CleanContent, &item.Content, &item.ContentHash,
 		&item.ImageCaption, &item.ImageCredit, &item.ImagePrimaryColor,
+		&item.Tags, &item.Category, &item.Truncated, &item.Gallery, &item.URL,
 	)
 
 	if err != nil {
@@ -92,20 +109,236 @@ func (db *DB) GetItemByGUID(sourceID int64, guid string) (*Item, error) {
 	return item, nil
 }
 
-// DeleteItem 删除文章
+// GetItemByURL 根据源 ID 和规范化链接获取文章（GUID 之外的第二去重键）
+func (db *DB) GetItemByURL(sourceID int64, url string) (*Item, error) {
+	item := &Item{}
+	err := db.QueryRow(`
+		SELECT id, source_id, guid, title, xml_content, 
+		       COALESCE(image_paths, ''), published_at, created_at,
+		       COALESCE(summary, ''), COALESCE(word_count, 0), COALESCE(reading_time, 0),
+		       COALESCE(cover_image, ''), COALESCE(author, ''),
+		       COALESCE(clean_content, ''), COALESCE(content, ''), COALESCE(content_hash, ''),
+		       COALESCE(image_caption, ''), COALESCE(image_credit, ''), COALESCE(image_primary_color, ''),
+		       COALESCE(tags, ''), COALESCE(category, ''), COALESCE(is_truncated, 0),
+		       COALESCE(gallery, ''), COALESCE(url, '')
+		FROM items WHERE source_id = ? AND url = ?
+		ORDER BY id LIMIT 1
+	`, sourceID, url).Scan(
+		&item.ID, &item.SourceID, &item.GUID, &item.Title,
+		&item.XMLContent, &item.ImagePaths, &item.PublishedAt, &item.CreatedAt,
+		&item.Summary, &item.WordCount, &item.ReadingTime,
+		&item.CoverImage, &item.Author, &item.CleanContent, &item.Content, &item.ContentHash,
+		&item.ImageCaption, &item.ImageCredit, &item.ImagePrimaryColor,
+		&item.Tags, &item.Category, &item.Truncated, &item.Gallery, &item.URL,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// UpdateItemContent 用 feed 中修改后的内容更新已入库文章（GUID、发布时间和分类保持不变）
+// 阅读状态按内容哈希从 previousHash 迁移到新哈希；markUnread 为 true 时，
+// 已发送但尚未阅读（status=1）的投递重置为待投递，让客户端重新拉取
+func (db *DB) UpdateItemContent(item *Item, previousHash string, markUnread bool) error {
+	now := time.Now()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		UPDATE items SET
+			title = ?, xml_content = ?, image_paths = ?,
+			summary = ?, difficulty = ?, word_count = ?, reading_time = ?, cover_image = ?, author = ?,
+			clean_content = ?, content = ?, content_hash = ?,
+			image_caption = ?, image_credit = ?, image_primary_color = ?, tags = ?, is_truncated = ?,
+			gallery = ?, image_blurhash = ?, cover_variants = ?,
+			itunes_duration = ?, itunes_image = ?, itunes_episode = ?, itunes_author = ?
+		WHERE id = ?
+	`, item.Title, item.XMLContent, item.ImagePaths,
+		item.Summary, item.Difficulty, item.WordCount, item.ReadingTime, item.CoverImage, item.Author,
+		item.CleanContent, item.Content, item.ContentHash,
+		item.ImageCaption, item.ImageCredit, item.ImagePrimaryColor, item.Tags, item.Truncated,
+		item.Gallery, item.ImageBlurhash, item.CoverVariants,
+		item.ITunesDuration, item.ITunesImage, item.ITunesEpisode, item.ITunesAuthor, item.ID); err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+
+	if previousHash != "" && previousHash != item.ContentHash {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO read_state (user_id, content_hash, is_read, read_progress, read_at, updated_at)
+			SELECT rs.user_id, ?, rs.is_read, rs.read_progress, rs.read_at, ?
+			FROM read_state rs
+			INNER JOIN user_deliveries ud ON ud.user_id = rs.user_id AND ud.item_id = ?
+			WHERE rs.content_hash = ?
+		`, item.ContentHash, now, item.ID, previousHash); err != nil {
+			return err
+		}
+	}
+
+	// updated_at 更新后，增量同步的客户端会重新拉取该文章
+	if _, err := tx.Exec(`
+		UPDATE user_deliveries
+		SET status = CASE WHEN ? AND status = 1 THEN 0 ELSE status END,
+		    updated_at = ?
+		WHERE item_id = ?
+	`, markUnread, now, item.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteItem 删除文章及其投递记录（同一事务，不依赖外键级联）
+// 按内容哈希保存的阅读状态保留，文章重新入库时可以恢复
 func (db *DB) DeleteItem(itemID int64) error {
-	_, err := db.Exec("DELETE FROM items WHERE id = ?", itemID)
-	return err
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM user_deliveries WHERE item_id = ?", itemID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM items WHERE id = ?", itemID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PurgeSourceItems 彻底清除源下的全部文章，返回删除的文章数
+// 与保留期清理不同，这里同时删除这些文章的阅读状态（其他源仍有同内容文章时保留），不留任何孤立记录
+func (db *DB) PurgeSourceItems(sourceID int64) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	purged, err := purgeSourceItemsTx(tx, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	// 清空后需要完整抓取一次，否则订阅源内容没有变化时以 304 响应，文章不会重新入库
+	if _, err := tx.Exec("UPDATE sources SET http_etag = NULL, http_last_modified = NULL WHERE id = ?", sourceID); err != nil {
+		return 0, err
+	}
+	return purged, tx.Commit()
+}
+
+// purgeSourceItemsTx 在事务中删除源下文章的阅读状态、投递记录、墓碑和文章本身
+// 墓碑一并删除，清空后重新抓取时 feed 中的文章全部重新入库
+func purgeSourceItemsTx(tx *sql.Tx, sourceID int64) (int64, error) {
+	if _, err := tx.Exec("DELETE FROM item_tombstones WHERE source_id = ?", sourceID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`
+		DELETE FROM read_state
+		WHERE content_hash IN (
+			SELECT content_hash FROM items WHERE source_id = ? AND content_hash IS NOT NULL AND content_hash != ''
+		)
+		AND content_hash NOT IN (
+			SELECT content_hash FROM items WHERE source_id != ? AND content_hash IS NOT NULL
+		)
+	`, sourceID, sourceID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM user_deliveries WHERE item_id IN (SELECT id FROM items WHERE source_id = ?)",
+		sourceID,
+	); err != nil {
+		return 0, err
+	}
+	result, err := tx.Exec("DELETE FROM items WHERE source_id = ?", sourceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// TrimSourceItems 只保留源最新的 max 篇文章（按发布时间，没有时按入库时间），删除更早的文章及其投递记录
+// 任一用户未确认、收藏或阅读中（进度 1-99）的文章不删除，但仍计入 max；阅读状态保留，由 PruneReadState 按期清理。
+// 删除的文章记录墓碑，仍在 feed 中时不会重新入库。
+// 返回删除的文章数和其中有本地图片的 image_paths，调用方在提交后删除图片文件
+func (db *DB) TrimSourceItems(sourceID int64, max int) (int64, []string, error) {
+	if max <= 0 {
+		return 0, nil, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	// published_at 由驱动写入带时区的时间，created_at 为 SQLite 默认格式，datetime() 统一后再排序
+	rows, err := tx.Query(`
+		SELECT i.id, COALESCE(i.image_paths, '')
+		FROM items i
+		WHERE i.source_id = ?
+		  AND i.id NOT IN (
+			SELECT id FROM items WHERE source_id = ?
+			ORDER BY datetime(COALESCE(published_at, created_at)) DESC, id DESC
+			LIMIT ?
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM user_deliveries ud
+			WHERE ud.item_id = i.id
+			  AND (ud.status = 0 OR COALESCE(ud.is_favorite, 0) = 1
+			       OR (ud.read_progress > 0 AND ud.read_progress < 100))
+		  )
+	`, sourceID, sourceID, max)
+	if err != nil {
+		return 0, nil, err
+	}
+	var itemIDs []int64
+	var imagePaths []string
+	for rows.Next() {
+		var id int64
+		var paths string
+		if err := rows.Scan(&id, &paths); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		itemIDs = append(itemIDs, id)
+		if paths != "" && paths != "[]" {
+			imagePaths = append(imagePaths, paths)
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil || len(itemIDs) == 0 {
+		return 0, nil, err
+	}
+
+	now := time.Now()
+	for _, id := range itemIDs {
+		// 删除的文章可能仍在 feed 中（feed 条目比 max 多），记录墓碑避免下次抓取时重新入库
+		if _, err := tx.Exec(insertTombstoneSQL, now, now, id); err != nil {
+			return 0, nil, err
+		}
+		if _, err := tx.Exec("DELETE FROM user_deliveries WHERE item_id = ?", id); err != nil {
+			return 0, nil, err
+		}
+		if _, err := tx.Exec("DELETE FROM items WHERE id = ?", id); err != nil {
+			return 0, nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return int64(len(itemIDs)), imagePaths, nil
 }
 
 // UserDelivery 相关操作
 
-// CreateUserDelivery 创建用户投递记录
+// CreateUserDelivery 创建用户投递记录（如有同内容的阅读状态则一并恢复）
 func (db *DB) CreateUserDelivery(userID, itemID int64) error {
-	_, err := db.Exec(
-		"INSERT OR IGNORE INTO user_deliveries (user_id, item_id, status) VALUES (?, ?, 0)",
-		userID, itemID,
-	)
+	_, err := db.Exec(insertDeliverySQL, userID, userID, itemID)
 	return err
 }
 
@@ -117,14 +350,14 @@ func (db *DB) BatchCreateUserDeliveries(itemID int64, userIDs []int64) error {
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare("INSERT OR IGNORE INTO user_deliveries (user_id, item_id, status) VALUES (?, ?, 0)")
+	stmt, err := tx.Prepare(insertDeliverySQL)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, userID := range userIDs {
-		if _, err := stmt.Exec(userID, itemID); err != nil {
+		if _, err := stmt.Exec(userID, userID, itemID); err != nil {
 			return err
 		}
 	}
@@ -132,65 +365,108 @@ func (db *DB) BatchCreateUserDeliveries(itemID int64, userIDs []int64) error {
 	return tx.Commit()
 }
 
+// BackfillDeliveries 为新订阅用户补发源中最近的 limit 篇文章（只含 retentionSeconds 内入库的文章）
+// 已有投递记录的文章保持不变，重复订阅不会重复投递；返回新建的投递数
+func (db *DB) BackfillDeliveries(userID, sourceID int64, limit, retentionSeconds int) (int64, error) {
+	result, err := db.Exec(`
+		INSERT OR IGNORE INTO user_deliveries (user_id, item_id, status, read_progress, read_at, published_at)
+		SELECT ?, i.id,
+		       CASE WHEN COALESCE(rs.is_read, 0) = 1 THEN 2 ELSE 0 END,
+		       COALESCE(rs.read_progress, 0), rs.read_at, i.published_at
+		FROM (
+			SELECT id, content_hash, published_at FROM items
+			WHERE source_id = ? AND created_at >= datetime('now', ?)
+			ORDER BY published_at DESC, id DESC
+			LIMIT ?
+		) i
+		LEFT JOIN read_state rs ON rs.user_id = ? AND rs.content_hash = i.content_hash
+	`, userID, sourceID, fmt.Sprintf("-%d seconds", retentionSeconds), limit, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// pendingItemColumns 待投递文章查询的列，与 scanPendingItem 对应
+const pendingItemColumns = `
+	i.id, i.source_id, i.guid, i.title, i.xml_content,
+	COALESCE(i.image_paths, ''), i.published_at, i.created_at,
+	COALESCE(i.clean_content, ''), COALESCE(i.content, ''),
+	COALESCE(i.cover_image, ''), COALESCE(i.summary, ''),
+	s.title, s.url,
+	COALESCE(i.image_primary_color, ''),
+	COALESCE(i.itunes_duration, ''), COALESCE(i.itunes_image, ''),
+	COALESCE(i.itunes_episode, ''), COALESCE(i.itunes_author, '')`
+
+// scanPendingItem 扫描一行待投递文章
+func scanPendingItem(rows *sql.Rows) (*Item, error) {
+	item := &Item{}
+	err := rows.Scan(
+		&item.ID, &item.SourceID, &item.GUID, &item.Title,
+		&item.XMLContent, &item.ImagePaths, &item.PublishedAt, &item.CreatedAt,
+		&item.CleanContent, &item.Content,
+		&item.CoverImage, &item.Summary,
+		&item.SourceTitle, &item.SourceURL,
+		&item.ImagePrimaryColor,
+		&item.ITunesDuration, &item.ITunesImage, &item.ITunesEpisode, &item.ITunesAuthor,
+	)
+	return item, err
+}
+
 // GetPendingDeliveries 获取用户待投递的文章
 func (db *DB) GetPendingDeliveries(userID int64, limit int) ([]*Item, error) {
-	rows, err := db.Query(`
-		SELECT i.id, i.source_id, i.guid, i.title, i.xml_content, 
-		       COALESCE(i.image_paths, ''), i.published_at, i.created_at,
-		       COALESCE(i.clean_content, ''), COALESCE(i.content, ''),
-		       COALESCE(i.cover_image, ''), COALESCE(i.summary, ''),
-		       s.title, s.url,
-		       COALESCE(i.image_primary_color, '')
-		FROM user_deliveries ud
-		INNER JOIN items i ON ud.item_id = i.id
-		INNER JOIN sources s ON i.source_id = s.id
-		WHERE ud.user_id = ? AND ud.status = 0
-		ORDER BY i.published_at DESC
-		LIMIT ?
-	`, userID, limit)
+	return db.getPendingDeliveries(userID, "", nil, limit)
+}
 
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// GetPendingDeliveriesBySourceURL 根据源URL获取用户待投递的文章
+func (db *DB) GetPendingDeliveriesBySourceURL(userID int64, sourceURL string, limit int) ([]*Item, error) {
+	return db.getPendingDeliveries(userID, sourceURL, nil, limit)
+}
 
-	items := make([]*Item, 0)
-	for rows.Next() {
-		item := &Item{}
-		err := rows.Scan(
-			&item.ID, &item.SourceID, &item.GUID, &item.Title,
-			&item.XMLContent, &item.ImagePaths, &item.PublishedAt, &item.CreatedAt,
-			&item.CleanContent, &item.Content,
-			&item.CoverImage, &item.Summary,
-			&item.SourceTitle, &item.SourceURL,
-			&item.ImagePrimaryColor,
-		)
+// EachPendingDelivery 按发布时间倒序逐篇处理用户待投递的文章（最多 limit 篇，sourceURL 非空时只取该源）
+// 每次只读取 pageSize 篇，读完一页即释放连接再调用 fn：内存占用与文章总数无关，
+// 数据库只有一个连接，也不会在 fn 写响应期间一直占用。fn 返回错误时停止
+func (db *DB) EachPendingDelivery(userID int64, sourceURL string, limit, pageSize int, fn func(*Item) error) error {
+	var after *Item
+	for remaining := limit; remaining > 0; {
+		page, err := db.getPendingDeliveries(userID, sourceURL, after, min(pageSize, remaining))
 		if err != nil {
-			return items, err
+			return err
 		}
-		items = append(items, item)
+		for _, item := range page {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(page) < min(pageSize, remaining) {
+			return nil
+		}
+		remaining -= len(page)
+		after = page[len(page)-1]
 	}
-
-	return items, rows.Err()
+	return nil
 }
 
-// GetPendingDeliveriesBySourceURL 根据源URL获取用户待投递的文章
-func (db *DB) GetPendingDeliveriesBySourceURL(userID int64, sourceURL string, limit int) ([]*Item, error) {
-	rows, err := db.Query(`
-		SELECT i.id, i.source_id, i.guid, i.title, i.xml_content, 
-		       COALESCE(i.image_paths, ''), i.published_at, i.created_at,
-		       COALESCE(i.clean_content, ''), COALESCE(i.content, ''),
-		       COALESCE(i.cover_image, ''), COALESCE(i.summary, ''),
-		       s.title, s.url,
-		       COALESCE(i.image_primary_color, '')
+// getPendingDeliveries 查询用户待投递的文章，after 不为空时从该文章之后（按发布时间、文章 ID 倒序）开始
+func (db *DB) getPendingDeliveries(userID int64, sourceURL string, after *Item, limit int) ([]*Item, error) {
+	query := `SELECT ` + pendingItemColumns + `
 		FROM user_deliveries ud
 		INNER JOIN items i ON ud.item_id = i.id
 		INNER JOIN sources s ON i.source_id = s.id
-		WHERE ud.user_id = ? AND ud.status = 0 AND s.url = ?
-		ORDER BY i.published_at DESC
-		LIMIT ?
-	`, userID, sourceURL, limit)
+		WHERE ud.user_id = ? AND ud.status = 0`
+	args := []interface{}{userID}
+	if sourceURL != "" {
+		query += " AND s.url = ?"
+		args = append(args, sourceURL)
+	}
+	if after != nil {
+		query += " AND (ud.published_at, ud.item_id) < (?, ?)"
+		args = append(args, after.PublishedAt, after.ID)
+	}
+	query += " ORDER BY ud.published_at DESC, ud.item_id DESC LIMIT ?"
+	args = append(args, limit)
 
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return make([]*Item, 0), err
 	}
@@ -198,21 +474,12 @@ func (db *DB) GetPendingDeliveriesBySourceURL(userID int64, sourceURL string, li
 
 	items := make([]*Item, 0)
 	for rows.Next() {
-		item := &Item{}
-		err := rows.Scan(
-			&item.ID, &item.SourceID, &item.GUID, &item.Title,
-			&item.XMLContent, &item.ImagePaths, &item.PublishedAt, &item.CreatedAt,
-			&item.CleanContent, &item.Content,
-			&item.CoverImage, &item.Summary,
-			&item.SourceTitle, &item.SourceURL,
-			&item.ImagePrimaryColor,
-		)
+		item, err := scanPendingItem(rows)
 		if err != nil {
 			return items, err
 		}
 		items = append(items, item)
 	}
-
 	return items, rows.Err()
 }
 
@@ -248,23 +515,20 @@ func (db *DB) BatchUpdateDeliveryStatus(userID int64, itemIDs []int64, status in
 	return tx.Commit()
 }
 
-// GetDeliveryStats 获取文章的投递统计
-func (db *DB) GetDeliveryStats(itemID int64) (total, acked int, err error) {
+// GetDeliveryStats 获取文章的投递统计：投递总数、已确认发送数，以及被收藏或阅读中（进度 1-99）需要保留的投递数
+func (db *DB) GetDeliveryStats(itemID int64) (total, acked, kept int, err error) {
 	err = db.QueryRow(`
-		SELECT COUNT(*) as total, 
-		       SUM(CASE WHEN status = 1 THEN 1 ELSE 0 END) as acked
+		SELECT COUNT(*) as total,
+		       COALESCE(SUM(CASE WHEN status = 1 THEN 1 ELSE 0 END), 0) as acked,
+		       COALESCE(SUM(CASE WHEN COALESCE(is_favorite, 0) = 1
+		                           OR (read_progress > 0 AND read_progress < 100)
+		                         THEN 1 ELSE 0 END), 0) as kept
 		FROM user_deliveries
 		WHERE item_id = ?
-	`, itemID).Scan(&total, &acked)
+	`, itemID).Scan(&total, &acked, &kept)
 	return
 }
 
-// DeleteUserDeliveries 删除文章的所有投递记录
-func (db *DB) DeleteUserDeliveries(itemID int64) error {
-	_, err := db.Exec("DELETE FROM user_deliveries WHERE item_id = ?", itemID)
-	return err
-}
-
 // GetUnreadCount 获取用户在某个源的未读文章数
 func (db *DB) GetUnreadCount(userID, sourceID int64) (int, error) {
 	var count int
@@ -277,6 +541,75 @@ func (db *DB) GetUnreadCount(userID, sourceID int64) (int, error) {
 	return count, err
 }
 
+// GetTotalUnreadCount 获取用户全部未读文章数，只走 idx_deliveries_user_status 索引，不关联 items，供角标轮询
+func (db *DB) GetTotalUnreadCount(userID int64) (int64, error) {
+	var count int64
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM user_deliveries WHERE user_id = ? AND status = 0",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+// GetSourceDeliveryWatermark 返回用户在某个源已投递文章的最大 ID（没有投递时为 0）
+// 文章 ID 自增，之后新投递的文章 ID 都大于该值，配合 CountSourceDeliveriesAfter 统计一次抓取新增的投递
+func (db *DB) GetSourceDeliveryWatermark(userID, sourceID int64) (int64, error) {
+	var watermark int64
+	err := db.QueryRow(`
+		SELECT COALESCE(MAX(ud.item_id), 0)
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		WHERE ud.user_id = ? AND i.source_id = ?
+	`, userID, sourceID).Scan(&watermark)
+	return watermark, err
+}
+
+// CountSourceDeliveriesAfter 统计用户在某个源文章 ID 大于 afterItemID 的投递数
+func (db *DB) CountSourceDeliveriesAfter(userID, sourceID, afterItemID int64) (int, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*)
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		WHERE ud.user_id = ? AND i.source_id = ? AND ud.item_id > ?
+	`, userID, sourceID, afterItemID).Scan(&count)
+	return count, err
+}
+
+// GetUserSourceCounts 一次分组查询获取用户每个订阅源的未读数和总数
+// 没有投递记录的订阅源也会返回（计数为 0），结果按 source_id 排序
+func (db *DB) GetUserSourceCounts(userID int64) ([]*SourceCount, error) {
+	rows, err := db.Query(`
+		SELECT sub.source_id, COALESCE(c.unread, 0), COALESCE(c.total, 0)
+		FROM subscriptions sub
+		LEFT JOIN (
+			SELECT i.source_id,
+			       SUM(CASE WHEN ud.status = 0 THEN 1 ELSE 0 END) AS unread,
+			       COUNT(*) AS total
+			FROM user_deliveries ud
+			INNER JOIN items i ON ud.item_id = i.id
+			WHERE ud.user_id = ?
+			GROUP BY i.source_id
+		) c ON c.source_id = sub.source_id
+		WHERE sub.user_id = ?
+		ORDER BY sub.source_id
+	`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]*SourceCount, 0)
+	for rows.Next() {
+		sc := &SourceCount{}
+		if err := rows.Scan(&sc.SourceID, &sc.Unread, &sc.Total); err != nil {
+			return nil, err
+		}
+		counts = append(counts, sc)
+	}
+	return counts, rows.Err()
+}
+
 // GetItemsBySource 获取某个订阅源下的所有文章
 func (db *DB) GetItemsBySource(sourceID int64) ([]*Item, error) {
 	rows, err := db.Query(`
@@ -313,12 +646,79 @@ func (db *DB) GetItemsBySource(sourceID int64) ([]*Item, error) {
 	return items, rows.Err()
 }
 
+// userArticleColumns UserArticle 查询使用的列（需配合 ud / i / s 别名）
+const userArticleColumns = `
+		i.id, i.source_id, i.guid, i.title, i.xml_content,
+		COALESCE(i.image_paths, ''), i.published_at, i.created_at,
+		s.title, s.url, ud.status,
+		COALESCE(i.summary, ''), COALESCE(i.word_count, 0), COALESCE(i.reading_time, 0),
+		COALESCE(i.cover_image, ''), COALESCE(i.author, ''),
+		COALESCE(i.clean_content, ''), COALESCE(i.content, ''), COALESCE(i.content_hash, ''),
+		COALESCE(i.image_caption, ''), COALESCE(i.image_credit, ''), COALESCE(i.image_primary_color, ''),
+		COALESCE(i.tags, ''), COALESCE(i.category, ''), COALESCE(i.is_truncated, 0),
+		COALESCE(i.image_blurhash, ''), COALESCE(i.cover_variants, ''),
+		COALESCE(ud.is_favorite, 0), COALESCE(ud.read_progress, 0),
+		ud.read_at, ud.updated_at, ud.delivered_at`
+
+// scanUserArticle 扫描一行 userArticleColumns，extra 为追加在末尾的列
+func scanUserArticle(rows rowScanner, extra ...interface{}) (*UserArticle, error) {
+	ua := &UserArticle{}
+	// COALESCE 会丢失列的时间类型，分别扫描后再回退
+	var updatedAt *time.Time
+	dest := []interface{}{
+		&ua.ID, &ua.SourceID, &ua.GUID, &ua.Title,
+		&ua.XMLContent, &ua.ImagePaths, &ua.PublishedAt, &ua.CreatedAt,
+		&ua.SourceTitle, &ua.SourceURL, &ua.Status,
+		&ua.Summary, &ua.WordCount, &ua.ReadingTime,
+		&ua.CoverImage, &ua.Author, &ua.CleanContent, &ua.Content, &ua.ContentHash,
+		&ua.ImageCaption, &ua.ImageCredit, &ua.ImagePrimaryColor,
+		&ua.Tags, &ua.Category, &ua.Truncated,
+		&ua.ImageBlurhash, &ua.CoverVariants,
+		&ua.IsFavorite, &ua.ReadProgress, &ua.ReadAt, &updatedAt, &ua.UpdatedAt,
+	}
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if updatedAt != nil {
+		ua.UpdatedAt = *updatedAt
+	}
+	return ua, nil
+}
+
+// GetRecentItemTexts 获取某个源最近文章的标题和正文（用于关键词语料统计）
+func (db *DB) GetRecentItemTexts(sourceID int64, limit int) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT title || ' ' || COALESCE(NULLIF(clean_content, ''), COALESCE(summary, ''))
+		FROM items
+		WHERE source_id = ?
+		ORDER BY created_at DESC
+		LIMIT ?
+	`, sourceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var texts []string
+	for rows.Next() {
+		var text string
+		if err := rows.Scan(&text); err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+	return texts, rows.Err()
+}
+
 // GetUserArticles 获取用户文章列表（包含源信息与投递状态，支持增量同步、游标分页和按源筛选）
 // 参数：
 //   - userID: 用户 ID
 //   - sourceID: 可选，订阅源 ID 过滤
-//   - sinceTime: 可选，返回该时间之后发布的文章（增量同步）
-//   - cursor: 可选，游标字符串 "timestamp_itemID"（历史翻页）
+//   - tag: 可选，标签过滤
+//   - category: 可选，分类过滤
+//   - sinceTime: 可选，返回该时间之后发布的文章（增量同步）；sort 为 updated 时改为返回该时间之后新投递或有变化（updated_at）的文章，可与 cursor 同时使用
+//   - cursor: 可选，上一页返回的游标（历史翻页），必须由同一排序方式生成，否则忽略
+//   - sort: 排序方式，空值按 newest 处理
 //   - limit: 返回数量限制
 //   - offset: 偏移量（当 sinceTime 和 cursor 都为空时使用）
 //
@@ -329,8 +729,12 @@ func (db *DB) GetItemsBySource(sourceID int64) ([]*Item, error) {
 func (db *DB) GetUserArticles(
 	userID int64,
 	sourceID *int64,
+	groupID *int64,
+	tag *string,
+	category *string,
 	sinceTime *time.Time,
 	cursor *string,
+	sort ArticleSort,
 	limit, offset int,
 ) (articles []*UserArticle, nextCursor *string, err error) {
 	if limit <= 0 {
@@ -342,20 +746,76 @@ func (db *DB) GetUserArticles(
 	if offset < 0 {
 		offset = 0
 	}
+	if sort == "" {
+		sort = SortNewest
+	}
 
 	// 多获取一条，用于判断是否有更多数据
 	queryLimit := limit + 1
 
-	query := `
-		SELECT i.id, i.source_id, i.guid, i.title, i.xml_content,
-		       COALESCE(i.image_paths, ''), i.published_at, i.created_at,
-		       s.title, s.url, ud.status,
-		       COALESCE(i.summary, ''), COALESCE(i.word_count, 0), COALESCE(i.reading_time, 0),
-		       COALESCE(i.cover_image, ''), COALESCE(i.author, ''),
-		       COALESCE(i.clean_content, ''), COALESCE(i.content, ''), COALESCE(i.content_hash, ''),
-		       COALESCE(i.image_caption, ''), COALESCE(i.image_credit, ''), COALESCE(i.image_primary_color, ''),
-		       COALESCE(ud.is_favorite, 0), COALESCE(ud.read_progress, 0),
-		       ud.read_at, COALESCE(ud.updated_at, ud.delivered_at)
+	query, args := buildUserArticlesQuery(userID, sourceID, groupID, tag, category, sinceTime, cursor, sort, queryLimit, offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var result []*UserArticle
+	var keys []string // 每行排序列的原始值，用于生成游标
+	for rows.Next() {
+		var key string
+		ua, err := scanUserArticle(rows, &key)
+		if err != nil {
+			return nil, nil, err
+		}
+		result = append(result, ua)
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// 判断是否有更多数据
+	hasMore := len(result) > limit
+	if hasMore {
+		// 移除多余的最后一条
+		result = result[:limit]
+		// 生成 nextCursor（基于最后一条记录）
+		last := result[len(result)-1]
+		var cursorStr string
+		if sort == SortNewest {
+			// 沿用 "timestamp_itemID" 格式，兼容旧客户端
+			cursorStr = utils.SimpleCursorEncode(last.PublishedAt.Unix(), last.ID)
+		} else {
+			cursorStr = articleCursor{Sort: sort, Read: last.Status == 2, Key: keys[limit-1], ID: last.ID}.encode()
+		}
+		nextCursor = &cursorStr
+	}
+
+	return result, nextCursor, nil
+}
+
+// buildUserArticlesQuery 构建 GetUserArticles 的查询语句和参数，queryLimit 已包含多取的一条
+// 末尾追加一列排序列的原始文本，供生成游标使用
+func buildUserArticlesQuery(
+	userID int64,
+	sourceID *int64,
+	groupID *int64,
+	tag *string,
+	category *string,
+	sinceTime *time.Time,
+	cursor *string,
+	sort ArticleSort,
+	queryLimit, offset int,
+) (string, []interface{}) {
+	spec, ok := articleSortSpecs[sort]
+	if !ok {
+		sort, spec = SortNewest, articleSortSpecs[SortNewest]
+	}
+
+	query := `SELECT ` + userArticleColumns + `, COALESCE(CAST(` + spec.keyColumn + ` AS TEXT), '')
 		FROM user_deliveries ud
 		INNER JOIN items i ON ud.item_id = i.id
 		INNER JOIN sources s ON i.source_id = s.id
@@ -370,83 +830,292 @@ func (db *DB) GetUserArticles(
 		args = append(args, *sourceID)
 	}
 
+	// 按分组过滤：只保留该用户订阅在此分组下的源（与 source_id 同时指定时，源不在分组内则结果为空）
+	if groupID != nil {
+		query += " AND i.source_id IN (SELECT source_id FROM subscriptions WHERE user_id = ? AND group_id = ?)"
+		args = append(args, userID, *groupID)
+	}
+
+	// 按标签过滤（tags 为 JSON 数组）
+	if tag != nil && *tag != "" {
+		query += " AND EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(i.tags) THEN i.tags ELSE '[]' END) WHERE json_each.value = ?)"
+		args = append(args, *tag)
+	}
+
+	// 按分类过滤（分类入库时已规范化）
+	if category != nil && *category != "" {
+		query += " AND i.category = ?"
+		args = append(args, *category)
+	}
+
+	// 按更新时间增量同步：ud.updated_at 在投递、内容更新和阅读、收藏等状态变化时刷新，
+	// 结果按同一列排序，翻页时与游标条件同时生效
+	if sinceTime != nil && sort == SortUpdated {
+		query += " AND ud.updated_at > ?"
+		args = append(args, sinceTime.UTC())
+	}
+
 	// 增量同步模式：since 优先
-	if sinceTime != nil {
-		query += " AND i.published_at > ?"
+	if sinceTime != nil && sort != SortUpdated {
+		query += " AND ud.published_at > ?"
 		args = append(args, *sinceTime)
 	} else if cursor != nil && *cursor != "" {
-		// 游标分页模式：解析 cursor
-		cursorData, err := utils.DecodeCursor(*cursor)
-		if err == nil {
-			cursorTime := cursorData.GetTime()
-			// 使用复合条件：(published_at, id) < (cursorTime, cursorID)
-			query += " AND (i.published_at < ? OR (i.published_at = ? AND i.id < ?))"
-			args = append(args, cursorTime, cursorTime, cursorData.ID)
+		// 游标分页模式：行值比较可直接定位到索引中的游标位置，
+		// 展开成 OR 条件时 SQLite 只能从该用户的第一条投递开始扫描
+		if sort == SortNewest {
+			cursorData, err := utils.DecodeCursor(*cursor)
+			if err == nil {
+				query += " AND " + spec.cursorCond
+				args = append(args, cursorData.GetTime(), cursorData.ID)
+			}
+		} else if cursorData, err := decodeArticleCursor(*cursor, sort); err == nil {
+			cond := spec.cursorCond
+			if sort == SortUnread {
+				cond = unreadCursorCondUnread
+				if cursorData.Read {
+					// 之后只剩已读文章，去掉恒定的 (status = 2) 排序项，SQLite 才能按索引顺序读取而不做临时排序
+					cond = unreadCursorCondRead
+					spec.orderBy = "ud.published_at DESC, ud.item_id DESC"
+				}
+			}
+			query += " AND " + cond
+			args = append(args, cursorData.Key, cursorData.ID)
 		}
 		// cursor 解析失败则忽略，按默认逻辑查询
 	}
 
-	// 排序和限制
+	// 排序和限制（按投递表上的列排序，ORDER BY 与索引顺序一致，无需临时排序）
+	query += `
+		ORDER BY ` + spec.orderBy
 	if sinceTime != nil || cursor != nil {
 		// 增量或游标模式：不使用 offset
 		query += `
-			ORDER BY i.published_at DESC, i.id DESC
 			LIMIT ?
 		`
 		args = append(args, queryLimit)
 	} else {
 		// 默认模式：使用 offset
 		query += `
-			ORDER BY i.published_at DESC, i.id DESC
 			LIMIT ? OFFSET ?
 		`
 		args = append(args, queryLimit, offset)
 	}
 
-	rows, err := db.Query(query, args...)
+	return query, args
+}
+
+// GetUserArticle 获取投递给用户的单篇文章，用户没有该文章的投递时返回 sql.ErrNoRows
+func (db *DB) GetUserArticle(userID, itemID int64) (*UserArticle, error) {
+	row := db.QueryRow(`SELECT `+userArticleColumns+`
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		INNER JOIN sources s ON i.source_id = s.id
+		WHERE ud.user_id = ? AND ud.item_id = ?
+	`, userID, itemID)
+	return scanUserArticle(row)
+}
+
+// GetDeliveredItemIDs 返回 itemIDs 中已投递给该用户的文章 ID（调用方负责限制 itemIDs 数量）
+func (db *DB) GetDeliveredItemIDs(userID int64, itemIDs []int64) (map[int64]bool, error) {
+	delivered := make(map[int64]bool)
+	if len(itemIDs) == 0 {
+		return delivered, nil
+	}
+
+	args := make([]interface{}, 0, len(itemIDs)+1)
+	args = append(args, userID)
+	for _, id := range itemIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(itemIDs)), ",")
+	rows, err := db.Query(
+		"SELECT item_id FROM user_deliveries WHERE user_id = ? AND item_id IN ("+placeholders+")",
+		args...,
+	)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	defer rows.Close()
 
-	var result []*UserArticle
 	for rows.Next() {
-		ua := &UserArticle{}
-		if err := rows.Scan(
-			&ua.ID, &ua.SourceID, &ua.GUID, &ua.Title,
-			&ua.XMLContent, &ua.ImagePaths, &ua.PublishedAt, &ua.CreatedAt,
-			&ua.SourceTitle, &ua.SourceURL, &ua.Status,
-			&ua.Summary, &ua.WordCount, &ua.ReadingTime,
-			&ua.CoverImage, &ua.Author, &ua.CleanContent, &ua.Content, &ua.ContentHash,
-			&ua.ImageCaption, &ua.ImageCredit, &ua.ImagePrimaryColor,
-			&ua.IsFavorite, &ua.ReadProgress, &ua.ReadAt, &ua.UpdatedAt,
-		); err != nil {
-			return nil, nil, err
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		delivered[id] = true
+	}
+	return delivered, rows.Err()
+}
+
+// GetContinueReadingArticles 获取用户读到一半的文章（0 < read_progress < 100），按最近更新时间倒序
+// 仅收藏但未开始阅读的文章 read_progress 为 0，不会出现在结果中
+func (db *DB) GetContinueReadingArticles(userID int64, limit int) ([]*UserArticle, error) {
+	rows, err := db.Query(`SELECT `+userArticleColumns+`
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		INNER JOIN sources s ON i.source_id = s.id
+		WHERE ud.user_id = ? AND ud.read_progress > 0 AND ud.read_progress < 100
+		ORDER BY ud.updated_at DESC, ud.item_id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []*UserArticle{}
+	for rows.Next() {
+		ua, err := scanUserArticle(rows)
+		if err != nil {
+			return nil, err
 		}
 		result = append(result, ua)
 	}
+	return result, rows.Err()
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, nil, err
+// GetRelatedUserArticles 查找与给定关键词有标签交集的用户文章（按交集数量和发布时间排序）
+// 只在 since 之后发布的文章中查找，并排除 itemID 本身
+func (db *DB) GetRelatedUserArticles(userID, itemID int64, terms []string, since time.Time, limit int) ([]*RelatedArticle, error) {
+	if len(terms) == 0 {
+		return []*RelatedArticle{}, nil
 	}
 
-	// 判断是否有更多数据
-	hasMore := len(result) > limit
-	if hasMore {
-		// 移除多余的最后一条
-		result = result[:limit]
-		// 生成 nextCursor（基于最后一条记录）
-		last := result[len(result)-1]
-		cursorStr := utils.SimpleCursorEncode(last.PublishedAt.Unix(), last.ID)
-		nextCursor = &cursorStr
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(terms)), ",")
+	query := `SELECT ` + userArticleColumns + `, COUNT(*) AS overlap
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		INNER JOIN sources s ON i.source_id = s.id,
+		     json_each(CASE WHEN json_valid(i.tags) THEN i.tags ELSE '[]' END) t
+		WHERE ud.user_id = ? AND i.id != ? AND i.published_at >= ?
+		  AND t.value IN (` + placeholders + `)
+		GROUP BY i.id
+		ORDER BY overlap DESC, i.published_at DESC
+		LIMIT ?
+	`
+
+	args := []interface{}{userID, itemID, since}
+	for _, term := range terms {
+		args = append(args, term)
 	}
+	args = append(args, limit)
 
-	return result, nextCursor, nil
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []*RelatedArticle{}
+	for rows.Next() {
+		var overlap int
+		ua, err := scanUserArticle(rows, &overlap)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &RelatedArticle{Article: ua, Overlap: overlap})
+	}
+	return result, rows.Err()
+}
+
+// GetRecentUserItemTitles 返回用户 since 之后发布的文章标题（按发布时间倒序，排除 itemID 本身）
+// 只读取 ID 和标题，供按标题相似度查找没有共同标签的相关文章
+func (db *DB) GetRecentUserItemTitles(userID, itemID int64, since time.Time, limit int) ([]*ItemTitle, error) {
+	rows, err := db.Query(`
+		SELECT i.id, i.title
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		WHERE ud.user_id = ? AND ud.published_at >= ? AND i.id != ?
+		ORDER BY ud.published_at DESC, ud.item_id DESC
+		LIMIT ?
+	`, userID, since, itemID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []*ItemTitle{}
+	for rows.Next() {
+		t := &ItemTitle{}
+		if err := rows.Scan(&t.ID, &t.Title); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// GetUserTagFacets 统计用户文章中各标签出现次数（按次数降序）
+func (db *DB) GetUserTagFacets(userID int64, sourceID *int64, limit int) ([]*TagFacet, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	query := `
+		SELECT t.value, COUNT(*) AS cnt
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id,
+		     json_each(CASE WHEN json_valid(i.tags) THEN i.tags ELSE '[]' END) t
+		WHERE ud.user_id = ?
+	`
+	args := []interface{}{userID}
+	if sourceID != nil {
+		query += " AND i.source_id = ?"
+		args = append(args, *sourceID)
+	}
+	query += " GROUP BY t.value ORDER BY cnt DESC, t.value ASC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []*TagFacet{}
+	for rows.Next() {
+		f := &TagFacet{}
+		if err := rows.Scan(&f.Tag, &f.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, f)
+	}
+	return facets, rows.Err()
+}
+
+// GetUserCategoryFacets 统计用户文章的分类及每个分类的文章数和未读数（按文章数降序）
+func (db *DB) GetUserCategoryFacets(userID int64) ([]*CategoryFacet, error) {
+	rows, err := db.Query(`
+		SELECT i.category, COUNT(*) AS cnt,
+		       SUM(CASE WHEN ud.status = 0 THEN 1 ELSE 0 END)
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		WHERE ud.user_id = ? AND i.category IS NOT NULL AND i.category != ''
+		GROUP BY i.category
+		ORDER BY cnt DESC, i.category ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []*CategoryFacet{}
+	for rows.Next() {
+		f := &CategoryFacet{}
+		if err := rows.Scan(&f.Category, &f.Count, &f.Unread); err != nil {
+			return nil, err
+		}
+		facets = append(facets, f)
+	}
+	return facets, rows.Err()
 }
 
 // Vocabulary 相关操作
 
 // UpsertVocabulary 插入或更新生词
+// 合并规则：已有同 ID 词条时，updated_at 更新的写入覆盖服务端；updated_at 更旧的写入忽略；
+// updated_at 相同（秒级时间戳，同一秒内的多次编辑）但内容不同时也接受写入，避免同一秒内的编辑丢失，
+// 内容完全相同的重复上传不做修改
 func (db *DB) UpsertVocabulary(vocab *Vocabulary) error {
 	_, err := db.Exec(`
 		INSERT INTO vocabularies (
@@ -472,6 +1141,21 @@ func (db *DB) UpsertVocabulary(vocab *Vocabulary) error {
 			updated_at = excluded.updated_at,
 			is_deleted = excluded.is_deleted
 			WHERE excluded.updated_at > vocabularies.updated_at
+				OR (excluded.updated_at = vocabularies.updated_at AND (
+					excluded.definition IS NOT vocabularies.definition OR
+					excluded.translation IS NOT vocabularies.translation OR
+					excluded.example IS NOT vocabularies.example OR
+					excluded.context IS NOT vocabularies.context OR
+					excluded.review_count IS NOT vocabularies.review_count OR
+					excluded.correct_count IS NOT vocabularies.correct_count OR
+					excluded.last_review_at IS NOT vocabularies.last_review_at OR
+					excluded.next_review_at IS NOT vocabularies.next_review_at OR
+					excluded.mastery_level IS NOT vocabularies.mastery_level OR
+					excluded.difficulty IS NOT vocabularies.difficulty OR
+					excluded.tags IS NOT vocabularies.tags OR
+					excluded.notes IS NOT vocabularies.notes OR
+					excluded.is_deleted IS NOT vocabularies.is_deleted
+				))
 	`,
 		vocab.ID, vocab.UserID, vocab.Word, vocab.Definition, vocab.Translation, vocab.Example, vocab.Context,
 		vocab.SourceArticleID, vocab.SourceArticleTitle, vocab.ArticleID,
@@ -482,8 +1166,78 @@ func (db *DB) UpsertVocabulary(vocab *Vocabulary) error {
 	return err
 }
 
-// GetVocabulariesSince 获取指定时间后更新的生词
-func (db *DB) GetVocabulariesSince(userID int64, sinceTimestamp int64) ([]*Vocabulary, error) {
+// CountUserVocabulary 获取用户未删除的生词数
+func (db *DB) CountUserVocabulary(userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM vocabularies WHERE user_id = ? AND is_deleted = 0",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+// GetActiveVocabularyIDs 返回 ids 中属于该用户且未删除的词条 ID（调用方负责限制 ids 数量）
+func (db *DB) GetActiveVocabularyIDs(userID int64, ids []string) (map[string]bool, error) {
+	active := make(map[string]bool)
+	if len(ids) == 0 {
+		return active, nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+1)
+	args = append(args, userID)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	rows, err := db.Query(
+		"SELECT id FROM vocabularies WHERE user_id = ? AND is_deleted = 0 AND id IN ("+placeholders+")",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		active[id] = true
+	}
+	return active, rows.Err()
+}
+
+// GetUserVocabularyWords 返回用户未删除的全部单词（小写），用于导入时判重
+func (db *DB) GetUserVocabularyWords(userID int64) (map[string]bool, error) {
+	rows, err := db.Query("SELECT word FROM vocabularies WHERE user_id = ? AND is_deleted = 0", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	words := make(map[string]bool)
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, err
+		}
+		words[strings.ToLower(word)] = true
+	}
+	return words, rows.Err()
+}
+
+// GetVocabulariesSince 按 (updated_at, id) 升序分页获取指定时间后更新的生词，最多返回 limit 条
+// afterID 非空时从上一页最后一条 (afterUpdatedAt, afterID) 之后继续；多查一条判断是否还有后续（hasMore）。
+// 翻页期间被更新的词条 updated_at 变大，会出现在后面的页中，不会因位置偏移而漏掉
+func (db *DB) GetVocabulariesSince(userID int64, sinceTimestamp int64, afterUpdatedAt int64, afterID string, limit int) ([]*Vocabulary, bool, error) {
+	where := "WHERE user_id = ? AND updated_at > ? AND is_deleted = 0"
+	args := []interface{}{userID, sinceTimestamp}
+	if afterID != "" {
+		where += " AND (updated_at, id) > (?, ?)"
+		args = append(args, afterUpdatedAt, afterID)
+	}
+
 	rows, err := db.Query(`
 		SELECT 
 			id, user_id, word, COALESCE(definition, ''), COALESCE(translation, ''),
@@ -493,13 +1247,12 @@ func (db *DB) GetVocabulariesSince(userID int64, sinceTimestamp int64) ([]*Vocab
 			COALESCE(last_review_at, 0), COALESCE(next_review_at, 0), COALESCE(mastery_level, 0),
 			COALESCE(difficulty, 'medium'), COALESCE(tags, ''), COALESCE(notes, ''),
 			COALESCE(added_at, 0), COALESCE(created_at, 0), COALESCE(updated_at, 0), is_deleted
-		FROM vocabularies
-		WHERE user_id = ? AND updated_at > ? AND is_deleted = 0
-		ORDER BY updated_at DESC
-	`, userID, sinceTimestamp)
-
+		FROM vocabularies `+where+`
+		ORDER BY updated_at, id
+		LIMIT ?
+	`, append(args, limit+1)...)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer rows.Close()
 
@@ -516,12 +1269,18 @@ func (db *DB) GetVocabulariesSince(userID int64, sinceTimestamp int64) ([]*Vocab
 			&vocab.AddedAt, &vocab.CreatedAt, &vocab.UpdatedAt, &vocab.IsDeleted,
 		)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		vocabs = append(vocabs, vocab)
 	}
 
-	return vocabs, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	if len(vocabs) > limit {
+		return vocabs[:limit], true, nil
+	}
+	return vocabs, false, nil
 }
 
 // GetVocabularyByID 根据ID获取生词
@@ -637,51 +1396,47 @@ func (db *DB) GetVocabulariesByUser(userID int64, limit int64, offset int64) ([]
 	return vocabs, rows.Err()
 }
 
-// GetDeliveredItems 获取所有已发送的文章列表
-func (db *DB) GetDeliveredItems() ([]int64, error) {
+// GetExpirableItems 获取可按保留策略清理的文章及其最近活动时间
+// 只返回所有投递都已发送（status=1）或已读（status=2）的文章；任一用户收藏或阅读中（进度 1-99）的文章不返回。
+// 已发送的投递按发送时间计算，已读的投递按阅读时间（没有时按更新时间）计算，避免只读不确认的客户端让文章无限累积
+func (db *DB) GetExpirableItems() ([]*ExpirableItem, error) {
+	// read_at / updated_at 由驱动写入带时区的时间，datetime() 统一转为 UTC 后再比较
 	rows, err := db.Query(`
-		SELECT DISTINCT item_id
-		FROM user_deliveries
-		WHERE status = 1
+		SELECT ud.item_id, i.source_id, COALESCE(s.retention_seconds, 0),
+		       COALESCE(MAX(CASE WHEN ud.status = 2
+		                         THEN datetime(COALESCE(ud.read_at, ud.updated_at, ud.delivered_at))
+		                         ELSE datetime(ud.delivered_at) END), '')
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		INNER JOIN sources s ON i.source_id = s.id
+		GROUP BY ud.item_id
+		HAVING SUM(CASE WHEN ud.status = 0
+		                  OR COALESCE(ud.is_favorite, 0) = 1
+		                  OR (ud.read_progress > 0 AND ud.read_progress < 100)
+		                THEN 1 ELSE 0 END) = 0
 	`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var itemIDs []int64
+	var items []*ExpirableItem
 	for rows.Next() {
-		var itemID int64
-		if err := rows.Scan(&itemID); err != nil {
+		item := &ExpirableItem{}
+		var lastActivityStr string
+		if err := rows.Scan(&item.ItemID, &item.SourceID, &item.RetentionSeconds, &lastActivityStr); err != nil {
 			return nil, err
 		}
-		itemIDs = append(itemIDs, itemID)
-	}
-	return itemIDs, rows.Err()
-}
-
-// GetItemDeliveredTime 获取文章最近的发送时间
-func (db *DB) GetItemDeliveredTime(itemID int64) (*time.Time, error) {
-	var deliveredAtStr sql.NullString
-	err := db.QueryRow(`
-		SELECT COALESCE(MAX(delivered_at), '')
-		FROM user_deliveries
-		WHERE item_id = ? AND status = 1
-	`, itemID).Scan(&deliveredAtStr)
-	if err != nil {
-		return nil, err
-	}
-
-	if !deliveredAtStr.Valid || deliveredAtStr.String == "" {
-		return nil, nil
-	}
-
-	// 解析时间字符串
-	deliveredAt, err := time.Parse("2006-01-02 15:04:05", deliveredAtStr.String)
-	if err != nil {
-		return nil, err
+		// 解析时间字符串（MAX() 的结果不带列类型，需手动解析）
+		lastActivityAt, err := time.Parse("2006-01-02 15:04:05", lastActivityStr)
+		if err != nil {
+			log.Printf("Skipping item %d with unparsable activity time %q", item.ItemID, lastActivityStr)
+			continue
+		}
+		item.LastActivityAt = lastActivityAt
+		items = append(items, item)
 	}
-	return &deliveredAt, nil
+	return items, rows.Err()
 }
 
 // Quest 5: 阅读状态管理函数
@@ -689,27 +1444,98 @@ func (db *DB) GetItemDeliveredTime(itemID int64) (*time.Time, error) {
 // MarkArticleAsRead 标记文章为已读
 func (db *DB) MarkArticleAsRead(userID, itemID int64) error {
 	now := time.Now()
-	_, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
 		UPDATE user_deliveries 
 		SET status = 2, 
 		    read_at = COALESCE(read_at, ?),
 		    updated_at = ?
 		WHERE user_id = ? AND item_id = ?
-	`, now, now, userID, itemID)
-	return err
+	`, now, now, userID, itemID); err != nil {
+		return err
+	}
+	if err := saveReadStatus(tx, userID, itemID, true, now); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // MarkArticleAsUnread 标记文章为未读
 func (db *DB) MarkArticleAsUnread(userID, itemID int64) error {
 	now := time.Now()
-	_, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
 		UPDATE user_deliveries 
 		SET status = 0, 
 		    read_at = NULL,
 		    updated_at = ?
 		WHERE user_id = ? AND item_id = ?
-	`, now, userID, itemID)
-	return err
+	`, now, userID, itemID); err != nil {
+		return err
+	}
+	if err := saveReadStatus(tx, userID, itemID, false, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MarkSourceReadBefore 将用户在某个源中发布时间不晚于 before 的未读文章全部标记为已读
+// 没有发布时间的文章按入库时间判断；返回被标记的文章数
+func (db *DB) MarkSourceReadBefore(userID, sourceID int64, before time.Time) (int64, error) {
+	now := time.Now()
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// 先按内容哈希同步 read_state，文章重新入库后仍保持已读
+	if _, err := tx.Exec(`
+		INSERT INTO read_state (user_id, content_hash, is_read, read_at, updated_at)
+		SELECT ud.user_id, i.content_hash, 1, ?, ?
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		WHERE ud.user_id = ? AND i.source_id = ? AND ud.status = 0
+		  AND COALESCE(i.published_at, i.created_at) <= ?
+		  AND COALESCE(i.content_hash, '') != ''
+		ON CONFLICT(user_id, content_hash) DO UPDATE SET
+			is_read = 1,
+			read_at = COALESCE(read_state.read_at, excluded.read_at),
+			updated_at = excluded.updated_at
+	`, now, now, userID, sourceID, before); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE user_deliveries
+		SET status = 2,
+		    read_at = COALESCE(read_at, ?),
+		    updated_at = ?
+		WHERE user_id = ? AND status = 0
+		  AND item_id IN (
+			SELECT id FROM items
+			WHERE source_id = ? AND COALESCE(published_at, created_at) <= ?
+		  )
+	`, now, now, userID, sourceID, before)
+	if err != nil {
+		return 0, err
+	}
+	marked, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return marked, tx.Commit()
 }
 
 // ToggleFavorite 切换文章收藏状态
@@ -765,11 +1591,22 @@ func (db *DB) UpdateReadProgress(userID, itemID int64, progress int) error {
 	}
 
 	now := time.Now()
-	_, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
 		UPDATE user_deliveries 
 		SET read_progress = ?,
 		    updated_at = ?
 		WHERE user_id = ? AND item_id = ?
-	`, progress, now, userID, itemID)
-	return err
+	`, progress, now, userID, itemID); err != nil {
+		return err
+	}
+	if err := saveReadProgress(tx, userID, itemID, progress, now); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
@@ -0,0 +1,576 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// seedUserArticles 为两个用户各投递 count 篇文章
+func seedUserArticles(t testing.TB, database *DB, count int) (*User, *Source) {
+	t.Helper()
+	user := createTestUser(t, database, "reader")
+	other := createTestUser(t, database, "other")
+	source := createTestSource(t, database, "https://example.com/feed")
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < count; i++ {
+		guid := fmt.Sprintf("item-%d", i)
+		item := createTestItem(t, database, source.ID, guid, "hash-"+guid, base.Add(time.Duration(i)*time.Minute))
+		for _, u := range []*User{user, other} {
+			if err := database.CreateUserDelivery(u.ID, item.ID); err != nil {
+				t.Fatalf("CreateUserDelivery: %v", err)
+			}
+		}
+	}
+	return user, source
+}
+
+// queryPlan 返回 EXPLAIN QUERY PLAN 的 detail 列
+func queryPlan(t *testing.T, database *DB, query string, args ...interface{}) string {
+	t.Helper()
+	rows, err := database.Query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	defer rows.Close()
+
+	var details []string
+	for rows.Next() {
+		var id, parent, notused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notused, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		details = append(details, detail)
+	}
+	return strings.Join(details, "\n")
+}
+
+// TestGetUserArticlesQueryPlan 文章列表各排序方式都应按对应索引顺序读取，不做临时排序
+func TestGetUserArticlesQueryPlan(t *testing.T) {
+	database := newTestDB(t)
+	user, _ := seedUserArticles(t, database, 50)
+
+	legacyCursor := utils.SimpleCursorEncode(time.Date(2026, 1, 1, 0, 30, 0, 0, time.UTC).Unix(), 30)
+	since := time.Date(2026, 1, 1, 0, 10, 0, 0, time.UTC)
+	indexes := map[ArticleSort]string{
+		SortNewest:  "idx_deliveries_user_published",
+		SortOldest:  "idx_deliveries_user_published",
+		SortUnread:  "idx_deliveries_user_unread",
+		SortUpdated: "idx_deliveries_user_updated",
+	}
+	for sort, index := range indexes {
+		cursor := articleCursor{Sort: sort, Key: "2026-01-01 00:30:00+00:00", ID: 30}.encode()
+		readCursor := articleCursor{Sort: sort, Read: true, Key: "2026-01-01 00:30:00+00:00", ID: 30}.encode()
+		if sort == SortNewest {
+			cursor, readCursor = legacyCursor, legacyCursor
+		}
+		cases := map[string]struct {
+			since  *time.Time
+			cursor *string
+		}{
+			"offset":      {},
+			"cursor":      {cursor: &cursor},
+			"read cursor": {cursor: &readCursor},
+			"since":       {since: &since},
+		}
+		if sort == SortUnread || sort == SortUpdated {
+			// since 只返回少量新文章，SQLite 按发布时间范围查找后再排序，比按排序索引扫描全部投递更快
+			delete(cases, "since")
+		}
+		for name, tc := range cases {
+			query, args := buildUserArticlesQuery(user.ID, nil, nil, nil, nil, tc.since, tc.cursor, sort, 21, 0)
+			plan := queryPlan(t, database, query, args...)
+			if !strings.Contains(plan, index) {
+				t.Errorf("%s %s: plan does not use %s:\n%s", sort, name, index, plan)
+			}
+			if strings.Contains(plan, "USE TEMP B-TREE") {
+				t.Errorf("%s %s: plan sorts with a temp b-tree:\n%s", sort, name, plan)
+			}
+		}
+	}
+}
+
+// pageUserArticles 按游标翻页取完全部文章，检查没有重复
+func pageUserArticles(t *testing.T, database *DB, userID int64, sort ArticleSort, pageSize int) []*UserArticle {
+	t.Helper()
+	seen := map[int64]bool{}
+	var all []*UserArticle
+	var cursor *string
+	for page := 0; page < 100; page++ {
+		articles, next, err := database.GetUserArticles(userID, nil, nil, nil, nil, nil, cursor, sort, pageSize, 0)
+		if err != nil {
+			t.Fatalf("GetUserArticles(%s): %v", sort, err)
+		}
+		for _, a := range articles {
+			if seen[a.ID] {
+				t.Fatalf("%s: article %d returned twice", sort, a.ID)
+			}
+			seen[a.ID] = true
+			all = append(all, a)
+		}
+		if next == nil {
+			return all
+		}
+		cursor = next
+	}
+	t.Fatalf("%s: paging did not terminate", sort)
+	return nil
+}
+
+// TestGetUserArticlesCursorPaging 游标翻页按发布时间倒序返回全部文章且不重复
+func TestGetUserArticlesCursorPaging(t *testing.T) {
+	database := newTestDB(t)
+	user, _ := seedUserArticles(t, database, 25)
+
+	seen := map[int64]bool{}
+	var cursor *string
+	var last time.Time
+	for page := 0; ; page++ {
+		articles, next, err := database.GetUserArticles(user.ID, nil, nil, nil, nil, nil, cursor, SortNewest, 10, 0)
+		if err != nil {
+			t.Fatalf("GetUserArticles: %v", err)
+		}
+		for _, a := range articles {
+			if seen[a.ID] {
+				t.Fatalf("article %d returned twice", a.ID)
+			}
+			if !last.IsZero() && a.PublishedAt.After(last) {
+				t.Fatalf("articles out of order")
+			}
+			seen[a.ID], last = true, *a.PublishedAt
+		}
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != 25 {
+		t.Fatalf("got %d articles, want 25", len(seen))
+	}
+}
+
+// TestGetUserArticlesOldestFirst 按发布时间正序翻页，游标与排序方向一致
+func TestGetUserArticlesOldestFirst(t *testing.T) {
+	database := newTestDB(t)
+	user, _ := seedUserArticles(t, database, 25)
+
+	articles := pageUserArticles(t, database, user.ID, SortOldest, 10)
+	if len(articles) != 25 {
+		t.Fatalf("got %d articles, want 25", len(articles))
+	}
+	for i := 1; i < len(articles); i++ {
+		if articles[i].PublishedAt.Before(*articles[i-1].PublishedAt) {
+			t.Fatalf("articles out of order at %d", i)
+		}
+	}
+
+	// 游标按排序方式区分，其他排序方式的游标被忽略
+	_, next, err := database.GetUserArticles(user.ID, nil, nil, nil, nil, nil, nil, SortOldest, 10, 0)
+	if err != nil || next == nil {
+		t.Fatalf("GetUserArticles: %v, next %v", err, next)
+	}
+	first, _, err := database.GetUserArticles(user.ID, nil, nil, nil, nil, nil, next, SortUnread, 10, 0)
+	if err != nil {
+		t.Fatalf("GetUserArticles: %v", err)
+	}
+	if len(first) == 0 || first[0].ID != articles[len(articles)-1].ID {
+		t.Errorf("cursor of another sort was not ignored")
+	}
+}
+
+// TestGetUserArticlesUnreadFirst 未读在前，翻页跨过未读和已读的分界时不重复、不遗漏
+func TestGetUserArticlesUnreadFirst(t *testing.T) {
+	database := newTestDB(t)
+	user, _ := seedUserArticles(t, database, 25)
+
+	all := pageUserArticles(t, database, user.ID, SortNewest, 50)
+	read := map[int64]bool{}
+	for i, a := range all {
+		if i%3 == 0 {
+			if err := database.MarkArticleAsRead(user.ID, a.ID); err != nil {
+				t.Fatalf("MarkArticleAsRead: %v", err)
+			}
+			read[a.ID] = true
+		}
+	}
+
+	// 每页 4 条，分界落在页中间
+	articles := pageUserArticles(t, database, user.ID, SortUnread, 4)
+	if len(articles) != 25 {
+		t.Fatalf("got %d articles, want 25", len(articles))
+	}
+	unreadCount := 25 - len(read)
+	for i, a := range articles {
+		if (i < unreadCount) == read[a.ID] {
+			t.Fatalf("article %d at position %d: read = %v", a.ID, i, read[a.ID])
+		}
+		if i > 0 && i != unreadCount && a.PublishedAt.After(*articles[i-1].PublishedAt) {
+			t.Fatalf("articles out of order at %d", i)
+		}
+	}
+}
+
+// TestGetUserArticlesRecentlyUpdated 最近更新的文章在前，游标保留完整的时间精度
+func TestGetUserArticlesRecentlyUpdated(t *testing.T) {
+	database := newTestDB(t)
+	user, _ := seedUserArticles(t, database, 25)
+
+	all := pageUserArticles(t, database, user.ID, SortNewest, 50)
+	// 同一秒内先后更新的两篇文章
+	older, newer := all[20], all[5]
+	for _, a := range []*UserArticle{older, newer} {
+		if err := database.SetFavorite(user.ID, a.ID, true); err != nil {
+			t.Fatalf("SetFavorite: %v", err)
+		}
+	}
+
+	articles := pageUserArticles(t, database, user.ID, SortUpdated, 1)
+	if len(articles) != 25 {
+		t.Fatalf("got %d articles, want 25", len(articles))
+	}
+	if articles[0].ID != newer.ID || articles[1].ID != older.ID {
+		t.Errorf("first articles = %d, %d; want %d, %d", articles[0].ID, articles[1].ID, newer.ID, older.ID)
+	}
+}
+
+func TestGetItemByURL(t *testing.T) {
+	database := newTestDB(t)
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+	other := createTestSource(t, database, "https://example.org/feed.xml")
+
+	link := utils.CanonicalizeArticleURL("http://www.example.com/post?utm_source=rss")
+	published := time.Now()
+	item, err := database.CreateItem(source.ID, "guid-1", "Title", "<item></item>", "",
+		&published, "summary", "easy", 100, 1,
+		"", "", "<p>body</p>", "<p>body</p>", "hash-1",
+		"", "", "", "", "", false, "", link, "",
+		"", "", "", "",
+		"")
+	if err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+
+	// 同一文章的另一种链接写法命中同一条记录
+	got, err := database.GetItemByURL(source.ID, utils.CanonicalizeArticleURL("https://example.com/post#top"))
+	if err != nil {
+		t.Fatalf("GetItemByURL: %v", err)
+	}
+	if got.ID != item.ID || got.URL != "https://example.com/post" {
+		t.Errorf("GetItemByURL = id %d url %q, want id %d", got.ID, got.URL, item.ID)
+	}
+
+	// 按源隔离
+	if _, err := database.GetItemByURL(other.ID, link); err == nil {
+		t.Error("GetItemByURL matched an item of another source")
+	}
+}
+
+func TestGetDeliveredItemIDs(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	bob := createTestUser(t, database, "bob")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+
+	mine := createTestItem(t, database, source.ID, "g1", "h1", time.Now())
+	theirs := createTestItem(t, database, source.ID, "g2", "h2", time.Now())
+	if err := database.CreateUserDelivery(alice.ID, mine.ID); err != nil {
+		t.Fatalf("CreateUserDelivery: %v", err)
+	}
+	if err := database.CreateUserDelivery(bob.ID, theirs.ID); err != nil {
+		t.Fatalf("CreateUserDelivery: %v", err)
+	}
+
+	delivered, err := database.GetDeliveredItemIDs(alice.ID, []int64{mine.ID, theirs.ID, 9999})
+	if err != nil {
+		t.Fatalf("GetDeliveredItemIDs: %v", err)
+	}
+	if len(delivered) != 1 || !delivered[mine.ID] {
+		t.Errorf("GetDeliveredItemIDs = %v, want only %d", delivered, mine.ID)
+	}
+}
+
+func TestBackfillDeliveries(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+
+	now := time.Now()
+	var items []*Item
+	for i := 0; i < 4; i++ {
+		items = append(items, createTestItem(t, database, source.ID, fmt.Sprintf("g%d", i), fmt.Sprintf("h%d", i), now.Add(time.Duration(i)*time.Hour)))
+	}
+	// 超出保留期的文章不补发
+	stale := createTestItem(t, database, source.ID, "old", "old", now.Add(10*time.Hour))
+	if _, err := database.Exec("UPDATE items SET created_at = datetime('now', '-2 days') WHERE id = ?", stale.ID); err != nil {
+		t.Fatal(err)
+	}
+	// 已有投递记录的文章保持不变
+	if err := database.CreateUserDelivery(alice.ID, items[3].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := database.BackfillDeliveries(alice.ID, source.ID, 3, 86400)
+	if err != nil {
+		t.Fatalf("BackfillDeliveries: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("backfilled = %d, want 2 (newest 3 in retention, 1 already delivered)", count)
+	}
+	if n := countRows(t, database, "user_deliveries", "user_id = ? AND item_id IN (?, ?, ?)", alice.ID, items[1].ID, items[2].ID, items[3].ID); n != 3 {
+		t.Errorf("newest deliveries = %d, want 3", n)
+	}
+	if n := countRows(t, database, "user_deliveries", "user_id = ? AND item_id IN (?, ?)", alice.ID, items[0].ID, stale.ID); n != 0 {
+		t.Errorf("older or expired items delivered: %d", n)
+	}
+
+	// 重复订阅不会重复投递
+	if count, err := database.BackfillDeliveries(alice.ID, source.ID, 3, 86400); err != nil || count != 0 {
+		t.Errorf("second backfill = %d, %v; want 0", count, err)
+	}
+}
+
+func TestListSourceItemsByPublished(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	bob := createTestUser(t, database, "bob")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+	other := createTestSource(t, database, "https://example.org/feed.xml")
+
+	day := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+	before := createTestItem(t, database, source.ID, "before", "h0", day.Add(-time.Hour))
+	morning := createTestItem(t, database, source.ID, "morning", "h1", day.Add(8*time.Hour))
+	evening := createTestItem(t, database, source.ID, "evening", "h2", day.Add(20*time.Hour))
+	createTestItem(t, database, source.ID, "next-day", "h3", day.Add(24*time.Hour))
+	createTestItem(t, database, other.ID, "other", "h4", day.Add(8*time.Hour))
+	for _, d := range []struct{ user, item int64 }{{alice.ID, morning.ID}, {bob.ID, morning.ID}, {alice.ID, before.ID}} {
+		if err := database.CreateUserDelivery(d.user, d.item); err != nil {
+			t.Fatalf("CreateUserDelivery: %v", err)
+		}
+	}
+
+	from, to := day, day.Add(24*time.Hour)
+	items, total, err := database.ListSourceItemsByPublished(source.ID, &from, &to, 10, 0)
+	if err != nil {
+		t.Fatalf("ListSourceItemsByPublished: %v", err)
+	}
+	if total != 2 || len(items) != 2 {
+		t.Fatalf("got %d items (total %d), want 2", len(items), total)
+	}
+	if items[0].ID != evening.ID || items[1].ID != morning.ID {
+		t.Errorf("order = [%s %s], want [evening morning]", items[0].GUID, items[1].GUID)
+	}
+	if items[0].DeliveredCount != 0 || items[1].DeliveredCount != 2 {
+		t.Errorf("delivered counts = [%d %d], want [0 2]", items[0].DeliveredCount, items[1].DeliveredCount)
+	}
+
+	// 分页：total 不受 limit 影响
+	items, total, err = database.ListSourceItemsByPublished(source.ID, nil, nil, 1, 1)
+	if err != nil {
+		t.Fatalf("ListSourceItemsByPublished: %v", err)
+	}
+	if total != 4 || len(items) != 1 || items[0].ID != evening.ID {
+		t.Errorf("page 2 = %d items (total %d), want evening of 4", len(items), total)
+	}
+}
+
+// TestGetExpirableItemsReadButUnacked 应用内已读但未确认发送的文章按阅读时间参与清理
+func TestGetExpirableItemsReadButUnacked(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "reader")
+	source := createTestSource(t, database, "https://example.com/feed")
+
+	newItem := func(guid string) *Item {
+		item := createTestItem(t, database, source.ID, guid, "hash-"+guid, time.Now())
+		if err := database.CreateUserDelivery(user.ID, item.ID); err != nil {
+			t.Fatalf("CreateUserDelivery: %v", err)
+		}
+		return item
+	}
+	read := func(item *Item) {
+		if err := database.MarkArticleAsRead(user.ID, item.ID); err != nil {
+			t.Fatalf("MarkArticleAsRead: %v", err)
+		}
+	}
+
+	expired := newItem("expired")
+	recent := newItem("recent")
+	unread := newItem("unread")
+	favorite := newItem("favorite")
+	for _, item := range []*Item{expired, recent, favorite} {
+		read(item)
+	}
+	if err := database.SetFavorite(user.ID, favorite.ID, true); err != nil {
+		t.Fatalf("SetFavorite: %v", err)
+	}
+
+	// 驱动按带时区的格式写入时间，使用非 UTC 时区确认比较前已统一换算
+	readAt := time.Now().Add(-10 * 24 * time.Hour).In(time.FixedZone("UTC+8", 8*3600))
+	longAgo := time.Now().Add(-30 * 24 * time.Hour)
+	if _, err := database.Exec(`UPDATE user_deliveries SET delivered_at = datetime('now', '-30 days'), read_at = ?, updated_at = ?
+		WHERE item_id = ?`, readAt, readAt, expired.ID); err != nil {
+		t.Fatal(err)
+	}
+	// 发送很早但刚刚阅读的文章按阅读时间计算
+	if _, err := database.Exec(`UPDATE user_deliveries SET delivered_at = ? WHERE item_id IN (?, ?)`,
+		longAgo.UTC().Format("2006-01-02 15:04:05"), recent.ID, unread.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := database.GetExpirableItems()
+	if err != nil {
+		t.Fatalf("GetExpirableItems: %v", err)
+	}
+	got := make(map[int64]time.Time)
+	for _, item := range items {
+		got[item.ItemID] = item.LastActivityAt
+	}
+	if len(got) != 2 {
+		t.Fatalf("expirable items = %v, want expired and recent only", got)
+	}
+	if diff := got[expired.ID].Sub(readAt); diff < -time.Second || diff > time.Second {
+		t.Errorf("expired item activity = %v, want %v", got[expired.ID], readAt.UTC())
+	}
+	if since := time.Since(got[recent.ID]); since < 0 || since > time.Minute {
+		t.Errorf("recent item activity = %v, want about now", got[recent.ID])
+	}
+}
+
+// TestGetUserArticlesByGroup 按分组过滤只返回分组内订阅源的文章，游标翻页同样适用
+func TestGetUserArticlesByGroup(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "reader")
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var sources []*Source
+	for i, url := range []string{"https://a.example.com/feed", "https://b.example.com/feed", "https://c.example.com/feed"} {
+		source := createTestSource(t, database, url)
+		if err := database.CreateSubscription(user.ID, source.ID); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 3; j++ {
+			guid := fmt.Sprintf("%d-%d", i, j)
+			item := createTestItem(t, database, source.ID, guid, "hash-"+guid, base.Add(time.Duration(i*3+j)*time.Minute))
+			if err := database.CreateUserDelivery(user.ID, item.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sources = append(sources, source)
+	}
+
+	group, err := database.CreateGroup(user.ID, "Tech", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.SetSubscriptionGroups(user.ID, []GroupAssignment{
+		{SourceID: sources[0].ID, GroupID: &group.ID},
+		{SourceID: sources[1].ID, GroupID: &group.ID},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var all []*UserArticle
+	var cursor *string
+	for page := 0; page < 10; page++ {
+		articles, next, err := database.GetUserArticles(user.ID, nil, &group.ID, nil, nil, nil, cursor, SortNewest, 4, 0)
+		if err != nil {
+			t.Fatalf("GetUserArticles: %v", err)
+		}
+		all = append(all, articles...)
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	if len(all) != 6 {
+		t.Fatalf("group articles = %d, want 6", len(all))
+	}
+	for _, a := range all {
+		if a.SourceID == sources[2].ID {
+			t.Errorf("article %d from source outside the group", a.ID)
+		}
+	}
+
+	// 与 source_id 同时指定：分组内的源只返回该源，分组外的源没有结果
+	inGroup, _, err := database.GetUserArticles(user.ID, &sources[1].ID, &group.ID, nil, nil, nil, nil, SortNewest, 50, 0)
+	if err != nil || len(inGroup) != 3 {
+		t.Errorf("source in group: %d articles, err=%v", len(inGroup), err)
+	}
+	outside, _, err := database.GetUserArticles(user.ID, &sources[2].ID, &group.ID, nil, nil, nil, nil, SortNewest, 50, 0)
+	if err != nil || len(outside) != 0 {
+		t.Errorf("source outside group: %d articles, err=%v", len(outside), err)
+	}
+}
+
+func TestTrimSourceItems(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "reader")
+	source := createTestSource(t, database, "https://example.com/feed")
+	other := createTestSource(t, database, "https://example.com/other")
+
+	// items[0] 最新，items[5] 最旧
+	now := time.Now()
+	var items []*Item
+	for i := 0; i < 6; i++ {
+		item := createTestItem(t, database, source.ID, fmt.Sprintf("item-%d", i), fmt.Sprintf("hash-%d", i), now.Add(-time.Duration(i)*time.Hour))
+		if err := database.CreateUserDelivery(user.ID, item.ID); err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+	otherItem := createTestItem(t, database, other.ID, "other", "hash-other", now.Add(-24*time.Hour))
+	var ids []int64
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	if err := database.BatchUpdateDeliveryStatus(user.ID, ids[:4], 1); err != nil {
+		t.Fatal(err)
+	}
+	// items[4] 未确认，items[5] 已确认但被收藏
+	if err := database.BatchUpdateDeliveryStatus(user.ID, ids[5:], 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.SetFavorite(user.ID, items[5].ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`UPDATE items SET image_paths = '["1/a.webp"]' WHERE id = ?`, items[3].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	trimmed, imagePaths, err := database.TrimSourceItems(source.ID, 2)
+	if err != nil {
+		t.Fatalf("TrimSourceItems: %v", err)
+	}
+	if trimmed != 2 || len(imagePaths) != 1 || imagePaths[0] != `["1/a.webp"]` {
+		t.Errorf("trimmed = %d, image paths = %v; want 2 items with one image list", trimmed, imagePaths)
+	}
+	for i, item := range items {
+		wantKept := i != 2 && i != 3
+		if kept := countRows(t, database, "items", "id = ?", item.ID) == 1; kept != wantKept {
+			t.Errorf("item %d kept = %v, want %v", i, kept, wantKept)
+		}
+	}
+	if n := countRows(t, database, "user_deliveries", "item_id IN (?, ?)", items[2].ID, items[3].ID); n != 0 {
+		t.Errorf("deliveries of trimmed items = %d, want 0", n)
+	}
+	if n := countRows(t, database, "items", "id = ?", otherItem.ID); n != 1 {
+		t.Error("item of another source was trimmed")
+	}
+	// 删除的文章记录墓碑，仍在 feed 中时不会重新入库
+	for _, guid := range []string{"item-2", "item-3"} {
+		if tombstoned, err := database.MatchItemTombstone(source.ID, guid, ""); err != nil || !tombstoned {
+			t.Errorf("trimmed %s tombstoned = %v, %v", guid, tombstoned, err)
+		}
+	}
+	if tombstoned, _ := database.MatchItemTombstone(source.ID, "item-4", ""); tombstoned {
+		t.Error("kept item-4 has a tombstone")
+	}
+
+	// 不限制时不删除
+	if trimmed, _, err := database.TrimSourceItems(source.ID, 0); err != nil || trimmed != 0 {
+		t.Errorf("TrimSourceItems(0) = %d, %v", trimmed, err)
+	}
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// VACUUM 触发条件：空闲页同时超过总页数的比例和绝对大小（避免小库频繁重建）
+const (
+	vacuumFreeRatio    = 0.2
+	vacuumMinFreeBytes = 16 << 20
+)
+
+// MaintenanceResult 一次数据库维护的结果
+type MaintenanceResult struct {
+	WALFrames       int64 // checkpoint 前 WAL 中的帧数
+	WALBytes        int64 // 截断的 WAL 大小（按帧数 × 页大小估算）
+	CheckpointBusy  bool  // 有读事务未结束，WAL 未能完全截断
+	FreeBytesBefore int64 // 维护前的空闲页大小
+	SizeBefore      int64 // 维护前的数据库文件大小
+	SizeAfter       int64 // 维护后的数据库文件大小
+	Vacuumed        bool
+	Duration        time.Duration
+}
+
+// Reclaimed 本次维护回收的磁盘空间（WAL 截断 + VACUUM 缩小）
+func (r *MaintenanceResult) Reclaimed() int64 {
+	return r.WALBytes + r.SizeBefore - r.SizeAfter
+}
+
+// Maintain 执行 WAL checkpoint，空闲页较多时 VACUUM
+// 连接池只有一个连接，执行期间其他查询会排队等待，调用方应避开抓取等大量写入的时段
+func (db *DB) Maintain() (*MaintenanceResult, error) {
+	start := time.Now()
+	result := &MaintenanceResult{}
+
+	pageSize, err := db.pragmaInt("page_size")
+	if err != nil {
+		return nil, err
+	}
+
+	// TRUNCATE 模式在检查点完成后把 WAL 文件截断为 0 字节
+	var busy, logFrames, checkpointed int64
+	if err := db.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return nil, fmt.Errorf("wal checkpoint failed: %w", err)
+	}
+	result.CheckpointBusy = busy != 0
+	if logFrames > 0 {
+		result.WALFrames = logFrames
+		result.WALBytes = logFrames * pageSize
+	}
+
+	pageCount, err := db.pragmaInt("page_count")
+	if err != nil {
+		return nil, err
+	}
+	freePages, err := db.pragmaInt("freelist_count")
+	if err != nil {
+		return nil, err
+	}
+	result.SizeBefore = pageCount * pageSize
+	result.FreeBytesBefore = freePages * pageSize
+	result.SizeAfter = result.SizeBefore
+
+	if pageCount > 0 && float64(freePages)/float64(pageCount) >= vacuumFreeRatio && result.FreeBytesBefore >= vacuumMinFreeBytes {
+		if _, err := db.Exec("VACUUM"); err != nil {
+			return nil, fmt.Errorf("vacuum failed: %w", err)
+		}
+		result.Vacuumed = true
+
+		// VACUUM 本身会写 WAL，再截断一次
+		if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			return nil, fmt.Errorf("wal checkpoint after vacuum failed: %w", err)
+		}
+		if pageCount, err = db.pragmaInt("page_count"); err != nil {
+			return nil, err
+		}
+		result.SizeAfter = pageCount * pageSize
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// pragmaInt 读取返回单个整数的 PRAGMA
+func (db *DB) pragmaInt(name string) (int64, error) {
+	var value int64
+	if err := db.QueryRow("PRAGMA " + name).Scan(&value); err != nil {
+		return 0, fmt.Errorf("read pragma %s failed: %w", name, err)
+	}
+	return value, nil
+}
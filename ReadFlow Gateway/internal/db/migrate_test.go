@@ -0,0 +1,65 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// TestMigrateSourceCategoryDefault 旧库的 sources.category 默认值 'Technology' 不应被文章继承
+func TestMigrateSourceCategoryDefault(t *testing.T) {
+	database := newTestDB(t)
+
+	// 模拟旧版本：category 列默认值为 'Technology'，旧文章没有分类
+	for _, stmt := range []string{
+		"DROP INDEX idx_sources_category",
+		"ALTER TABLE sources DROP COLUMN category",
+		"ALTER TABLE sources ADD COLUMN category TEXT DEFAULT 'Technology'",
+	} {
+		if _, err := database.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	defaulted := createTestSource(t, database, "https://a.example.com/feed")
+	explicit := createTestSource(t, database, "https://b.example.com/feed")
+	if _, err := database.Exec("UPDATE sources SET category = '  machine learning ' WHERE id = ?", explicit.ID); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	createTestItem(t, database, defaulted.ID, "a1", "hash-a1", now)
+	createTestItem(t, database, explicit.ID, "b1", "hash-b1", now)
+	if _, err := database.Exec("UPDATE items SET category = NULL"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	if got := database.columnDefault("sources", "category"); got != "" {
+		t.Errorf("sources.category default = %q, want none", got)
+	}
+	if n := countRows(t, database, "sources", "category IS NULL AND id = ?", defaulted.ID); n != 1 {
+		t.Errorf("defaulted source category was kept")
+	}
+	if n := countRows(t, database, "items", "source_id = ? AND category = ''", defaulted.ID); n != 1 {
+		t.Errorf("item of defaulted source should have an empty category")
+	}
+	if n := countRows(t, database, "items", "source_id = ? AND category = 'Machine Learning'", explicit.ID); n != 1 {
+		t.Errorf("item of explicit source should inherit the normalized category")
+	}
+
+	// 新建的源不再带默认分类；再次迁移不会改动已有数据
+	fresh := createTestSource(t, database, "https://c.example.com/feed")
+	if _, err := database.Exec("UPDATE items SET category = 'Kept' WHERE source_id = ?", defaulted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.migrate(); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if n := countRows(t, database, "sources", "category IS NULL AND id = ?", fresh.ID); n != 1 {
+		t.Errorf("new source got a default category")
+	}
+	if n := countRows(t, database, "items", "category = 'Kept'"); n != 1 {
+		t.Errorf("second migration rewrote item categories")
+	}
+}
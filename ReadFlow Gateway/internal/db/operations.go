@@ -2,10 +2,16 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/readflow/gateway/internal/utils"
 )
 
+// ErrStalePreferences 偏好设置已被其他设备更新，客户端提交所基于的版本已过期
+var ErrStalePreferences = errors.New("preferences were updated by another client")
+
 // User 相关操作
 
 // CreateUser 创建新用户
@@ -30,8 +36,9 @@ func (db *DB) CreateUser(username, email, passwordHash string) (*User, error) {
 		}
 	}
 
+	// 第一个注册的用户自动成为管理员
 	result, err := db.Exec(
-		"INSERT INTO users (username, email, password_hash) VALUES (?, ?, ?)",
+		"INSERT INTO users (username, email, password_hash, is_admin) VALUES (?, ?, ?, NOT EXISTS (SELECT 1 FROM users))",
 		username, email, passwordHash,
 	)
 	if err != nil {
@@ -64,9 +71,9 @@ func (db *DB) CreateOrGetUser(username string) (*User, error) {
 func (db *DB) GetUserByID(id int64) (*User, error) {
 	user := &User{}
 	err := db.QueryRow(
-		"SELECT id, username, COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(token, ''), created_at, last_login_at FROM users WHERE id = ?",
+		"SELECT id, username, COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(token, ''), created_at, last_login_at, COALESCE(is_admin, 0) FROM users WHERE id = ?",
 		id,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Token, &user.CreatedAt, &user.LastLoginAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Token, &user.CreatedAt, &user.LastLoginAt, &user.IsAdmin)
 
 	if err != nil {
 		return nil, err
@@ -78,9 +85,9 @@ func (db *DB) GetUserByID(id int64) (*User, error) {
 func (db *DB) GetUserByUsername(username string) (*User, error) {
 	user := &User{}
 	err := db.QueryRow(
-		"SELECT id, username, COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(token, ''), created_at, last_login_at FROM users WHERE username = ?",
+		"SELECT id, username, COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(token, ''), created_at, last_login_at, COALESCE(is_admin, 0) FROM users WHERE username = ?",
 		username,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Token, &user.CreatedAt, &user.LastLoginAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Token, &user.CreatedAt, &user.LastLoginAt, &user.IsAdmin)
 
 	if err != nil {
 		return nil, err
@@ -92,9 +99,9 @@ func (db *DB) GetUserByUsername(username string) (*User, error) {
 func (db *DB) GetUserByEmail(email string) (*User, error) {
 	user := &User{}
 	err := db.QueryRow(
-		"SELECT id, username, COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(token, ''), created_at, last_login_at FROM users WHERE email = ?",
+		"SELECT id, username, COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(token, ''), created_at, last_login_at, COALESCE(is_admin, 0) FROM users WHERE email = ?",
 		email,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Token, &user.CreatedAt, &user.LastLoginAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Token, &user.CreatedAt, &user.LastLoginAt, &user.IsAdmin)
 
 	if err != nil {
 		return nil, err
@@ -106,9 +113,9 @@ func (db *DB) GetUserByEmail(email string) (*User, error) {
 func (db *DB) GetUserByToken(token string) (*User, error) {
 	user := &User{}
 	err := db.QueryRow(
-		"SELECT id, username, COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(token, ''), created_at, last_login_at FROM users WHERE token = ?",
+		"SELECT id, username, COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(token, ''), created_at, last_login_at, COALESCE(is_admin, 0) FROM users WHERE token = ?",
 		token,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Token, &user.CreatedAt, &user.LastLoginAt)
+	).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash, &user.Token, &user.CreatedAt, &user.LastLoginAt, &user.IsAdmin)
 
 	if err != nil {
 		return nil, err
@@ -125,15 +132,65 @@ func (db *DB) UpdateUserToken(userID int64, token string) error {
 	return err
 }
 
+// UpdateUserPasswordHash 更新用户密码哈希（登录时按新成本重新哈希同一密码，不影响重置令牌；修改密码见 ResetPasswordWithToken）
+func (db *DB) UpdateUserPasswordHash(userID int64, passwordHash string) error {
+	_, err := db.Exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, userID)
+	return err
+}
+
+// PromoteAdmins 将指定用户名的用户设为管理员（用于环境变量引导），返回更新的行数
+func (db *DB) PromoteAdmins(usernames []string) (int64, error) {
+	var total int64
+	for _, username := range usernames {
+		result, err := db.Exec("UPDATE users SET is_admin = 1 WHERE username = ? AND COALESCE(is_admin, 0) = 0", username)
+		if err != nil {
+			return total, err
+		}
+		n, _ := result.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
+
 // DeleteUser 删除用户
 func (db *DB) DeleteUser(userID int64) error {
 	_, err := db.Exec("DELETE FROM users WHERE id = ?", userID)
 	return err
 }
 
+// GetUserQuotaOverride 获取管理员为用户设置的配额覆盖值
+func (db *DB) GetUserQuotaOverride(userID int64) (*UserQuotaOverride, error) {
+	o := &UserQuotaOverride{}
+	err := db.QueryRow(
+		"SELECT max_subscriptions, max_vocabulary FROM users WHERE id = ?",
+		userID,
+	).Scan(&o.MaxSubscriptions, &o.MaxVocabulary)
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// SetUserQuotaOverride 设置用户的配额覆盖值，字段为 nil 时恢复为全局配置
+func (db *DB) SetUserQuotaOverride(userID int64, o *UserQuotaOverride) error {
+	result, err := db.Exec(
+		"UPDATE users SET max_subscriptions = ?, max_vocabulary = ? WHERE id = ?",
+		o.MaxSubscriptions, o.MaxVocabulary, userID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 // UpsertUserPreferences 更新或插入用户偏好设置
-func (db *DB) UpsertUserPreferences(pref *UserPreference) error {
-	_, err := db.Exec(`
+// expectedUpdatedAt 不为 nil 时做乐观并发检查：已保存记录的 updated_at 晚于该值则不写入并返回 ErrStalePreferences。
+// 每次更新 updated_at 至少加 1 秒，同一秒内的两次写入也能区分先后
+func (db *DB) UpsertUserPreferences(pref *UserPreference, expectedUpdatedAt *int64) error {
+	query := `
 		INSERT INTO user_preferences (
 			user_id, reading_settings, translation_provider, 
 			enable_auto_translation, enable_title_translation, 
@@ -154,16 +211,32 @@ func (db *DB) UpsertUserPreferences(pref *UserPreference) error {
 			proxy_mode_enabled = excluded.proxy_mode_enabled,
 			proxy_server_url = excluded.proxy_server_url,
 			proxy_token = excluded.proxy_token,
-			updated_at = excluded.updated_at
-	`,
+			updated_at = MAX(excluded.updated_at, COALESCE(user_preferences.updated_at, 0) + 1)
+	`
+	args := []interface{}{
 		pref.UserID, pref.ReadingSettings, pref.TranslationProvider,
 		pref.EnableAutoTranslation, pref.EnableTitleTranslation,
 		pref.MaxConcurrentTranslations, pref.TranslationTimeout,
 		pref.DefaultCategory, pref.EnableNotifications,
 		pref.ProxyModeEnabled, pref.ProxyServerURL, pref.ProxyToken,
 		time.Now().Unix(),
-	)
-	return err
+	}
+	if expectedUpdatedAt != nil {
+		// 检查和写入在同一条语句中完成，并发提交不会同时通过检查
+		query += " WHERE COALESCE(user_preferences.updated_at, 0) <= ?"
+		args = append(args, *expectedUpdatedAt)
+	}
+
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if expectedUpdatedAt != nil {
+		if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+			return ErrStalePreferences
+		}
+	}
+	return nil
 }
 
 // GetUserPreferences 获取用户偏好设置
@@ -194,10 +267,17 @@ func (db *DB) GetUserPreferences(userID int64) (*UserPreference, error) {
 // Source 相关操作
 
 // CreateSource 创建订阅源
-func (db *DB) CreateSource(url, title, description string) (*Source, error) {
+func (db *DB) CreateSource(url, title, description string, fetchInterval int) (*Source, error) {
+	return db.CreateClassifiedSource(url, title, description, fetchInterval, "", "")
+}
+
+// CreateClassifiedSource 创建带分类和语言的订阅源，category / language 为空时写入 NULL
+// （分类为空的源不向文章传递分类，语言为空的源在首次抓取时使用 feed 声明的语言）
+func (db *DB) CreateClassifiedSource(url, title, description string, fetchInterval int, category, language string) (*Source, error) {
 	result, err := db.Exec(
-		"INSERT INTO sources (url, title, description) VALUES (?, ?, ?)",
-		url, title, description,
+		`INSERT INTO sources (url, title, description, fetch_interval, category, language)
+		 VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))`,
+		url, title, description, fetchInterval, category, language,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create source: %w", err)
@@ -211,57 +291,88 @@ func (db *DB) CreateSource(url, title, description string) (*Source, error) {
 	return db.GetSourceByID(id)
 }
 
-// GetSourceByID 根据 ID 获取订阅源
-func (db *DB) GetSourceByID(id int64) (*Source, error) {
+// sourceColumns Source 查询使用的列（需配合 s 别名）
+const sourceColumns = `
+	s.id, s.url, COALESCE(s.title, ''), COALESCE(s.description, ''),
+	s.last_fetch_time, s.fetch_interval, s.is_active, s.error_count,
+	COALESCE(s.last_error, ''), s.created_at, COALESCE(s.image_mode, 'process'),
+	s.last_item_added_at, COALESCE(s.proxy_url, ''),
+	COALESCE(s.retention_seconds, 0), COALESCE(s.category, ''),
+	COALESCE(s.content_update_mode, 'off'), COALESCE(s.favicon, ''),
+	s.favicon_updated_at, COALESCE(s.summary_length, 0), COALESCE(s.gallery_enabled, 0),
+	COALESCE(s.full_content, 0), COALESCE(s.min_word_count, 0), COALESCE(s.lenient_parse, 0),
+	COALESCE(s.body_images_only, 0), COALESCE(s.language, ''), COALESCE(s.max_items, 0),
+	COALESCE(s.last_parse_note, ''), COALESCE(s.http_etag, ''), COALESCE(s.http_last_modified, ''),
+	COALESCE(s.feed_title, '')`
+
+// rowScanner 同时兼容 *sql.Row 和 *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSource 扫描一行 sourceColumns
+func scanSource(row rowScanner) (*Source, error) {
 	source := &Source{}
-	err := db.QueryRow(`
-		SELECT id, url, COALESCE(title, ''), COALESCE(description, ''), 
-		       last_fetch_time, fetch_interval, is_active, error_count, 
-		       COALESCE(last_error, ''), created_at 
-		FROM sources WHERE id = ?`,
-		id,
-	).Scan(
+	err := row.Scan(
 		&source.ID, &source.URL, &source.Title, &source.Description,
 		&source.LastFetchTime, &source.FetchInterval, &source.IsActive,
-		&source.ErrorCount, &source.LastError, &source.CreatedAt,
+		&source.ErrorCount, &source.LastError, &source.CreatedAt, &source.ImageMode,
+		&source.LastItemAddedAt, &source.ProxyURL, &source.RetentionSeconds,
+		&source.Category, &source.ContentUpdateMode, &source.Favicon,
+		&source.FaviconUpdatedAt, &source.SummaryLength, &source.GalleryEnabled,
+		&source.FullContent, &source.MinWordCount, &source.LenientParse,
+		&source.BodyImagesOnly, &source.Language, &source.MaxItems,
+		&source.LastParseNote, &source.HTTPETag, &source.HTTPLastModified,
+		&source.FeedTitle,
 	)
-
 	if err != nil {
 		return nil, err
 	}
 	return source, nil
 }
 
+// GetSourceByID 根据 ID 获取订阅源
+func (db *DB) GetSourceByID(id int64) (*Source, error) {
+	return scanSource(db.QueryRow(`SELECT `+sourceColumns+` FROM sources s WHERE s.id = ?`, id))
+}
+
 // GetSourceByURL 根据 URL 获取订阅源
 func (db *DB) GetSourceByURL(url string) (*Source, error) {
-	source := &Source{}
-	err := db.QueryRow(`
-		SELECT id, url, COALESCE(title, ''), COALESCE(description, ''), 
-		       last_fetch_time, fetch_interval, is_active, error_count, 
-		       COALESCE(last_error, ''), created_at 
-		FROM sources WHERE url = ?`,
-		url,
-	).Scan(
-		&source.ID, &source.URL, &source.Title, &source.Description,
-		&source.LastFetchTime, &source.FetchInterval, &source.IsActive,
-		&source.ErrorCount, &source.LastError, &source.CreatedAt,
-	)
+	return scanSource(db.QueryRow(`SELECT `+sourceColumns+` FROM sources s WHERE s.url = ?`, url))
+}
 
+// GetActiveSources 获取所有活跃的订阅源
+func (db *DB) GetActiveSources() ([]*Source, error) {
+	rows, err := db.Query(`
+		SELECT ` + sourceColumns + `
+		FROM sources s
+		WHERE s.is_active = 1
+		ORDER BY s.last_fetch_time ASC NULLS FIRST
+	`)
 	if err != nil {
 		return nil, err
 	}
-	return source, nil
+	defer rows.Close()
+
+	var sources []*Source
+	for rows.Next() {
+		source, err := scanSource(rows)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, rows.Err()
 }
 
-// GetActiveSources 获取所有活跃的订阅源
-func (db *DB) GetActiveSources() ([]*Source, error) {
+// GetFailedSources 获取已停用或抓取出错的订阅源，错误次数多的在前
+func (db *DB) GetFailedSources() ([]*Source, error) {
 	rows, err := db.Query(`
-		SELECT id, url, COALESCE(title, ''), COALESCE(description, ''), 
-		       last_fetch_time, fetch_interval, is_active, error_count, 
-		       COALESCE(last_error, ''), created_at 
-		FROM sources 
-		WHERE is_active = 1
-		ORDER BY last_fetch_time ASC NULLS FIRST
+		SELECT ` + sourceColumns + `
+		FROM sources s
+		WHERE s.is_active = 0 OR s.error_count > 0
+		ORDER BY s.is_active ASC, s.error_count DESC, s.id ASC
 	`)
 	if err != nil {
 		return nil, err
@@ -270,12 +381,7 @@ func (db *DB) GetActiveSources() ([]*Source, error) {
 
 	var sources []*Source
 	for rows.Next() {
-		source := &Source{}
-		err := rows.Scan(
-			&source.ID, &source.URL, &source.Title, &source.Description,
-			&source.LastFetchTime, &source.FetchInterval, &source.IsActive,
-			&source.ErrorCount, &source.LastError, &source.CreatedAt,
-		)
+		source, err := scanSource(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -285,6 +391,12 @@ func (db *DB) GetActiveSources() ([]*Source, error) {
 	return sources, rows.Err()
 }
 
+// ReactivateSource 重新启用源并清零错误计数（last_error 保留到下次抓取有结果）
+func (db *DB) ReactivateSource(sourceID int64) error {
+	_, err := db.Exec("UPDATE sources SET is_active = 1, error_count = 0 WHERE id = ?", sourceID)
+	return err
+}
+
 // UpdateSourceFetchTime 更新源的抓取时间
 func (db *DB) UpdateSourceFetchTime(sourceID int64) error {
 	_, err := db.Exec(
@@ -294,6 +406,12 @@ func (db *DB) UpdateSourceFetchTime(sourceID int64) error {
 	return err
 }
 
+// UpdateSourceLastItemAdded 记录源最近一次新增文章的时间
+func (db *DB) UpdateSourceLastItemAdded(sourceID int64) error {
+	_, err := db.Exec("UPDATE sources SET last_item_added_at = ? WHERE id = ?", time.Now(), sourceID)
+	return err
+}
+
 // UpdateSourceError 更新源的错误信息
 func (db *DB) UpdateSourceError(sourceID int64, errMsg string) error {
 	_, err := db.Exec(`
@@ -312,12 +430,183 @@ func (db *DB) UpdateSourceActive(sourceID int64, isActive bool) error {
 	return err
 }
 
-// DeleteSource 删除订阅源（级联删除关联的 items、subscriptions、user_deliveries 由外键负责）
-func (db *DB) DeleteSource(sourceID int64) error {
-	_, err := db.Exec("DELETE FROM sources WHERE id = ?", sourceID)
+// activateSubscribedSourceQuery 激活源，因连续抓取失败（见 UpdateSourceError）停用的源保持停用
+const activateSubscribedSourceQuery = "UPDATE sources SET is_active = 1 WHERE id = ? AND error_count < 3"
+
+// ActivateSubscribedSource 有人订阅或恢复订阅时重新激活因无人订阅停用的源
+func (db *DB) ActivateSubscribedSource(sourceID int64) error {
+	_, err := db.Exec(activateSubscribedSourceQuery, sourceID)
+	return err
+}
+
+// UpdateSourceImageMode 更新源的图片处理模式
+func (db *DB) UpdateSourceImageMode(sourceID int64, mode string) error {
+	_, err := db.Exec("UPDATE sources SET image_mode = ? WHERE id = ?", mode, sourceID)
+	return err
+}
+
+// UpdateSourceContentUpdateMode 更新源的文章内容更新模式
+func (db *DB) UpdateSourceContentUpdateMode(sourceID int64, mode string) error {
+	_, err := db.Exec("UPDATE sources SET content_update_mode = ? WHERE id = ?", mode, sourceID)
+	return err
+}
+
+// UpdateSourceFavicon 更新源图标路径并记录解析时间（解析失败时 favicon 为空）
+func (db *DB) UpdateSourceFavicon(sourceID int64, favicon string) error {
+	_, err := db.Exec("UPDATE sources SET favicon = ?, favicon_updated_at = ? WHERE id = ?", favicon, time.Now(), sourceID)
+	return err
+}
+
+// UpdateSourceProxy 更新源级出站代理（空字符串表示使用全局代理）
+func (db *DB) UpdateSourceProxy(sourceID int64, proxyURL string) error {
+	_, err := db.Exec("UPDATE sources SET proxy_url = ? WHERE id = ?", proxyURL, sourceID)
+	return err
+}
+
+// UpdateSourceRetention 更新源级文章保留时间（0 表示使用全局设置）
+func (db *DB) UpdateSourceRetention(sourceID int64, seconds int) error {
+	var value interface{}
+	if seconds > 0 {
+		value = seconds
+	}
+	_, err := db.Exec("UPDATE sources SET retention_seconds = ? WHERE id = ?", value, sourceID)
+	return err
+}
+
+// UpdateSourceSummaryLength 更新源级摘要长度（0 表示使用默认长度）
+func (db *DB) UpdateSourceSummaryLength(sourceID int64, length int) error {
+	var value interface{}
+	if length > 0 {
+		value = length
+	}
+	_, err := db.Exec("UPDATE sources SET summary_length = ? WHERE id = ?", value, sourceID)
+	return err
+}
+
+// UpdateSourceGallery 开启或关闭源的图集提取
+func (db *DB) UpdateSourceGallery(sourceID int64, enabled bool) error {
+	_, err := db.Exec("UPDATE sources SET gallery_enabled = ? WHERE id = ?", enabled, sourceID)
+	return err
+}
+
+// UpdateSourceFullContent 开启或关闭源的全文提取
+func (db *DB) UpdateSourceFullContent(sourceID int64, enabled bool) error {
+	_, err := db.Exec("UPDATE sources SET full_content = ? WHERE id = ?", enabled, sourceID)
+	return err
+}
+
+// UpdateSourceLenientParse 开启或关闭源的宽松解析
+func (db *DB) UpdateSourceLenientParse(sourceID int64, enabled bool) error {
+	_, err := db.Exec("UPDATE sources SET lenient_parse = ? WHERE id = ?", enabled, sourceID)
+	return err
+}
+
+// UpdateSourceBodyImagesOnly 开启或关闭源的"只缓存正文图片"
+func (db *DB) UpdateSourceBodyImagesOnly(sourceID int64, enabled bool) error {
+	_, err := db.Exec("UPDATE sources SET body_images_only = ? WHERE id = ?", enabled, sourceID)
+	return err
+}
+
+// UpdateSourceMeta 更新源的标题、描述和最近一次抓取到的 feed 标题（抓取时从 feed 同步）
+func (db *DB) UpdateSourceMeta(sourceID int64, title, description, feedTitle string) error {
+	_, err := db.Exec("UPDATE sources SET title = ?, description = ?, feed_title = ? WHERE id = ?", title, description, feedTitle, sourceID)
+	return err
+}
+
+// UpdateSourceLanguageIfUnset 源语言未知时记录 feed 声明的语言，已设置的语言（创建时指定或管理员修正）不被覆盖
+func (db *DB) UpdateSourceLanguageIfUnset(sourceID int64, language string) error {
+	_, err := db.Exec("UPDATE sources SET language = ? WHERE id = ? AND COALESCE(language, '') = ''", language, sourceID)
+	return err
+}
+
+// UpdateSourceClassification 修正源的分类和语言，nil 表示不修改，空字符串表示清除
+// 修改分类时，继承自旧分类的已入库文章（分类为空或等于旧分类）一并改为新分类，返回更新的文章数
+func (db *DB) UpdateSourceClassification(sourceID int64, category, language *string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if language != nil {
+		if _, err := tx.Exec("UPDATE sources SET language = NULLIF(?, '') WHERE id = ?", *language, sourceID); err != nil {
+			return 0, err
+		}
+	}
+
+	var updated int64
+	if category != nil {
+		var oldCategory string
+		if err := tx.QueryRow("SELECT COALESCE(category, '') FROM sources WHERE id = ?", sourceID).Scan(&oldCategory); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("UPDATE sources SET category = NULLIF(?, '') WHERE id = ?", *category, sourceID); err != nil {
+			return 0, err
+		}
+
+		textProcessor := utils.NewTextProcessor()
+		result, err := tx.Exec(
+			"UPDATE items SET category = ? WHERE source_id = ? AND (COALESCE(category, '') = '' OR category = ?)",
+			textProcessor.NormalizeCategory(*category), sourceID, textProcessor.NormalizeCategory(oldCategory),
+		)
+		if err != nil {
+			return 0, err
+		}
+		updated, _ = result.RowsAffected()
+	}
+
+	return updated, tx.Commit()
+}
+
+// UpdateSourceMaxItems 更新源最多保留的文章数（0 表示不限制）
+func (db *DB) UpdateSourceMaxItems(sourceID int64, maxItems int) error {
+	_, err := db.Exec("UPDATE sources SET max_items = ? WHERE id = ?", maxItems, sourceID)
 	return err
 }
 
+// UpdateSourceParseNote 记录最近一次抓取的数据质量提示（空字符串表示没有问题）
+func (db *DB) UpdateSourceParseNote(sourceID int64, note string) error {
+	_, err := db.Exec("UPDATE sources SET last_parse_note = ? WHERE id = ?", note, sourceID)
+	return err
+}
+
+// UpdateSourceValidators 记录源最近一次成功抓取响应的 ETag 和 Last-Modified（空字符串表示响应没有提供）
+func (db *DB) UpdateSourceValidators(sourceID int64, etag, lastModified string) error {
+	_, err := db.Exec("UPDATE sources SET http_etag = ?, http_last_modified = ? WHERE id = ?", etag, lastModified, sourceID)
+	return err
+}
+
+// UpdateSourceMinWordCount 更新源级最小字数（0 表示不限制）
+func (db *DB) UpdateSourceMinWordCount(sourceID int64, count int) error {
+	_, err := db.Exec("UPDATE sources SET min_word_count = ? WHERE id = ?", count, sourceID)
+	return err
+}
+
+// DeleteSource 删除订阅源及全部依赖数据：文章、投递记录、阅读状态、过滤规则绑定、订阅关系和凭据
+// 在一个事务中显式删除，不依赖外键级联（旧数据库迁移出的表可能缺少外键约束）
+func (db *DB) DeleteSource(sourceID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := purgeSourceItemsTx(tx, sourceID); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		"DELETE FROM filter_bindings WHERE source_id = ?",
+		"DELETE FROM subscriptions WHERE source_id = ?",
+		"DELETE FROM source_credentials WHERE source_id = ?",
+		"DELETE FROM sources WHERE id = ?",
+	} {
+		if _, err := tx.Exec(query, sourceID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // Subscription 相关操作
 
 // CreateSubscription 创建订阅关系
@@ -338,22 +627,85 @@ func (db *DB) DeleteSubscription(userID, sourceID int64) error {
 	return err
 }
 
-// GetSubscriptionCount 获取订阅源的订阅数
+// GetSubscriptionCount 获取订阅源未暂停的订阅数（维持源活跃的订阅数）
 func (db *DB) GetSubscriptionCount(sourceID int64) (int, error) {
 	var count int
 	err := db.QueryRow(
-		"SELECT COUNT(*) FROM subscriptions WHERE source_id = ?",
+		"SELECT COUNT(*) FROM subscriptions WHERE source_id = ? AND COALESCE(is_paused, 0) = 0",
 		sourceID,
 	).Scan(&count)
 	return count, err
 }
 
+// SetSubscriptionPaused 暂停或恢复用户的订阅，未订阅该源时返回 ErrNotSubscribed
+// 暂停后源没有未暂停的订阅时停用该源；恢复时重新激活（因连续抓取失败停用的源保持停用）
+func (db *DB) SetSubscriptionPaused(userID, sourceID int64, paused bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE subscriptions SET is_paused = ? WHERE user_id = ? AND source_id = ?",
+		paused, userID, sourceID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotSubscribed
+	}
+
+	if paused {
+		_, err = tx.Exec(`
+			UPDATE sources SET is_active = 0
+			WHERE id = ? AND NOT EXISTS (
+				SELECT 1 FROM subscriptions WHERE source_id = ? AND COALESCE(is_paused, 0) = 0
+			)
+		`, sourceID, sourceID)
+	} else {
+		_, err = tx.Exec(activateSubscribedSourceQuery, sourceID)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPausedSubscriptions 获取用户已暂停的订阅（source_id 集合）
+func (db *DB) GetPausedSubscriptions(userID int64) (map[int64]bool, error) {
+	rows, err := db.Query("SELECT source_id FROM subscriptions WHERE user_id = ? AND is_paused = 1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	paused := make(map[int64]bool)
+	for rows.Next() {
+		var sourceID int64
+		if err := rows.Scan(&sourceID); err != nil {
+			return nil, err
+		}
+		paused[sourceID] = true
+	}
+	return paused, rows.Err()
+}
+
+// CountUserSubscriptions 获取用户的订阅数
+func (db *DB) CountUserSubscriptions(userID int64) (int, error) {
+	var count int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM subscriptions WHERE user_id = ?",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
 // GetUserSubscriptions 获取用户的订阅列表
 func (db *DB) GetUserSubscriptions(userID int64) ([]*Source, error) {
 	rows, err := db.Query(`
-		SELECT s.id, s.url, COALESCE(s.title, ''), COALESCE(s.description, ''), 
-		       s.last_fetch_time, s.fetch_interval, s.is_active, s.error_count, 
-		       COALESCE(s.last_error, ''), s.created_at 
+		SELECT `+sourceColumns+`
 		FROM sources s
 		INNER JOIN subscriptions sub ON s.id = sub.source_id
 		WHERE sub.user_id = ?
@@ -366,12 +718,7 @@ func (db *DB) GetUserSubscriptions(userID int64) ([]*Source, error) {
 
 	var sources []*Source
 	for rows.Next() {
-		source := &Source{}
-		err := rows.Scan(
-			&source.ID, &source.URL, &source.Title, &source.Description,
-			&source.LastFetchTime, &source.FetchInterval, &source.IsActive,
-			&source.ErrorCount, &source.LastError, &source.CreatedAt,
-		)
+		source, err := scanSource(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -381,10 +728,10 @@ func (db *DB) GetUserSubscriptions(userID int64) ([]*Source, error) {
 	return sources, rows.Err()
 }
 
-// GetSubscribedUserIDs 获取订阅某个源的所有用户 ID
+// GetSubscribedUserIDs 获取订阅某个源的所有用户 ID（不含已暂停订阅的用户）
 func (db *DB) GetSubscribedUserIDs(sourceID int64) ([]int64, error) {
 	rows, err := db.Query(
-		"SELECT user_id FROM subscriptions WHERE source_id = ?",
+		"SELECT user_id FROM subscriptions WHERE source_id = ? AND COALESCE(is_paused, 0) = 0",
 		sourceID,
 	)
 	if err != nil {
@@ -404,24 +751,23 @@ func (db *DB) GetSubscribedUserIDs(sourceID int64) ([]int64, error) {
 	return userIDs, rows.Err()
 }
 
+// GetUserSourceByID 获取用户订阅的源，未订阅时返回 sql.ErrNoRows
+func (db *DB) GetUserSourceByID(userID, sourceID int64) (*Source, error) {
+	return scanSource(db.QueryRow(`
+		SELECT `+sourceColumns+`
+		FROM sources s
+		INNER JOIN subscriptions sub ON s.id = sub.source_id
+		WHERE sub.user_id = ? AND s.id = ?
+	`, userID, sourceID))
+}
+
 // GetUserSourceByURL 根据 URL 获取用户订阅的源
 func (db *DB) GetUserSourceByURL(userID int64, sourceURL string) (*Source, error) {
-	source := &Source{}
-	err := db.QueryRow(`
-		SELECT s.id, s.url, COALESCE(s.title, ''), COALESCE(s.description, ''), 
-		       s.last_fetch_time, s.fetch_interval, s.is_active, s.error_count, 
-		       COALESCE(s.last_error, ''), s.created_at 
+	return scanSource(db.QueryRow(`
+		SELECT `+sourceColumns+`
 		FROM sources s
 		INNER JOIN subscriptions sub ON s.id = sub.source_id
 		WHERE sub.user_id = ? AND s.url = ?
 		LIMIT 1
-	`, userID, sourceURL).Scan(
-		&source.ID, &source.URL, &source.Title, &source.Description,
-		&source.LastFetchTime, &source.FetchInterval, &source.IsActive,
-		&source.ErrorCount, &source.LastError, &source.CreatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return source, nil
+	`, userID, sourceURL))
 }
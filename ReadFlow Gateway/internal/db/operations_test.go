@@ -0,0 +1,406 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserQuotaOverride(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "alice")
+
+	o, err := database.GetUserQuotaOverride(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserQuotaOverride: %v", err)
+	}
+	if o.MaxSubscriptions != nil || o.MaxVocabulary != nil {
+		t.Fatalf("new user should have no override, got %+v", o)
+	}
+
+	subs := 10
+	if err := database.SetUserQuotaOverride(user.ID, &UserQuotaOverride{MaxSubscriptions: &subs}); err != nil {
+		t.Fatalf("SetUserQuotaOverride: %v", err)
+	}
+	o, err = database.GetUserQuotaOverride(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserQuotaOverride: %v", err)
+	}
+	if o.MaxSubscriptions == nil || *o.MaxSubscriptions != 10 || o.MaxVocabulary != nil {
+		t.Errorf("override = %+v, want max_subscriptions=10 and no vocabulary override", o)
+	}
+
+	if err := database.SetUserQuotaOverride(9999, &UserQuotaOverride{}); err != sql.ErrNoRows {
+		t.Errorf("SetUserQuotaOverride for missing user = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestVocabularyCountsIgnoreDeleted(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	bob := createTestUser(t, database, "bob")
+
+	words := []*Vocabulary{
+		{ID: "a1", UserID: alice.ID, Word: "Apple", UpdatedAt: 1},
+		{ID: "a2", UserID: alice.ID, Word: "banana", UpdatedAt: 1, IsDeleted: true},
+		{ID: "b1", UserID: bob.ID, Word: "cherry", UpdatedAt: 1},
+	}
+	for _, w := range words {
+		if err := database.UpsertVocabulary(w); err != nil {
+			t.Fatalf("UpsertVocabulary: %v", err)
+		}
+	}
+
+	count, err := database.CountUserVocabulary(alice.ID)
+	if err != nil {
+		t.Fatalf("CountUserVocabulary: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("CountUserVocabulary = %d, want 1", count)
+	}
+
+	active, err := database.GetActiveVocabularyIDs(alice.ID, []string{"a1", "a2", "b1", "missing"})
+	if err != nil {
+		t.Fatalf("GetActiveVocabularyIDs: %v", err)
+	}
+	if len(active) != 1 || !active["a1"] {
+		t.Errorf("GetActiveVocabularyIDs = %v, want only a1", active)
+	}
+
+	existing, err := database.GetUserVocabularyWords(alice.ID)
+	if err != nil {
+		t.Fatalf("GetUserVocabularyWords: %v", err)
+	}
+	if len(existing) != 1 || !existing["apple"] {
+		t.Errorf("GetUserVocabularyWords = %v, want only apple", existing)
+	}
+}
+
+func TestUpsertUserPreferencesRejectsStaleWrite(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "alice")
+
+	if err := database.UpsertUserPreferences(&UserPreference{UserID: user.ID, DefaultCategory: "tech"}, nil); err != nil {
+		t.Fatalf("UpsertUserPreferences: %v", err)
+	}
+	first, err := database.GetUserPreferences(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+
+	// 设备 A 基于 first 提交成功，版本号前进
+	if err := database.UpsertUserPreferences(&UserPreference{UserID: user.ID, DefaultCategory: "news"}, &first.UpdatedAt); err != nil {
+		t.Fatalf("UpsertUserPreferences with current version: %v", err)
+	}
+	second, err := database.GetUserPreferences(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if second.UpdatedAt <= first.UpdatedAt {
+		t.Fatalf("updated_at = %d, want > %d", second.UpdatedAt, first.UpdatedAt)
+	}
+
+	// 设备 B 仍基于 first 提交，被拒绝且不覆盖
+	err = database.UpsertUserPreferences(&UserPreference{UserID: user.ID, DefaultCategory: "sports"}, &first.UpdatedAt)
+	if err != ErrStalePreferences {
+		t.Fatalf("stale write err = %v, want ErrStalePreferences", err)
+	}
+	current, err := database.GetUserPreferences(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if current.DefaultCategory != "news" {
+		t.Errorf("DefaultCategory = %q, want news", current.DefaultCategory)
+	}
+
+	// 不带版本（强制覆盖）时直接写入
+	if err := database.UpsertUserPreferences(&UserPreference{UserID: user.ID, DefaultCategory: "sports"}, nil); err != nil {
+		t.Fatalf("forced UpsertUserPreferences: %v", err)
+	}
+	current, err = database.GetUserPreferences(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserPreferences: %v", err)
+	}
+	if current.DefaultCategory != "sports" {
+		t.Errorf("DefaultCategory after force = %q, want sports", current.DefaultCategory)
+	}
+}
+
+func TestDeleteSourceLeavesNoOrphans(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+	other := createTestSource(t, database, "https://example.org/feed.xml")
+
+	if err := database.CreateSubscription(alice.ID, source.ID); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	unique := createTestItem(t, database, source.ID, "g1", "hash-unique", time.Now())
+	shared := createTestItem(t, database, source.ID, "g2", "hash-shared", time.Now())
+	createTestItem(t, database, other.ID, "g3", "hash-shared", time.Now())
+	for _, item := range []*Item{unique, shared} {
+		if err := database.CreateUserDelivery(alice.ID, item.ID); err != nil {
+			t.Fatalf("CreateUserDelivery: %v", err)
+		}
+	}
+
+	for _, stmt := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{"INSERT INTO read_state (user_id, content_hash, is_read) VALUES (?, 'hash-unique', 1), (?, 'hash-shared', 1)", []interface{}{alice.ID, alice.ID}},
+		{"INSERT INTO source_credentials (source_id, auth_type, username) VALUES (?, 'basic', 'u')", []interface{}{source.ID}},
+		{"INSERT INTO filter_rules (id, user_id, keyword) VALUES (1, ?, 'ads')", []interface{}{alice.ID}},
+		{"INSERT INTO filter_bindings (rule_id, user_id, source_id) VALUES (1, ?, ?)", []interface{}{alice.ID, source.ID}},
+	} {
+		if _, err := database.Exec(stmt.query, stmt.args...); err != nil {
+			t.Fatalf("%s: %v", stmt.query, err)
+		}
+	}
+
+	if err := database.DeleteSource(source.ID); err != nil {
+		t.Fatalf("DeleteSource: %v", err)
+	}
+
+	checks := []struct {
+		table, where string
+		args         []interface{}
+	}{
+		{"sources", "id = ?", []interface{}{source.ID}},
+		{"items", "source_id = ?", []interface{}{source.ID}},
+		{"user_deliveries", "item_id IN (?, ?)", []interface{}{unique.ID, shared.ID}},
+		{"subscriptions", "source_id = ?", []interface{}{source.ID}},
+		{"source_credentials", "source_id = ?", []interface{}{source.ID}},
+		{"filter_bindings", "source_id = ?", []interface{}{source.ID}},
+		{"read_state", "content_hash = 'hash-unique'", nil},
+	}
+	for _, c := range checks {
+		if n := countRows(t, database, c.table, c.where, c.args...); n != 0 {
+			t.Errorf("%s has %d orphan rows after DeleteSource", c.table, n)
+		}
+	}
+
+	// 其他源仍有同内容文章时，阅读状态保留
+	if n := countRows(t, database, "read_state", "content_hash = 'hash-shared'"); n != 1 {
+		t.Errorf("shared read_state rows = %d, want 1", n)
+	}
+	if n := countRows(t, database, "items", "source_id = ?", other.ID); n != 1 {
+		t.Errorf("other source items = %d, want 1", n)
+	}
+}
+
+func TestGetVocabulariesSincePages(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+
+	// 7 个词条，其中几条 updated_at 相同，按 id 区分先后
+	for i, updatedAt := range []int64{10, 20, 20, 20, 30, 40, 40} {
+		vocab := &Vocabulary{ID: fmt.Sprintf("w_%d", i), UserID: alice.ID, Word: fmt.Sprintf("word%d", i), UpdatedAt: updatedAt}
+		if err := database.UpsertVocabulary(vocab); err != nil {
+			t.Fatalf("UpsertVocabulary: %v", err)
+		}
+	}
+
+	var got []string
+	var afterUpdatedAt int64
+	var afterID string
+	for page := 0; ; page++ {
+		vocabs, hasMore, err := database.GetVocabulariesSince(alice.ID, 10, afterUpdatedAt, afterID, 2)
+		if err != nil {
+			t.Fatalf("GetVocabulariesSince: %v", err)
+		}
+		if len(vocabs) > 2 {
+			t.Fatalf("page %d has %d words, want at most 2", page, len(vocabs))
+		}
+		for _, v := range vocabs {
+			got = append(got, v.ID)
+		}
+		if !hasMore {
+			break
+		}
+		if page > 5 {
+			t.Fatal("pagination does not terminate")
+		}
+		last := vocabs[len(vocabs)-1]
+		afterUpdatedAt, afterID = last.UpdatedAt, last.ID
+	}
+
+	want := "w_1,w_2,w_3,w_4,w_5,w_6"
+	if strings.Join(got, ",") != want {
+		t.Errorf("paged words = %v, want %s", got, want)
+	}
+
+	// 恰好取满时 hasMore 为 false
+	vocabs, hasMore, err := database.GetVocabulariesSince(alice.ID, 30, 0, "", 2)
+	if err != nil {
+		t.Fatalf("GetVocabulariesSince: %v", err)
+	}
+	if len(vocabs) != 2 || hasMore {
+		t.Errorf("got %d words, hasMore=%v; want 2, false", len(vocabs), hasMore)
+	}
+}
+
+func TestUpsertVocabularyMergeRule(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+
+	upsert := func(notes string, updatedAt int64) {
+		t.Helper()
+		vocab := &Vocabulary{ID: "w_1", UserID: alice.ID, Word: "word", Notes: notes, UpdatedAt: updatedAt}
+		if err := database.UpsertVocabulary(vocab); err != nil {
+			t.Fatalf("UpsertVocabulary: %v", err)
+		}
+	}
+	notes := func() string {
+		t.Helper()
+		vocab, err := database.GetVocabularyByID("w_1")
+		if err != nil {
+			t.Fatalf("GetVocabularyByID: %v", err)
+		}
+		return vocab.Notes
+	}
+
+	upsert("first", 100)
+	// 同一秒内的第二次编辑不丢失
+	upsert("second", 100)
+	if got := notes(); got != "second" {
+		t.Errorf("equal updated_at with different content: notes = %q, want second", got)
+	}
+	// 更旧的写入忽略
+	upsert("stale", 99)
+	if got := notes(); got != "second" {
+		t.Errorf("older updated_at: notes = %q, want second", got)
+	}
+	upsert("third", 101)
+	if got := notes(); got != "third" {
+		t.Errorf("newer updated_at: notes = %q, want third", got)
+	}
+}
+
+func TestUpdateSourceClassification(t *testing.T) {
+	database := newTestDB(t)
+
+	source, err := database.CreateClassifiedSource("https://example.com/feed", "feed", "", 3600, "Technology", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for guid, category := range map[string]string{"inherited": "Technology", "empty": "", "own": "Sports"} {
+		if _, err := database.Exec("INSERT INTO items (source_id, guid, title, xml_content, category) VALUES (?, ?, ?, '', ?)",
+			source.ID, guid, guid, category); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// feed 声明的语言只在未设置时记录
+	if err := database.UpdateSourceLanguageIfUnset(source.ID, "en"); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdateSourceLanguageIfUnset(source.ID, "fr"); err != nil {
+		t.Fatal(err)
+	}
+
+	category, language := "News", "zh"
+	updated, err := database.UpdateSourceClassification(source.ID, &category, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated != 2 {
+		t.Errorf("items updated = %d, want 2", updated)
+	}
+	got, _ := database.GetSourceByID(source.ID)
+	if got.Category != "News" || got.Language != "en" {
+		t.Errorf("after category change: category=%q language=%q", got.Category, got.Language)
+	}
+
+	rows, err := database.Query("SELECT guid, category FROM items WHERE source_id = ?", source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	want := map[string]string{"inherited": "News", "empty": "News", "own": "Sports"}
+	for rows.Next() {
+		var guid, itemCategory string
+		if err := rows.Scan(&guid, &itemCategory); err != nil {
+			t.Fatal(err)
+		}
+		if itemCategory != want[guid] {
+			t.Errorf("item %s category = %q, want %q", guid, itemCategory, want[guid])
+		}
+	}
+
+	if _, err := database.UpdateSourceClassification(source.ID, nil, &language); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = database.GetSourceByID(source.ID)
+	if got.Category != "News" || got.Language != "zh" {
+		t.Errorf("after language change: category=%q language=%q", got.Category, got.Language)
+	}
+}
+
+func TestSetSubscriptionPaused(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	bob := createTestUser(t, database, "bob")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+	for _, user := range []*User{alice, bob} {
+		if err := database.CreateSubscription(user.ID, source.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active := func() bool {
+		t.Helper()
+		got, err := database.GetSourceByID(source.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.IsActive
+	}
+
+	if err := database.SetSubscriptionPaused(alice.ID, source.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	ids, _ := database.GetSubscribedUserIDs(source.ID)
+	if len(ids) != 1 || ids[0] != bob.ID {
+		t.Errorf("subscribed users = %v, want only bob", ids)
+	}
+	if paused, _ := database.GetPausedSubscriptions(alice.ID); !paused[source.ID] {
+		t.Error("alice's subscription not reported as paused")
+	}
+	if !active() {
+		t.Error("source deactivated while bob is still subscribed")
+	}
+
+	// 全部订阅暂停后源停用，恢复后重新激活
+	if err := database.SetSubscriptionPaused(bob.ID, source.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := database.GetSubscriptionCount(source.ID); count != 0 || active() {
+		t.Errorf("all paused: count=%d active=%v, want 0 and inactive", count, active())
+	}
+	if err := database.SetSubscriptionPaused(alice.ID, source.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if !active() {
+		t.Error("source not reactivated on resume")
+	}
+
+	// 因连续抓取失败停用的源恢复订阅后保持停用
+	for i := 0; i < 3; i++ {
+		database.UpdateSourceError(source.ID, "boom")
+	}
+	if err := database.SetSubscriptionPaused(bob.ID, source.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if active() {
+		t.Error("failing source reactivated on resume")
+	}
+
+	other := createTestSource(t, database, "https://example.org/feed.xml")
+	if err := database.SetSubscriptionPaused(alice.ID, other.ID, true); !errors.Is(err, ErrNotSubscribed) {
+		t.Errorf("unsubscribed source: err = %v, want ErrNotSubscribed", err)
+	}
+}
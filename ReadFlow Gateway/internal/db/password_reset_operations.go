@@ -0,0 +1,63 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrInvalidPasswordReset 重置令牌不存在、已使用或已过期
+var ErrInvalidPasswordReset = errors.New("invalid or expired password reset token")
+
+// PasswordReset 相关操作（令牌只保存哈希，调用方负责生成和哈希）
+
+// CreatePasswordReset 为用户创建重置令牌
+// 同一用户只保留最新的令牌，之前未使用的令牌随之失效；顺带清理所有已过期的令牌
+func (db *DB) CreatePasswordReset(userID int64, tokenHash string, expiresAt time.Time) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id = ? OR expires_at <= ?", userID, time.Now().Unix()); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES (?, ?, ?)",
+		userID, tokenHash, expiresAt.Unix(),
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ResetPasswordWithToken 使用重置令牌设置新的密码哈希，返回用户 ID
+// 令牌无效时返回 ErrInvalidPasswordReset；成功后删除该用户的全部重置令牌，令牌只能使用一次
+func (db *DB) ResetPasswordWithToken(tokenHash, passwordHash string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	err = tx.QueryRow(
+		"SELECT user_id FROM password_resets WHERE token_hash = ? AND expires_at > ?",
+		tokenHash, time.Now().Unix(),
+	).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidPasswordReset
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("UPDATE users SET password_hash = ? WHERE id = ?", passwordHash, userID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id = ?", userID); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
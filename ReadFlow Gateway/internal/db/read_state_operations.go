@@ -0,0 +1,78 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// ReadState 相关操作
+// read_state 以 (user_id, content_hash) 为键，与 user_deliveries 同步写入，
+// 文章被清理后以新的 item_id 重新入库时，可据此恢复阅读进度和已读状态
+
+// execer 同时兼容 *DB 和 *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertDeliverySQL 创建投递记录，并从 read_state 恢复已读状态和阅读进度
+// 同时复制文章的 published_at，供文章列表走 idx_deliveries_user_published 排序
+// 参数：userID, userID, itemID
+const insertDeliverySQL = `
+	INSERT OR IGNORE INTO user_deliveries (user_id, item_id, status, read_progress, read_at, published_at)
+	SELECT ?, i.id,
+	       CASE WHEN COALESCE(rs.is_read, 0) = 1 THEN 2 ELSE 0 END,
+	       COALESCE(rs.read_progress, 0), rs.read_at, i.published_at
+	FROM items i
+	LEFT JOIN read_state rs ON rs.user_id = ? AND rs.content_hash = i.content_hash
+	WHERE i.id = ?
+`
+
+// saveReadStatus 按文章内容哈希记录已读 / 未读状态
+// 无内容哈希的文章、以及用户没有投递记录的文章忽略
+func saveReadStatus(ex execer, userID, itemID int64, isRead bool, now time.Time) error {
+	var readAt *time.Time
+	if isRead {
+		readAt = &now
+	}
+	_, err := ex.Exec(`
+		INSERT INTO read_state (user_id, content_hash, is_read, read_at, updated_at)
+		SELECT ud.user_id, i.content_hash, ?, ?, ?
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		WHERE ud.user_id = ? AND ud.item_id = ? AND COALESCE(i.content_hash, '') != ''
+		ON CONFLICT(user_id, content_hash) DO UPDATE SET
+			is_read = excluded.is_read,
+			read_at = CASE WHEN excluded.is_read THEN COALESCE(read_state.read_at, excluded.read_at) ELSE NULL END,
+			updated_at = excluded.updated_at
+	`, isRead, readAt, now, userID, itemID)
+	return err
+}
+
+// saveReadProgress 按文章内容哈希记录阅读进度（同样只记录用户有投递的文章）
+func saveReadProgress(ex execer, userID, itemID int64, progress int, now time.Time) error {
+	_, err := ex.Exec(`
+		INSERT INTO read_state (user_id, content_hash, read_progress, updated_at)
+		SELECT ud.user_id, i.content_hash, ?, ?
+		FROM user_deliveries ud
+		INNER JOIN items i ON ud.item_id = i.id
+		WHERE ud.user_id = ? AND ud.item_id = ? AND COALESCE(i.content_hash, '') != ''
+		ON CONFLICT(user_id, content_hash) DO UPDATE SET
+			read_progress = excluded.read_progress,
+			updated_at = excluded.updated_at
+	`, progress, now, userID, itemID)
+	return err
+}
+
+// PruneReadState 删除 before 之前更新、且当前没有任何文章使用其内容哈希的阅读状态
+// 文章清理后通常很快会以新 ID 重新入库，超过保留期仍未出现的内容不会再回来；返回删除的行数
+func (db *DB) PruneReadState(before time.Time) (int64, error) {
+	result, err := db.Exec(`
+		DELETE FROM read_state
+		WHERE updated_at < ?
+		  AND NOT EXISTS (SELECT 1 FROM items i WHERE i.content_hash = read_state.content_hash)
+	`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
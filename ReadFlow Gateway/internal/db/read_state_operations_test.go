@@ -0,0 +1,114 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadStateSurvivesReingestion(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "alice")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+	published := time.Now().Add(-time.Hour)
+
+	original := createTestItem(t, database, source.ID, "guid-1", "hash-1", published)
+	if err := database.CreateUserDelivery(user.ID, original.ID); err != nil {
+		t.Fatalf("CreateUserDelivery: %v", err)
+	}
+	if err := database.MarkArticleAsRead(user.ID, original.ID); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+	if err := database.UpdateReadProgress(user.ID, original.ID, 40); err != nil {
+		t.Fatalf("UpdateReadProgress: %v", err)
+	}
+
+	// 文章被清理后，同样的内容以新 ID 重新入库
+	if err := database.DeleteItem(original.ID); err != nil {
+		t.Fatalf("DeleteItem: %v", err)
+	}
+	reingested := createTestItem(t, database, source.ID, "guid-1", "hash-1", published)
+	if reingested.ID == original.ID {
+		t.Fatalf("re-ingested item reused id %d", original.ID)
+	}
+	if err := database.CreateUserDelivery(user.ID, reingested.ID); err != nil {
+		t.Fatalf("CreateUserDelivery: %v", err)
+	}
+
+	article, err := database.GetUserArticle(user.ID, reingested.ID)
+	if err != nil {
+		t.Fatalf("GetUserArticle: %v", err)
+	}
+	if article.Status != 2 {
+		t.Errorf("status = %d, want 2 (read)", article.Status)
+	}
+	if article.ReadProgress != 40 {
+		t.Errorf("read_progress = %d, want 40", article.ReadProgress)
+	}
+}
+
+func TestReadStateRequiresDelivery(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	bob := createTestUser(t, database, "bob")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+	item := createTestItem(t, database, source.ID, "guid-1", "hash-1", time.Now())
+	if err := database.CreateUserDelivery(alice.ID, item.ID); err != nil {
+		t.Fatalf("CreateUserDelivery: %v", err)
+	}
+
+	// bob 没有该文章的投递，不应留下阅读状态
+	if err := database.MarkArticleAsRead(bob.ID, item.ID); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+	if err := database.UpdateReadProgress(bob.ID, item.ID, 50); err != nil {
+		t.Fatalf("UpdateReadProgress: %v", err)
+	}
+	if n := countRows(t, database, "read_state", "user_id = ?", bob.ID); n != 0 {
+		t.Errorf("read_state rows for user without delivery = %d, want 0", n)
+	}
+
+	if err := database.MarkArticleAsRead(alice.ID, item.ID); err != nil {
+		t.Fatalf("MarkArticleAsRead: %v", err)
+	}
+	if n := countRows(t, database, "read_state", "user_id = ?", alice.ID); n != 1 {
+		t.Errorf("read_state rows for delivered user = %d, want 1", n)
+	}
+}
+
+func TestPruneReadState(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "alice")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+	createTestItem(t, database, source.ID, "guid-live", "hash-live", time.Now())
+
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	recent := time.Now().Add(-time.Hour)
+	for _, row := range []struct {
+		hash      string
+		updatedAt time.Time
+	}{
+		{"hash-live", old},     // 文章仍在，保留
+		{"hash-gone-old", old}, // 文章已不在且过期，清理
+		{"hash-gone-new", recent},
+	} {
+		if _, err := database.Exec(
+			"INSERT INTO read_state (user_id, content_hash, is_read, updated_at) VALUES (?, ?, 1, ?)",
+			user.ID, row.hash, row.updatedAt); err != nil {
+			t.Fatalf("insert read_state: %v", err)
+		}
+	}
+
+	pruned, err := database.PruneReadState(time.Now().Add(-90 * 24 * time.Hour))
+	if err != nil {
+		t.Fatalf("PruneReadState: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("pruned = %d, want 1", pruned)
+	}
+	if n := countRows(t, database, "read_state", "content_hash = ?", "hash-gone-old"); n != 0 {
+		t.Error("expired orphaned read_state row was not pruned")
+	}
+	if n := countRows(t, database, "read_state", "content_hash IN ('hash-live', 'hash-gone-new')"); n != 2 {
+		t.Errorf("kept rows = %d, want 2", n)
+	}
+}
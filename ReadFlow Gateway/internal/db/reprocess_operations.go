@@ -0,0 +1,119 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// ListItemsForReprocess 按 ID 升序返回 afterID 之后的文章，用于重建派生字段
+// sourceID 为 0 时包含所有源；只读取重建需要的字段
+func (db *DB) ListItemsForReprocess(sourceID, afterID int64, limit int) ([]*Item, error) {
+	rows, err := db.Query(`
+		SELECT id, source_id, title, COALESCE(xml_content, ''),
+		       COALESCE(summary, ''), COALESCE(difficulty, ''), COALESCE(word_count, 0), COALESCE(reading_time, 0),
+		       COALESCE(cover_image, ''), COALESCE(clean_content, ''), COALESCE(content, ''), COALESCE(content_hash, ''),
+		       COALESCE(image_caption, ''), COALESCE(image_credit, ''), COALESCE(image_primary_color, ''),
+		       COALESCE(image_blurhash, ''), COALESCE(cover_variants, ''), COALESCE(tags, ''), COALESCE(category, '')
+		FROM items
+		WHERE id > ? AND (? = 0 OR source_id = ?)
+		ORDER BY id
+		LIMIT ?
+	`, afterID, sourceID, sourceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*Item
+	for rows.Next() {
+		item := &Item{}
+		if err := rows.Scan(
+			&item.ID, &item.SourceID, &item.Title, &item.XMLContent,
+			&item.Summary, &item.Difficulty, &item.WordCount, &item.ReadingTime,
+			&item.CoverImage, &item.CleanContent, &item.Content, &item.ContentHash,
+			&item.ImageCaption, &item.ImageCredit, &item.ImagePrimaryColor,
+			&item.ImageBlurhash, &item.CoverVariants, &item.Tags, &item.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// CountItemsAfter 统计 afterID 之后的文章数（sourceID 为 0 时包含所有源），用于报告重建进度
+func (db *DB) CountItemsAfter(sourceID, afterID int64) (int64, error) {
+	var count int64
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM items WHERE id > ? AND (? = 0 OR source_id = ?)
+	`, afterID, sourceID, sourceID).Scan(&count)
+	return count, err
+}
+
+// ListItemImagesForReprocess 按 ID 升序返回源中 afterID 之后有缓存图片的文章，用于重新压缩缓存图片
+// 只读取图片路径、封面地址和原文（原文中的图片地址用于找回没有附属记录的图片的原图）
+func (db *DB) ListItemImagesForReprocess(sourceID, afterID int64, limit int) ([]*Item, error) {
+	rows, err := db.Query(`
+		SELECT id, source_id, image_paths, COALESCE(cover_image, ''), COALESCE(content, '')
+		FROM items
+		WHERE source_id = ? AND id > ? AND COALESCE(image_paths, '') NOT IN ('', '[]')
+		ORDER BY id
+		LIMIT ?
+	`, sourceID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*Item
+	for rows.Next() {
+		item := &Item{}
+		if err := rows.Scan(&item.ID, &item.SourceID, &item.ImagePaths, &item.CoverImage, &item.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// CountItemImagesAfter 统计源中 afterID 之后有缓存图片的文章数，用于报告重新压缩的进度
+func (db *DB) CountItemImagesAfter(sourceID, afterID int64) (int64, error) {
+	var count int64
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM items
+		WHERE source_id = ? AND id > ? AND COALESCE(image_paths, '') NOT IN ('', '[]')
+	`, sourceID, afterID).Scan(&count)
+	return count, err
+}
+
+// UpdateItemDerived 写入重新计算的派生字段（摘要、字数、阅读时间、难度、封面和标签）
+// 只在内容哈希仍为 item.ContentHash 时更新，避免覆盖重建期间抓取到的新内容；返回是否实际更新
+// 同时刷新投递记录的 updated_at，增量同步的客户端会重新拉取该文章
+func (db *DB) UpdateItemDerived(item *Item) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE items SET
+			summary = ?, word_count = ?, reading_time = ?, difficulty = ?,
+			cover_image = ?, image_caption = ?, image_credit = ?, image_primary_color = ?, image_blurhash = ?,
+			cover_variants = ?, tags = ?
+		WHERE id = ? AND COALESCE(content_hash, '') = ?
+	`, item.Summary, item.WordCount, item.ReadingTime, item.Difficulty,
+		item.CoverImage, item.ImageCaption, item.ImageCredit, item.ImagePrimaryColor, item.ImageBlurhash,
+		item.CoverVariants, item.Tags, item.ID, item.ContentHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to update derived fields: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec("UPDATE user_deliveries SET updated_at = ? WHERE item_id = ?", time.Now(), item.ID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -10,11 +10,15 @@ CREATE TABLE IF NOT EXISTS users (
     password_hash TEXT,
     token TEXT UNIQUE,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-    last_login_at DATETIME
+    last_login_at DATETIME,
+    is_admin BOOLEAN DEFAULT 0,
+    max_subscriptions INTEGER,
+    max_vocabulary INTEGER
 );
 
 CREATE INDEX IF NOT EXISTS idx_users_token ON users(token);
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+CREATE INDEX IF NOT EXISTS idx_users_last_login ON users(last_login_at DESC);
 
 -- 订阅源表（全局共享）
 CREATE TABLE IF NOT EXISTS sources (
@@ -31,15 +35,71 @@ CREATE TABLE IF NOT EXISTS sources (
     -- 扩展字段
     content_type TEXT DEFAULT 'image_text',
     language TEXT DEFAULT 'en',
-    category TEXT DEFAULT 'Technology',
+    category TEXT,
     favicon TEXT,
     article_count INTEGER DEFAULT 0,
-    update_frequency INTEGER DEFAULT 3600
+    update_frequency INTEGER DEFAULT 3600,
+    image_mode TEXT DEFAULT 'process',
+    last_item_added_at DATETIME,
+    proxy_url TEXT,
+    retention_seconds INTEGER,
+    content_update_mode TEXT DEFAULT 'off',
+    favicon_updated_at DATETIME,
+    summary_length INTEGER,
+    gallery_enabled BOOLEAN DEFAULT 0,
+    full_content BOOLEAN DEFAULT 0,
+    min_word_count INTEGER DEFAULT 0,
+    lenient_parse BOOLEAN DEFAULT 0,
+    body_images_only BOOLEAN DEFAULT 0,
+    max_items INTEGER DEFAULT 0,
+    last_parse_note TEXT,
+    http_etag TEXT,
+    http_last_modified TEXT,
+    feed_title TEXT
 );
 
 CREATE INDEX IF NOT EXISTS idx_sources_url ON sources(url);
 CREATE INDEX IF NOT EXISTS idx_sources_active_fetch ON sources(is_active, last_fetch_time);
 
+-- 订阅源凭据表（私有源认证信息，secret 加密存储）
+CREATE TABLE IF NOT EXISTS source_credentials (
+    source_id INTEGER PRIMARY KEY,
+    auth_type TEXT NOT NULL DEFAULT 'basic',
+    username TEXT,
+    secret_encrypted TEXT,
+    param_name TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+);
+
+-- 密码重置令牌表（只保存令牌的 SHA-256 哈希，expires_at 为 Unix 时间戳；使用后即删除）
+CREATE TABLE IF NOT EXISTS password_resets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id);
+
+-- 用户 API 令牌表（供脚本等长期使用，只保存哈希）
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    token_hash TEXT NOT NULL UNIQUE,
+    prefix TEXT NOT NULL,
+    scope TEXT NOT NULL DEFAULT 'write',
+    last_used_at INTEGER,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+
 -- 用户订阅关系表（用户专属配置）
 CREATE TABLE IF NOT EXISTS subscriptions (
     user_id INTEGER NOT NULL,
@@ -52,6 +112,7 @@ CREATE TABLE IF NOT EXISTS subscriptions (
     max_articles INTEGER DEFAULT 20,
     unread_count INTEGER DEFAULT 0,
     custom_title TEXT,
+    is_paused BOOLEAN DEFAULT 0, -- 暂停后不再投递新文章，也不计入维持源活跃的订阅数
     PRIMARY KEY (user_id, source_id),
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
     FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
@@ -89,6 +150,15 @@ CREATE TABLE IF NOT EXISTS items (
     image_caption TEXT,
     image_credit TEXT,
     image_primary_color TEXT,
+    is_truncated BOOLEAN DEFAULT 0,
+    gallery TEXT,
+    image_blurhash TEXT,
+    cover_variants TEXT,
+    -- 播客（iTunes 命名空间）字段
+    itunes_duration TEXT,
+    itunes_image TEXT,
+    itunes_episode TEXT,
+    itunes_author TEXT,
     FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
 );
 
@@ -113,6 +183,8 @@ CREATE TABLE IF NOT EXISTS user_deliveries (
     scroll_position INTEGER DEFAULT 0,
     user_tags TEXT,
     reading_time_spent INTEGER DEFAULT 0,
+    -- 冗余 items.published_at，文章列表按 (user_id, published_at, item_id) 索引顺序分页
+    published_at DATETIME,
     PRIMARY KEY (user_id, item_id),
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
     FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE
@@ -123,6 +195,32 @@ CREATE INDEX IF NOT EXISTS idx_deliveries_item ON user_deliveries(item_id);
 CREATE INDEX IF NOT EXISTS idx_deliveries_user_status_item ON user_deliveries(user_id, status, item_id);
 CREATE INDEX IF NOT EXISTS idx_deliveries_favorite ON user_deliveries(user_id, is_favorite);
 CREATE INDEX IF NOT EXISTS idx_deliveries_updated ON user_deliveries(updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_deliveries_in_progress ON user_deliveries(user_id, updated_at DESC) WHERE read_progress > 0 AND read_progress < 100;
+
+-- 按内容哈希保存的阅读状态（文章被清理后以新 ID 重新入库时仍可恢复）
+CREATE TABLE IF NOT EXISTS read_state (
+    user_id INTEGER NOT NULL,
+    content_hash TEXT NOT NULL,
+    is_read BOOLEAN DEFAULT 0,
+    read_progress INTEGER DEFAULT 0,
+    read_at DATETIME,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, content_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 已删除文章的墓碑：确认后立即清理或超出条数上限被删除的文章仍在 feed 中时，抓取按墓碑跳过，不重新入库
+CREATE TABLE IF NOT EXISTS item_tombstones (
+    source_id INTEGER NOT NULL,
+    guid TEXT NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    deleted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (source_id, guid),
+    FOREIGN KEY (source_id) REFERENCES sources(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_tombstones_url ON item_tombstones(source_id, url);
 
 -- 生词本表（完整版，已包含所有字段）
 CREATE TABLE IF NOT EXISTS vocabularies (
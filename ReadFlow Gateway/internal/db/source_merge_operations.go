@@ -0,0 +1,165 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SourceMergeResult 合并订阅源的结果统计
+type SourceMergeResult struct {
+	MergedSources       int   `json:"merged_sources"`       // 删除的重复源数
+	MovedItems          int64 `json:"moved_items"`          // 移到保留源的文章数
+	DroppedItems        int64 `json:"dropped_items"`        // 与保留源 guid 重复而删除的文章数
+	MovedDeliveries     int64 `json:"moved_deliveries"`     // 从重复文章改指向保留文章的投递记录数
+	MovedSubscriptions  int64 `json:"moved_subscriptions"`  // 改为订阅保留源的订阅数
+	MergedSubscriptions int64 `json:"merged_subscriptions"` // 用户已订阅保留源、直接删除的订阅数
+
+	// DroppedImagePaths 被删除文章的图片路径（JSON 数组字符串），事务提交后由调用方删除文件
+	DroppedImagePaths []string `json:"-"`
+}
+
+// MergeSources 把 mergeIDs 中的源合并到 keepID，在一个事务中完成：
+// guid 与保留源重复的文章保留已有文章、删除重复文章（投递记录改指向保留文章，用户已有投递时保留已有的），
+// 其余文章、订阅（含过滤规则绑定）和凭据移到保留源，最后删除被合并的源。
+// 任一源不存在时返回 sql.ErrNoRows
+func (db *DB) MergeSources(keepID int64, mergeIDs []int64) (*SourceMergeResult, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 订阅的 source_id 被过滤规则绑定引用，两者依次改指向保留源，外键在提交时再检查
+	if _, err := tx.Exec("PRAGMA defer_foreign_keys = ON"); err != nil {
+		return nil, err
+	}
+
+	if err := sourceExistsTx(tx, keepID); err != nil {
+		return nil, err
+	}
+
+	result := &SourceMergeResult{}
+	for _, mergeID := range mergeIDs {
+		if err := sourceExistsTx(tx, mergeID); err != nil {
+			return nil, err
+		}
+		if err := mergeSourceTx(tx, keepID, mergeID, result); err != nil {
+			return nil, fmt.Errorf("merge source %d: %w", mergeID, err)
+		}
+		result.MergedSources++
+	}
+
+	// 保留源的最近新增时间按合并后的文章重新计算
+	if _, err := tx.Exec(
+		"UPDATE sources SET last_item_added_at = COALESCE((SELECT MAX(created_at) FROM items WHERE source_id = ?), last_item_added_at) WHERE id = ?",
+		keepID, keepID,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// sourceExistsTx 检查源是否存在，不存在时返回 sql.ErrNoRows
+func sourceExistsTx(tx *sql.Tx, sourceID int64) error {
+	var id int64
+	return tx.QueryRow("SELECT id FROM sources WHERE id = ?", sourceID).Scan(&id)
+}
+
+// mergeSourceTx 在事务中把单个源合并到保留源
+func mergeSourceTx(tx *sql.Tx, keepID, mergeID int64, result *SourceMergeResult) error {
+	// 1. guid 重复的文章：投递记录改指向保留源的同 guid 文章，冲突（用户两篇都有投递）的保留已有记录
+	res, err := tx.Exec(`
+		UPDATE OR IGNORE user_deliveries
+		SET (item_id, published_at) = (
+			SELECT k.id, k.published_at FROM items k JOIN items m ON m.guid = k.guid
+			WHERE m.id = user_deliveries.item_id AND k.source_id = ?
+		)
+		WHERE item_id IN (
+			SELECT m.id FROM items m JOIN items k ON k.guid = m.guid AND k.source_id = ?
+			WHERE m.source_id = ?
+		)
+	`, keepID, keepID, mergeID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.MovedDeliveries += n
+	}
+
+	// 2. 删除重复文章（剩余投递记录随之级联删除），先记下图片路径
+	const duplicates = `SELECT m.id FROM items m JOIN items k ON k.guid = m.guid AND k.source_id = ? WHERE m.source_id = ?`
+	rows, err := tx.Query(`
+		SELECT image_paths FROM items
+		WHERE id IN (`+duplicates+`) AND image_paths IS NOT NULL AND image_paths != '' AND image_paths != '[]'
+	`, keepID, mergeID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var paths string
+		if err := rows.Scan(&paths); err != nil {
+			rows.Close()
+			return err
+		}
+		result.DroppedImagePaths = append(result.DroppedImagePaths, paths)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	res, err = tx.Exec("DELETE FROM items WHERE id IN ("+duplicates+")", keepID, mergeID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.DroppedItems += n
+	}
+
+	// 3. 其余文章移到保留源
+	res, err = tx.Exec("UPDATE items SET source_id = ? WHERE source_id = ?", keepID, mergeID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.MovedItems += n
+	}
+	// 已删除文章的墓碑同样移到保留源，保留源已有同 guid 墓碑的随源删除
+	if _, err := tx.Exec("UPDATE OR IGNORE item_tombstones SET source_id = ? WHERE source_id = ?", keepID, mergeID); err != nil {
+		return err
+	}
+
+	// 4. 订阅和过滤规则绑定移到保留源；用户已订阅保留源的保留已有订阅，剩余记录随源删除
+	res, err = tx.Exec("UPDATE OR IGNORE subscriptions SET source_id = ? WHERE source_id = ?", keepID, mergeID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.MovedSubscriptions += n
+	}
+	var remaining int64
+	if err := tx.QueryRow("SELECT COUNT(*) FROM subscriptions WHERE source_id = ?", mergeID).Scan(&remaining); err != nil {
+		return err
+	}
+	result.MergedSubscriptions += remaining
+
+	if _, err := tx.Exec("UPDATE OR IGNORE filter_bindings SET source_id = ? WHERE source_id = ?", keepID, mergeID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM filter_bindings WHERE source_id = ?", mergeID); err != nil {
+		return err
+	}
+
+	// 5. 保留源没有凭据时沿用被合并源的凭据
+	if _, err := tx.Exec("UPDATE OR IGNORE source_credentials SET source_id = ? WHERE source_id = ?", keepID, mergeID); err != nil {
+		return err
+	}
+
+	// 6. 删除被合并的源（剩余订阅和凭据级联删除）
+	_, err = tx.Exec("DELETE FROM sources WHERE id = ?", mergeID)
+	return err
+}
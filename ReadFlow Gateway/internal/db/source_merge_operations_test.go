@@ -0,0 +1,77 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeSources(t *testing.T) {
+	database := newTestDB(t)
+	alice := createTestUser(t, database, "alice")
+	bob := createTestUser(t, database, "bob")
+	keep := createTestSource(t, database, "https://example.com/feed")
+	dup := createTestSource(t, database, "https://example.com/feed/")
+
+	now := time.Now()
+	kept := createTestItem(t, database, keep.ID, "shared", "h1", now)
+	dupShared := createTestItem(t, database, dup.ID, "shared", "h1", now)
+	dupOnly := createTestItem(t, database, dup.ID, "only-dup", "h2", now)
+	if _, err := database.Exec("UPDATE items SET image_paths = ? WHERE id = ?", `["images/2/a.webp"]`, dupShared.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// alice 两个源都订阅，bob 只订阅了重复源并绑定了过滤规则
+	for _, sub := range []struct{ user, source int64 }{{alice.ID, keep.ID}, {alice.ID, dup.ID}, {bob.ID, dup.ID}} {
+		if err := database.CreateSubscription(sub.user, sub.source); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := database.Exec("INSERT INTO filter_rules (id, user_id, keyword) VALUES (1, ?, 'ads')", bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec("INSERT INTO filter_bindings (rule_id, user_id, source_id) VALUES (1, ?, ?)", bob.ID, dup.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []struct{ user, item int64 }{{alice.ID, kept.ID}, {alice.ID, dupShared.ID}, {bob.ID, dupShared.ID}, {bob.ID, dupOnly.ID}} {
+		if err := database.CreateUserDelivery(d.user, d.item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := database.MergeSources(keep.ID, []int64{dup.ID})
+	if err != nil {
+		t.Fatalf("MergeSources: %v", err)
+	}
+	want := SourceMergeResult{MergedSources: 1, MovedItems: 1, DroppedItems: 1, MovedDeliveries: 1, MovedSubscriptions: 1, MergedSubscriptions: 1,
+		DroppedImagePaths: []string{`["images/2/a.webp"]`}}
+	if !reflect.DeepEqual(*result, want) {
+		t.Errorf("result = %+v, want %+v", *result, want)
+	}
+
+	if _, err := database.GetSourceByID(dup.ID); err == nil {
+		t.Errorf("merged source still exists")
+	}
+	if n := countRows(t, database, "items", "source_id = ?", keep.ID); n != 2 {
+		t.Errorf("items of kept source = %d, want 2", n)
+	}
+	if n := countRows(t, database, "subscriptions", "source_id = ?", keep.ID); n != 2 {
+		t.Errorf("subscriptions of kept source = %d, want 2", n)
+	}
+	if n := countRows(t, database, "filter_bindings", "user_id = ? AND source_id = ?", bob.ID, keep.ID); n != 1 {
+		t.Errorf("filter binding not moved")
+	}
+	// bob 的重复文章投递改指向保留文章；alice 已有保留文章的投递，不重复
+	if n := countRows(t, database, "user_deliveries", "item_id = ?", kept.ID); n != 2 {
+		t.Errorf("deliveries of kept item = %d, want 2", n)
+	}
+	if n := countRows(t, database, "user_deliveries", "user_id = ? AND item_id = ?", bob.ID, dupOnly.ID); n != 1 {
+		t.Errorf("delivery of moved item lost")
+	}
+
+	if _, err := database.MergeSources(keep.ID, []int64{9999}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("merge unknown source = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,61 @@
+package db
+
+import "time"
+
+// ItemTombstone 相关操作
+// 文章在确认后立即清理、或超出源的条数上限被删除时记录墓碑（source_id、guid、规范化链接），
+// 抓取时命中墓碑的条目视为已处理过，避免仍在 feed 中的文章以新 ID 重新入库并再次投递
+
+// insertTombstoneSQL 按文章记录墓碑，已有墓碑时刷新链接和最近出现时间
+// 参数：now, now, itemID
+const insertTombstoneSQL = `
+	INSERT INTO item_tombstones (source_id, guid, url, deleted_at, last_seen_at)
+	SELECT source_id, guid, COALESCE(url, ''), ?, ? FROM items WHERE id = ?
+	ON CONFLICT(source_id, guid) DO UPDATE SET
+		url = excluded.url,
+		last_seen_at = excluded.last_seen_at
+`
+
+// DeleteItemWithTombstone 与 DeleteItem 相同，但删除前记录墓碑，文章仍在 feed 中时不会重新入库
+func (db *DB) DeleteItemWithTombstone(itemID int64) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.Exec(insertTombstoneSQL, now, now, itemID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM user_deliveries WHERE item_id = ?", itemID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM items WHERE id = ?", itemID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MatchItemTombstone 判断源中是否有该 GUID（url 不为空时也按规范化链接）的墓碑
+// 命中时刷新 last_seen_at，仍在 feed 中的文章的墓碑不会被 PruneItemTombstones 清理
+func (db *DB) MatchItemTombstone(sourceID int64, guid, url string) (bool, error) {
+	result, err := db.Exec(`
+		UPDATE item_tombstones SET last_seen_at = ?
+		WHERE source_id = ? AND (guid = ? OR (? != '' AND url = ?))
+	`, time.Now(), sourceID, guid, url, url)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// PruneItemTombstones 删除 before 之后没有再出现在 feed 中的墓碑，返回删除的行数
+func (db *DB) PruneItemTombstones(before time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM item_tombstones WHERE last_seen_at < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestItemTombstones(t *testing.T) {
+	database := newTestDB(t)
+	user := createTestUser(t, database, "alice")
+	source := createTestSource(t, database, "https://example.com/feed.xml")
+	other := createTestSource(t, database, "https://example.com/other.xml")
+
+	item := createTestItem(t, database, source.ID, "guid-1", "hash-1", time.Now())
+	if _, err := database.Exec("UPDATE items SET url = ? WHERE id = ?", "https://example.com/a", item.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.CreateUserDelivery(user.ID, item.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := database.DeleteItemWithTombstone(item.ID); err != nil {
+		t.Fatalf("DeleteItemWithTombstone: %v", err)
+	}
+	if n := countRows(t, database, "items", "id = ?", item.ID); n != 0 {
+		t.Error("item not deleted")
+	}
+	if n := countRows(t, database, "user_deliveries", "item_id = ?", item.ID); n != 0 {
+		t.Error("deliveries not deleted")
+	}
+
+	for name, tc := range map[string]struct {
+		sourceID  int64
+		guid, url string
+		want      bool
+	}{
+		"same guid":    {source.ID, "guid-1", "", true},
+		"same url":     {source.ID, "https://example.com/a", "https://example.com/a", true},
+		"url unused":   {source.ID, "https://example.com/a", "", false},
+		"other guid":   {source.ID, "guid-2", "https://example.com/b", false},
+		"other source": {other.ID, "guid-1", "https://example.com/a", false},
+	} {
+		got, err := database.MatchItemTombstone(tc.sourceID, tc.guid, tc.url)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: MatchItemTombstone = %v, %v; want %v", name, got, err, tc.want)
+		}
+	}
+
+	// 仍在 feed 中（最近命中过）的墓碑不清理，长期未出现的清理
+	if pruned, err := database.PruneItemTombstones(time.Now().Add(-time.Hour)); err != nil || pruned != 0 {
+		t.Errorf("PruneItemTombstones(recent) = %d, %v; want 0", pruned, err)
+	}
+	if pruned, err := database.PruneItemTombstones(time.Now().Add(time.Hour)); err != nil || pruned != 1 {
+		t.Errorf("PruneItemTombstones(future) = %d, %v; want 1", pruned, err)
+	}
+
+	// 清空源时墓碑一并删除，重新抓取时文章重新入库
+	again := createTestItem(t, database, source.ID, "guid-1", "hash-1", time.Now())
+	if err := database.DeleteItemWithTombstone(again.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.PurgeSourceItems(source.ID); err != nil {
+		t.Fatalf("PurgeSourceItems: %v", err)
+	}
+	if n := countRows(t, database, "item_tombstones", "source_id = ?", source.ID); n != 0 {
+		t.Errorf("tombstones after purge = %d, want 0", n)
+	}
+}
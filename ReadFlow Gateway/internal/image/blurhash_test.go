@@ -0,0 +1,43 @@
+package image
+
+import (
+	goimage "image"
+	"image/color"
+	"testing"
+)
+
+func TestEncodeBlurhashSolidColor(t *testing.T) {
+	img := goimage.NewRGBA(goimage.Rect(0, 0, 32, 24))
+	for y := 0; y < 24; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	// 尺寸标记 L（4x3），直流分量 TI:j 即 #ff0000；离散余弦求和使奇数分量不为零，与参考实现一致
+	want := "LDTI:j]9fQ]9|co1fQo1fQfQfQfQ"
+	if got := encodeBlurhash(img, 4, 3); got != want {
+		t.Errorf("encodeBlurhash = %q, want %q", got, want)
+	}
+}
+
+func TestEncodeBlurhashGradient(t *testing.T) {
+	img := goimage.NewRGBA(goimage.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			v := uint8(x * 255 / 31)
+			img.Set(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+
+	hash := encodeBlurhash(img, blurhashXComponents, blurhashYComponents)
+	if len(hash) != 28 {
+		t.Fatalf("len(hash) = %d, want 28", len(hash))
+	}
+	if hash[1] == '0' {
+		t.Errorf("gradient hash %q has zero AC maximum", hash)
+	}
+	if encodeBlurhash(goimage.NewRGBA(goimage.Rect(0, 0, 0, 0)), 4, 3) != "" {
+		t.Error("empty image should produce empty hash")
+	}
+}
@@ -0,0 +1,95 @@
+package image
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// CoverVariant 封面缩略图
+type CoverVariant struct {
+	Path  string // 本地路径（/static/images/{sourceID}/{hash}-{width}w.webp）
+	Width int
+}
+
+// CoverResult 封面图处理结果
+type CoverResult struct {
+	PrimaryColor string
+	Blurhash     string
+	Variants     []CoverVariant // 按 widths 的顺序，跳过的尺寸不包含在内
+}
+
+// ProcessCover 下载封面图并提取主色调（withBlurhash 为 true 时同时生成 BlurHash），再按 widths 生成 WebP 缩略图
+// 全部处理共用一次下载；单个缩略图失败只记录日志，不影响其余结果
+func (p *Processor) ProcessCover(sourceID int64, url string, withBlurhash bool, widths []int) (*CoverResult, error) {
+	result := &CoverResult{}
+	if url == "" {
+		return result, nil
+	}
+	data, err := p.downloadImage(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	if result.PrimaryColor, result.Blurhash, err = p.extractCoverPlaceholder(data, withBlurhash); err != nil {
+		return nil, err
+	}
+	if len(widths) > 0 {
+		result.Variants = p.coverVariants(sourceID, url, data, widths)
+	}
+	return result, nil
+}
+
+// coverVariants 为封面图生成各宽度的缩略图，不小于原图宽度的尺寸跳过
+// 文件按原图内容哈希和宽度命名，同一封面被多篇文章使用时复用已生成的文件
+func (p *Processor) coverVariants(sourceID int64, url string, data []byte, widths []int) []CoverVariant {
+	sourceWidth, err := imageWidth(data)
+	if err != nil {
+		log.Printf("[Image] Failed to read cover size: %v", err)
+		return nil
+	}
+
+	hash := p.calculateHash(data)
+	var variants []CoverVariant
+	for _, width := range widths {
+		if width >= sourceWidth {
+			continue
+		}
+		fileName := fmt.Sprintf("%s-%dw.webp", hash[:12], width)
+		localPath := fmt.Sprintf("/static/images/%d/%s", sourceID, fileName)
+		fullPath := filepath.Join(p.config.StaticDir, "images", fmt.Sprintf("%d", sourceID), fileName)
+
+		if _, err := os.Stat(fullPath); err != nil {
+			// 缩放与正文图片共用并发限制
+			params := currentImageParams(FormatWebP, width)
+			p.limiter.acquire()
+			webpData, err := encodeImageParams(data, params)
+			p.limiter.release()
+			if err == nil {
+				err = p.saveImage(fullPath, webpData)
+			}
+			if err != nil {
+				log.Printf("[Image] Failed to create %dw cover variant: %v", width, err)
+				continue
+			}
+			writeImageMeta(fullPath, imageMeta{URL: url, imageParams: params})
+		} else {
+			recordImageSource(fullPath, url)
+		}
+		variants = append(variants, CoverVariant{Path: localPath, Width: width})
+	}
+	return variants
+}
+
+// imageWidth 读取图片宽度（像素）
+func imageWidth(data []byte) (int, error) {
+	img, err := vips.NewImageFromBuffer(data)
+	if err != nil {
+		return 0, err
+	}
+	defer img.Close()
+	return img.Width(), nil
+}
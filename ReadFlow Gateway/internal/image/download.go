@@ -0,0 +1,80 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// 图片下载默认时限（环境变量未设置或无效时使用）
+const (
+	defaultImageHeaderTimeout = 10 * time.Second // 建立连接到收到响应头
+	defaultImageReadTimeout   = 10 * time.Second // 读取响应体时单次读取的最长等待
+	defaultImageArticleBudget = 60 * time.Second // 单篇文章全部图片的处理时限
+	imageDownloadTimeout      = 30 * time.Second // 单张图片的总时限（客户端不设 Timeout，由请求的 context 控制）
+	maxImageBytes             = 10 * 1024 * 1024 // 单张图片最大字节数
+)
+
+// errImageTimeout 图片下载超时（连接、响应头、读取停滞或超出文章的处理时限），与其他失败分开统计
+var errImageTimeout = errors.New("image download timed out")
+
+// imageTimeouts 图片下载的分段时限
+type imageTimeouts struct {
+	header time.Duration
+	read   time.Duration
+}
+
+// fetchImage 在 ctx 内下载图片：timeouts.header 内没有收到响应头、或读取响应体时超过 timeouts.read 没有新数据时放弃，
+// 停滞的图片很快让出并发名额；超时（包括 ctx 到期）返回包装了 errImageTimeout 的错误
+func fetchImage(ctx context.Context, client *http.Client, req *http.Request, timeouts imageTimeouts) ([]byte, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// 同一个计时器先限制响应头，之后在每次读取前重置为读取时限
+	timer := time.AfterFunc(timeouts.header, func() { cancel(errImageTimeout) })
+	defer timer.Stop()
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, imageDownloadError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	body := &stallReader{r: io.LimitReader(resp.Body, maxImageBytes), timer: timer, timeout: timeouts.read}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, imageDownloadError(ctx, err)
+	}
+	return data, nil
+}
+
+// stallReader 每次读取前重置计时器，计时器到期即取消请求
+type stallReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	s.timer.Reset(s.timeout)
+	return s.r.Read(p)
+}
+
+// imageDownloadError 超时类错误统一包装为 errImageTimeout
+func imageDownloadError(ctx context.Context, err error) error {
+	cause := context.Cause(ctx)
+	var netErr net.Error
+	if errors.Is(cause, errImageTimeout) || errors.Is(cause, context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %v", errImageTimeout, err)
+	}
+	return err
+}
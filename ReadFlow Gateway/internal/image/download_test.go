@@ -0,0 +1,82 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFetchImageTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow-headers", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/stalled-body", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	// 每个分块都在读取时限内到达，总时长超过读取时限
+	mux.HandleFunc("/steady-body", func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+			time.Sleep(40 * time.Millisecond)
+		}
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	timeouts := imageTimeouts{header: 100 * time.Millisecond, read: 100 * time.Millisecond}
+	fetch := func(ctx context.Context, path string) ([]byte, time.Duration, error) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		data, err := fetchImage(ctx, server.Client(), req, timeouts)
+		return data, time.Since(start), err
+	}
+
+	for _, path := range []string{"/slow-headers", "/stalled-body"} {
+		_, elapsed, err := fetch(context.Background(), path)
+		if !errors.Is(err, errImageTimeout) {
+			t.Errorf("%s: err = %v, want errImageTimeout", path, err)
+		}
+		if elapsed > 2*time.Second {
+			t.Errorf("%s: abandoned after %v", path, elapsed)
+		}
+	}
+
+	data, _, err := fetch(context.Background(), "/steady-body")
+	if err != nil || string(data) != "chunkchunkchunkchunkchunk" {
+		t.Errorf("/steady-body = %q, %v", data, err)
+	}
+
+	if _, _, err := fetch(context.Background(), "/missing"); err == nil || errors.Is(err, errImageTimeout) {
+		t.Errorf("/missing: err = %v, want non-timeout error", err)
+	}
+
+	// 文章的处理时限到期同样计为超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	timeouts.header = time.Minute
+	if _, _, err := fetch(ctx, "/slow-headers"); !errors.Is(err, errImageTimeout) {
+		t.Errorf("expired budget: err = %v, want errImageTimeout", err)
+	}
+}
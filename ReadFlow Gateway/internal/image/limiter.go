@@ -0,0 +1,39 @@
+package image
+
+import "sync"
+
+// limiter 上限可变的并发限制器
+// 每次获取许可时重新读取上限，运行时调小上限后已在处理的任务继续完成，新任务等到并发数降到上限以下；
+// 调大上限在下一次有任务释放许可时生效
+type limiter struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active int
+	limit  func() int
+}
+
+// newLimiter 创建并发限制器，limit 返回当前允许的最大并发数（小于 1 时按 1 处理）
+func newLimiter(limit func() int) *limiter {
+	l := &limiter{limit: limit}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire 获取许可，达到上限时阻塞
+func (l *limiter) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.active >= max(l.limit(), 1) {
+		l.cond.Wait()
+	}
+	l.active++
+}
+
+// release 释放许可
+func (l *limiter) release() {
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	// 上限可能已调大，唤醒所有等待者重新判断
+	l.cond.Broadcast()
+}
@@ -0,0 +1,56 @@
+package image
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterFollowsChangingLimit(t *testing.T) {
+	var limit atomic.Int32
+	limit.Store(1)
+	l := newLimiter(func() int { return int(limit.Load()) })
+
+	l.acquire()
+	acquired := make(chan struct{})
+	go func() {
+		l.acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquire succeeded above limit 1")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 调大上限后，下一次释放唤醒等待者，两个许可可以同时持有
+	limit.Store(3)
+	l.acquire()
+	l.release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("waiter not woken after limit increased")
+	}
+
+	// 此时持有 2 个许可，调小上限到 1 后新任务需等到全部释放
+	limit.Store(1)
+	var wg sync.WaitGroup
+	var running atomic.Int32
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.acquire()
+		running.Add(1)
+		l.release()
+	}()
+	l.release()
+	time.Sleep(50 * time.Millisecond)
+	if running.Load() != 0 {
+		t.Fatal("acquire succeeded while active permits exceed lowered limit")
+	}
+	l.release()
+	wg.Wait()
+}
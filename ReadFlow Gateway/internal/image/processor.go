@@ -2,12 +2,14 @@ package image
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	goimage "image"
 	"image/jpeg"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
@@ -18,115 +20,144 @@ import (
 
 	"github.com/davidbyttow/govips/v2/vips"
 	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/metrics"
+	"github.com/readflow/gateway/internal/utils"
 	"golang.org/x/net/html"
 )
 
+// refererMap 图片域名 -> Referer 映射表（防盗链绕过）
+var refererMap = map[string]string{
+	// cnBeta
+	"cnbetacdn.com":        "https://www.cnbeta.com.tw/",
+	"static.cnbetacdn.com": "https://www.cnbeta.com.tw/",
+	// Engadget / Yahoo
+	"yimg.com":       "https://www.engadget.com/",
+	"s.yimg.com":     "https://www.engadget.com/",
+	"aolcdn.com":     "https://www.engadget.com/",
+	"o.aolcdn.com":   "https://www.engadget.com/",
+	"cloudfront.net": "https://www.engadget.com/",
+	// Twitter
+	"twimg.com":     "https://twitter.com/",
+	"pbs.twimg.com": "https://twitter.com/",
+	// Facebook/Instagram
+	"fbcdn.net":        "https://www.facebook.com/",
+	"cdninstagram.com": "https://www.instagram.com/",
+	// Medium
+	"medium.com":      "https://medium.com/",
+	"miro.medium.com": "https://medium.com/",
+	// Imgur
+	"imgur.com":   "https://imgur.com/",
+	"i.imgur.com": "https://imgur.com/",
+	// WordPress
+	"wp.com":    "https://wordpress.com/",
+	"i0.wp.com": "https://wordpress.com/",
+	"i1.wp.com": "https://wordpress.com/",
+	"i2.wp.com": "https://wordpress.com/",
+	// GitHub
+	"githubusercontent.com":     "https://github.com/",
+	"raw.githubusercontent.com": "https://github.com/",
+	// Unsplash
+	"unsplash.com":        "https://unsplash.com/",
+	"images.unsplash.com": "https://unsplash.com/",
+	// Flickr
+	"staticflickr.com": "https://www.flickr.com/",
+	// Giphy
+	"giphy.com":       "https://giphy.com/",
+	"media.giphy.com": "https://giphy.com/",
+	// Reddit
+	"redd.it":         "https://www.reddit.com/",
+	"i.redd.it":       "https://www.reddit.com/",
+	"preview.redd.it": "https://www.reddit.com/",
+	// Client Identified Domains
+	"sspai.com":    "https://sspai.com/",
+	"ifanr.com":    "https://www.ifanr.com/",
+	"ifanr.cn":     "https://www.ifanr.com/",
+	"36kr.com":     "https://36kr.com/",
+	"weibo.com":    "https://weibo.com/",
+	"sinaimg.cn":   "https://weibo.com/",
+	"zhihu.com":    "https://www.zhihu.com/",
+	"zhimg.com":    "https://www.zhihu.com/",
+	"bilibili.com": "https://www.bilibili.com/",
+	"hdslb.com":    "https://www.bilibili.com/",
+	"douban.com":   "https://www.douban.com/",
+	"doubanio.com": "https://www.douban.com/",
+}
+
 // Processor 图片处理器
 type Processor struct {
-	config     *config.Config
-	httpClient *http.Client
-	semaphore  chan struct{}
-	baseURL    string
-	refererMap map[string]string
+	config        *config.Config
+	httpClient    *http.Client
+	timeouts      imageTimeouts // 连接到响应头、单次读取的时限，单张图片的总时限见 imageDownloadTimeout
+	articleBudget time.Duration // 单篇文章全部图片的处理时限
+	limiter       *limiter
+	baseURL       string
+	refererMap    map[string]string
 }
 
-// NewProcessor 创建图片处理器
-func NewProcessor(cfg *config.Config) *Processor {
+// NewProcessor 创建图片处理器，transport 为与订阅源抓取共用的连接池，nil 时按出站代理配置单独创建
+func NewProcessor(cfg *config.Config, transport *http.Transport) *Processor {
 	// 初始化 vips
 	vips.LoggingSettings(nil, vips.LogLevelError)
 	vips.Startup(nil)
 
-	// 初始化 Referer 映射表（防盗链绕过）
-	refererMap := map[string]string{
-		// cnBeta
-		"cnbetacdn.com":        "https://www.cnbeta.com.tw/",
-		"static.cnbetacdn.com": "https://www.cnbeta.com.tw/",
-		// Engadget / Yahoo
-		"yimg.com":       "https://www.engadget.com/",
-		"s.yimg.com":     "https://www.engadget.com/",
-		"aolcdn.com":     "https://www.engadget.com/",
-		"o.aolcdn.com":   "https://www.engadget.com/",
-		"cloudfront.net": "https://www.engadget.com/",
-		// Twitter
-		"twimg.com":     "https://twitter.com/",
-		"pbs.twimg.com": "https://twitter.com/",
-		// Facebook/Instagram
-		"fbcdn.net":        "https://www.facebook.com/",
-		"cdninstagram.com": "https://www.instagram.com/",
-		// Medium
-		"medium.com":      "https://medium.com/",
-		"miro.medium.com": "https://medium.com/",
-		// Imgur
-		"imgur.com":   "https://imgur.com/",
-		"i.imgur.com": "https://imgur.com/",
-		// WordPress
-		"wp.com":    "https://wordpress.com/",
-		"i0.wp.com": "https://wordpress.com/",
-		"i1.wp.com": "https://wordpress.com/",
-		"i2.wp.com": "https://wordpress.com/",
-		// GitHub
-		"githubusercontent.com":     "https://github.com/",
-		"raw.githubusercontent.com": "https://github.com/",
-		// Unsplash
-		"unsplash.com":        "https://unsplash.com/",
-		"images.unsplash.com": "https://unsplash.com/",
-		// Flickr
-		"staticflickr.com": "https://www.flickr.com/",
-		// Giphy
-		"giphy.com":       "https://giphy.com/",
-		"media.giphy.com": "https://giphy.com/",
-		// Reddit
-		"redd.it":         "https://www.reddit.com/",
-		"i.redd.it":       "https://www.reddit.com/",
-		"preview.redd.it": "https://www.reddit.com/",
-		// Client Identified Domains
-		"sspai.com":    "https://sspai.com/",
-		"ifanr.com":    "https://www.ifanr.com/",
-		"ifanr.cn":     "https://www.ifanr.com/",
-		"36kr.com":     "https://36kr.com/",
-		"weibo.com":    "https://weibo.com/",
-		"sinaimg.cn":   "https://weibo.com/",
-		"zhihu.com":    "https://www.zhihu.com/",
-		"zhimg.com":    "https://www.zhihu.com/",
-		"bilibili.com": "https://www.bilibili.com/",
-		"hdslb.com":    "https://www.bilibili.com/",
-		"douban.com":   "https://www.douban.com/",
-		"doubanio.com": "https://www.douban.com/",
-	}
+	// 出站代理在启动时已校验
+	proxy, _ := utils.ParseProxyURL(cfg.OutboundProxy)
 
 	return &Processor{
 		config: cfg,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:       10,
-				IdleConnTimeout:    90 * time.Second,
-				DisableCompression: false,
-			},
+		httpClient: utils.NewHTTPClient(utils.HTTPClientOptions{
+			Proxy:           proxy,
+			Transport:       transport,
+			MaxIdleConns:    10,
+			IdleConnTimeout: 90 * time.Second,
+		}),
+		timeouts: imageTimeouts{
+			header: secondsOr(cfg.ImageHeaderTimeout, defaultImageHeaderTimeout),
+			read:   secondsOr(cfg.ImageReadTimeout, defaultImageReadTimeout),
 		},
-		semaphore:  make(chan struct{}, cfg.ImageConcurrent),
-		baseURL:    fmt.Sprintf("http://localhost:%s", cfg.ServerPort),
-		refererMap: refererMap,
+		articleBudget: secondsOr(cfg.ImageArticleBudget, defaultImageArticleBudget),
+		limiter:       newLimiter(config.GetRuntimeConfig().GetImageConcurrent),
+		baseURL:       fmt.Sprintf("http://localhost:%s", cfg.ServerPort),
+		refererMap:    refererMap,
+	}
+}
+
+// secondsOr 将秒数配置转为时长，未设置或无效时使用默认值
+func secondsOr(seconds int, fallback time.Duration) time.Duration {
+	if seconds <= 0 {
+		return fallback
 	}
+	return time.Duration(seconds) * time.Second
 }
 
 // ProcessContent 处理HTML内容中的图片
-func (p *Processor) ProcessContent(sourceID int64, htmlContent string) (processedHTML string, imagePaths string, err error) {
+// localPaths 为原始地址到本地路径的映射（处理失败的图片为空字符串）
+func (p *Processor) ProcessContent(sourceID int64, htmlContent string) (processedHTML string, imagePaths string, localPaths map[string]string, err error) {
+	return p.ProcessArticleImages(sourceID, htmlContent, nil, "")
+}
+
+// ProcessArticleImages 与 ProcessContent 相同，但只下载 article 节点（正文主体）中出现的图片，
+// 其余图片（页眉、页脚、侧栏中的广告和挂件）保留原始地址；article 为 nil 时处理全部图片
+// link 为文章地址，比较前两边的图片地址都按它解析（readability 会把协议相对、相对地址改写为绝对地址）
+func (p *Processor) ProcessArticleImages(sourceID int64, htmlContent string, article *html.Node, link string) (processedHTML string, imagePaths string, localPaths map[string]string, err error) {
 	if htmlContent == "" {
-		return htmlContent, "", nil
+		return htmlContent, "", nil, nil
 	}
 
 	// 解析HTML
 	doc, err := html.Parse(strings.NewReader(htmlContent))
 	if err != nil {
 		log.Printf("HTML parse failed: %v", err)
-		return htmlContent, "", nil
+		return htmlContent, "", nil, nil
 	}
 
 	// 提取图片URL
 	imageURLs := p.extractImageURLs(doc)
+	if article != nil {
+		imageURLs = p.scopeImageURLs(imageURLs, article, link)
+	}
 	if len(imageURLs) == 0 {
-		return htmlContent, "", nil
+		return htmlContent, "", nil, nil
 	}
 
 	log.Printf("Found %d images in source %d", len(imageURLs), sourceID)
@@ -137,7 +168,71 @@ func (p *Processor) ProcessContent(sourceID int64, htmlContent string) (processe
 	// 替换HTML中的图片链接
 	p.replaceImageURLs(doc, urlMapping)
 
-	// 渲染新的HTML，但只提取 body 中的内容
+	rendered, err := renderBody(doc)
+	if err != nil {
+		log.Printf("HTML render failed: %v", err)
+		return htmlContent, "", nil, nil
+	}
+
+	// 构建image_paths JSON
+	var paths []string
+	for _, localPath := range urlMapping {
+		if localPath != "" {
+			paths = append(paths, localPath)
+		}
+	}
+
+	var imagePathsJSON string
+	if len(paths) > 0 {
+		pathsBytes, _ := json.Marshal(paths)
+		imagePathsJSON = string(pathsBytes)
+	}
+
+	return rendered, imagePathsJSON, urlMapping, nil
+}
+
+// ProxyContent 将内容中的图片地址改写为 /api/image 代理地址（不下载图片）
+func (p *Processor) ProxyContent(htmlContent string) string {
+	if htmlContent == "" {
+		return htmlContent
+	}
+
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		log.Printf("HTML parse failed: %v", err)
+		return htmlContent
+	}
+
+	imageURLs := p.extractImageURLs(doc)
+	if len(imageURLs) == 0 {
+		return htmlContent
+	}
+
+	urlMapping := make(map[string]string, len(imageURLs))
+	for _, imgURL := range imageURLs {
+		urlMapping[imgURL] = ProxyPath(imgURL, p.config.GetImageProxyKey())
+	}
+	p.replaceImageURLs(doc, urlMapping)
+
+	rendered, err := renderBody(doc)
+	if err != nil {
+		log.Printf("HTML render failed: %v", err)
+		return htmlContent
+	}
+	return rendered
+}
+
+// ProxyPath 生成图片代理的相对路径，附带按 key 计算的地址签名
+// 代理接口只转发签名有效的地址，不能被用来访问任意 URL
+func ProxyPath(imageURL, key string) string {
+	if strings.HasPrefix(imageURL, "//") {
+		imageURL = "https:" + imageURL
+	}
+	return "/api/image?url=" + url.QueryEscape(imageURL) + "&sig=" + utils.SignURL(key, imageURL)
+}
+
+// renderBody 渲染 HTML，只输出 body 中的内容（输入不是完整文档时渲染整个文档）
+func renderBody(doc *html.Node) (string, error) {
 	var buf strings.Builder
 	var bodyFound bool
 	var f func(*html.Node)
@@ -156,30 +251,13 @@ func (p *Processor) ProcessContent(sourceID int64, htmlContent string) (processe
 	}
 	f(doc)
 
-	// 如果没有找到 body（例如输入不是完整的HTML），则直接渲染整个文档
 	if !bodyFound {
 		buf.Reset()
 		if err := html.Render(&buf, doc); err != nil {
-			log.Printf("HTML render failed: %v", err)
-			return htmlContent, "", nil
-		}
-	}
-
-	// 构建image_paths JSON
-	var paths []string
-	for _, localPath := range urlMapping {
-		if localPath != "" {
-			paths = append(paths, localPath)
+			return "", err
 		}
 	}
-
-	var imagePathsJSON string
-	if len(paths) > 0 {
-		pathsBytes, _ := json.Marshal(paths)
-		imagePathsJSON = string(pathsBytes)
-	}
-
-	return buf.String(), imagePathsJSON, nil
+	return buf.String(), nil
 }
 
 // extractImageURLs 提取所有图片URL
@@ -206,6 +284,42 @@ func (p *Processor) extractImageURLs(n *html.Node) []string {
 	return urls
 }
 
+// scopeImageURLs 只保留同时出现在 article 节点中的图片地址，两边都按 link 解析后比较
+func (p *Processor) scopeImageURLs(imageURLs []string, article *html.Node, link string) []string {
+	base, _ := url.Parse(link)
+	inArticle := make(map[string]bool)
+	for _, imgURL := range p.extractImageURLs(article) {
+		inArticle[resolveImageURL(base, imgURL)] = true
+	}
+
+	scoped := imageURLs[:0]
+	for _, imgURL := range imageURLs {
+		if inArticle[resolveImageURL(base, imgURL)] {
+			scoped = append(scoped, imgURL)
+		}
+	}
+	if skipped := len(imageURLs) - len(scoped); skipped > 0 {
+		log.Printf("Skipped %d images outside the article body", skipped)
+	}
+	return scoped
+}
+
+// resolveImageURL 将图片地址按文章地址解析为绝对地址；无法解析时原样返回
+func resolveImageURL(base *url.URL, imageURL string) string {
+	ref, err := url.Parse(imageURL)
+	if err != nil {
+		return imageURL
+	}
+	if base == nil || base.Scheme == "" {
+		// 没有文章地址时协议相对地址按 https 处理，与下载时一致
+		if ref.Scheme == "" && ref.Host != "" {
+			ref.Scheme = "https"
+		}
+		return ref.String()
+	}
+	return base.ResolveReference(ref).String()
+}
+
 // isValidImageURL 检查是否是有效的图片URL
 func (p *Processor) isValidImageURL(url string) bool {
 	if url == "" {
@@ -226,6 +340,7 @@ func (p *Processor) isValidImageURL(url string) bool {
 }
 
 // processImages 并发处理图片
+// 全部图片共用 articleBudget 的处理时限，到期后尚未完成的下载立即放弃，未开始的不再下载，均保留原始地址
 func (p *Processor) processImages(sourceID int64, imageURLs []string) map[string]string {
 	urlMapping := make(map[string]string)
 	resultChan := make(chan struct {
@@ -233,13 +348,30 @@ func (p *Processor) processImages(sourceID int64, imageURLs []string) map[string
 		localPath string
 	}, len(imageURLs))
 
+	ctx, cancel := context.WithTimeout(context.Background(), p.articleBudget)
+	defer cancel()
+
 	// 并发处理每个图片
 	for _, url := range imageURLs {
 		go func(imgURL string) {
-			p.semaphore <- struct{}{}        // 获取许可
-			defer func() { <-p.semaphore }() // 释放许可
-
-			localPath, err := p.processImage(sourceID, imgURL)
+			p.limiter.acquire()       // 获取许可，并发数随运行时配置 image_concurrent 调整
+			defer p.limiter.release() // 释放许可
+
+			var localPath string
+			err := ctx.Err()
+			if err == nil {
+				localPath, err = p.processImage(ctx, sourceID, imgURL)
+			} else {
+				err = fmt.Errorf("%w: article image budget %v exceeded", errImageTimeout, p.articleBudget)
+			}
+			switch {
+			case err == nil:
+				metrics.GetMetrics().RecordImageProcess(true)
+			case errors.Is(err, errImageTimeout):
+				metrics.GetMetrics().RecordImageTimeout()
+			default:
+				metrics.GetMetrics().RecordImageProcess(false)
+			}
 			if err != nil {
 				log.Printf("Process image failed: url=%s, error=%v", imgURL, err)
 				localPath = "" // 失败时保留原始URL
@@ -262,9 +394,9 @@ func (p *Processor) processImages(sourceID int64, imageURLs []string) map[string
 }
 
 // processImage 处理单个图片
-func (p *Processor) processImage(sourceID int64, url string) (string, error) {
+func (p *Processor) processImage(ctx context.Context, sourceID int64, url string) (string, error) {
 	// 下载图片
-	imageData, err := p.downloadImage(url)
+	imageData, err := p.downloadImage(ctx, url)
 	if err != nil {
 		return "", err
 	}
@@ -279,25 +411,97 @@ func (p *Processor) processImage(sourceID int64, url string) (string, error) {
 
 	// 检查文件是否已存在
 	if _, err := os.Stat(fullPath); err == nil {
-		// 文件已存在，直接返回
+		// 文件已存在，直接返回；没有附属记录的旧文件补记原图地址
+		recordImageSource(fullPath, url)
 		return localPath, nil
 	}
 
 	// 压缩图片
-	webpData, err := p.compressImage(imageData)
+	params := currentImageParams(FormatWebP, 0)
+	webpData, err := encodeImageParams(imageData, params)
 	if err != nil {
 		return "", err
 	}
 
-	// 保存到磁盘
+	// 保存到磁盘，同时记录原图地址和压缩参数，配置变化后可以重新压缩
 	if err := p.saveImage(fullPath, webpData); err != nil {
 		return "", err
 	}
+	writeImageMeta(fullPath, imageMeta{URL: url, imageParams: params})
 
 	log.Printf("Image processed: %s -> %s", url, localPath)
 	return localPath, nil
 }
 
+// faviconSize 源图标缓存的最大边长（像素）
+const faviconSize = 64
+
+// icoMagic ICO 文件头（libvips 不支持 ICO，原样保存）
+var icoMagic = []byte{0x00, 0x00, 0x01, 0x00}
+
+// ProcessFavicon 下载源图标并缓存到 /static/favicons/，返回本地路径
+func (p *Processor) ProcessFavicon(sourceID int64, url string) (string, error) {
+	imageData, err := p.downloadImage(context.Background(), url)
+	if err != nil {
+		return "", err
+	}
+	if len(imageData) == 0 {
+		return "", fmt.Errorf("empty favicon")
+	}
+
+	hash := p.calculateHash(imageData)
+
+	var data []byte
+	var ext string
+	if bytes.HasPrefix(imageData, icoMagic) {
+		data, ext = imageData, ".ico"
+	} else {
+		if data, err = p.compressFavicon(imageData); err != nil {
+			return "", err
+		}
+		ext = ".webp"
+	}
+
+	fileName := fmt.Sprintf("%d-%s%s", sourceID, hash[:12], ext)
+	localPath := "/static/favicons/" + fileName
+	fullPath := filepath.Join(p.config.StaticDir, "favicons", fileName)
+
+	if _, err := os.Stat(fullPath); err == nil {
+		return localPath, nil
+	}
+	if err := p.saveImage(fullPath, data); err != nil {
+		return "", err
+	}
+
+	log.Printf("Favicon processed: %s -> %s", url, localPath)
+	return localPath, nil
+}
+
+// compressFavicon 将图标缩放到 faviconSize 以内并转为 WebP
+func (p *Processor) compressFavicon(imageData []byte) ([]byte, error) {
+	img, err := vips.NewImageFromBuffer(imageData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load favicon: %w", err)
+	}
+	defer img.Close()
+
+	if longest := max(img.Width(), img.Height()); longest > faviconSize {
+		if err := img.Resize(float64(faviconSize)/float64(longest), vips.KernelLanczos3); err != nil {
+			return nil, fmt.Errorf("failed to resize favicon: %w", err)
+		}
+	}
+
+	ep := vips.NewWebpExportParams()
+	ep.Quality = config.GetRuntimeConfig().GetImageQuality()
+	ep.StripMetadata = true
+
+	webpBytes, _, err := img.ExportWebp(ep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export webp: %w", err)
+	}
+	return webpBytes, nil
+}
+
 // replaceImageURLs 替换HTML中的图片URL
 func (p *Processor) replaceImageURLs(n *html.Node, urlMapping map[string]string) {
 	var f func(*html.Node)
@@ -320,8 +524,8 @@ func (p *Processor) replaceImageURLs(n *html.Node, urlMapping map[string]string)
 	f(n)
 }
 
-// downloadImage 下载图片
-func (p *Processor) downloadImage(url string) ([]byte, error) {
+// downloadImage 下载图片，时限见 fetchImage
+func (p *Processor) downloadImage(ctx context.Context, url string) ([]byte, error) {
 	// 处理协议相对的 URL，例如 //example.com/image.jpg，默认使用 https
 	if strings.HasPrefix(url, "//") {
 		url = "https:" + url
@@ -341,53 +545,9 @@ func (p *Processor) downloadImage(url string) ([]byte, error) {
 		log.Printf("[Image] Set Referer: %s for %s", referer, url)
 	}
 
-	resp, err := p.httpClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
-	}
-
-	// 限制最大10MB
-	data, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
-	if err != nil {
-		return nil, err
-	}
-
-	return data, nil
-}
-
-// compressImage 压缩图片为WebP
-func (p *Processor) compressImage(imageData []byte) ([]byte, error) {
-	// 加载图片
-	img, err := vips.NewImageFromBuffer(imageData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load image: %w", err)
-	}
-	defer img.Close()
-
-	// 如果宽度超过设定值，等比缩放
-	if img.Width() > p.config.ImageMaxWidth {
-		scale := float64(p.config.ImageMaxWidth) / float64(img.Width())
-		if err := img.Resize(scale, vips.KernelLanczos3); err != nil {
-			return nil, fmt.Errorf("failed to resize image: %w", err)
-		}
-	}
-
-	// 转换为WebP
-	ep := vips.NewWebpExportParams()
-	ep.Quality = p.config.ImageQuality
-	ep.StripMetadata = true
-
-	webpBytes, _, err := img.ExportWebp(ep)
-	if err != nil {
-		return nil, fmt.Errorf("failed to export webp: %w", err)
-	}
-
-	return webpBytes, nil
+	ctx, cancel := context.WithTimeout(ctx, imageDownloadTimeout)
+	defer cancel()
+	return fetchImage(ctx, p.httpClient, req, p.timeouts)
 }
 
 // calculateHash 计算SHA256哈希
@@ -397,6 +557,7 @@ func (p *Processor) calculateHash(data []byte) string {
 }
 
 // saveImage 保存图片到磁盘
+// 重新压缩时会覆盖正在被访问的缓存图片，先写临时文件再替换，客户端不会读到写了一半的图片
 func (p *Processor) saveImage(fullPath string, data []byte) error {
 	// 确保目录存在
 	dir := filepath.Dir(fullPath)
@@ -404,16 +565,21 @@ func (p *Processor) saveImage(fullPath string, data []byte) error {
 		return err
 	}
 
-	// 写入文件
-	if err := os.WriteFile(fullPath, data, 0644); err != nil {
-		return err
-	}
-
-	return nil
+	return writeFileAtomic(fullPath, data)
 }
 
 // getReferer 根据域名获取合适的 Referer
 func (p *Processor) getReferer(targetURL string) string {
+	return refererFor(p.refererMap, targetURL)
+}
+
+// RefererFor 根据图片域名获取合适的 Referer（供图片代理使用）
+func RefererFor(targetURL string) string {
+	return refererFor(refererMap, targetURL)
+}
+
+// refererFor 在映射表中查找 Referer
+func refererFor(mapping map[string]string, targetURL string) string {
 	u, err := url.Parse(targetURL)
 	if err != nil {
 		return ""
@@ -421,12 +587,12 @@ func (p *Processor) getReferer(targetURL string) string {
 	host := strings.ToLower(u.Host)
 
 	// 精确匹配
-	if ref, ok := p.refererMap[host]; ok {
+	if ref, ok := mapping[host]; ok {
 		return ref
 	}
 
 	// 部分匹配
-	for domain, referer := range p.refererMap {
+	for domain, referer := range mapping {
 		if strings.HasSuffix(host, domain) || strings.Contains(host, domain) {
 			return referer
 		}
@@ -441,29 +607,22 @@ func (p *Processor) Shutdown() {
 	vips.Shutdown()
 }
 
-// GetDominantColorFromURL 下载图片并提取主色调
-func (p *Processor) GetDominantColorFromURL(url string) (string, error) {
-	if url == "" {
-		return "", nil
-	}
-	data, err := p.downloadImage(url)
-	if err != nil {
-		return "", err
-	}
-	return p.extractDominantColor(data)
-}
-
-// extractDominantColor 从图片数据中提取主色调
-func (p *Processor) extractDominantColor(data []byte) (string, error) {
+// extractCoverPlaceholder 将图片缩小后解码，计算主色调和（可选的）BlurHash
+func (p *Processor) extractCoverPlaceholder(data []byte, withBlurhash bool) (string, string, error) {
 	img, err := vips.NewImageFromBuffer(data)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	defer img.Close()
 
-	// Resize to 10x10 to reduce processing
-	if err := img.Thumbnail(10, 10, vips.InterestingCentre); err != nil {
-		return "", err
+	// 只取主色调时缩到 10x10；BlurHash 需要保留宽高比和更多细节，缩到 32px 以内
+	if withBlurhash {
+		err = img.Thumbnail(32, 32, vips.InterestingNone)
+	} else {
+		err = img.Thumbnail(10, 10, vips.InterestingCentre)
+	}
+	if err != nil {
+		return "", "", err
 	}
 
 	// Export as JPEG for easy decoding
@@ -471,15 +630,28 @@ func (p *Processor) extractDominantColor(data []byte) (string, error) {
 	ep.Quality = 80
 	jpgBytes, _, err := img.ExportJpeg(ep)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// Decode with Go standard lib
 	goImg, err := jpeg.Decode(bytes.NewReader(jpgBytes))
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
+	color, err := averageColor(goImg)
+	if err != nil {
+		return "", "", err
+	}
+	var blurhash string
+	if withBlurhash {
+		blurhash = encodeBlurhash(goImg, blurhashXComponents, blurhashYComponents)
+	}
+	return color, blurhash, nil
+}
+
+// averageColor 计算图片的平均色（#rrggbb）
+func averageColor(goImg goimage.Image) (string, error) {
 	// Calculate Average
 	bounds := goImg.Bounds()
 	var r, g, b, count uint64
@@ -0,0 +1,59 @@
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestScopeImageURLs(t *testing.T) {
+	// readability 按文章地址把正文中的协议相对地址改写为绝对地址
+	article, err := html.Parse(strings.NewReader(`<div><img src="https://cdn.example.com/a.jpg"><img src="https://blog.example.com/b.png"></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	imageURLs := []string{"//cdn.example.com/a.jpg", "https://blog.example.com/b.png", "//ads.example.com/banner.gif"}
+
+	p := &Processor{}
+	got := p.scopeImageURLs(imageURLs, article, "https://blog.example.com/posts/1")
+	if len(got) != 2 || got[0] != "//cdn.example.com/a.jpg" || got[1] != "https://blog.example.com/b.png" {
+		t.Errorf("scoped = %v, want protocol-relative and absolute body images in original form", got)
+	}
+
+	// 没有文章地址时协议相对地址按 https 比较
+	imageURLs = []string{"//cdn.example.com/a.jpg", "//ads.example.com/banner.gif"}
+	if got := p.scopeImageURLs(imageURLs, article, ""); len(got) != 1 || got[0] != "//cdn.example.com/a.jpg" {
+		t.Errorf("scoped without link = %v", got)
+	}
+}
+
+func TestSaveImageReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	fullPath := filepath.Join(dir, "1", "abc.webp")
+
+	p := &Processor{}
+	if err := p.saveImage(fullPath, []byte("old")); err != nil {
+		t.Fatalf("saveImage: %v", err)
+	}
+	if err := p.saveImage(fullPath, []byte("new image")); err != nil {
+		t.Fatalf("saveImage overwrite: %v", err)
+	}
+
+	data, err := os.ReadFile(fullPath)
+	if err != nil || string(data) != "new image" {
+		t.Fatalf("content = %q, %v", data, err)
+	}
+	if info, err := os.Stat(fullPath); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+	// 临时文件已重命名，目录中只留下图片本身
+	entries, err := os.ReadDir(filepath.Dir(fullPath))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory entries = %d, %v; want only the image", len(entries), err)
+	}
+}
@@ -0,0 +1,200 @@
+package image
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// imageMetaSuffix 缓存图片附属记录的文件后缀（与图片同名，如 abc123.webp.json）
+const imageMetaSuffix = ".json"
+
+// imageMeta 缓存图片的附属记录：原图地址和压缩参数
+// 原图不保留，重新压缩时从原图地址重新下载；参数为零值表示未知（记录之前缓存的图片）
+type imageMeta struct {
+	URL string `json:"url"`
+	imageParams
+}
+
+// IsImageMeta 是否为缓存图片的附属记录文件，统计缓存图片数时跳过
+func IsImageMeta(path string) bool {
+	return strings.HasSuffix(path, imageMetaSuffix)
+}
+
+// readImageMeta 读取缓存图片的附属记录，没有记录时返回 nil
+func readImageMeta(fullPath string) (*imageMeta, error) {
+	data, err := os.ReadFile(fullPath + imageMetaSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta := &imageMeta{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// writeImageMeta 写入缓存图片的附属记录，失败只记录日志（只影响之后的重新压缩）
+func writeImageMeta(fullPath string, meta imageMeta) {
+	data, _ := json.Marshal(meta)
+	if err := writeFileAtomic(fullPath+imageMetaSuffix, data); err != nil {
+		log.Printf("[Image] Failed to write image meta %s: %v", fullPath, err)
+	}
+}
+
+// recordImageSource 为没有附属记录的缓存图片补记原图地址，压缩参数未知，重新压缩时视为过期
+func recordImageSource(fullPath, url string) {
+	if meta, err := readImageMeta(fullPath); err == nil && meta == nil {
+		writeImageMeta(fullPath, imageMeta{URL: url})
+	}
+}
+
+// cachedImageName 缓存图片的文件名：原图内容哈希前 12 位，封面缩略图另带宽度后缀
+var cachedImageName = regexp.MustCompile(`^([0-9a-f]{12})(?:-([0-9]+)w)?\.webp$`)
+
+// ImageReprocessCounts 重新压缩缓存图片的统计
+type ImageReprocessCounts struct {
+	Regenerated int // 按当前参数重新生成
+	UpToDate    int // 已是当前参数，跳过
+	Unavailable int // 无法重新生成：原图地址未知、原图内容已变化或缓存文件不存在
+	Failed      int // 下载或压缩失败
+}
+
+// Add 累加另一组统计
+func (c *ImageReprocessCounts) Add(other ImageReprocessCounts) {
+	c.Regenerated += other.Regenerated
+	c.UpToDate += other.UpToDate
+	c.Unavailable += other.Unavailable
+	c.Failed += other.Failed
+}
+
+// originalImage 重新下载的原图
+type originalImage struct {
+	url  string
+	data []byte
+}
+
+// ReprocessImages 按当前 image_quality、image_max_width 重新压缩一篇文章的缓存图片 localPaths（含封面缩略图，缩略图保持原宽度）
+// 压缩参数与当前配置相同的图片跳过；原图从附属记录中的地址重新下载，内容哈希与文件名不符（原图已变化）时保留旧文件。
+// 附属记录之前缓存的图片没有原图地址，下载 candidates（文章原文中的图片和封面地址）按内容哈希匹配。
+// 全部图片共用 articleBudget 的处理时限
+func (p *Processor) ReprocessImages(localPaths, candidates []string) ImageReprocessCounts {
+	var counts ImageReprocessCounts
+	ctx, cancel := context.WithTimeout(context.Background(), p.articleBudget)
+	defer cancel()
+
+	var originals map[string]originalImage // 候选原图按内容哈希前 12 位索引，第一次需要时下载
+	for _, localPath := range localPaths {
+		fullPath, hash, width, ok := p.cachedImageFile(localPath)
+		if !ok {
+			counts.Unavailable++
+			continue
+		}
+		if _, err := os.Stat(fullPath); err != nil {
+			counts.Unavailable++
+			continue
+		}
+		meta, err := readImageMeta(fullPath)
+		if err != nil {
+			log.Printf("[Image] Failed to read image meta %s: %v", fullPath, err)
+		}
+
+		want := currentImageParams(FormatWebP, width)
+		if meta != nil && meta.imageParams == want {
+			counts.UpToDate++
+			continue
+		}
+
+		var original originalImage
+		if meta != nil && meta.URL != "" {
+			data, err := p.downloadImage(ctx, meta.URL)
+			if err != nil {
+				log.Printf("[Image] Reprocess %s: download %s failed: %v", localPath, meta.URL, err)
+				counts.Failed++
+				continue
+			}
+			if p.calculateHash(data)[:12] != hash {
+				log.Printf("[Image] Reprocess %s: original %s has changed, keeping cached file", localPath, meta.URL)
+				counts.Unavailable++
+				continue
+			}
+			original = originalImage{url: meta.URL, data: data}
+		} else {
+			if originals == nil {
+				originals = p.downloadOriginals(ctx, candidates)
+			}
+			if original, ok = originals[hash]; !ok {
+				counts.Unavailable++
+				continue
+			}
+		}
+
+		// 压缩与正文图片共用并发限制
+		p.limiter.acquire()
+		data, err := encodeImageParams(original.data, want)
+		p.limiter.release()
+		if err == nil {
+			err = p.saveImage(fullPath, data)
+		}
+		if err != nil {
+			log.Printf("[Image] Reprocess %s failed: %v", localPath, err)
+			counts.Failed++
+			continue
+		}
+		writeImageMeta(fullPath, imageMeta{URL: original.url, imageParams: want})
+		counts.Regenerated++
+	}
+	return counts
+}
+
+// cachedImageFile 解析缓存图片的本地路径（/static/images/{sourceID}/{hash}[-{width}w].webp），
+// 返回磁盘路径、原图哈希前缀和缩略图宽度（正文图片为 0）
+func (p *Processor) cachedImageFile(localPath string) (fullPath, hash string, width int, ok bool) {
+	rel, found := strings.CutPrefix(localPath, "/static/images/")
+	if !found {
+		return "", "", 0, false
+	}
+	dir, name := filepath.Split(rel)
+	if _, err := strconv.ParseInt(strings.TrimSuffix(dir, "/"), 10, 64); err != nil {
+		return "", "", 0, false
+	}
+	match := cachedImageName.FindStringSubmatch(name)
+	if match == nil {
+		return "", "", 0, false
+	}
+	if match[2] != "" {
+		if width, _ = strconv.Atoi(match[2]); width <= 0 {
+			return "", "", 0, false
+		}
+	}
+	return filepath.Join(p.config.StaticDir, "images", dir, name), match[1], width, true
+}
+
+// downloadOriginals 下载候选原图，按内容哈希前 12 位索引；下载失败的跳过
+func (p *Processor) downloadOriginals(ctx context.Context, candidates []string) map[string]originalImage {
+	originals := make(map[string]originalImage)
+	seen := make(map[string]bool)
+	for _, url := range candidates {
+		url = strings.TrimSpace(url)
+		if seen[url] || !p.isValidImageURL(url) {
+			continue
+		}
+		seen[url] = true
+		data, err := p.downloadImage(ctx, url)
+		if err != nil {
+			log.Printf("[Image] Reprocess: download candidate %s failed: %v", url, err)
+			continue
+		}
+		originals[p.calculateHash(data)[:12]] = originalImage{url: url, data: data}
+	}
+	return originals
+}
@@ -0,0 +1,74 @@
+package image
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/readflow/gateway/internal/config"
+)
+
+func TestReprocessImagesSkipsWithoutRegenerating(t *testing.T) {
+	original := []byte("original image bytes")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("changed image bytes"))
+	}))
+	defer server.Close()
+
+	staticDir := t.TempDir()
+	p := &Processor{
+		config:        &config.Config{StaticDir: staticDir},
+		httpClient:    server.Client(),
+		timeouts:      imageTimeouts{header: time.Second, read: time.Second},
+		articleBudget: 5 * time.Second,
+		limiter:       newLimiter(func() int { return 1 }),
+	}
+	hash := p.calculateHash(original)[:12]
+	write := func(name string, meta *imageMeta) string {
+		t.Helper()
+		fullPath := filepath.Join(staticDir, "images", "7", name)
+		if err := p.saveImage(fullPath, []byte("cached")); err != nil {
+			t.Fatal(err)
+		}
+		if meta != nil {
+			writeImageMeta(fullPath, *meta)
+		}
+		return "/static/images/7/" + name
+	}
+
+	current := write(hash+".webp", &imageMeta{URL: server.URL, imageParams: currentImageParams(FormatWebP, 0)})
+	// 缩略图按自身宽度比较，不受 image_max_width 影响
+	variant := write(hash+"-160w.webp", &imageMeta{URL: server.URL, imageParams: currentImageParams(FormatWebP, 160)})
+	// 参数过期但原图已变化：保留旧文件
+	changed := write("aaaaaaaaaaaa.webp", &imageMeta{URL: server.URL, imageParams: imageParams{Quality: 1, MaxWidth: 1, Format: FormatWebP}})
+	// 没有附属记录，候选原图都不匹配
+	legacy := write("bbbbbbbbbbbb.webp", nil)
+
+	counts := p.ReprocessImages(
+		[]string{current, variant, changed, legacy, "/static/images/7/missing.webp", "/static/images/7/../x.webp"},
+		[]string{server.URL + "/other.jpg"},
+	)
+	want := ImageReprocessCounts{UpToDate: 2, Unavailable: 4}
+	if counts != want {
+		t.Errorf("counts = %+v, want %+v", counts, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(staticDir, "images", "7", "aaaaaaaaaaaa.webp"))
+	if err != nil || string(data) != "cached" {
+		t.Errorf("changed original overwrote cached file: %q, %v", data, err)
+	}
+}
+
+func TestIsImageMeta(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/static/images/1/abc.webp":      false,
+		"/static/images/1/abc.webp.json": true,
+	} {
+		if got := IsImageMeta(path); got != want {
+			t.Errorf("IsImageMeta(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
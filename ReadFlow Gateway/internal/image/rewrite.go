@@ -0,0 +1,69 @@
+package image
+
+import (
+	"log"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ProxyImageURLs 将 HTML 中外部图片的地址改写为 baseURL + 图片代理路径，baseURL 为空时使用相对路径
+// 只改写返回给客户端的内容，入库内容保持原样；已是网关本地地址（process 模式缓存的图片、代理地址）的图片不改写
+func ProxyImageURLs(htmlContent, key, baseURL string) string {
+	if htmlContent == "" || !strings.Contains(htmlContent, "<img") {
+		return htmlContent
+	}
+
+	doc, err := html.Parse(strings.NewReader(htmlContent))
+	if err != nil {
+		log.Printf("HTML parse failed: %v", err)
+		return htmlContent
+	}
+
+	changed := false
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "img" {
+			for i, attr := range n.Attr {
+				if attr.Key == "src" {
+					if proxied := ProxyImageURL(strings.TrimSpace(attr.Val), key, baseURL); proxied != attr.Val {
+						n.Attr[i].Val = proxied
+						changed = true
+					}
+					break
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(doc)
+	if !changed {
+		return htmlContent
+	}
+
+	rendered, err := renderBody(doc)
+	if err != nil {
+		log.Printf("HTML render failed: %v", err)
+		return htmlContent
+	}
+	return rendered
+}
+
+// ProxyImageURL 将单个外部图片地址改写为 baseURL + 图片代理路径，非外部 http(s) 地址原样返回
+func ProxyImageURL(imageURL, key, baseURL string) string {
+	if !strings.HasPrefix(imageURL, "http://") && !strings.HasPrefix(imageURL, "https://") && !strings.HasPrefix(imageURL, "//") {
+		return imageURL
+	}
+	u, err := url.Parse(imageURL)
+	if err != nil || u.Host == "" {
+		return imageURL
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1":
+		return imageURL
+	}
+	return strings.TrimSuffix(baseURL, "/") + ProxyPath(imageURL, key)
+}
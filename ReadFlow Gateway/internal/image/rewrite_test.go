@@ -0,0 +1,32 @@
+package image
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProxyImageURLs(t *testing.T) {
+	content := `<p>text</p><img src="https://cdn.example.com/a.jpg"><img src="http://localhost:8080/static/images/1/abc.webp"><img src="data:image/png;base64,xx">`
+
+	got := ProxyImageURLs(content, "key", "https://gw.example.com/")
+	want := `<img src="https://gw.example.com` + ProxyPath("https://cdn.example.com/a.jpg", "key") + `"/>`
+	if !strings.Contains(got, strings.ReplaceAll(want, "&", "&amp;")) {
+		t.Errorf("external image not proxied: %s", got)
+	}
+	if !strings.Contains(got, `src="http://localhost:8080/static/images/1/abc.webp"`) {
+		t.Errorf("local image rewritten: %s", got)
+	}
+	if !strings.Contains(got, `src="data:image/png;base64,xx"`) {
+		t.Errorf("data URI rewritten: %s", got)
+	}
+
+	// 没有需要改写的图片时原样返回
+	local := `<p><img src="/static/images/1/abc.webp"></p>`
+	if got := ProxyImageURLs(local, "key", ""); got != local {
+		t.Errorf("content without external images changed: %s", got)
+	}
+
+	if got := ProxyImageURL("//cdn.example.com/b.png", "key", ""); got != ProxyPath("//cdn.example.com/b.png", "key") {
+		t.Errorf("protocol-relative cover = %s", got)
+	}
+}
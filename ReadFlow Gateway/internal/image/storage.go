@@ -8,6 +8,35 @@ import (
 	"path/filepath"
 )
 
+// writeFileAtomic 先写入同目录下的临时文件，再重命名为 path（同一文件系统内 os.Rename 是原子的）
+// 读取方看到的要么是旧文件、要么是完整的新文件；失败时删除临时文件
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	// CreateTemp 创建的文件权限为 0600，与 os.WriteFile 写入的缓存文件保持一致
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // DeleteImageFiles 删除图片文件
 func DeleteImageFiles(staticDir, imagePathsJSON string) error {
 	if imagePathsJSON == "" || imagePathsJSON == "[]" {
@@ -33,11 +62,26 @@ func DeleteImageFiles(staticDir, imagePathsJSON string) error {
 		} else {
 			log.Printf("Deleted image file: %s", fullPath)
 		}
+		// 附属记录（原图地址和压缩参数）随图片删除
+		if err := os.Remove(fullPath + imageMetaSuffix); err != nil && !os.IsNotExist(err) {
+			log.Printf("ERROR: Failed to delete image meta: %s, error: %v", fullPath, err)
+		}
 	}
 
 	return nil
 }
 
+// DeleteFavicon 删除缓存的源图标文件
+func DeleteFavicon(staticDir, faviconPath string) {
+	if faviconPath == "" {
+		return
+	}
+	fullPath := filepath.Clean(filepath.Join(staticDir, "..", faviconPath)) // path 已包含 /static
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("ERROR: Failed to delete favicon: %s, error: %v", fullPath, err)
+	}
+}
+
 // RemoveEmptyDir 删除空目录
 func RemoveEmptyDir(dirPath string) error {
 	if dirPath == "" {
@@ -0,0 +1,90 @@
+package image
+
+import (
+	"fmt"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/readflow/gateway/internal/config"
+)
+
+// 图片转码的目标格式
+const (
+	FormatWebP = "webp"
+	FormatAVIF = "avif"
+)
+
+// transcodeSlots 图片代理实时转码的并发限制，与缓存图片处理分开计数
+var transcodeSlots = newLimiter(config.GetRuntimeConfig().GetImageConcurrent)
+
+// FormatContentType 返回目标格式的 Content-Type
+func FormatContentType(format string) string {
+	return "image/" + format
+}
+
+// Transcode 将图片转码为 WebP 或 AVIF，缩放和品质与缓存图片相同（image_max_width、image_quality）
+// 供图片代理在客户端支持新格式而源站只提供 JPEG/PNG 时使用
+func Transcode(imageData []byte, format string) ([]byte, error) {
+	transcodeSlots.acquire()
+	defer transcodeSlots.release()
+	return encodeImage(imageData, format)
+}
+
+// imageParams 图片的压缩参数，随缓存图片一起记录（见 imageMeta），配置变化后据此重新压缩
+type imageParams struct {
+	Quality  int    `json:"quality"`
+	MaxWidth int    `json:"max_width"`
+	Format   string `json:"format"`
+}
+
+// currentImageParams 按运行时配置（image_quality、image_max_width）生成压缩参数，maxWidth 大于 0 时使用指定宽度
+// 每张图片读取一次运行时配置，管理后台修改后立即对后续图片生效
+func currentImageParams(format string, maxWidth int) imageParams {
+	rc := config.GetRuntimeConfig()
+	if maxWidth <= 0 {
+		maxWidth = rc.GetImageMaxWidth()
+	}
+	return imageParams{Quality: rc.GetImageQuality(), MaxWidth: maxWidth, Format: format}
+}
+
+// encodeImage 按运行时配置缩放图片并导出为指定格式
+func encodeImage(imageData []byte, format string) ([]byte, error) {
+	return encodeImageParams(imageData, currentImageParams(format, 0))
+}
+
+// encodeImageParams 将宽度超过 params.MaxWidth 的图片等比缩放，按 params.Quality 导出为 params.Format
+func encodeImageParams(imageData []byte, params imageParams) ([]byte, error) {
+	// 加载图片
+	img, err := vips.NewImageFromBuffer(imageData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image: %w", err)
+	}
+	defer img.Close()
+
+	// 如果宽度超过设定值，等比缩放
+	if img.Width() > params.MaxWidth {
+		scale := float64(params.MaxWidth) / float64(img.Width())
+		if err := img.Resize(scale, vips.KernelLanczos3); err != nil {
+			return nil, fmt.Errorf("failed to resize image: %w", err)
+		}
+	}
+
+	var data []byte
+	switch params.Format {
+	case FormatWebP:
+		ep := vips.NewWebpExportParams()
+		ep.Quality = params.Quality
+		ep.StripMetadata = true
+		data, _, err = img.ExportWebp(ep)
+	case FormatAVIF:
+		ep := vips.NewAvifExportParams()
+		ep.Quality = params.Quality
+		ep.StripMetadata = true
+		data, _, err = img.ExportAvif(ep)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", params.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s: %w", params.Format, err)
+	}
+	return data, nil
+}
@@ -25,6 +25,10 @@ type Metrics struct {
 	imageProcessed   int64
 	imageSuccess     int64
 	imageFailed      int64
+	imageTimeout     int64 // 下载超时（不计入 imageFailed）
+
+	// 各源最近的抓取耗时（只保存在内存中，重启后重新统计）
+	sourceLatency map[int64]*sourceLatencyState
 
 	// 业务指标
 	activeUsers      int
@@ -43,6 +47,7 @@ func GetMetrics() *Metrics {
 		globalMetrics = &Metrics{
 			apiRequests:  make(map[string]int64),
 			apiDurations: make([]time.Duration, 0, 1000),
+			sourceLatency: make(map[int64]*sourceLatencyState),
 			startTime:    time.Now(),
 		}
 	})
@@ -94,6 +99,15 @@ func (m *Metrics) RecordImageProcess(success bool) {
 	}
 }
 
+// RecordImageTimeout 记录一次图片下载超时（连接、读取停滞或超出文章的图片处理时限）
+func (m *Metrics) RecordImageTimeout() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.imageProcessed++
+	m.imageTimeout++
+}
+
 // UpdateActiveUsers 更新活跃用户数
 func (m *Metrics) UpdateActiveUsers(count int) {
 	m.mu.Lock()
@@ -133,6 +147,7 @@ func (m *Metrics) GetStats() map[string]interface{} {
 			"processed": m.imageProcessed,
 			"success":   m.imageSuccess,
 			"failed":    m.imageFailed,
+			"timeout":   m.imageTimeout,
 		},
 		"business": map[string]interface{}{
 			"active_users":   m.activeUsers,
@@ -176,4 +191,6 @@ func (m *Metrics) Reset() {
 	m.imageProcessed = 0
 	m.imageSuccess = 0
 	m.imageFailed = 0
+	m.imageTimeout = 0
+	m.sourceLatency = make(map[int64]*sourceLatencyState)
 }
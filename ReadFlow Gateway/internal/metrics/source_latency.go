@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// sourceLatencyWindow 滚动平均使用的最近抓取次数
+	sourceLatencyWindow = 10
+	// nearTimeoutRatio 平均耗时达到超时时间的该比例时视为接近超时
+	nearTimeoutRatio = 0.8
+	// nearTimeoutMinSamples 至少有这么多次抓取才标记接近超时，避免偶发的慢请求误报
+	nearTimeoutMinSamples = 3
+)
+
+// SourceLatency 单个源最近的抓取耗时（抓取和处理文章的总时间，超时按超时时间计）
+type SourceLatency struct {
+	SourceID     int64     `json:"source_id"`
+	LastMs       int64     `json:"last_ms"`
+	AvgMs        int64     `json:"avg_ms"`        // 最近 sourceLatencyWindow 次的平均值
+	MaxMs        int64     `json:"max_ms"`        // 最近 sourceLatencyWindow 次的最大值
+	Samples      int       `json:"samples"`       // 滚动窗口内的次数
+	Timeouts     int       `json:"timeouts"`      // 滚动窗口内超时的次数
+	TotalFetches int64     `json:"total_fetches"` // 进程启动以来的抓取次数
+	NearTimeout  bool      `json:"near_timeout"`  // 平均耗时持续接近超时时间
+	TimeoutMs    int64     `json:"timeout_ms"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// sourceLatencyState 单个源最近几次抓取的滚动窗口
+type sourceLatencyState struct {
+	durations [sourceLatencyWindow]time.Duration
+	timedOut  [sourceLatencyWindow]bool
+	next      int
+	count     int
+	total     int64
+	timeout   time.Duration
+	updatedAt time.Time
+}
+
+// RecordSourceFetch 记录一次源抓取的耗时，timedOut 表示因超过 timeout 被放弃
+func (m *Metrics) RecordSourceFetch(sourceID int64, duration, timeout time.Duration, timedOut bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.sourceLatency[sourceID]
+	if !ok {
+		state = &sourceLatencyState{}
+		m.sourceLatency[sourceID] = state
+	}
+	state.durations[state.next] = duration
+	state.timedOut[state.next] = timedOut
+	state.next = (state.next + 1) % sourceLatencyWindow
+	if state.count < sourceLatencyWindow {
+		state.count++
+	}
+	state.total++
+	state.timeout = timeout
+	state.updatedAt = time.Now()
+}
+
+// GetSourceLatency 获取单个源的抓取耗时，进程启动后未抓取过时返回 false
+func (m *Metrics) GetSourceLatency(sourceID int64) (SourceLatency, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	state, ok := m.sourceLatency[sourceID]
+	if !ok {
+		return SourceLatency{}, false
+	}
+	return state.snapshot(sourceID), true
+}
+
+// GetSourceLatencies 获取所有源的抓取耗时，按平均耗时降序排列
+func (m *Metrics) GetSourceLatencies() []SourceLatency {
+	m.mu.RLock()
+	result := make([]SourceLatency, 0, len(m.sourceLatency))
+	for sourceID, state := range m.sourceLatency {
+		result = append(result, state.snapshot(sourceID))
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].AvgMs != result[j].AvgMs {
+			return result[i].AvgMs > result[j].AvgMs
+		}
+		return result[i].SourceID < result[j].SourceID
+	})
+	return result
+}
+
+// ForgetSource 删除源的耗时记录（源被删除或合并时调用）
+func (m *Metrics) ForgetSource(sourceID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sourceLatency, sourceID)
+}
+
+// snapshot 计算滚动窗口的统计值，调用方持有锁
+func (s *sourceLatencyState) snapshot(sourceID int64) SourceLatency {
+	result := SourceLatency{
+		SourceID:     sourceID,
+		Samples:      s.count,
+		TotalFetches: s.total,
+		TimeoutMs:    s.timeout.Milliseconds(),
+		UpdatedAt:    s.updatedAt,
+	}
+	if s.count == 0 {
+		return result
+	}
+
+	var sum, max time.Duration
+	for i := 0; i < s.count; i++ {
+		sum += s.durations[i]
+		if s.durations[i] > max {
+			max = s.durations[i]
+		}
+		if s.timedOut[i] {
+			result.Timeouts++
+		}
+	}
+	last := (s.next + sourceLatencyWindow - 1) % sourceLatencyWindow
+	avg := sum / time.Duration(s.count)
+
+	result.LastMs = s.durations[last].Milliseconds()
+	result.AvgMs = avg.Milliseconds()
+	result.MaxMs = max.Milliseconds()
+	result.NearTimeout = s.timeout > 0 && s.count >= nearTimeoutMinSamples &&
+		float64(avg) >= float64(s.timeout)*nearTimeoutRatio
+	return result
+}
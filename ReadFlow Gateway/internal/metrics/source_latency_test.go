@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSourceLatency(t *testing.T) {
+	m := GetMetrics()
+	m.Reset()
+	timeout := 10 * time.Second
+
+	if _, ok := m.GetSourceLatency(1); ok {
+		t.Fatal("unfetched source should have no latency")
+	}
+
+	m.RecordSourceFetch(1, 2*time.Second, timeout, false)
+	m.RecordSourceFetch(1, 4*time.Second, timeout, false)
+	l, _ := m.GetSourceLatency(1)
+	if l.LastMs != 4000 || l.AvgMs != 3000 || l.MaxMs != 4000 || l.Samples != 2 || l.NearTimeout {
+		t.Errorf("latency = %+v", l)
+	}
+
+	// 窗口滚动后只统计最近的抓取；平均耗时持续接近超时时标记
+	for i := 0; i < sourceLatencyWindow; i++ {
+		m.RecordSourceFetch(1, 9*time.Second, timeout, false)
+	}
+	m.RecordSourceFetch(1, timeout, timeout, true)
+	l, _ = m.GetSourceLatency(1)
+	if l.Samples != sourceLatencyWindow || l.TotalFetches != sourceLatencyWindow+3 || l.Timeouts != 1 || l.LastMs != 10000 || !l.NearTimeout {
+		t.Errorf("latency after window = %+v", l)
+	}
+
+	m.RecordSourceFetch(2, time.Second, timeout, false)
+	if list := m.GetSourceLatencies(); len(list) != 2 || list[0].SourceID != 1 {
+		t.Errorf("latencies not sorted by avg: %+v", list)
+	}
+
+	m.ForgetSource(1)
+	if _, ok := m.GetSourceLatency(1); ok {
+		t.Errorf("forgotten source still has latency")
+	}
+}
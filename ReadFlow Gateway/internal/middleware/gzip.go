@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GzipConfig gzip 压缩配置
+type GzipConfig struct {
+	Level         int      // 压缩级别 1-9
+	MinSize       int      // 响应体小于该字节数时不压缩
+	ExcludedPaths []string // 不压缩的路径前缀（如已压缩的图片）
+}
+
+// Gzip 响应压缩中间件
+// 仅在客户端声明 Accept-Encoding: gzip 时生效；跳过已编码的响应、图片和 SSE 流
+func Gzip(cfg GzipConfig) gin.HandlerFunc {
+	if cfg.Level < gzip.BestSpeed || cfg.Level > gzip.BestCompression {
+		cfg.Level = gzip.DefaultCompression
+	}
+	pool := &sync.Pool{
+		New: func() interface{} {
+			gz, _ := gzip.NewWriterLevel(nil, cfg.Level)
+			return gz
+		},
+	}
+
+	return func(c *gin.Context) {
+		if !shouldCompress(c.Request, cfg.ExcludedPaths) {
+			c.Next()
+			return
+		}
+
+		gw := &gzipWriter{ResponseWriter: c.Writer, minSize: cfg.MinSize, pool: pool}
+		c.Writer = gw
+		c.Header("Vary", "Accept-Encoding")
+
+		c.Next()
+
+		gw.finish()
+		c.Writer = gw.ResponseWriter
+	}
+}
+
+// shouldCompress 根据请求判断是否需要尝试压缩
+func shouldCompress(req *http.Request, excludedPaths []string) bool {
+	if req.Method == http.MethodHead || !strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") {
+		return false
+	}
+	if strings.Contains(req.Header.Get("Accept"), "text/event-stream") {
+		return false
+	}
+	for _, prefix := range excludedPaths {
+		if strings.HasPrefix(req.URL.Path, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// gzipWriter 先缓冲响应体，达到 MinSize 后再决定是否压缩
+type gzipWriter struct {
+	gin.ResponseWriter
+	minSize  int
+	pool     *sync.Pool
+	buf      bytes.Buffer
+	gz       *gzip.Writer
+	decided  bool
+	finished bool
+}
+
+// Write 写入响应体
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	w.buf.Write(data)
+	if w.buf.Len() >= w.minSize {
+		if err := w.decide(w.compressible()); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// WriteString 写入字符串响应体
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 流式响应需要立即下发已缓冲的数据
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(w.compressible())
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Hijack 透传连接劫持（WebSocket 等）
+func (w *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.Hijack()
+}
+
+// compressible 根据响应头判断内容是否适合压缩
+func (w *gzipWriter) compressible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	switch {
+	case contentType == "":
+		return true
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		return false
+	case strings.HasPrefix(contentType, "text/"),
+		strings.Contains(contentType, "json"),
+		strings.Contains(contentType, "xml"),
+		strings.Contains(contentType, "javascript"):
+		return true
+	}
+	return false
+}
+
+// decide 确定是否压缩，并写出已缓冲的数据
+func (w *gzipWriter) decide(compress bool) error {
+	w.decided = true
+	if compress {
+		header := w.Header()
+		header.Set("Content-Encoding", "gzip")
+		// 压缩后长度变化，由 chunked 传输代替
+		header.Del("Content-Length")
+		w.gz = w.pool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+		_, err := w.gz.Write(w.buf.Bytes())
+		w.buf.Reset()
+		return err
+	}
+
+	// 不压缩时原样输出，Content-Length 等响应头保持不变
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// finish 请求结束时写出剩余数据并归还压缩器
+func (w *gzipWriter) finish() {
+	if w.finished {
+		return
+	}
+	w.finished = true
+
+	if !w.decided {
+		// 响应体小于阈值，不压缩
+		_ = w.decide(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(nil)
+		w.pool.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGzip(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	large := strings.Repeat(`{"title":"hello world"}`, 100)
+	router := gin.New()
+	router.Use(Gzip(GzipConfig{Level: gzip.BestSpeed, MinSize: 256, ExcludedPaths: []string{"/static"}}))
+	router.GET("/large", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(large))
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(`{"ok":true}`))
+	})
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/webp", []byte(large))
+	})
+	router.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "br")
+		c.Data(http.StatusOK, "application/json", []byte(large))
+	})
+	router.GET("/static/x.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", []byte(large))
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 客户端接受 gzip 且响应足够大：压缩，并声明 Vary
+	rec := get("/large", "gzip, deflate")
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("large response not compressed: %v", rec.Header())
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", rec.Header().Get("Vary"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil || string(body) != large {
+		t.Errorf("decompressed body mismatch: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		path, acceptEncoding string
+		wantEncoding         string
+	}{
+		"not accepted":    {"/large", "", ""},
+		"other encoding":  {"/large", "br", ""},
+		"below min size":  {"/small", "gzip", ""},
+		"image":           {"/image", "gzip", ""},
+		"already encoded": {"/encoded", "gzip", "br"},
+		"excluded path":   {"/static/x.json", "gzip", ""},
+	} {
+		rec := get(tc.path, tc.acceptEncoding)
+		if got := rec.Header().Get("Content-Encoding"); got != tc.wantEncoding {
+			t.Errorf("%s: Content-Encoding = %q, want %q", name, got, tc.wantEncoding)
+		}
+		if tc.wantEncoding == "" && strings.TrimSpace(rec.Body.String()) == "" {
+			t.Errorf("%s: empty body", name)
+		}
+	}
+
+	// 不压缩的小响应保留原始内容
+	if rec := get("/small", "gzip"); rec.Body.String() != `{"ok":true}` {
+		t.Errorf("small body = %q", rec.Body.String())
+	}
+}
@@ -3,22 +3,24 @@ package middleware
 import (
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/readflow/gateway/internal/api"
 	"golang.org/x/time/rate"
 )
 
 // RateLimiter 限流器
 type RateLimiter struct {
-	limiters sync.Map // key: userID, value: *rate.Limiter
-	rps      int      // 每秒请求数
-	burst    int      // 突发容量
+	limiters sync.Map   // key: userID, value: *rate.Limiter
+	limit    rate.Limit // 每秒补充的令牌数
+	burst    int        // 突发容量
 }
 
 // NewRateLimiter 创建限流器
 func NewRateLimiter(rps, burst int) *RateLimiter {
 	return &RateLimiter{
-		rps:   rps,
+		limit: rate.Limit(rps),
 		burst: burst,
 	}
 }
@@ -27,7 +29,7 @@ func NewRateLimiter(rps, burst int) *RateLimiter {
 func (rl *RateLimiter) GetLimiter(userID int64) *rate.Limiter {
 	limiter, ok := rl.limiters.Load(userID)
 	if !ok {
-		limiter = rate.NewLimiter(rate.Limit(rl.rps), rl.burst)
+		limiter = rate.NewLimiter(rl.limit, rl.burst)
 		rl.limiters.Store(userID, limiter)
 	}
 	return limiter.(*rate.Limiter)
@@ -48,14 +50,7 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 		limiter := rl.GetLimiter(userID)
 
 		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"success": false,
-				"error": gin.H{
-					"code":    "RATE_LIMIT_EXCEEDED",
-					"message": "请求频率超限，请稍后重试",
-				},
-			})
-			c.Abort()
+			api.AbortWithError(c, http.StatusTooManyRequests, api.CodeRateLimited, "请求频率超限，请稍后重试")
 			return
 		}
 
@@ -82,3 +77,90 @@ func NewVocabLimiter() *RateLimiter {
 func NewSubscribeLimiter() *RateLimiter {
 	return NewRateLimiter(2, 10) // 约100 req/hour，突发允许10次
 }
+
+// NewSourceRefreshLimiter 创建单源刷新接口的用户限流器（每分钟 6 次，突发 5 次），避免客户端频繁触发抓取
+func NewSourceRefreshLimiter() *RateLimiter {
+	return &RateLimiter{limit: rate.Every(10 * time.Second), burst: 5}
+}
+
+// IPRateLimiter 按客户端 IP 限流，用于登录、注册等无需认证的接口
+// 客户端 IP 取自 c.ClientIP()，只有配置了 TRUSTED_PROXIES 时才采信 X-Forwarded-For
+type IPRateLimiter struct {
+	mu        sync.Mutex
+	limiters  map[string]*ipLimiter
+	limit     rate.Limit
+	burst     int
+	idleTTL   time.Duration // 超过该时长没有请求的 IP 令牌已回满，可以移除
+	lastSweep time.Time
+}
+
+// ipLimiter 单个 IP 的限流器和最近请求时间
+type ipLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewIPRateLimiter 创建按 IP 的限流器，limit 为每秒补充的令牌数，burst 为突发容量
+func NewIPRateLimiter(limit rate.Limit, burst int) *IPRateLimiter {
+	return &IPRateLimiter{
+		limiters: make(map[string]*ipLimiter),
+		limit:    limit,
+		burst:    burst,
+		idleTTL:  time.Duration(float64(burst) / float64(limit) * float64(time.Second)),
+	}
+}
+
+// Allow 判断该 IP 的请求是否放行，并顺带清理空闲的 IP，避免大量来源地址占满内存
+func (rl *IPRateLimiter) Allow(ip string) bool {
+	now := time.Now()
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > time.Minute {
+		for key, l := range rl.limiters {
+			if now.Sub(l.lastSeen) > rl.idleTTL {
+				delete(rl.limiters, key)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	l, ok := rl.limiters[ip]
+	if !ok {
+		l = &ipLimiter{limiter: rate.NewLimiter(rl.limit, rl.burst)}
+		rl.limiters[ip] = l
+	}
+	l.lastSeen = now
+	return l.limiter.AllowN(now, 1)
+}
+
+// Middleware 按 IP 限流中间件
+func (rl *IPRateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rl.Allow(c.ClientIP()) {
+			api.AbortWithError(c, http.StatusTooManyRequests, api.CodeRateLimited, "请求频率超限，请稍后重试")
+			return
+		}
+		c.Next()
+	}
+}
+
+// NewLoginLimiter 创建登录接口的 IP 限流器（每分钟 5 次，突发 10 次），减缓撞库
+func NewLoginLimiter() *IPRateLimiter {
+	return NewIPRateLimiter(rate.Every(12*time.Second), 10)
+}
+
+// NewRegisterLimiter 创建注册接口的 IP 限流器（每小时 10 次，突发 5 次）
+func NewRegisterLimiter() *IPRateLimiter {
+	return NewIPRateLimiter(rate.Every(6*time.Minute), 5)
+}
+
+// NewPasswordResetLimiter 创建找回密码接口的 IP 限流器（每小时 10 次，突发 5 次），限制重置通知的发送频率
+func NewPasswordResetLimiter() *IPRateLimiter {
+	return NewIPRateLimiter(rate.Every(6*time.Minute), 5)
+}
+
+// NewImageProxyLimiter 创建图片代理的 IP 限流器（每秒 20 次，突发 100 次，一篇文章可能有几十张图片）
+func NewImageProxyLimiter() *IPRateLimiter {
+	return NewIPRateLimiter(20, 100)
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+func TestIPRateLimiterIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if err := router.SetTrustedProxies(nil); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	router.POST("/login", NewIPRateLimiter(rate.Every(time.Hour), 2).Middleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// 未配置可信代理时，伪造 X-Forwarded-For 不能换出新的配额
+	for i, xff := range []string{"", "1.1.1.1", "2.2.2.2"} {
+		want := http.StatusOK
+		if i >= 2 {
+			want = http.StatusTooManyRequests
+		}
+		if code := request("203.0.113.7:1234", xff); code != want {
+			t.Errorf("request %d (X-Forwarded-For %q) = %d, want %d", i, xff, code, want)
+		}
+	}
+
+	// 其他 IP 不受影响
+	if code := request("198.51.100.1:1234", ""); code != http.StatusOK {
+		t.Errorf("other IP = %d, want 200", code)
+	}
+}
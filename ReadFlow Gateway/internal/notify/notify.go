@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// PasswordReset 密码重置通知内容
+type PasswordReset struct {
+	UserID    int64
+	Username  string
+	Email     string
+	Token     string // 明文重置令牌，只出现在通知中，数据库保存的是哈希
+	ExpiresAt time.Time
+}
+
+// Notifier 向用户发送账户通知（目前只有密码重置）
+// 默认实现为 LogNotifier 和 WebhookNotifier，邮件等其他渠道实现该接口即可接入
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, msg PasswordReset) error
+}
+
+// New 按配置选择通知方式：配置了 webhook 地址时推送到 webhook，否则写入日志
+func New(webhookURL string) Notifier {
+	if webhookURL != "" {
+		return NewWebhookNotifier(webhookURL)
+	}
+	return LogNotifier{}
+}
+
+// LogNotifier 把通知写入服务日志，适合单人部署或尚未配置通知渠道时由管理员转交
+// 日志中包含可直接使用的重置令牌，日志需妥善保管
+type LogNotifier struct{}
+
+// SendPasswordReset 记录密码重置令牌
+func (LogNotifier) SendPasswordReset(ctx context.Context, msg PasswordReset) error {
+	log.Printf("[NOTIFY] Password reset token for user %s (%s): %s, expires at %s",
+		msg.Username, msg.Email, msg.Token, msg.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// WebhookNotifier 以 JSON POST 推送通知，由外部服务（邮件网关、IM 机器人等）负责送达
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// webhookPayload webhook 请求体
+type webhookPayload struct {
+	Event     string `json:"event"`
+	UserID    int64  `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// NewWebhookNotifier 创建 webhook 通知
+// webhook 由管理员配置，通常是内网服务，因此直连且不限制内网地址
+func NewWebhookNotifier(webhookURL string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    webhookURL,
+		client: utils.NewHTTPClient(utils.HTTPClientOptions{Timeout: 10 * time.Second}),
+	}
+}
+
+// SendPasswordReset 推送密码重置事件，非 2xx 响应视为失败
+func (n *WebhookNotifier) SendPasswordReset(ctx context.Context, msg PasswordReset) error {
+	body, err := json.Marshal(webhookPayload{
+		Event:     "password_reset",
+		UserID:    msg.UserID,
+		Username:  msg.Username,
+		Email:     msg.Email,
+		Token:     msg.Token,
+		ExpiresAt: msg.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// trackingParams 常见的追踪参数，规范化时移除（utm_* 按前缀匹配）
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"gbraid":  true,
+	"wbraid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"mkt_tok": true,
+	"spm":     true,
+	"ref":     true,
+	"ref_src": true,
+	"ref_url": true,
+}
+
+// CanonicalizeArticleURL 规范化文章链接，作为 GUID 之外的去重键
+// 统一为小写主机名、去掉 www. 前缀和默认端口、移除追踪参数和片段，其余查询参数按名称排序；
+// 域名主机在默认端口上的 http 链接升级为 https（IP 地址、localhost 和自定义端口保持 http）。
+// 无法解析或不是 http(s) 的链接原样返回（去掉首尾空白）
+func CanonicalizeArticleURL(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return rawURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if scheme == "http" && port == "" && supportsHTTPS(host) {
+		scheme = "https"
+	}
+	host = strings.TrimPrefix(host, "www.")
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]" // IPv6
+	}
+	if port != "" {
+		host += ":" + port
+	}
+
+	query := u.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if trackingParams[lower] || strings.HasPrefix(lower, "utm_") {
+			query.Del(key)
+		}
+	}
+
+	canonical := url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     u.Path,
+		RawPath:  u.RawPath,
+		RawQuery: query.Encode(),
+	}
+	if canonical.Path == "" {
+		canonical.Path = "/"
+	}
+	return canonical.String()
+}
+
+// supportsHTTPS 判断主机是否视为支持 https：公网域名默认支持，IP 地址和本地主机名不升级
+func supportsHTTPS(host string) bool {
+	if net.ParseIP(host) != nil || host == "localhost" || !strings.Contains(host, ".") {
+		return false
+	}
+	return !strings.HasSuffix(host, ".local") && !strings.HasSuffix(host, ".localhost")
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestCanonicalizeArticleURL(t *testing.T) {
+	want := "https://example.com/posts/hello"
+	variants := []string{
+		"https://example.com/posts/hello",
+		"http://example.com/posts/hello",
+		"https://www.example.com/posts/hello",
+		"HTTP://WWW.Example.COM:80/posts/hello",
+		"https://example.com:443/posts/hello#comments",
+		"https://example.com/posts/hello?utm_source=rss&utm_medium=feed&utm_campaign=x",
+		"  https://example.com/posts/hello?fbclid=abc&ref=rss  ",
+	}
+	for _, v := range variants {
+		if got := CanonicalizeArticleURL(v); got != want {
+			t.Errorf("CanonicalizeArticleURL(%q) = %q, want %q", v, got, want)
+		}
+	}
+
+	cases := []struct{ in, want string }{
+		// 保留有意义的参数，并按名称排序
+		{"https://example.com/read?id=42&utm_source=x&page=2", "https://example.com/read?id=42&page=2"},
+		{"https://example.com/read?page=2&id=42", "https://example.com/read?id=42&page=2"},
+		{"https://example.com", "https://example.com/"},
+		// IP、localhost 和自定义端口不升级为 https
+		{"http://192.168.1.10/post", "http://192.168.1.10/post"},
+		{"http://localhost/post", "http://localhost/post"},
+		{"http://example.com:8080/post", "http://example.com:8080/post"},
+		// 非 http(s) 链接和无法解析的链接原样返回
+		{"mailto:someone@example.com", "mailto:someone@example.com"},
+		{"urn:uuid:1234", "urn:uuid:1234"},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		if got := CanonicalizeArticleURL(tc.in); got != tc.want {
+			t.Errorf("CanonicalizeArticleURL(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
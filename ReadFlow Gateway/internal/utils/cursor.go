@@ -40,12 +40,14 @@ func DecodeCursor(cursor string) (*CursorData, error) {
 
 	// 尝试 Base64 解码
 	decoded, err := base64.URLEncoding.DecodeString(cursor)
-	if err != nil {
-		// 如果解码失败，可能是未编码的格式（向后兼容）
-		return parseCursorRaw(cursor)
+	if err == nil {
+		if data, err := parseCursorRaw(string(decoded)); err == nil {
+			return data, nil
+		}
 	}
 
-	return parseCursorRaw(string(decoded))
+	// 未编码的格式（向后兼容）；"timestamp_id" 长度恰为 4 的倍数时也能按 Base64 解码，解析失败同样回退
+	return parseCursorRaw(cursor)
 }
 
 // parseCursorRaw 解析原始游标字符串（格式："timestamp_id"）
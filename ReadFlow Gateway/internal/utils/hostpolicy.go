@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrHostNotAllowed 目标主机不在允许列表中，或在禁止列表中
+var ErrHostNotAllowed = errors.New("host not allowed")
+
+// HostPolicy 出站请求的主机允许/禁止列表（RSS_ALLOWED_HOSTS / RSS_DENIED_HOSTS）
+// 条目按域名后缀匹配：example.com 同时匹配 example.com 和 feeds.example.com，开头的 "*." 可省略
+// 禁止列表优先；允许列表为空时允许所有未被禁止的主机。nil 表示不限制
+type HostPolicy struct {
+	allowed []string
+	denied  []string
+}
+
+// NewHostPolicy 创建主机策略，两个列表都为空时返回 nil（不限制）
+func NewHostPolicy(allowed, denied []string) *HostPolicy {
+	p := &HostPolicy{allowed: normalizeHostEntries(allowed), denied: normalizeHostEntries(denied)}
+	if len(p.allowed) == 0 && len(p.denied) == 0 {
+		return nil
+	}
+	return p
+}
+
+// Allows 判断主机名（不含端口）是否允许访问
+func (p *HostPolicy) Allows(host string) bool {
+	if p == nil {
+		return true
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if host == "" || matchHostEntries(host, p.denied) {
+		return false
+	}
+	return len(p.allowed) == 0 || matchHostEntries(host, p.allowed)
+}
+
+// Check 检查地址的主机是否允许访问，不允许时返回包装了 ErrHostNotAllowed 的错误
+func (p *HostPolicy) Check(rawURL string) error {
+	if p == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if !p.Allows(u.Hostname()) {
+		return fmt.Errorf("%w: %s", ErrHostNotAllowed, u.Hostname())
+	}
+	return nil
+}
+
+// maxRedirects 重定向次数上限，与 http.Client 的默认值一致
+const maxRedirects = 10
+
+// CheckRedirect 用作 http.Client.CheckRedirect：每一跳在发出请求前检查目标主机，
+// 避免被重定向到列表之外的主机；nil 策略只限制重定向次数
+func (p *HostPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return p.Check(req.URL.String())
+}
+
+// normalizeHostEntries 规范化列表条目：小写，去掉开头的 "*." / "." 和结尾的 "."
+func normalizeHostEntries(entries []string) []string {
+	var result []string
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		entry = strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+		entry = strings.TrimSuffix(entry, ".")
+		if entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// matchHostEntries 主机名等于某个条目或是其子域名
+func matchHostEntries(host string, entries []string) bool {
+	for _, entry := range entries {
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHostPolicy(t *testing.T) {
+	if NewHostPolicy(nil, []string{" ", ""}) != nil {
+		t.Errorf("empty lists should disable the policy")
+	}
+	var unrestricted *HostPolicy
+	if !unrestricted.Allows("anything.example") || unrestricted.Check("https://anything.example/feed") != nil {
+		t.Errorf("nil policy should allow everything")
+	}
+
+	policy := NewHostPolicy([]string{"example.com", "*.feeds.net", "Blog.Example.org."}, []string{"private.example.com"})
+	cases := map[string]bool{
+		"example.com":           true,
+		"www.example.com":       true,
+		"EXAMPLE.COM.":          true,
+		"notexample.com":        false,
+		"private.example.com":   false,
+		"a.private.example.com": false,
+		"feeds.net":             true,
+		"x.feeds.net":           true,
+		"blog.example.org":      true,
+		"example.org":           false,
+		"":                      false,
+	}
+	for host, want := range cases {
+		if got := policy.Allows(host); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", host, got, want)
+		}
+	}
+
+	denyOnly := NewHostPolicy(nil, []string{"bad.example"})
+	if !denyOnly.Allows("good.example") || denyOnly.Allows("cdn.bad.example") {
+		t.Errorf("deny-only policy should allow everything except denied hosts")
+	}
+
+	if err := policy.Check("https://www.example.com:8443/rss"); err != nil {
+		t.Errorf("Check allowed URL: %v", err)
+	}
+	if err := policy.Check("https://evil.test/rss"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("Check denied URL = %v, want ErrHostNotAllowed", err)
+	}
+}
+
+func TestHostPolicyCheckRedirect(t *testing.T) {
+	policy := NewHostPolicy([]string{"example.com"}, nil)
+	req := func(rawURL string) *http.Request {
+		r, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	if err := policy.CheckRedirect(req("https://cdn.example.com/rss"), nil); err != nil {
+		t.Errorf("redirect to allowed host: %v", err)
+	}
+	if err := policy.CheckRedirect(req("https://evil.test/rss"), nil); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("redirect to denied host = %v, want ErrHostNotAllowed", err)
+	}
+	via := make([]*http.Request, maxRedirects)
+	if err := policy.CheckRedirect(req("https://example.com/rss"), via); err == nil {
+		t.Error("expected error after too many redirects")
+	}
+	// nil 策略只限制重定向次数
+	var none *HostPolicy
+	if err := none.CheckRedirect(req("https://evil.test/rss"), nil); err != nil {
+		t.Errorf("nil policy redirect: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProxyDirect 订阅源代理设置为该值时绕过全局出站代理直连
+const ProxyDirect = "direct"
+
+// HTTPClientOptions 出站 HTTP 客户端参数，零值字段使用默认值
+type HTTPClientOptions struct {
+	Timeout             time.Duration
+	Proxy               *url.URL // 为 nil 时直连
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	CheckRedirect       func(req *http.Request, via []*http.Request) error
+	PublicOnly          bool // 只允许连接公网地址，拒绝内网、回环和链路本地地址
+	// 共享连接池，设置后忽略 Proxy 和连接池参数（代理由共享连接池决定）；PublicOnly 的客户端不使用共享连接池
+	// 共享连接池的客户端通常不设 Timeout，由每个请求的 context 控制时限
+	Transport *http.Transport
+}
+
+// 共享连接池参数：订阅源、正文和图片经常来自同一 CDN，每个主机保留更多空闲连接以复用 TLS 连接
+const (
+	sharedMaxIdleConns        = 100
+	sharedMaxIdleConnsPerHost = 16
+	sharedIdleConnTimeout     = 90 * time.Second
+)
+
+// NewSharedTransport 创建供 RSS 抓取、正文提取和图片处理共用的连接池，proxy 为 nil 时直连
+// 连接池不设整体超时，超时由各客户端的 Timeout 或请求的 context 控制
+func NewSharedTransport(proxy *url.URL) *http.Transport {
+	transport := newTransport(proxy, sharedMaxIdleConns, sharedMaxIdleConnsPerHost, sharedIdleConnTimeout)
+	// 空闲连接保持较久，同一主机的后续请求无需重新解析 DNS 和握手
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// NewHTTPClient 创建出站 HTTP 客户端
+// RSS、正文、图片等所有对外请求统一经此创建，保证出站代理一致生效
+// HTTPS 请求经代理时使用 CONNECT 隧道，TLS 握手仍在本端与源站之间完成
+func NewHTTPClient(opts HTTPClientOptions) *http.Client {
+	if opts.Transport != nil && !opts.PublicOnly {
+		return &http.Client{
+			Timeout:       opts.Timeout,
+			Transport:     opts.Transport,
+			CheckRedirect: opts.CheckRedirect,
+		}
+	}
+
+	if opts.MaxIdleConns == 0 {
+		opts.MaxIdleConns = 10
+	}
+	if opts.IdleConnTimeout == 0 {
+		opts.IdleConnTimeout = 90 * time.Second
+	}
+
+	transport := newTransport(opts.Proxy, opts.MaxIdleConns, opts.MaxIdleConnsPerHost, opts.IdleConnTimeout)
+	var roundTripper http.RoundTripper = transport
+	if opts.PublicOnly {
+		if opts.Proxy != nil {
+			// 经代理时本端只连接代理，改为在每次请求前校验目标主机
+			roundTripper = &publicOnlyTransport{next: transport}
+		} else {
+			dialer := &net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+				Control:   publicOnlyControl,
+			}
+			transport.DialContext = dialer.DialContext
+		}
+	}
+
+	return &http.Client{
+		Timeout:       opts.Timeout,
+		Transport:     roundTripper,
+		CheckRedirect: opts.CheckRedirect,
+	}
+}
+
+// newTransport 创建启用 HTTP/2 的连接池
+func newTransport(proxy *url.URL, maxIdle, maxIdlePerHost int, idleTimeout time.Duration) *http.Transport {
+	transport := &http.Transport{
+		MaxIdleConns:          maxIdle,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		IdleConnTimeout:       idleTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     true,
+	}
+	if proxy != nil {
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return transport
+}
+
+// ParseProxyURL 解析出站代理地址，支持 http / https / socks5
+// 空字符串返回 nil（直连）；地址可带 user:pass@ 认证信息
+func ParseProxyURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url")
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (http/https/socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url missing host")
+	}
+	return u, nil
+}
+
+// RedactProxyURL 隐藏代理地址中的密码，用于日志和接口输出
+func RedactProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// newCountingTLSServer 启动统计新建连接数的 HTTPS 测试服务器
+func newCountingTLSServer(tb testing.TB) (*httptest.Server, *int64) {
+	var conns int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.StartTLS()
+	tb.Cleanup(server.Close)
+	return server, &conns
+}
+
+// trustServer 让连接池信任测试服务器的证书
+func trustServer(transport *http.Transport, server *httptest.Server) {
+	transport.TLSClientConfig = &tls.Config{RootCAs: server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+}
+
+func get(tb testing.TB, client *http.Client, url string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		tb.Fatalf("GET: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func TestSharedTransportReusesConnections(t *testing.T) {
+	server, conns := newCountingTLSServer(t)
+
+	transport := NewSharedTransport(nil)
+	trustServer(transport, server)
+	defer transport.CloseIdleConnections()
+
+	// 模拟 RSS 抓取、正文提取和图片处理三个客户端依次访问同一主机
+	for i := 0; i < 3; i++ {
+		get(t, NewHTTPClient(HTTPClientOptions{Transport: transport}), server.URL)
+	}
+	if got := atomic.LoadInt64(conns); got != 1 {
+		t.Errorf("new connections = %d, want 1", got)
+	}
+}
+
+// BenchmarkImageFetch 对比各客户端独立连接池与共享连接池访问同一主机的开销
+func BenchmarkImageFetch(b *testing.B) {
+	server, _ := newCountingTLSServer(b)
+
+	b.Run("separate", func(b *testing.B) {
+		clients := make([]*http.Client, 3)
+		for i := range clients {
+			transport := NewSharedTransport(nil)
+			trustServer(transport, server)
+			clients[i] = NewHTTPClient(HTTPClientOptions{Transport: transport})
+		}
+		for i := 0; i < b.N; i++ {
+			get(b, clients[i%len(clients)], server.URL)
+		}
+	})
+
+	b.Run("shared", func(b *testing.B) {
+		transport := NewSharedTransport(nil)
+		trustServer(transport, server)
+		clients := make([]*http.Client, 3)
+		for i := range clients {
+			clients[i] = NewHTTPClient(HTTPClientOptions{Transport: transport})
+		}
+		for i := 0; i < b.N; i++ {
+			get(b, clients[i%len(clients)], server.URL)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// ErrPrivateAddress 目标地址为内网、回环或链路本地地址
+// 代用户访问任意地址的接口（图片代理、订阅源预览）遇到该错误时拒绝请求，防止被用作 SSRF 跳板
+var ErrPrivateAddress = errors.New("destination resolves to a private address")
+
+// cgnatNet 运营商级 NAT 地址段（100.64.0.0/10），net.IP.IsPrivate 不包含该段
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublicIP 判断 IP 是否为可公开访问的地址
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		// 0.0.0.0/8 在部分系统上等同于本机
+		if ip4[0] == 0 || cgnatNet.Contains(ip4) {
+			return false
+		}
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// CheckPublicHost 解析主机名，任一地址不是公网地址时返回 ErrPrivateAddress
+// 用于经出站代理的请求（实际连接由代理建立）和重定向的逐跳校验
+func CheckPublicHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if !IsPublicIP(ip) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !IsPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s (%s)", ErrPrivateAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// publicOnlyControl net.Dialer 的 Control 回调，在每次建立连接前检查实际连接的 IP
+// 检查发生在 DNS 解析之后，重定向和 DNS rebinding 都无法绕过
+func publicOnlyControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(net.ParseIP(host)) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+// publicOnlyTransport 经出站代理时使用：连接由代理建立，只能在发出请求前解析校验目标主机
+type publicOnlyTransport struct {
+	next http.RoundTripper
+}
+
+func (t *publicOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := CheckPublicHost(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// SignURL 使用 HMAC-SHA256 为地址生成签名（十六进制，截取前 16 字节）
+func SignURL(key, rawURL string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(rawURL))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// VerifyURLSignature 校验 SignURL 生成的签名
+func VerifyURLSignature(key, rawURL, signature string) bool {
+	return hmac.Equal([]byte(SignURL(key, rawURL)), []byte(signature))
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	cases := map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	}
+	for addr, want := range cases {
+		if got := IsPublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestPublicOnlyClientRejectsLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPClientOptions{PublicOnly: true})
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("expected ErrPrivateAddress, got %v", err)
+	}
+
+	// 未开启时正常访问
+	resp, err := NewHTTPClient(HTTPClientOptions{}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestURLSignature(t *testing.T) {
+	sig := SignURL("key", "https://example.com/a.png")
+	if !VerifyURLSignature("key", "https://example.com/a.png", sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if VerifyURLSignature("key", "http://127.0.0.1/a.png", sig) {
+		t.Fatal("signature accepted for a different url")
+	}
+	if VerifyURLSignature("other", "https://example.com/a.png", sig) {
+		t.Fatal("signature accepted for a different key")
+	}
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"sync"
+	"unicode/utf8"
+)
+
+// 用户提供的正则表达式（过滤规则等）统一经由本文件校验、编译和匹配
+// Go 的 regexp 是 RE2 实现，不会灾难性回溯，但匹配耗时与 "编译后的程序大小 × 文本长度" 成正比，
+// 因此在创建时限制表达式长度和复杂度，匹配时限制文本长度并设超时兜底
+
+const (
+	// MaxRegexPatternLen 表达式最大字符数
+	MaxRegexPatternLen = 500
+	// MaxRegexInputLen 参与匹配的最大文本字节数，超出部分不匹配
+	MaxRegexInputLen = 1 << 20
+	// maxRegexProgSize 编译后的最大指令数，x{1000} 之类的计数重复会展开成大量指令
+	maxRegexProgSize = 2000
+	// maxRegexCacheSize 编译结果缓存的最大条目数，满时整体清空
+	maxRegexCacheSize = 1000
+)
+
+var (
+	// ErrRegexTooLong 表达式超过 MaxRegexPatternLen 个字符
+	ErrRegexTooLong = errors.New("regex too long")
+	// ErrRegexTooComplex 表达式编译后的程序过大
+	ErrRegexTooComplex = errors.New("regex too complex")
+	// ErrRegexTimeout 匹配超时，调用方应视为不匹配
+	ErrRegexTimeout = errors.New("regex match timed out")
+)
+
+var regexCache = struct {
+	sync.Mutex
+	entries map[string]*regexp.Regexp
+}{entries: make(map[string]*regexp.Regexp)}
+
+// ValidateRegex 校验用户提供的正则表达式：语法错误返回 *syntax.Error，过长或过于复杂时返回 ErrRegexTooLong / ErrRegexTooComplex
+func ValidateRegex(pattern string) error {
+	if utf8.RuneCountInString(pattern) > MaxRegexPatternLen {
+		return ErrRegexTooLong
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return err
+	}
+	if len(prog.Inst) > maxRegexProgSize {
+		return fmt.Errorf("%w: %d instructions", ErrRegexTooComplex, len(prog.Inst))
+	}
+	return nil
+}
+
+// CompileRegex 校验并编译用户提供的正则表达式，编译结果按表达式缓存（*regexp.Regexp 可并发使用）
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCache.Lock()
+	re, ok := regexCache.entries[pattern]
+	regexCache.Unlock()
+	if ok {
+		return re, nil
+	}
+
+	if err := ValidateRegex(pattern); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	regexCache.Lock()
+	if len(regexCache.entries) >= maxRegexCacheSize {
+		regexCache.entries = make(map[string]*regexp.Regexp)
+	}
+	regexCache.entries[pattern] = re
+	regexCache.Unlock()
+	return re, nil
+}
+
+// FindRegexIndex 在 ctx 的期限内查找 re 在 text 中的首个匹配，返回字节位置，未匹配时为 nil
+// 只匹配前 MaxRegexInputLen 字节；ctx 先结束时放弃等待并返回 ErrRegexTimeout，
+// 后台的匹配在有限时间内自行结束（RE2 的耗时与文本长度线性相关）
+func FindRegexIndex(ctx context.Context, re *regexp.Regexp, text string) ([]int, error) {
+	text = truncateUTF8(text, MaxRegexInputLen)
+
+	done := make(chan []int, 1)
+	go func() {
+		done <- re.FindStringIndex(text)
+	}()
+	select {
+	case span := <-done:
+		return span, nil
+	case <-ctx.Done():
+		return nil, ErrRegexTimeout
+	}
+}
+
+// truncateUTF8 截断到不超过 maxBytes 字节，不截断多字节字符
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	for maxBytes > 0 && !utf8.RuneStart(s[maxBytes]) {
+		maxBytes--
+	}
+	return s[:maxBytes]
+}
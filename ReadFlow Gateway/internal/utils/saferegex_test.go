@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"regexp/syntax"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRegex(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		want      error
+		syntaxErr bool
+	}{
+		{pattern: `广告\d+`},
+		{pattern: `(?i)^(sponsored|promoted):`},
+		{pattern: `(a+)+$`}, // 回溯引擎的经典灾难模式，RE2 下为线性时间
+		{pattern: `a{1000}`},
+		{pattern: strings.Repeat("a", MaxRegexPatternLen+1), want: ErrRegexTooLong},
+		{pattern: `[a-z]{1000}[0-9]{1000}`, want: ErrRegexTooComplex},
+		{pattern: `((((\w{100}){10}){10}){10})`, syntaxErr: true}, // 嵌套重复总次数超过 1000
+		{pattern: `(abc`, syntaxErr: true},
+		{pattern: `a(?=b)`, syntaxErr: true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateRegex(tt.pattern)
+		var syntaxErr *syntax.Error
+		switch {
+		case tt.want != nil:
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ValidateRegex(%.40q) = %v, want %v", tt.pattern, err, tt.want)
+			}
+		case tt.syntaxErr:
+			if !errors.As(err, &syntaxErr) {
+				t.Errorf("ValidateRegex(%q) = %v, want syntax error", tt.pattern, err)
+			}
+		case err != nil:
+			t.Errorf("ValidateRegex(%q) = %v, want nil", tt.pattern, err)
+		}
+	}
+}
+
+func TestCompileRegexCaches(t *testing.T) {
+	first, err := CompileRegex(`foo\d+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := CompileRegex(`foo\d+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("CompileRegex did not reuse the cached regexp")
+	}
+	if _, err := CompileRegex(`[a-z]{1000}[0-9]{1000}`); !errors.Is(err, ErrRegexTooComplex) {
+		t.Errorf("CompileRegex(too complex) = %v", err)
+	}
+}
+
+func TestFindRegexIndexAdversarial(t *testing.T) {
+	// 回溯引擎下为指数时间的模式与输入，RE2 应在线性时间内给出结果
+	patterns := []string{`(a+)+$`, `(a|aa)*b`, `(\w+\s?)*x$`, `(.*a){20}`}
+	input := strings.Repeat("a", 100<<10) + "!"
+
+	for _, pattern := range patterns {
+		re, err := CompileRegex(pattern)
+		if err != nil {
+			t.Fatalf("CompileRegex(%q): %v", pattern, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		start := time.Now()
+		_, err = FindRegexIndex(ctx, re, input)
+		cancel()
+		if err != nil {
+			t.Errorf("FindRegexIndex(%q) = %v after %v", pattern, err, time.Since(start))
+		}
+	}
+}
+
+func TestFindRegexIndexLimits(t *testing.T) {
+	re, err := CompileRegex(`x$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过 MaxRegexInputLen 的部分不参与匹配
+	span, err := FindRegexIndex(context.Background(), re, strings.Repeat("a", MaxRegexInputLen)+"x")
+	if err != nil || span != nil {
+		t.Errorf("match beyond input limit = %v, %v, want no match", span, err)
+	}
+
+	// 期限已过时放弃等待
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slow, err := CompileRegex(`(\w+\s?)*x$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindRegexIndex(ctx, slow, strings.Repeat("ab ", MaxRegexInputLen/3)); !errors.Is(err, ErrRegexTimeout) {
+		t.Errorf("FindRegexIndex with expired context = %v, want ErrRegexTimeout", err)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if got := truncateUTF8("广告", 4); got != "广" {
+		t.Errorf("truncateUTF8 = %q, want %q", got, "广")
+	}
+	if got := truncateUTF8("abc", 10); got != "abc" {
+		t.Errorf("truncateUTF8 = %q, want %q", got, "abc")
+	}
+}
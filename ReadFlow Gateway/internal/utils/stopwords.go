@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 关键词提取使用的停用词表（按语言区分）
+// 英文停用词对所有语言都生效，因为中文等内容里经常夹杂英文
+
+var englishStopWords = toSet(
+	"the", "a", "an", "and", "or", "but", "in", "on", "at", "to", "for", "of", "as", "by",
+	"is", "was", "are", "were", "be", "been", "being", "it", "its", "this", "that", "these",
+	"those", "with", "from", "into", "about", "than", "then", "there", "their", "they", "them",
+	"what", "which", "who", "whom", "when", "where", "why", "how", "all", "any", "both", "each",
+	"few", "more", "most", "other", "some", "such", "not", "only", "own", "same", "too", "very",
+	"can", "will", "just", "should", "would", "could", "may", "might", "must", "has", "have",
+	"had", "having", "does", "did", "doing", "done", "you", "your", "yours", "our", "ours",
+	"his", "her", "hers", "she", "him", "we", "i", "me", "my", "mine", "also", "after", "before",
+	"over", "under", "again", "out", "up", "down", "off", "once", "here", "because", "while",
+	"if", "so", "no", "nor", "now", "new", "one", "two", "said", "says", "like", "get", "got",
+	"make", "made", "many", "much", "even", "still", "well", "back", "way", "year", "years",
+	"people", "time", "according", "via", "per", "among", "within", "without", "through",
+	"during", "against", "between", "including", "since", "until", "although", "however",
+	"yet", "another", "every", "first", "last", "next", "see", "use", "used", "using",
+	"read", "continue", "reading", "click", "http", "https", "www", "com",
+)
+
+var chineseStopWords = toSet(
+	"我们", "你们", "他们", "她们", "它们", "这个", "那个", "这些", "那些", "一个", "一些",
+	"没有", "因为", "所以", "但是", "如果", "可以", "什么", "已经", "还是", "就是", "自己",
+	"以及", "进行", "通过", "其中", "对于", "由于", "这样", "那样", "这种", "那种", "不是",
+	"还有", "以上", "以下", "之后", "之前", "目前", "表示", "认为", "时候", "今天", "现在",
+	"一下", "一样", "同时", "而且", "并且", "或者", "然后", "其实", "不过", "只是", "非常",
+	"可能", "需要", "应该", "这里", "那里", "我的", "你的", "他的", "她的", "的是", "了一",
+	"是一", "在这", "也是", "都是", "就会", "不会", "会在", "有一", "一种", "一位", "问题",
+)
+
+// chineseStopChars 单字停用词：包含这些字的二元组直接丢弃
+var chineseStopChars = map[rune]bool{
+	'的': true, '了': true, '在': true, '是': true, '我': true, '有': true, '和': true,
+	'就': true, '不': true, '也': true, '都': true, '而': true, '及': true,
+	'与': true, '着': true, '或': true, '一': true, '个': true, '这': true,
+	'那': true, '你': true, '他': true, '她': true, '它': true, '们': true, '吗': true,
+	'呢': true, '吧': true, '啊': true, '之': true, '为': true, '被': true, '把': true,
+	'让': true, '给': true, '从': true, '向': true, '对': true, '等': true, '其': true,
+	'此': true, '并': true, '还': true, '又': true, '很': true, '要': true,
+	'将': true, '已': true, '于': true, '以': true, '所': true, '但': true,
+}
+
+var japaneseStopWords = toSet(
+	"これ", "それ", "あれ", "この", "その", "あの", "ここ", "そこ", "です", "ます", "した",
+	"して", "する", "いる", "ある", "なる", "こと", "もの", "ため", "よう", "から", "まで",
+)
+
+var frenchStopWords = toSet(
+	"le", "la", "les", "des", "une", "un", "du", "de", "et", "est", "que", "qui", "dans",
+	"pour", "par", "sur", "avec", "sont", "pas", "plus", "mais", "ont", "été", "aux", "ces",
+	"cette", "son", "ses", "leur", "leurs", "nous", "vous", "ils", "elle", "elles", "comme",
+)
+
+var germanStopWords = toSet(
+	"der", "die", "das", "und", "ist", "nicht", "mit", "von", "den", "dem", "des", "ein",
+	"eine", "einen", "einem", "auf", "für", "sich", "auch", "als", "wie", "aus", "bei",
+	"nach", "oder", "aber", "sind", "wird", "werden", "wurde", "noch", "nur", "über",
+)
+
+var spanishStopWords = toSet(
+	"el", "la", "los", "las", "un", "una", "unos", "unas", "de", "del", "y", "que", "en",
+	"es", "por", "para", "con", "no", "se", "su", "sus", "al", "lo", "como", "más", "pero",
+	"sobre", "este", "esta", "ese", "esa", "son", "fue", "ha", "han", "muy", "también",
+)
+
+// stopWordsByLanguage 语言代码 -> 停用词表
+var stopWordsByLanguage = map[string]map[string]bool{
+	"en": englishStopWords,
+	"zh": chineseStopWords,
+	"ja": japaneseStopWords,
+	"fr": frenchStopWords,
+	"de": germanStopWords,
+	"es": spanishStopWords,
+}
+
+// latinLanguages 拉丁字母文本中按停用词命中数区分的语言（第一个为默认）
+var latinLanguages = []string{"en", "fr", "de", "es"}
+
+// minLanguageHits 判定为非英文语言所需的最少停用词命中数
+const minLanguageHits = 3
+
+// detectLatinLanguage 按各语言停用词的命中数识别拉丁字母文本的语言，命中太少时按英文处理
+func detectLatinLanguage(text string) string {
+	hits := make(map[string]int, len(latinLanguages))
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		for _, lang := range latinLanguages {
+			if stopWordsByLanguage[lang][w] {
+				hits[lang]++
+			}
+		}
+	}
+
+	best := latinLanguages[0]
+	for _, lang := range latinLanguages[1:] {
+		if hits[lang] >= minLanguageHits && hits[lang] > hits[best] {
+			best = lang
+		}
+	}
+	return best
+}
+
+// isStopWord 判断词语在指定语言下是否为停用词
+func isStopWord(word, lang string) bool {
+	if englishStopWords[word] {
+		return true
+	}
+	if set, ok := stopWordsByLanguage[lang]; ok && set[word] {
+		return true
+	}
+	// 中文内容常与其他语言混排，统一检查中文停用词
+	return chineseStopWords[word]
+}
+
+// toSet 将字符串列表转换为集合
+func toSet(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
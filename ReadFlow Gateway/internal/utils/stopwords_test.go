@@ -0,0 +1,33 @@
+package utils
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	p := NewTextProcessor()
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"The new release of the compiler is faster than the previous one.", "en"},
+		{"Le gouvernement a présenté mardi un projet de loi sur la réforme des retraites, qui sera examiné par les députés.", "fr"},
+		{"Die Regierung hat am Dienstag einen Gesetzentwurf vorgelegt, der auch die Rente betrifft und nicht nur die Steuern.", "de"},
+		{"El gobierno presentó el martes un proyecto de ley para la reforma de las pensiones, que también afecta a los jóvenes.", "es"},
+		{"今天发布的新版本编译速度更快", "zh"},
+		{"Kubernetes", "en"},
+	}
+	for _, c := range cases {
+		if got := p.DetectLanguage(c.text); got != c.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", c.text, got, c.want)
+		}
+	}
+}
+
+func TestExtractKeywordsDropsFrenchStopWords(t *testing.T) {
+	p := NewTextProcessor()
+	text := "Les retraites sont dans les débats: les retraites, les syndicats et les retraites pour les députés."
+	for _, kw := range p.ExtractKeywords(text, 5) {
+		if kw == "les" || kw == "pour" || kw == "dans" {
+			t.Errorf("stop word %q returned as keyword", kw)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 摘要长度（字符数）
+const (
+	DefaultSummaryLength = 200
+	MinSummaryLength     = 50
+	MaxSummaryLength     = 1000
+)
+
+// 判断 feed 自带 description 是否为人工摘要的阈值
+const (
+	minHumanSummaryRunes = 20  // 太短的通常是 "Read more"、"Comments" 之类的占位文字
+	maxHumanSummaryRatio = 0.8 // 接近正文长度的是全文而不是摘要
+)
+
+// tagLikeRegex 提取纯文本后仍残留的标签（description 被重复转义时常见）
+var tagLikeRegex = regexp.MustCompile(`<[a-zA-Z/!][^<>]*>`)
+
+// summarySkipElements 提取摘要文本时整体跳过的元素
+var summarySkipElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Iframe: true, atom.Svg: true,
+}
+
+// summaryBlockElements 块级元素，前后补空格避免相邻段落的文字粘连
+var summaryBlockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Tr: true, atom.Td: true, atom.Th: true,
+	atom.Figcaption: true, atom.Section: true, atom.Article: true, atom.Header: true, atom.Footer: true,
+}
+
+// GenerateSummary 从 HTML 生成纯文本摘要
+// maxLength 为最大字符数，<= 0 时使用 DefaultSummaryLength；优先在句末标点处截断
+func (p *TextProcessor) GenerateSummary(htmlText string, maxLength int) string {
+	if maxLength <= 0 {
+		maxLength = DefaultSummaryLength
+	}
+	return truncateAtBoundary(p.summaryText(htmlText), maxLength)
+}
+
+// SelectSummary 生成文章摘要：feed 自带的 description 是人工撰写的摘要时优先使用，否则从正文生成
+func (p *TextProcessor) SelectSummary(description, content string, maxLength int) string {
+	if desc := p.summaryText(description); isHumanSummary(desc, p.summaryText(content)) {
+		if maxLength <= 0 {
+			maxLength = DefaultSummaryLength
+		}
+		return truncateAtBoundary(desc, maxLength)
+	}
+	if content == "" {
+		content = description
+	}
+	return p.GenerateSummary(content, maxLength)
+}
+
+// isHumanSummary 判断 description 是否为独立撰写的摘要
+// 正文开头的节选（如 WordPress 自动生成的 "[…]" 摘要）和全文都不算
+func isHumanSummary(desc, content string) bool {
+	descRunes := utf8.RuneCountInString(desc)
+	if descRunes < minHumanSummaryRunes {
+		return false
+	}
+	if content == "" {
+		return false // 没有正文时 description 就是正文
+	}
+	if float64(descRunes) > float64(utf8.RuneCountInString(content))*maxHumanSummaryRatio {
+		return false
+	}
+
+	excerpt := strings.TrimSpace(strings.TrimRight(desc, ".…[] "))
+	return !strings.HasPrefix(content, excerpt)
+}
+
+// summaryText 提取 HTML 中的可读文本（跳过脚本和样式，块级元素之间补空格）并合并空白
+// description 被重复转义时（&lt;p&gt;）第一次提取得到的仍是 HTML，再提取一次；
+// 最后去掉残留的标签，保证摘要中不含 HTML
+func (p *TextProcessor) summaryText(htmlText string) string {
+	text := p.readableText(htmlText)
+	if tagLikeRegex.MatchString(text) {
+		text = p.readableText(text)
+	}
+	text = tagLikeRegex.ReplaceAllString(text, " ")
+	return p.cleanWhitespace(text)
+}
+
+// readableText 提取 HTML 文本节点，解析失败时回退到正则去标签
+func (p *TextProcessor) readableText(htmlText string) string {
+	if htmlText == "" {
+		return ""
+	}
+
+	doc, err := html.Parse(strings.NewReader(htmlText))
+	if err != nil {
+		return p.stripHTMLRegex(htmlText)
+	}
+
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && summarySkipElements[n.DataAtom] {
+			return
+		}
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+		block := n.Type == html.ElementNode && summaryBlockElements[n.DataAtom]
+		if block {
+			sb.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			sb.WriteByte(' ')
+		}
+	}
+	walk(doc)
+	return sb.String()
+}
+
+// 截断时依次尝试的断点：句末标点 > 分句标点 > 空格
+const (
+	sentenceEnds = "。！？!?.…"
+	clauseEnds   = "，；、,;：:"
+)
+
+// closingMarks 句末标点后紧跟的引号或括号，截断时一并保留
+const closingMarks = "”’」』）)》\"'"
+
+// truncateAtBoundary 按字符数截断纯文本，尽量在句子边界处截断
+// 中文没有空格分词，找不到句末标点时退而在逗号等分句标点处截断，最后才硬截断
+func truncateAtBoundary(text string, maxLength int) string {
+	// 使用 rune 计数（支持多字节字符）
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+
+	// 断点不早于一半长度，避免摘要过短
+	minCut := maxLength / 2
+
+	// 1. 句末标点：完整句子不需要省略号
+	for i := maxLength - 1; i >= minCut; i-- {
+		if !strings.ContainsRune(sentenceEnds, runes[i]) || !isSentenceEnd(runes, i) {
+			continue
+		}
+		end := i + 1
+		for end < maxLength && strings.ContainsRune(closingMarks, runes[end]) {
+			end++
+		}
+		return strings.TrimSpace(string(runes[:end]))
+	}
+
+	// 2. 分句标点
+	for i := maxLength - 1; i >= minCut; i-- {
+		if strings.ContainsRune(clauseEnds, runes[i]) {
+			return strings.TrimSpace(string(runes[:i])) + "..."
+		}
+	}
+
+	// 3. 空格（英文单词边界）
+	for i := maxLength; i >= minCut; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return strings.TrimSpace(string(runes[:i])) + "..."
+		}
+	}
+
+	return string(runes[:maxLength]) + "..."
+}
+
+// isSentenceEnd 判断 runes[i] 处的标点是否结束了一个句子
+// 英文句点等半角标点后必须跟空白，避免把 3.14、example.com 之类当成句末
+func isSentenceEnd(runes []rune, i int) bool {
+	if runes[i] > unicode.MaxASCII {
+		return true
+	}
+	next := i + 1
+	for next < len(runes) && strings.ContainsRune(closingMarks, runes[next]) {
+		next++
+	}
+	return next >= len(runes) || unicode.IsSpace(runes[next])
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncateAtBoundary(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		max  int
+		want string
+	}{
+		{
+			name: "short text unchanged",
+			text: "短文本。",
+			max:  10,
+			want: "短文本。",
+		},
+		{
+			name: "cut at CJK sentence end",
+			text: "今天天气很好。我们去公园散步吧！然后一起吃午饭好不好？接下来的安排还没有确定下来",
+			max:  30,
+			want: "今天天气很好。我们去公园散步吧！然后一起吃午饭好不好？",
+		},
+		{
+			name: "closing quote kept with sentence",
+			text: "他说：“明天见。”然后转身离开了会场，留下大家面面相觑",
+			max:  14,
+			want: "他说：“明天见。”",
+		},
+		{
+			name: "decimal point is not a sentence end",
+			text: "The value of pi is roughly 3.14 and it appears in many formulas",
+			max:  40,
+			want: "The value of pi is roughly 3.14 and it...",
+		},
+		{
+			name: "domain is not a sentence end",
+			text: "Visit example.com for details about the upcoming release",
+			max:  30,
+			want: "Visit example.com for details...",
+		},
+		{
+			name: "English sentence end followed by space",
+			text: "The release is out. Upgrading is recommended for all users today",
+			max:  30,
+			want: "The release is out.",
+		},
+		{
+			name: "clause punctuation fallback",
+			text: "这是一个很长的句子，其中包含逗号分隔的多个部分，但是一直没有句号结尾的地方出现",
+			max:  30,
+			want: "这是一个很长的句子，其中包含逗号分隔的多个部分...",
+		},
+		{
+			name: "hard cut without any boundary",
+			text: strings.Repeat("字", 60),
+			max:  20,
+			want: strings.Repeat("字", 20) + "...",
+		},
+	}
+	for _, c := range cases {
+		if got := truncateAtBoundary(c.text, c.max); got != c.want {
+			t.Errorf("%s: truncateAtBoundary = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestSelectSummary(t *testing.T) {
+	p := NewTextProcessor()
+	content := "<p>新版本的编译器在大型项目上的构建速度提升了一倍，主要得益于增量编译和并行链接。</p>" +
+		"<p>此外，错误信息也变得更加友好，会直接给出修复建议和相关文档的链接。</p>" +
+		"<p>升级时需要注意，部分已废弃的命令行参数已经被移除，构建脚本可能需要调整。</p>"
+
+	// WordPress 自动生成的节选摘要：不如从正文生成
+	excerpt := "新版本的编译器在大型项目上的构建速度提升了一倍，主要得益于 [&hellip;]"
+	if got, want := p.SelectSummary(excerpt, content, 100), p.GenerateSummary(content, 100); got != want {
+		t.Errorf("excerpt description: got %q, want summary generated from content %q", got, want)
+	}
+
+	// 人工撰写的摘要优先
+	human := "编译器新版本发布：构建提速一倍、错误提示更友好，升级前请检查构建脚本。"
+	if got := p.SelectSummary(human, content, 100); got != human {
+		t.Errorf("human description: got %q, want %q", got, human)
+	}
+
+	// 太短的占位文字不作为摘要
+	if got := p.SelectSummary("Read more", content, 100); got == "Read more" {
+		t.Errorf("placeholder description used as summary")
+	}
+
+	// 没有正文时从 description 生成
+	if got := p.SelectSummary("<p>只有描述的文章。</p>", "", 100); got != "只有描述的文章。" {
+		t.Errorf("description-only item: got %q", got)
+	}
+}
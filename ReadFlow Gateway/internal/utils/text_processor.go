@@ -2,21 +2,30 @@ package utils
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 // TextProcessor 文本处理器
-type TextProcessor struct{}
+type TextProcessor struct {
+	corpus *CorpusIndex // 可选，TF-IDF 关键词提取使用的语料
+}
 
 // NewTextProcessor 创建文本处理器
 func NewTextProcessor() *TextProcessor {
 	return &TextProcessor{}
 }
 
+// NewTextProcessorWithCorpus 创建带语料索引的文本处理器
+func NewTextProcessorWithCorpus(corpus *CorpusIndex) *TextProcessor {
+	return &TextProcessor{corpus: corpus}
+}
+
 // CountWords 统计字数（支持中英文混合）
 // 中文按字符数，英文按单词数
 func (p *TextProcessor) CountWords(htmlText string) int {
@@ -72,38 +81,155 @@ func (p *TextProcessor) EstimateReadingTime(wordCount int) int {
 	return minutes
 }
 
-// GenerateSummary 从HTML生成摘要
-func (p *TextProcessor) GenerateSummary(htmlText string, maxLength int) string {
-	// 1. 去除HTML标签
-	plainText := p.StripHTML(htmlText)
+// TruncateHTML 按正文字符数截断 HTML，保留截断点之前的标签结构
+// 返回截断后的 HTML，以及是否发生了截断
+func (p *TextProcessor) TruncateHTML(htmlText string, maxChars int) (string, bool) {
+	if maxChars <= 0 || utf8.RuneCountInString(p.StripHTML(htmlText)) <= maxChars {
+		return htmlText, false
+	}
 
-	// 2. 清理多余空白
-	plainText = p.cleanWhitespace(plainText)
+	nodes, err := html.ParseFragment(strings.NewReader(htmlText), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		return "<p>" + html.EscapeString(p.GenerateSummary(htmlText, maxChars)) + "</p>", true
+	}
 
-	// 3. 截取指定长度
-	if maxLength <= 0 {
-		maxLength = 200 // 默认200字符
+	remaining := maxChars
+	exhausted := false
+	// trim 返回 false 表示该节点应被整体移除
+	var trim func(n *html.Node) bool
+	trim = func(n *html.Node) bool {
+		if exhausted {
+			return false
+		}
+		if n.Type == html.TextNode {
+			runes := []rune(n.Data)
+			if len(runes) > remaining {
+				n.Data = truncateAtBoundary(n.Data, remaining)
+				exhausted = true
+			} else {
+				remaining -= len(runes)
+			}
+			return true
+		}
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if !trim(c) {
+				n.RemoveChild(c)
+			}
+			c = next
+		}
+		return true
 	}
 
-	// 使用rune计数（支持多字节字符）
-	runes := []rune(plainText)
-	if len(runes) <= maxLength {
-		return plainText
+	var sb strings.Builder
+	for _, n := range nodes {
+		if !trim(n) {
+			break
+		}
+		if err := html.Render(&sb, n); err != nil {
+			return "<p>" + html.EscapeString(p.GenerateSummary(htmlText, maxChars)) + "</p>", true
+		}
 	}
+	return sb.String(), true
+}
 
-	// 截取并添加省略号
-	summary := string(runes[:maxLength])
+// voidElements 没有闭合标签的 HTML 元素
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
 
-	// 尝试在句子或单词边界截断
-	if lastPeriod := strings.LastIndexAny(summary, ".。!！?？"); lastPeriod > maxLength/2 {
-		summary = summary[:lastPeriod+1]
-	} else if lastSpace := strings.LastIndex(summary, " "); lastSpace > maxLength/2 {
-		summary = summary[:lastSpace] + "..."
-	} else {
-		summary += "..."
+// TruncateHTMLBytes 按字节数（含标签）截断 HTML，只在标签或字符边界处截断，并补齐未闭合的标签
+// 返回截断后的 HTML，以及是否发生了截断
+func (p *TextProcessor) TruncateHTMLBytes(htmlText string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 || len(htmlText) <= maxBytes {
+		return htmlText, false
 	}
 
-	return summary
+	var sb strings.Builder
+	var open []string
+	closing := 0 // 补齐闭合标签需要预留的字节数
+	z := html.NewTokenizer(strings.NewReader(htmlText))
+
+tokens:
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		remaining := maxBytes - sb.Len() - closing
+
+		switch tt {
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			if voidElements[tag] {
+				if len(raw) > remaining {
+					break tokens
+				}
+				sb.Write(raw)
+				continue
+			}
+			if len(raw)+len(tag)+3 > remaining {
+				break tokens
+			}
+			sb.Write(raw)
+			open = append(open, tag)
+			closing += len(tag) + 3
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			idx := len(open) - 1
+			for idx >= 0 && open[idx] != tag {
+				idx--
+			}
+			if idx < 0 {
+				continue // 多余的闭合标签直接丢弃
+			}
+			// 中间未闭合的标签一并补齐
+			for i := len(open) - 1; i > idx; i-- {
+				sb.WriteString("</" + open[i] + ">")
+				closing -= len(open[i]) + 3
+			}
+			sb.Write(raw)
+			closing -= len(tag) + 3
+			open = open[:idx]
+		case html.TextToken:
+			if len(raw) <= remaining {
+				sb.Write(raw)
+				continue
+			}
+			if remaining > 0 {
+				cut := remaining
+				for cut > 0 && !utf8.RuneStart(raw[cut]) {
+					cut--
+				}
+				text := raw[:cut]
+				// 不留下被截断的字符实体（如 "&am"）
+				if amp := strings.LastIndexByte(string(text), '&'); amp >= 0 && !strings.Contains(string(text[amp:]), ";") {
+					text = text[:amp]
+				}
+				sb.Write(text)
+			}
+			break tokens
+		default:
+			// 自闭合标签、注释、doctype
+			if len(raw) > remaining {
+				break tokens
+			}
+			sb.Write(raw)
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		sb.WriteString("</" + open[i] + ">")
+	}
+	return sb.String(), true
 }
 
 // StripHTML 去除HTML标签
@@ -284,57 +410,196 @@ func (p *TextProcessor) NormalizeWhitespace(text string) string {
 	return text
 }
 
-// ExtractKeywords 提取关键词（简单版本）
+// ExtractKeywords 提取关键词（自动识别语言）
 func (p *TextProcessor) ExtractKeywords(htmlText string, maxKeywords int) []string {
-	plainText := p.StripHTML(htmlText)
-	plainText = strings.ToLower(plainText)
+	return p.ExtractKeywordsForLanguage(htmlText, "", maxKeywords)
+}
 
-	// 分词
-	words := strings.Fields(plainText)
+// ExtractKeywordsForLanguage 按指定语言提取关键词，lang 为空时自动识别
+// 英文等按单词切分，中日文按相邻两字（二元组）切分
+func (p *TextProcessor) ExtractKeywordsForLanguage(htmlText, lang string, maxKeywords int) []string {
+	if maxKeywords <= 0 {
+		return []string{}
+	}
 
-	// 统计词频（忽略停用词）
-	stopWords := map[string]bool{
-		"the": true, "a": true, "an": true, "and": true, "or": true,
-		"but": true, "in": true, "on": true, "at": true, "to": true,
-		"for": true, "of": true, "as": true, "by": true, "is": true,
-		"was": true, "are": true, "were": true, "be": true, "been": true,
-		"的": true, "了": true, "在": true, "是": true, "我": true,
-		"有": true, "和": true, "就": true, "不": true, "人": true,
+	plainText := strings.ToLower(p.StripHTML(htmlText))
+	if lang == "" {
+		lang = p.DetectLanguage(plainText)
 	}
 
-	wordFreq := make(map[string]int)
-	for _, word := range words {
-		// 过滤短词和停用词
-		if len(word) < 3 || stopWords[word] {
-			continue
+	return topTerms(p.termFrequencies(plainText, lang), maxKeywords)
+}
+
+// DetectLanguage 粗略识别文本语言（zh / ja / ko / en / fr / de / es）
+// 中日韩按字符区间判断，拉丁字母文本按停用词命中数区分
+func (p *TextProcessor) DetectLanguage(text string) string {
+	var han, kana, hangul, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.IsLetter(r):
+			latin++
 		}
-		wordFreq[word]++
 	}
 
-	// 排序（简化：返回频率最高的词）
-	type wordCount struct {
-		word  string
-		count int
+	cjk := han + kana + hangul
+	// 中日韩字符一个字即一个语素，按 1:4 与拉丁字母比较
+	if cjk == 0 || cjk*4 < latin {
+		return detectLatinLanguage(text)
 	}
-	var counts []wordCount
-	for word, count := range wordFreq {
-		counts = append(counts, wordCount{word, count})
+	switch {
+	case kana > 0 && kana*10 >= cjk:
+		return "ja"
+	case hangul > han:
+		return "ko"
+	default:
+		return "zh"
 	}
+}
+
+// termFrequencies 分词并统计词频（已过滤停用词）
+func (p *TextProcessor) termFrequencies(plainText, lang string) map[string]int {
+	freq := make(map[string]int)
+
+	var word []rune
+	var cjkRun []rune
 
-	// 简单排序
-	for i := 0; i < len(counts); i++ {
-		for j := i + 1; j < len(counts); j++ {
-			if counts[j].count > counts[i].count {
-				counts[i], counts[j] = counts[j], counts[i]
+	flushWord := func() {
+		if len(word) > 0 {
+			w := string(word)
+			// 过滤短词、纯数字和停用词
+			if len(word) >= 3 && !isNumeric(w) && !isStopWord(w, lang) {
+				freq[w]++
+			}
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i := 0; i+1 < len(cjkRun); i++ {
+			if chineseStopChars[cjkRun[i]] || chineseStopChars[cjkRun[i+1]] {
+				continue
 			}
+			bigram := string(cjkRun[i : i+2])
+			if !isStopWord(bigram, lang) {
+				freq[bigram]++
+			}
+		}
+		cjkRun = cjkRun[:0]
+	}
+
+	for _, r := range plainText {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjkRun = append(cjkRun, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' && len(word) > 0:
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
 		}
 	}
+	flushWord()
+	flushCJK()
 
-	// 返回前N个关键词
-	keywords := []string{}
-	for i := 0; i < maxKeywords && i < len(counts); i++ {
-		keywords = append(keywords, counts[i].word)
+	return freq
+}
+
+// topTerms 按词频降序返回前 N 个词（词频相同按字典序，保证结果稳定）
+func topTerms(freq map[string]int, n int) []string {
+	terms := make([]string, 0, len(freq))
+	for term := range freq {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if freq[terms[i]] != freq[terms[j]] {
+			return freq[terms[i]] > freq[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+
+	if len(terms) > n {
+		terms = terms[:n]
 	}
+	return terms
+}
 
-	return keywords
+// NormalizeTags 规范化标签：去空白、转小写、去重，并限制数量和长度
+func (p *TextProcessor) NormalizeTags(tags []string, maxTags int) []string {
+	const maxTagLength = 32
+
+	result := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		tag = strings.Trim(tag, "#,;")
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+		if maxTags > 0 && len(result) >= maxTags {
+			break
+		}
+	}
+	return result
+}
+
+// NormalizeCategory 规范化分类名：合并空白、去掉首尾分隔符，英文单词首字母大写
+// 全大写的短词视为缩写保留原样（如 AI、US）；超长的分类返回空字符串
+func (p *TextProcessor) NormalizeCategory(category string) string {
+	const maxCategoryLength = 32
+
+	words := strings.Fields(strings.Trim(strings.TrimSpace(category), "#,;/"))
+	for i, word := range words {
+		if utf8.RuneCountInString(word) <= 4 && strings.ToUpper(word) == word {
+			continue
+		}
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	category = strings.Join(words, " ")
+	if utf8.RuneCountInString(category) > maxCategoryLength {
+		return ""
+	}
+	return category
+}
+
+// NormalizeLanguage 将 feed 声明的语言（如 en-US、zh_CN）规范化为小写的主语言代码（en、zh），
+// 与订阅源目录的取值一致；无法识别时返回空字符串
+func NormalizeLanguage(language string) string {
+	primary, _, _ := strings.Cut(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"), "-")
+	primary = strings.ToLower(primary)
+	if len(primary) < 2 || len(primary) > 3 {
+		return ""
+	}
+	for _, r := range primary {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	return primary
+}
+
+// isCJK 判断字符是否为中日韩文字
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// isNumeric 判断字符串是否全为数字
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// minCorpusDocs 语料少于该篇数时 IDF 没有区分度，退回简单词频
+const minCorpusDocs = 5
+
+// CorpusLoader 加载某个订阅源最近文章的文本（HTML 或纯文本均可）
+type CorpusLoader func(sourceID int64) ([]string, error)
+
+// CorpusIndex 按订阅源缓存的文档频率统计，用于 TF-IDF 关键词提取
+type CorpusIndex struct {
+	loader  CorpusLoader
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[int64]*corpusEntry
+	loading map[int64]*corpusLoad // 正在加载的源，并发请求等待同一次加载
+}
+
+// corpusLoad 一次进行中的语料加载，done 关闭后 entry 可读
+type corpusLoad struct {
+	done  chan struct{}
+	entry *corpusEntry
+}
+
+// corpusEntry 单个订阅源的文档频率
+type corpusEntry struct {
+	docFreq  map[string]int
+	docCount int
+	builtAt  time.Time
+}
+
+// NewCorpusIndex 创建语料索引，ttl 为每个源统计结果的缓存时间
+func NewCorpusIndex(loader CorpusLoader, ttl time.Duration) *CorpusIndex {
+	return &CorpusIndex{
+		loader:  loader,
+		ttl:     ttl,
+		entries: make(map[int64]*corpusEntry),
+		loading: make(map[int64]*corpusLoad),
+	}
+}
+
+// Invalidate 清除某个源的缓存（下次使用时重新统计）
+func (c *CorpusIndex) Invalidate(sourceID int64) {
+	c.mu.Lock()
+	delete(c.entries, sourceID)
+	c.mu.Unlock()
+}
+
+// get 获取某个源的文档频率，缓存过期时重新加载
+// 加载在锁外进行，慢查询只阻塞同一个源的请求；同一个源的并发请求共用一次加载
+func (c *CorpusIndex) get(p *TextProcessor, sourceID int64) *corpusEntry {
+	c.mu.Lock()
+	if entry, ok := c.entries[sourceID]; ok && time.Since(entry.builtAt) < c.ttl {
+		c.mu.Unlock()
+		return entry
+	}
+	if load, ok := c.loading[sourceID]; ok {
+		c.mu.Unlock()
+		<-load.done
+		return load.entry
+	}
+	load := &corpusLoad{done: make(chan struct{})}
+	c.loading[sourceID] = load
+	c.mu.Unlock()
+
+	load.entry = c.build(p, sourceID)
+
+	c.mu.Lock()
+	delete(c.loading, sourceID)
+	if load.entry != nil {
+		c.entries[sourceID] = load.entry
+	}
+	c.mu.Unlock()
+	close(load.done)
+	return load.entry
+}
+
+// build 加载某个源的语料并统计文档频率，加载失败时返回 nil
+func (c *CorpusIndex) build(p *TextProcessor, sourceID int64) *corpusEntry {
+	docs, err := c.loader(sourceID)
+	if err != nil {
+		log.Printf("[Keywords] Failed to load corpus for source %d: %v", sourceID, err)
+		return nil
+	}
+
+	entry := &corpusEntry{
+		docFreq:  make(map[string]int),
+		docCount: len(docs),
+		builtAt:  time.Now(),
+	}
+	for _, doc := range docs {
+		plain := strings.ToLower(p.StripHTML(doc))
+		for term := range p.termFrequencies(plain, p.DetectLanguage(plain)) {
+			entry.docFreq[term]++
+		}
+	}
+	return entry
+}
+
+// ExtractKeywordsTFIDF 基于订阅源语料的 TF-IDF 提取关键词
+// 在该源大多数文章中都出现的词（如站点固定文案）会被降权
+// 未配置语料或语料不足时退回 ExtractKeywords
+func (p *TextProcessor) ExtractKeywordsTFIDF(htmlText string, sourceID int64, maxKeywords int) []string {
+	if p.corpus == nil || maxKeywords <= 0 {
+		return p.ExtractKeywords(htmlText, maxKeywords)
+	}
+
+	entry := p.corpus.get(p, sourceID)
+	if entry == nil || entry.docCount < minCorpusDocs {
+		return p.ExtractKeywords(htmlText, maxKeywords)
+	}
+
+	plainText := strings.ToLower(p.StripHTML(htmlText))
+	freq := p.termFrequencies(plainText, p.DetectLanguage(plainText))
+
+	// 平滑 IDF：ln((N+1)/(df+1)) + 1
+	scores := make(map[string]float64, len(freq))
+	terms := make([]string, 0, len(freq))
+	for term, tf := range freq {
+		idf := math.Log(float64(entry.docCount+1)/float64(entry.docFreq[term]+1)) + 1
+		scores[term] = float64(tf) * idf
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if scores[terms[i]] != scores[terms[j]] {
+			return scores[terms[i]] > scores[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+
+	if len(terms) > maxKeywords {
+		terms = terms[:maxKeywords]
+	}
+	return terms
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// boilerplate 每篇文章末尾都有的站点固定文案
+const boilerplate = "Subscribe to our newsletter for weekly newsletter updates."
+
+func corpusDocs() []string {
+	topics := []string{"kubernetes", "postgres", "compiler", "firmware", "bluetooth", "webassembly"}
+	docs := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		docs = append(docs, fmt.Sprintf("<p>Notes about %s internals.</p><p>%s</p>", topic, boilerplate))
+	}
+	return docs
+}
+
+func TestExtractKeywordsTFIDFDemotesBoilerplate(t *testing.T) {
+	article := "<p>Rust ownership explained: ownership rules and borrowing.</p><p>" + boilerplate + "</p>"
+
+	// 没有语料时，重复出现的固定文案排在最前
+	plain := NewTextProcessor().ExtractKeywords(article, 1)
+	if len(plain) != 1 || plain[0] != "newsletter" {
+		t.Fatalf("ExtractKeywords top term = %v, want [newsletter]", plain)
+	}
+
+	corpus := NewCorpusIndex(func(int64) ([]string, error) { return corpusDocs(), nil }, time.Hour)
+	keywords := NewTextProcessorWithCorpus(corpus).ExtractKeywordsTFIDF(article, 1, 3)
+	if len(keywords) == 0 || keywords[0] != "ownership" {
+		t.Fatalf("ExtractKeywordsTFIDF top term = %v, want ownership first", keywords)
+	}
+	for _, kw := range keywords {
+		if kw == "newsletter" || kw == "subscribe" {
+			t.Errorf("boilerplate term %q not demoted: %v", kw, keywords)
+		}
+	}
+}
+
+func TestExtractKeywordsTFIDFSmallCorpusFallsBack(t *testing.T) {
+	corpus := NewCorpusIndex(func(int64) ([]string, error) { return corpusDocs()[:2], nil }, time.Hour)
+	article := "<p>Rust ownership explained.</p><p>" + boilerplate + "</p>"
+
+	got := NewTextProcessorWithCorpus(corpus).ExtractKeywordsTFIDF(article, 1, 3)
+	want := NewTextProcessor().ExtractKeywords(article, 3)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ExtractKeywordsTFIDF with small corpus = %v, want fallback %v", got, want)
+	}
+}
+
+func TestCorpusIndexLoadsOncePerSource(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	corpus := NewCorpusIndex(func(sourceID int64) ([]string, error) {
+		atomic.AddInt32(&loads, 1)
+		if sourceID == 1 {
+			<-release // 源 1 的加载很慢
+		}
+		return corpusDocs(), nil
+	}, time.Hour)
+	p := NewTextProcessorWithCorpus(corpus)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if corpus.get(p, 1) == nil {
+				t.Error("get returned nil entry")
+			}
+		}()
+	}
+
+	// 源 1 加载期间，其他源不受影响
+	done := make(chan struct{})
+	go func() {
+		corpus.get(p, 2)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("loading source 2 blocked behind source 1")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&loads); n != 2 {
+		t.Errorf("loader called %d times, want 2 (one per source)", n)
+	}
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// xmlDeclEncoding 匹配 XML 声明中的 encoding 属性
+var xmlDeclEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?encoding\s*=\s*["'])([A-Za-z0-9._:-]+)(["'])`)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// decodeFeedBody 将订阅源内容转为 UTF-8
+// 编码依次取自 BOM、XML 声明、Content-Type；内容本身已是合法 UTF-8 或编码无法识别时不转码。
+// 转码后 XML 声明会改写为 UTF-8，避免解析器按原编码再解码一次
+func decodeFeedBody(body []byte, contentType string) []byte {
+	if bytes.HasPrefix(body, utf8BOM) {
+		return body[len(utf8BOM):]
+	}
+
+	var enc encoding.Encoding
+	switch {
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		enc = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	default:
+		// 很多服务器对 UTF-8 内容也声明 ISO-8859-1，合法 UTF-8 时以内容为准；
+		// XML 声明同样改写为 UTF-8，否则解析器会按声明的编码再解码一次
+		if utf8.Valid(body) {
+			return xmlDeclEncoding.ReplaceAll(body, []byte("${1}UTF-8${3}"))
+		}
+		enc = lookupEncoding(declaredCharset(body, contentType))
+	}
+	if enc == nil {
+		return body
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return xmlDeclEncoding.ReplaceAll(decoded, []byte("${1}UTF-8${3}"))
+}
+
+// declaredCharset 读取内容声明的编码，XML 声明优先于 Content-Type
+// （源站响应头往往是服务器默认值，XML 声明由生成 feed 的程序写入，更可信）
+func declaredCharset(body []byte, contentType string) string {
+	head := body
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if m := xmlDeclEncoding.FindSubmatch(head); m != nil {
+		return string(m[2])
+	}
+
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		return params["charset"]
+	}
+	return ""
+}
+
+// lookupEncoding 按 WHATWG 标签查找编码（如 gb2312 → GBK），UTF-8 或未知标签返回 nil
+func lookupEncoding(label string) encoding.Encoding {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return nil
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil || enc == encoding.Replacement {
+		return nil
+	}
+	if name, _ := htmlindex.Name(enc); name == "utf-8" {
+		return nil
+	}
+	return enc
+}
@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+const gbkFeedTitle = "36氪 - 科技资讯"
+
+func parseDecoded(t *testing.T, body []byte, contentType string) *gofeed.Feed {
+	t.Helper()
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(decodeFeedBody(body, contentType)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return feed
+}
+
+func TestDecodeFeedBodyXMLDeclaration(t *testing.T) {
+	body, err := os.ReadFile("testdata/gbk_feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 响应头声明的 ISO-8859-1 不应覆盖 XML 声明中的 GBK
+	feed := parseDecoded(t, body, "application/rss+xml; charset=ISO-8859-1")
+	if feed.Title != gbkFeedTitle {
+		t.Errorf("title = %q, want %q", feed.Title, gbkFeedTitle)
+	}
+	if len(feed.Items) == 0 || feed.Items[0].Title != "国产大模型进入商业化落地关键期" {
+		t.Errorf("unexpected first item: %+v", feed.Items)
+	}
+}
+
+func TestDecodeFeedBodyContentTypeOnly(t *testing.T) {
+	body, err := os.ReadFile("testdata/gbk_feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body = bytes.Replace(body, []byte(` encoding="GBK"`), nil, 1)
+
+	feed := parseDecoded(t, body, "text/xml; charset=gb2312")
+	if feed.Title != gbkFeedTitle {
+		t.Errorf("title = %q, want %q", feed.Title, gbkFeedTitle)
+	}
+}
+
+func TestDecodeFeedBodyMislabelledUTF8(t *testing.T) {
+	body := []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="2.0"><channel><title>少数派 · Café</title>
+<item><title>效率工具</title><link>https://sspai.com/post/1</link></item>
+</channel></rss>`)
+
+	for _, contentType := range []string{"application/rss+xml; charset=ISO-8859-1", ""} {
+		feed := parseDecoded(t, body, contentType)
+		if feed.Title != "少数派 · Café" {
+			t.Errorf("content type %q: title = %q", contentType, feed.Title)
+		}
+	}
+}
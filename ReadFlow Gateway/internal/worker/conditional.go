@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// errFeedNotModified 订阅源以 304 响应条件请求，内容自上次成功抓取后没有变化
+var errFeedNotModified = errors.New("feed not modified")
+
+// feedValidators 条件请求的校验值，取自源上次成功抓取响应的 ETag 和 Last-Modified
+// 抓取时作为 If-None-Match / If-Modified-Since 发送，收到 200 响应后更新为新的值；nil 表示不发条件请求
+type feedValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// empty 没有可用的校验值
+func (v *feedValidators) empty() bool {
+	return v == nil || (v.ETag == "" && v.LastModified == "")
+}
+
+// apply 为请求设置条件请求头
+func (v *feedValidators) apply(req *http.Request) {
+	if v.empty() {
+		return
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// update 从成功的响应中取出新的校验值，响应没有提供时清空（下次不发对应的条件请求头）
+func (v *feedValidators) update(resp *http.Response) {
+	if v == nil {
+		return
+	}
+	v.ETag = resp.Header.Get("ETag")
+	v.LastModified = resp.Header.Get("Last-Modified")
+}
+
+// fetchJitter 源本次抓取间隔的随机偏移，在间隔的 ±percent% 之间
+// 按源 ID 和上次抓取时间取值：同一抓取周期内每轮调度判断的结果一致，不同源的偏移分散，避免同时到期的源集中在同一轮抓取
+func fetchJitter(sourceID int64, lastFetch time.Time, interval time.Duration, percent int) time.Duration {
+	if percent <= 0 || interval <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatInt(sourceID, 10) + ":" + strconv.FormatInt(lastFetch.Unix(), 10)))
+	// 映射到 [-1, 1]
+	ratio := float64(h.Sum64()%20001)/10000 - 1
+	return time.Duration(ratio * float64(interval) * float64(percent) / 100)
+}
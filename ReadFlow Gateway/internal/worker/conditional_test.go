@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestDownloadFeedConditional(t *testing.T) {
+	const rss = `<?xml version="1.0"?><rss version="2.0"><channel><title>T</title></channel></rss>`
+	const lastModified = "Wed, 14 Oct 2026 08:00:00 GMT"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write([]byte(rss))
+	}))
+	defer server.Close()
+
+	w := &Worker{parser: gofeed.NewParser()}
+	validators := &feedValidators{}
+	if _, _, err := w.downloadFeed(context.Background(), server.Client(), server.URL, nil, validators); err != nil {
+		t.Fatal(err)
+	}
+	if validators.ETag != `"v1"` || validators.LastModified != lastModified {
+		t.Fatalf("validators = %+v", validators)
+	}
+
+	if _, _, err := w.downloadFeed(context.Background(), server.Client(), server.URL, nil, validators); !errors.Is(err, errFeedNotModified) {
+		t.Errorf("conditional fetch err = %v, want errFeedNotModified", err)
+	}
+
+	// 不带校验值时照常下载
+	if body, _, err := w.downloadFeed(context.Background(), server.Client(), server.URL, nil, nil); err != nil || len(body) == 0 {
+		t.Errorf("unconditional fetch = %q, %v", body, err)
+	}
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+}
+
+func TestRecordFeedValidators(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	source, err := database.CreateSource("https://example.com/feed", "Example", "", 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.UpdateSourceValidators(source.ID, `"v1"`, "Wed, 14 Oct 2026 08:00:00 GMT"); err != nil {
+		t.Fatal(err)
+	}
+	source, err = database.GetSourceByID(source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Worker{db: database}
+	stored := func() (string, string) {
+		t.Helper()
+		s, err := database.GetSourceByID(source.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.HTTPETag, s.HTTPLastModified
+	}
+
+	// 有文章处理失败：ETag 不变、只有 Last-Modified 变化时也不记录，下次仍完整抓取
+	changed := &feedValidators{ETag: `"v1"`, LastModified: "Thu, 15 Oct 2026 08:00:00 GMT"}
+	w.recordFeedValidators(source, changed, 1)
+	if etag, lastModified := stored(); etag != `"v1"` || lastModified != "Wed, 14 Oct 2026 08:00:00 GMT" {
+		t.Errorf("after failed item: stored (%q, %q), want previous validators", etag, lastModified)
+	}
+	if source.HTTPLastModified != "Wed, 14 Oct 2026 08:00:00 GMT" {
+		t.Errorf("source updated after failed item: %q", source.HTTPLastModified)
+	}
+
+	// 全部成功后记录
+	w.recordFeedValidators(source, changed, 0)
+	if etag, lastModified := stored(); etag != changed.ETag || lastModified != changed.LastModified {
+		t.Errorf("after success: stored (%q, %q), want %+v", etag, lastModified, changed)
+	}
+}
+
+func TestFetchJitter(t *testing.T) {
+	interval := time.Hour
+	lastFetch := time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC)
+	maxJitter := interval * 5 / 100
+
+	if got := fetchJitter(1, lastFetch, interval, 0); got != 0 {
+		t.Errorf("jitter with 0%% = %v, want 0", got)
+	}
+	if fetchJitter(1, lastFetch, interval, 5) != fetchJitter(1, lastFetch, interval, 5) {
+		t.Error("jitter changed between checks of the same fetch cycle")
+	}
+
+	distinct := make(map[time.Duration]bool)
+	for id := int64(1); id <= 50; id++ {
+		jitter := fetchJitter(id, lastFetch, interval, 5)
+		if jitter < -maxJitter || jitter > maxJitter {
+			t.Fatalf("jitter of source %d = %v, want within ±%v", id, jitter, maxJitter)
+		}
+		distinct[jitter] = true
+	}
+	if len(distinct) < 40 {
+		t.Errorf("only %d distinct jitter values for 50 sources", len(distinct))
+	}
+}
@@ -25,9 +25,9 @@ func SelectBestImage(enhanced *EnhancedItem) string {
 			}
 		}
 		
-		// 如果没有 medium="image"，使用第一个有 URL 的
+		// 如果没有 medium="image"，使用第一个可能是图片的
 		for _, media := range enhanced.MediaContent {
-			if media.URL != "" {
+			if media.URL != "" && media.IsImageCandidate() {
 				if !IsPlaceholderImage(media.URL, "") {
 					url := ProcessImageURL(media.URL)
 					log.Printf("[Content] Selected image from media:content: %s", url)
@@ -97,8 +97,6 @@ func IsPlaceholderImage(url string, alt string) bool {
 		"gray-placeholder",
 		"dummy",
 		"blank",
-		"default.png",
-		"default.jpg",
 		"spacer",
 		"1x1",
 		"pixel",
@@ -110,6 +108,11 @@ func IsPlaceholderImage(url string, alt string) bool {
 			return true
 		}
 	}
+
+	if isDefaultImageName(urlLower) {
+		log.Printf("[Content] Detected placeholder image (default file name): %s", url)
+		return true
+	}
 	
 	// 检查 alt 属性特征
 	if altLower == "loading" || altLower == "image unavailable" {
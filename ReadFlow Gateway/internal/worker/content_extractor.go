@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -11,45 +12,104 @@ import (
 	"time"
 
 	"github.com/go-shiori/go-readability"
+	"github.com/readflow/gateway/internal/utils"
+	"golang.org/x/net/html"
 )
 
 // ContentExtractor 完整内容提取器
 // 使用 Mozilla Readability 算法从原始URL提取干净的文章内容
 type ContentExtractor struct {
-	httpClient *http.Client
-	userAgent  string
+	httpClient     *http.Client
+	userAgent      string
+	ogImages       ogImageCache      // og:image 查找结果缓存
+	maxBodyBytes   int64             // 页面大小上限，0 表示不限制
+	paywallMarkers []string          // 付费墙提示语（小写），为空时使用 defaultPaywallMarkers
+	hosts          *utils.HostPolicy // 主机允许/禁止列表（RSS_ALLOWED_HOSTS / RSS_DENIED_HOSTS），nil 表示不限制
+	ownsTransport  bool              // 客户端的连接池为提取器独有，Close 时可以关闭空闲连接
 }
 
-// NewContentExtractor 创建内容提取器
-func NewContentExtractor() *ContentExtractor {
+// contentFetchTimeout 单次页面请求的时限（共享连接池的客户端不设 Timeout，由请求的 context 控制）
+const contentFetchTimeout = 30 * time.Second
+
+// extractedArticle Readability 提取结果
+type extractedArticle struct {
+	title    string
+	content  string // 已清理的正文 HTML
+	image    string // 页面声明的主图（og:image 等）
+	siteName string
+	byline   string
+	paywall  string // 判断为付费墙或登录墙预览的依据，空表示不是
+}
+
+// NewContentExtractor 创建内容提取器，使用传入的共享连接池；transport 为 nil 时使用独立的直连连接池
+// hosts 限制可访问的主机（含重定向），nil 表示不限制
+func NewContentExtractor(transport *http.Transport, hosts *utils.HostPolicy) *ContentExtractor {
+	e := newContentExtractor(utils.NewHTTPClient(utils.HTTPClientOptions{
+		Transport:           transport,
+		MaxIdleConns:        10,
+		MaxIdleConnsPerHost: 5,
+		IdleConnTimeout:     30 * time.Second,
+		CheckRedirect:       hosts.CheckRedirect,
+	}), hosts)
+	e.ownsTransport = transport == nil
+	return e
+}
+
+// newReaderContentExtractor 创建阅读模式使用的提取器：地址由用户任意提交，只允许连接公网地址并限制页面大小
+func newReaderContentExtractor(proxy *url.URL, hosts *utils.HostPolicy) *ContentExtractor {
+	e := newContentExtractor(utils.NewHTTPClient(utils.HTTPClientOptions{
+		Timeout:       readTimeout,
+		Proxy:         proxy,
+		PublicOnly:    true,
+		CheckRedirect: hosts.CheckRedirect,
+	}), hosts)
+	e.maxBodyBytes = readMaxPageBytes
+	e.ownsTransport = true
+	return e
+}
+
+// newContentExtractor 使用指定的客户端创建内容提取器
+func newContentExtractor(client *http.Client, hosts *utils.HostPolicy) *ContentExtractor {
 	return &ContentExtractor{
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        10,
-				IdleConnTimeout:     30 * time.Second,
-				DisableCompression:  false,
-				DisableKeepAlives:   false,
-				MaxIdleConnsPerHost: 5,
-			},
-		},
-		userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		httpClient: client,
+		hosts:      hosts,
+		userAgent:  "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
 	}
 }
 
 // ExtractFullContent 从URL提取完整内容
 // 使用 Readability 算法提取干净的文章正文
 func (e *ContentExtractor) ExtractFullContent(urlStr string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), contentFetchTimeout)
+	defer cancel()
+	return e.extractFullContent(ctx, urlStr, nil)
+}
+
+// extractFullContent 从URL提取完整内容，与订阅源同域时附带源凭据
+// 提取结果只是付费墙或登录墙的预览时返回 errPaywalled
+func (e *ContentExtractor) extractFullContent(ctx context.Context, urlStr string, auth *feedAuth) (string, error) {
+	article, err := e.extractArticle(ctx, urlStr, auth)
+	if err != nil {
+		return "", err
+	}
+	if article.paywall != "" {
+		return "", fmt.Errorf("%w (%s)", errPaywalled, article.paywall)
+	}
+	return article.content, nil
+}
+
+// extractArticle 抓取页面并用 Readability 提取标题、正文和主图，ctx 限制包括重试在内的全部请求
+func (e *ContentExtractor) extractArticle(ctx context.Context, urlStr string, auth *feedAuth) (*extractedArticle, error) {
 	if urlStr == "" {
-		return "", fmt.Errorf("empty URL")
+		return nil, fmt.Errorf("empty URL")
 	}
 
 	log.Printf("[ContentExtractor] Extracting full content from: %s", urlStr)
 
 	// 1. 获取HTML内容（带重试）
-	htmlContent, err := e.fetchWithRetry(urlStr, 2)
+	htmlContent, err := e.fetchWithRetry(ctx, urlStr, 2, auth)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL: %w", err)
+		return nil, fmt.Errorf("failed to fetch URL: %w", auth.redactError(err))
 	}
 
 	// 2. 使用 Readability 提取
@@ -63,18 +123,42 @@ func (e *ContentExtractor) ExtractFullContent(urlStr string) (string, error) {
 	article, err := readability.FromReader(strings.NewReader(htmlContent), parsedURL)
 	if err != nil {
 		log.Printf("[ContentExtractor] Readability failed: %v", err)
-		return "", fmt.Errorf("readability extraction failed: %w", err)
+		return nil, fmt.Errorf("readability extraction failed: %w", err)
 	}
 
-	// 3. 清理HTML
+	// 3. 清理HTML（付费墙判断需要清理前的登录表单）
+	paywall := detectPaywall(htmlContent, article.Content, e.paywallMarkers)
 	cleanedContent := e.cleanHTML(article.Content)
 
 	log.Printf("[ContentExtractor] Successfully extracted content (%d bytes)", len(cleanedContent))
-	return cleanedContent, nil
+	return &extractedArticle{
+		title:    strings.TrimSpace(article.Title),
+		content:  cleanedContent,
+		image:    strings.TrimSpace(article.Image),
+		siteName: strings.TrimSpace(article.SiteName),
+		byline:   strings.TrimSpace(article.Byline),
+		paywall:  paywall,
+	}, nil
+}
+
+// extractArticleNode 用 Readability 从 feed 正文中识别正文主体，返回主体节点和渲染后的 HTML；
+// 识别失败或主体没有文字时返回 nil，调用方按整个正文处理
+func extractArticleNode(body, link string) (*html.Node, string) {
+	pageURL, _ := url.Parse(link)
+	article, err := readability.FromReader(strings.NewReader(body), pageURL)
+	if err != nil || article.Node == nil || strings.TrimSpace(article.TextContent) == "" {
+		return nil, ""
+	}
+	return article.Node, article.Content
 }
 
 // ExtractFullContentWithTimeout 带超时的内容提取
 func (e *ContentExtractor) ExtractFullContentWithTimeout(url string, timeout time.Duration) (string, error) {
+	return e.extractWithTimeout(url, nil, timeout)
+}
+
+// extractWithTimeout 带超时且可附带源凭据的内容提取
+func (e *ContentExtractor) extractWithTimeout(url string, auth *feedAuth, timeout time.Duration) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -86,7 +170,7 @@ func (e *ContentExtractor) ExtractFullContentWithTimeout(url string, timeout tim
 	ch := make(chan result, 1)
 
 	go func() {
-		content, err := e.ExtractFullContent(url)
+		content, err := e.extractFullContent(ctx, url, auth)
 		ch <- result{content, err}
 	}()
 
@@ -99,32 +183,46 @@ func (e *ContentExtractor) ExtractFullContentWithTimeout(url string, timeout tim
 }
 
 // fetchWithRetry 带重试的HTTP请求
-func (e *ContentExtractor) fetchWithRetry(url string, maxRetries int) (string, error) {
+func (e *ContentExtractor) fetchWithRetry(ctx context.Context, url string, maxRetries int, auth *feedAuth) (string, error) {
 	var lastErr error
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
-			log.Printf("[ContentExtractor] Retry attempt %d/%d for %s", attempt, maxRetries, url)
-			time.Sleep(time.Duration(attempt) * time.Second) // 递增延迟
+			log.Printf("[ContentExtractor] Retry attempt %d/%d for %s", attempt, maxRetries, auth.redact(url))
+			select {
+			case <-time.After(time.Duration(attempt) * time.Second): // 递增延迟
+			case <-ctx.Done():
+				return "", fmt.Errorf("all %d attempts failed: %w", attempt, lastErr)
+			}
 		}
 
-		content, err := e.fetch(url)
+		content, err := e.fetch(ctx, url, auth)
 		if err == nil {
 			return content, nil
 		}
 
 		lastErr = err
+		// 地址被拒绝或页面过大时重试没有意义
+		if errors.Is(err, utils.ErrPrivateAddress) || errors.Is(err, utils.ErrHostNotAllowed) || errors.Is(err, errPageTooLarge) {
+			return "", err
+		}
 	}
 
 	return "", fmt.Errorf("all %d attempts failed: %w", maxRetries+1, lastErr)
 }
 
 // fetch 执行HTTP请求
-func (e *ContentExtractor) fetch(url string) (string, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func (e *ContentExtractor) fetch(ctx context.Context, url string, auth *feedAuth) (string, error) {
+	if err := e.hosts.Check(url); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, contentFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", auth.applyURL(url), nil)
 	if err != nil {
 		return "", err
 	}
+	auth.applyRequest(req)
 
 	// 设置 User-Agent 避免被反爬
 	req.Header.Set("User-Agent", e.userAgent)
@@ -141,82 +239,24 @@ func (e *ContentExtractor) fetch(url string) (string, error) {
 		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	var body io.Reader = resp.Body
+	if e.maxBodyBytes > 0 {
+		body = io.LimitReader(resp.Body, e.maxBodyBytes+1)
+	}
+	bodyBytes, err := io.ReadAll(body)
 	if err != nil {
 		return "", err
 	}
+	if e.maxBodyBytes > 0 && int64(len(bodyBytes)) > e.maxBodyBytes {
+		return "", fmt.Errorf("%w: page larger than %d bytes", errPageTooLarge, e.maxBodyBytes)
+	}
 
 	return string(bodyBytes), nil
 }
 
-// cleanHTML 清理HTML内容
+// cleanHTML 清理HTML内容：移除脚本、样式、事件属性等（见 sanitizeHTML）
 func (e *ContentExtractor) cleanHTML(htmlContent string) string {
-	// 简单清理：移除脚本和样式标签
-	cleaned := htmlContent
-
-	// 移除 <script> 标签
-	cleaned = removeTag(cleaned, "script")
-
-	// 移除 <style> 标签
-	cleaned = removeTag(cleaned, "style")
-
-	// 移除注释
-	cleaned = removeHTMLComments(cleaned)
-
-	return cleaned
-}
-
-// removeTag 移除指定的HTML标签及其内容
-func removeTag(html string, tagName string) string {
-	// 简化实现：使用字符串替换
-	// 更严谨的实现应该使用HTML parser
-	for {
-		startTag := fmt.Sprintf("<%s", tagName)
-		endTag := fmt.Sprintf("</%s>", tagName)
-
-		startIdx := strings.Index(strings.ToLower(html), strings.ToLower(startTag))
-		if startIdx == -1 {
-			break
-		}
-
-		// 查找标签结束
-		tagEndIdx := strings.Index(html[startIdx:], ">")
-		if tagEndIdx == -1 {
-			break
-		}
-
-		// 查找闭合标签
-		closeIdx := strings.Index(strings.ToLower(html[startIdx:]), strings.ToLower(endTag))
-		if closeIdx == -1 {
-			// 自闭合标签
-			html = html[:startIdx] + html[startIdx+tagEndIdx+1:]
-		} else {
-			// 完整标签
-			endIdx := startIdx + closeIdx + len(endTag)
-			html = html[:startIdx] + html[endIdx:]
-		}
-	}
-
-	return html
-}
-
-// removeHTMLComments 移除HTML注释
-func removeHTMLComments(html string) string {
-	for {
-		startIdx := strings.Index(html, "<!--")
-		if startIdx == -1 {
-			break
-		}
-
-		endIdx := strings.Index(html[startIdx:], "-->")
-		if endIdx == -1 {
-			break
-		}
-
-		html = html[:startIdx] + html[startIdx+endIdx+3:]
-	}
-
-	return html
+	return sanitizeHTML(htmlContent)
 }
 
 // shouldUseCorsProxy 判断是否需要使用CORS代理
@@ -236,9 +276,9 @@ func (e *ContentExtractor) shouldUseCorsProxy(url string) bool {
 	return false
 }
 
-// Close 关闭提取器，清理资源
+// Close 关闭提取器，清理资源；使用共享连接池时不关闭其中的连接，连接池由其他模块继续使用
 func (e *ContentExtractor) Close() {
-	if e.httpClient != nil {
+	if e.httpClient != nil && e.ownsTransport {
 		e.httpClient.CloseIdleConnections()
 	}
 }
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/readflow/gateway/internal/utils"
+)
+
+func TestContentExtractorCloseKeepsSharedTransport(t *testing.T) {
+	var conns int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body><p>ok</p></body></html>"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	transport := utils.NewSharedTransport(nil)
+	defer transport.CloseIdleConnections()
+	extractor := NewContentExtractor(transport, nil)
+	if _, err := extractor.fetch(context.Background(), server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 关闭提取器后，共享连接池中的空闲连接仍可供其他模块复用
+	extractor.Close()
+	client := utils.NewHTTPClient(utils.HTTPClientOptions{Transport: transport})
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := atomic.LoadInt64(&conns); got != 1 {
+		t.Errorf("new connections = %d, want 1", got)
+	}
+}
@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"encoding/json"
+
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/image"
+)
+
+// encodeCoverVariants 将封面缩略图编码为 cover_variants JSON（没有缩略图时为空字符串），
+// 并把缩略图路径追加到 image_paths JSON 中，返回两者
+func encodeCoverVariants(variants []image.CoverVariant, imagePaths string) (string, string) {
+	if len(variants) == 0 {
+		return "", imagePaths
+	}
+
+	var paths []string
+	if imagePaths != "" {
+		_ = json.Unmarshal([]byte(imagePaths), &paths)
+	}
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		seen[p] = true
+	}
+
+	encoded := make([]db.CoverVariant, 0, len(variants))
+	for _, v := range variants {
+		encoded = append(encoded, db.CoverVariant{URL: v.Path, Width: v.Width})
+		if !seen[v.Path] {
+			seen[v.Path] = true
+			paths = append(paths, v.Path)
+		}
+	}
+
+	variantsJSON, _ := json.Marshal(encoded)
+	pathsJSON, _ := json.Marshal(paths)
+	return string(variantsJSON), string(pathsJSON)
+}
@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/readflow/gateway/internal/image"
+)
+
+func TestEncodeCoverVariants(t *testing.T) {
+	variants := []image.CoverVariant{
+		{Path: "/static/images/1/abc-160w.webp", Width: 160},
+		{Path: "/static/images/1/abc-640w.webp", Width: 640},
+	}
+
+	// 正文图片与缩略图合并去重
+	got, paths := encodeCoverVariants(variants, `["/static/images/1/def.webp","/static/images/1/abc-160w.webp"]`)
+	wantVariants := `[{"url":"/static/images/1/abc-160w.webp","width":160},{"url":"/static/images/1/abc-640w.webp","width":640}]`
+	if got != wantVariants {
+		t.Errorf("variants = %s, want %s", got, wantVariants)
+	}
+	wantPaths := `["/static/images/1/def.webp","/static/images/1/abc-160w.webp","/static/images/1/abc-640w.webp"]`
+	if paths != wantPaths {
+		t.Errorf("image_paths = %s, want %s", paths, wantPaths)
+	}
+
+	// 正文没有本地图片
+	if _, paths := encodeCoverVariants(variants[:1], ""); paths != `["/static/images/1/abc-160w.webp"]` {
+		t.Errorf("image_paths = %s", paths)
+	}
+
+	// 没有缩略图时 image_paths 保持不变
+	if got, paths := encodeCoverVariants(nil, `["/static/images/1/def.webp"]`); got != "" || paths != `["/static/images/1/def.webp"]` {
+		t.Errorf("no variants = %q, %q", got, paths)
+	}
+}
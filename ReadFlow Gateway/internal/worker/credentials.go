@@ -0,0 +1,154 @@
+package worker
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// 凭据类型
+const (
+	authTypeBasic  = "basic"
+	authTypeHeader = "header"
+	authTypeToken  = "token"
+	// authTypeSession 会话模式，secret 为登录脚本（见 FetchScript）
+	authTypeSession = "session"
+
+	// tokenPlaceholder 源 URL 中的令牌占位符，例如 https://example.com/feed?key={token}
+	tokenPlaceholder = "{token}"
+	// defaultTokenParam token 模式下未指定参数名时使用的查询参数
+	defaultTokenParam = "token"
+)
+
+// feedAuth 解密后的订阅源凭据，只在内存中存在
+// 所有方法都允许 nil 接收者，表示该源无需认证
+type feedAuth struct {
+	authType  string
+	username  string
+	secret    string
+	paramName string
+	host      string // 凭据只作用于该主机（订阅源所在域名）
+
+	// 会话模式
+	sourceID int64
+	version  time.Time      // 凭据的 updated_at，凭据更新后缓存的会话失效
+	script   *FetchScript   // 登录脚本
+	cookies  []*http.Cookie // 登录取得（或缓存）的会话 Cookie
+}
+
+// loadFeedAuth 加载并解密订阅源凭据，未配置凭据时返回 nil
+func (w *Worker) loadFeedAuth(source *db.Source) (*feedAuth, error) {
+	cred, err := w.db.GetSourceCredential(source.ID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load credentials failed: %w", err)
+	}
+
+	secret, err := utils.DecryptString(w.config.GetCredentialKey(), cred.SecretEncrypted)
+	if err != nil {
+		// 注意：不要把密文或明文带入错误信息
+		return nil, fmt.Errorf("decrypt credentials failed")
+	}
+
+	auth := &feedAuth{
+		authType:  cred.AuthType,
+		username:  cred.Username,
+		secret:    secret,
+		paramName: cred.ParamName,
+	}
+	if u, err := url.Parse(strings.ReplaceAll(source.URL, tokenPlaceholder, "")); err == nil {
+		auth.host = strings.ToLower(u.Host)
+	}
+	if auth.authType == authTypeSession {
+		if auth.script, err = ParseFetchScript(secret, source.URL); err != nil {
+			return nil, fmt.Errorf("invalid fetch script: %w", err)
+		}
+		auth.sourceID, auth.version = source.ID, cred.UpdatedAt
+		auth.cookies = w.cachedSession(source.ID, cred.UpdatedAt)
+	}
+	return auth, nil
+}
+
+// applyURL 对 token 模式的凭据填充 URL（替换占位符或追加查询参数）
+func (a *feedAuth) applyURL(rawURL string) string {
+	if a == nil || a.authType != authTypeToken || a.secret == "" {
+		return rawURL
+	}
+
+	if strings.Contains(rawURL, tokenPlaceholder) {
+		return strings.ReplaceAll(rawURL, tokenPlaceholder, url.QueryEscape(a.secret))
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || !a.matchesHost(u.Host) {
+		return rawURL
+	}
+	param := a.paramName
+	if param == "" {
+		param = defaultTokenParam
+	}
+	q := u.Query()
+	q.Set(param, a.secret)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// applyRequest 为同域请求设置认证头（basic / header 模式）或会话 Cookie（session 模式）
+func (a *feedAuth) applyRequest(req *http.Request) {
+	if a == nil || req == nil || req.URL == nil || !a.matchesHost(req.URL.Host) {
+		return
+	}
+
+	switch a.authType {
+	case authTypeBasic:
+		req.SetBasicAuth(a.username, a.secret)
+	case authTypeHeader:
+		if a.paramName != "" && a.secret != "" {
+			req.Header.Set(a.paramName, a.secret)
+		}
+	case authTypeSession:
+		for _, cookie := range a.cookies {
+			req.AddCookie(cookie)
+		}
+	}
+}
+
+// matchesHost 判断请求主机是否与订阅源主机一致
+func (a *feedAuth) matchesHost(host string) bool {
+	return a.host != "" && strings.EqualFold(a.host, host)
+}
+
+// redact 抹去文本中出现的凭据，用于错误信息和日志
+func (a *feedAuth) redact(text string) string {
+	if a == nil {
+		return text
+	}
+	for _, s := range []string{a.secret, url.QueryEscape(a.secret), url.PathEscape(a.secret)} {
+		if s != "" {
+			text = strings.ReplaceAll(text, s, "***")
+		}
+	}
+	for _, cookie := range a.cookies {
+		if cookie.Value != "" {
+			text = strings.ReplaceAll(text, cookie.Value, "***")
+		}
+	}
+	return text
+}
+
+// redactError 返回抹去凭据后的错误
+func (a *feedAuth) redactError(err error) error {
+	if err == nil || a == nil {
+		return err
+	}
+	return errors.New(a.redact(err.Error()))
+}
@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// localDateLayouts 不带时区的常见日期格式，按配置的时区（feed_timezone）解析
+// gofeed 会把这类时间当作 UTC，源实际使用本地时间时排序和 since 同步会偏移数小时
+var localDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006/01/02 15:04:05",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006-01-02",
+	"2006/1/2",
+	"2006年1月2日 15:04:05",
+	"2006年1月2日 15:04",
+	"2006年1月2日",
+	"Mon, 2 Jan 2006 15:04:05",
+	"Mon, 2 Jan 2006 15:04",
+	"2 Jan 2006 15:04:05",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006",
+	"January 2, 2006",
+}
+
+// zonedDateLayouts gofeed 无法解析、但带时区的格式
+var zonedDateLayouts = []string{
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02T15:04:05.000-0700",
+	"2006-01-02T15:04:05-0700",
+	"Mon, 2 Jan 2006 15:04:05 MST-0700",
+	"Mon, 2 Jan 2006 15:04:05 GMT-0700",
+	"Mon, 02 Jan 06 15:04:05 -0700",
+	"Mon Jan 2 15:04:05 MST 2006",
+	"Monday, 02-Jan-06 15:04:05 MST",
+}
+
+// 可信的发布时间范围：早于 minPublishedYear 或晚于当前时间 maxPublishedSkew 的视为无效
+const (
+	minPublishedYear = 1990
+	maxPublishedSkew = 24 * time.Hour
+)
+
+// itemPublishedAt 确定文章的发布时间（UTC），第二个返回值表示是否从 feed 中解析成功
+// 依次尝试：原始字符串按无时区格式在 loc 中解析、gofeed 解析结果、额外的带时区格式；
+// 都失败或时间明显不合理（过早或在未来）时使用 now，避免存入 NULL 打乱 published_at 排序
+func itemPublishedAt(feedItem *gofeed.Item, loc *time.Location, now time.Time) (time.Time, bool) {
+	raws := []string{normalizeDateString(feedItem.Published), normalizeDateString(feedItem.Updated)}
+
+	for _, raw := range raws {
+		if t, ok := parseDate(raw, localDateLayouts, loc); ok && plausibleDate(t, now) {
+			return t.UTC(), true
+		}
+	}
+	for _, parsed := range []*time.Time{feedItem.PublishedParsed, feedItem.UpdatedParsed} {
+		if parsed != nil && plausibleDate(*parsed, now) {
+			return parsed.UTC(), true
+		}
+	}
+	for _, raw := range raws {
+		if t, ok := parseDate(raw, zonedDateLayouts, time.UTC); ok && plausibleDate(t, now) {
+			return t.UTC(), true
+		}
+		// 部分源直接输出 Unix 时间戳（秒或毫秒）
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			if n > 1e12 {
+				n /= 1000
+			}
+			if t := time.Unix(n, 0); plausibleDate(t, now) {
+				return t.UTC(), true
+			}
+		}
+	}
+	return now.UTC(), false
+}
+
+// parseDate 按给定格式依次解析，无时区的格式使用 loc
+func parseDate(value string, layouts []string, loc *time.Location) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// normalizeDateString 去掉首尾空白并合并连续空白（常见于模板拼接的日期）
+func normalizeDateString(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// plausibleDate 判断发布时间是否在可信范围内
+func plausibleDate(t, now time.Time) bool {
+	return t.Year() >= minPublishedYear && !t.After(now.Add(maxPublishedSkew))
+}
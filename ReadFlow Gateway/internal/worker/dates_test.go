@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestItemPublishedAt(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	rfc := time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		item   *gofeed.Item
+		want   time.Time
+		wantOK bool
+	}{
+		{
+			name:   "parsed by gofeed",
+			item:   &gofeed.Item{Published: "Mon, 12 Oct 2026 16:00:00 +0800", PublishedParsed: &rfc},
+			want:   rfc,
+			wantOK: true,
+		},
+		{
+			// gofeed 把无时区时间当作 UTC，按配置时区重新解析
+			name:   "no time zone",
+			item:   &gofeed.Item{Published: "2026-10-12 16:00:00", PublishedParsed: ptrTime(time.Date(2026, 10, 12, 16, 0, 0, 0, time.UTC))},
+			want:   rfc,
+			wantOK: true,
+		},
+		{
+			name:   "chinese date with extra spaces",
+			item:   &gofeed.Item{Published: "  2026年10月12日   16:00 "},
+			want:   rfc,
+			wantOK: true,
+		},
+		{
+			name:   "zone after GMT offset",
+			item:   &gofeed.Item{Published: "Mon, 12 Oct 2026 16:00:00 GMT+0800"},
+			want:   rfc,
+			wantOK: true,
+		},
+		{
+			name:   "unix milliseconds",
+			item:   &gofeed.Item{Published: "1791792000000"},
+			want:   rfc,
+			wantOK: true,
+		},
+		{
+			name:   "falls back to updated",
+			item:   &gofeed.Item{Published: "yesterday", Updated: "2026-10-12T08:00:00.000+0000"},
+			want:   rfc,
+			wantOK: true,
+		},
+		{
+			name:   "unparseable",
+			item:   &gofeed.Item{Published: "sometime last week"},
+			want:   now,
+			wantOK: false,
+		},
+		{
+			name:   "zero date",
+			item:   &gofeed.Item{Published: "0001-01-01T00:00:00Z", PublishedParsed: &time.Time{}},
+			want:   now,
+			wantOK: false,
+		},
+		{
+			name:   "far future",
+			item:   &gofeed.Item{PublishedParsed: ptrTime(now.Add(30 * 24 * time.Hour))},
+			want:   now,
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := itemPublishedAt(tt.item, shanghai, now)
+			if !got.Equal(tt.want) || ok != tt.wantOK {
+				t.Errorf("itemPublishedAt = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("location = %v, want UTC", got.Location())
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
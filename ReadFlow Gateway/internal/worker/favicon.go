@@ -0,0 +1,200 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/image"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 源图标相关常量
+const (
+	// 图标重新解析间隔
+	faviconRefreshInterval = 7 * 24 * time.Hour
+	// 抓取站点首页查找 <link rel="icon"> 的超时与最大读取字节数
+	faviconPageTimeout = 15 * time.Second
+	maxFaviconPageSize = 1 << 20
+)
+
+// needsFavicon 源从未解析过图标，或距上次解析已超过 faviconRefreshInterval
+func needsFavicon(source *db.Source) bool {
+	return source.FaviconUpdatedAt == nil || time.Since(*source.FaviconUpdatedAt) > faviconRefreshInterval
+}
+
+// RefreshFavicon 立即重新解析指定源的图标（供管理后台调用），返回新的本地路径
+func (w *Worker) RefreshFavicon(source *db.Source) (string, error) {
+	auth, err := w.loadFeedAuth(source)
+	if err != nil {
+		return "", err
+	}
+	client, err := w.feedClient(source)
+	if err != nil {
+		return "", err
+	}
+	feed, err := w.fetchFeed(client, w.resolveFeedURL(source.URL), auth, source.LenientParse, nil)
+	if err != nil {
+		return "", fmt.Errorf("parse RSS failed: %w", err)
+	}
+	return w.updateFavicon(source, feed, client)
+}
+
+// updateFavicon 解析并缓存源图标，写入 sources.favicon
+// 所有候选都失败时保留旧图标，只更新解析时间，避免每次抓取都重试
+func (w *Worker) updateFavicon(source *db.Source, feed *gofeed.Feed, client *http.Client) (string, error) {
+	favicon, resolveErr := w.resolveFavicon(source, feed, client)
+	if resolveErr != nil {
+		favicon = source.Favicon
+	}
+
+	if err := w.db.UpdateSourceFavicon(source.ID, favicon); err != nil {
+		return "", err
+	}
+	if favicon != source.Favicon {
+		image.DeleteFavicon(w.staticDir, source.Favicon)
+	}
+	now := time.Now()
+	source.Favicon, source.FaviconUpdatedAt = favicon, &now
+
+	return favicon, resolveErr
+}
+
+// resolveFavicon 依次尝试 feed 的 image、站点 /favicon.ico、首页 <link rel="icon">
+func (w *Worker) resolveFavicon(source *db.Source, feed *gofeed.Feed, client *http.Client) (string, error) {
+	var candidates []string
+	if feed.Image != nil && feed.Image.URL != "" {
+		candidates = append(candidates, feed.Image.URL)
+	}
+
+	site := faviconSiteURL(source, feed)
+	if site != nil {
+		candidates = append(candidates, site.Scheme+"://"+site.Host+"/favicon.ico")
+	}
+
+	tried := make(map[string]bool)
+	try := func(candidates []string) string {
+		for _, candidate := range candidates {
+			if tried[candidate] {
+				continue
+			}
+			tried[candidate] = true
+			localPath, err := w.imageProcessor.ProcessFavicon(source.ID, candidate)
+			if err == nil {
+				return localPath
+			}
+			log.Printf("[Favicon] Candidate %s failed for source %d: %v", candidate, source.ID, err)
+		}
+		return ""
+	}
+
+	if localPath := try(candidates); localPath != "" {
+		return localPath, nil
+	}
+	// 首页声明的图标需要额外请求一次页面，放在最后
+	if site != nil {
+		if localPath := try(w.pageIconURLs(client, site)); localPath != "" {
+			return localPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("no usable favicon among %d candidates", len(tried))
+}
+
+// faviconSiteURL 源站首页地址：优先 feed 的 link，其次订阅地址本身
+func faviconSiteURL(source *db.Source, feed *gofeed.Feed) *url.URL {
+	for _, raw := range []string{feed.Link, source.URL} {
+		u, err := url.Parse(raw)
+		if err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			return u
+		}
+	}
+	return nil
+}
+
+// pageIconURLs 抓取站点首页，返回 <head> 中 <link rel="icon"> 声明的图标地址
+func (w *Worker) pageIconURLs(client *http.Client, site *url.URL) []string {
+	ctx, cancel := context.WithTimeout(context.Background(), faviconPageTimeout)
+	defer cancel()
+
+	home := &url.URL{Scheme: site.Scheme, Host: site.Host, Path: "/"}
+	req, err := http.NewRequestWithContext(ctx, "GET", home.String(), nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", w.parser.UserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconPageSize))
+	if err != nil {
+		return nil
+	}
+	return iconLinks(page, resp.Request.URL)
+}
+
+// iconLinks 解析页面中的 <link rel="icon"> / <link rel="apple-touch-icon">，相对地址按页面地址解析
+func iconLinks(page []byte, base *url.URL) []string {
+	var icons []string
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return icons
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.DataAtom == atom.Body {
+				return icons
+			}
+			if token.DataAtom != atom.Link {
+				continue
+			}
+			var rel, href string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "rel":
+					rel = strings.ToLower(attr.Val)
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				}
+			}
+			if href == "" || !isIconRel(rel) {
+				continue
+			}
+			ref, err := url.Parse(href)
+			if err != nil {
+				continue
+			}
+			resolved := base.ResolveReference(ref)
+			if resolved.Scheme == "http" || resolved.Scheme == "https" {
+				icons = append(icons, resolved.String())
+			}
+		}
+	}
+}
+
+// isIconRel rel 属性是否声明了站点图标
+func isIconRel(rel string) bool {
+	for _, value := range strings.Fields(rel) {
+		if value == "icon" || value == "apple-touch-icon" {
+			return true
+		}
+	}
+	return false
+}
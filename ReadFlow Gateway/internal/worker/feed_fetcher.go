@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// maxFeedSize 订阅源响应体最大字节数
+const maxFeedSize = 20 << 20
+
+// fetchFeed 下载并解析订阅源（支持私有源凭据，会话模式下先登录）
+// 与 gofeed.ParseURL 行为一致，但允许在请求上附加认证信息；lenient 为源级宽松解析开关
+func (w *Worker) fetchFeed(client *http.Client, feedURL string, auth *feedAuth, lenient bool, validators *feedValidators) (*gofeed.Feed, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	body, contentType, err := w.downloadFeedWithSession(ctx, client, feedURL, auth, validators)
+	if err != nil {
+		return nil, err
+	}
+	// 网页、空响应等交给 gofeed 可能被解析成没有文章的空 feed，需要在解析前拦下
+	if err := validateFeedResponse(body, contentType); err != nil {
+		return nil, err
+	}
+
+	feed, err := w.parseFeedBody(body, feedURL, lenient)
+	if err != nil {
+		return nil, auth.redactError(err)
+	}
+	return feed, nil
+}
+
+// downloadFeed 下载订阅源内容并转为 UTF-8（gofeed 只认 XML 声明，不看响应头中的编码）
+// 同时返回响应的 Content-Type
+// 主机不在 RSS_ALLOWED_HOSTS / RSS_DENIED_HOSTS 允许范围内时返回 utils.ErrHostNotAllowed
+// （重定向由客户端的 CheckRedirect 在请求前检查，见 feedHosts.CheckRedirect）
+// validators 不为空时发送条件请求，订阅源响应 304 时返回 errFeedNotModified，成功时更新为响应的校验值
+func (w *Worker) downloadFeed(ctx context.Context, client *http.Client, feedURL string, auth *feedAuth, validators *feedValidators) ([]byte, string, error) {
+	if err := w.feedHosts.Check(feedURL); err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", auth.applyURL(feedURL), nil)
+	if err != nil {
+		return nil, "", auth.redactError(err)
+	}
+	req.Header.Set("User-Agent", w.parser.UserAgent)
+	auth.applyRequest(req)
+	validators.apply(req)
+
+	// 单次请求的时限从排队等待主机并发名额开始计算（客户端不设 Timeout），排队时间同样受限
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+	release, err := w.hostSlots.acquire(ctx, req.URL.Host)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", auth.redactError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && !validators.empty() {
+		return nil, "", errFeedNotModified
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", gofeed.HTTPError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, "", auth.redactError(err)
+	}
+	validators.update(resp)
+	contentType := resp.Header.Get("Content-Type")
+	return decodeFeedBody(body, contentType), contentType, nil
+}
+
+// feedClient 返回抓取该源使用的 HTTP 客户端
+// 未设置源级代理时使用全局客户端；设置为 direct 时绕过全局代理直连
+// 同一代理地址的客户端会被复用，以保留连接池
+func (w *Worker) feedClient(source *db.Source) (*http.Client, error) {
+	if source.ProxyURL == "" {
+		return w.parser.Client, nil
+	}
+	if client, ok := w.sourceClients.Load(source.ProxyURL); ok {
+		return client.(*http.Client), nil
+	}
+
+	var proxy *url.URL
+	if source.ProxyURL != utils.ProxyDirect {
+		parsed, err := utils.ParseProxyURL(source.ProxyURL)
+		if err != nil {
+			// 注意：代理地址可能包含密码，不要带入错误信息
+			return nil, fmt.Errorf("invalid source proxy: %w", err)
+		}
+		proxy = parsed
+	}
+
+	// 不设 Timeout：时限由每个请求的 context 控制，见 downloadFeed
+	client := utils.NewHTTPClient(utils.HTTPClientOptions{
+		Proxy:           proxy,
+		MaxIdleConns:    10,
+		IdleConnTimeout: 90 * time.Second,
+		CheckRedirect:   w.feedHosts.CheckRedirect,
+	})
+	actual, _ := w.sourceClients.LoadOrStore(source.ProxyURL, client)
+	return actual.(*http.Client), nil
+}
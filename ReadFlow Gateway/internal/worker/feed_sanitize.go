@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// parseFeedBody 解析订阅源内容
+// 源开启了宽松解析（lenient_parse）且严格解析失败时，清理 XML 中的非法字符后重试一次；
+// 未实际清理任何内容时不重试，直接返回原错误；未开启时只在日志中提示可以开启。裸 & 和未知实体 gofeed 本身已能容忍，无需修复
+func (w *Worker) parseFeedBody(body []byte, feedURL string, lenient bool) (*gofeed.Feed, error) {
+	feed, err := w.parser.Parse(bytes.NewReader(body))
+	if err == nil {
+		return feed, nil
+	}
+
+	sanitized, removed := sanitizeFeedXML(body)
+	if removed == 0 {
+		return nil, err
+	}
+	if !lenient {
+		log.Printf("[RSS] %s contains %d invalid XML characters, enable lenient parse for this source to recover", feedURL, removed)
+		return nil, err
+	}
+	log.Printf("[RSS] Strict parse failed for %s (%v), retrying with lenient parse: %d invalid characters removed", feedURL, err, removed)
+	return w.parser.Parse(bytes.NewReader(sanitized))
+}
+
+// sanitizeFeedXML 清理 XML 1.0 不允许的字符，返回清理后的内容和清理的字符数：
+//   - 除制表符、换行、回车外的控制字符，以及 U+FFFE、U+FFFF
+//   - 指向上述字符的数字字符引用（如 &#0;、&#x1B;）
+//   - 无效的 UTF-8 字节，替换为 U+FFFD
+func sanitizeFeedXML(body []byte) ([]byte, int) {
+	var out []byte // 遇到第一处需要清理的位置时才分配
+	removed := 0
+	for i := 0; i < len(body); {
+		r, size := utf8.DecodeRune(body[i:])
+
+		skip, replacement := 0, ""
+		switch {
+		case r == utf8.RuneError && size == 1:
+			skip, replacement = 1, "\uFFFD"
+		case !isXMLChar(r):
+			skip = size
+		case r == '&':
+			skip = invalidCharRefLen(body[i:])
+		}
+
+		if skip == 0 {
+			if out != nil {
+				out = append(out, body[i:i+size]...)
+			}
+			i += size
+			continue
+		}
+		if out == nil {
+			out = make([]byte, i, len(body))
+			copy(out, body[:i])
+		}
+		out = append(out, replacement...)
+		removed++
+		i += skip
+	}
+
+	if out == nil {
+		return body, 0
+	}
+	return out, removed
+}
+
+// isXMLChar 判断字符是否允许出现在 XML 1.0 文档中
+func isXMLChar(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n' || r == '\r':
+		return true
+	case r < 0x20:
+		return false
+	case r == 0xFFFE || r == 0xFFFF:
+		return false
+	}
+	return true
+}
+
+// invalidCharRefLen data 以数字字符引用开头且引用的字符不允许出现在 XML 中时返回引用的长度，否则返回 0
+func invalidCharRefLen(data []byte) int {
+	if len(data) < 4 || data[1] != '#' {
+		return 0
+	}
+	end := bytes.IndexByte(data, ';')
+	if end < 0 || end > 12 {
+		return 0
+	}
+
+	digits, base := string(data[2:end]), 10
+	if len(digits) > 0 && (digits[0] == 'x' || digits[0] == 'X') {
+		digits, base = digits[1:], 16
+	}
+	value, err := strconv.ParseUint(digits, base, 32)
+	if err != nil || isXMLChar(rune(value)) {
+		return 0
+	}
+	return end + 1
+}
@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestSanitizeFeedXML(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+		removed        int
+	}{
+		{"clean", `<title>AT&T &amp; &#169; &#x41;</title>`, `<title>AT&T &amp; &#169; &#x41;</title>`, 0},
+		{"control chars", "<title>a\x0bb\x00c\tok</title>", "<title>abc\tok</title>", 2},
+		{"invalid char refs", `<title>a&#0;b&#x1B;c&#11;</title>`, `<title>abc</title>`, 3},
+		{"noncharacters", "<title>a\uFFFEb\uFFFF</title>", "<title>ab</title>", 2},
+		{"invalid utf-8", "<title>Caf\xe9</title>", "<title>Caf\uFFFD</title>", 1},
+		{"unterminated ref", `<title>&#12 x</title>`, `<title>&#12 x</title>`, 0},
+	}
+	for _, tc := range cases {
+		got, removed := sanitizeFeedXML([]byte(tc.in))
+		if string(got) != tc.want || removed != tc.removed {
+			t.Errorf("%s: sanitizeFeedXML(%q) = %q, %d; want %q, %d", tc.name, tc.in, got, removed, tc.want, tc.removed)
+		}
+	}
+}
+
+func TestParseFeedBodyLenient(t *testing.T) {
+	w := &Worker{parser: gofeed.NewParser()}
+	cases := []struct {
+		file, title, firstItem string
+	}{
+		{"testdata/broken_control_chars.xml", "R&D 周报", "Q&A：如何评估开源项目的健康度"},
+		{"testdata/broken_char_refs.xml", "Engineering Notes", "Terminal colours [1m done right"},
+		{"testdata/broken_invalid_utf8.xml", "Le Petit Caf\uFFFD", "Menu d'\uFFFDt\uFFFD"},
+	}
+	for _, tc := range cases {
+		body, err := os.ReadFile(tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// 这些源在严格解析下失败
+		if _, err := w.parseFeedBody(body, tc.file, false); err == nil {
+			t.Errorf("%s: strict parse succeeded, fixture is no longer broken", tc.file)
+		}
+
+		feed, err := w.parseFeedBody(body, tc.file, true)
+		if err != nil {
+			t.Errorf("%s: lenient parse: %v", tc.file, err)
+			continue
+		}
+		if feed.Title != tc.title {
+			t.Errorf("%s: title = %q, want %q", tc.file, feed.Title, tc.title)
+		}
+		if len(feed.Items) == 0 || strings.TrimSpace(feed.Items[0].Title) != tc.firstItem {
+			t.Errorf("%s: unexpected first item: %+v", tc.file, feed.Items)
+		}
+	}
+
+	// 清理后仍无法解析时返回错误
+	if _, err := w.parseFeedBody([]byte("<rss><channel>\x01<title>"), "broken", true); err == nil {
+		t.Errorf("truncated feed parsed without error")
+	}
+}
@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// ErrNotAFeed 地址返回的内容不是 RSS / Atom / JSON Feed
+var ErrNotAFeed = errors.New("not a valid feed")
+
+// sniffLen 判断响应类型时检查的内容前缀长度
+const sniffLen = 1024
+
+// feedPrefixes 订阅源内容的典型开头（小写比较）
+var feedPrefixes = [][]byte{
+	[]byte("<?xml"),
+	[]byte("<rss"),
+	[]byte("<feed"),
+	[]byte("<rdf:rdf"),
+	[]byte("{"), // JSON Feed
+}
+
+// htmlPrefixes 网页内容的典型开头（小写比较）
+var htmlPrefixes = [][]byte{
+	[]byte("<!doctype html"),
+	[]byte("<html"),
+	[]byte("<head"),
+	[]byte("<body"),
+}
+
+// validateFeedResponse 解析前检查响应是否可能是订阅源
+// 内容开头像订阅源时一律放行（不少源站把 feed 标成 text/html）；
+// 空响应、网页（错误页、登录页）和图片等明显不是订阅源的响应返回 ErrNotAFeed，
+// 其余情况交给解析器判断
+func validateFeedResponse(body []byte, contentType string) error {
+	head := bytes.TrimLeft(body, "\ufeff \t\r\n")
+	if len(head) == 0 {
+		return fmt.Errorf("%w: empty response (Content-Type: %s)", ErrNotAFeed, displayContentType(contentType))
+	}
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	lower := bytes.ToLower(head)
+
+	if looksLikeFeed(lower) {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case hasAnyPrefix(lower, htmlPrefixes), mediaType == "text/html", mediaType == "application/xhtml+xml":
+		if title := htmlTitle(lower, head); title != "" {
+			return fmt.Errorf("%w: got an HTML page instead of a feed (Content-Type: %s, title: %q)",
+				ErrNotAFeed, displayContentType(contentType), title)
+		}
+		return fmt.Errorf("%w: got an HTML page instead of a feed (Content-Type: %s)", ErrNotAFeed, displayContentType(contentType))
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"), mediaType == "application/pdf":
+		return fmt.Errorf("%w: unexpected Content-Type %s", ErrNotAFeed, mediaType)
+	}
+	return nil
+}
+
+// looksLikeFeed 内容开头（已转小写）是否为 XML / RSS / Atom / JSON Feed，允许前置注释
+func looksLikeFeed(lower []byte) bool {
+	if hasAnyPrefix(lower, feedPrefixes) {
+		return true
+	}
+	if bytes.HasPrefix(lower, []byte("<!--")) {
+		return bytes.Contains(lower, []byte("<rss")) ||
+			bytes.Contains(lower, []byte("<feed")) ||
+			bytes.Contains(lower, []byte("<rdf:rdf"))
+	}
+	return false
+}
+
+// hasAnyPrefix 是否以任一前缀开头
+func hasAnyPrefix(data []byte, prefixes [][]byte) bool {
+	for _, p := range prefixes {
+		if bytes.HasPrefix(data, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlTitle 从网页开头提取 <title>，便于在 last_error 中分辨错误页或登录页
+func htmlTitle(lower, original []byte) string {
+	if len(lower) != len(original) {
+		original = lower // 少数字符转小写后字节数不同，下标无法对应原文
+	}
+	start := bytes.Index(lower, []byte("<title"))
+	if start < 0 {
+		return ""
+	}
+	open := bytes.IndexByte(lower[start:], '>')
+	if open < 0 {
+		return ""
+	}
+	start += open + 1
+	end := bytes.Index(lower[start:], []byte("</title"))
+	if end < 0 {
+		return ""
+	}
+	title := strings.Join(strings.Fields(string(original[start:start+end])), " ")
+	if runes := []rune(title); len(runes) > 80 {
+		title = string(runes[:80]) + "…"
+	}
+	return title
+}
+
+// displayContentType 响应未带 Content-Type 时显示为 none
+func displayContentType(contentType string) string {
+	if contentType == "" {
+		return "none"
+	}
+	return contentType
+}
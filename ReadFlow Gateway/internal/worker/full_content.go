@@ -0,0 +1,181 @@
+package worker
+
+import (
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/readflow/gateway/internal/db"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 全文提取限制
+const (
+	// feed 正文纯文本少于该字符数时视为只有摘要，尝试从原文链接提取全文
+	fullContentMinChars = 500
+	// 单篇文章的提取时限，需明显小于单个源的处理时限 sourceTimeout
+	fullContentTimeout = 20 * time.Second
+	// 同时进行的全文提取数（所有源共享），避免压垮响应慢的站点
+	maxConcurrentExtractions = 3
+)
+
+// fetchFullContent 源开启全文提取且 feed 正文过短时，从原文链接提取全文
+// 返回清理后的 HTML；不需要提取或提取失败时返回空字符串，由调用方沿用 feed 内容
+func (w *Worker) fetchFullContent(source *db.Source, link, content string) string {
+	if !source.FullContent || link == "" {
+		return ""
+	}
+	if utf8.RuneCountInString(strings.TrimSpace(CleanHTMLTags(content))) >= fullContentMinChars {
+		return ""
+	}
+
+	// 与订阅源同域的原文链接附带源凭据
+	auth, err := w.loadFeedAuth(source)
+	if err != nil {
+		log.Printf("[Worker] Skip full content for %s: %v", link, err)
+		return ""
+	}
+
+	w.extractSlots <- struct{}{}
+	defer func() { <-w.extractSlots }()
+
+	extracted, err := w.contentExtractor.extractWithTimeout(link, auth, fullContentTimeout)
+	if errors.Is(err, errPaywalled) {
+		log.Printf("[Worker] Paywall or login wall at %s, keeping feed content: %v", auth.redact(link), err)
+		return ""
+	}
+	if err != nil {
+		log.Printf("[Worker] Full content extraction failed for %s, using feed content: %v", auth.redact(link), err)
+		return ""
+	}
+	// 提取结果不比 feed 内容长时没有意义
+	if utf8.RuneCountInString(CleanHTMLTags(extracted)) <= utf8.RuneCountInString(CleanHTMLTags(content)) {
+		return ""
+	}
+	return extracted
+}
+
+// unsafeElements 清理时连同内容一起移除的元素
+var unsafeElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Object:   true,
+	atom.Form:     true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Math:     true,
+}
+
+// unsafeVoidElements 清理时移除的空元素（没有闭合标签）
+var unsafeVoidElements = map[atom.Atom]bool{
+	atom.Embed: true,
+	atom.Link:  true,
+	atom.Meta:  true,
+	atom.Base:  true,
+	atom.Input: true,
+}
+
+// urlAttributes 取值为地址的属性，只允许 http(s)、相对地址等安全协议
+var urlAttributes = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"poster":     true,
+	"background": true,
+	"xlink:href": true,
+}
+
+// sanitizeHTML 清理从网页提取的 HTML：移除脚本、样式、内嵌框架和表单，
+// 去掉事件属性（on*）和 javascript: 等危险协议的地址，同时丢弃注释
+func sanitizeHTML(content string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	var b strings.Builder
+	var skip atom.Atom // 正在跳过的元素
+	skipDepth := 0
+
+	for {
+		tt := tokenizer.Next()
+		if tt == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				log.Printf("[Worker] sanitizeHTML stopped early: %v", tokenizer.Err())
+			}
+			return b.String()
+		}
+		token := tokenizer.Token()
+
+		if skipDepth > 0 {
+			switch {
+			case tt == html.StartTagToken && token.DataAtom == skip:
+				skipDepth++
+			case tt == html.EndTagToken && token.DataAtom == skip:
+				skipDepth--
+			}
+			continue
+		}
+
+		switch tt {
+		case html.CommentToken, html.DoctypeToken:
+			continue
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if unsafeVoidElements[token.DataAtom] {
+				continue
+			}
+			if unsafeElements[token.DataAtom] {
+				if tt == html.StartTagToken {
+					skip, skipDepth = token.DataAtom, 1
+				}
+				continue
+			}
+			token.Attr = safeAttributes(token.Attr)
+		case html.EndTagToken:
+			if unsafeElements[token.DataAtom] || unsafeVoidElements[token.DataAtom] {
+				continue
+			}
+		}
+		b.WriteString(token.String())
+	}
+}
+
+// safeAttributes 过滤事件属性和危险协议的地址属性
+func safeAttributes(attrs []html.Attribute) []html.Attribute {
+	kept := attrs[:0]
+	for _, attr := range attrs {
+		name := strings.ToLower(attr.Key)
+		if attr.Namespace != "" {
+			name = strings.ToLower(attr.Namespace) + ":" + name
+		}
+		if strings.HasPrefix(name, "on") || name == "srcdoc" {
+			continue
+		}
+		if urlAttributes[name] && !isSafeURL(attr.Val) {
+			continue
+		}
+		kept = append(kept, attr)
+	}
+	return kept
+}
+
+// isSafeURL 判断属性中的地址是否可以保留（相对地址、http(s)、mailto，以及 data:image）
+func isSafeURL(value string) bool {
+	v := strings.ToLower(strings.Join(strings.Fields(value), ""))
+	colon := strings.Index(v, ":")
+	if colon < 0 || strings.ContainsAny(v[:colon], "/?#") {
+		return true // 相对地址
+	}
+	switch v[:colon] {
+	case "http", "https", "mailto":
+		return true
+	case "data":
+		return strings.HasPrefix(v, "data:image/") && !strings.HasPrefix(v, "data:image/svg")
+	}
+	return false
+}
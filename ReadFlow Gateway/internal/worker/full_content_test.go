@@ -0,0 +1,107 @@
+package worker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	cases := []struct {
+		name, in, want string
+	}{
+		{"script", `<p>a</p><script>alert(1)</script><p>b</p>`, `<p>a</p><p>b</p>`},
+		{"nested skip", `<div><object data="x"><object></object>inner</object>after</div>`, `<div>after</div>`},
+		{"event attribute", `<img src="a.jpg" onerror="alert(1)" alt="x">`, `<img src="a.jpg" alt="x">`},
+		{"javascript url", `<a href=" JaVa&#x09;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"relative url kept", `<a href="/post/1?a=b:c">x</a>`, `<a href="/post/1?a=b:c">x</a>`},
+		{"data image kept", `<img src="data:image/png;base64,AAAA">`, `<img src="data:image/png;base64,AAAA">`},
+		{"comment and void", `<!-- c --><link rel="x"><p>t</p>`, `<p>t</p>`},
+	}
+	for _, tc := range cases {
+		if got := sanitizeHTML(tc.in); got != tc.want {
+			t.Errorf("%s: sanitizeHTML(%q) = %q, want %q", tc.name, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFetchFullContent(t *testing.T) {
+	paragraph := strings.Repeat("Full article sentence with enough words to count. ", 20)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>T</title><script>var x = 1;</script></head><body>
+			<nav>menu</nav><article><h1>T</h1><p>` + paragraph + `</p><p onclick="x()">` + paragraph + `</p></article></body></html>`))
+	})
+	teaser := strings.Repeat("Teaser sentence before the paywall. ", 8)
+	mux.HandleFunc("/paywalled", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><body><article><h1>T</h1><p>` + teaser + `</p><p>Subscribe to continue reading this story.</p></article></body></html>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	w := &Worker{
+		db:               database,
+		contentExtractor: NewContentExtractor(nil, nil),
+		extractSlots:     make(chan struct{}, 1),
+	}
+	source := &db.Source{ID: 1, URL: srv.URL + "/feed", FullContent: true}
+	summary := "<p>Short teaser.</p>"
+
+	got := w.fetchFullContent(source, srv.URL+"/article", summary)
+	if !strings.Contains(got, "Full article sentence") {
+		t.Fatalf("expected extracted article, got %q", got)
+	}
+	if strings.Contains(got, "<script") || strings.Contains(got, "onclick") {
+		t.Errorf("extracted content not sanitized: %q", got)
+	}
+
+	// 关闭、正文已足够长、提取失败时都返回空字符串，由调用方沿用 feed 内容
+	if got := w.fetchFullContent(&db.Source{ID: 1}, srv.URL+"/article", summary); got != "" {
+		t.Errorf("disabled source extracted %q", got)
+	}
+	if got := w.fetchFullContent(source, srv.URL+"/article", "<p>"+paragraph+"</p>"); got != "" {
+		t.Errorf("long feed content should not be replaced, got %q", got)
+	}
+	if got := w.fetchFullContent(source, srv.URL+"/missing", summary); got != "" {
+		t.Errorf("failed extraction should fall back, got %q", got)
+	}
+	// 付费墙预览比 feed 摘要长，也不替换 feed 内容
+	if got := w.fetchFullContent(source, srv.URL+"/paywalled", summary); got != "" {
+		t.Errorf("paywalled stub should fall back, got %q", got)
+	}
+}
+
+func TestExtractArticleNodeSkipsChrome(t *testing.T) {
+	paragraph := "<p>" + strings.Repeat("This is the body of the article with enough words to score well. ", 6) + "</p>"
+	body := `<header><img src="https://ads.example.com/banner.gif"></header>` +
+		`<article>` + paragraph + `<img src="https://example.com/photo.jpg">` + paragraph + `</article>` +
+		`<aside class="sidebar widget"><img src="https://ads.example.com/widget.png"></aside>` +
+		`<footer><img src="https://ads.example.com/footer.gif"></footer>`
+
+	node, articleHTML := extractArticleNode(body, "https://example.com/post")
+	if node == nil {
+		t.Fatal("article node not found")
+	}
+	if !strings.Contains(articleHTML, "https://example.com/photo.jpg") {
+		t.Errorf("article image missing: %s", articleHTML)
+	}
+	if strings.Contains(articleHTML, "ads.example.com") {
+		t.Errorf("chrome images kept: %s", articleHTML)
+	}
+
+	if node, _ := extractArticleNode("", ""); node != nil {
+		t.Errorf("empty body should not yield an article node")
+	}
+}
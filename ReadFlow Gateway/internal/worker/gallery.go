@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/image"
+)
+
+// 图集限制：只取正文前几张图片，本地化后的文件总大小超过上限即停止
+const (
+	maxGalleryImages = 8
+	maxGalleryBytes  = 8 << 20
+)
+
+// buildGallery 收集正文中的前几张图片作为图集，返回 JSON 数组（没有图片时为空字符串）
+// 占位图和声明尺寸过小的图片会被过滤；图片地址按源的图片模式给出：
+// process 使用 ProcessContent 已缓存的本地路径，proxy 改写为代理地址，off 保留原始地址
+func (w *Worker) buildGallery(source *db.Source, content string, localPaths map[string]string) string {
+	if !source.GalleryEnabled || content == "" {
+		return ""
+	}
+
+	rc := config.GetRuntimeConfig()
+	minWidth, minHeight := rc.GetCoverMinWidth(), rc.GetCoverMinHeight()
+
+	var gallery []db.GalleryImage
+	seen := make(map[string]bool)
+	var totalBytes int64
+	for _, candidate := range w.imageExtractor.extractFromHTML(content) {
+		if len(gallery) >= maxGalleryImages {
+			break
+		}
+		imageURL := strings.TrimSpace(candidate.URL)
+		if seen[imageURL] || w.imageExtractor.isPlaceholderImage(imageURL, candidate.Alt) ||
+			isTooSmall(candidate.Width, candidate.Height, minWidth, minHeight) {
+			continue
+		}
+		seen[imageURL] = true
+
+		switch source.ImageMode {
+		case db.ImageModeOff:
+		case db.ImageModeProxy:
+			imageURL = image.ProxyPath(imageURL, w.config.GetImageProxyKey())
+		default:
+			localPath := localPaths[imageURL]
+			if localPath == "" {
+				continue // 下载或压缩失败的图片不进入图集
+			}
+			imageURL = localPath
+			totalBytes += w.staticFileSize(localPath)
+		}
+		if totalBytes > maxGalleryBytes {
+			break
+		}
+
+		gallery = append(gallery, db.GalleryImage{URL: imageURL, Alt: strings.TrimSpace(candidate.Alt)})
+	}
+
+	if len(gallery) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(gallery)
+	return string(data)
+}
+
+// staticFileSize 返回 /static 下本地文件的大小，文件不存在时为 0
+func (w *Worker) staticFileSize(localPath string) int64 {
+	info, err := os.Stat(filepath.Clean(filepath.Join(w.staticDir, "..", localPath))) // path 已包含 /static
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
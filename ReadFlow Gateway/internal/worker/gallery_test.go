@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestBuildGallery(t *testing.T) {
+	w := &Worker{imageExtractor: NewImageExtractor(nil)}
+	source := &db.Source{GalleryEnabled: true, ImageMode: db.ImageModeOff}
+
+	var sb strings.Builder
+	sb.WriteString(`<p><img src="https://cdn.example.com/placeholder.png" alt="loading"></p>`)
+	sb.WriteString(`<p><img src="https://cdn.example.com/tiny.jpg" width="10" height="10"></p>`)
+	for i := 0; i < maxGalleryImages+2; i++ {
+		sb.WriteString(fmt.Sprintf(`<figure><img src="https://cdn.example.com/%d.jpg" alt="photo %d"></figure>`, i, i))
+	}
+	sb.WriteString(`<img src="https://cdn.example.com/0.jpg">`)
+
+	var gallery []db.GalleryImage
+	if err := json.Unmarshal([]byte(w.buildGallery(source, sb.String(), nil)), &gallery); err != nil {
+		t.Fatal(err)
+	}
+	if len(gallery) != maxGalleryImages {
+		t.Fatalf("got %d images, want %d", len(gallery), maxGalleryImages)
+	}
+	for i, img := range gallery {
+		if want := fmt.Sprintf("https://cdn.example.com/%d.jpg", i); img.URL != want || img.Alt != fmt.Sprintf("photo %d", i) {
+			t.Errorf("gallery[%d] = %+v, want %s", i, img, want)
+		}
+	}
+
+	// process 模式只收录已本地化的图片
+	source.ImageMode = db.ImageModeProcess
+	local := map[string]string{"https://cdn.example.com/1.jpg": "/static/images/1/abc.webp"}
+	if got := w.buildGallery(source, sb.String(), local); got != `[{"url":"/static/images/1/abc.webp","alt":"photo 1"}]` {
+		t.Errorf("process mode gallery = %s", got)
+	}
+
+	source.GalleryEnabled = false
+	if got := w.buildGallery(source, sb.String(), nil); got != "" {
+		t.Errorf("disabled source produced gallery %s", got)
+	}
+}
@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// itemKey 文章的去重标识
+type itemKey struct {
+	guid     string // 入库和去重使用的 GUID
+	collided string // 同一 feed 中被多篇文章共用的原始 GUID，未冲突时为空
+}
+
+// itemContentHash 计算文章内容哈希（用于去重和检测文章修改）
+func itemContentHash(feedItem *gofeed.Item) string {
+	content := feedItem.Content
+	if content == "" {
+		content = feedItem.Description
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(feedItem.Title+content)))
+}
+
+// itemKeys 为 feed 中的每篇文章确定去重标识
+// 优先使用 feed 的 GUID，没有时使用链接；两者都没有，或同一 feed 中多篇文章共用一个 GUID 时，
+// 改用标题、发布时间和内容哈希生成的合成 GUID：内容不同的文章不会被当作同一篇丢弃，原样重发的文章仍会去重。
+// 共用 GUID 的文章全部改用合成 GUID，结果不受文章在 feed 中的顺序影响
+func itemKeys(sourceURL string, items []*gofeed.Item) []itemKey {
+	counts := make(map[string]int, len(items))
+	for _, feedItem := range items {
+		if feedItem == nil {
+			continue
+		}
+		if guid := feedGUID(feedItem); guid != "" {
+			counts[guid]++
+		}
+	}
+
+	keys := make([]itemKey, len(items))
+	for i, feedItem := range items {
+		if feedItem == nil {
+			continue
+		}
+		guid := feedGUID(feedItem)
+		switch {
+		case guid == "":
+			keys[i].guid = syntheticGUID(feedItem)
+			log.Printf("[Worker] Item %q in %s has no GUID or link, using synthetic GUID %s", feedItem.Title, sourceURL, keys[i].guid)
+		case counts[guid] > 1:
+			keys[i] = itemKey{guid: syntheticGUID(feedItem), collided: guid}
+			log.Printf("[Worker] GUID %s is shared by %d items in %s, using synthetic GUID %s for %q",
+				guid, counts[guid], sourceURL, keys[i].guid, feedItem.Title)
+		default:
+			keys[i].guid = guid
+		}
+	}
+	return keys
+}
+
+// feedGUID feed 提供的 GUID，没有时使用链接
+func feedGUID(feedItem *gofeed.Item) string {
+	if feedItem.GUID != "" {
+		return feedItem.GUID
+	}
+	return feedItem.Link
+}
+
+// syntheticGUID 由标题、发布时间（原始字符串）和内容哈希生成确定的 GUID
+func syntheticGUID(feedItem *gofeed.Item) string {
+	published := feedItem.Published
+	if published == "" {
+		published = feedItem.Updated
+	}
+	sum := sha256.Sum256([]byte(feedItem.Title + "\x00" + published + "\x00" + itemContentHash(feedItem)))
+	return fmt.Sprintf("synthetic:%x", sum[:16])
+}
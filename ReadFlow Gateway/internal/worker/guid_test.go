@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+func TestItemKeysMissingGUIDAndLink(t *testing.T) {
+	items := []*gofeed.Item{
+		{Title: "First", Published: "Mon, 12 Oct 2026 08:00:00 GMT", Description: "one"},
+		{Title: "Second", Published: "Mon, 12 Oct 2026 09:00:00 GMT", Description: "two"},
+		{Title: "Linked", Link: "https://example.com/a"},
+	}
+
+	keys := itemKeys("https://example.com/feed", items)
+	if keys[0].guid == "" || keys[0].guid == keys[1].guid {
+		t.Fatalf("distinct items without GUID got keys %q and %q", keys[0].guid, keys[1].guid)
+	}
+	if keys[0].collided != "" {
+		t.Errorf("missing GUID marked as collided: %q", keys[0].collided)
+	}
+	if keys[2].guid != "https://example.com/a" {
+		t.Errorf("link fallback = %q, want the link", keys[2].guid)
+	}
+
+	// 原样重发的文章得到相同的合成 GUID
+	again := itemKeys("https://example.com/feed", []*gofeed.Item{
+		{Title: "First", Published: "Mon, 12 Oct 2026 08:00:00 GMT", Description: "one"},
+	})
+	if again[0].guid != keys[0].guid {
+		t.Errorf("re-emitted item got %q, want %q", again[0].guid, keys[0].guid)
+	}
+}
+
+func TestItemKeysDuplicateGUID(t *testing.T) {
+	items := []*gofeed.Item{
+		{GUID: "same", Title: "Morning news", Published: "2026-10-12T08:00:00Z", Content: "a"},
+		{GUID: "unique", Title: "Other", Content: "b"},
+		{GUID: "same", Title: "Evening news", Published: "2026-10-12T20:00:00Z", Content: "c"},
+		{GUID: "same", Title: "Morning news", Published: "2026-10-12T08:00:00Z", Content: "a"},
+	}
+
+	keys := itemKeys("https://example.com/feed", items)
+	if keys[1].guid != "unique" || keys[1].collided != "" {
+		t.Errorf("unique GUID changed: %+v", keys[1])
+	}
+	for _, i := range []int{0, 2, 3} {
+		if keys[i].collided != "same" || keys[i].guid == "same" {
+			t.Errorf("item %d key = %+v, want synthetic GUID collided with %q", i, keys[i], "same")
+		}
+	}
+	if keys[0].guid == keys[2].guid {
+		t.Errorf("distinct items sharing a GUID got the same key %q", keys[0].guid)
+	}
+	if keys[0].guid != keys[3].guid {
+		t.Errorf("identical items got different keys %q and %q", keys[0].guid, keys[3].guid)
+	}
+
+	// 结果与文章顺序无关
+	reordered := itemKeys("https://example.com/feed", []*gofeed.Item{items[2], items[0]})
+	if reordered[0].guid != keys[2].guid || reordered[1].guid != keys[0].guid {
+		t.Errorf("keys depend on item order: %+v", reordered)
+	}
+}
+
+func TestGUIDIsLink(t *testing.T) {
+	link := utils.CanonicalizeArticleURL("https://example.com/post")
+	for _, tc := range []struct {
+		guid string
+		want bool
+	}{
+		{"", true},
+		{"https://example.com/post", true},
+		{"http://example.com/post?utm_source=rss", true},
+		{"tag:example.com,2026:post-1", false},
+		{"https://example.com/post#update-2", true},
+		{"12345", false},
+	} {
+		if got := guidIsLink(&gofeed.Item{GUID: tc.guid, Link: "https://example.com/post"}, link); got != tc.want {
+			t.Errorf("guidIsLink(%q) = %v, want %v", tc.guid, got, tc.want)
+		}
+	}
+}
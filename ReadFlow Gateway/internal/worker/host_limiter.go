@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+)
+
+// hostLimiter 按主机限制同时进行的订阅源请求数
+// 多个源在同一主机（如自建 RSSHub、同一博客平台）时，手动刷新和重试的并发会集中打到一个主机上触发限流；
+// 与手动刷新并发数分开控制，不同主机的请求互不影响。每次获取许可时重新读取上限，运行时修改后对新请求生效
+type hostLimiter struct {
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+	limit func() int
+}
+
+// hostSlots 单个主机的请求状态，没有进行中和等待中的请求时从 map 中移除
+type hostSlots struct {
+	active   int
+	waiting  int
+	released chan struct{} // 有请求释放许可时关闭并替换，唤醒等待者重新判断
+}
+
+// newHostLimiter 创建按主机的并发限制器，limit 返回当前每个主机允许的最大并发数（小于 1 表示不限制）
+func newHostLimiter(limit func() int) *hostLimiter {
+	return &hostLimiter{hosts: make(map[string]*hostSlots), limit: limit}
+}
+
+// acquire 获取主机的请求许可，达到上限时排队等待直到有请求完成或 ctx 结束
+// 返回的 release 必须在请求（含读取响应体）结束后调用；nil 限制器不限制
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	slots := l.hosts[host]
+	if slots == nil {
+		slots = &hostSlots{released: make(chan struct{})}
+		l.hosts[host] = slots
+	}
+	queued := false
+	for {
+		limit := l.limit()
+		if limit < 1 || slots.active < limit {
+			break
+		}
+		if !queued {
+			queued = true
+			log.Printf("[Worker] Feed request to %s queued behind per-host limit (%d in flight, limit %d)", host, slots.active, limit)
+		}
+		released := slots.released
+		slots.waiting++
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			l.mu.Lock()
+			slots.waiting--
+			l.removeIdle(host, slots)
+			l.mu.Unlock()
+			return nil, ctx.Err()
+		}
+
+		l.mu.Lock()
+		slots.waiting--
+	}
+	slots.active++
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() { once.Do(func() { l.release(host, slots) }) }, nil
+}
+
+// release 释放许可并唤醒该主机的等待者
+func (l *hostLimiter) release(host string, slots *hostSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots.active--
+	close(slots.released)
+	slots.released = make(chan struct{})
+	l.removeIdle(host, slots)
+}
+
+// removeIdle 主机没有进行中和等待中的请求时移除其状态，调用方需持有锁
+func (l *hostLimiter) removeIdle(host string, slots *hostSlots) {
+	if slots.active == 0 && slots.waiting == 0 {
+		delete(l.hosts, host)
+	}
+}
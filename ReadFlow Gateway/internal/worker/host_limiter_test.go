@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHostLimiter(t *testing.T) {
+	l := newHostLimiter(func() int { return 1 })
+	ctx := context.Background()
+
+	release, err := l.acquire(ctx, "RSSHub.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不同主机互不影响
+	other, err := l.acquire(ctx, "blog.example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other()
+
+	// 同一主机（不区分大小写）达到上限时排队，ctx 结束后放弃
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(timeout, "rsshub.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want DeadlineExceeded", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		next, err := l.acquire(ctx, "rsshub.example.com")
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- next
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired while the host was at its limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	release() // 重复释放无效
+	select {
+	case next := <-acquired:
+		next()
+	case <-time.After(time.Second):
+		t.Fatal("queued request not woken after release")
+	}
+
+	if len(l.hosts) != 0 {
+		t.Errorf("idle hosts not removed: %d left", len(l.hosts))
+	}
+}
+
+func TestHostLimiterUnlimited(t *testing.T) {
+	l := newHostLimiter(func() int { return 0 })
+	for i := 0; i < 5; i++ {
+		if _, err := l.acquire(context.Background(), "rsshub.example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var nilLimiter *hostLimiter
+	release, err := nilLimiter.acquire(context.Background(), "rsshub.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+func TestHostPolicyRedirects(t *testing.T) {
+	deniedHits := 0
+	denied := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deniedHits++
+		w.Write([]byte(`<rss version="2.0"><channel><title>T</title></channel></rss>`))
+	}))
+	defer denied.Close()
+	// 允许列表只有 localhost，重定向目标使用 127.0.0.1 访问
+	origin := httptest.NewServer(http.RedirectHandler(denied.URL+"/feed", http.StatusFound))
+	defer origin.Close()
+	originURL := strings.Replace(origin.URL, "127.0.0.1", "localhost", 1)
+
+	hosts := utils.NewHostPolicy([]string{"localhost"}, nil)
+	w := &Worker{parser: gofeed.NewParser(), feedHosts: hosts}
+	client := utils.NewHTTPClient(utils.HTTPClientOptions{CheckRedirect: hosts.CheckRedirect})
+
+	if _, _, err := w.downloadFeed(context.Background(), client, originURL, nil, nil); !errors.Is(err, utils.ErrHostNotAllowed) {
+		t.Errorf("downloadFeed err = %v, want ErrHostNotAllowed", err)
+	}
+	if _, _, err := w.downloadFeed(context.Background(), client, denied.URL, nil, nil); !errors.Is(err, utils.ErrHostNotAllowed) {
+		t.Errorf("downloadFeed denied host err = %v, want ErrHostNotAllowed", err)
+	}
+
+	// 全文提取和阅读模式同样受限
+	extractor := NewContentExtractor(nil, hosts)
+	if _, err := extractor.fetchWithRetry(context.Background(), originURL, 2, nil); !errors.Is(err, utils.ErrHostNotAllowed) {
+		t.Errorf("full content fetch err = %v, want ErrHostNotAllowed", err)
+	}
+	if _, err := extractor.fetchOGImage(denied.URL); !errors.Is(err, utils.ErrHostNotAllowed) {
+		t.Errorf("og:image fetch err = %v, want ErrHostNotAllowed", err)
+	}
+
+	if deniedHits != 0 {
+		t.Errorf("denied host received %d requests", deniedHits)
+	}
+}
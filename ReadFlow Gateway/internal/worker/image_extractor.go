@@ -2,11 +2,16 @@ package worker
 
 import (
 	"log"
+	"net/http"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/utils"
 	"golang.org/x/net/html"
 )
 
@@ -23,24 +28,30 @@ type ImageCandidate struct {
 }
 
 // ImageExtractor 智能图片提取器
+// 封面最小尺寸和尺寸探测开关从运行时配置读取
 type ImageExtractor struct {
-	// 最小尺寸要求
-	MinWidth  int
-	MinHeight int
+	httpClient *http.Client // 探测图片尺寸
 }
 
-// NewImageExtractor 创建图片提取器
-func NewImageExtractor() *ImageExtractor {
+// NewImageExtractor 创建图片提取器，transport 为 nil 时使用独立的直连连接池
+func NewImageExtractor(transport *http.Transport) *ImageExtractor {
 	return &ImageExtractor{
-		MinWidth:  300,
-		MinHeight: 200,
+		httpClient: utils.NewHTTPClient(utils.HTTPClientOptions{Transport: transport}), // 时限见 probeImageSize
 	}
 }
 
 // ExtractBestImage 从RSS item和内容中提取最佳图片
 // 这是主入口函数，复刻客户端的智能提取逻辑
 func (e *ImageExtractor) ExtractBestImage(feedItem *gofeed.Item, contentHTML string) *ImageCandidate {
+	return e.selectBestImage(feedItem, contentHTML, config.GetRuntimeConfig().GetCoverProbeEnabled())
+}
+
+// selectBestImage 从候选图中按评分选择封面，probe 为 false 时不探测图片尺寸（不访问网络）
+// feedItem 为 nil 时只从 HTML 内容中选择
+func (e *ImageExtractor) selectBestImage(feedItem *gofeed.Item, contentHTML string, probe bool) *ImageCandidate {
 	candidates := []ImageCandidate{}
+	rc := config.GetRuntimeConfig()
+	minWidth, minHeight := rc.GetCoverMinWidth(), rc.GetCoverMinHeight()
 
 	// 1. 增强的RSS item (media:content, media:thumbnail)
 	enhanced := EnhanceItem(feedItem)
@@ -55,28 +66,45 @@ func (e *ImageExtractor) ExtractBestImage(feedItem *gofeed.Item, contentHTML str
 		candidates = append(candidates, e.extractFromHTML(contentHTML)...)
 	}
 
-	// 3. 过滤占位图
+	// 3. 过滤占位图和声明尺寸过小的图（如追踪像素）
 	filtered := []ImageCandidate{}
 	for _, candidate := range candidates {
-		if !e.isPlaceholderImage(candidate.URL, candidate.Alt) {
-			filtered = append(filtered, candidate)
+		if e.isPlaceholderImage(candidate.URL, candidate.Alt) {
+			continue
 		}
+		if isTooSmall(candidate.Width, candidate.Height, minWidth, minHeight) {
+			log.Printf("[ImageExtractor] Skipped small image (%dx%d): %s", candidate.Width, candidate.Height, candidate.URL)
+			continue
+		}
+		filtered = append(filtered, candidate)
 	}
 
 	// 4. 评分排序
 	for i := range filtered {
-		filtered[i].Score = e.scoreImage(&filtered[i])
+		filtered[i].Score = e.scoreImage(&filtered[i], minWidth, minHeight)
 	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Score > filtered[j].Score
+	})
 
-	// 5. 选择最高分
-	var best *ImageCandidate
+	// 5. 按分数选择；未声明尺寸的正文图片先探测真实尺寸
+	probes := 0
 	for i := range filtered {
-		if best == nil || filtered[i].Score > best.Score {
-			best = &filtered[i]
+		best := &filtered[i]
+		if best.Source == "content_html" && best.Width == 0 && best.Height == 0 &&
+			probe && probes < maxCoverProbes {
+			probes++
+			width, height, err := e.probeImageSize(e.processImageURL(best.URL))
+			if err == nil {
+				if isTooSmall(width, height, minWidth, minHeight) {
+					log.Printf("[ImageExtractor] Skipped small image (probed %dx%d): %s", width, height, best.URL)
+					continue
+				}
+				best.Width, best.Height = width, height
+			}
+			// 探测失败（网络错误或格式不支持）时保留候选图
 		}
-	}
 
-	if best != nil {
 		best.URL = e.processImageURL(best.URL)
 		log.Printf("[ImageExtractor] Selected best image: %s (source: %s, score: %d)", best.URL, best.Source, best.Score)
 		return best
@@ -85,6 +113,11 @@ func (e *ImageExtractor) ExtractBestImage(feedItem *gofeed.Item, contentHTML str
 	return nil
 }
 
+// isTooSmall 判断已知尺寸是否小于最小要求（未知的维度不参与判断）
+func isTooSmall(width, height, minWidth, minHeight int) bool {
+	return (width > 0 && width < minWidth) || (height > 0 && height < minHeight)
+}
+
 // extractFromMediaContent 从 media:content 提取
 func (e *ImageExtractor) extractFromMediaContent(enhanced *EnhancedItem) []ImageCandidate {
 	var candidates []ImageCandidate
@@ -118,10 +151,10 @@ func (e *ImageExtractor) extractFromMediaContent(enhanced *EnhancedItem) []Image
 		}
 	}
 
-	// 如果没有 medium="image"，使用所有有URL的
+	// 如果没有 medium="image"，使用所有可能是图片的（排除音频、Flash 等）
 	if len(candidates) == 0 {
 		for _, media := range enhanced.MediaContent {
-			if media.URL != "" {
+			if media.URL != "" && media.IsImageCandidate() {
 				candidate := ImageCandidate{
 					URL:    media.URL,
 					Source: "media:content",
@@ -265,8 +298,6 @@ func (e *ImageExtractor) isPlaceholderImage(url string, alt string) bool {
 		"gray-placeholder",
 		"dummy",
 		"blank",
-		"default.png",
-		"default.jpg",
 		"spacer",
 		"1x1",
 		"pixel",
@@ -281,6 +312,11 @@ func (e *ImageExtractor) isPlaceholderImage(url string, alt string) bool {
 		}
 	}
 
+	if isDefaultImageName(urlLower) {
+		log.Printf("[ImageExtractor] Detected placeholder image (default file name): %s", url)
+		return true
+	}
+
 	// alt 属性特征
 	if altLower == "loading" || altLower == "image unavailable" || altLower == "placeholder" {
 		log.Printf("[ImageExtractor] Detected placeholder image (alt: %s)", alt)
@@ -290,6 +326,16 @@ func (e *ImageExtractor) isPlaceholderImage(url string, alt string) bool {
 	return false
 }
 
+// isDefaultImageName 文件名恰好是 default.jpg / default.png 时视为占位图
+// 只比较完整文件名：YouTube 的 hqdefault.jpg、maxresdefault.jpg 等是正常缩略图
+func isDefaultImageName(urlLower string) bool {
+	if i := strings.IndexAny(urlLower, "?#"); i >= 0 {
+		urlLower = urlLower[:i]
+	}
+	name := path.Base(urlLower)
+	return name == "default.jpg" || name == "default.png"
+}
+
 // processImageURL 处理特殊格式的图片URL
 // 复刻客户端逻辑：处理Engadget等特殊格式
 func (e *ImageExtractor) processImageURL(url string) string {
@@ -373,7 +419,7 @@ func (e *ImageExtractor) isAntiHotlinkDomain(url string) bool {
 
 // scoreImage 为图片评分
 // 评分标准：尺寸、来源、位置等
-func (e *ImageExtractor) scoreImage(candidate *ImageCandidate) int {
+func (e *ImageExtractor) scoreImage(candidate *ImageCandidate, minWidth, minHeight int) int {
 	score := 0
 
 	// 1. 来源评分（优先级）
@@ -397,7 +443,7 @@ func (e *ImageExtractor) scoreImage(candidate *ImageCandidate) int {
 		score += 20
 
 		// 符合最小尺寸要求加分
-		if candidate.Width >= e.MinWidth && candidate.Height >= e.MinHeight {
+		if candidate.Width >= minWidth && candidate.Height >= minHeight {
 			score += 30
 		}
 
@@ -414,6 +460,13 @@ func (e *ImageExtractor) scoreImage(candidate *ImageCandidate) int {
 		if ratio >= 0.5 && ratio <= 2.5 {
 			score += 10
 		}
+
+		// 分辨率加分（每 10 万像素 1 分，最多 20 分），media:group 多个尺寸时选最清晰的
+		pixels := candidate.Width * candidate.Height
+		if pixels > 2000000 {
+			pixels = 2000000
+		}
+		score += pixels / 100000
 	}
 
 	// 3. URL质量评分
@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	goimage "image"
+	_ "image/gif"  // 注册 GIF 解码器（探测尺寸）
+	_ "image/jpeg" // 注册 JPEG 解码器（探测尺寸）
+	_ "image/png"  // 注册 PNG 解码器（探测尺寸）
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/readflow/gateway/internal/image"
+)
+
+// 封面尺寸探测常量
+const (
+	// 只下载文件头部，足以解析常见格式的尺寸
+	probeMaxBytes = 64 * 1024
+	probeTimeout  = 5 * time.Second
+	// 每篇文章最多探测的候选图数量
+	maxCoverProbes = 3
+)
+
+// probeImageSize 部分下载图片并解析尺寸
+// 不支持的格式（如 WebP / AVIF）返回错误，由调用方决定是否保留候选图
+func (e *ImageExtractor) probeImageSize(imageURL string) (int, int, error) {
+	if strings.HasPrefix(imageURL, "//") {
+		imageURL = "https:" + imageURL
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("Accept", "image/*,*/*")
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", probeMaxBytes-1))
+	if referer := image.RefererFor(imageURL); referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return 0, 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	cfg, _, err := goimage.DecodeConfig(io.LimitReader(resp.Body, probeMaxBytes))
+	if err != nil {
+		return 0, 0, err
+	}
+	return cfg.Width, cfg.Height, nil
+}
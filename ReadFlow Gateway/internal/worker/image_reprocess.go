@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/readflow/gateway/internal/image"
+)
+
+// 重新压缩缓存图片时每批处理的文章数（需要重新下载原图，比重建派生字段小）
+const (
+	DefaultImageReprocessBatch = 20
+	MaxImageReprocessBatch     = 100
+)
+
+// ImageReprocessResult 一批缓存图片重新压缩的结果
+type ImageReprocessResult struct {
+	image.ImageReprocessCounts
+	Processed  int   // 本批读取的文章数
+	NextCursor int64 // 本批最后一篇文章的 ID，作为下一批的游标
+	Remaining  int64 // 游标之后尚未处理的有缓存图片的文章数
+}
+
+// ReprocessImages 按当前 image_quality、image_max_width 重新压缩源中 afterID 之后最多 limit 篇文章的缓存图片
+// 已是当前参数的图片跳过；多篇文章共用的图片在一批内只处理一次。修改压缩配置后按 NextCursor 分批调用
+func (w *Worker) ReprocessImages(sourceID, afterID int64, limit int) (*ImageReprocessResult, error) {
+	items, err := w.db.ListItemImagesForReprocess(sourceID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list items: %w", err)
+	}
+
+	result := &ImageReprocessResult{NextCursor: afterID}
+	seen := make(map[string]bool)
+	for _, item := range items {
+		var paths []string
+		if err := json.Unmarshal([]byte(item.ImagePaths), &paths); err != nil {
+			log.Printf("[Reprocess] Invalid image_paths of item %d: %v", item.ID, err)
+		}
+		pending := paths[:0]
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				pending = append(pending, path)
+			}
+		}
+
+		if len(pending) > 0 {
+			// 没有附属记录的图片从原文中的图片和封面地址找回原图
+			candidates := []string{item.CoverImage}
+			for _, candidate := range w.imageExtractor.extractFromHTML(item.Content) {
+				candidates = append(candidates, candidate.URL)
+			}
+			result.Add(w.imageProcessor.ReprocessImages(pending, candidates))
+		}
+		result.Processed++
+		result.NextCursor = item.ID
+	}
+
+	if result.Remaining, err = w.db.CountItemImagesAfter(sourceID, result.NextCursor); err != nil {
+		return nil, fmt.Errorf("count items: %w", err)
+	}
+	log.Printf("[Reprocess] Images of source %d (cursor %d -> %d): %d items, %d regenerated, %d up to date, %d unavailable, %d failed, %d items remaining",
+		sourceID, afterID, result.NextCursor, result.Processed,
+		result.Regenerated, result.UpToDate, result.Unavailable, result.Failed, result.Remaining)
+	return result, nil
+}
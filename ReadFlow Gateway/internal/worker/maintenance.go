@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"log"
+	"time"
+
+	"github.com/readflow/gateway/internal/config"
+)
+
+// maintainDBIfDue 距上次维护超过配置的间隔时执行数据库维护（随清理任务检查）
+// 数据库只有一个连接，VACUUM 期间其他查询都要排队，因此抓取、用户刷新或批量重试进行中时顺延到下一轮
+func (w *Worker) maintainDBIfDue() {
+	interval := time.Duration(config.GetRuntimeConfig().GetDBMaintenanceInterval()) * time.Second
+	if !w.lastMaintenance.IsZero() && time.Since(w.lastMaintenance) < interval {
+		return
+	}
+
+	// 持有抓取锁，维护期间定时抓取会跳过本轮
+	if !w.fetching.TryLock() {
+		log.Println("[Maintenance] Fetch in progress, postponing database maintenance")
+		return
+	}
+	defer w.fetching.Unlock()
+	if !w.retrying.TryLock() {
+		log.Println("[Maintenance] Source retry in progress, postponing database maintenance")
+		return
+	}
+	defer w.retrying.Unlock()
+	if w.userRefreshInProgress() {
+		log.Println("[Maintenance] User refresh in progress, postponing database maintenance")
+		return
+	}
+
+	w.lastMaintenance = time.Now()
+	result, err := w.db.Maintain()
+	if err != nil {
+		log.Printf("[Maintenance] Database maintenance failed: %v", err)
+		return
+	}
+
+	log.Printf("[Maintenance] Database maintenance finished in %v: WAL truncated %.1f MB (%d frames, busy=%v), vacuum=%v, size %.1f MB -> %.1f MB, reclaimed %.1f MB",
+		result.Duration.Round(time.Millisecond),
+		megabytes(result.WALBytes), result.WALFrames, result.CheckpointBusy,
+		result.Vacuumed, megabytes(result.SizeBefore), megabytes(result.SizeAfter),
+		megabytes(result.Reclaimed()))
+}
+
+// userRefreshInProgress 是否有用户刷新正在进行
+func (w *Worker) userRefreshInProgress() bool {
+	busy := false
+	w.refreshing.Range(func(_, _ interface{}) bool {
+		busy = true
+		return false
+	})
+	return busy
+}
+
+// megabytes 字节数转 MB，用于日志
+func megabytes(n int64) float64 {
+	return float64(n) / (1 << 20)
+}
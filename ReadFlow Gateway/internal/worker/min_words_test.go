@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestProcessItemMinWordCount(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := database.CreateSource("https://example.com/feed", "Example", "", 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.ImageMode = db.ImageModeOff
+	source.MinWordCount = 50
+
+	w := &Worker{db: database, imageExtractor: NewImageExtractor(nil)}
+	short := &gofeed.Item{GUID: "short", Title: "Link roundup", Content: "<p>See the links below.</p>"}
+	long := &gofeed.Item{GUID: "long", Title: "Harbour mornings",
+		Content: "<p>" + strings.Repeat("Ships leave the harbour before sunrise every morning. ", 20) + "</p>"}
+
+	// 字数不足：入库（用于去重）但不投递
+	if err := w.processItem(source, short, itemKey{guid: short.GUID}, []int64{user.ID}); !errors.Is(err, errBelowMinWords) {
+		t.Fatalf("short item err = %v, want errBelowMinWords", err)
+	}
+	if item, err := database.GetItemByGUID(source.ID, "short"); err != nil || item == nil {
+		t.Fatalf("short item not stored: %v", err)
+	}
+
+	if err := w.processItem(source, long, itemKey{guid: long.GUID}, []int64{user.ID}); err != nil {
+		t.Fatalf("long item: %v", err)
+	}
+
+	pending, err := database.GetPendingDeliveries(user.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].GUID != "long" {
+		t.Fatalf("pending = %d items, want only the long item", len(pending))
+	}
+
+	// 再次抓取到同一篇短文时按 GUID 去重，仍不投递
+	if err := w.processItem(source, short, itemKey{guid: short.GUID}, []int64{user.ID}); err != nil {
+		t.Errorf("repeated short item err = %v, want nil", err)
+	}
+}
@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// og:image 回退常量
+const (
+	ogImageTimeout = 8 * time.Second
+	// og / twitter 标签都在 <head> 中，只读取页面开头
+	ogImageMaxBytes = 512 * 1024
+	// 结果缓存（含未找到的结果），避免文章被清理后重新入库时重复抓取
+	ogImageCacheTTL  = 24 * time.Hour
+	ogImageCacheSize = 5000
+)
+
+// ogImageEntry og:image 缓存项（url 为空表示该页面没有可用图片）
+type ogImageEntry struct {
+	url     string
+	expires time.Time
+}
+
+// ogImageCache 按文章链接缓存 og:image 查找结果
+type ogImageCache struct {
+	mu      sync.Mutex
+	entries map[string]ogImageEntry
+}
+
+// get 读取未过期的缓存
+func (c *ogImageCache) get(link string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[link]
+	if !ok || time.Now().After(entry.expires) {
+		return "", false
+	}
+	return entry.url, true
+}
+
+// set 写入缓存，超出容量时先清理过期项，仍然超出则整体重置
+func (c *ogImageCache) set(link, imageURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]ogImageEntry)
+	}
+	if len(c.entries) >= ogImageCacheSize {
+		now := time.Now()
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= ogImageCacheSize {
+			c.entries = make(map[string]ogImageEntry)
+		}
+	}
+	c.entries[link] = ogImageEntry{url: imageURL, expires: time.Now().Add(ogImageCacheTTL)}
+}
+
+// ExtractOGImage 抓取文章页面，返回 og:image / twitter:image 声明的图片地址
+// 找不到时返回空字符串（结果会被缓存）
+func (e *ContentExtractor) ExtractOGImage(link string) (string, error) {
+	if link == "" {
+		return "", nil
+	}
+	if imageURL, ok := e.ogImages.get(link); ok {
+		return imageURL, nil
+	}
+
+	imageURL, err := e.fetchOGImage(link)
+	if err != nil {
+		// 网络错误同样缓存为未找到，避免对失效页面反复请求
+		e.ogImages.set(link, "")
+		return "", err
+	}
+	e.ogImages.set(link, imageURL)
+	return imageURL, nil
+}
+
+// fetchOGImage 请求文章页面并解析 <head> 中的图片 meta 标签
+func (e *ContentExtractor) fetchOGImage(link string) (string, error) {
+	if err := e.hosts.Check(link); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ogImageTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", e.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	imageURL := findMetaImage(io.LimitReader(resp.Body, ogImageMaxBytes))
+	if imageURL == "" {
+		return "", nil
+	}
+
+	// 相对地址按最终页面地址（可能经过重定向）解析
+	base := resp.Request.URL
+	ref, err := url.Parse(imageURL)
+	if err != nil {
+		return "", nil
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", nil
+	}
+	return resolved.String(), nil
+}
+
+// findMetaImage 扫描 <head>，og:image 优先于 twitter:image
+func findMetaImage(r io.Reader) string {
+	var twitterImage string
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return twitterImage
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.DataAtom == atom.Body {
+				return twitterImage
+			}
+			if token.DataAtom != atom.Meta {
+				continue
+			}
+
+			var name, content string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "property", "name":
+					name = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "content":
+					content = strings.TrimSpace(attr.Val)
+				}
+			}
+			if content == "" {
+				continue
+			}
+			switch name {
+			case "og:image", "og:image:url", "og:image:secure_url":
+				return content
+			case "twitter:image", "twitter:image:src":
+				if twitterImage == "" {
+					twitterImage = content
+				}
+			}
+		case html.EndTagToken:
+			if tokenizer.Token().DataAtom == atom.Head {
+				return twitterImage
+			}
+		}
+	}
+}
+
+// coverFromArticlePage 没有任何其他封面时，回退到文章页面的 og:image
+func (w *Worker) coverFromArticlePage(link string) string {
+	imageURL, err := w.contentExtractor.ExtractOGImage(link)
+	if err != nil {
+		log.Printf("[Worker] og:image lookup failed for %s: %v", link, err)
+		return ""
+	}
+	if imageURL != "" {
+		log.Printf("[Worker] Using og:image as cover: %s", imageURL)
+	}
+	return imageURL
+}
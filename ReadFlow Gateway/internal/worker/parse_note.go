@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// inspectFeedItems 检查 feed 中文章的数据质量，返回可以入库的文章和给用户看的提示（没有问题时为空）
+// 既没有标题也没有内容的文章属于解析残缺的空壳，直接跳过；缺少日期、链接的文章照常处理，只计入提示。
+// 提示只用于说明源为什么看起来文章偏少或排序异常，不影响抓取结果
+func inspectFeedItems(items []*gofeed.Item, loc *time.Location, now time.Time) ([]*gofeed.Item, string) {
+	if len(items) == 0 {
+		return items, "feed contains no items"
+	}
+
+	kept := make([]*gofeed.Item, 0, len(items))
+	var broken, noDate, noLink int
+	for _, item := range items {
+		if item == nil || isEmptyFeedItem(item) {
+			broken++
+			continue
+		}
+		if _, ok := itemPublishedAt(item, loc, now); !ok {
+			noDate++
+		}
+		if strings.TrimSpace(item.Link) == "" {
+			noLink++
+		}
+		kept = append(kept, item)
+	}
+
+	var notes []string
+	total := len(items)
+	if broken > 0 {
+		notes = append(notes, fmt.Sprintf("%d of %d items had no title or content and were skipped", broken, total))
+	}
+	if noDate > 0 {
+		notes = append(notes, fmt.Sprintf("%d of %d items had no valid date (fetch time used)", noDate, total))
+	}
+	if noLink > 0 {
+		notes = append(notes, fmt.Sprintf("%d of %d items had no link", noLink, total))
+	}
+	return kept, strings.Join(notes, "; ")
+}
+
+// isEmptyFeedItem 文章既没有标题也没有内容（content、description 均为空白）
+// 只有图片的内容不算空，图片类的源可能没有标题和文字
+func isEmptyFeedItem(item *gofeed.Item) bool {
+	return strings.TrimSpace(item.Title) == "" &&
+		strings.TrimSpace(item.Content) == "" &&
+		strings.TrimSpace(item.Description) == ""
+}
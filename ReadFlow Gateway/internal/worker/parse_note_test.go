@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestInspectFeedItems(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	published := time.Date(2026, 10, 12, 16, 0, 0, 0, time.UTC)
+	items := []*gofeed.Item{
+		{Title: "Dated", Link: "https://example.com/a", PublishedParsed: &published},
+		{Title: "Undated", Link: "https://example.com/b"},
+		{Content: `<img src="https://example.com/photo.jpg">`, Link: "https://example.com/c", PublishedParsed: &published},
+		{Title: "  ", Description: " \n", Link: "https://example.com/d"},
+		nil,
+	}
+
+	kept, note := inspectFeedItems(items, time.UTC, now)
+	if len(kept) != 3 {
+		t.Errorf("kept %d items, want 3 (image-only item kept, empty shells skipped)", len(kept))
+	}
+	want := "2 of 5 items had no title or content and were skipped; 1 of 5 items had no valid date (fetch time used)"
+	if note != want {
+		t.Errorf("note = %q, want %q", note, want)
+	}
+
+	if _, note := inspectFeedItems(items[:1], time.UTC, now); note != "" {
+		t.Errorf("clean feed note = %q, want empty", note)
+	}
+	if _, note := inspectFeedItems(nil, time.UTC, now); note != "feed contains no items" {
+		t.Errorf("empty feed note = %q", note)
+	}
+}
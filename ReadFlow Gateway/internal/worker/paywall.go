@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// paywallStubChars 提取的正文纯文本少于该字符数时才按付费墙特征判断，长正文里偶尔出现的订阅提示不算
+const paywallStubChars = 2000
+
+// errPaywalled 提取结果是付费墙或登录墙的预览，调用方应沿用 feed 内容
+var errPaywalled = errors.New("paywall detected")
+
+// defaultPaywallMarkers 付费墙、登录墙页面常见的提示语（小写，按子串匹配），PAYWALL_MARKERS 可替换
+var defaultPaywallMarkers = []string{
+	"subscribe to continue",
+	"subscribe to read",
+	"subscribers only",
+	"subscriber-only",
+	"this article is for subscribers",
+	"already a subscriber",
+	"sign in to continue",
+	"log in to continue",
+	"login to continue",
+	"create a free account to continue",
+	"become a member to read",
+	"订阅后继续阅读",
+	"订阅后阅读全文",
+	"登录后继续阅读",
+	"登录后阅读全文",
+	"会员专享",
+	"付费阅读",
+}
+
+var (
+	// 结构化数据声明文章不免费（schema.org isAccessibleForFree）
+	paywallFreeFlag = regexp.MustCompile(`(?i)"isAccessibleForFree"\s*:\s*"?false`)
+	// <meta property="article:content_tier" content="locked">，属性顺序不固定
+	paywallContentTier = regexp.MustCompile(`(?i)<meta[^>]+(content_tier["'][^>]*content=["']locked|content=["']locked["'][^>]*content_tier)`)
+	// 正文中的密码输入框（登录表单）
+	paywallPasswordInput = regexp.MustCompile(`(?i)<input[^>]+type=["']?password`)
+)
+
+// detectPaywall 判断 Readability 的提取结果是否只是付费墙预览或登录表单，是时返回判断依据（用于日志），否则返回空字符串
+// page 为页面原始 HTML，content 为清理前的提取结果；只有提取的正文较短时才判断，
+// 依据为：页面声明文章不免费、正文中有登录表单，或正文包含 markers 中的提示语（markers 为空时使用默认列表）
+func detectPaywall(page, content string, markers []string) string {
+	text := strings.TrimSpace(CleanHTMLTags(content))
+	if utf8.RuneCountInString(text) >= paywallStubChars {
+		return ""
+	}
+
+	switch {
+	case paywallFreeFlag.MatchString(page):
+		return "isAccessibleForFree=false"
+	case paywallContentTier.MatchString(page):
+		return "content_tier=locked"
+	case paywallPasswordInput.MatchString(content):
+		return "login form"
+	}
+
+	if len(markers) == 0 {
+		markers = defaultPaywallMarkers
+	}
+	lower := strings.ToLower(text)
+	for _, marker := range markers {
+		if strings.Contains(lower, marker) {
+			return fmt.Sprintf("marker %q", marker)
+		}
+	}
+	return ""
+}
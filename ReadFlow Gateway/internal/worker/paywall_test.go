@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectPaywall(t *testing.T) {
+	teaser := "<p>" + strings.Repeat("Opening paragraph of the story. ", 5) + "</p>"
+	long := "<p>" + strings.Repeat("A long paragraph of the full article. ", 80) + "</p>"
+	cases := []struct {
+		name, page, content string
+		markers             []string
+		paywalled           bool
+	}{
+		{"plain article", "<html></html>", long, nil, false},
+		{"default marker", "", teaser + "<p>Subscribe to continue reading.</p>", nil, true},
+		{"chinese marker", "", teaser + "<p>登录后继续阅读全文</p>", nil, true},
+		{"marker in long article", "", long + "<p>Subscribe to continue reading.</p>", nil, false},
+		{"structured data", `<script type="application/ld+json">{"isAccessibleForFree": "False"}</script>`, teaser, nil, true},
+		{"content tier", `<meta content="locked" property="article:content_tier">`, teaser, nil, true},
+		{"metered tier", `<meta property="article:content_tier" content="metered">`, teaser, nil, false},
+		{"login form", "", teaser + `<form><input type="password" name="pw"></form>`, nil, true},
+		{"custom markers replace defaults", "", teaser + "<p>Subscribe to continue reading.</p>", []string{"members only"}, false},
+		{"custom marker", "", teaser + "<p>Members Only content.</p>", []string{"members only"}, true},
+	}
+	for _, tc := range cases {
+		if got := detectPaywall(tc.page, tc.content, tc.markers); (got != "") != tc.paywalled {
+			t.Errorf("%s: detectPaywall = %q, want paywalled=%v", tc.name, got, tc.paywalled)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 订阅源预览常量
+const (
+	previewTimeout  = 15 * time.Second
+	previewMaxItems = 5
+)
+
+// FeedPreview 订阅前预览的源信息
+type FeedPreview struct {
+	URL         string            `json:"url"` // 实际解析的订阅源地址（经自动发现时与输入地址不同）
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	SiteURL     string            `json:"site_url"`
+	Favicon     string            `json:"favicon"`
+	Language    string            `json:"language"`
+	ItemCount   int               `json:"item_count"`
+	Items       []FeedPreviewItem `json:"items"`
+}
+
+// FeedPreviewItem 预览中的文章
+type FeedPreviewItem struct {
+	Title       string     `json:"title"`
+	Link        string     `json:"link"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+// PreviewFeed 抓取并解析订阅源，不写入数据库
+// 地址是普通网页时，按页面 <link rel="alternate"> 声明的订阅源重试一次
+func (w *Worker) PreviewFeed(feedURL string) (*FeedPreview, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+	defer cancel()
+
+	body, _, err := w.downloadFeed(ctx, w.previewClient, feedURL, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	feed, parseErr := w.parser.Parse(bytes.NewReader(body))
+	if parseErr != nil {
+		discovered := discoverFeedURL(body, feedURL)
+		if discovered == "" {
+			return nil, fmt.Errorf("%w: %v", ErrNotAFeed, parseErr)
+		}
+		if body, _, err = w.downloadFeed(ctx, w.previewClient, discovered, nil, nil); err != nil {
+			return nil, err
+		}
+		if feed, parseErr = w.parser.Parse(bytes.NewReader(body)); parseErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrNotAFeed, parseErr)
+		}
+		feedURL = discovered
+	}
+
+	return buildFeedPreview(feedURL, feed), nil
+}
+
+// buildFeedPreview 提取源元数据和最近的几篇文章
+func buildFeedPreview(feedURL string, feed *gofeed.Feed) *FeedPreview {
+	preview := &FeedPreview{
+		URL:         feedURL,
+		Title:       strings.TrimSpace(feed.Title),
+		Description: strings.TrimSpace(feed.Description),
+		SiteURL:     feed.Link,
+		Language:    feed.Language,
+		ItemCount:   len(feed.Items),
+		Items:       []FeedPreviewItem{},
+	}
+	if feed.Image != nil && feed.Image.URL != "" {
+		preview.Favicon = feed.Image.URL
+	} else if site, err := url.Parse(feed.Link); err == nil && site.Host != "" {
+		preview.Favicon = site.Scheme + "://" + site.Host + "/favicon.ico"
+	}
+
+	items := make([]FeedPreviewItem, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		if item == nil {
+			continue
+		}
+		publishedAt := item.PublishedParsed
+		if publishedAt == nil {
+			publishedAt = item.UpdatedParsed
+		}
+		items = append(items, FeedPreviewItem{
+			Title:       strings.TrimSpace(item.Title),
+			Link:        item.Link,
+			PublishedAt: publishedAt,
+		})
+	}
+	// 没有发布时间的文章排在后面
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].PublishedAt, items[j].PublishedAt
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.After(*b)
+	})
+	if len(items) > previewMaxItems {
+		items = items[:previewMaxItems]
+	}
+	preview.Items = append(preview.Items, items...)
+	return preview
+}
+
+// feedLinkTypes 页面 <link rel="alternate"> 中表示订阅源的 type
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/json":      true,
+}
+
+// discoverFeedURL 从网页的 <link rel="alternate" type="application/rss+xml"> 中找出订阅源地址
+// 相对地址按页面地址解析；找不到时返回空字符串
+func discoverFeedURL(page []byte, pageURL string) string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := z.Token()
+			if token.DataAtom == atom.Body {
+				return "" // 订阅源声明都在 <head> 中
+			}
+			if token.DataAtom != atom.Link {
+				continue
+			}
+			var rel, typ, href string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "rel":
+					rel = strings.ToLower(attr.Val)
+				case "type":
+					typ = strings.ToLower(strings.TrimSpace(attr.Val))
+				case "href":
+					href = strings.TrimSpace(attr.Val)
+				}
+			}
+			if href == "" || !feedLinkTypes[typ] || !strings.Contains(rel, "alternate") {
+				continue
+			}
+			ref, err := url.Parse(href)
+			if err != nil {
+				continue
+			}
+			resolved := base.ResolveReference(ref)
+			if resolved.Scheme == "http" || resolved.Scheme == "https" {
+				return resolved.String()
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/readflow/gateway/internal/image"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// 阅读模式（临时链接）常量
+const (
+	readTimeout      = 20 * time.Second
+	readMaxPageBytes = 5 << 20
+	readMinChars     = 50 // 提取出的正文纯文本少于该字符数时视为没有正文
+	// 结果短暂缓存，同一链接重复打开或多端同时打开时不重复抓取
+	readCacheTTL  = 10 * time.Minute
+	readCacheSize = 500
+)
+
+// ErrNoReadableContent 页面中没有可提取的正文
+var ErrNoReadableContent = errors.New("no readable content")
+
+// errPageTooLarge 页面超过大小上限
+var errPageTooLarge = errors.New("page too large")
+
+// ReadableArticle 阅读模式提取结果，不写入数据库
+type ReadableArticle struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Content     string `json:"content"`     // 已清理的正文 HTML，图片改写为 /api/image 代理地址
+	CoverImage  string `json:"cover_image"` // 代理地址，没有主图时为空
+	SiteName    string `json:"site_name"`
+	Byline      string `json:"byline"`
+	WordCount   int    `json:"word_count"`
+	ReadingTime int    `json:"reading_time"`
+}
+
+// readCacheEntry 阅读模式缓存项
+type readCacheEntry struct {
+	article *ReadableArticle
+	expires time.Time
+}
+
+// readCache 按链接缓存提取成功的结果（失败不缓存）
+type readCache struct {
+	mu      sync.Mutex
+	entries map[string]readCacheEntry
+}
+
+// get 读取未过期的缓存
+func (c *readCache) get(link string) (*ReadableArticle, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[link]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.article, true
+}
+
+// set 写入缓存，超出容量时先清理过期项，仍然超出则整体重置
+func (c *readCache) set(link string, article *ReadableArticle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]readCacheEntry)
+	}
+	if len(c.entries) >= readCacheSize {
+		now := time.Now()
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= readCacheSize {
+			c.entries = make(map[string]readCacheEntry)
+		}
+	}
+	c.entries[link] = readCacheEntry{article: article, expires: time.Now().Add(readCacheTTL)}
+}
+
+// ReadURL 提取任意网页的正文（阅读模式），不创建订阅源和文章
+// 只连接公网地址，页面超过 5MB 或 20 秒内未完成时返回错误；正文图片改写为代理地址，不下载不缓存
+func (w *Worker) ReadURL(pageURL string) (*ReadableArticle, error) {
+	if article, ok := w.readCache.get(pageURL); ok {
+		return article, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+	defer cancel()
+
+	type result struct {
+		article *extractedArticle
+		err     error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		article, err := w.readExtractor.extractArticle(ctx, pageURL, nil)
+		ch <- result{article, err}
+	}()
+
+	var extracted *extractedArticle
+	select {
+	case res := <-ch:
+		if res.err != nil {
+			return nil, res.err
+		}
+		extracted = res.article
+	case <-ctx.Done():
+		return nil, fmt.Errorf("extract %s: %w", pageURL, ctx.Err())
+	}
+
+	if utf8.RuneCountInString(strings.TrimSpace(CleanHTMLTags(extracted.content))) < readMinChars {
+		return nil, ErrNoReadableContent
+	}
+
+	textProcessor := utils.NewTextProcessor()
+	wordCount := textProcessor.CountWords(extracted.content)
+	article := &ReadableArticle{
+		URL:         pageURL,
+		Title:       extracted.title,
+		Content:     w.imageProcessor.ProxyContent(extracted.content),
+		CoverImage:  w.readCoverImage(pageURL, extracted),
+		SiteName:    extracted.siteName,
+		Byline:      extracted.byline,
+		WordCount:   wordCount,
+		ReadingTime: textProcessor.EstimateReadingTime(wordCount),
+	}
+	if article.Title == "" {
+		if u, err := url.Parse(pageURL); err == nil {
+			article.Title = u.Host
+		}
+	}
+
+	w.readCache.set(pageURL, article)
+	return article, nil
+}
+
+// readCoverImage 选择封面：优先页面声明的主图，其次正文中第一张非占位图，返回代理地址
+func (w *Worker) readCoverImage(pageURL string, extracted *extractedArticle) string {
+	cover := extracted.image
+	if cover == "" {
+		for _, candidate := range w.imageExtractor.extractFromHTML(extracted.content) {
+			if !w.imageExtractor.isPlaceholderImage(candidate.URL, candidate.Alt) {
+				cover = strings.TrimSpace(candidate.URL)
+				break
+			}
+		}
+	}
+	if cover == "" {
+		return ""
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(cover)
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return image.ProxyPath(resolved.String(), w.config.GetImageProxyKey())
+}
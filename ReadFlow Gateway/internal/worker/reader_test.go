@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/image"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+func TestReadURL(t *testing.T) {
+	paragraph := strings.Repeat("Readable sentence with enough words to count. ", 20)
+	var hits atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Ad-hoc Post</title><meta property="og:image" content="/cover.jpg"></head><body>
+			<nav>menu</nav><article><h1>Ad-hoc Post</h1><p>` + paragraph + `</p>
+			<img src="https://cdn.example.com/a.jpg"><p onclick="x()">` + paragraph + `</p></article></body></html>`))
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><p>hi</p></body></html>`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cfg := &config.Config{JWTSecret: "test"}
+	extractor := NewContentExtractor(nil, nil) // 测试服务器在回环地址上，不使用公网限制
+	extractor.maxBodyBytes = readMaxPageBytes
+	w := &Worker{
+		config:         cfg,
+		readExtractor:  extractor,
+		imageProcessor: image.NewProcessor(cfg, nil),
+		imageExtractor: NewImageExtractor(nil),
+	}
+
+	article, err := w.ReadURL(srv.URL + "/article")
+	if err != nil {
+		t.Fatalf("ReadURL: %v", err)
+	}
+	if article.Title != "Ad-hoc Post" {
+		t.Errorf("title = %q", article.Title)
+	}
+	if strings.Contains(article.Content, "onclick") || !strings.Contains(article.Content, "Readable sentence") {
+		t.Errorf("content not sanitized: %s", article.Content)
+	}
+	proxied := strings.ReplaceAll(image.ProxyPath("https://cdn.example.com/a.jpg", cfg.GetImageProxyKey()), "&", "&amp;")
+	if !strings.Contains(article.Content, proxied) {
+		t.Errorf("image not proxied: %s", article.Content)
+	}
+	if want := image.ProxyPath(srv.URL+"/cover.jpg", cfg.GetImageProxyKey()); article.CoverImage != want {
+		t.Errorf("cover = %q, want %q", article.CoverImage, want)
+	}
+
+	// 再次读取命中缓存
+	if _, err := w.ReadURL(srv.URL + "/article"); err != nil {
+		t.Fatalf("cached ReadURL: %v", err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("page fetched %d times, want 1", n)
+	}
+
+	if _, err := w.ReadURL(srv.URL + "/empty"); !errors.Is(err, ErrNoReadableContent) {
+		t.Errorf("empty page error = %v, want ErrNoReadableContent", err)
+	}
+
+	// 阅读模式的提取器拒绝内网地址
+	w.readExtractor = newReaderContentExtractor(nil, nil)
+	if _, err := w.ReadURL(srv.URL + "/other"); !errors.Is(err, utils.ErrPrivateAddress) {
+		t.Errorf("loopback error = %v, want ErrPrivateAddress", err)
+	}
+}
@@ -0,0 +1,121 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+	"github.com/readflow/gateway/internal/utils"
+)
+
+// 重建派生字段时每批处理的文章数
+const (
+	DefaultReprocessBatch = 100
+	MaxReprocessBatch     = 500
+)
+
+// ReprocessResult 一批派生字段重建的结果
+type ReprocessResult struct {
+	Processed  int   // 本批读取的文章数
+	Updated    int   // 派生字段有变化并已写入的文章数
+	NextCursor int64 // 本批最后一篇文章的 ID，作为下一批的游标
+	Remaining  int64 // 游标之后尚未处理的文章数
+}
+
+// ReprocessItems 用已保存的内容重新计算 afterID 之后最多 limit 篇文章的派生字段
+// （摘要、字数、阅读时间、难度、封面和标签），不访问网络；sourceID 为 0 时处理所有源
+// 算法改进后按返回的 NextCursor 分批调用，即可把历史文章更新为新的结果
+func (w *Worker) ReprocessItems(sourceID, afterID int64, limit int) (*ReprocessResult, error) {
+	items, err := w.db.ListItemsForReprocess(sourceID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list items: %w", err)
+	}
+
+	result := &ReprocessResult{NextCursor: afterID}
+	textProcessor := utils.NewTextProcessorWithCorpus(w.corpus)
+	sources := make(map[int64]*db.Source)
+	for _, item := range items {
+		source, ok := sources[item.SourceID]
+		if !ok {
+			if source, err = w.db.GetSourceByID(item.SourceID); err != nil {
+				return nil, fmt.Errorf("load source %d: %w", item.SourceID, err)
+			}
+			sources[item.SourceID] = source
+		}
+
+		derived := w.deriveItemFields(textProcessor, source, item)
+		if *derived != *item {
+			updated, err := w.db.UpdateItemDerived(derived)
+			if err != nil {
+				return nil, fmt.Errorf("update item %d: %w", item.ID, err)
+			}
+			if updated {
+				result.Updated++
+			}
+		}
+		result.Processed++
+		result.NextCursor = item.ID
+	}
+
+	if result.Remaining, err = w.db.CountItemsAfter(sourceID, result.NextCursor); err != nil {
+		return nil, fmt.Errorf("count items: %w", err)
+	}
+	log.Printf("[Reprocess] Processed %d items (source=%d, cursor %d -> %d), %d updated, %d remaining",
+		result.Processed, sourceID, afterID, result.NextCursor, result.Updated, result.Remaining)
+	return result, nil
+}
+
+// deriveItemFields 按 processItem 的规则重新计算派生字段，返回更新后的副本
+// feed 原始分类没有保存，以文章分类（继承自源的除外）代替；图片主色调、BlurHash 和封面缩略图需要下载图片，封面变化时清空
+func (w *Worker) deriveItemFields(textProcessor *utils.TextProcessor, source *db.Source, item *db.Item) *db.Item {
+	derived := *item
+	content := item.CleanContent
+	if content == "" {
+		content = item.Content
+	}
+	description := xmlContentDescription(item.XMLContent)
+
+	derived.WordCount = textProcessor.CountWords(content)
+	derived.ReadingTime = textProcessor.EstimateReadingTime(derived.WordCount)
+	derived.Summary = textProcessor.SelectSummary(description, content, source.SummaryLength)
+	derived.Difficulty = textProcessor.CalculateDifficulty(content)
+
+	feedItem := &gofeed.Item{Title: item.Title, Description: description}
+	if item.Category != "" && item.Category != textProcessor.NormalizeCategory(source.Category) {
+		feedItem.Categories = []string{item.Category}
+	}
+	derived.Tags = buildItemTags(textProcessor, item.SourceID, feedItem, content)
+
+	// 现有封面可能来自 media、enclosure 等未保存的 feed 元数据或文章页面的 og:image，仍然有效时保留；
+	// 没有封面或现有封面按当前规则属于占位图时，从保存的正文中重新选择（不探测尺寸）
+	if item.CoverImage == "" || w.imageExtractor.isPlaceholderImage(item.CoverImage, item.ImageCaption) {
+		derived.CoverImage, derived.ImageCaption, derived.ImageCredit = "", "", ""
+		for _, html := range []string{item.Content, item.CleanContent} {
+			if candidate := w.imageExtractor.selectBestImage(nil, html, false); candidate != nil {
+				derived.CoverImage, derived.ImageCaption, derived.ImageCredit = candidate.URL, candidate.Alt, candidate.Credit
+				break
+			}
+		}
+		if derived.CoverImage != item.CoverImage {
+			derived.ImagePrimaryColor, derived.ImageBlurhash, derived.CoverVariants = "", "", ""
+		}
+	}
+	return &derived
+}
+
+// xmlContentDescription 从 buildXMLContent 生成的 xml_content 中取出 feed 的 description
+func xmlContentDescription(xmlContent string) string {
+	const prefix, suffix = "<description><![CDATA[", "]]></description>"
+	start := strings.Index(xmlContent, prefix)
+	if start < 0 {
+		return ""
+	}
+	rest := xmlContent[start+len(prefix):]
+	end := strings.Index(rest, suffix)
+	if end < 0 {
+		return ""
+	}
+	return rest[:end]
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestReprocessItems(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	source, err := database.CreateSource("https://example.com/feed", "Example", "", 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := database.CreateSource("https://example.org/feed", "Other", "", 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `<p><img src="https://example.com/photo.jpg" alt="Harbour at dawn"></p><p>` +
+		strings.Repeat("Ships leave the harbour before sunrise every single morning. ", 60) + `</p>`
+	description := "A hand-written teaser about the harbour and its early morning departures."
+	xmlContent := "<description><![CDATA[" + description + "]]></description>\n      <content:encoded><![CDATA[" + body + "]]></content:encoded>"
+	published := time.Now()
+	// 模拟旧算法留下的派生字段
+	createItem := func(sourceID int64, guid string) *db.Item {
+		item, err := database.CreateItem(sourceID, guid, "Harbour mornings", xmlContent, "",
+			&published, "stale summary", "medium", 1, 1,
+			"", "", body, body, "hash-"+guid,
+			"", "", "", "", "", false, "", "", "",
+			"", "", "", "",
+			"")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return item
+	}
+	first := createItem(source.ID, "a")
+	createItem(other.ID, "b")
+	second := createItem(source.ID, "c")
+
+	w := &Worker{db: database, imageExtractor: NewImageExtractor(nil)}
+
+	// 每批一篇，按游标继续，只处理指定源
+	result, err := w.ReprocessItems(source.ID, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Processed != 1 || result.Updated != 1 || result.NextCursor != first.ID || result.Remaining != 1 {
+		t.Fatalf("first batch = %+v", result)
+	}
+
+	item, err := database.GetItemByID(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Summary != description {
+		t.Errorf("summary = %q, want feed description", item.Summary)
+	}
+	if item.WordCount < 500 || item.ReadingTime < 2 || item.Difficulty != "medium" && item.Difficulty != "hard" {
+		t.Errorf("word_count=%d reading_time=%d difficulty=%q", item.WordCount, item.ReadingTime, item.Difficulty)
+	}
+	if item.CoverImage != "https://example.com/photo.jpg" || item.ImageCaption != "Harbour at dawn" {
+		t.Errorf("cover = %q (%q)", item.CoverImage, item.ImageCaption)
+	}
+	if item.Tags == "" {
+		t.Errorf("expected keyword tags")
+	}
+
+	result, err = w.ReprocessItems(source.ID, result.NextCursor, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Processed != 1 || result.NextCursor != second.ID || result.Remaining != 0 {
+		t.Fatalf("second batch = %+v", result)
+	}
+
+	// 结果已是最新时不再写入
+	result, err = w.ReprocessItems(source.ID, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Processed != 2 || result.Updated != 0 {
+		t.Errorf("repeated run = %+v, want no updates", result)
+	}
+
+	// 游标之后没有文章时保持原游标
+	result, err = w.ReprocessItems(source.ID, second.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Processed != 0 || result.NextCursor != second.ID || result.Remaining != 0 {
+		t.Errorf("empty batch = %+v", result)
+	}
+}
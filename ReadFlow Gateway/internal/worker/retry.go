@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/readflow/gateway/internal/config"
+	"github.com/readflow/gateway/internal/db"
+)
+
+// 批量重试中单个源的结果状态
+const (
+	RetrySucceeded = "succeeded" // 抓取成功
+	RetryFailed    = "failed"    // 抓取失败，error 中为原因
+	RetryRunning   = "running"   // 返回时仍在排队或抓取，结果在后台写入源的抓取状态
+	RetrySkipped   = "skipped"   // 超过时限未开始抓取，源已重新启用，由定时调度抓取
+)
+
+// retryResponseWait 批量重试最多等待结果的时间，需明显小于 HTTP 的 WriteTimeout（30 秒）
+// 超过后立即返回，其余源按刷新时限继续在后台抓取
+const retryResponseWait = 20 * time.Second
+
+// SourceRetryOutcome 批量重试中单个源的结果
+type SourceRetryOutcome struct {
+	SourceID int64  `json:"source_id"`
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	Status   string `json:"status"` // succeeded | failed | running | skipped
+	Error    string `json:"error,omitempty"`
+}
+
+// RetrySources 重新启用并立即抓取给定的源，返回每个源的结果
+// 并发数和整体时限沿用用户刷新的运行时配置，但最多等待 retryResponseWait，未完成的源标记为 running；
+// 已有批量重试在进行时返回 ErrRefreshInProgress
+func (w *Worker) RetrySources(sources []*db.Source) ([]SourceRetryOutcome, error) {
+	if !w.retrying.TryLock() {
+		return nil, ErrRefreshInProgress
+	}
+
+	// 先全部重新启用，超时未轮到的源也会由定时调度继续抓取
+	outcomes := make([]SourceRetryOutcome, len(sources))
+	pending := make([]int, 0, len(sources))
+	for i, s := range sources {
+		outcomes[i] = SourceRetryOutcome{SourceID: s.ID, URL: s.URL, Title: s.Title, Status: RetrySkipped}
+		if err := w.db.ReactivateSource(s.ID); err != nil {
+			outcomes[i].Status, outcomes[i].Error = RetryFailed, fmt.Sprintf("reactivate source failed: %v", err)
+			continue
+		}
+		outcomes[i].Status = RetryRunning
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		w.retrying.Unlock()
+		return outcomes, nil
+	}
+
+	rc := config.GetRuntimeConfig()
+	concurrency := rc.GetRefreshConcurrency()
+	if concurrency > len(pending) {
+		concurrency = len(pending)
+	}
+	timeout := time.Duration(rc.GetRefreshTimeout()) * time.Second
+
+	log.Printf("[Worker] 开始重试 %d 个失败的源（并发 %d，时限 %v）", len(pending), concurrency, timeout)
+
+	// 派发和抓取在后台按刷新时限进行，返回后不取消
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	// outcomes 在超时返回后仍可能被进行中的抓取写入，统一加锁
+	var mu sync.Mutex
+	setOutcome := func(i int, status string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		outcomes[i].Status = status
+		if err != nil {
+			outcomes[i].Error = err.Error()
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				setOutcome(idx, RetryRunning, nil)
+				if err := w.retrySource(sources[idx]); err != nil {
+					setOutcome(idx, RetryFailed, err)
+				} else {
+					setOutcome(idx, RetrySucceeded, nil)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for n, i := range pending {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				for _, j := range pending[n:] {
+					setOutcome(j, RetrySkipped, nil)
+				}
+				return
+			}
+		}
+	}()
+
+	// 进行中的抓取全部结束后才释放锁，避免提前返回后立即叠加新的批量重试
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		cancel()
+		w.retrying.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(retryResponseWait):
+		log.Printf("[Worker] 批量重试 %v 内未完成，其余源将在后台继续抓取（时限 %v）", retryResponseWait, timeout)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]SourceRetryOutcome(nil), outcomes...), nil
+}
+
+// retrySource 抓取单个源，结果写入源的抓取时间或错误信息
+func (w *Worker) retrySource(s *db.Source) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	if err := w.fetchSourceWithTimeout(s); err != nil {
+		log.Printf("[Worker] 源 %s 重试失败: %v", s.URL, err)
+		w.db.UpdateSourceError(s.ID, err.Error())
+		return err
+	}
+	w.db.UpdateSourceFetchTime(s.ID)
+	return nil
+}
@@ -1,6 +1,7 @@
 package worker
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/mmcdole/gofeed"
@@ -29,18 +30,18 @@ type MediaThumbnailInfo struct {
 // EnhancedItem 增强的 RSS 条目
 type EnhancedItem struct {
 	*gofeed.Item
-	MediaContent   []MediaContentInfo
+	MediaContent   []MediaContentInfo // 非视频的 media:content（含 media:group 变体）
+	MediaVideos    []MediaContentInfo // 视频类 media:content
 	MediaThumbnail *MediaThumbnailInfo
 }
 
 // ExtractMediaContent 从 gofeed.Item 中提取 media:content 信息
+// 包括顶层的 media:content 和 media:group 中的多个尺寸变体
 func ExtractMediaContent(item *gofeed.Item) []MediaContentInfo {
 	if item == nil || item.Extensions == nil {
 		return nil
 	}
 
-	var mediaContents []MediaContentInfo
-
 	// 获取 media 扩展
 	mediaExts, ok := item.Extensions["media"]
 	if !ok {
@@ -48,16 +49,32 @@ func ExtractMediaContent(item *gofeed.Item) []MediaContentInfo {
 	}
 
 	// 提取 item 级别的 media 元数据
-	itemMediaDesc := getMapExtensionText(mediaExts, "description")
-	itemMediaCredit := getMapExtensionText(mediaExts, "credit")
-	itemMediaTitle := getMapExtensionText(mediaExts, "title")
+	fallback := MediaContentInfo{
+		Description: getMapExtensionText(mediaExts, "description"),
+		Credit:      getMapExtensionText(mediaExts, "credit"),
+		Title:       getMapExtensionText(mediaExts, "title"),
+	}
 
-	// 获取所有 media:content 节点
-	contents, ok := mediaExts["content"]
-	if !ok {
-		return nil
+	// 顶层 media:content 节点
+	mediaContents := parseMediaContents(mediaExts["content"], fallback)
+
+	// media:group 节点：同一媒体的多个分辨率 / 格式变体
+	for _, group := range mediaExts["group"] {
+		groupFallback := MediaContentInfo{
+			Description: firstNonEmpty(getMapExtensionText(group.Children, "description"), fallback.Description),
+			Credit:      firstNonEmpty(getMapExtensionText(group.Children, "credit"), fallback.Credit),
+			Title:       firstNonEmpty(getMapExtensionText(group.Children, "title"), fallback.Title),
+		}
+		mediaContents = append(mediaContents, parseMediaContents(group.Children["content"], groupFallback)...)
 	}
 
+	return mediaContents
+}
+
+// parseMediaContents 解析一组 media:content 节点，缺少的元数据使用 fallback 补齐
+func parseMediaContents(contents []ext.Extension, fallback MediaContentInfo) []MediaContentInfo {
+	var mediaContents []MediaContentInfo
+
 	for _, content := range contents {
 		mediaInfo := MediaContentInfo{
 			URL:    getAttr(content.Attrs, "url"),
@@ -84,15 +101,15 @@ func ExtractMediaContent(item *gofeed.Item) []MediaContentInfo {
 			}
 		}
 
-		// 如果内部没有，使用 item 级别的
+		// 如果内部没有，使用上层的
 		if mediaInfo.Description == "" {
-			mediaInfo.Description = itemMediaDesc
+			mediaInfo.Description = fallback.Description
 		}
 		if mediaInfo.Credit == "" {
-			mediaInfo.Credit = itemMediaCredit
+			mediaInfo.Credit = fallback.Credit
 		}
 		if mediaInfo.Title == "" {
-			mediaInfo.Title = itemMediaTitle
+			mediaInfo.Title = fallback.Title
 		}
 
 		// 只保留有 URL 的 media:content
@@ -104,7 +121,25 @@ func ExtractMediaContent(item *gofeed.Item) []MediaContentInfo {
 	return mediaContents
 }
 
+// IsVideo 判断 media:content 是否为视频
+func (m MediaContentInfo) IsVideo() bool {
+	return m.Medium == "video" || strings.HasPrefix(strings.ToLower(m.Type), "video/")
+}
+
+// IsImageCandidate 判断 media:content 是否可能是图片
+// 未声明 medium 和 type 时视为图片；音频、视频、Flash 等其他类型排除
+func (m MediaContentInfo) IsImageCandidate() bool {
+	if m.Medium != "" {
+		return m.Medium == "image"
+	}
+	if m.Type != "" {
+		return strings.HasPrefix(strings.ToLower(m.Type), "image/")
+	}
+	return true
+}
+
 // ExtractMediaThumbnail 从 gofeed.Item 中提取 media:thumbnail 信息
+// 顶层没有时使用 media:group 中的缩略图（如 YouTube），多个时取最大的
 func ExtractMediaThumbnail(item *gofeed.Item) *MediaThumbnailInfo {
 	if item == nil || item.Extensions == nil {
 		return nil
@@ -115,22 +150,33 @@ func ExtractMediaThumbnail(item *gofeed.Item) *MediaThumbnailInfo {
 		return nil
 	}
 
-	thumbnails, ok := mediaExts["thumbnail"]
-	if !ok || len(thumbnails) == 0 {
-		return nil
+	thumbnails := mediaExts["thumbnail"]
+	if len(thumbnails) == 0 {
+		for _, group := range mediaExts["group"] {
+			thumbnails = append(thumbnails, group.Children["thumbnail"]...)
+		}
 	}
 
-	thumbnail := thumbnails[0]
-	url := getAttr(thumbnail.Attrs, "url")
-	if url == "" {
-		return nil
+	var best *MediaThumbnailInfo
+	bestArea := -1
+	for _, thumbnail := range thumbnails {
+		url := getAttr(thumbnail.Attrs, "url")
+		if url == "" {
+			continue
+		}
+		info := &MediaThumbnailInfo{
+			URL:    url,
+			Width:  getAttr(thumbnail.Attrs, "width"),
+			Height: getAttr(thumbnail.Attrs, "height"),
+		}
+		width, _ := strconv.Atoi(info.Width)
+		height, _ := strconv.Atoi(info.Height)
+		if area := width * height; area > bestArea {
+			best, bestArea = info, area
+		}
 	}
 
-	return &MediaThumbnailInfo{
-		URL:    url,
-		Width:  getAttr(thumbnail.Attrs, "width"),
-		Height: getAttr(thumbnail.Attrs, "height"),
-	}
+	return best
 }
 
 // EnhanceItem 增强 gofeed.Item，提取 media 信息
@@ -141,10 +187,18 @@ func EnhanceItem(item *gofeed.Item) *EnhancedItem {
 
 	enhanced := &EnhancedItem{
 		Item:           item,
-		MediaContent:   ExtractMediaContent(item),
 		MediaThumbnail: ExtractMediaThumbnail(item),
 	}
 
+	// 视频单独存放，避免被当作封面图
+	for _, media := range ExtractMediaContent(item) {
+		if media.IsVideo() {
+			enhanced.MediaVideos = append(enhanced.MediaVideos, media)
+		} else {
+			enhanced.MediaContent = append(enhanced.MediaContent, media)
+		}
+	}
+
 	return enhanced
 }
 
@@ -156,6 +210,16 @@ func getMapExtensionText(exts map[string][]ext.Extension, key string) string {
 	return ""
 }
 
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // getAttr 从属性映射中获取值
 func getAttr(attrs map[string]string, key string) string {
 	if val, ok := attrs[key]; ok {
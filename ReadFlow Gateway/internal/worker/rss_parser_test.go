@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func parseFixture(t *testing.T, name string) *gofeed.Feed {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	feed, err := gofeed.NewParser().Parse(f)
+	if err != nil {
+		t.Fatalf("parse %s: %v", name, err)
+	}
+	return feed
+}
+
+func TestEnhanceItemMediaGroup(t *testing.T) {
+	feed := parseFixture(t, "media_group.xml")
+	if len(feed.Items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(feed.Items))
+	}
+
+	// 图片变体进入 MediaContent，视频单独存放，组级 credit 作为回退
+	news := EnhanceItem(feed.Items[0])
+	if len(news.MediaContent) != 3 {
+		t.Fatalf("expected 3 image variants, got %d", len(news.MediaContent))
+	}
+	if len(news.MediaVideos) != 1 || news.MediaVideos[0].URL != "https://cdn.example.com/harbour/clip-1920.mp4" {
+		t.Fatalf("unexpected videos: %+v", news.MediaVideos)
+	}
+	if news.MediaContent[0].Credit != "Jane Doe / Example News" {
+		t.Errorf("group credit not applied: %q", news.MediaContent[0].Credit)
+	}
+
+	// 分组缩略图取最大的一张
+	video := EnhanceItem(feed.Items[1])
+	if video.MediaThumbnail == nil || video.MediaThumbnail.URL != "https://img.example.com/vi/abc123/hqdefault.jpg" {
+		t.Fatalf("unexpected thumbnail: %+v", video.MediaThumbnail)
+	}
+}
+
+func TestExtractBestImageMediaGroup(t *testing.T) {
+	feed := parseFixture(t, "media_group.xml")
+	extractor := NewImageExtractor(nil)
+
+	cases := []struct {
+		item int
+		want string
+	}{
+		{0, "https://cdn.example.com/harbour/1280x720.jpg"},
+		{1, "https://img.example.com/vi/abc123/hqdefault.jpg"},
+	}
+	for _, tc := range cases {
+		best := extractor.ExtractBestImage(feed.Items[tc.item], "")
+		if best == nil || best.URL != tc.want {
+			t.Errorf("item %d: got %+v, want %s", tc.item, best, tc.want)
+		}
+	}
+}
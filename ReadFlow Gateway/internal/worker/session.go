@@ -0,0 +1,290 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// 会话模式（auth_type=session）：抓取订阅源前先按登录脚本请求一次，把响应设置的 Cookie 附加到同域请求。
+// 用于需要先登录取得会话 Cookie 的源（如开启认证的 RSSHub 路由）
+
+const (
+	// maxFetchScriptSteps 登录脚本的最大步数，目前只支持单步登录
+	maxFetchScriptSteps = 1
+	// maxLoginResponseSize 登录响应体最多读取的字节数（响应体不使用，只取 Cookie）
+	maxLoginResponseSize = 1 << 20
+)
+
+// ErrSessionExpired 重新登录后订阅源仍然拒绝访问，会话已失效或登录脚本、账号不再有效
+var ErrSessionExpired = errors.New("session expired or rejected")
+
+// FetchScript 会话模式的登录脚本，以 JSON 形式与凭据一起加密存储
+// 只是声明式的数据：每一步向订阅源所在主机发送一个请求并提取响应设置的 Cookie，不执行任何代码
+type FetchScript struct {
+	Steps []FetchStep `json:"steps"`
+}
+
+// FetchStep 登录脚本中的一步请求
+type FetchStep struct {
+	Method         string            `json:"method"`                 // GET 或 POST，默认 GET
+	URL            string            `json:"url"`                    // 必须与订阅源同一主机
+	Headers        map[string]string `json:"headers,omitempty"`      // 附加请求头
+	Body           string            `json:"body,omitempty"`         // POST 请求体
+	ContentType    string            `json:"content_type,omitempty"` // 请求体类型，默认 application/x-www-form-urlencoded
+	ExtractCookies []string          `json:"extract_cookies"`        // 需要提取的 Cookie 名，为空时提取响应设置的全部 Cookie
+}
+
+// ParseFetchScript 解析并校验登录脚本：步数、请求方法，以及每一步的地址与订阅源 sourceURL 同一主机
+func ParseFetchScript(data, sourceURL string) (*FetchScript, error) {
+	script := &FetchScript{}
+	if err := json.Unmarshal([]byte(data), script); err != nil {
+		return nil, fmt.Errorf("登录脚本不是有效的 JSON")
+	}
+	if len(script.Steps) == 0 {
+		return nil, fmt.Errorf("登录脚本至少需要一步")
+	}
+	if len(script.Steps) > maxFetchScriptSteps {
+		return nil, fmt.Errorf("登录脚本目前只支持 %d 步", maxFetchScriptSteps)
+	}
+
+	source, err := url.Parse(strings.ReplaceAll(sourceURL, tokenPlaceholder, ""))
+	if err != nil || source.Host == "" {
+		return nil, fmt.Errorf("订阅源地址无效")
+	}
+	for i := range script.Steps {
+		step := &script.Steps[i]
+		step.Method = strings.ToUpper(strings.TrimSpace(step.Method))
+		if step.Method == "" {
+			step.Method = http.MethodGet
+		}
+		if step.Method != http.MethodGet && step.Method != http.MethodPost {
+			return nil, fmt.Errorf("第 %d 步的 method 仅支持 GET/POST", i+1)
+		}
+		if step.Method == http.MethodGet && step.Body != "" {
+			return nil, fmt.Errorf("第 %d 步为 GET 请求，不能带 body", i+1)
+		}
+		u, err := url.Parse(step.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("第 %d 步的 url 无效", i+1)
+		}
+		if !strings.EqualFold(u.Host, source.Host) {
+			return nil, fmt.Errorf("第 %d 步的 url 必须与订阅源同一主机（%s）", i+1, source.Host)
+		}
+		for name := range step.Headers {
+			if strings.EqualFold(name, "Host") || strings.EqualFold(name, "Cookie") {
+				return nil, fmt.Errorf("第 %d 步不能设置 %s 请求头", i+1, name)
+			}
+		}
+	}
+	return script, nil
+}
+
+// feedSession 登录取得的会话，按源缓存，凭据更新（version 变化）后失效
+type feedSession struct {
+	cookies   []*http.Cookie
+	version   time.Time // 对应凭据的 updated_at
+	expiresAt time.Time // 最早过期的 Cookie 的过期时间，零值表示会话 Cookie（不主动过期）
+}
+
+// valid 会话对应当前凭据且 Cookie 均未过期
+func (s *feedSession) valid(version, now time.Time) bool {
+	return s.version.Equal(version) && (s.expiresAt.IsZero() || now.Before(s.expiresAt))
+}
+
+// cachedSession 取出源缓存的会话 Cookie，没有或已失效时返回 nil
+func (w *Worker) cachedSession(sourceID int64, version time.Time) []*http.Cookie {
+	value, ok := w.sessions.Load(sourceID)
+	if !ok {
+		return nil
+	}
+	session := value.(*feedSession)
+	if !session.valid(version, time.Now()) {
+		w.sessions.Delete(sourceID)
+		return nil
+	}
+	return session.cookies
+}
+
+// downloadFeedWithSession 下载订阅源；会话模式下先登录（或沿用缓存的会话），
+// 订阅源以 401/403 拒绝缓存的会话时重新登录再试一次，新登录的会话仍被拒绝时返回 ErrSessionExpired
+func (w *Worker) downloadFeedWithSession(ctx context.Context, client *http.Client, feedURL string, auth *feedAuth, validators *feedValidators) ([]byte, string, error) {
+	if auth == nil || auth.authType != authTypeSession {
+		return w.downloadFeed(ctx, client, feedURL, auth, validators)
+	}
+
+	fresh := false
+	if len(auth.cookies) == 0 {
+		if err := w.login(ctx, client, auth); err != nil {
+			return nil, "", err
+		}
+		fresh = true
+	}
+	body, contentType, err := w.downloadFeed(ctx, client, feedURL, auth, validators)
+	if !isAuthRejected(err) {
+		return body, contentType, err
+	}
+
+	if !fresh {
+		log.Printf("[Session] Cached session of source %d rejected, logging in again", auth.sourceID)
+		if err := w.login(ctx, client, auth); err != nil {
+			return nil, "", err
+		}
+		body, contentType, err = w.downloadFeed(ctx, client, feedURL, auth, validators)
+		if !isAuthRejected(err) {
+			return body, contentType, err
+		}
+	}
+	w.sessions.Delete(auth.sourceID)
+	return nil, "", fmt.Errorf("%w: feed returned %v right after login, check the fetch script and account", ErrSessionExpired, err)
+}
+
+// isAuthRejected 订阅源是否以 401/403 拒绝了请求
+func isAuthRejected(err error) bool {
+	var httpErr gofeed.HTTPError
+	return errors.As(err, &httpErr) &&
+		(httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden)
+}
+
+// login 按登录脚本依次请求，取得的 Cookie 写入 auth 并按源缓存；前面步骤取得的 Cookie 随后续步骤发送
+func (w *Worker) login(ctx context.Context, client *http.Client, auth *feedAuth) error {
+	var cookies []*http.Cookie
+	for i, step := range auth.script.Steps {
+		got, err := w.runFetchStep(ctx, client, auth, step, cookies)
+		if err != nil {
+			return fmt.Errorf("session login step %d failed: %w", i+1, auth.redactError(err))
+		}
+		cookies = mergeCookies(cookies, got)
+	}
+
+	auth.cookies = cookies
+	session := &feedSession{cookies: cookies, version: auth.version}
+	for _, cookie := range cookies {
+		if expires := cookieExpiry(cookie); !expires.IsZero() && (session.expiresAt.IsZero() || expires.Before(session.expiresAt)) {
+			session.expiresAt = expires
+		}
+	}
+	w.sessions.Store(auth.sourceID, session)
+	log.Printf("[Session] Logged in for source %d, %d cookies", auth.sourceID, len(cookies))
+	return nil
+}
+
+// runFetchStep 执行登录脚本的一步，返回需要提取的 Cookie
+// 不跟随重定向：登录接口常在 302 响应中设置 Cookie，跟随后会丢失
+func (w *Worker) runFetchStep(ctx context.Context, client *http.Client, auth *feedAuth, step FetchStep, cookies []*http.Cookie) ([]*http.Cookie, error) {
+	if err := w.feedHosts.Check(step.URL); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(step.URL)
+	if err != nil || !auth.matchesHost(u.Host) {
+		return nil, fmt.Errorf("login url must be on the feed host")
+	}
+	// 与 downloadFeed 相同，时限包括等待主机并发名额的时间
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(step.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, step.Method, step.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", w.parser.UserAgent)
+	if step.Body != "" {
+		contentType := step.ContentType
+		if contentType == "" {
+			contentType = "application/x-www-form-urlencoded"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range step.Headers {
+		req.Header.Set(name, value)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	release, err := w.hostSlots.acquire(ctx, req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxLoginResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("login returned HTTP %d", resp.StatusCode)
+	}
+	return extractCookies(resp.Cookies(), step.ExtractCookies)
+}
+
+// extractCookies 按名称取出需要的 Cookie，names 为空时取全部；缺少任一指定的 Cookie 时返回错误
+func extractCookies(cookies []*http.Cookie, names []string) ([]*http.Cookie, error) {
+	if len(names) == 0 {
+		if len(cookies) == 0 {
+			return nil, fmt.Errorf("login response set no cookies")
+		}
+		return cookies, nil
+	}
+
+	byName := make(map[string]*http.Cookie, len(cookies))
+	for _, cookie := range cookies {
+		byName[cookie.Name] = cookie
+	}
+	extracted := make([]*http.Cookie, 0, len(names))
+	for _, name := range names {
+		cookie, ok := byName[name]
+		if !ok || cookie.Value == "" {
+			return nil, fmt.Errorf("login response did not set cookie %q", name)
+		}
+		extracted = append(extracted, cookie)
+	}
+	return extracted, nil
+}
+
+// mergeCookies 合并 Cookie，同名的以后取得的为准
+func mergeCookies(existing, added []*http.Cookie) []*http.Cookie {
+	merged := make([]*http.Cookie, 0, len(existing)+len(added))
+	for _, cookie := range existing {
+		replaced := false
+		for _, a := range added {
+			if a.Name == cookie.Name {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, cookie)
+		}
+	}
+	return append(merged, added...)
+}
+
+// cookieExpiry Cookie 的过期时间，会话 Cookie 返回零值
+func cookieExpiry(cookie *http.Cookie) time.Time {
+	if cookie.MaxAge > 0 {
+		return time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+	}
+	if cookie.MaxAge < 0 {
+		return time.Now()
+	}
+	return cookie.Expires
+}
@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestParseFetchScript(t *testing.T) {
+	const sourceURL = "https://rsshub.example.com/private/feed"
+	script, err := ParseFetchScript(`{"steps":[{"method":"post","url":"https://rsshub.example.com/login","body":"u=a&p=b","extract_cookies":["sid"]}]}`, sourceURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if script.Steps[0].Method != http.MethodPost {
+		t.Errorf("method = %q, want POST", script.Steps[0].Method)
+	}
+
+	for name, data := range map[string]string{
+		"not json":    `steps`,
+		"no steps":    `{"steps":[]}`,
+		"two steps":   `{"steps":[{"url":"https://rsshub.example.com/a"},{"url":"https://rsshub.example.com/b"}]}`,
+		"other host":  `{"steps":[{"url":"https://evil.example.org/login"}]}`,
+		"bad method":  `{"steps":[{"method":"DELETE","url":"https://rsshub.example.com/login"}]}`,
+		"get body":    `{"steps":[{"url":"https://rsshub.example.com/login","body":"x"}]}`,
+		"cookie hdr":  `{"steps":[{"url":"https://rsshub.example.com/login","headers":{"Cookie":"a=b"}}]}`,
+		"file scheme": `{"steps":[{"url":"file:///etc/passwd"}]}`,
+	} {
+		if _, err := ParseFetchScript(data, sourceURL); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFetchFeedWithSession(t *testing.T) {
+	const rss = `<?xml version="1.0"?><rss version="2.0"><channel><title>Private</title><item><title>A</title><guid>a</guid></item></channel></rss>`
+	logins := 0
+	issued, accepted := "s1", "s1" // 登录下发的会话和订阅源接受的会话
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		logins++
+		if r.Method != http.MethodPost || r.FormValue("password") != "secret" {
+			http.Error(w, "denied", http.StatusForbidden)
+			return
+		}
+		// Cookie 设置在重定向响应中
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: issued})
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("sid"); err != nil || cookie.Value != accepted {
+			http.Error(w, "login required", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(rss))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	script, err := ParseFetchScript(`{"steps":[{"method":"POST","url":"`+server.URL+`/login","body":"password=secret","extract_cookies":["sid"]}]}`, server.URL+"/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(server.URL)
+	w := &Worker{parser: gofeed.NewParser()}
+	fetch := func() error {
+		t.Helper()
+		auth := &feedAuth{authType: authTypeSession, host: u.Host, sourceID: 1, script: script}
+		auth.cookies = w.cachedSession(1, auth.version)
+		feed, err := w.fetchFeed(server.Client(), server.URL+"/feed", auth, false, nil)
+		if err == nil && len(feed.Items) != 1 {
+			t.Errorf("items = %d, want 1", len(feed.Items))
+		}
+		return err
+	}
+
+	// 第一次抓取登录，之后沿用缓存的会话
+	for i := 0; i < 2; i++ {
+		if err := fetch(); err != nil {
+			t.Fatalf("fetch %d: %v", i, err)
+		}
+	}
+	if logins != 1 {
+		t.Errorf("logins = %d, want 1", logins)
+	}
+
+	// 缓存的会话过期：重新登录一次后继续
+	issued, accepted = "s2", "s2"
+	if err := fetch(); err != nil {
+		t.Fatalf("fetch after expiry: %v", err)
+	}
+	if logins != 2 {
+		t.Errorf("logins = %d, want 2", logins)
+	}
+
+	// 重新登录后仍被拒绝时报告会话失效，不再重试
+	accepted = "s3"
+	for i, wantLogins := range []int{3, 4} {
+		if err := fetch(); !errors.Is(err, ErrSessionExpired) {
+			t.Errorf("rejected fetch %d: err = %v, want ErrSessionExpired", i, err)
+		}
+		if logins != wantLogins {
+			t.Errorf("rejected fetch %d: logins = %d, want %d", i, logins, wantLogins)
+		}
+	}
+}
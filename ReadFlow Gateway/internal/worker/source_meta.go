@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+)
+
+// 源标题和描述的长度上限（字符数），超出部分截断
+const (
+	maxSourceTitleLength       = 200
+	maxSourceDescriptionLength = 500
+)
+
+// sourceMetaUpdate 根据 feed 的标题和描述计算源应更新的元数据，不需要更新时 ok 为 false
+// 标题为空时使用 feed 标题；feed 标题与上次记录的 feed 标题（sources.feed_title）有实质差异
+// （忽略大小写、空白和标点）时视为改名，源标题仍沿用旧 feed 标题的才同步更新标题和描述，
+// 管理员修改过的标题不被覆盖；描述为空时使用 feed 描述。feedTitle 为应记录的 feed 标题。
+// 用户在订阅上设置的自定义标题保存在订阅关系中，不受影响
+func sourceMetaUpdate(source *db.Source, feed *gofeed.Feed) (title, description, feedTitle string, ok bool) {
+	title, description, feedTitle = source.Title, source.Description, source.FeedTitle
+	newFeedTitle := truncateRunes(CleanHTMLTags(feed.Title), maxSourceTitleLength)
+	feedDescription := truncateRunes(CleanHTMLTags(feed.Description), maxSourceDescriptionLength)
+
+	// 还没有记录 feed 标题（新源或升级前的源）时只记录，不视为改名
+	renamed := newFeedTitle != "" && feedTitle != "" && titleKey(feedTitle) != titleKey(newFeedTitle) &&
+		titleKey(title) == titleKey(feedTitle)
+	if newFeedTitle != "" && (title == "" || renamed) {
+		title = newFeedTitle
+	}
+	if feedDescription != "" && (description == "" || renamed) {
+		description = feedDescription
+	}
+	if newFeedTitle != "" {
+		feedTitle = newFeedTitle
+	}
+	ok = title != source.Title || description != source.Description || feedTitle != source.FeedTitle
+	return title, description, feedTitle, ok
+}
+
+// titleKey 标题的比较键：只保留字母和数字并转为小写
+func titleKey(title string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, title)
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return strings.TrimSpace(string([]rune(s)[:max]))
+}
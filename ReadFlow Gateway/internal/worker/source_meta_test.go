@@ -0,0 +1,35 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestSourceMetaUpdate(t *testing.T) {
+	cases := []struct {
+		name                         string
+		title, desc, lastFeedTitle   string
+		feedTitle, feedDesc          string
+		wantTitle, wantDesc, wantRec string
+		wantOK                       bool
+	}{
+		{"untitled", "", "", "", "Example Blog", "Notes &amp; essays", "Example Blog", "Notes & essays", "Example Blog", true},
+		{"keep description", "", "mine", "", "Example Blog", "theirs", "Example Blog", "mine", "Example Blog", true},
+		{"cosmetic change", "Example Blog", "mine", "Example Blog", "example blog!", "theirs", "Example Blog", "mine", "example blog!", true},
+		{"renamed", "Example Blog", "old", "Example Blog", "Example Weekly", "new", "Example Weekly", "new", "Example Weekly", true},
+		{"admin title kept", "My Reading", "old", "Example Blog", "Example Weekly", "new", "My Reading", "old", "Example Weekly", true},
+		{"first record", "My Reading", "old", "", "Example Blog", "new", "My Reading", "old", "Example Blog", true},
+		{"empty feed title", "Example Blog", "", "Example Blog", "", "desc", "Example Blog", "desc", "Example Blog", true},
+		{"nothing to do", "My Reading", "desc", "Example Blog", "Example Blog", "", "My Reading", "desc", "Example Blog", false},
+	}
+	for _, tc := range cases {
+		source := &db.Source{Title: tc.title, Description: tc.desc, FeedTitle: tc.lastFeedTitle}
+		feed := &gofeed.Feed{Title: tc.feedTitle, Description: tc.feedDesc}
+		title, desc, feedTitle, ok := sourceMetaUpdate(source, feed)
+		if title != tc.wantTitle || desc != tc.wantDesc || feedTitle != tc.wantRec || ok != tc.wantOK {
+			t.Errorf("%s: got (%q, %q, %q, %v), want (%q, %q, %q, %v)", tc.name, title, desc, feedTitle, ok, tc.wantTitle, tc.wantDesc, tc.wantRec, tc.wantOK)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/readflow/gateway/internal/db"
+)
+
+func TestProcessItemSkipsTombstonedItems(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	user, err := database.CreateUser("alice", "alice@example.com", "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := database.CreateSource("https://example.com/feed", "Example", "", 900)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.ImageMode = db.ImageModeOff
+
+	w := &Worker{db: database, imageExtractor: NewImageExtractor(nil)}
+	// 正文带图片，不回退抓取文章页面的 og:image
+	withGUID := &gofeed.Item{GUID: "post-1", Title: "Harbour", Link: "https://example.com/posts/1", Content: `<p>Ships leave early.</p><img src="https://example.com/1.jpg">`}
+	linkOnly := &gofeed.Item{Title: "Lighthouse", Link: "https://example.com/posts/2", Content: `<p>The lamp turns all night.</p><img src="https://example.com/2.jpg">`}
+	keys := map[*gofeed.Item]itemKey{withGUID: {guid: "post-1"}, linkOnly: {guid: "https://example.com/posts/2"}}
+
+	for item, key := range keys {
+		if err := w.processItem(source, item, key, []int64{user.ID}); err != nil {
+			t.Fatalf("processItem %s: %v", item.Title, err)
+		}
+		stored, err := database.GetItemByGUID(source.ID, key.guid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 确认后立即清理（或超出条数上限被删除）
+		if err := database.DeleteItemWithTombstone(stored.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 文章仍在 feed 中：再次抓取时按墓碑跳过，不重新入库也不再投递
+	for item, key := range keys {
+		if err := w.processItem(source, item, key, []int64{user.ID}); err != nil {
+			t.Fatalf("processItem %s again: %v", item.Title, err)
+		}
+	}
+	// 只有链接的条目换了链接形式（追踪参数）时按规范化链接命中
+	variant := &gofeed.Item{Title: "Lighthouse", Link: "https://example.com/posts/2?utm_source=rss", Content: linkOnly.Content}
+	if err := w.processItem(source, variant, itemKey{guid: variant.Link}, []int64{user.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM items WHERE source_id = ?", source.ID).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("items after re-fetch = %d, want 0", count)
+	}
+	pending, err := database.GetPendingDeliveries(user.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("pending deliveries = %d, want 0", len(pending))
+	}
+}
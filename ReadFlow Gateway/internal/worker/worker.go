@@ -2,11 +2,14 @@ package worker
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -15,70 +18,132 @@ import (
 	"github.com/readflow/gateway/internal/config"
 	"github.com/readflow/gateway/internal/db"
 	"github.com/readflow/gateway/internal/image"
+	"github.com/readflow/gateway/internal/metrics"
 	"github.com/readflow/gateway/internal/utils"
+	"golang.org/x/net/html"
 )
 
 // 常量定义
 const (
+	// 调度间隔：每轮检查各源是否到期，实际抓取频率由源的 fetch_interval 决定
+	schedulerInterval = 60 * time.Second
 	// RSS 抓取超时时间
 	fetchTimeout = 60 * time.Second
 	// 单个源处理超时时间
 	sourceTimeout = 120 * time.Second
 	// HTTP 请求超时
 	httpTimeout = 30 * time.Second
+	// 每篇文章最多保留的标签数
+	maxItemTags = 8
+	// TF-IDF 语料：每个源取最近的文章数及统计缓存时间
+	corpusSize = 50
+	corpusTTL  = time.Hour
+	// 用户刷新失败时在错误信息中保留的源数量
+	maxRefreshErrorSamples = 5
+	// 用户刷新最多等待的时间，需明显小于 HTTP 的 WriteTimeout（30 秒），其余源在后台继续抓取
+	refreshResponseWait = 20 * time.Second
+	// 没有对应文章的阅读状态保留时间，超过后清理
+	readStateRetention = 90 * 24 * time.Hour
+	// 墓碑在最后一次出现在 feed 中之后的保留时间，超过后清理
+	itemTombstoneRetention = 90 * 24 * time.Hour
 )
 
+// ErrRefreshInProgress 同一用户已有刷新在进行
+var ErrRefreshInProgress = errors.New("refresh already in progress")
+
 // Worker RSS 抓取工作器
 type Worker struct {
 	db               *db.DB
 	config           *config.Config
 	parser           *gofeed.Parser
+	previewClient    *http.Client      // 订阅源预览使用，只允许连接公网地址
+	feedHosts        *utils.HostPolicy // 订阅源主机允许/禁止列表，nil 表示不限制
+	hostSlots        *hostLimiter      // 同一主机的订阅源请求并发限制，nil 表示不限制
 	imageProcessor   *image.Processor
 	imageExtractor   *ImageExtractor
 	contentExtractor *ContentExtractor
+	readExtractor    *ContentExtractor // 阅读模式使用，只允许连接公网地址
+	readCache        readCache
+	extractSlots     chan struct{} // 全文提取并发限制
+	corpus           *utils.CorpusIndex
 	staticDir        string
+	outboundProxy    *url.URL   // 全局出站代理，nil 表示直连
+	sourceClients    sync.Map   // 源级代理地址 -> *http.Client
+	sessions         sync.Map   // 源 ID -> *feedSession，会话模式登录取得的 Cookie
 	fetching         sync.Mutex // 防止并发抓取
+	refreshing       sync.Map   // 正在刷新的用户 ID，防止同一用户并发刷新
+	retrying         sync.Mutex // 防止并发批量重试失败的源
+	lastMaintenance  time.Time  // 上次数据库维护时间，仅在 Start 协程中读写
 }
 
 // New 创建新的 Worker
 func New(database *db.DB, cfg *config.Config) *Worker {
-	// 创建 HTTP 客户端（带超时）
-	httpClient := &http.Client{
-		Timeout: httpTimeout,
-		Transport: &http.Transport{
-			MaxIdleConns:        100,
-			IdleConnTimeout:     90 * time.Second,
-			TLSHandshakeTimeout: 10 * time.Second,
-		},
+	// 出站代理（OUTBOUND_PROXY），启动时已校验
+	outboundProxy, err := utils.ParseProxyURL(cfg.OutboundProxy)
+	if err != nil {
+		log.Printf("[Worker] Ignoring invalid OUTBOUND_PROXY: %v", err)
 	}
 
+	// RSS 抓取、正文提取和图片处理共用一个连接池，同一主机的连接和 TLS 会话可以互相复用
+	transport := utils.NewSharedTransport(outboundProxy)
+
+	// 订阅源、全文提取和阅读模式的主机允许/禁止列表，重定向的每一跳同样检查
+	feedHosts := utils.NewHostPolicy(cfg.GetRSSAllowedHosts(), cfg.GetRSSDeniedHosts())
+
+	// 创建 HTTP 客户端：不设 Timeout，时限由每个请求的 context 控制（包括等待主机并发名额的时间，见 downloadFeed）
+	httpClient := utils.NewHTTPClient(utils.HTTPClientOptions{
+		Transport:     transport,
+		CheckRedirect: feedHosts.CheckRedirect,
+	})
+
 	// 创建 RSS Parser
 	parser := gofeed.NewParser()
 	parser.Client = httpClient
 
+	// 预览接口抓取用户提交的任意地址，拒绝连接内网地址
+	previewClient := utils.NewHTTPClient(utils.HTTPClientOptions{
+		Timeout:       previewTimeout,
+		Proxy:         outboundProxy,
+		PublicOnly:    true,
+		CheckRedirect: feedHosts.CheckRedirect,
+	})
+
 	// 创建图片处理器
-	imgProcessor := image.NewProcessor(cfg)
+	imgProcessor := image.NewProcessor(cfg, transport)
 
 	// 创建智能图片提取器
-	imgExtractor := NewImageExtractor()
+	imgExtractor := NewImageExtractor(transport)
 
 	// 创建内容提取器
-	contentExtractor := NewContentExtractor()
+	contentExtractor := NewContentExtractor(transport, feedHosts)
+	contentExtractor.paywallMarkers = cfg.GetPaywallMarkers()
+
+	// 创建关键词语料索引（按源统计文档频率）
+	corpus := utils.NewCorpusIndex(func(sourceID int64) ([]string, error) {
+		return database.GetRecentItemTexts(sourceID, corpusSize)
+	}, corpusTTL)
 
 	return &Worker{
 		db:               database,
 		config:           cfg,
 		parser:           parser,
+		previewClient:    previewClient,
+		feedHosts:        feedHosts,
+		hostSlots:        newHostLimiter(config.GetRuntimeConfig().GetFeedHostConcurrency),
 		imageProcessor:   imgProcessor,
 		imageExtractor:   imgExtractor,
 		contentExtractor: contentExtractor,
+		readExtractor:    newReaderContentExtractor(outboundProxy, feedHosts),
+		extractSlots:     make(chan struct{}, maxConcurrentExtractions),
+		corpus:           corpus,
 		staticDir:        cfg.StaticDir,
+		outboundProxy:    outboundProxy,
 	}
 }
 
 // Start 启动 Worker
 func (w *Worker) Start(ctx context.Context) {
-	ticker := time.NewTicker(time.Duration(w.config.FetchInterval) * time.Second)
+	ticker := time.NewTicker(schedulerInterval)
 	defer ticker.Stop()
 
 	// 清理任务每初执行一次，間隔为抬取间隔的 1/3
@@ -99,6 +164,7 @@ func (w *Worker) Start(ctx context.Context) {
 			w.FetchAll()
 		case <-cleanupTicker.C:
 			w.CleanupExpiredItems()
+			w.maintainDBIfDue()
 		}
 	}
 }
@@ -127,11 +193,14 @@ func (w *Worker) FetchAll() {
 
 	log.Printf("Fetching %d active sources", len(sources))
 
-	for _, source := range sources {
-		// 检查是否应该抓取
-		if !w.shouldFetch(source) {
-			continue
-		}
+	// 源按上次抓取时间升序排列，达到每轮上限后剩余的到期源顺延到下一轮
+	due := dueSources(sources, w.shouldFetch)
+	if limit := config.GetRuntimeConfig().GetMaxSourcesPerRound(); limit > 0 && len(due) > limit {
+		log.Printf("[WORKER] %d sources due, fetching %d this round, %d deferred", len(due), limit, len(due)-limit)
+		due = due[:limit]
+	}
+
+	for _, source := range due {
 
 		// 为每个源设置超时
 		if err := w.fetchSourceWithTimeout(source); err != nil {
@@ -143,8 +212,9 @@ func (w *Worker) FetchAll() {
 	}
 }
 
-// fetchSourceWithTimeout 带超时的源抓取
+// fetchSourceWithTimeout 带超时的源抓取，抓取和处理的总耗时记入源的耗时统计
 func (w *Worker) fetchSourceWithTimeout(source *db.Source) error {
+	start := time.Now()
 	// 创建带超时的 context
 	ctx, cancel := context.WithTimeout(context.Background(), sourceTimeout)
 	defer cancel()
@@ -165,13 +235,27 @@ func (w *Worker) fetchSourceWithTimeout(source *db.Source) error {
 	// 等待结果或超时
 	select {
 	case err := <-errChan:
+		metrics.GetMetrics().RecordSourceFetch(source.ID, time.Since(start), sourceTimeout, false)
 		return err
 	case <-ctx.Done():
+		metrics.GetMetrics().RecordSourceFetch(source.ID, sourceTimeout, sourceTimeout, true)
 		return fmt.Errorf("timeout after %v", sourceTimeout)
 	}
 }
 
-// shouldFetch 判断是否应该抓取该源
+// dueSources 按原顺序返回到期需要抓取的源
+func dueSources(sources []*db.Source, isDue func(*db.Source) bool) []*db.Source {
+	due := make([]*db.Source, 0, len(sources))
+	for _, source := range sources {
+		if isDue(source) {
+			due = append(due, source)
+		}
+	}
+	return due
+}
+
+// shouldFetch 判断是否应该抓取该源（距上次抓取已超过源自身的 fetch_interval）
+// 间隔按 fetch_jitter_percent 随机偏移，避免间隔相同的源在同一轮集中抓取同一主机
 func (w *Worker) shouldFetch(source *db.Source) bool {
 	if source.LastFetchTime == nil {
 		return true
@@ -179,6 +263,7 @@ func (w *Worker) shouldFetch(source *db.Source) bool {
 
 	elapsed := time.Since(*source.LastFetchTime)
 	interval := time.Duration(source.FetchInterval) * time.Second
+	interval += fetchJitter(source.ID, *source.LastFetchTime, interval, config.GetRuntimeConfig().GetFetchJitterPercent())
 
 	return elapsed >= interval
 }
@@ -193,92 +278,219 @@ func (w *Worker) FetchSource(source *db.Source) error {
 }
 
 // FetchAllSourcesForUser 并发抓取用户订阅的所有源（供 Sync API 刷新模式调用）
+// 并发数和整体时限由运行时配置决定，但最多等待 refreshResponseWait，未完成的源在后台按时限继续抓取；
+// 同一用户的刷新未结束时返回 ErrRefreshInProgress
 func (w *Worker) FetchAllSourcesForUser(userID int64) error {
+	if _, busy := w.refreshing.LoadOrStore(userID, struct{}{}); busy {
+		return ErrRefreshInProgress
+	}
+
 	sources, err := w.db.GetUserSubscriptions(userID)
 	if err != nil {
+		w.refreshing.Delete(userID)
 		return fmt.Errorf("get user sources failed: %w", err)
 	}
 
 	if len(sources) == 0 {
+		w.refreshing.Delete(userID)
 		log.Printf("[Worker] 用户 %d 没有订阅源", userID)
 		return nil
 	}
 
-	log.Printf("[Worker] 开始为用户 %d 并发刷新 %d 个源", userID, len(sources))
+	rc := config.GetRuntimeConfig()
+	concurrency := rc.GetRefreshConcurrency()
+	if concurrency > len(sources) {
+		concurrency = len(sources)
+	}
+	timeout := time.Duration(rc.GetRefreshTimeout()) * time.Second
 
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(sources))
+	log.Printf("[Worker] 开始为用户 %d 刷新 %d 个源（并发 %d，时限 %v）", userID, len(sources), concurrency, timeout)
 
-	// 并发限制：最多同时抓取 3 个源
-	semaphore := make(chan struct{}, 3)
+	// 派发和抓取在后台按刷新时限进行，返回后不取消
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
-	for _, source := range sources {
+	// 固定数量的协程从无缓冲队列取任务，队列满时分发方阻塞，不会一次性创建全部协程
+	jobs := make(chan *db.Source)
+	result := &refreshResult{}
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
 		wg.Add(1)
-		go func(s *db.Source) {
+		go func() {
 			defer wg.Done()
+			for s := range jobs {
+				w.refreshUserSource(s, result)
+			}
+		}()
+	}
 
-			// 获取信号量
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+	// skipped 只由派发协程写入，done 关闭后读取
+	skipped := 0
+	go func() {
+		defer close(jobs)
+		for i, source := range sources {
+			select {
+			case jobs <- source:
+			case <-ctx.Done():
+				skipped = len(sources) - i
+				return
+			}
+		}
+	}()
 
-			// 添加 panic 恢复
-			defer func() {
-				if r := recover(); r != nil {
-					errChan <- fmt.Errorf("panic while fetching source %s: %v", s.URL, r)
-				}
-			}()
+	// 进行中的抓取全部结束后才释放用户锁，避免提前返回后立即叠加新的刷新
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		cancel()
+		w.refreshing.Delete(userID)
+		close(done)
+	}()
 
-			if err := w.fetchSourceWithTimeout(s); err != nil {
-				log.Printf("[Worker] 源 %s 抓取失败: %v", s.URL, err)
-				w.db.UpdateSourceError(s.ID, err.Error())
-				errChan <- err
-			} else {
-				w.db.UpdateSourceFetchTime(s.ID)
-			}
-		}(source)
+	select {
+	case <-done:
+	case <-time.After(refreshResponseWait):
+		succeeded, failed, samples := result.snapshot()
+		log.Printf("[Worker] 用户 %d 刷新 %v 内未完成（%d 个成功，%d 个失败），其余源将在后台继续抓取（时限 %v）",
+			userID, refreshResponseWait, succeeded, failed, timeout)
+		if failed > 0 {
+			return fmt.Errorf("%d sources failed to fetch (%s), others still running", failed, strings.Join(samples, "; "))
+		}
+		return nil
 	}
 
-	wg.Wait()
-	close(errChan)
+	succeeded, failed, samples := result.snapshot()
+	log.Printf("[Worker] 用户 %d 源刷新结束，%d 个成功，%d 个失败，%d 个未开始", userID, succeeded, failed, skipped)
 
-	// 统计错误数量
-	errorCount := 0
-	for err := range errChan {
-		if err != nil {
-			errorCount++
+	switch {
+	case failed > 0 && skipped > 0:
+		return fmt.Errorf("%d sources failed to fetch (%s), %d skipped after deadline", failed, strings.Join(samples, "; "), skipped)
+	case failed > 0:
+		return fmt.Errorf("%d sources failed to fetch (%s)", failed, strings.Join(samples, "; "))
+	case skipped > 0:
+		return fmt.Errorf("refresh deadline %v exceeded, %d sources skipped", timeout, skipped)
+	}
+	return nil
+}
+
+// refreshUserSource 刷新单个源并记录结果
+func (w *Worker) refreshUserSource(s *db.Source, result *refreshResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			result.fail(s, fmt.Errorf("panic: %v", r))
 		}
+	}()
+
+	if err := w.fetchSourceWithTimeout(s); err != nil {
+		log.Printf("[Worker] 源 %s 抓取失败: %v", s.URL, err)
+		w.db.UpdateSourceError(s.ID, err.Error())
+		result.fail(s, err)
+		return
 	}
+	w.db.UpdateSourceFetchTime(s.ID)
+	result.succeed()
+}
+
+// refreshResult 用户刷新结果统计，只保留少量错误样本，内存不随源数量增长
+type refreshResult struct {
+	mu        sync.Mutex
+	succeeded int
+	failed    int
+	samples   []string
+}
 
-	log.Printf("[Worker] 用户 %d 源刷新完成，%d 个成功，%d 个失败", userID, len(sources)-errorCount, errorCount)
+// succeed 记录一个成功的源
+func (r *refreshResult) succeed() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.succeeded++
+}
 
-	if errorCount > 0 {
-		return fmt.Errorf("%d sources failed to fetch", errorCount)
+// fail 记录一个失败的源
+func (r *refreshResult) fail(s *db.Source, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed++
+	if len(r.samples) < maxRefreshErrorSamples {
+		r.samples = append(r.samples, fmt.Sprintf("%s: %v", s.URL, err))
 	}
-	return nil
+}
+
+// snapshot 读取当前统计（超时返回时仍有抓取在进行）
+func (r *refreshResult) snapshot() (int, int, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.succeeded, r.failed, append([]string(nil), r.samples...)
 }
 
 // fetchSource 抓取单个源
 func (w *Worker) fetchSource(source *db.Source) error {
-	url := source.URL
-	log.Printf("Fetching source: %s", url)
+	log.Printf("Fetching source: %s", source.URL)
+	url := w.resolveFeedURL(source.URL)
 
-	// 处理 rsshub:// 协议
-	if strings.HasPrefix(url, "rsshub://") {
-		rsshubHost := "https://rsshub.app"
-		// 如果将来有配置，可以从 w.config 获取
-		url = rsshubHost + "/" + strings.TrimPrefix(url, "rsshub://")
-		log.Printf("[WORKER] Transforming rsshub:// to %s", url)
+	// 加载私有源凭据（可能为空）
+	auth, err := w.loadFeedAuth(source)
+	if err != nil {
+		return err
+	}
+
+	// 源级代理优先于全局出站代理
+	client, err := w.feedClient(source)
+	if err != nil {
+		return err
 	}
 
-	// 解析 RSS
-	feed, err := w.parser.ParseURL(url)
+	// 解析 RSS：带上次成功抓取的校验值发条件请求，内容没有变化时跳过解析和处理
+	validators := &feedValidators{ETag: source.HTTPETag, LastModified: source.HTTPLastModified}
+	feed, err := w.fetchFeed(client, url, auth, source.LenientParse, validators)
+	if errors.Is(err, errFeedNotModified) {
+		log.Printf("Source %s not modified since last fetch", source.URL)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("parse RSS failed: %w", err)
 	}
 
-	// 更新源信息
-	if source.Title == "" && feed.Title != "" {
-		// 这里可以更新源的标题和描述
+	// 更新源信息：标题或描述为空、或 feed 已改名时从 feed 同步（每次抓取最多写一次）
+	if title, description, feedTitle, ok := sourceMetaUpdate(source, feed); ok {
+		if err := w.db.UpdateSourceMeta(source.ID, title, description, feedTitle); err != nil {
+			log.Printf("[Worker] Failed to update title for source %d: %v", source.ID, err)
+		} else {
+			if title != source.Title {
+				log.Printf("[Worker] Source %d title: %q -> %q", source.ID, source.Title, title)
+			}
+			source.Title, source.Description, source.FeedTitle = title, description, feedTitle
+		}
+	}
+
+	// 源语言未知时记录 feed 声明的语言（RSS <language>、Atom xml:lang）
+	if source.Language == "" {
+		if language := utils.NormalizeLanguage(feed.Language); language != "" {
+			if err := w.db.UpdateSourceLanguageIfUnset(source.ID, language); err != nil {
+				log.Printf("[Worker] Failed to record language for source %d: %v", source.ID, err)
+			} else {
+				source.Language = language
+			}
+		}
+	}
+
+	// 首次抓取或定期重新解析源图标，失败不影响文章抓取
+	if needsFavicon(source) {
+		if _, err := w.updateFavicon(source, feed, client); err != nil {
+			log.Printf("[Favicon] No favicon for source %s: %v", source.URL, err)
+		}
+	}
+
+	// 检查文章的数据质量：跳过空壳文章，提示记录到源上（变化时才写入）
+	items, note := inspectFeedItems(feed.Items, config.GetRuntimeConfig().GetFeedLocation(), time.Now())
+	if note != source.LastParseNote {
+		if err := w.db.UpdateSourceParseNote(source.ID, note); err != nil {
+			log.Printf("[Worker] Failed to record parse note for source %d: %v", source.ID, err)
+		} else {
+			source.LastParseNote = note
+		}
+	}
+	if note != "" {
+		log.Printf("[Worker] Source %s: %s", source.URL, note)
 	}
 
 	// 获取订阅该源的用户列表
@@ -294,55 +506,147 @@ func (w *Worker) fetchSource(source *db.Source) error {
 
 	// 处理每篇文章
 	newItemsCount := 0
-	for _, feedItem := range feed.Items {
+	belowMinWords := 0
+	failed := 0
+	keys := itemKeys(source.URL, items)
+	for i, feedItem := range items {
 		// 创建新文章
-		if err := w.processItem(source.ID, feedItem, userIDs); err != nil {
+		if err := w.processItem(source, feedItem, keys[i], userIDs); err != nil {
+			if errors.Is(err, errBelowMinWords) {
+				belowMinWords++
+				continue
+			}
 			log.Printf("Failed to process item %s: %v", feedItem.GUID, err)
+			failed++
 			continue
 		}
 
 		newItemsCount++
 	}
 
-	log.Printf("Fetched %d new items from source %s", newItemsCount, source.URL)
+	w.recordFeedValidators(source, validators, failed)
+
+	if belowMinWords > 0 {
+		log.Printf("Fetched %d new items from source %s, skipped delivery of %d items below %d words",
+			newItemsCount, source.URL, belowMinWords, source.MinWordCount)
+	} else {
+		log.Printf("Fetched %d new items from source %s", newItemsCount, source.URL)
+	}
+
+	// 源设置了文章数上限时删除超出的旧文章（与按时间的保留策略同时生效）
+	if newItemsCount > 0 && source.MaxItems > 0 {
+		if _, err := w.TrimSource(source); err != nil {
+			log.Printf("[CLEANUP] Failed to trim source %d: %v", source.ID, err)
+		}
+	}
 	return nil
 }
 
+// resolveFeedURL 将 rsshub:// 协议转换为实际抓取地址
+func (w *Worker) resolveFeedURL(feedURL string) string {
+	if !strings.HasPrefix(feedURL, "rsshub://") {
+		return feedURL
+	}
+	rsshubHost := "https://rsshub.app"
+	// 如果将来有配置，可以从 w.config 获取
+	resolved := rsshubHost + "/" + strings.TrimPrefix(feedURL, "rsshub://")
+	log.Printf("[WORKER] Transforming rsshub:// to %s", resolved)
+	return resolved
+}
+
+// guidIsLink 判断文章的身份是否就是链接：没有 GUID，或 GUID 与规范化链接一致
+func guidIsLink(feedItem *gofeed.Item, link string) bool {
+	guid := strings.TrimSpace(feedItem.GUID)
+	return guid == "" || utils.CanonicalizeArticleURL(guid) == link
+}
+
+// errBelowMinWords 文章字数低于源的最小字数，已入库但没有投递
+var errBelowMinWords = errors.New("item below minimum word count")
+
 // processItem 处理单篇文章（增强版）
 // 集成智能图片提取、内容处理、字数统计等功能
-func (w *Worker) processItem(sourceID int64, feedItem *gofeed.Item, userIDs []int64) error {
+// key 为 itemKeys 确定的去重标识
+func (w *Worker) processItem(source *db.Source, feedItem *gofeed.Item, key itemKey, userIDs []int64) error {
 	if feedItem == nil {
 		return fmt.Errorf("feedItem is nil")
 	}
+	sourceID := source.ID
+
+	// 提取内容
+	content := feedItem.Content
+	if content == "" {
+		content = feedItem.Description
+	}
+
+	// 计算内容哈希（用于去重和检测文章修改）
+	contentHash := itemContentHash(feedItem)
 
 	// GUID 去重（基于 source 和 GUID）
-	guid := feedItem.GUID
-	if guid == "" {
-		guid = feedItem.Link
+	guid := key.guid
+	existing, err := w.db.GetItemByGUID(sourceID, guid)
+	if err != nil && err != sql.ErrNoRows {
+		return err
 	}
-	if guid == "" {
-		return fmt.Errorf("item missing both GUID and Link")
+
+	link := utils.CanonicalizeArticleURL(feedItem.Link)
+	if existing == nil && key.collided != "" {
+		// GUID 开始冲突前入库的文章仍使用原始 GUID，内容一致时视为同一篇
+		existing, err = w.db.GetItemByGUID(sourceID, key.collided)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if existing != nil && existing.ContentHash != contentHash {
+			existing = nil
+		}
+	} else if existing == nil && link != "" && guidIsLink(feedItem, link) {
+		// feed 没有 GUID 或以链接作 GUID 时，按规范化链接再查一次，避免 http/https、追踪参数等变体重复入库
+		// 有独立 GUID 的文章共用链接（如同一页面的多条更新）时仍是不同文章，不按链接匹配
+		// GUID 冲突的文章通常也共用链接，不按链接匹配
+		existing, err = w.db.GetItemByURL(sourceID, link)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
 	}
 
-	// 检查是否已存在
-	_, err := w.db.GetItemByGUID(sourceID, guid)
-	if err == nil {
-		// 已存在，跳过
-		return nil
+	// 确认后立即清理、或超出条数上限被删除的文章仍在 feed 中时按墓碑跳过，不以新 ID 重新入库
+	// 与上面的链接回退一致，只有 GUID 为空或就是链接时才按链接匹配
+	if existing == nil {
+		tombstoneURL := ""
+		if key.collided == "" && guidIsLink(feedItem, link) {
+			tombstoneURL = link
+		}
+		tombstoned, err := w.db.MatchItemTombstone(sourceID, guid, tombstoneURL)
+		if err != nil {
+			return err
+		}
+		if tombstoned {
+			return nil
+		}
 	}
-	if err != sql.ErrNoRows {
-		return err
+
+	// 已存在的文章只有在源开启内容更新且内容变化时才重新处理
+	if existing != nil && !shouldUpdateItem(source, existing, contentHash) {
+		return nil
 	}
-	// 不存在，继续创建
-	exists := false
-	if exists {
-		return nil // 文章已存在，跳过
+
+	// 源开启全文提取且 feed 只提供摘要时，从原文链接提取正文；失败时沿用 feed 内容
+	// 提取结果与 feed 内容一样经过图片处理，写入 clean_content；content 仍保存 feed 原文
+	body := content
+	extractedFull := false
+	if extracted := w.fetchFullContent(source, feedItem.Link, content); extracted != "" {
+		log.Printf("[Worker] Using extracted full content for item %s (%d bytes)", guid, len(extracted))
+		body = extracted
+		extractedFull = true
 	}
 
-	// 提取内容
-	content := feedItem.Content
-	if content == "" {
-		content = feedItem.Description
+	// 源开启"只缓存正文图片"时，用 Readability 识别正文主体，图片缓存、封面和图集只考虑其中的图片；
+	// 全文提取的结果已经是正文主体，不再重复识别
+	var article *html.Node
+	imageBody := body
+	if source.BodyImagesOnly && !extractedFull && body != "" {
+		if node, articleHTML := extractArticleNode(body, feedItem.Link); node != nil {
+			article, imageBody = node, articleHTML
+		}
 	}
 
 	// 【新增】使用智能图片提取器
@@ -351,7 +655,7 @@ func (w *Worker) processItem(sourceID int64, feedItem *gofeed.Item, userIDs []in
 	var imageCaption string
 	var imageCredit string
 
-	imageCandidate := w.imageExtractor.ExtractBestImage(feedItem, content)
+	imageCandidate := w.imageExtractor.ExtractBestImage(feedItem, imageBody)
 	if imageCandidate != nil {
 		finalCoverImageURL = imageCandidate.URL
 		imageCaption = imageCandidate.Alt
@@ -361,21 +665,47 @@ func (w *Worker) processItem(sourceID int64, feedItem *gofeed.Item, userIDs []in
 		finalCoverImageURL = w.extractBestImageURL(feedItem)
 	}
 
-	// 处理内容中的图片（下载+压缩+替换）
-	processedContent := content
-	var imagePaths string
+	// 元数据和正文都没有图片时，回退到文章页面的 og:image（每篇多一次请求）
+	if finalCoverImageURL == "" && feedItem.Link != "" && config.GetRuntimeConfig().GetCoverOGImageEnabled() {
+		finalCoverImageURL = w.coverFromArticlePage(feedItem.Link)
+	}
 
-	if content != "" {
-		var err error
-		processedContent, imagePaths, err = w.imageProcessor.ProcessContent(sourceID, content)
-		if err != nil {
-			log.Printf("[Worker] Failed to process images for item %s: %v", guid, err)
-			processedContent = content
+	// 按源的图片模式处理内容中的图片
+	processedContent := body
+	var imagePaths string
+	var localPaths map[string]string
+
+	if body != "" {
+		switch source.ImageMode {
+		case db.ImageModeOff:
+			// 保留原始图片地址
+		case db.ImageModeProxy:
+			// 改写为代理地址，由客户端按需经 /api/image 加载
+			processedContent = w.imageProcessor.ProxyContent(body)
+		default:
+			// 下载+压缩+替换
+			var err error
+			processedContent, imagePaths, localPaths, err = w.imageProcessor.ProcessArticleImages(sourceID, body, article, feedItem.Link)
+			if err != nil {
+				log.Printf("[Worker] Failed to process images for item %s: %v", guid, err)
+				processedContent = body
+			}
 		}
 	}
 
 	// 【新增】文本处理
-	textProcessor := utils.NewTextProcessor()
+	textProcessor := utils.NewTextProcessorWithCorpus(w.corpus)
+
+	// 正文超过大小上限时截断（原文链接保留在 xml_content 中，客户端可打开原文查看全文）
+	storedContent := content
+	truncated := false
+	if maxBytes := config.GetRuntimeConfig().GetMaxContentBytes(); len(processedContent) > maxBytes || len(content) > maxBytes {
+		originalSize := len(processedContent)
+		processedContent, truncated = textProcessor.TruncateHTMLBytes(processedContent, maxBytes)
+		storedContent, _ = textProcessor.TruncateHTMLBytes(content, maxBytes)
+		truncated = truncated || len(storedContent) < len(content)
+		log.Printf("[Worker] Truncated content of item %s: %d -> %d bytes (limit %d)", guid, originalSize, len(processedContent), maxBytes)
+	}
 
 	// 计算字数
 	wordCount := textProcessor.CountWords(processedContent)
@@ -383,42 +713,84 @@ func (w *Worker) processItem(sourceID int64, feedItem *gofeed.Item, userIDs []in
 	// 估算阅读时间
 	readingTime := textProcessor.EstimateReadingTime(wordCount)
 
-	// 生成摘要
-	summary := textProcessor.GenerateSummary(processedContent, 200)
-
-	// 计算难度（之后可用于扩展字段）
-	_ = textProcessor.CalculateDifficulty(processedContent)
-
-	// 【新增】完整内容字段（替代clean_content）
-	// fullContent := processedContent // fullContent placeholder, now used
+	// 生成摘要（feed 自带人工摘要时优先使用，长度按源设置）
+	summary := textProcessor.SelectSummary(feedItem.Description, processedContent, source.SummaryLength)
 
-	// 可选：尝试从原始URL提取完整内容
-	// if feedItem.Link != "" && len(processedContent) < 500 {
-	// 	log.Printf("[Worker] Content too short, attempting full content extraction from: %s", feedItem.Link)
-	// 	if extracted, err := w.contentExtractor.ExtractFullContentWithTimeout(feedItem.Link, 30*time.Second); err == nil && extracted != "" {
-	// 		fullContent = extracted
-	// 		log.Printf("[Worker] Successfully extracted full content (%d bytes)", len(fullContent))
-	// 	}
-	// }
+	// 计算难度
+	difficulty := textProcessor.CalculateDifficulty(processedContent)
 
 	// 构建 XML content（兼容现有客户端）
 	xmlContent := w.buildXMLContent(feedItem, processedContent)
 
-	// 计算内容哈希（用于去重）
-	contentHash := fmt.Sprintf("%x", sha256.Sum256([]byte(feedItem.Title+content)))
+	// 生成标签（feed 自带分类优先，关键词补足）
+	tags := buildItemTags(textProcessor, sourceID, feedItem, processedContent)
+
+	// 分类：feed 首个分类优先，没有时继承源分类
+	category := itemCategory(textProcessor, feedItem, source.Category)
+
+	// 图集（源开启时）：正文前几张图片
+	gallery := w.buildGallery(source, imageBody, localPaths)
+
+	// 播客字段（iTunes 命名空间），同步输出时原样写回
+	podcast := itemPodcast(feedItem)
+
+	// 提取封面图主色调和 BlurHash（用于客户端占位背景），并生成封面缩略图（仅 process 模式会下载图片）
+	// 缩略图路径同时记入 image_paths，随文章一起清理
+	var imagePrimaryColor, imageBlurhash, coverVariants string
+	if finalCoverImageURL != "" && source.ImageMode != db.ImageModeProxy && source.ImageMode != db.ImageModeOff {
+		rc := config.GetRuntimeConfig()
+		if cover, err := w.imageProcessor.ProcessCover(sourceID, finalCoverImageURL, rc.GetCoverBlurhashEnabled(), rc.GetCoverVariantWidths()); err != nil {
+			log.Printf("[Worker] Failed to process cover for item %s: %v", guid, err)
+		} else {
+			imagePrimaryColor, imageBlurhash = cover.PrimaryColor, cover.Blurhash
+			coverVariants, imagePaths = encodeCoverVariants(cover.Variants, imagePaths)
+		}
+	}
+
+	// 已存在的文章：原地更新内容，不重复投递
+	if existing != nil {
+		updated := &db.Item{
+			ID:                existing.ID,
+			Title:             feedItem.Title,
+			XMLContent:        xmlContent,
+			ImagePaths:        imagePaths,
+			Summary:           summary,
+			Difficulty:        difficulty,
+			WordCount:         wordCount,
+			ReadingTime:       readingTime,
+			CoverImage:        finalCoverImageURL,
+			Author:            getAuthor(feedItem),
+			CleanContent:      processedContent,
+			Content:           storedContent,
+			ContentHash:       contentHash,
+			ImageCaption:      imageCaption,
+			ImageCredit:       imageCredit,
+			ImagePrimaryColor: imagePrimaryColor,
+			ImageBlurhash:     imageBlurhash,
+			CoverVariants:     coverVariants,
+			Tags:              tags,
+			Truncated:         truncated,
+			Gallery:           gallery,
+			ITunesDuration:    podcast.duration,
+			ITunesImage:       podcast.image,
+			ITunesEpisode:     podcast.episode,
+			ITunesAuthor:      podcast.author,
+		}
+		markUnread := source.ContentUpdateMode == db.ContentUpdateUnread
+		if err := w.db.UpdateItemContent(updated, existing.ContentHash, markUnread); err != nil {
+			return err
+		}
+		w.removeReplacedImages(existing.ImagePaths, imagePaths)
 
-	// 【新增】提取分类（从source或feedItem）
-	// category := "" // category placeholder, now used
-	// if len(feedItem.Categories) > 0 {
-	// 	category = feedItem.Categories[0]
-	// }
+		log.Printf("[Worker] Item updated: id=%d, title=%s, mark_unread=%v", existing.ID, feedItem.Title, markUnread)
+		return nil
+	}
 
 	// 保存到 items 表（使用扩展字段）
-	var publishedAt *time.Time
-	if feedItem.PublishedParsed != nil {
-		publishedAt = feedItem.PublishedParsed
-	} else if feedItem.UpdatedParsed != nil {
-		publishedAt = feedItem.UpdatedParsed
+	// 发布时间统一为 UTC；无法解析时使用当前时间，不存 NULL
+	publishedAt, ok := itemPublishedAt(feedItem, config.GetRuntimeConfig().GetFeedLocation(), time.Now())
+	if !ok {
+		log.Printf("[Worker] Unparseable published date %q for item %s, using fetch time", feedItem.Published, guid)
 	}
 
 	// 使用CreateItem方法的正确signature
@@ -428,18 +800,30 @@ func (w *Worker) processItem(sourceID int64, feedItem *gofeed.Item, userIDs []in
 		feedItem.Title,
 		xmlContent,
 		imagePaths,
-		publishedAt,
+		&publishedAt,
 		summary,
+		difficulty,
 		wordCount,
 		readingTime,
 		finalCoverImageURL,
 		getAuthor(feedItem),
 		processedContent,
-		content, // Original content
+		storedContent, // Original content
 		contentHash,
 		imageCaption,
 		imageCredit,
 		imagePrimaryColor,
+		tags,
+		category,
+		truncated,
+		gallery,
+		link,
+		imageBlurhash,
+		podcast.duration,
+		podcast.image,
+		podcast.episode,
+		podcast.author,
+		coverVariants,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create item: %w", err)
@@ -448,6 +832,16 @@ func (w *Worker) processItem(sourceID int64, feedItem *gofeed.Item, userIDs []in
 	log.Printf("[Worker] Item processed: id=%d, title=%s, words=%d, reading_time=%d min",
 		item.ID, feedItem.Title, wordCount, readingTime)
 
+	// 记录源最近产出文章的时间（用于判断源是否已停更）
+	if err := w.db.UpdateSourceLastItemAdded(sourceID); err != nil {
+		log.Printf("[Worker] Failed to update last item time for source %d: %v", sourceID, err)
+	}
+
+	// 标题占位、链接汇总等字数过少的文章只入库（用于去重），不投递给用户
+	if source.MinWordCount > 0 && wordCount < source.MinWordCount {
+		return errBelowMinWords
+	}
+
 	// 为所有订阅该源的用户创建投递记录
 	for _, userID := range userIDs {
 		if err := w.db.CreateUserDelivery(userID, item.ID); err != nil {
@@ -458,6 +852,104 @@ func (w *Worker) processItem(sourceID int64, feedItem *gofeed.Item, userIDs []in
 	return nil
 }
 
+// shouldUpdateItem 判断已入库文章是否需要按 feed 中修改后的内容更新
+// 需要源开启内容更新、内容哈希变化且文章仍在保留期内；没有内容哈希的旧数据不更新
+func shouldUpdateItem(source *db.Source, existing *db.Item, contentHash string) bool {
+	if source.ContentUpdateMode != db.ContentUpdateApply && source.ContentUpdateMode != db.ContentUpdateUnread {
+		return false
+	}
+	if existing.ContentHash == "" || existing.ContentHash == contentHash {
+		return false
+	}
+
+	retention := config.GetRuntimeConfig().GetItemRetentionTime()
+	if source.RetentionSeconds > 0 {
+		retention = source.RetentionSeconds
+	}
+	return time.Since(existing.CreatedAt) < time.Duration(retention)*time.Second
+}
+
+// removeReplacedImages 删除文章更新后不再引用的本地图片
+func (w *Worker) removeReplacedImages(oldPaths, newPaths string) {
+	var previous, current []string
+	if oldPaths == "" || json.Unmarshal([]byte(oldPaths), &previous) != nil {
+		return
+	}
+	if newPaths != "" {
+		_ = json.Unmarshal([]byte(newPaths), &current)
+	}
+
+	kept := make(map[string]bool, len(current))
+	for _, p := range current {
+		kept[p] = true
+	}
+	var stale []string
+	for _, p := range previous {
+		if !kept[p] {
+			stale = append(stale, p)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	data, _ := json.Marshal(stale)
+	if err := image.DeleteImageFiles(w.staticDir, string(data)); err != nil {
+		log.Printf("[Worker] Failed to delete replaced images: %v", err)
+	}
+}
+
+// buildItemTags 合并 feed 分类和正文关键词，返回规范化后的标签 JSON 数组
+func buildItemTags(textProcessor *utils.TextProcessor, sourceID int64, feedItem *gofeed.Item, content string) string {
+	candidates := make([]string, 0, len(feedItem.Categories)+maxItemTags)
+	for _, category := range feedItem.Categories {
+		// 部分 feed 把多个分类写在同一个字段里
+		candidates = append(candidates, strings.Split(category, ",")...)
+	}
+	candidates = append(candidates, textProcessor.ExtractKeywordsTFIDF(feedItem.Title+" "+content, sourceID, maxItemTags)...)
+
+	tags := textProcessor.NormalizeTags(candidates, maxItemTags)
+	if len(tags) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// itemCategory 取 feed 中第一个有效分类，没有时使用源明确设置的分类（未设置时为空）
+func itemCategory(textProcessor *utils.TextProcessor, feedItem *gofeed.Item, sourceCategory string) string {
+	for _, category := range feedItem.Categories {
+		for _, part := range strings.Split(category, ",") {
+			if normalized := textProcessor.NormalizeCategory(part); normalized != "" {
+				return normalized
+			}
+		}
+	}
+	return textProcessor.NormalizeCategory(sourceCategory)
+}
+
+// podcastFields 播客条目的 iTunes 扩展字段
+type podcastFields struct {
+	duration, image, episode, author string
+}
+
+// itemPodcast 读取 feed 条目的 iTunes 扩展字段，不是播客时全部为空
+func itemPodcast(feedItem *gofeed.Item) podcastFields {
+	ext := feedItem.ITunesExt
+	if ext == nil {
+		return podcastFields{}
+	}
+	return podcastFields{
+		duration: strings.TrimSpace(ext.Duration),
+		image:    strings.TrimSpace(ext.Image),
+		episode:  strings.TrimSpace(ext.Episode),
+		author:   strings.TrimSpace(ext.Author),
+	}
+}
+
 func getAuthor(feedItem *gofeed.Item) string {
 	if len(feedItem.Authors) > 0 && feedItem.Authors[0] != nil {
 		return feedItem.Authors[0].Name
@@ -476,10 +968,14 @@ func (w *Worker) extractBestImageURL(feedItem *gofeed.Item) string {
 		return feedItem.Image.URL
 	}
 
-	// 2. 尝试从 media:content 扩展中获取
+	// 2. 尝试从 media:content 扩展中获取（跳过视频和声明尺寸过小的图）
 	if mediaExts, ok := feedItem.Extensions["media"]; ok {
 		if contents, ok := mediaExts["content"]; ok {
 			for _, ext := range contents {
+				media := MediaContentInfo{Medium: ext.Attrs["medium"], Type: ext.Attrs["type"]}
+				if media.IsVideo() || declaredTooSmall(ext.Attrs) {
+					continue
+				}
 				if url := strings.TrimSpace(ext.Attrs["url"]); url != "" {
 					return url
 				}
@@ -488,6 +984,9 @@ func (w *Worker) extractBestImageURL(feedItem *gofeed.Item) string {
 		// 3. 再尝试 media:thumbnail
 		if thumbs, ok := mediaExts["thumbnail"]; ok {
 			for _, ext := range thumbs {
+				if declaredTooSmall(ext.Attrs) {
+					continue
+				}
 				if url := strings.TrimSpace(ext.Attrs["url"]); url != "" {
 					return url
 				}
@@ -505,6 +1004,14 @@ func (w *Worker) extractBestImageURL(feedItem *gofeed.Item) string {
 	return ""
 }
 
+// declaredTooSmall 判断 media 节点声明的尺寸是否小于封面最小尺寸
+func declaredTooSmall(attrs map[string]string) bool {
+	width, _ := strconv.Atoi(getAttr(attrs, "width"))
+	height, _ := strconv.Atoi(getAttr(attrs, "height"))
+	rc := config.GetRuntimeConfig()
+	return isTooSmall(width, height, rc.GetCoverMinWidth(), rc.GetCoverMinHeight())
+}
+
 // buildXMLContent 构建 XML 内容片段
 func (w *Worker) buildXMLContent(feedItem *gofeed.Item, content string) string {
 	var sb strings.Builder
@@ -532,6 +1039,8 @@ func (w *Worker) buildXMLContent(feedItem *gofeed.Item, content string) string {
 }
 
 // CleanupExpiredItems 清理已超时的文章
+// 保留时间优先使用源级设置，未设置时使用全局 ItemRetentionTime；已发送的文章从发送时间算起，
+// 在应用内已读但未确认发送的文章从阅读时间算起；收藏、未发送和阅读中的文章不会被清理
 func (w *Worker) CleanupExpiredItems() {
 	// 添加 panic 恢复
 	defer func() {
@@ -541,37 +1050,48 @@ func (w *Worker) CleanupExpiredItems() {
 	}()
 
 	rc := config.GetRuntimeConfig()
-	retentionTime := int64(rc.GetItemRetentionTime())
-	nowUnix := time.Now().Unix()
-	expiryTime := nowUnix - retentionTime
+	globalRetention := rc.GetItemRetentionTime()
+	now := time.Now()
 
-	log.Printf("[CLEANUP] Starting cleanup task, expiry threshold: %d seconds ago", retentionTime)
+	log.Printf("[CLEANUP] Starting cleanup task, default expiry threshold: %d seconds ago", globalRetention)
 
-	// 获取所有已常新的文章（status=1）
-	deliveredItems, err := w.db.GetDeliveredItems()
+	// 清理长期没有对应文章的阅读状态（按内容哈希保存，文章删除时不会级联删除）
+	if pruned, err := w.db.PruneReadState(now.Add(-readStateRetention)); err != nil {
+		log.Printf("[CLEANUP] Failed to prune read state: %v", err)
+	} else if pruned > 0 {
+		log.Printf("[CLEANUP] Pruned %d orphaned read state rows", pruned)
+	}
+
+	// 清理长期没有再出现在 feed 中的墓碑（文章已从 feed 移除，不会再被抓到）
+	if pruned, err := w.db.PruneItemTombstones(now.Add(-itemTombstoneRetention)); err != nil {
+		log.Printf("[CLEANUP] Failed to prune item tombstones: %v", err)
+	} else if pruned > 0 {
+		log.Printf("[CLEANUP] Pruned %d stale item tombstones", pruned)
+	}
+
+	// 获取可清理的已发送或已读文章（附带源级保留时间）
+	items, err := w.db.GetExpirableItems()
 	if err != nil {
 		log.Printf("[CLEANUP] Failed to get delivered items: %v", err)
 		return
 	}
 
-	if len(deliveredItems) == 0 {
-		log.Printf("[CLEANUP] No delivered items to clean")
+	if len(items) == 0 {
+		log.Printf("[CLEANUP] No delivered or read items to clean")
 		return
 	}
 
 	cleaned := 0
-	for _, itemID := range deliveredItems {
-		// 获取文章的最近投递时间
-		deliveredTime, err := w.db.GetItemDeliveredTime(itemID)
-		if err != nil {
-			log.Printf("[CLEANUP] Failed to get delivered time for item %d: %v", itemID, err)
-			continue
+	for _, item := range items {
+		retention := globalRetention
+		if item.RetentionSeconds > 0 {
+			retention = item.RetentionSeconds
 		}
 
-		// 判断是否超时
-		if deliveredTime.Unix() < expiryTime {
-			if err := w.cleanupItem(itemID); err != nil {
-				log.Printf("[CLEANUP] Failed to cleanup item %d: %v", itemID, err)
+		// 判断是否超时（从最近一次发送或阅读算起）
+		if now.Sub(item.LastActivityAt) > time.Duration(retention)*time.Second {
+			if err := w.cleanupItem(item.ItemID); err != nil {
+				log.Printf("[CLEANUP] Failed to cleanup item %d: %v", item.ItemID, err)
 			} else {
 				cleaned++
 			}
@@ -583,6 +1103,43 @@ func (w *Worker) CleanupExpiredItems() {
 	}
 }
 
+// recordFeedValidators 记录本次抓取响应的校验值（有变化时才写入）
+// 文章全部处理成功后才记录：有文章处理失败（failed > 0）时下次仍完整抓取，失败的文章可以重试
+func (w *Worker) recordFeedValidators(source *db.Source, validators *feedValidators, failed int) {
+	if failed > 0 || (validators.ETag == source.HTTPETag && validators.LastModified == source.HTTPLastModified) {
+		return
+	}
+	if err := w.db.UpdateSourceValidators(source.ID, validators.ETag, validators.LastModified); err != nil {
+		log.Printf("[Worker] Failed to record validators for source %d: %v", source.ID, err)
+		return
+	}
+	source.HTTPETag, source.HTTPLastModified = validators.ETag, validators.LastModified
+}
+
+// TrimSource 按源的文章数上限（MaxItems）删除最旧的文章及其图片，返回删除的文章数
+// 未确认、收藏或阅读中的文章保留，见 db.TrimSourceItems
+func (w *Worker) TrimSource(source *db.Source) (int, error) {
+	trimmed, imagePaths, err := w.db.TrimSourceItems(source.ID, source.MaxItems)
+	if err != nil || trimmed == 0 {
+		return 0, err
+	}
+
+	for _, paths := range imagePaths {
+		if err := image.DeleteImageFiles(w.staticDir, paths); err != nil {
+			log.Printf("[CLEANUP] Failed to delete image files for source %d: %v", source.ID, err)
+		}
+	}
+	if len(imagePaths) > 0 {
+		imageDir := image.GetImageDirPath(w.staticDir, source.ID)
+		if err := image.RemoveEmptyDir(imageDir); err != nil {
+			log.Printf("[CLEANUP] Failed to remove empty dir %s: %v", imageDir, err)
+		}
+	}
+
+	log.Printf("[CLEANUP] Trimmed %d items from source %d (max %d)", trimmed, source.ID, source.MaxItems)
+	return int(trimmed), nil
+}
+
 // cleanupItem 清理文章及相关资源
 func (w *Worker) cleanupItem(itemID int64) error {
 	// 获取文章信息
@@ -605,12 +1162,7 @@ func (w *Worker) cleanupItem(itemID int64) error {
 		}
 	}
 
-	// 删除投递记录
-	if err := w.db.DeleteUserDeliveries(itemID); err != nil {
-		return err
-	}
-
-	// 删除文章
+	// 删除文章及投递记录
 	if err := w.db.DeleteItem(itemID); err != nil {
 		return err
 	}